@@ -0,0 +1,270 @@
+// Command build cross-compiles the binaries and packages them for release:
+//
+//	go run ./build [-version 1.2.3] [-targets linux/amd64,windows/arm64] [-out dist] [-skip-tests]
+//
+// It runs the conformance suite first: the unit and golden tests, then the
+// client's full exchange with a freshly built server. Each target then gets
+// mcp-server, mcp-client and mcp-host, built with the version, commit and
+// build date embedded as the Makefile does, in a .tar.gz (a .zip for Windows)
+// with the README and license. SHA256SUMS lists the checksums of the archives.
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// binaries are the commands that are built, relative to the module root.
+var binaries = []string{"cmd/mcp-server", "cmd/mcp-client", "cmd/mcp-host"}
+
+// defaultTargets are the platforms built without -targets.
+var defaultTargets = []string{
+	"linux/amd64", "linux/arm64",
+	"darwin/amd64", "darwin/arm64",
+	"windows/amd64", "windows/arm64",
+}
+
+// extraFiles are added to every archive.
+var extraFiles = []string{"README.md", "LICENSE"}
+
+// conformanceTimeout bounds the client's run against the server.
+const conformanceTimeout = 2 * time.Minute
+
+func main() {
+	version := flag.String("version", "0.1.0", "Version embedded in the binaries and archive names")
+	targets := flag.String("targets", strings.Join(defaultTargets, ","), "Comma-separated GOOS/GOARCH pairs to build")
+	out := flag.String("out", "dist", "Directory the archives are written to")
+	skipTests := flag.Bool("skip-tests", false, "Do not run the conformance suite before building")
+	flag.Parse()
+	log.SetFlags(0)
+	log.SetPrefix("build: ")
+
+	if !*skipTests {
+		if err := conformance(); err != nil {
+			log.Fatalf("conformance suite failed: %v", err)
+		}
+	}
+
+	ldflags := fmt.Sprintf("-s -w -X sqirvy/mcp/pkg/server.version=%s -X sqirvy/mcp/pkg/server.commit=%s -X sqirvy/mcp/pkg/server.buildDate=%s",
+		*version, gitCommit(), time.Now().UTC().Format(time.RFC3339))
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		log.Fatal(err)
+	}
+	var archives []string
+	for _, target := range strings.Split(*targets, ",") {
+		goos, goarch, ok := strings.Cut(strings.TrimSpace(target), "/")
+		if !ok {
+			log.Fatalf("invalid target %q (want GOOS/GOARCH)", target)
+		}
+		archive, err := release(*out, *version, goos, goarch, ldflags)
+		if err != nil {
+			log.Fatalf("%s/%s: %v", goos, goarch, err)
+		}
+		log.Printf("wrote %s", archive)
+		archives = append(archives, archive)
+	}
+	if err := writeChecksums(filepath.Join(*out, "SHA256SUMS"), archives); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run runs a command with its output passed through, in the module root.
+func run(env []string, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	cmd.Env = append(os.Environ(), env...)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s %s: %w", name, strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// conformance runs the tests, then the client's whole exchange with the server
+// over stdio, with binaries built for this machine.
+func conformance() error {
+	log.Print("running go vet and go test")
+	if err := run(nil, "go", "vet", "./..."); err != nil {
+		return err
+	}
+	if err := run(nil, "go", "test", "./..."); err != nil {
+		return err
+	}
+
+	log.Print("running mcp-client against mcp-server")
+	dir, err := os.MkdirTemp("", "mcp-conformance-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	server := filepath.Join(dir, "mcp-server"+exeSuffix(runtime.GOOS))
+	client := filepath.Join(dir, "mcp-client"+exeSuffix(runtime.GOOS))
+	if err := run(nil, "go", "build", "-o", server, "./cmd/mcp-server"); err != nil {
+		return err
+	}
+	if err := run(nil, "go", "build", "-o", client, "./cmd/mcp-client"); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), conformanceTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, client, "-server-path", server, "-server-log", filepath.Join(dir, "mcp-server.log"))
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		os.Stderr.Write(output)
+		return fmt.Errorf("mcp-client: %w", err)
+	}
+	return nil
+}
+
+// release builds every binary for goos/goarch and archives them.
+func release(out, version, goos, goarch, ldflags string) (string, error) {
+	name := fmt.Sprintf("mcp_%s_%s_%s", version, goos, goarch)
+	stage := filepath.Join(out, name)
+	if err := os.RemoveAll(stage); err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(stage)
+	env := []string{"GOOS=" + goos, "GOARCH=" + goarch, "CGO_ENABLED=0"}
+	files := append([]string(nil), extraFiles...)
+	for _, pkg := range binaries {
+		binary := filepath.Base(pkg) + exeSuffix(goos)
+		if err := run(env, "go", "build", "-trimpath", "-ldflags", ldflags, "-o", filepath.Join(stage, binary), "./"+pkg); err != nil {
+			return "", err
+		}
+		files = append(files, filepath.Join(stage, binary))
+	}
+	if goos == "windows" {
+		return filepath.Join(out, name+".zip"), writeZip(filepath.Join(out, name+".zip"), name, files)
+	}
+	return filepath.Join(out, name+".tar.gz"), writeTarGz(filepath.Join(out, name+".tar.gz"), name, files)
+}
+
+// writeTarGz archives files under the directory prefix.
+func writeTarGz(path, prefix string, files []string) (err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = prefix + "/" + filepath.Base(file)
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if err := copyFile(tw, file); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// writeZip archives files under the directory prefix.
+func writeZip(path, prefix string, files []string) (err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	zw := zip.NewWriter(f)
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = prefix + "/" + filepath.Base(file)
+		header.Method = zip.Deflate
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		if err := copyFile(w, file); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// copyFile copies the contents of the file at path to w.
+func copyFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// writeChecksums writes the SHA-256 of each file in the format of sha256sum.
+func writeChecksums(path string, files []string) error {
+	var sums strings.Builder
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		h := sha256.New()
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&sums, "%s  %s\n", hex.EncodeToString(h.Sum(nil)), filepath.Base(file))
+	}
+	return os.WriteFile(path, []byte(sums.String()), 0o644)
+}
+
+// gitCommit returns the commit being built, or "" outside a git checkout.
+func gitCommit() string {
+	out, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func exeSuffix(goos string) string {
+	if goos == "windows" {
+		return ".exe"
+	}
+	return ""
+}
@@ -0,0 +1,9 @@
+// Command mcp-client runs the MCP client of sqirvy/mcp/pkg/client against a
+// spawned or remote server.
+package main
+
+import "sqirvy/mcp/pkg/client"
+
+func main() {
+	client.Main()
+}
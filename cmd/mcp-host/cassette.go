@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// CassetteMode selects whether a cassette transport records live traffic or replays it.
+type CassetteMode int
+
+const (
+	// CassetteRecord forwards requests to the provider and stores each interaction.
+	CassetteRecord CassetteMode = iota
+	// CassetteReplay answers requests from the cassette file and never touches the network.
+	CassetteReplay
+)
+
+// interaction is one recorded request/response pair in a cassette file.
+type interaction struct {
+	Key         string          `json:"key"`
+	Method      string          `json:"method"`
+	Path        string          `json:"path"`
+	Request     json.RawMessage `json:"request,omitempty"`
+	Status      int             `json:"status"`
+	ContentType string          `json:"contentType,omitempty"`
+	Response    json.RawMessage `json:"response"`
+}
+
+// cassetteFile is the on-disk format of a cassette.
+type cassetteFile struct {
+	Interactions []interaction `json:"interactions"`
+}
+
+// CassetteTransport is an http.RoundTripper that records provider calls to a file
+// or replays them from it, VCR style. Interactions are keyed by a hash of the
+// method, path and canonicalized request body; identical requests are replayed
+// in the order they were recorded.
+type CassetteTransport struct {
+	path  string
+	mode  CassetteMode
+	inner http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []interaction
+	played       map[string]int // key -> number of times replayed
+}
+
+// NewCassetteTransport opens (replay) or creates (record) the cassette at path.
+// inner is used only in record mode; nil means http.DefaultTransport.
+func NewCassetteTransport(path string, mode CassetteMode, inner http.RoundTripper) (*CassetteTransport, error) {
+	if inner == nil {
+		inner = http.DefaultTransport
+	}
+	t := &CassetteTransport{
+		path:   path,
+		mode:   mode,
+		inner:  inner,
+		played: make(map[string]int),
+	}
+	if mode == CassetteReplay {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cassette: %w", err)
+		}
+		var file cassetteFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+		}
+		t.interactions = file.Interactions
+	}
+	return t, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *CassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+	}
+	key := cassetteKey(req.Method, req.URL.Path, body)
+
+	if t.mode == CassetteReplay {
+		return t.replay(req, key)
+	}
+
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	resp, err := t.inner.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	// Only successful JSON responses are worth replaying; errors and streams are passed through.
+	if resp.StatusCode/100 == 2 && json.Valid(respBody) {
+		rec := interaction{
+			Key:         key,
+			Method:      req.Method,
+			Path:        req.URL.Path,
+			Status:      resp.StatusCode,
+			ContentType: resp.Header.Get("Content-Type"),
+			Response:    respBody,
+		}
+		if json.Valid(body) {
+			rec.Request = body
+		}
+		if err := t.record(rec); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// replay returns the next recorded response for key.
+func (t *CassetteTransport) replay(req *http.Request, key string) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	skip := t.played[key]
+	for _, rec := range t.interactions {
+		if rec.Key != key {
+			continue
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		t.played[key]++
+		header := make(http.Header)
+		if rec.ContentType != "" {
+			header.Set("Content-Type", rec.ContentType)
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", rec.Status, http.StatusText(rec.Status)),
+			StatusCode:    rec.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(rec.Response)),
+			ContentLength: int64(len(rec.Response)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("cassette %s has no recording for %s %s (key %s)", t.path, req.Method, req.URL.Path, key)
+}
+
+// record appends rec and rewrites the cassette file, so an interrupted run keeps what it captured.
+func (t *CassetteTransport) record(rec interaction) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.interactions = append(t.interactions, rec)
+	data, err := json.MarshalIndent(cassetteFile{Interactions: t.interactions}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal cassette: %w", err)
+	}
+	if err := os.WriteFile(t.path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
+
+// cassetteKey hashes the parts of a request that determine the provider's answer.
+// JSON bodies are re-encoded so that key order and whitespace do not matter.
+func cassetteKey(method, path string, body []byte) string {
+	var v any
+	if err := json.Unmarshal(body, &v); err == nil {
+		if canonical, err := json.Marshal(v); err == nil {
+			body = canonical
+		}
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", method, path)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,64 @@
+// Anthropic API Client
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+)
+
+func main() {
+	recordPath := flag.String("record", "", "Record provider calls to this cassette file")
+	replayPath := flag.String("replay", "", "Replay provider calls from this cassette file (no network access)")
+	flag.Parse()
+
+	if *recordPath != "" && *replayPath != "" {
+		fmt.Println("-record and -replay are mutually exclusive")
+		return
+	}
+
+	var opts []option.RequestOption
+	switch {
+	case *replayPath != "":
+		cassette, err := NewCassetteTransport(*replayPath, CassetteReplay, nil)
+		if err != nil {
+			fmt.Println("Error opening cassette:", err)
+			return
+		}
+		// Replay never reaches the API, so a placeholder key is sufficient
+		opts = append(opts, option.WithHTTPClient(&http.Client{Transport: cassette}), option.WithAPIKey("replay"), option.WithMaxRetries(0))
+	case *recordPath != "":
+		cassette, err := NewCassetteTransport(*recordPath, CassetteRecord, nil)
+		if err != nil {
+			fmt.Println("Error opening cassette:", err)
+			return
+		}
+		opts = append(opts, option.WithHTTPClient(&http.Client{Transport: cassette}))
+	}
+
+	if *replayPath == "" && os.Getenv("ANTHROPIC_API_KEY") == "" {
+		fmt.Println("ANTHROPIC_API_KEY environment variable not set")
+		return
+	}
+
+	// Initialize the Anthropic client with the API key from environment variable
+	var client = anthropic.NewClient(opts...)
+
+	// Example usage of QueryText method
+	ctx := context.Background()
+	prompts := []string{"Hello, how are you?"}
+	model := "claude-3-7-sonnet-latest"
+
+	response, err := QueryText(ctx, &client, prompts, model)
+	if err != nil {
+		fmt.Println("Error querying text:", err)
+		return
+	}
+
+	fmt.Println("Response:", response)
+}
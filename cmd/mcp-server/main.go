@@ -0,0 +1,9 @@
+// Command mcp-server serves the MCP tools, resources and prompts of
+// sqirvy/mcp/pkg/server over stdio, sockets, HTTP or gRPC.
+package main
+
+import "sqirvy/mcp/pkg/server"
+
+func main() {
+	server.Main()
+}
@@ -0,0 +1,102 @@
+// Package layout checks the dependency rules between the parts of the module:
+//
+//   - pkg/ is the supported API, so it imports nothing from internal/ or cmd/;
+//   - internal/ holds checks of the module itself and imports nothing from cmd/;
+//   - each binary under cmd/ builds on pkg/ only, so that everything it does
+//     can also be done by other programs.
+package layout
+
+import (
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLayers(t *testing.T) {
+	root := filepath.Join("..", "..")
+	gomod, err := os.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	module := strings.TrimSpace(strings.TrimPrefix(strings.SplitN(string(gomod), "\n", 2)[0], "module"))
+
+	checked := 0
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".go") {
+			return err
+		}
+		rel, _ := filepath.Rel(root, filepath.Dir(path))
+		dir := filepath.ToSlash(rel)
+		file, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.ImportsOnly)
+		if err != nil {
+			return err
+		}
+		for _, spec := range file.Imports {
+			imported, _ := strconv.Unquote(spec.Path.Value)
+			local, ok := strings.CutPrefix(imported, module+"/")
+			if !ok {
+				continue
+			}
+			checked++
+			if reason := forbidden(dir, local); reason != "" {
+				t.Errorf("%s imports %s: %s", path, imported, reason)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checked == 0 {
+		t.Fatal("no imports of the module's own packages found")
+	}
+}
+
+// forbidden returns why the package in dir may not import the module's
+// package at imported, or "" if it may. Both are relative to the module root.
+func forbidden(dir, imported string) string {
+	top := func(p string) string { return strings.SplitN(p, "/", 2)[0] }
+	switch top(dir) {
+	case "pkg":
+		if top(imported) != "pkg" {
+			return "the public API under pkg/ must not depend on internal/ or cmd/"
+		}
+	case "internal":
+		if top(imported) == "cmd" {
+			return "internal/ must not depend on a binary"
+		}
+	case "cmd":
+		switch top(imported) {
+		case "cmd":
+			return "a binary must not depend on another binary"
+		case "internal":
+			return "a binary must build on the public API under pkg/, not internal/"
+		}
+	}
+	return ""
+}
+
+func TestForbidden(t *testing.T) {
+	for _, tt := range []struct {
+		dir, imported string
+		allowed       bool
+	}{
+		{"pkg/server", "pkg/mcp", true},
+		{"pkg/server", "internal/layout", false},
+		{"pkg/server", "cmd/mcp-server", false},
+		{"internal/layout", "pkg/mcp", true},
+		{"internal/layout", "cmd/mcp-server", false},
+		{"cmd/mcp-server", "pkg/server", true},
+		{"cmd/mcp-server", "internal/layout", false},
+		{"cmd/mcp-server", "cmd/mcp-client", false},
+	} {
+		if reason := forbidden(tt.dir, tt.imported); (reason == "") != tt.allowed {
+			t.Errorf("forbidden(%s, %s) = %q", tt.dir, tt.imported, reason)
+		}
+	}
+}
@@ -1,35 +1,63 @@
-package main
+// Package client implements mcp-client. A Client runs one MCP session with a
+// server over a transport.Transport, answering the server's requests as it
+// goes; Main is the mcp-client command, which cmd/mcp-client runs.
+package client
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"strings"
+	"sync"
 	"sync/atomic"
 
 	"sqirvy/mcp/pkg/mcp" // Use the correct module path
+	"sqirvy/mcp/pkg/transport"
 )
 
 const (
-	protocolVersion         = "2024-11-05" // Match the server/spec version
-	clientName              = "GoMCPExampleClient"
-	clientVersion           = "0.1.0"
-	notificationInitialized = "initialized" // Method name for the initialized notification
+	protocolVersion = "2024-11-05" // Match the server/spec version
+	clientName      = "GoMCPExampleClient"
+	clientTitle     = "Go MCP Example Client"
+	clientVersion   = "0.1.0"
 )
 
 // Client handles the MCP client logic.
 type Client struct {
-	transport *StdioTransport
+	transport transport.Transport
 	logger    *log.Logger
 	requestID atomic.Int64 // Safely incrementing request ID
+
+	mu             sync.Mutex                     // Protects handlers, notifyHandlers, onOrphan and serverCaps
+	handlers       map[string]RequestHandler      // Handlers for server-initiated requests, keyed by method
+	notifyHandlers map[string]NotificationHandler // Handlers for server notifications, keyed by method
+	onOrphan       OrphanHandler                  // Told about responses to unknown request IDs; may be nil
+	sent           map[string]sentRequest         // Requests awaiting a response, by ID; see hooks.go
+	hooks          mcp.Hooks                      // Lifecycle hooks, see hooks.go
+	serverCaps     mcp.ServerCapabilities         // Advertised by the server in initialize, see extension.go
+
+	linkMu       sync.Mutex                   // Protects resolveLinks and linked
+	resolveLinks bool                         // Replace resource links in tool results, see links.go
+	linked       map[string][]json.RawMessage // Contents of linked resources already read, by URI
 }
 
-// NewClient creates a new MCP client instance.
-func NewClient(transport *StdioTransport, logger *log.Logger) *Client {
-	return &Client{
-		transport: transport,
+// NewClient creates a new MCP client instance. Hooks, if any, are called in
+// order at points in the session's life.
+func NewClient(t transport.Transport, logger *log.Logger, hooks ...mcp.Hooks) *Client {
+	c := &Client{
+		transport: t,
 		logger:    logger,
+		handlers: map[string]RequestHandler{
+			mcp.MethodPing: pingHandler,
+		},
+		notifyHandlers: make(map[string]NotificationHandler),
+		sent:           make(map[string]sentRequest),
+	}
+	if len(hooks) > 0 {
+		c.hooks = mcp.ChainHooks(hooks...)
 	}
+	return c
 }
 
 // nextID generates the next request ID.
@@ -37,16 +65,82 @@ func (c *Client) nextID() int64 {
 	return c.requestID.Add(1)
 }
 
-// Run performs the initial MCP handshake: initialize -> initialized notification.
-func (c *Client) Run() error {
+// Run performs the initial MCP handshake and then exercises the demo server's tools, resources and prompts.
+func (c *Client) Run() (err error) {
 	defer c.transport.Close() // Ensure transport is closed when Run finishes
+	defer func() {
+		reason := "client finished"
+		if err != nil {
+			reason = err.Error()
+		}
+		c.hookShutdown(reason)
+	}()
+
+	if _, err := c.Initialize(); err != nil {
+		return err // Error already logged in Initialize
+	}
+
+	// Call Ping Tool
+	if err := c.callPingTool(); err != nil {
+		return err // Error already logged in callPingTool
+	}
+
+	// Read Random Data Resource
+	if err := c.readRandomDataResource(); err != nil {
+		return err // Error already logged in readRandomDataResource
+	}
+	// Get Sqirvy Query Prompt
+	if err := c.getSqirvyQueryPrompt(); err != nil {
+		return err // Error already logged in getSqirvyQueryPrompt
+	}
+
+	// List Tools
+	if _, err := c.listTools(); err != nil {
+		return err // Error already logged
+	}
 
+	// List Resource Templates
+	if _, err := c.listResourceTemplates(); err != nil {
+		return err // Error already logged
+	}
+
+	// List Prompts
+	if _, err := c.listPrompts(); err != nil {
+		return err // Error already logged
+	}
+
+	// List Resources
+	if _, err := c.listResources(); err != nil {
+		return err // Error already logged
+	}
+
+	// Read File Resource
+	if err := c.readFileResource("file:///documents/example.txt"); err != nil {
+		return err // Error already logged
+	}
+
+	// Summarize it with the server's summarize tool, which samples through this client
+	if c.supportsSampling() {
+		if err := c.callSummarizeTool("file:///documents/example.txt"); err != nil {
+			c.logger.Println(err)
+			return err
+		}
+	}
+
+	c.logger.Println("All client operations complete. Client will now terminate.")
+	return nil // Success
+}
+
+// Initialize performs the MCP handshake: initialize request -> response -> initialized notification.
+// It returns the server's InitializeResult.
+func (c *Client) Initialize() (*mcp.InitializeResult, error) {
 	// 1. Send Initialize Request
 	initID := c.nextID()
 	initParams := mcp.InitializeParams{
 		ProtocolVersion: protocolVersion,
 		ClientInfo: mcp.Implementation{
 			Name:    clientName,
+			Title:   clientTitle,
 			Version: clientVersion,
 		},
 		Capabilities: mcp.ClientCapabilities{
@@ -55,25 +149,38 @@ func (c *Client) Run() error {
 			// Roots: &struct { ListChanged bool `json:"listChanged,omitempty"` }{ListChanged: true},
 		},
 	}
+	if c.supportsSampling() {
+		initParams.Capabilities.Sampling = map[string]interface{}{}
+	}
+	// The server features Run uses; the server warns about any it lacks
+	if err := initParams.Capabilities.Experimental.Set(mcp.ExperimentalRequiredServerCapabilities, []string{"tools", "resources", "prompts"}); err != nil {
+		return nil, err
+	}
+	if c.hooks.OnInitialize != nil {
+		if rpcErr := c.hooks.OnInitialize(requestContext(initID), initParams); rpcErr != nil {
+			c.logger.Printf("Initialize rejected by hook: %s", mcp.FormatError("initialize", rpcErr))
+			return nil, fmt.Errorf("initialize rejected by hook: %w", rpcErr)
+		}
+	}
 
 	initRequestBytes, err := mcp.MarshalInitializeRequest(initID, initParams)
 	if err != nil {
 		c.logger.Printf("Failed to marshal initialize request: %v", err)
-		return fmt.Errorf("failed to marshal initialize request: %w", err)
+		return nil, fmt.Errorf("failed to marshal initialize request: %w", err)
 	}
 
 	c.logger.Println("Sending initialize request...")
-	if err := c.transport.WriteMessage(initRequestBytes); err != nil {
+	if err := c.sendRequest(initRequestBytes); err != nil {
 		c.logger.Printf("Failed to send initialize request: %v", err)
-		return fmt.Errorf("failed to send initialize request: %w", err)
+		return nil, fmt.Errorf("failed to send initialize request: %w", err)
 	}
 
 	// 2. Wait for Initialize Response
 	c.logger.Println("Waiting for initialize response...")
-	initResponseBytes, err := c.transport.ReadMessage()
+	initResponseBytes, err := c.readResponse(initID)
 	if err != nil {
 		c.logger.Printf("Failed to read initialize response: %v", err)
-		return fmt.Errorf("failed to read initialize response: %w", err)
+		return nil, fmt.Errorf("failed to read initialize response: %w", err)
 	}
 	c.logger.Printf("Received initialize response JSON: %s", string(initResponseBytes)) // Log the raw JSON
 
@@ -81,95 +188,65 @@ func (c *Client) Run() error {
 	initResult, respID, rpcErr, parseErr := mcp.UnmarshalInitializeResponse(initResponseBytes)
 	if parseErr != nil {
 		c.logger.Printf("Failed to parse initialize response: %v", parseErr)
-		return fmt.Errorf("failed to parse initialize response: %w", parseErr)
+		return nil, fmt.Errorf("failed to parse initialize response: %w", parseErr)
 	}
 	// Basic ID check (type might differ float64 vs int64, so compare values)
 	if fmt.Sprintf("%v", respID) != fmt.Sprintf("%v", initID) {
 		c.logger.Printf("Initialize response ID mismatch. Got: %v (%T), Want: %v (%T)", respID, respID, initID, initID)
-		return fmt.Errorf("initialize response ID mismatch. Got: %v, Want: %v", respID, initID)
+		return nil, fmt.Errorf("initialize response ID mismatch. Got: %v, Want: %v", respID, initID)
 	}
 	if rpcErr != nil {
-		c.logger.Printf("Received RPC error in initialize response: Code=%d, Message=%s, Data=%v", rpcErr.Code, rpcErr.Message, rpcErr.Data)
-		return fmt.Errorf("received RPC error in initialize response: %w", rpcErr)
+		c.logger.Printf("Received RPC error in initialize response: %s", mcp.FormatError("initialize", rpcErr))
+		return nil, fmt.Errorf("received RPC error in initialize response: %w", rpcErr)
 	}
 	if initResult == nil {
 		c.logger.Println("Initialize response contained no result.")
-		return fmt.Errorf("initialize response contained no result")
+		return nil, fmt.Errorf("initialize response contained no result")
 	}
 
 	c.logger.Printf("Server initialized successfully. ProtocolVersion: %s", initResult.ProtocolVersion)
-	c.logger.Printf("Server Info: Name=%s, Version=%s", initResult.ServerInfo.Name, initResult.ServerInfo.Version)
+	c.mu.Lock()
+	c.serverCaps = initResult.Capabilities
+	c.mu.Unlock()
+	c.logger.Printf("Server Info: %s, Version=%s", label(initResult.ServerInfo.DisplayName(), initResult.ServerInfo.Name), initResult.ServerInfo.Version)
 	// Log capabilities (consider pretty printing if complex)
 	capsBytes, _ := json.MarshalIndent(initResult.Capabilities, "", "  ")
 	c.logger.Printf("Server Capabilities:\n%s", string(capsBytes))
+	for _, w := range initResult.CapabilityWarnings() {
+		c.logger.Printf("WARNING: server capability downgrade: %s", w)
+	}
 
 	// 4. Send Initialized Notification
 	// Notifications have no ID.
-	initializedNotification := mcp.RPCRequest{
-		JSONRPC: mcp.JSONRPCVersion,
-		Method:  notificationInitialized,
-		Params:  map[string]interface{}{}, // Empty params object as per spec
-		// ID field is omitted for notifications
-	}
-
-	initializedBytes, err := json.Marshal(initializedNotification)
+	// Empty params object as per spec, unless -empty-params asks otherwise
+	initializedBytes, err := mcp.MarshalNotification(mcp.MethodInitialized, map[string]interface{}{})
 	if err != nil {
 		c.logger.Printf("Failed to marshal initialized notification: %v", err)
-		return fmt.Errorf("failed to marshal initialized notification: %w", err)
+		return nil, fmt.Errorf("failed to marshal initialized notification: %w", err)
 	}
 
 	c.logger.Println("Sending initialized notification...")
 	if err := c.transport.WriteMessage(initializedBytes); err != nil {
 		c.logger.Printf("Failed to send initialized notification: %v", err)
-		return fmt.Errorf("failed to send initialized notification: %w", err)
+		return nil, fmt.Errorf("failed to send initialized notification: %w", err)
 	}
 	c.logger.Println("MCP handshake complete.")
-
-	// Call Ping Tool
-	if err := c.callPingTool(); err != nil {
-		return err // Error already logged in callPingTool
-	}
-
-	// Read Random Data Resource
-	if err := c.readRandomDataResource(); err != nil {
-		return err // Error already logged in readRandomDataResource
-	}
-	// Get Sqirvy Query Prompt
-	if err := c.getSqirvyQueryPrompt(); err != nil {
-		return err // Error already logged in getSqirvyQueryPrompt
-	}
-
-	// List Tools
-	if err := c.listTools(); err != nil {
-		return err // Error already logged
-	}
-
-	// List Resource Templates
-	if err := c.listResourceTemplates(); err != nil {
-		return err // Error already logged
-	}
-
-	// List Prompts
-	if err := c.listPrompts(); err != nil {
-		return err // Error already logged
+	if c.hooks.OnInitialized != nil {
+		c.hooks.OnInitialized(context.Background())
 	}
+	return initResult, nil
+}
 
-	// List Resources
-	if err := c.listResources(); err != nil {
-		return err // Error already logged
-	}
+// --- Helper Functions for MCP Calls ---
 
-	// Read File Resource
-	if err := c.readFileResource("file:///documents/example.txt"); err != nil {
-		return err // Error already logged
+// logTiming logs server-reported latency for a call, if the server included it.
+func (c *Client) logTiming(call string, timing *mcp.Timing) {
+	if timing == nil {
+		return
 	}
-
-	c.logger.Println("All client operations complete. Client will now terminate.")
-	return nil // Success
+	c.logger.Printf("Server timing for %s: queue=%v execution=%v", call, timing.Queue(), timing.Execution())
 }
 
-// --- Helper Functions for MCP Calls ---
-
 // callPingTool sends a tools/call request for the 'ping' tool and processes the response.
 func (c *Client) callPingTool() error {
 	pingID := c.nextID()
@@ -184,13 +261,13 @@ func (c *Client) callPingTool() error {
 	}
 
 	c.logger.Println("Sending ping tool request...")
-	if err := c.transport.WriteMessage(pingRequestBytes); err != nil {
+	if err := c.sendRequest(pingRequestBytes); err != nil {
 		c.logger.Printf("Failed to send ping request: %v", err)
 		return fmt.Errorf("failed to send ping request: %w", err)
 	}
 
 	c.logger.Println("Waiting for ping response...")
-	pingResponseBytes, err := c.transport.ReadMessage()
+	pingResponseBytes, err := c.readResponse(pingID)
 	if err != nil {
 		c.logger.Printf("Failed to read ping response: %v", err)
 		return fmt.Errorf("failed to read ping response: %w", err)
@@ -207,13 +284,15 @@ func (c *Client) callPingTool() error {
 		return fmt.Errorf("ping response ID mismatch. Got: %v, Want: %v", pingRespID, pingID)
 	}
 	if pingRPCErr != nil {
-		c.logger.Printf("Received RPC error in ping response: Code=%d, Message=%s, Data=%v", pingRPCErr.Code, pingRPCErr.Message, pingRPCErr.Data)
+		c.logger.Printf("Received RPC error in ping response: %s", mcp.FormatError("Tool 'ping'", pingRPCErr))
 		return fmt.Errorf("received RPC error in ping response: %w", pingRPCErr)
 	}
 	if pingResult == nil {
 		c.logger.Println("Ping response contained no result.")
 		return fmt.Errorf("ping response contained no result")
 	}
+	c.logTiming("ping tool", pingResult.Timing())
+	c.expandResourceLinks(pingResult)
 
 	if len(pingResult.Content) > 0 {
 		var textContent mcp.TextContent
@@ -247,13 +326,13 @@ func (c *Client) readRandomDataResource() error {
 	}
 
 	c.logger.Printf("Sending read resource request for URI: %s", readParams.URI)
-	if err := c.transport.WriteMessage(readRequestBytes); err != nil {
+	if err := c.sendRequest(readRequestBytes); err != nil {
 		c.logger.Printf("Failed to send read resource request: %v", err)
 		return fmt.Errorf("failed to send read resource request: %w", err)
 	}
 
 	c.logger.Println("Waiting for read resource response...")
-	readResponseBytes, err := c.transport.ReadMessage()
+	readResponseBytes, err := c.readResponse(readID)
 	if err != nil {
 		c.logger.Printf("Failed to read resource response: %v", err)
 		return fmt.Errorf("failed to read resource response: %w", err)
@@ -270,13 +349,14 @@ func (c *Client) readRandomDataResource() error {
 		return fmt.Errorf("read resource response ID mismatch. Got: %v, Want: %v", readRespID, readID)
 	}
 	if readRPCErr != nil {
-		c.logger.Printf("Received RPC error in read resource response: Code=%d, Message=%s, Data=%v", readRPCErr.Code, readRPCErr.Message, readRPCErr.Data)
+		c.logger.Printf("Received RPC error in read resource response: %s", mcp.FormatError("Resource '"+readParams.URI+"'", readRPCErr))
 		return fmt.Errorf("received RPC error in read resource response: %w", readRPCErr)
 	}
 	if readResult == nil {
 		c.logger.Println("Read resource response contained no result.")
 		return fmt.Errorf("read resource response contained no result")
 	}
+	c.logTiming("read resource", readResult.Timing())
 
 	if len(readResult.Contents) > 0 {
 		var textContent mcp.TextResourceContents
@@ -309,13 +389,13 @@ func (c *Client) readFileResource(fileURI string) error {
 	}
 
 	c.logger.Printf("Sending read resource request for URI: %s", readParams.URI)
-	if err := c.transport.WriteMessage(readRequestBytes); err != nil {
+	if err := c.sendRequest(readRequestBytes); err != nil {
 		c.logger.Printf("Failed to send read file resource request: %v", err)
 		return fmt.Errorf("failed to send read file resource request: %w", err)
 	}
 
 	c.logger.Println("Waiting for read file resource response...")
-	readResponseBytes, err := c.transport.ReadMessage()
+	readResponseBytes, err := c.readResponse(readID)
 	if err != nil {
 		c.logger.Printf("Failed to read file resource response: %v", err)
 		return fmt.Errorf("failed to read file resource response: %w", err)
@@ -333,7 +413,7 @@ func (c *Client) readFileResource(fileURI string) error {
 	}
 	if readRPCErr != nil {
 		// Log the specific RPC error received from the server
-		c.logger.Printf("Received RPC error in read file resource response: Code=%d, Message=%s, Data=%v", readRPCErr.Code, readRPCErr.Message, readRPCErr.Data)
+		c.logger.Printf("Received RPC error in read file resource response: %s", mcp.FormatError("Resource '"+readParams.URI+"'", readRPCErr))
 		// Check if the error indicates the file wasn't found (using InvalidParams code as per server logic)
 		if readRPCErr.Code == mcp.ErrorCodeInvalidParams && strings.Contains(readRPCErr.Message, "not found") {
 			c.logger.Printf("Server reported file not found for URI: %s", fileURI)
@@ -348,6 +428,7 @@ func (c *Client) readFileResource(fileURI string) error {
 		c.logger.Println("Read file resource response contained no result.")
 		return fmt.Errorf("read file resource response contained no result")
 	}
+	c.logTiming("read file resource", readResult.Timing())
 
 	if len(readResult.Contents) > 0 {
 		// Attempt to unmarshal as TextResourceContents first
@@ -386,13 +467,13 @@ func (c *Client) getSqirvyQueryPrompt() error {
 	}
 
 	c.logger.Printf("Sending get prompt request for prompt: %s", promptParams.Name)
-	if err := c.transport.WriteMessage(promptRequestBytes); err != nil {
+	if err := c.sendRequest(promptRequestBytes); err != nil {
 		c.logger.Printf("Failed to send get prompt request: %v", err)
 		return fmt.Errorf("failed to send get prompt request: %w", err)
 	}
 
 	c.logger.Println("Waiting for get prompt response...")
-	promptResponseBytes, err := c.transport.ReadMessage()
+	promptResponseBytes, err := c.readResponse(promptID)
 	if err != nil {
 		c.logger.Printf("Failed to read prompt response: %v", err)
 		return fmt.Errorf("failed to read prompt response: %w", err)
@@ -409,7 +490,7 @@ func (c *Client) getSqirvyQueryPrompt() error {
 		return fmt.Errorf("get prompt response ID mismatch. Got: %v, Want: %v", promptRespID, promptID)
 	}
 	if promptRPCErr != nil {
-		c.logger.Printf("Received RPC error in get prompt response: Code=%d, Message=%s, Data=%v", promptRPCErr.Code, promptRPCErr.Message, promptRPCErr.Data)
+		c.logger.Printf("Received RPC error in get prompt response: %s", mcp.FormatError("Prompt '"+promptParams.Name+"'", promptRPCErr))
 		return fmt.Errorf("received RPC error in get prompt response: %w", promptRPCErr)
 	}
 	if promptResult == nil {
@@ -417,13 +498,14 @@ func (c *Client) getSqirvyQueryPrompt() error {
 		return fmt.Errorf("get prompt response contained no result")
 	}
 
-	if len(promptResult.Messages) > 0 {
+	// Content meant only for the model is not shown
+	if messages := mcp.FilterPromptMessages(promptResult.Messages, mcp.RoleUser); len(messages) > 0 {
 		var textContent mcp.TextContent
-		if err := json.Unmarshal(promptResult.Messages[0].Content, &textContent); err != nil {
+		if err := json.Unmarshal(messages[0].Content, &textContent); err != nil {
 			c.logger.Printf("Failed to unmarshal prompt message content into TextContent: %v", err)
-			c.logger.Printf("Raw prompt message content[0]: %s", string(promptResult.Messages[0].Content))
+			c.logger.Printf("Raw prompt message content[0]: %s", string(messages[0].Content))
 		} else {
-			c.logger.Printf("Prompt '%s' (Role: %s) content:\n%s", promptParams.Name, promptResult.Messages[0].Role, textContent.Text)
+			c.logger.Printf("Prompt '%s' (Role: %s) content:\n%s", promptParams.Name, messages[0].Role, textContent.Text)
 		}
 	} else {
 		c.logger.Println("Get prompt response result contained no messages.")
@@ -0,0 +1,251 @@
+package client
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+
+	// Use the absolute module path based on go.mod
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/transport"
+)
+
+// Main runs the mcp-client command with the process's arguments and flags.
+// It exits the process on errors.
+func Main() {
+	// --- Command Line Flags ---
+	// Default path assumes 'mcp-client' is run from the repository root.
+	serverPath := flag.String("server-path", "bin/mcp-server", "Path to the mcp-server executable")
+	serverLog := flag.String("server-log", "mcp-server-from-client.log", "Log file for the server subprocess")
+	serverURL := flag.String("url", "", "Connect to a remote server over Streamable HTTP instead of spawning one, e.g. https://example.com/mcp")
+	oauth := flag.Bool("oauth", false, "Authorize with the -url server using OAuth 2.1 when it asks for it")
+	oauthRedirect := flag.String("oauth-redirect", "http://127.0.0.1:8976/callback", "Loopback redirect URL for -oauth")
+	oauthHeadless := flag.Bool("oauth-headless", false, "Print the -oauth authorization URL and read the redirect URL from stdin instead of opening a browser")
+	oauthClientID := flag.String("oauth-client-id", "", "Pre-registered OAuth client ID; by default the client registers itself")
+	oauthScopes := flag.String("oauth-scopes", "", "Space-separated OAuth scopes to request")
+	oauthStore := flag.String("oauth-store", defaultOAuthStore(), "File that keeps OAuth clients and tokens between runs")
+	var headers headerFlags
+	flag.Var(&headers, "header", "Extra header for -url requests, e.g. \"X-Api-Key: secret\" (repeatable)")
+	proxyURL := flag.String("proxy", "", "Proxy for -url, e.g. http://proxy:3128; default uses HTTP_PROXY/HTTPS_PROXY/NO_PROXY")
+	caFile := flag.String("ca-file", "", "PEM bundle of extra certificate authorities to trust for -url")
+	insecureSkipVerify := flag.Bool("insecure-skip-verify", false, "Do not verify the -url server's TLS certificate (INSECURE, testing only)")
+	transportName := flag.String("transport", "stdio", "How to reach the server: stdio (spawn -server-path), tcp (connect to -addr, like -connect tcp:<addr>), http (Streamable HTTP at http://<addr>/mcp, like -url) or grpc (the MCPTransport gRPC service on -addr)")
+	addr := flag.String("addr", "localhost:8080", "Server address of -transport=tcp, http or grpc")
+	connectAddr := flag.String("connect", "", "Connect to a server socket instead of spawning one, e.g. unix:/tmp/mcp.sock or tcp:localhost:9000")
+	framingName := flag.String("framing", "newline", "Message framing for -connect and the stdio server: newline, length, or content-length (LSP-style headers)")
+	hmacSecretFile := flag.String("hmac-secret-file", "", "Sign and verify every -connect message with HMAC-SHA256 using the shared secret in this file")
+	sampling := flag.Bool("sampling", false, "Answer the server's sampling/createMessage requests with the Anthropic API (needs ANTHROPIC_API_KEY) and try the summarize tool")
+	samplingModel := flag.String("sampling-model", "claude-3-5-haiku-latest", "Anthropic model used for -sampling")
+	resolveLinks := flag.Bool("resolve-links", false, "Replace the resource links in tool results with the linked contents, read with resources/read")
+	chaosSpec := flag.String("chaos", "", "Inject transport faults for testing, e.g. latency=20ms,jitter=10ms,dup=0.05,reorder=0.2,disconnect=0.001,seed=1")
+	outputName := flag.String("output", "", "Instead of the demo calls, list what the server offers and print a report: json, table or md. The exit status is 0 if the server is healthy, 1 if the handshake failed and 2 if a list failed")
+	emptyParamsName := flag.String("empty-params", "default", "How to send requests and notifications without params, for picky servers: omit the member, or object for \"params\":{}")
+	flag.Parse()
+
+	// --- Logger Setup ---
+	// Log directly to stdout for the client, or to stderr when stdout carries the -output report
+	output, err := parseOutputFormat(*outputName)
+	logOutput := os.Stdout
+	if output != outputNone {
+		logOutput = os.Stderr
+	}
+	logger := log.New(logOutput, "MCP-CLIENT: ", log.LstdFlags|log.Lshortfile)
+	if err != nil {
+		logger.Fatalf("Invalid -output value: %v", err)
+	}
+	logger.Println("--------------------------------------------------")
+	logger.Println("MCP Client starting...")
+	logger.Printf("Server executable: %s", *serverPath)
+	logger.Printf("Server log file: %s", *serverLog)
+
+	// -transport=tcp and http are -connect and -url by other names, matching the server's flags
+	var grpcAddr string
+	switch *transportName {
+	case "stdio":
+	case "tcp":
+		if *connectAddr == "" {
+			*connectAddr = "tcp:" + *addr
+		}
+	case "http":
+		if *serverURL == "" {
+			*serverURL = "http://" + *addr + "/mcp"
+		}
+	case "grpc":
+		grpcAddr = *addr
+	default:
+		logger.Fatalf("Invalid -transport value: %q (want stdio, tcp, http or grpc)", *transportName)
+	}
+
+	emptyParams, err := mcp.ParseEmptyParams(*emptyParamsName)
+	if err != nil {
+		logger.Fatalf("Invalid -empty-params value: %v", err)
+	}
+
+	// --- Initialize Transport ---
+	framing, err := transport.ParseFraming(*framingName)
+	if err != nil {
+		logger.Fatalf("Invalid -framing value: %v", err)
+	}
+	var clientTransport transport.Transport
+	if grpcAddr != "" {
+		logger.Printf("Connecting to %s over gRPC...", grpcAddr)
+		if clientTransport, err = dialGRPC(grpcAddr); err != nil {
+			logger.Fatalf("Failed to connect to %s: %v", grpcAddr, err)
+		}
+	} else if *serverURL != "" {
+		logger.Printf("Connecting to %s...", *serverURL)
+		if *insecureSkipVerify {
+			logger.Println("WARNING: -insecure-skip-verify is set. TLS certificates are NOT verified; anyone on the network path can read and alter this session.")
+		}
+		httpClient, err := transport.NewHTTPClient(transport.NetworkConfig{ProxyURL: *proxyURL, CAFile: *caFile, InsecureSkipVerify: *insecureSkipVerify})
+		if err != nil {
+			logger.Fatalf("Invalid network options: %v", err)
+		}
+		opts := transport.HTTPOptions{Client: httpClient, Header: headers.header}
+		if *oauth {
+			consent := transport.BrowserConsent(*oauthRedirect, os.Stderr)
+			if *oauthHeadless {
+				consent = transport.HeadlessConsent(os.Stdin, os.Stderr)
+			}
+			authorizer, err := transport.NewOAuth(*serverURL, httpClient.Transport, transport.OAuthConfig{
+				Consent:     consent,
+				RedirectURL: *oauthRedirect,
+				ClientName:  "mcp-client",
+				ClientID:    *oauthClientID,
+				Scopes:      strings.Fields(*oauthScopes),
+				Store:       &transport.FileCredentialStore{Path: *oauthStore},
+				Client:      httpClient,
+			})
+			if err != nil {
+				logger.Fatalf("Failed to set up OAuth: %v", err)
+			}
+			opts.Client = &http.Client{Transport: authorizer}
+			logger.Printf("OAuth enabled; credentials are kept in %s", *oauthStore)
+		}
+		httpTransport, err := transport.NewHTTP(*serverURL, opts)
+		if err != nil {
+			logger.Fatalf("Invalid -url value: %v", err)
+		}
+		clientTransport = httpTransport
+	} else if *connectAddr != "" {
+		logger.Printf("Connecting to %s...", *connectAddr)
+		network, address, err := transport.ParseAddress(*connectAddr)
+		if err != nil {
+			logger.Fatalf("Invalid -connect value: %v", err)
+		}
+		if clientTransport, err = transport.Dial(network, address, framing); err != nil {
+			logger.Fatalf("Failed to connect to %s: %v", *connectAddr, err)
+		}
+		if *hmacSecretFile != "" {
+			secret, err := transport.ReadSecretFile(*hmacSecretFile)
+			if err != nil {
+				clientTransport.Close()
+				logger.Fatalf("Invalid -hmac-secret-file: %v", err)
+			}
+			logger.Printf("Signing messages with the secret in %s", *hmacSecretFile)
+			clientTransport = transport.NewSigned(clientTransport, secret, transport.SignedClient)
+		}
+	} else {
+		logger.Println("Initializing stdio transport...")
+		stdio, err := NewStdioTransport(*serverPath, *serverLog, framing, logger)
+		if err != nil {
+			logger.Fatalf("Failed to initialize transport: %v", err)
+		}
+		clientTransport = stdio
+	}
+	// Transport closing is handled by client.Run() via defer
+	if *chaosSpec != "" {
+		chaosConfig, err := transport.ParseChaosConfig(*chaosSpec)
+		if err != nil {
+			clientTransport.Close()
+			logger.Fatalf("Invalid -chaos value: %v", err)
+		}
+		logger.Printf("Chaos transport enabled: %+v", chaosConfig)
+		clientTransport = transport.NewChaos(clientTransport, chaosConfig)
+	}
+	if emptyParams != mcp.EmptyParamsDefault {
+		clientTransport = transport.NewEmptyParamsWriter(clientTransport, emptyParams)
+	}
+
+	// --- Initialize and Run Client ---
+	logger.Println("Creating MCP client...")
+	client := NewClient(clientTransport, logger)
+	if *resolveLinks {
+		client.EnableLinkResolution()
+	}
+	if *sampling {
+		if os.Getenv("ANTHROPIC_API_KEY") == "" {
+			clientTransport.Close()
+			logger.Fatalf("-sampling needs the ANTHROPIC_API_KEY environment variable")
+		}
+		anthropicClient := anthropic.NewClient()
+		client.EnableSampling(AnthropicSampler(&anthropicClient, *samplingModel))
+		logger.Printf("Sampling enabled with model %s", *samplingModel)
+	}
+
+	if output != outputNone {
+		report := client.Inspect()
+		if err := writeReport(os.Stdout, report, output); err != nil {
+			logger.Fatalf("Failed to write report: %v", err)
+		}
+		os.Exit(report.exitCode())
+	}
+
+	logger.Println("Running client handshake...")
+	if err := client.Run(); err != nil {
+		logger.Printf("Client run failed: %v", err)
+		logger.Println("--------------------------------------------------")
+		// Attempt to close transport even on error, logging any further issues
+		if closeErr := clientTransport.Close(); closeErr != nil {
+			logger.Printf("Error closing transport after client failure: %v", closeErr)
+		}
+		os.Exit(1) // Exit with error status
+	}
+
+	// --- Shutdown ---
+	logger.Println("Client finished successfully.")
+	logger.Println("--------------------------------------------------")
+	// Transport is closed via defer in client.Run()
+	// No explicit exit needed here, main will return 0
+}
+
+// defaultOAuthStore returns the default -oauth-store path in the user's config directory.
+func defaultOAuthStore() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "."
+	}
+	return filepath.Join(dir, "mcp-client", "oauth.json")
+}
+
+// headerFlags collects repeated -header flags.
+type headerFlags struct {
+	header http.Header
+}
+
+func (h *headerFlags) String() string {
+	var lines []string
+	for name, values := range h.header {
+		for _, v := range values {
+			lines = append(lines, name+": "+v)
+		}
+	}
+	return strings.Join(lines, ", ")
+}
+
+func (h *headerFlags) Set(line string) error {
+	name, value, err := transport.ParseHeader(line)
+	if err != nil {
+		return err
+	}
+	if h.header == nil {
+		h.header = http.Header{}
+	}
+	h.header.Add(name, value)
+	return nil
+}
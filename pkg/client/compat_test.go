@@ -0,0 +1,252 @@
+//go:build compat
+
+// Compatibility tests against the official reference MCP servers.
+// They launch the servers with npx/uvx, which need network access the first time, so they only
+// build with the "compat" tag:
+//
+//	go test -tags compat -v ./pkg/client -run TestReferenceServers
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"sqirvy/mcp/pkg/mcp"
+)
+
+// referenceServer describes how to launch one reference implementation.
+type referenceServer struct {
+	name    string
+	command string
+	args    []string
+}
+
+var referenceServers = []referenceServer{
+	{name: "server-everything", command: "npx", args: []string{"-y", "@modelcontextprotocol/server-everything"}},
+	{name: "server-fetch", command: "uvx", args: []string{"mcp-server-fetch"}},
+}
+
+// compatTimeout bounds each reference server session, including package download time.
+const compatTimeout = 3 * time.Minute
+
+func TestReferenceServers(t *testing.T) {
+	for _, rs := range referenceServers {
+		t.Run(rs.name, func(t *testing.T) {
+			if _, err := exec.LookPath(rs.command); err != nil {
+				t.Skipf("%s not found in PATH, skipping %s", rs.command, rs.name)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), compatTimeout)
+			defer cancel()
+
+			logger := log.New(os.Stderr, "COMPAT "+rs.name+": ", log.Lmicroseconds)
+			cmd := exec.CommandContext(ctx, rs.command, rs.args...)
+			cmd.Stderr = os.Stderr // Surface npx/uvx download or startup failures
+			transport, err := newStdioTransportCmd(cmd, logger)
+			if err != nil {
+				t.Fatalf("failed to start %s: %v", rs.name, err)
+			}
+			defer transport.Close()
+
+			client := NewClient(transport, logger)
+			initResult, err := client.Initialize()
+			if err != nil {
+				t.Fatalf("initialize handshake failed: %v", err)
+			}
+			if initResult.ServerInfo.Name == "" || initResult.ProtocolVersion == "" {
+				t.Errorf("incomplete InitializeResult: %+v", initResult)
+			}
+
+			s := &compatSession{t: t, client: client}
+			caps := initResult.Capabilities
+			if caps.Tools != nil {
+				s.checkTools()
+			}
+			if caps.Resources != nil {
+				s.checkResources()
+			}
+			if caps.Prompts != nil {
+				s.checkPrompts()
+			}
+			t.Logf("%s: %d notifications received during the session", rs.name, s.notifications)
+		})
+	}
+}
+
+// compatSession drives a reference server at the message level, tolerating the notifications
+// real servers interleave with responses.
+type compatSession struct {
+	t             *testing.T
+	client        *Client
+	notifications int
+}
+
+// request marshals a request with a fresh id, sends it and returns the raw response with the matching id,
+// counting notifications and answering server-initiated pings on the way.
+func (s *compatSession) request(marshal func(id int64) ([]byte, error)) []byte {
+	s.t.Helper()
+	id := s.client.nextID()
+	payload, err := marshal(id)
+	if err != nil {
+		s.t.Fatalf("failed to marshal request %d: %v", id, err)
+	}
+	if err := s.client.transport.WriteMessage(payload); err != nil {
+		s.t.Fatalf("failed to send request %d: %v", id, err)
+	}
+	for {
+		msg, err := s.client.transport.ReadMessage()
+		if err != nil {
+			s.t.Fatalf("failed to read response %d: %v", id, err)
+		}
+		info, err := mcp.ClassifyMessage(msg)
+		if err != nil {
+			s.t.Fatalf("server sent an invalid message: %s (%v)", msg, err)
+		}
+		switch info.Kind {
+		case mcp.KindNotification:
+			s.notifications++
+		case mcp.KindRequest:
+			// Servers may ping the client at any time; answer with an empty result
+			response, _ := json.Marshal(mcp.RPCResponse{JSONRPC: mcp.JSONRPCVersion, Result: json.RawMessage(`{}`), ID: info.ID})
+			if info.Method != mcp.MethodPing {
+				response, _ = mcp.MarshalErrorResponse(info.ID, mcp.NewRPCError(mcp.ErrorCodeMethodNotFound, "not supported by compat test", nil))
+			}
+			if err := s.client.transport.WriteMessage(response); err != nil {
+				s.t.Fatalf("failed to answer server request %v: %v", info.ID, err)
+			}
+		default:
+			if fmt.Sprintf("%v", info.ID) != fmt.Sprintf("%v", id) {
+				s.t.Fatalf("response ID mismatch. Got: %v, Want: %v", info.ID, id)
+			}
+			return msg
+		}
+	}
+}
+
+// maxPages guards against servers that never stop returning a cursor.
+const maxPages = 100
+
+func (s *compatSession) checkTools() {
+	var tools []mcp.Tool
+	cursor := ""
+	for page := 0; page < maxPages; page++ {
+		result, _, rpcErr, err := mcp.UnmarshalListToolsResponse(s.request(func(id int64) ([]byte, error) {
+			return mcp.MarshalListToolsRequest(id, &mcp.ListToolsParams{Cursor: cursor})
+		}))
+		if err != nil || rpcErr != nil {
+			s.t.Fatalf("tools/list failed: err=%v rpcErr=%v", err, rpcErr)
+		}
+		tools = append(tools, result.Tools...)
+		if cursor = result.NextCursor; cursor == "" {
+			break
+		}
+	}
+	if len(tools) == 0 {
+		s.t.Errorf("server advertises tools but tools/list returned none")
+	}
+	for _, tool := range tools {
+		if tool.Name == "" || tool.InputSchema["type"] != "object" {
+			s.t.Errorf("tool with invalid name or schema: %+v", tool)
+		}
+		if tool.Name == "echo" {
+			s.callEcho()
+		}
+	}
+}
+
+func (s *compatSession) callEcho() {
+	params := mcp.CallToolParams{Name: "echo", Arguments: map[string]interface{}{"message": "compat"}}
+	result, _, rpcErr, err := mcp.UnmarshalCallToolResponse(s.request(func(id int64) ([]byte, error) {
+		return mcp.MarshalCallToolRequest(id, params)
+	}))
+	if err != nil || rpcErr != nil {
+		s.t.Fatalf("tools/call echo failed: err=%v rpcErr=%v", err, rpcErr)
+	}
+	if result.IsError || len(result.Content) == 0 {
+		s.t.Fatalf("echo returned an error or no content: %+v", result)
+	}
+	var text mcp.TextContent
+	if err := json.Unmarshal(result.Content[0], &text); err != nil || text.Type != "text" {
+		s.t.Errorf("echo content is not TextContent: %s", result.Content[0])
+	}
+}
+
+func (s *compatSession) checkResources() {
+	var resources []mcp.Resource
+	cursor := ""
+	pages := 0
+	for ; pages < maxPages; pages++ {
+		result, _, rpcErr, err := mcp.UnmarshalListResourcesResponse(s.request(func(id int64) ([]byte, error) {
+			return mcp.MarshalListResourcesRequest(id, &mcp.ListResourcesParams{Cursor: cursor})
+		}))
+		if err != nil || rpcErr != nil {
+			s.t.Fatalf("resources/list failed: err=%v rpcErr=%v", err, rpcErr)
+		}
+		resources = append(resources, result.Resources...)
+		if cursor = result.NextCursor; cursor == "" {
+			break
+		}
+	}
+	s.t.Logf("resources/list: %d resources over %d page(s)", len(resources), pages+1)
+
+	if len(resources) > 0 {
+		uri := resources[0].URI
+		result, _, rpcErr, err := mcp.UnmarshalReadResourcesResponse(s.request(func(id int64) ([]byte, error) {
+			return mcp.MarshalReadResourcesRequest(id, mcp.ReadResourceParams{URI: uri})
+		}))
+		if err != nil || rpcErr != nil {
+			s.t.Fatalf("resources/read %s failed: err=%v rpcErr=%v", uri, err, rpcErr)
+		}
+		for _, raw := range result.Contents {
+			var contents struct {
+				URI  string  `json:"uri"`
+				Text *string `json:"text"`
+				Blob *string `json:"blob"`
+			}
+			if err := json.Unmarshal(raw, &contents); err != nil || (contents.Text == nil && contents.Blob == nil) {
+				s.t.Errorf("resource contents are neither text nor blob: %s", raw)
+			}
+		}
+	}
+
+	templates, _, rpcErr, err := mcp.UnmarshalListResourceTemplatesResponse(s.request(func(id int64) ([]byte, error) {
+		return mcp.MarshalListResourceTemplatesRequest(id, nil)
+	}))
+	if err != nil {
+		s.t.Fatalf("resources/templates/list response could not be parsed: %v", err)
+	}
+	if rpcErr == nil {
+		s.t.Logf("resources/templates/list: %d templates", len(templates.ResourceTemplates))
+	}
+}
+
+func (s *compatSession) checkPrompts() {
+	result, _, rpcErr, err := mcp.UnmarshalListPromptsResponse(s.request(func(id int64) ([]byte, error) {
+		return mcp.MarshalListPromptsRequest(id, nil)
+	}))
+	if err != nil || rpcErr != nil {
+		s.t.Fatalf("prompts/list failed: err=%v rpcErr=%v", err, rpcErr)
+	}
+	for _, prompt := range result.Prompts {
+		if len(prompt.Arguments) > 0 {
+			continue // Only fetch prompts that can be rendered without arguments
+		}
+		got, _, rpcErr, err := mcp.UnmarshalGetPromptResponse(s.request(func(id int64) ([]byte, error) {
+			return mcp.MarshalGetPromptRequest(id, mcp.GetPromptParams{Name: prompt.Name})
+		}))
+		if err != nil || rpcErr != nil {
+			s.t.Fatalf("prompts/get %s failed: err=%v rpcErr=%v", prompt.Name, err, rpcErr)
+		}
+		for _, msg := range got.Messages {
+			if msg.Role != mcp.RoleUser && msg.Role != mcp.RoleAssistant {
+				s.t.Errorf("prompt %s has message with unknown role %q", prompt.Name, msg.Role)
+			}
+		}
+	}
+}
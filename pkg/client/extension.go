@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"sqirvy/mcp/pkg/mcp"
+)
+
+// CallRaw sends a request for a non-standard method, named x-<vendor>/<method>
+// (see mcp.ValidateExtensionMethod), and returns the raw result. params is
+// marshalled as given; nil sends no params. An error response is returned as
+// an *mcp.RPCError.
+//
+// Like the client's other calls, CallRaw waits for the response while answering
+// server requests and passing on notifications; ctx is checked before the
+// request is sent. A method the server did not advertise under
+// capabilities.experimental.methods is still sent, with a warning, since not
+// every server lists its extensions.
+func (c *Client) CallRaw(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	if err := mcp.ValidateExtensionMethod(method); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if !c.advertises(method) {
+		c.logger.Printf("WARNING: server did not advertise extension method %s", method)
+	}
+
+	id := c.nextID()
+	request, err := mcp.MarshalRequest(id, method, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s request: %w", method, err)
+	}
+	if err := c.sendRequest(request); err != nil {
+		return nil, fmt.Errorf("failed to send %s request: %w", method, err)
+	}
+	payload, err := c.readResponse(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s response: %w", method, err)
+	}
+	var response mcp.RPCResponse
+	if err := json.Unmarshal(payload, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse %s response: %w", method, err)
+	}
+	if response.Error != nil {
+		return nil, response.Error
+	}
+	return response.Result, nil
+}
+
+// advertises reports whether the server listed method among its extension
+// methods during initialize.
+func (c *Client) advertises(method string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, m := range c.serverCaps.ExtensionMethods() {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+
+	"sqirvy/mcp/pkg/client"
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/mcptest"
+	"sqirvy/mcp/pkg/transport"
+)
+
+func TestCallRaw(t *testing.T) {
+	srv := mcptest.NewServer(t)
+	srv.ExpectInitialize()
+	srv.Expect("x-test/echo").Respond(map[string]bool{"ok": true})
+	srv.Expect("x-test/fail").RespondError(mcp.NewRPCError(mcp.ErrorCodeInvalidParams, "bad", nil))
+
+	conn := srv.Conn()
+	c := client.NewClient(transport.NewStream(conn, conn), log.New(io.Discard, "", 0))
+	if _, err := c.Initialize(); err != nil {
+		t.Fatalf("initialize failed: %v", err)
+	}
+
+	result, err := c.CallRaw(context.Background(), "x-test/echo", map[string]int{"n": 1})
+	if err != nil || string(result) != `{"ok":true}` {
+		t.Errorf("CallRaw = %s, %v", result, err)
+	}
+	var rpcErr *mcp.RPCError
+	if _, err := c.CallRaw(context.Background(), "x-test/fail", nil); !errors.As(err, &rpcErr) || rpcErr.Message != "bad" {
+		t.Errorf("CallRaw of failing method = %v, want the RPC error", err)
+	}
+
+	// Neither is sent
+	if _, err := c.CallRaw(context.Background(), mcp.MethodListTools, nil); err == nil {
+		t.Error("CallRaw accepted a standard method")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.CallRaw(ctx, "x-test/echo", nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("CallRaw with canceled context = %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"sqirvy/mcp/pkg/transport"
+)
+
+// dialGRPC opens a Connect stream of the MCPTransport gRPC service (see
+// pkg/transport/proto/transport.proto) on the server at addr, as served by
+// mcp-server -transport=grpc. The connection is not encrypted. Closing the
+// transport ends the stream and the connection.
+func dialGRPC(addr string) (transport.Transport, error) {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	desc := &grpc.StreamDesc{StreamName: "Connect", ClientStreams: true, ServerStreams: true}
+	stream, err := conn.NewStream(ctx, desc, transport.GRPCConnectMethod, grpc.ForceCodec(transport.FrameCodec{}))
+	if err != nil {
+		cancel()
+		conn.Close()
+		return nil, fmt.Errorf("failed to open a gRPC stream to %s: %w", addr, err)
+	}
+	return transport.NewMessageStream(stream, func() error {
+		err := stream.CloseSend()
+		cancel()
+		return errors.Join(err, conn.Close())
+	}), nil
+}
@@ -0,0 +1,67 @@
+package client
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"sqirvy/mcp/pkg/transport"
+)
+
+func TestDialGRPC(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A Connect handler that echoes every frame until the client closes its side
+	ended := make(chan error, 1)
+	srv := grpc.NewServer(grpc.ForceServerCodec(transport.FrameCodec{}))
+	srv.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "mcp.transport.v1.MCPTransport",
+		HandlerType: (*interface{})(nil),
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "Connect",
+			ServerStreams: true,
+			ClientStreams: true,
+			Handler: func(_ interface{}, stream grpc.ServerStream) error {
+				echo := transport.NewMessageStream(stream, nil)
+				for {
+					payload, err := echo.ReadMessage()
+					if err != nil {
+						ended <- err
+						return nil
+					}
+					if err := echo.WriteMessage(payload); err != nil {
+						return err
+					}
+				}
+			},
+		}},
+	}, nil)
+	go srv.Serve(ln)
+	defer srv.Stop()
+
+	client, err := dialGRPC(ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := `{"jsonrpc":"2.0","id":1,"method":"ping"}`
+	if err := client.WriteMessage([]byte(msg)); err != nil {
+		t.Fatal(err)
+	}
+	if payload, err := client.ReadMessage(); err != nil || string(payload) != msg {
+		t.Fatalf("ReadMessage() = %q, %v; want the echo", payload, err)
+	}
+
+	// Close ends the stream for the server
+	if err := client.Close(); err != nil {
+		t.Errorf("Close() = %v", err)
+	}
+	select {
+	case <-ended:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the stream did not end")
+	}
+}
@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"sqirvy/mcp/pkg/mcp"
+)
+
+// sentRequest is a request sent through sendRequest that awaits its response.
+type sentRequest struct {
+	method string
+	sent   time.Time
+}
+
+// sendRequest writes a request to the server after OnRequest has seen it. A
+// request the hook rejects is not sent and its error is returned.
+func (c *Client) sendRequest(payload []byte) error {
+	info, err := mcp.ClassifyMessage(payload)
+	if err != nil {
+		return err
+	}
+	if c.hooks.OnRequest != nil {
+		if rpcErr := c.hooks.OnRequest(requestContext(info.ID), info.Method, mcp.MessageParams(payload)); rpcErr != nil {
+			return rpcErr
+		}
+	}
+	c.mu.Lock()
+	c.sent[fmt.Sprintf("%v", info.ID)] = sentRequest{method: info.Method, sent: time.Now()}
+	c.mu.Unlock()
+	return c.transport.WriteMessage(payload)
+}
+
+// hookResponse reports the response to request id to OnResponse, and its error
+// to OnError.
+func (c *Client) hookResponse(id int64, payload []byte) {
+	key := fmt.Sprintf("%v", id)
+	c.mu.Lock()
+	req, ok := c.sent[key]
+	delete(c.sent, key)
+	c.mu.Unlock()
+	if !ok {
+		return // Not sent through sendRequest
+	}
+	ctx := requestContext(id)
+	if c.hooks.OnResponse != nil {
+		c.hooks.OnResponse(ctx, req.method, payload, time.Since(req.sent))
+	}
+	var resp struct {
+		Error *mcp.RPCError `json:"error"`
+	}
+	if c.hooks.OnError != nil && json.Unmarshal(payload, &resp) == nil && resp.Error != nil {
+		c.hooks.OnError(ctx, req.method, resp.Error)
+	}
+}
+
+func (c *Client) hookNotification(method string, payload []byte) {
+	if c.hooks.OnNotification != nil {
+		c.hooks.OnNotification(context.Background(), method, mcp.MessageParams(payload))
+	}
+}
+
+func (c *Client) hookShutdown(reason string) {
+	if c.hooks.OnShutdown != nil {
+		c.hooks.OnShutdown(context.Background(), reason)
+	}
+}
+
+// requestContext returns the context request hooks get: it carries the request ID.
+func requestContext(id mcp.RequestID) context.Context {
+	return mcp.WithRequest(context.Background(), nil, id, nil)
+}
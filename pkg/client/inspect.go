@@ -0,0 +1,256 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// outputFormat is how -output prints the inspection report.
+type outputFormat string
+
+const (
+	outputNone     outputFormat = ""      // No report: run the demo calls, see Client.Run
+	outputJSON     outputFormat = "json"  // Indented JSON, for scripts
+	outputTable    outputFormat = "table" // Aligned columns, for terminals
+	outputMarkdown outputFormat = "md"    // Markdown tables, for docs
+)
+
+// parseOutputFormat parses an -output value.
+func parseOutputFormat(s string) (outputFormat, error) {
+	switch format := outputFormat(s); format {
+	case outputNone, outputJSON, outputTable, outputMarkdown:
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown output format %q, want json, table or md", s)
+}
+
+// Health of the server as seen by an inspection, and the exit status it gives
+// mcp-client -output.
+const (
+	healthOK       = "healthy"  // Exit status 0: the handshake and every list succeeded
+	healthFailed   = "failed"   // Exit status 1: no session, the connection or initialize failed
+	healthDegraded = "degraded" // Exit status 2: a list failed or the server downgraded a capability
+)
+
+// inspectReport is what an inspection found out about the server. Fields and
+// list items are in a fixed order, so reports of the same server compare equal.
+type inspectReport struct {
+	Health            string        `json:"health"`
+	Server            string        `json:"server"`
+	Version           string        `json:"version"`
+	ProtocolVersion   string        `json:"protocolVersion"`
+	Capabilities      []string      `json:"capabilities"` // Paths as in ServerCapabilities.Has
+	Tools             []inspectItem `json:"tools"`
+	Resources         []inspectItem `json:"resources"`
+	ResourceTemplates []inspectItem `json:"resourceTemplates"`
+	Prompts           []inspectItem `json:"prompts"`
+	Problems          []string      `json:"problems"`
+}
+
+// inspectItem is one listed tool, resource, resource template or prompt.
+type inspectItem struct {
+	Name        string `json:"name"`
+	Title       string `json:"title,omitempty"`
+	URI         string `json:"uri,omitempty"` // Of a resource, or the URI template of a template
+	Description string `json:"description,omitempty"`
+}
+
+// exitCode returns the process exit status for the report's health.
+func (r *inspectReport) exitCode() int {
+	switch r.Health {
+	case healthOK:
+		return 0
+	case healthDegraded:
+		return 2
+	}
+	return 1
+}
+
+// capabilityPaths lists, in order, the capabilities a report looks for.
+var capabilityPaths = []string{
+	"completions", "logging",
+	"prompts", "prompts.listChanged",
+	"resources", "resources.listChanged", "resources.subscribe",
+	"tools", "tools.listChanged",
+}
+
+// Inspect performs the handshake, lists everything the server advertises and
+// reports what it found, instead of running the demo calls of Run.
+func (c *Client) Inspect() *inspectReport {
+	defer c.transport.Close()
+	report := &inspectReport{
+		Health:            healthOK,
+		Capabilities:      []string{},
+		Tools:             []inspectItem{},
+		Resources:         []inspectItem{},
+		ResourceTemplates: []inspectItem{},
+		Prompts:           []inspectItem{},
+		Problems:          []string{},
+	}
+	defer func() { c.hookShutdown("inspection " + report.Health) }()
+
+	result, err := c.Initialize()
+	if err != nil {
+		report.Health = healthFailed
+		report.Problems = append(report.Problems, err.Error())
+		return report
+	}
+	report.Server, report.Version = result.ServerInfo.Name, result.ServerInfo.Version
+	report.ProtocolVersion = result.ProtocolVersion
+	caps := result.Capabilities
+	for _, path := range capabilityPaths {
+		if caps.Has(path) {
+			report.Capabilities = append(report.Capabilities, path)
+		}
+	}
+	for _, name := range caps.Experimental.Names() {
+		report.Capabilities = append(report.Capabilities, "experimental."+name)
+	}
+	for _, w := range result.CapabilityWarnings() {
+		report.Problems = append(report.Problems, "capability downgrade: "+w.String())
+	}
+
+	problem := func(err error) {
+		report.Problems = append(report.Problems, err.Error())
+	}
+	if caps.Has("tools") {
+		tools, err := c.listTools()
+		if err != nil {
+			problem(err)
+		}
+		for _, tool := range tools {
+			report.Tools = append(report.Tools, inspectItem{Name: tool.Name, Title: tool.Title, Description: tool.Description})
+		}
+	}
+	if caps.Has("resources") {
+		resources, err := c.listResources()
+		if err != nil {
+			problem(err)
+		}
+		for _, resource := range resources {
+			report.Resources = append(report.Resources, inspectItem{Name: resource.Name, Title: resource.Title, URI: resource.URI, Description: resource.Description})
+		}
+		templates, err := c.listResourceTemplates()
+		if err != nil {
+			problem(err)
+		}
+		for _, template := range templates {
+			report.ResourceTemplates = append(report.ResourceTemplates, inspectItem{Name: template.Name, Title: template.Title, URI: template.URITemplate, Description: template.Description})
+		}
+	}
+	if caps.Has("prompts") {
+		prompts, err := c.listPrompts()
+		if err != nil {
+			problem(err)
+		}
+		for _, prompt := range prompts {
+			report.Prompts = append(report.Prompts, inspectItem{Name: prompt.Name, Title: prompt.Title, Description: prompt.Description})
+		}
+	}
+	for _, items := range [][]inspectItem{report.Tools, report.Resources, report.ResourceTemplates, report.Prompts} {
+		sort.SliceStable(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+	}
+	if len(report.Problems) > 0 {
+		report.Health = healthDegraded
+	}
+	return report
+}
+
+// writeReport prints the report to w in format.
+func writeReport(w io.Writer, r *inspectReport, format outputFormat) error {
+	switch format {
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case outputMarkdown:
+		return writeMarkdownReport(w, r)
+	default:
+		return writeTableReport(w, r)
+	}
+}
+
+// reportSection is one kind of item in a report.
+type reportSection struct {
+	kind    string // Singular, for the KIND column of a table
+	heading string // For the Markdown heading
+	items   []inspectItem
+}
+
+// sections returns the item lists of the report, in the order they are printed.
+func (r *inspectReport) sections() []reportSection {
+	return []reportSection{
+		{"tool", "Tools", r.Tools},
+		{"resource", "Resources", r.Resources},
+		{"template", "Resource Templates", r.ResourceTemplates},
+		{"prompt", "Prompts", r.Prompts},
+	}
+}
+
+// writeTableReport prints a summary followed by one aligned table of every item.
+func writeTableReport(w io.Writer, r *inspectReport) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "SERVER\t%s %s\n", r.Server, r.Version)
+	fmt.Fprintf(tw, "PROTOCOL\t%s\n", r.ProtocolVersion)
+	fmt.Fprintf(tw, "HEALTH\t%s\n", r.Health)
+	fmt.Fprintf(tw, "CAPABILITIES\t%s\n", strings.Join(r.Capabilities, ", "))
+	for _, problem := range r.Problems {
+		fmt.Fprintf(tw, "PROBLEM\t%s\n", oneLine(problem))
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "KIND\tNAME\tTITLE\tURI\tDESCRIPTION")
+	for _, section := range r.sections() {
+		for _, item := range section.items {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", section.kind, item.Name, item.Title, item.URI, oneLine(item.Description))
+		}
+	}
+	return tw.Flush()
+}
+
+// writeMarkdownReport prints the report as a Markdown document with a table per kind.
+func writeMarkdownReport(w io.Writer, r *inspectReport) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s %s\n\n", markdownCell(r.Server), markdownCell(r.Version))
+	fmt.Fprintf(&b, "- Protocol: %s\n", r.ProtocolVersion)
+	fmt.Fprintf(&b, "- Health: %s\n", r.Health)
+	capabilities := make([]string, len(r.Capabilities))
+	for i, path := range r.Capabilities {
+		capabilities[i] = "`" + path + "`"
+	}
+	fmt.Fprintf(&b, "- Capabilities: %s\n", strings.Join(capabilities, ", "))
+	if len(r.Problems) > 0 {
+		b.WriteString("\n## Problems\n\n")
+		for _, problem := range r.Problems {
+			fmt.Fprintf(&b, "- %s\n", oneLine(problem))
+		}
+	}
+	for _, section := range r.sections() {
+		if len(section.items) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n## %s\n\n| Name | Title | URI | Description |\n| --- | --- | --- | --- |\n", section.heading)
+		for _, item := range section.items {
+			uri := ""
+			if item.URI != "" {
+				uri = "`" + markdownCell(item.URI) + "`"
+			}
+			fmt.Fprintf(&b, "| `%s` | %s | %s | %s |\n", markdownCell(item.Name), markdownCell(item.Title), uri, markdownCell(item.Description))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// oneLine joins the lines of s with spaces, for a table cell.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// markdownCell makes s safe in a Markdown table cell.
+func markdownCell(s string) string {
+	return strings.ReplaceAll(oneLine(s), "|", `\|`)
+}
@@ -0,0 +1,64 @@
+package client
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"strings"
+	"testing"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/mcptest"
+	"sqirvy/mcp/pkg/transport"
+)
+
+func TestInspect(t *testing.T) {
+	srv := mcptest.NewServer(t)
+	srv.ExpectInitialize()
+	srv.Expect(mcp.MethodListTools).Respond(mcp.ListToolsResult{Tools: []mcp.Tool{
+		{Name: "zeta", Description: "Last"},
+		{Name: "alpha", Title: "Alpha", Description: "First | with a pipe\nand a line break"},
+	}})
+	srv.Expect(mcp.MethodListResources).Respond(mcp.ListResourcesResult{Resources: []mcp.Resource{{Name: "doc", URI: "file:///doc"}}})
+	srv.Expect(mcp.MethodListResourceTemplates).RespondError(mcp.NewRPCError(mcp.ErrorCodeMethodNotFound, "no templates", nil))
+	srv.Expect(mcp.MethodListPrompts).Respond(mcp.ListPromptsResult{Prompts: []mcp.Prompt{}})
+
+	conn := srv.Conn()
+	report := NewClient(transport.NewStream(conn, conn), log.New(io.Discard, "", 0)).Inspect()
+
+	// Items are sorted, and a failed list degrades the server
+	if report.Health != healthDegraded || report.exitCode() != 2 || len(report.Problems) != 1 {
+		t.Errorf("health = %s (exit %d), problems %q", report.Health, report.exitCode(), report.Problems)
+	}
+	if len(report.Tools) != 2 || report.Tools[0].Name != "alpha" || report.Tools[1].Name != "zeta" {
+		t.Errorf("tools = %+v", report.Tools)
+	}
+
+	var out strings.Builder
+	if err := writeReport(&out, report, outputJSON); err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(out.String()), &decoded); err != nil || decoded["server"] != "mcptest" {
+		t.Errorf("JSON report = %s (%v)", out.String(), err)
+	}
+	if !strings.HasPrefix(out.String(), "{\n  \"health\": \"degraded\",\n  \"server\"") || !strings.Contains(out.String(), `"resourceTemplates": []`) {
+		t.Errorf("JSON report fields out of order or missing:\n%s", out.String())
+	}
+
+	out.Reset()
+	writeReport(&out, report, outputMarkdown)
+	if !strings.Contains(out.String(), "| `alpha` | Alpha |  | First \\| with a pipe and a line break |\n") || strings.Contains(out.String(), "## Prompts") {
+		t.Errorf("Markdown report:\n%s", out.String())
+	}
+
+	out.Reset()
+	writeReport(&out, report, outputTable)
+	if !strings.Contains(out.String(), "HEALTH        degraded\n") || !strings.Contains(out.String(), "resource  doc    ") {
+		t.Errorf("table report:\n%s", out.String())
+	}
+
+	if _, err := parseOutputFormat("yaml"); err == nil {
+		t.Error("parseOutputFormat accepted yaml")
+	}
+}
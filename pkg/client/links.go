@@ -0,0 +1,97 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"sqirvy/mcp/pkg/mcp"
+)
+
+// EnableLinkResolution makes the client read the resources that tool results
+// link to (resource_link blocks) and put their contents in the result in place
+// of the links, as embedded resources. Each URI is read once per client.
+func (c *Client) EnableLinkResolution() {
+	c.linkMu.Lock()
+	defer c.linkMu.Unlock()
+	c.resolveLinks = true
+	if c.linked == nil {
+		c.linked = make(map[string][]json.RawMessage)
+	}
+}
+
+// expandResourceLinks replaces the resource_link blocks of result with the
+// contents of the linked resources, if link resolution is enabled. A link
+// whose resource cannot be read is left in place.
+func (c *Client) expandResourceLinks(result *mcp.CallToolResult) {
+	c.linkMu.Lock()
+	enabled := c.resolveLinks
+	c.linkMu.Unlock()
+	if !enabled {
+		return
+	}
+	content := make([]json.RawMessage, 0, len(result.Content))
+	for _, block := range result.Content {
+		var link mcp.ResourceLink
+		if json.Unmarshal(block, &link) != nil || link.Type != "resource_link" {
+			content = append(content, block)
+			continue
+		}
+		contents, err := c.ResolveLink(link)
+		if err != nil {
+			c.logger.Printf("Failed to resolve resource link %s: %v", link.URI, err)
+			content = append(content, block)
+			continue
+		}
+		c.logger.Printf("Resolved resource link %s (%d content item(s))", link.URI, len(contents))
+		for _, item := range contents {
+			content = append(content, mcp.NewEmbeddedResource(item))
+		}
+	}
+	result.Content = content
+}
+
+// ResolveLink returns the contents of the resource link points at, reading it
+// with resources/read the first time it is asked for.
+func (c *Client) ResolveLink(link mcp.ResourceLink) ([]json.RawMessage, error) {
+	c.linkMu.Lock()
+	contents, ok := c.linked[link.URI]
+	c.linkMu.Unlock()
+	if ok {
+		return contents, nil
+	}
+	result, err := c.readResource(link.URI)
+	if err != nil {
+		return nil, err
+	}
+	c.linkMu.Lock()
+	if c.linked == nil {
+		c.linked = make(map[string][]json.RawMessage)
+	}
+	c.linked[link.URI] = result.Contents
+	c.linkMu.Unlock()
+	return result.Contents, nil
+}
+
+// readResource sends a resources/read request for uri and returns the result.
+func (c *Client) readResource(uri string) (*mcp.ReadResourceResult, error) {
+	id := c.nextID()
+	request, err := mcp.MarshalReadResourcesRequest(id, mcp.ReadResourceParams{URI: uri})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal read resource request: %w", err)
+	}
+	if err := c.sendRequest(request); err != nil {
+		return nil, fmt.Errorf("failed to send read resource request: %w", err)
+	}
+	response, err := c.readResponse(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read resource response: %w", err)
+	}
+	result, _, rpcErr, parseErr := mcp.UnmarshalReadResourcesResponse(response)
+	switch {
+	case parseErr != nil:
+		return nil, fmt.Errorf("failed to parse read resource response: %w", parseErr)
+	case rpcErr != nil:
+		return nil, fmt.Errorf("received RPC error in read resource response: %w", rpcErr)
+	}
+	return result, nil
+}
@@ -0,0 +1,64 @@
+package client
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"testing"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/mcptest"
+	"sqirvy/mcp/pkg/transport"
+)
+
+func TestExpandResourceLinks(t *testing.T) {
+	srv := mcptest.NewServer(t)
+	read := srv.Expect(mcp.MethodReadResource).WithParams(func(params json.RawMessage) bool {
+		var p mcp.ReadResourceParams
+		return json.Unmarshal(params, &p) == nil && p.URI == "file:///big.txt"
+	})
+	var contents mcp.ReadResourceResult
+	contents.Add(mcp.NewTextResource("file:///big.txt", "text/plain", "the whole body"))
+	read.Respond(contents)
+	srv.Expect(mcp.MethodReadResource).RespondError(mcp.NewRPCError(mcp.ErrorCodeInvalidParams, "Resource not found", nil))
+
+	conn := srv.Conn()
+	c := NewClient(transport.NewStream(conn, conn), log.New(io.Discard, "", 0))
+	link := mcp.ResourceLink{URI: "file:///big.txt", Name: "big.txt"}
+	result := mcp.CallToolResult{Content: []json.RawMessage{mcp.NewTextContent("see"), mcp.NewResourceLink(link)}}
+
+	// Off by default: links are left alone and nothing is read
+	c.expandResourceLinks(&result)
+	if len(result.ResourceLinks()) != 1 || read.Met() {
+		t.Fatalf("links resolved without EnableLinkResolution: %s", result.Content)
+	}
+
+	c.EnableLinkResolution()
+	missing := mcp.NewResourceLink(mcp.ResourceLink{URI: "file:///missing.txt", Name: "missing.txt"})
+	result.Content = append(result.Content, missing, mcp.NewResourceLink(link))
+	c.expandResourceLinks(&result)
+
+	if len(result.Content) != 4 {
+		t.Fatalf("content = %s, want text, two embedded resources and the unresolved link", result.Content)
+	}
+	for _, i := range []int{1, 3} {
+		var embedded mcp.EmbeddedResource
+		var text mcp.TextResourceContents
+		if json.Unmarshal(result.Content[i], &embedded) != nil || embedded.Type != "resource" || json.Unmarshal(embedded.Resource, &text) != nil || text.Text != "the whole body" {
+			t.Errorf("content[%d] = %s, want the embedded resource", i, result.Content[i])
+		}
+	}
+	if links := result.ResourceLinks(); len(links) != 1 || links[0].URI != "file:///missing.txt" {
+		t.Errorf("unresolved links = %+v, want the missing one", links)
+	}
+	// The same link twice is read once
+	n := 0
+	for _, msg := range srv.Received() {
+		if msg.Method == mcp.MethodReadResource {
+			n++
+		}
+	}
+	if n != 2 {
+		t.Errorf("%d resources/read requests, want 2", n)
+	}
+}
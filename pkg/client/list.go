@@ -0,0 +1,353 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"sqirvy/mcp/pkg/mcp" // Use the correct module path
+)
+
+// --- Helper Functions for MCP List Calls ---
+
+// maxListPages bounds how many pages a list helper follows, in case a server
+// keeps handing out cursors.
+const maxListPages = 100
+
+// collectPages calls fetch with an empty cursor and then with each NextCursor it
+// returns until the server reports no further pages. A cursor seen before, or more
+// than maxListPages pages, is treated as a server bug and ends the walk with an error.
+func (c *Client) collectPages(what string, fetch func(cursor string) (next string, err error)) error {
+	seen := make(map[string]bool)
+	cursor := ""
+	for page := 1; ; page++ {
+		next, err := fetch(cursor)
+		if err != nil {
+			return err
+		}
+		if next == "" {
+			return nil
+		}
+		if seen[next] {
+			c.logger.Printf("List %s returned cursor %q twice; stopping.", what, next)
+			return fmt.Errorf("list %s returned cursor %q twice", what, next)
+		}
+		if page >= maxListPages {
+			c.logger.Printf("List %s has more than %d pages; stopping.", what, maxListPages)
+			return fmt.Errorf("list %s has more than %d pages", what, maxListPages)
+		}
+		seen[next] = true
+		c.logger.Printf("Fetching next page of %s (cursor: %s)...", what, next)
+		cursor = next
+	}
+}
+
+// label names a listed item for display: its title followed by its name, or
+// just the name if it has no title.
+func label(displayName, name string) string {
+	if displayName == name {
+		return name
+	}
+	return fmt.Sprintf("%s (%s)", displayName, name)
+}
+
+// listTools fetches every page of tools, following NextCursor, and logs the combined list.
+func (c *Client) listTools() ([]mcp.Tool, error) {
+	var tools []mcp.Tool
+	err := c.collectPages("tools", func(cursor string) (string, error) {
+		result, err := c.listToolsPage(cursor)
+		if err != nil {
+			return "", err
+		}
+		tools = append(tools, result.Tools...)
+		return result.NextCursor, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.logger.Printf("Available Tools (%d):", len(tools))
+	for _, tool := range tools {
+		schemaBytes, _ := json.Marshal(tool.InputSchema) // Marshal schema for logging
+		c.logger.Printf("  - %s, Description: %s, Schema: %s", label(tool.DisplayName(), tool.Name), tool.Description, string(schemaBytes))
+	}
+	c.logger.Println("List tools call complete.")
+	return tools, nil
+}
+
+// listToolsPage sends a tools/list request for the page at cursor (the first page if empty) and returns its result.
+func (c *Client) listToolsPage(cursor string) (*mcp.ListToolsResult, error) {
+	listID := c.nextID()
+	var params *mcp.ListToolsParams
+	if cursor != "" {
+		params = &mcp.ListToolsParams{Cursor: cursor}
+	}
+	listRequestBytes, err := mcp.MarshalListToolsRequest(listID, params)
+	if err != nil {
+		c.logger.Printf("Failed to marshal list tools request: %v", err)
+		return nil, fmt.Errorf("failed to marshal list tools request: %w", err)
+	}
+
+	c.logger.Println("Sending list tools request...")
+	if err := c.sendRequest(listRequestBytes); err != nil {
+		c.logger.Printf("Failed to send list tools request: %v", err)
+		return nil, fmt.Errorf("failed to send list tools request: %w", err)
+	}
+
+	c.logger.Println("Waiting for list tools response...")
+	listResponseBytes, err := c.readResponse(listID)
+	if err != nil {
+		c.logger.Printf("Failed to read list tools response: %v", err)
+		return nil, fmt.Errorf("failed to read list tools response: %w", err)
+	}
+	c.logger.Printf("Received list tools response JSON: %s", string(listResponseBytes))
+
+	listResult, listRespID, listRPCErr, listParseErr := mcp.UnmarshalListToolsResponse(listResponseBytes)
+	if listParseErr != nil {
+		c.logger.Printf("Failed to parse list tools response: %v", listParseErr)
+		return nil, fmt.Errorf("failed to parse list tools response: %w", listParseErr)
+	}
+	if fmt.Sprintf("%v", listRespID) != fmt.Sprintf("%v", listID) {
+		c.logger.Printf("List tools response ID mismatch. Got: %v (%T), Want: %v (%T)", listRespID, listRespID, listID, listID)
+		return nil, fmt.Errorf("list tools response ID mismatch. Got: %v, Want: %v", listRespID, listID)
+	}
+	if listRPCErr != nil {
+		c.logger.Printf("Received RPC error in list tools response: %s", mcp.FormatError(mcp.MethodListTools, listRPCErr))
+		return nil, fmt.Errorf("received RPC error in list tools response: %w", listRPCErr)
+	}
+	if listResult == nil {
+		c.logger.Println("List tools response contained no result.")
+		return nil, fmt.Errorf("list tools response contained no result")
+	}
+
+	return listResult, nil
+}
+
+// listResources fetches every page of resources, following NextCursor, and logs the combined list.
+func (c *Client) listResources() ([]mcp.Resource, error) {
+	var resources []mcp.Resource
+	err := c.collectPages("resources", func(cursor string) (string, error) {
+		result, err := c.listResourcesPage(cursor)
+		if err != nil {
+			return "", err
+		}
+		resources = append(resources, result.Resources...)
+		return result.NextCursor, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.logger.Printf("Available Resources (%d):", len(resources))
+	for _, resource := range resources {
+		sizeStr := "N/A"
+		if resource.Size != nil {
+			sizeStr = fmt.Sprintf("%d bytes", *resource.Size)
+		}
+		c.logger.Printf("  - %s, URI: %s, Description: %s, MimeType: %s, Size: %s",
+			label(resource.DisplayName(), resource.Name), resource.URI, resource.Description, resource.MimeType, sizeStr)
+	}
+	c.logger.Println("List resources call complete.")
+	return resources, nil
+}
+
+// listResourcesPage sends a resources/list request for the page at cursor (the first page if empty) and returns its result.
+func (c *Client) listResourcesPage(cursor string) (*mcp.ListResourcesResult, error) {
+	listID := c.nextID()
+	var params *mcp.ListResourcesParams
+	if cursor != "" {
+		params = &mcp.ListResourcesParams{Cursor: cursor}
+	}
+	listRequestBytes, err := mcp.MarshalListResourcesRequest(listID, params)
+	if err != nil {
+		c.logger.Printf("Failed to marshal list resources request: %v", err)
+		return nil, fmt.Errorf("failed to marshal list resources request: %w", err)
+	}
+
+	c.logger.Println("Sending list resources request...")
+	if err := c.sendRequest(listRequestBytes); err != nil {
+		c.logger.Printf("Failed to send list resources request: %v", err)
+		return nil, fmt.Errorf("failed to send list resources request: %w", err)
+	}
+
+	c.logger.Println("Waiting for list resources response...")
+	listResponseBytes, err := c.readResponse(listID)
+	if err != nil {
+		c.logger.Printf("Failed to read list resources response: %v", err)
+		return nil, fmt.Errorf("failed to read list resources response: %w", err)
+	}
+	c.logger.Printf("Received list resources response JSON: %s", string(listResponseBytes))
+
+	listResult, listRespID, listRPCErr, listParseErr := mcp.UnmarshalListResourcesResponse(listResponseBytes)
+	if listParseErr != nil {
+		c.logger.Printf("Failed to parse list resources response: %v", listParseErr)
+		return nil, fmt.Errorf("failed to parse list resources response: %w", listParseErr)
+	}
+	if fmt.Sprintf("%v", listRespID) != fmt.Sprintf("%v", listID) {
+		c.logger.Printf("List resources response ID mismatch. Got: %v (%T), Want: %v (%T)", listRespID, listRespID, listID, listID)
+		return nil, fmt.Errorf("list resources response ID mismatch. Got: %v, Want: %v", listRespID, listID)
+	}
+	if listRPCErr != nil {
+		c.logger.Printf("Received RPC error in list resources response: %s", mcp.FormatError(mcp.MethodListResources, listRPCErr))
+		return nil, fmt.Errorf("received RPC error in list resources response: %w", listRPCErr)
+	}
+	if listResult == nil {
+		c.logger.Println("List resources response contained no result.")
+		return nil, fmt.Errorf("list resources response contained no result")
+	}
+
+	return listResult, nil
+}
+
+// listResourceTemplates fetches every page of resource templates, following NextCursor, and logs the combined list.
+func (c *Client) listResourceTemplates() ([]mcp.ResourceTemplate, error) {
+	var templates []mcp.ResourceTemplate
+	err := c.collectPages("resource templates", func(cursor string) (string, error) {
+		result, err := c.listResourceTemplatesPage(cursor)
+		if err != nil {
+			return "", err
+		}
+		templates = append(templates, result.ResourceTemplates...)
+		return result.NextCursor, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.logger.Printf("Available Resource Templates (%d):", len(templates))
+	for _, template := range templates {
+		c.logger.Printf("  - %s, URI Template: %s, Description: %s, MimeType: %s",
+			label(template.DisplayName(), template.Name), template.URITemplate, template.Description, template.MimeType)
+	}
+	c.logger.Println("List resource templates call complete.")
+	return templates, nil
+}
+
+// listResourceTemplatesPage sends a resources/templates/list request for the page at cursor (the first page if empty) and returns its result.
+func (c *Client) listResourceTemplatesPage(cursor string) (*mcp.ListResourceTemplatesResult, error) {
+	listID := c.nextID()
+	var params *mcp.ListResourceTemplatesParams
+	if cursor != "" {
+		params = &mcp.ListResourceTemplatesParams{Cursor: cursor}
+	}
+	listRequestBytes, err := mcp.MarshalListResourceTemplatesRequest(listID, params)
+	if err != nil {
+		c.logger.Printf("Failed to marshal list resource templates request: %v", err)
+		return nil, fmt.Errorf("failed to marshal list resource templates request: %w", err)
+	}
+
+	c.logger.Println("Sending list resource templates request...")
+	if err := c.sendRequest(listRequestBytes); err != nil {
+		c.logger.Printf("Failed to send list resource templates request: %v", err)
+		return nil, fmt.Errorf("failed to send list resource templates request: %w", err)
+	}
+
+	c.logger.Println("Waiting for list resource templates response...")
+	listResponseBytes, err := c.readResponse(listID)
+	if err != nil {
+		c.logger.Printf("Failed to read list resource templates response: %v", err)
+		return nil, fmt.Errorf("failed to read list resource templates response: %w", err)
+	}
+	c.logger.Printf("Received list resource templates response JSON: %s", string(listResponseBytes))
+
+	listResult, listRespID, listRPCErr, listParseErr := mcp.UnmarshalListResourceTemplatesResponse(listResponseBytes)
+	if listParseErr != nil {
+		c.logger.Printf("Failed to parse list resource templates response: %v", listParseErr)
+		return nil, fmt.Errorf("failed to parse list resource templates response: %w", listParseErr)
+	}
+	if fmt.Sprintf("%v", listRespID) != fmt.Sprintf("%v", listID) {
+		c.logger.Printf("List resource templates response ID mismatch. Got: %v (%T), Want: %v (%T)", listRespID, listRespID, listID, listID)
+		return nil, fmt.Errorf("list resource templates response ID mismatch. Got: %v, Want: %v", listRespID, listID)
+	}
+	if listRPCErr != nil {
+		c.logger.Printf("Received RPC error in list resource templates response: %s", mcp.FormatError(mcp.MethodListResourceTemplates, listRPCErr))
+		return nil, fmt.Errorf("received RPC error in list resource templates response: %w", listRPCErr)
+	}
+	if listResult == nil {
+		c.logger.Println("List resource templates response contained no result.")
+		return nil, fmt.Errorf("list resource templates response contained no result")
+	}
+
+	return listResult, nil
+}
+
+// listPrompts fetches every page of prompts, following NextCursor, and logs the combined list.
+func (c *Client) listPrompts() ([]mcp.Prompt, error) {
+	var prompts []mcp.Prompt
+	err := c.collectPages("prompts", func(cursor string) (string, error) {
+		result, err := c.listPromptsPage(cursor)
+		if err != nil {
+			return "", err
+		}
+		prompts = append(prompts, result.Prompts...)
+		return result.NextCursor, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.logger.Printf("Available Prompts (%d):", len(prompts))
+	for _, prompt := range prompts {
+		argsStr := ""
+		if len(prompt.Arguments) > 0 {
+			args := make([]string, len(prompt.Arguments))
+			for i, arg := range prompt.Arguments {
+				reqStr := ""
+				if arg.Required {
+					reqStr = " (required)"
+				}
+				args[i] = fmt.Sprintf("%s%s", label(arg.DisplayName(), arg.Name), reqStr)
+			}
+			argsStr = fmt.Sprintf(" Args: [%s]", args)
+		}
+		c.logger.Printf("  - %s, Description: %s%s", label(prompt.DisplayName(), prompt.Name), prompt.Description, argsStr)
+	}
+	c.logger.Println("List prompts call complete.")
+	return prompts, nil
+}
+
+// listPromptsPage sends a prompts/list request for the page at cursor (the first page if empty) and returns its result.
+func (c *Client) listPromptsPage(cursor string) (*mcp.ListPromptsResult, error) {
+	listID := c.nextID()
+	var params *mcp.ListPromptsParams
+	if cursor != "" {
+		params = &mcp.ListPromptsParams{Cursor: cursor}
+	}
+	listRequestBytes, err := mcp.MarshalListPromptsRequest(listID, params)
+	if err != nil {
+		c.logger.Printf("Failed to marshal list prompts request: %v", err)
+		return nil, fmt.Errorf("failed to marshal list prompts request: %w", err)
+	}
+
+	c.logger.Println("Sending list prompts request...")
+	if err := c.sendRequest(listRequestBytes); err != nil {
+		c.logger.Printf("Failed to send list prompts request: %v", err)
+		return nil, fmt.Errorf("failed to send list prompts request: %w", err)
+	}
+
+	c.logger.Println("Waiting for list prompts response...")
+	listResponseBytes, err := c.readResponse(listID)
+	if err != nil {
+		c.logger.Printf("Failed to read list prompts response: %v", err)
+		return nil, fmt.Errorf("failed to read list prompts response: %w", err)
+	}
+	c.logger.Printf("Received list prompts response JSON: %s", string(listResponseBytes))
+
+	listResult, listRespID, listRPCErr, listParseErr := mcp.UnmarshalListPromptsResponse(listResponseBytes)
+	if listParseErr != nil {
+		c.logger.Printf("Failed to parse list prompts response: %v", listParseErr)
+		return nil, fmt.Errorf("failed to parse list prompts response: %w", listParseErr)
+	}
+	if fmt.Sprintf("%v", listRespID) != fmt.Sprintf("%v", listID) {
+		c.logger.Printf("List prompts response ID mismatch. Got: %v (%T), Want: %v (%T)", listRespID, listRespID, listID, listID)
+		return nil, fmt.Errorf("list prompts response ID mismatch. Got: %v, Want: %v", listRespID, listID)
+	}
+	if listRPCErr != nil {
+		c.logger.Printf("Received RPC error in list prompts response: %s", mcp.FormatError(mcp.MethodListPrompts, listRPCErr))
+		return nil, fmt.Errorf("received RPC error in list prompts response: %w", listRPCErr)
+	}
+	if listResult == nil {
+		c.logger.Println("List prompts response contained no result.")
+		return nil, fmt.Errorf("list prompts response contained no result")
+	}
+
+	return listResult, nil
+}
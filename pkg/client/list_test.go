@@ -0,0 +1,72 @@
+package client
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"strings"
+	"testing"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/mcptest"
+	"sqirvy/mcp/pkg/transport"
+)
+
+// cursorIs matches list requests whose params carry the given cursor ("" for the first page).
+func cursorIs(cursor string) func(json.RawMessage) bool {
+	return func(params json.RawMessage) bool {
+		var p struct {
+			Cursor string `json:"cursor"`
+		}
+		return json.Unmarshal(params, &p) == nil && p.Cursor == cursor
+	}
+}
+
+func TestListToolsFollowsCursors(t *testing.T) {
+	srv := mcptest.NewServer(t)
+	srv.ExpectInitialize()
+	srv.Expect(mcp.MethodListTools).WithParams(cursorIs("")).
+		Respond(mcp.ListToolsResult{Tools: []mcp.Tool{{Name: "a"}, {Name: "b"}}, NextCursor: "p2"})
+	srv.Expect(mcp.MethodListTools).WithParams(cursorIs("p2")).
+		Respond(mcp.ListToolsResult{Tools: []mcp.Tool{{Name: "c"}}, NextCursor: "p3"})
+	srv.Expect(mcp.MethodListTools).WithParams(cursorIs("p3")).
+		Respond(mcp.ListToolsResult{Tools: []mcp.Tool{{Name: "d"}}})
+
+	conn := srv.Conn()
+	c := NewClient(transport.NewStream(conn, conn), log.New(io.Discard, "", 0))
+	if _, err := c.Initialize(); err != nil {
+		t.Fatalf("initialize failed: %v", err)
+	}
+
+	tools, err := c.listTools()
+	if err != nil {
+		t.Fatalf("listTools failed: %v", err)
+	}
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.Name)
+	}
+	if got := strings.Join(names, ","); got != "a,b,c,d" {
+		t.Errorf("tools = %s, want a,b,c,d", got)
+	}
+	srv.AssertExpectations()
+}
+
+func TestListPromptsStopsOnRepeatedCursor(t *testing.T) {
+	srv := mcptest.NewServer(t)
+	srv.ExpectInitialize()
+	srv.Expect(mcp.MethodListPrompts).WithParams(cursorIs("")).
+		Respond(mcp.ListPromptsResult{Prompts: []mcp.Prompt{{Name: "a"}}, NextCursor: "again"})
+	srv.Expect(mcp.MethodListPrompts).WithParams(cursorIs("again")).
+		Respond(mcp.ListPromptsResult{Prompts: []mcp.Prompt{{Name: "b"}}, NextCursor: "again"})
+
+	conn := srv.Conn()
+	c := NewClient(transport.NewStream(conn, conn), log.New(io.Discard, "", 0))
+	if _, err := c.Initialize(); err != nil {
+		t.Fatalf("initialize failed: %v", err)
+	}
+
+	if _, err := c.listPrompts(); err == nil || !strings.Contains(err.Error(), `cursor "again" twice`) {
+		t.Errorf("listPrompts error = %v, want a repeated cursor error", err)
+	}
+}
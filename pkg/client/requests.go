@@ -0,0 +1,164 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"sqirvy/mcp/pkg/mcp"
+)
+
+// RequestHandler answers a request initiated by the server, such as sampling/createMessage
+// or roots/list. It returns either a result to marshal or an RPC error.
+type RequestHandler func(params json.RawMessage) (interface{}, *mcp.RPCError)
+
+// HandleRequest registers h for server-initiated requests with the given method,
+// replacing any previous handler. ping is answered automatically unless overridden.
+func (c *Client) HandleRequest(method string, h RequestHandler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handlers[method] = h
+}
+
+// NotificationHandler receives a notification from the server, such as a log message
+// or progress update, that arrived while the client was waiting for a response.
+type NotificationHandler func(params json.RawMessage)
+
+// HandleNotification registers h for server notifications with the given method,
+// replacing any previous handler. Notifications without a handler are logged and dropped.
+func (c *Client) HandleNotification(method string, h NotificationHandler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.notifyHandlers[method] = h
+}
+
+// OrphanHandler receives a response whose ID matches no pending request, such as
+// a late reply to a call the client already gave up on or a duplicate.
+type OrphanHandler func(info mcp.MessageInfo, payload []byte)
+
+// OnOrphanResponse registers h to be told about responses to unknown request IDs,
+// replacing any previous hook. Orphans are logged and skipped either way.
+func (c *Client) OnOrphanResponse(h OrphanHandler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onOrphan = h
+}
+
+// pingHandler answers server pings with an empty result.
+func pingHandler(json.RawMessage) (interface{}, *mcp.RPCError) {
+	return struct{}{}, nil
+}
+
+// readResponse reads messages until the response (or error response) to request id arrives
+// and returns it. Requests from the server are answered on the way, notifications are logged
+// and passed to their handlers, and responses to other IDs are handed to the orphan hook,
+// so the call helpers only ever see replies to their own requests.
+func (c *Client) readResponse(id int64) ([]byte, error) {
+	for {
+		payload, err := c.transport.ReadMessage()
+		if err != nil {
+			return nil, err
+		}
+		info, err := mcp.ClassifyMessage(payload)
+		if err != nil {
+			// Let the caller's unmarshal report the problem with the full payload
+			return payload, nil
+		}
+		switch info.Kind {
+		case mcp.KindRequest:
+			if err := c.answerServerRequest(info, payload); err != nil {
+				return nil, err
+			}
+		case mcp.KindNotification:
+			c.hookNotification(info.Method, payload)
+			if info.Method == mcp.MethodShutdown {
+				var n struct {
+					Params mcp.ShutdownParams `json:"params"`
+				}
+				_ = json.Unmarshal(payload, &n)
+				c.logger.Printf("Server is shutting down (%s); pending requests will complete", n.Params.Reason)
+				continue
+			}
+			c.logger.Printf("Received notification from server: %s", info.Method)
+			c.deliverNotification(info, payload)
+		default:
+			// Compare by value; the decoded ID is a json.Number or string
+			if info.ID != nil && fmt.Sprintf("%v", info.ID) != fmt.Sprintf("%v", id) {
+				c.orphanResponse(info, payload)
+				continue
+			}
+			c.hookResponse(id, payload)
+			return payload, nil
+		}
+	}
+}
+
+// deliverNotification passes a notification's params to its handler, if one is registered.
+// Handlers run before the read resumes, so they see notifications in arrival order.
+func (c *Client) deliverNotification(info mcp.MessageInfo, payload []byte) {
+	c.mu.Lock()
+	h, ok := c.notifyHandlers[info.Method]
+	c.mu.Unlock()
+	if !ok {
+		return
+	}
+	var n struct {
+		Params json.RawMessage `json:"params"`
+	}
+	_ = json.Unmarshal(payload, &n) // Already validated by ClassifyMessage
+	h(n.Params)
+}
+
+// orphanResponse logs a response nobody is waiting for and passes it to the hook, if any.
+func (c *Client) orphanResponse(info mcp.MessageInfo, payload []byte) {
+	c.logger.Printf("Ignoring response to unknown request ID %v (waiting for another reply)", info.ID)
+	c.mu.Lock()
+	h := c.onOrphan
+	c.mu.Unlock()
+	if h != nil {
+		h(info, payload)
+	}
+}
+
+// answerServerRequest dispatches a server-initiated request to its handler and sends the reply.
+// Unknown methods are answered with MethodNotFound.
+func (c *Client) answerServerRequest(info mcp.MessageInfo, payload []byte) error {
+	c.mu.Lock()
+	h, ok := c.handlers[info.Method]
+	c.mu.Unlock()
+
+	var response []byte
+	if !ok {
+		c.logger.Printf("Server sent unsupported request '%s' (ID: %v)", info.Method, info.ID)
+		rpcErr := mcp.NewRPCError(mcp.ErrorCodeMethodNotFound, fmt.Sprintf("Method '%s' not found", info.Method), map[string]string{"method": info.Method})
+		response = mcp.MustErrorResponse(info.ID, rpcErr)
+	} else {
+		var req struct {
+			Params json.RawMessage `json:"params"`
+		}
+		_ = json.Unmarshal(payload, &req) // Already validated by ClassifyMessage
+		result, rpcErr := h(req.Params)
+		if rpcErr == nil {
+			var err error
+			if response, err = marshalResult(info.ID, result); err != nil {
+				c.logger.Printf("Failed to marshal reply to server request %v: %v", info.ID, err)
+				rpcErr = mcp.NewRPCError(mcp.ErrorCodeInternalError, "Failed to marshal result", nil)
+			}
+		}
+		if rpcErr != nil {
+			response = mcp.MustErrorResponse(info.ID, rpcErr)
+		}
+	}
+	if err := c.transport.WriteMessage(response); err != nil {
+		return fmt.Errorf("failed to send reply to server request %v: %w", info.ID, err)
+	}
+	return nil
+}
+
+// marshalResult wraps result in a JSON-RPC response envelope.
+func marshalResult(id mcp.RequestID, result interface{}) ([]byte, error) {
+	resultBytes, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(mcp.RPCResponse{JSONRPC: mcp.JSONRPCVersion, Result: resultBytes, ID: id})
+}
@@ -0,0 +1,236 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/mcptest"
+	"sqirvy/mcp/pkg/transport"
+)
+
+func TestClientAnswersServerRequests(t *testing.T) {
+	srv := mcptest.NewServer(t)
+	srv.ExpectInitialize()
+	srv.Expect(mcp.MethodListTools).Respond(mcp.ListToolsResult{Tools: []mcp.Tool{}})
+
+	conn := srv.Conn()
+	c := NewClient(transport.NewStream(conn, conn), log.New(io.Discard, "", 0))
+	c.HandleRequest(mcp.MethodListRoots, func(params json.RawMessage) (interface{}, *mcp.RPCError) {
+		return map[string]interface{}{"roots": []map[string]string{{"uri": "file:///work"}}}, nil
+	})
+
+	if _, err := c.Initialize(); err != nil {
+		t.Fatalf("initialize failed: %v", err)
+	}
+
+	// Queued ahead of the tools/list response, so the client must handle them while waiting for it
+	if err := srv.Request("s1", mcp.MethodPing, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Notify("notifications/message", map[string]string{"level": "info"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Request("s2", mcp.MethodListRoots, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Request("s3", mcp.MethodCreateMessage, map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.listTools(); err != nil {
+		t.Fatalf("listTools failed: %v", err)
+	}
+
+	ping, ok := srv.WaitForResponse("s1", time.Second)
+	if !ok || ping.Error != nil || string(ping.Result) != "{}" {
+		t.Errorf("ping reply = %+v (found %v), want empty result", ping, ok)
+	}
+	roots, ok := srv.WaitForResponse("s2", time.Second)
+	if !ok || roots.Error != nil || string(roots.Result) != `{"roots":[{"uri":"file:///work"}]}` {
+		t.Errorf("roots/list reply = %+v (found %v)", roots, ok)
+	}
+	sampling, ok := srv.WaitForResponse("s3", time.Second)
+	if !ok || sampling.Error == nil || sampling.Error.Code != mcp.ErrorCodeMethodNotFound {
+		t.Errorf("sampling reply = %+v (found %v), want MethodNotFound", sampling, ok)
+	}
+}
+
+func TestClientSamplingHandler(t *testing.T) {
+	srv := mcptest.NewServer(t)
+	srv.ExpectInitialize().WithParams(func(params json.RawMessage) bool {
+		var p mcp.InitializeParams
+		return json.Unmarshal(params, &p) == nil && p.Capabilities.Sampling != nil
+	})
+	srv.Expect(mcp.MethodListTools).Respond(mcp.ListToolsResult{Tools: []mcp.Tool{}})
+
+	conn := srv.Conn()
+	c := NewClient(transport.NewStream(conn, conn), log.New(io.Discard, "", 0))
+	c.EnableSampling(func(params mcp.CreateMessageParams) (mcp.CreateMessageResult, error) {
+		var text mcp.TextContent
+		json.Unmarshal(params.Messages[0].Content, &text)
+		content, _ := json.Marshal(mcp.TextContent{Type: "text", Text: "echo: " + text.Text})
+		return mcp.CreateMessageResult{Role: mcp.RoleAssistant, Content: content, Model: "echo"}, nil
+	})
+	if _, err := c.Initialize(); err != nil {
+		t.Fatalf("initialize failed: %v", err)
+	}
+
+	params := mcp.CreateMessageParams{Messages: []mcp.SamplingMessage{mcp.NewTextSamplingMessage(mcp.RoleUser, "hi")}, MaxTokens: 10}
+	if err := srv.Request("s1", mcp.MethodCreateMessage, params); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Request("s2", mcp.MethodCreateMessage, mcp.CreateMessageParams{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.listTools(); err != nil {
+		t.Fatalf("listTools failed: %v", err)
+	}
+
+	reply, ok := srv.WaitForResponse("s1", time.Second)
+	var result mcp.CreateMessageResult
+	var text mcp.TextContent
+	json.Unmarshal(reply.Result, &result)
+	json.Unmarshal(result.Content, &text)
+	if !ok || reply.Error != nil || result.Model != "echo" || text.Text != "echo: hi" {
+		t.Errorf("sampling reply = %+v (found %v)", reply, ok)
+	}
+	if reply, ok := srv.WaitForResponse("s2", time.Second); !ok || reply.Error == nil || reply.Error.Code != mcp.ErrorCodeInvalidParams {
+		t.Errorf("sampling without messages = %+v (found %v), want invalid params", reply, ok)
+	}
+	srv.AssertExpectations()
+}
+
+func TestClientSkipsOrphanResponses(t *testing.T) {
+	srv := mcptest.NewServer(t)
+	srv.ExpectInitialize()
+	srv.Expect(mcp.MethodListTools).Respond(mcp.ListToolsResult{Tools: []mcp.Tool{}})
+
+	conn := srv.Conn()
+	c := NewClient(transport.NewStream(conn, conn), log.New(io.Discard, "", 0))
+	var orphans []string
+	c.OnOrphanResponse(func(info mcp.MessageInfo, payload []byte) {
+		orphans = append(orphans, fmt.Sprintf("%v", info.ID))
+	})
+
+	if _, err := c.Initialize(); err != nil {
+		t.Fatalf("initialize failed: %v", err)
+	}
+
+	// A late reply to the initialize request and one nobody asked for, both queued ahead of tools/list
+	if err := srv.Respond(1, map[string]string{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Respond("stray", map[string]string{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.listTools(); err != nil {
+		t.Fatalf("listTools failed: %v", err)
+	}
+	if want := []string{"1", "stray"}; fmt.Sprint(orphans) != fmt.Sprint(want) {
+		t.Errorf("orphans = %v, want %v", orphans, want)
+	}
+}
+
+func TestClientDeliversNotificationsDuringCalls(t *testing.T) {
+	srv := mcptest.NewServer(t)
+	srv.ExpectInitialize()
+	srv.Expect(mcp.MethodListTools).Respond(mcp.ListToolsResult{Tools: []mcp.Tool{}})
+
+	conn := srv.Conn()
+	c := NewClient(transport.NewStream(conn, conn), log.New(io.Discard, "", 0))
+	var got []string
+	c.HandleNotification("notifications/message", func(params json.RawMessage) {
+		got = append(got, string(params))
+	})
+
+	if _, err := c.Initialize(); err != nil {
+		t.Fatalf("initialize failed: %v", err)
+	}
+
+	// Queued ahead of the tools/list response; the one without a handler is just logged
+	if err := srv.Notify("notifications/message", map[string]string{"data": "one"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Notify("notifications/progress", map[string]interface{}{"progressToken": "t", "progress": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Notify("notifications/message", map[string]string{"data": "two"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.listTools(); err != nil {
+		t.Fatalf("listTools failed: %v", err)
+	}
+	if want := []string{`{"data":"one"}`, `{"data":"two"}`}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("notifications = %v, want %v", got, want)
+	}
+}
+
+func TestClientHooks(t *testing.T) {
+	srv := mcptest.NewServer(t)
+	srv.ExpectInitialize()
+	srv.Expect(mcp.MethodListPrompts).RespondError(mcp.NewRPCError(mcp.ErrorCodeInternalError, "no prompts", nil))
+
+	var events []string
+	conn := srv.Conn()
+	c := NewClient(transport.NewStream(conn, conn), log.New(io.Discard, "", 0), mcp.Hooks{
+		OnInitialize: func(_ context.Context, params mcp.InitializeParams) *mcp.RPCError {
+			events = append(events, "initialize "+params.ClientInfo.Name)
+			return nil
+		},
+		OnInitialized: func(context.Context) { events = append(events, "initialized") },
+		OnRequest: func(ctx context.Context, method string, _ json.RawMessage) *mcp.RPCError {
+			id, _ := mcp.RequestIDFromContext(ctx)
+			events = append(events, fmt.Sprintf("request %s id=%v", method, id))
+			if method == mcp.MethodListTools {
+				return mcp.NewRPCError(mcp.ErrorCodeInvalidRequest, "tools are off", nil)
+			}
+			return nil
+		},
+		OnResponse: func(_ context.Context, method string, _ []byte, _ time.Duration) {
+			events = append(events, "response "+method)
+		},
+		OnNotification: func(_ context.Context, method string, _ json.RawMessage) {
+			events = append(events, "notification "+method)
+		},
+		OnError: func(_ context.Context, method string, err *mcp.RPCError) {
+			events = append(events, fmt.Sprintf("error %s %s", method, err.Message))
+		},
+	})
+
+	if _, err := c.Initialize(); err != nil {
+		t.Fatalf("initialize failed: %v", err)
+	}
+	if _, err := c.listTools(); err == nil || !strings.Contains(err.Error(), "tools are off") {
+		t.Errorf("listTools error = %v, want the hook's error", err)
+	}
+	if err := srv.Notify("notifications/message", map[string]string{"level": "info"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.listPrompts(); err == nil {
+		t.Error("listPrompts succeeded, want the server's error")
+	}
+
+	want := []string{
+		"initialize " + clientName,
+		"request initialize id=1",
+		"response initialize",
+		"initialized",
+		"request tools/list id=2",
+		"request prompts/list id=3",
+		"notification notifications/message",
+		"response prompts/list",
+		"error prompts/list no prompts",
+	}
+	if got := strings.Join(events, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("events:\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,136 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+
+	"sqirvy/mcp/pkg/mcp"
+)
+
+// samplingTimeout bounds one completion requested by the server.
+const samplingTimeout = 90 * time.Second
+
+// Sampler runs an LLM completion for a sampling/createMessage request.
+type Sampler func(params mcp.CreateMessageParams) (mcp.CreateMessageResult, error)
+
+// EnableSampling answers sampling/createMessage requests with sampler. The
+// client then announces the sampling capability when it initializes.
+func (c *Client) EnableSampling(sampler Sampler) {
+	c.HandleRequest(mcp.MethodCreateMessage, func(raw json.RawMessage) (interface{}, *mcp.RPCError) {
+		var params mcp.CreateMessageParams
+		if err := json.Unmarshal(raw, &params); err != nil || len(params.Messages) == 0 {
+			return nil, mcp.NewRPCError(mcp.ErrorCodeInvalidParams, "sampling/createMessage needs messages", nil)
+		}
+		c.logger.Printf("Server requested a completion of %d message(s), up to %d tokens", len(params.Messages), params.MaxTokens)
+		result, err := sampler(params)
+		if err != nil {
+			c.logger.Printf("Sampling failed: %v", err)
+			return nil, mcp.NewRPCError(mcp.ErrorCodeInternalError, err.Error(), nil)
+		}
+		return result, nil
+	})
+}
+
+// supportsSampling reports whether a sampling handler is registered.
+func (c *Client) supportsSampling() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.handlers[mcp.MethodCreateMessage]
+	return ok
+}
+
+// anthropicStopReasons maps the API's stop reasons to the MCP names.
+var anthropicStopReasons = map[anthropic.MessageStopReason]string{
+	anthropic.MessageStopReasonEndTurn:      "endTurn",
+	anthropic.MessageStopReasonMaxTokens:    "maxTokens",
+	anthropic.MessageStopReasonStopSequence: "stopSequence",
+}
+
+// AnthropicSampler returns a Sampler that completes text conversations with
+// the given Anthropic model. The server's model preferences are ignored.
+func AnthropicSampler(client *anthropic.Client, model string) Sampler {
+	return func(params mcp.CreateMessageParams) (mcp.CreateMessageResult, error) {
+		request := anthropic.MessageNewParams{
+			Model:         anthropic.Model(model),
+			MaxTokens:     int64(params.MaxTokens),
+			StopSequences: params.StopSequences,
+		}
+		if params.SystemPrompt != "" {
+			request.System = []anthropic.TextBlockParam{{Text: params.SystemPrompt}}
+		}
+		if params.Temperature != nil {
+			request.Temperature = anthropic.Float(*params.Temperature)
+		}
+		// Content meant only for the user is not sent to the model
+		for _, m := range mcp.FilterSamplingMessages(params.Messages, mcp.RoleAssistant) {
+			var content mcp.TextContent
+			if err := json.Unmarshal(m.Content, &content); err != nil || content.Type != "text" {
+				return mcp.CreateMessageResult{}, fmt.Errorf("only text messages are supported")
+			}
+			block := anthropic.NewTextBlock(content.Text)
+			if m.Role == mcp.RoleAssistant {
+				request.Messages = append(request.Messages, anthropic.NewAssistantMessage(block))
+			} else {
+				request.Messages = append(request.Messages, anthropic.NewUserMessage(block))
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), samplingTimeout)
+		defer cancel()
+		message, err := client.Messages.New(ctx, request)
+		if err != nil {
+			return mcp.CreateMessageResult{}, fmt.Errorf("failed to create message: %w", err)
+		}
+		var text strings.Builder
+		for _, block := range message.Content {
+			text.WriteString(block.Text)
+		}
+		content, _ := json.Marshal(mcp.TextContent{Type: "text", Text: text.String()})
+		stopReason, ok := anthropicStopReasons[message.StopReason]
+		if !ok {
+			stopReason = string(message.StopReason)
+		}
+		return mcp.CreateMessageResult{Role: mcp.RoleAssistant, Content: content, Model: string(message.Model), StopReason: stopReason}, nil
+	}
+}
+
+// callSummarizeTool asks the server to summarize uri. The server samples the
+// summary through this client, so the request is answered while waiting.
+func (c *Client) callSummarizeTool(uri string) error {
+	id := c.nextID()
+	request, err := mcp.MarshalCallToolRequest(id, mcp.CallToolParams{Name: "summarize", Arguments: map[string]interface{}{"uri": uri}})
+	if err != nil {
+		return fmt.Errorf("failed to marshal summarize request: %w", err)
+	}
+	c.logger.Printf("Sending summarize tool request for %s...", uri)
+	if err := c.sendRequest(request); err != nil {
+		return fmt.Errorf("failed to send summarize request: %w", err)
+	}
+	response, err := c.readResponse(id)
+	if err != nil {
+		return fmt.Errorf("failed to read summarize response: %w", err)
+	}
+	result, _, rpcErr, parseErr := mcp.UnmarshalCallToolResponse(response)
+	switch {
+	case parseErr != nil:
+		return fmt.Errorf("failed to parse summarize response: %w", parseErr)
+	case rpcErr != nil:
+		return fmt.Errorf("received RPC error in summarize response: %w", rpcErr)
+	case result == nil || len(result.Content) == 0:
+		return fmt.Errorf("summarize response contained no content")
+	}
+	c.expandResourceLinks(result)
+	var text mcp.TextContent
+	json.Unmarshal(result.Content[0], &text)
+	if result.IsError {
+		c.logger.Printf("summarize tool reported an error: %s", text.Text)
+		return nil
+	}
+	c.logger.Printf("Summary of %s:\n%s", uri, text.Text)
+	return nil
+}
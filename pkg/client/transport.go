@@ -1,4 +1,4 @@
-package main
+package client
 
 import (
 	"bufio"
@@ -8,6 +8,8 @@ import (
 	"log"
 	"os/exec"
 	"sync"
+
+	"sqirvy/mcp/pkg/transport"
 )
 
 // StdioTransport manages communication with a server subprocess over stdio.
@@ -16,14 +18,36 @@ type StdioTransport struct {
 	stdin  io.WriteCloser
 	stdout io.ReadCloser
 	reader *bufio.Reader
-	writer io.Writer // Embed the writer for direct use
+	writer io.Writer           // Embed the writer for direct use
+	framed transport.Transport // Frames the messages for framings other than newline, nil for newline
 	logger *log.Logger
 	mu     sync.Mutex // Protects writer access
 }
 
 // NewStdioTransport creates and starts a new server subprocess and establishes stdio pipes.
-func NewStdioTransport(serverPath, serverLog string, logger *log.Logger) (*StdioTransport, error) {
-	cmd := exec.Command(serverPath, "--log", serverLog)
+// The server is started with -framing unless the framing is newline, the default.
+func NewStdioTransport(serverPath, serverLog string, framing transport.Framing, logger *log.Logger) (*StdioTransport, error) {
+	args := []string{"--log", serverLog}
+	if framing != transport.FramingNewline {
+		args = append(args, "-framing", framing.String())
+	}
+	t, err := newStdioTransportCmd(exec.Command(serverPath, args...), logger)
+	if err != nil {
+		return nil, err
+	}
+	if framing != transport.FramingNewline {
+		if t.framed, err = transport.NewFramed(t.stdout, t.stdin, framing); err != nil {
+			t.Close()
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// newStdioTransportCmd starts an arbitrary server command (e.g. a reference server launched via npx)
+// and establishes stdio pipes to it. The command must not have been started yet.
+func newStdioTransportCmd(cmd *exec.Cmd, logger *log.Logger) (*StdioTransport, error) {
+	serverPath := cmd.Path
 
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
@@ -63,6 +87,9 @@ func (t *StdioTransport) WriteMessage(payload []byte) error {
 
 	t.logger.Printf("Send    : %s", string(payload)) // Log the message being sent
 
+	if t.framed != nil {
+		return t.framed.WriteMessage(payload)
+	}
 	if _, err := t.writer.Write(payload); err != nil {
 		return fmt.Errorf("failed to write message payload: %w", err)
 	}
@@ -76,6 +103,15 @@ func (t *StdioTransport) WriteMessage(payload []byte) error {
 
 // ReadMessage reads a single JSON message (a line ending in newline) from the server's stdout.
 func (t *StdioTransport) ReadMessage() ([]byte, error) {
+	if t.framed != nil {
+		payload, err := t.framed.ReadMessage()
+		if err != nil {
+			t.logger.Printf("Read Error: %v", err)
+			return nil, err
+		}
+		t.logger.Printf("Receive : %s", string(payload))
+		return payload, nil
+	}
 	// ReadBytes includes the delimiter, so we need to trim it later if needed.
 	payload, err := t.reader.ReadBytes('\n')
 	if err != nil {
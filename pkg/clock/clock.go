@@ -0,0 +1,172 @@
+// Package clock abstracts the passage of time, so that timeouts, timers and
+// caches can be tested deterministically: production code uses Real, tests a
+// Fake clock that only moves when told to.
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock tells the time and makes timers.
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	// NewTimer returns a timer that sends the time on its channel after d.
+	NewTimer(d time.Duration) Timer
+	// AfterFunc returns a timer that calls f after d. Its channel is nil.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is the part of *time.Timer a Clock provides.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// Real is the system clock.
+var Real Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                  { return time.Now() }
+func (realClock) Since(t time.Time) time.Duration { return time.Since(t) }
+func (realClock) NewTimer(d time.Duration) Timer  { return realTimer{time.NewTimer(d)} }
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return realTimer{time.AfterFunc(d, f)}
+}
+
+type realTimer struct{ t *time.Timer }
+
+func (r realTimer) C() <-chan time.Time        { return r.t.C }
+func (r realTimer) Stop() bool                 { return r.t.Stop() }
+func (r realTimer) Reset(d time.Duration) bool { return r.t.Reset(d) }
+
+// Fake is a Clock whose time only changes with Advance and Set. Timers fire
+// during the call that moves the time past their deadline, in deadline order;
+// AfterFunc functions run on that goroutine before it returns.
+type Fake struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer // Active timers
+}
+
+// NewFake returns a fake clock set to now.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+// Now returns the fake time.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Since returns the fake time elapsed since t.
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+// NewTimer returns a timer that fires once the fake time reaches now+d.
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	t := &fakeTimer{clock: f, c: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+// AfterFunc returns a timer that calls fn once the fake time reaches now+d.
+func (f *Fake) AfterFunc(d time.Duration, fn func()) Timer {
+	t := &fakeTimer{clock: f, fn: fn}
+	t.Reset(d)
+	return t
+}
+
+// Advance moves the time forward by d and fires the timers that are due.
+func (f *Fake) Advance(d time.Duration) {
+	f.Set(f.Now().Add(d))
+}
+
+// Set moves the time to now, which must not be before the current fake time,
+// and fires the timers that are due.
+func (f *Fake) Set(now time.Time) {
+	for {
+		f.mu.Lock()
+		if len(f.timers) == 0 || f.timers[0].deadline.After(now) {
+			if now.After(f.now) {
+				f.now = now
+			}
+			f.mu.Unlock()
+			return
+		}
+		// Fire the earliest timer at its own deadline, so that what it does sees
+		// the time it was due and timers it sets are ordered with the rest
+		t := f.timers[0]
+		f.timers = f.timers[1:]
+		if t.deadline.After(f.now) {
+			f.now = t.deadline
+		}
+		fired := f.now
+		f.mu.Unlock()
+		if t.fn != nil {
+			t.fn()
+		} else {
+			select {
+			case t.c <- fired:
+			default: // Like a real timer, an unread tick is not repeated
+			}
+		}
+	}
+}
+
+// Timers returns the number of timers that have not fired or been stopped,
+// so a test can wait for the code under test to start one.
+func (f *Fake) Timers() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.timers)
+}
+
+type fakeTimer struct {
+	clock    *Fake
+	c        chan time.Time
+	fn       func()
+	deadline time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+// Stop removes the timer, reporting whether it was active.
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	return t.clock.remove(t)
+}
+
+// Reset sets the timer to fire d after the current fake time, reporting
+// whether it was active. A timer reset to d <= 0 fires on the next Advance or Set.
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	f := t.clock
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	active := f.remove(t)
+	t.deadline = f.now.Add(d)
+	i := sort.Search(len(f.timers), func(i int) bool { return f.timers[i].deadline.After(t.deadline) })
+	f.timers = append(f.timers, nil)
+	copy(f.timers[i+1:], f.timers[i:])
+	f.timers[i] = t
+	return active
+}
+
+// remove drops t from the active timers. The caller holds f.mu.
+func (f *Fake) remove(t *fakeTimer) bool {
+	for i, other := range f.timers {
+		if other == t {
+			f.timers = append(f.timers[:i], f.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
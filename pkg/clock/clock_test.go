@@ -0,0 +1,80 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeTimers(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	f := NewFake(start)
+	var fired []string
+	late := f.AfterFunc(3*time.Second, func() { fired = append(fired, "late at "+f.Since(start).String()) })
+	f.AfterFunc(time.Second, func() {
+		fired = append(fired, "early at "+f.Since(start).String())
+		// A timer set while firing is ordered with the others
+		f.AfterFunc(time.Second, func() { fired = append(fired, "chained at "+f.Since(start).String()) })
+	})
+	timer := f.NewTimer(2 * time.Second)
+	if f.Timers() != 3 {
+		t.Fatalf("Timers() = %d, want 3", f.Timers())
+	}
+
+	f.Advance(1500 * time.Millisecond)
+	if len(fired) != 1 || fired[0] != "early at 1s" {
+		t.Fatalf("after 1.5s fired %v", fired)
+	}
+	if got := f.Since(start); got != 1500*time.Millisecond {
+		t.Errorf("Since = %v, want 1.5s", got)
+	}
+	select {
+	case <-timer.C():
+		t.Fatal("timer fired early")
+	default:
+	}
+
+	f.Advance(time.Second)
+	if len(fired) != 2 || fired[1] != "chained at 2s" {
+		t.Errorf("after 2.5s fired %v", fired)
+	}
+	select {
+	case at := <-timer.C():
+		if !at.Equal(start.Add(2 * time.Second)) {
+			t.Errorf("timer fired at %v", at)
+		}
+	default:
+		t.Error("timer did not fire")
+	}
+
+	if !late.Stop() || late.Stop() {
+		t.Error("Stop should report the timer active once")
+	}
+	f.Advance(time.Hour)
+	if len(fired) != 2 {
+		t.Errorf("stopped timer fired: %v", fired)
+	}
+
+	if timer.Reset(time.Minute) {
+		t.Error("Reset of a fired timer reported it active")
+	}
+	f.Advance(time.Minute)
+	select {
+	case <-timer.C():
+	default:
+		t.Error("reset timer did not fire")
+	}
+	if f.Timers() != 0 {
+		t.Errorf("Timers() = %d after all fired", f.Timers())
+	}
+}
+
+func TestRealClock(t *testing.T) {
+	timer := Real.NewTimer(time.Millisecond)
+	<-timer.C()
+	done := make(chan struct{})
+	Real.AfterFunc(time.Millisecond, func() { close(done) })
+	<-done
+	if Real.Since(Real.Now()) < 0 {
+		t.Error("Since went backwards")
+	}
+}
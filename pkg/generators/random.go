@@ -0,0 +1,127 @@
+// Package generators produces random data for resources and tools, such as
+// the data://random_data resource of mcp-server. Other servers can import it
+// to offer the same data.
+package generators
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	mathrand "math/rand/v2"
+	"sync"
+)
+
+// Alphabets to draw characters from.
+const (
+	Alphanumeric = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	Letters      = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	Digits       = "0123456789"
+	Hex          = "0123456789abcdef"
+	// Base64URL is the alphabet of unpadded URL-safe base64, RFC 4648.
+	Base64URL = Alphanumeric + "-_"
+)
+
+// DefaultMaxLength is the longest string a generator makes unless told otherwise.
+const DefaultMaxLength = 1024
+
+// Errors returned by String for a bad length, wrapped with the details.
+var (
+	ErrLengthNotPositive = errors.New("length must be positive")
+	ErrLengthTooLong     = errors.New("exceeds maximum allowed length")
+)
+
+// Options configures a Generator. The zero value generates cryptographically
+// secure alphanumeric strings of up to DefaultMaxLength characters.
+type Options struct {
+	// Alphabet holds the characters to draw from, each equally likely. It must
+	// not repeat a character. Empty means Alphanumeric.
+	Alphabet string
+	// MaxLength is the longest string String makes; 0 means DefaultMaxLength.
+	MaxLength int
+	// Seed, if non-zero, makes the generator deterministic: the same seed gives
+	// the same strings. Meant for tests; seeded output is not secret.
+	Seed uint64
+}
+
+// Generator makes random strings over an alphabet. It is safe for concurrent use.
+type Generator struct {
+	alphabet  string
+	maxLength int
+
+	mu     sync.Mutex // Serializes reads from source; a seeded one is not safe for concurrent use
+	source io.Reader
+}
+
+// New returns a generator configured by opts.
+func New(opts Options) (*Generator, error) {
+	g := &Generator{alphabet: opts.Alphabet, maxLength: opts.MaxLength, source: rand.Reader}
+	if g.alphabet == "" {
+		g.alphabet = Alphanumeric
+	}
+	var seen [256]bool
+	for i := 0; i < len(g.alphabet); i++ {
+		if seen[g.alphabet[i]] {
+			return nil, fmt.Errorf("alphabet repeats %q", g.alphabet[i])
+		}
+		seen[g.alphabet[i]] = true
+	}
+	if g.maxLength == 0 {
+		g.maxLength = DefaultMaxLength
+	}
+	if g.maxLength < 0 {
+		return nil, fmt.Errorf("maximum length %d is negative", g.maxLength)
+	}
+	if opts.Seed != 0 {
+		var seed [32]byte
+		binary.LittleEndian.PutUint64(seed[:], opts.Seed)
+		g.source = mathrand.NewChaCha8(seed)
+	}
+	return g, nil
+}
+
+// defaultGenerator backs RandomData.
+var defaultGenerator, _ = New(Options{})
+
+// RandomData returns a cryptographically secure random string of length
+// alphanumeric characters (a-z, A-Z, 0-9), at most DefaultMaxLength.
+func RandomData(length int) (string, error) {
+	return defaultGenerator.String(length)
+}
+
+// String returns a random string of length characters from the alphabet.
+// It returns an error wrapping ErrLengthNotPositive or ErrLengthTooLong for a
+// bad length, or the error of the random source.
+func (g *Generator) String(length int) (string, error) {
+	if length <= 0 {
+		return "", ErrLengthNotPositive
+	}
+	if length > g.maxLength {
+		return "", fmt.Errorf("requested length %d %w %d", length, ErrLengthTooLong, g.maxLength)
+	}
+
+	// Rejection sampling: a random byte below limit, the largest multiple of
+	// the alphabet size that fits in a byte, maps onto the alphabet without
+	// bias; bytes at or above it are drawn again
+	n := len(g.alphabet)
+	limit := 256 - 256%n
+	result := make([]byte, 0, length)
+	buf := make([]byte, length+length/4+8) // Room for the expected rejections
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for len(result) < length {
+		if _, err := io.ReadFull(g.source, buf); err != nil {
+			return "", fmt.Errorf("failed to read random bytes: %w", err)
+		}
+		for _, b := range buf {
+			if int(b) < limit {
+				result = append(result, g.alphabet[int(b)%n])
+				if len(result) == length {
+					break
+				}
+			}
+		}
+	}
+	return string(result), nil
+}
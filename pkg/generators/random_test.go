@@ -0,0 +1,110 @@
+package generators
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRandomData(t *testing.T) {
+	s, err := RandomData(64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s) != 64 || strings.Trim(s, Alphanumeric) != "" {
+		t.Errorf("RandomData(64) = %q", s)
+	}
+	for length, want := range map[int]error{0: ErrLengthNotPositive, -1: ErrLengthNotPositive, DefaultMaxLength + 1: ErrLengthTooLong} {
+		if _, err := RandomData(length); !errors.Is(err, want) {
+			t.Errorf("RandomData(%d) error = %v, want %v", length, err, want)
+		}
+	}
+}
+
+func TestSourceFailure(t *testing.T) {
+	g, _ := New(Options{})
+	g.source = strings.NewReader("abc") // Runs out
+	if _, err := g.String(16); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("String with a failing source = %v", err)
+	}
+}
+
+// TestUniformDistribution checks with a chi-squared test that every character
+// is equally likely. An alphabet of 100 characters does not divide 256, so a
+// plain modulo would make the first 56 characters half again as likely.
+func TestUniformDistribution(t *testing.T) {
+	alphabet := make([]byte, 100)
+	for i := range alphabet {
+		alphabet[i] = byte(' ' + i)
+	}
+	g, err := New(Options{Alphabet: string(alphabet), MaxLength: 1 << 20, Seed: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const samples = 200000
+	s, err := g.String(samples)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := make(map[byte]int)
+	for i := 0; i < len(s); i++ {
+		counts[s[i]]++
+	}
+	expected := float64(samples) / float64(len(alphabet))
+	var chi2 float64
+	for _, c := range alphabet {
+		d := float64(counts[c]) - expected
+		chi2 += d * d / expected
+	}
+	// The 99.9th percentile of the chi-squared distribution with 99 degrees of freedom
+	if chi2 > 148.2 {
+		t.Errorf("chi-squared = %.1f over %d characters, the distribution is not uniform", chi2, len(alphabet))
+	}
+}
+
+func TestGeneratorOptions(t *testing.T) {
+	g, err := New(Options{Alphabet: Hex, MaxLength: 8, Seed: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := g.String(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Trim(s, Hex) != "" {
+		t.Errorf("String(8) = %q, want hex digits", s)
+	}
+	if _, err := g.String(9); err == nil {
+		t.Error("String(9) exceeded MaxLength 8")
+	}
+
+	// The same seed gives the same strings
+	again, _ := New(Options{Alphabet: Hex, MaxLength: 8, Seed: 42})
+	if s2, _ := again.String(8); s2 != s {
+		t.Errorf("seeded generators differ: %q and %q", s, s2)
+	}
+
+	for _, opts := range []Options{{Alphabet: "abca"}, {MaxLength: -1}} {
+		if _, err := New(opts); err == nil {
+			t.Errorf("New(%+v) succeeded", opts)
+		}
+	}
+}
+
+func BenchmarkRandomData(b *testing.B) {
+	for b.Loop() {
+		if _, err := RandomData(DefaultMaxLength); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSeeded(b *testing.B) {
+	g, _ := New(Options{Seed: 1})
+	for b.Loop() {
+		if _, err := g.String(DefaultMaxLength); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package index
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	chunkMaxChars = 1500 // Target chunk size; a chunk holds whole lines
+	chunkOverlap  = 2    // Lines repeated at the start of the next chunk
+)
+
+// span is a chunk of a file, lines Start through End (1-based, inclusive).
+type span struct {
+	Start, End int
+	Text       string
+}
+
+// splitChunks splits text into chunks of whole lines of about chunkMaxChars,
+// overlapping by chunkOverlap lines so that text at a boundary is found in
+// context. A single line longer than chunkMaxChars is truncated. Chunks holding
+// only white space are dropped.
+func splitChunks(text string) []span {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	var spans []span
+	for start := 0; start < len(lines); {
+		end, size := start, 0
+		for end < len(lines) && (end == start || size+len(lines[end]) <= chunkMaxChars) {
+			size += len(lines[end])
+			end++
+		}
+		chunk := strings.Join(lines[start:end], "")
+		if len(chunk) > chunkMaxChars {
+			chunk = truncateUTF8(chunk, chunkMaxChars)
+		}
+		if strings.TrimSpace(chunk) != "" {
+			spans = append(spans, span{Start: start + 1, End: end, Text: chunk})
+		}
+		if end == len(lines) {
+			break
+		}
+		start = max(end-chunkOverlap, start+1)
+	}
+	return spans
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a character.
+func truncateUTF8(s string, n int) string {
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// isText reports whether data looks like UTF-8 text rather than a binary file.
+func isText(data []byte) bool {
+	head := data[:min(len(data), 8000)]
+	for _, b := range head {
+		if b == 0 {
+			return false
+		}
+	}
+	return utf8.Valid(data)
+}
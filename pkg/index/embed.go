@@ -0,0 +1,191 @@
+package index
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Embedder turns texts into vectors for similarity search. All vectors of one
+// embedder have the same length and are normalized to unit length, so their dot
+// product is the cosine similarity.
+type Embedder interface {
+	// Name identifies the provider and model. An index built with a different
+	// embedder is discarded and rebuilt.
+	Name() string
+	// Embed returns one vector per text.
+	Embed(texts []string) ([][]float32, error)
+}
+
+// DefaultHashDims is the vector length of the hash embedder.
+const DefaultHashDims = 512
+
+// NewEmbedder returns the embedder named by spec: "hash" for the built-in
+// offline embedder, or "openai:<model>" for an OpenAI-compatible /embeddings
+// endpoint at baseURL (default https://api.openai.com/v1; Ollama and most
+// self-hosted servers offer the same API).
+func NewEmbedder(spec, baseURL, apiKey string, timeout time.Duration) (Embedder, error) {
+	provider, model, _ := strings.Cut(spec, ":")
+	switch provider {
+	case "hash":
+		return HashEmbedder{Dims: DefaultHashDims}, nil
+	case "openai":
+		if model == "" {
+			return nil, fmt.Errorf("embeddings spec %q needs a model, e.g. openai:text-embedding-3-small", spec)
+		}
+		if baseURL == "" {
+			baseURL = "https://api.openai.com/v1"
+		}
+		return &OpenAIEmbedder{http: &http.Client{Timeout: timeout}, baseURL: strings.TrimSuffix(baseURL, "/"), model: model, apiKey: apiKey}, nil
+	}
+	return nil, fmt.Errorf("unknown embeddings provider %q (want hash or openai:<model>)", provider)
+}
+
+// HashEmbedder is a lexical embedder that needs no model: words and word pairs
+// are hashed into a fixed number of dimensions (the "hashing trick"). It finds
+// chunks sharing vocabulary with the query, not paraphrases.
+type HashEmbedder struct {
+	Dims int
+}
+
+// Name implements Embedder.
+func (h HashEmbedder) Name() string { return fmt.Sprintf("hash-%d", h.Dims) }
+
+// Embed implements Embedder.
+func (h HashEmbedder) Embed(texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		v := make([]float32, h.Dims)
+		words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+		})
+		for j, w := range words {
+			h.add(v, w, 1)
+			if j > 0 {
+				h.add(v, words[j-1]+" "+w, 0.5)
+			}
+		}
+		vectors[i] = normalize(v)
+	}
+	return vectors, nil
+}
+
+// add adds weight to the dimension feature hashes to, with a sign from the
+// hash so that collisions tend to cancel out.
+func (h HashEmbedder) add(v []float32, feature string, weight float32) {
+	f := fnv.New64a()
+	f.Write([]byte(feature))
+	sum := f.Sum64()
+	if sum>>63 == 1 {
+		weight = -weight
+	}
+	v[sum%uint64(len(v))] += weight
+}
+
+// OpenAIEmbedder calls an OpenAI-compatible embeddings API.
+type OpenAIEmbedder struct {
+	http    *http.Client
+	baseURL string
+	model   string
+	apiKey  string
+}
+
+// openAIBatchSize is the number of texts sent per request.
+const openAIBatchSize = 64
+
+// Name implements Embedder.
+func (o *OpenAIEmbedder) Name() string { return "openai:" + o.model }
+
+// Embed implements Embedder.
+func (o *OpenAIEmbedder) Embed(texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += openAIBatchSize {
+		batch := texts[start:min(start+openAIBatchSize, len(texts))]
+		embedded, err := o.embedBatch(batch)
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, embedded...)
+	}
+	return vectors, nil
+}
+
+func (o *OpenAIEmbedder) embedBatch(texts []string) ([][]float32, error) {
+	body, _ := json.Marshal(map[string]interface{}{"model": o.model, "input": texts})
+	req, err := http.NewRequest(http.MethodPost, o.baseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+	resp, err := o.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("embeddings API unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embeddings response: %w", err)
+	}
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil || resp.StatusCode/100 != 2 {
+		if result.Error != nil && result.Error.Message != "" {
+			return nil, fmt.Errorf("embeddings API: %s", result.Error.Message)
+		}
+		return nil, fmt.Errorf("embeddings API: %s", resp.Status)
+	}
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("embeddings API returned %d vectors for %d texts", len(result.Data), len(texts))
+	}
+	vectors := make([][]float32, len(texts))
+	for _, d := range result.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("embeddings API returned index %d out of range", d.Index)
+		}
+		vectors[d.Index] = normalize(d.Embedding)
+	}
+	return vectors, nil
+}
+
+// normalize scales v to unit length in place and returns it.
+func normalize(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+	scale := float32(1 / math.Sqrt(sum))
+	for i := range v {
+		v[i] *= scale
+	}
+	return v
+}
+
+// Similarity returns the cosine similarity of two vectors of one embedder,
+// which is their dot product since embedders normalize them.
+func Similarity(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
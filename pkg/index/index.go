@@ -0,0 +1,299 @@
+// Package index maintains a semantic search index over the text files under a
+// directory. Files are split into chunks of lines, each chunk is embedded into
+// a vector, and the vectors are kept in a local file so that only changed files
+// are embedded again on the next refresh.
+package index
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	storeVersion = 1
+	// MaxFileSize is the largest file that is indexed; larger files are skipped.
+	MaxFileSize = 1 << 20
+	// MaxFiles is the most files indexed under one root.
+	MaxFiles = 20000
+)
+
+// skipDirs are directory names never descended into, besides hidden ones.
+var skipDirs = map[string]bool{"node_modules": true, "vendor": true, "__pycache__": true}
+
+// chunk is one embedded chunk of a file.
+type chunk struct {
+	Start, End int
+	Text       string
+	Vector     []float32
+}
+
+// fileEntry is the indexed state of one file; a file whose size or
+// modification time changed is embedded again.
+type fileEntry struct {
+	Size    int64
+	ModTime time.Time
+	Chunks  []chunk
+}
+
+// store is the on-disk form of the index.
+type store struct {
+	Version  int
+	Embedder string
+	Files    map[string]*fileEntry // Keyed by slash-separated path relative to the root
+}
+
+// Index is a semantic search index over the files under a root directory. It is
+// safe for concurrent use; searches see the previous state while a refresh runs.
+type Index struct {
+	root     string
+	path     string // Store file; empty keeps the index in memory only
+	embedder Embedder
+
+	refreshMu   sync.Mutex // Serializes refreshes
+	mu          sync.RWMutex
+	files       map[string]*fileEntry
+	lastRefresh time.Time
+}
+
+// Result is one chunk found by Search.
+type Result struct {
+	URI       string  `json:"uri"` // file:// URI relative to the root, as read by resources/read
+	Path      string  `json:"path"`
+	StartLine int     `json:"startLine"`
+	EndLine   int     `json:"endLine"`
+	Score     float64 `json:"score"` // Cosine similarity to the query, at most 1
+	Text      string  `json:"text"`
+}
+
+// RefreshStats describes what a refresh did.
+type RefreshStats struct {
+	Files    int // Files in the index
+	Chunks   int // Chunks in the index
+	Embedded int // Files embedded because they were new or changed
+	Removed  int // Files dropped because they no longer exist
+	Skipped  int // Files not indexed: binary, too large or unreadable
+}
+
+// Open returns the index of root stored at storePath, loading what an earlier
+// run stored there. A missing store, or one built with another embedder, gives
+// an empty index. Call Refresh to bring it up to date.
+func Open(root, storePath string, embedder Embedder) (*Index, error) {
+	ix := &Index{root: filepath.Clean(root), path: storePath, embedder: embedder, files: make(map[string]*fileEntry)}
+	if storePath == "" {
+		return ix, nil
+	}
+	f, err := os.Open(storePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return ix, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open index: %w", err)
+	}
+	defer f.Close()
+	var st store
+	if err := gob.NewDecoder(f).Decode(&st); err != nil {
+		return nil, fmt.Errorf("failed to read index %s: %w (delete it to rebuild)", storePath, err)
+	}
+	if st.Version == storeVersion && st.Embedder == embedder.Name() && st.Files != nil {
+		ix.files = st.Files
+	}
+	return ix, nil
+}
+
+// Embedder returns the embedder of the index.
+func (ix *Index) Embedder() Embedder { return ix.embedder }
+
+// Stats returns the number of files and chunks in the index and the time of the
+// last refresh (zero before the first).
+func (ix *Index) Stats() (files, chunks int, lastRefresh time.Time) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	for _, e := range ix.files {
+		chunks += len(e.Chunks)
+	}
+	return len(ix.files), chunks, ix.lastRefresh
+}
+
+// Refresh walks the root, embeds new and changed files, drops deleted ones and
+// saves the index. If embedding fails the index is left as it was.
+func (ix *Index) Refresh() (RefreshStats, error) {
+	ix.refreshMu.Lock()
+	defer ix.refreshMu.Unlock()
+	ix.mu.RLock()
+	old := ix.files
+	ix.mu.RUnlock()
+
+	var stats RefreshStats
+	files := make(map[string]*fileEntry)
+	err := filepath.WalkDir(ix.root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == ix.root {
+				return err
+			}
+			stats.Skipped++
+			return nil
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if p != ix.root && (strings.HasPrefix(name, ".") || skipDirs[name]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || strings.HasPrefix(name, ".") {
+			return nil
+		}
+		if len(files) >= MaxFiles {
+			stats.Skipped++
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() > MaxFileSize {
+			stats.Skipped++
+			return nil
+		}
+		rel, err := filepath.Rel(ix.root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if e, ok := old[rel]; ok && e.Size == info.Size() && e.ModTime.Equal(info.ModTime()) {
+			files[rel] = e
+			return nil
+		}
+		entry, err := ix.embedFile(p, info)
+		if err != nil {
+			return err
+		}
+		if entry == nil {
+			stats.Skipped++
+			return nil
+		}
+		files[rel] = entry
+		stats.Embedded++
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+	for rel := range old {
+		if _, ok := files[rel]; !ok {
+			stats.Removed++
+		}
+	}
+	stats.Files = len(files)
+	for _, e := range files {
+		stats.Chunks += len(e.Chunks)
+	}
+
+	ix.mu.Lock()
+	ix.files = files
+	ix.lastRefresh = time.Now()
+	ix.mu.Unlock()
+	if ix.path != "" && (stats.Embedded > 0 || stats.Removed > 0) {
+		if err := ix.save(files); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// embedFile reads and embeds one file. It returns nil for files that are not
+// text or cannot be read; only embedding errors are returned.
+func (ix *Index) embedFile(p string, info fs.FileInfo) (*fileEntry, error) {
+	data, err := os.ReadFile(p)
+	if err != nil || !isText(data) {
+		return nil, nil
+	}
+	entry := &fileEntry{Size: info.Size(), ModTime: info.ModTime()}
+	spans := splitChunks(string(data))
+	if len(spans) == 0 {
+		return entry, nil
+	}
+	texts := make([]string, len(spans))
+	for i, s := range spans {
+		texts[i] = s.Text
+	}
+	vectors, err := ix.embedder.Embed(texts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed %s: %w", p, err)
+	}
+	for i, s := range spans {
+		entry.Chunks = append(entry.Chunks, chunk{Start: s.Start, End: s.End, Text: s.Text, Vector: vectors[i]})
+	}
+	return entry, nil
+}
+
+// save writes the index to a temporary file and renames it over the store, so
+// a crash never leaves a partial index behind.
+func (ix *Index) save(files map[string]*fileEntry) error {
+	tmp, err := os.CreateTemp(filepath.Dir(ix.path), filepath.Base(ix.path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to save index: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	st := store{Version: storeVersion, Embedder: ix.embedder.Name(), Files: files}
+	if err := gob.NewEncoder(tmp).Encode(&st); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save index: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save index: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), ix.path); err != nil {
+		return fmt.Errorf("failed to save index: %w", err)
+	}
+	return nil
+}
+
+// Search returns the k chunks most similar to query, best first. If prefix is
+// not empty only files whose path relative to the root starts with it are searched.
+func (ix *Index) Search(query string, k int, prefix string) ([]Result, error) {
+	vectors, err := ix.embedder.Embed([]string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	q := vectors[0]
+	prefix = strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(prefix)), "/")
+
+	ix.mu.RLock()
+	var results []Result
+	for rel, e := range ix.files {
+		if prefix != "" && rel != prefix && !strings.HasPrefix(rel, prefix+"/") {
+			continue
+		}
+		for _, c := range e.Chunks {
+			if len(c.Vector) != len(q) {
+				continue
+			}
+			results = append(results, Result{
+				URI: "file:///" + rel, Path: rel, StartLine: c.Start, EndLine: c.End,
+				Score: Similarity(q, c.Vector), Text: c.Text,
+			})
+		}
+	}
+	ix.mu.RUnlock()
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		if results[i].Path != results[j].Path {
+			return results[i].Path < results[j].Path
+		}
+		return results[i].StartLine < results[j].StartLine
+	})
+	if len(results) > k {
+		results = results[:k]
+	}
+	return results, nil
+}
@@ -0,0 +1,164 @@
+package index
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitChunks(t *testing.T) {
+	var lines []string
+	for i := 0; i < 100; i++ {
+		lines = append(lines, strings.Repeat("x", 49)) // 50 bytes with the newline
+	}
+	spans := splitChunks(strings.Join(lines, "\n") + "\n")
+	if len(spans) != 4 || spans[0].Start != 1 || spans[0].End != 30 || spans[1].Start != 29 || spans[3].End != 100 {
+		t.Fatalf("spans: %+v", spanLines(spans))
+	}
+	for _, s := range spans {
+		if len(s.Text) > chunkMaxChars {
+			t.Errorf("chunk of %d bytes", len(s.Text))
+		}
+	}
+	long := splitChunks("short\n" + strings.Repeat("é", chunkMaxChars) + "\n\n\n")
+	if len(long) != 2 || long[1].Start != 2 || len(long[1].Text) > chunkMaxChars || !isText([]byte(long[1].Text)) {
+		t.Errorf("long line: %+v", spanLines(long))
+	}
+	if spans := splitChunks(""); len(spans) != 0 {
+		t.Errorf("empty text: %+v", spans)
+	}
+}
+
+func spanLines(spans []span) [][2]int {
+	var lines [][2]int
+	for _, s := range spans {
+		lines = append(lines, [2]int{s.Start, s.End})
+	}
+	return lines
+}
+
+func writeFile(t *testing.T, root, rel, content string) {
+	t.Helper()
+	p := filepath.Join(root, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIndexRefreshAndSearch(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "docs/deploy.md", "How to deploy the server\nRun make deploy to push the container image.\n")
+	writeFile(t, root, "src/parser.go", "package parser\n\n// Parse reads a JSON-RPC message from the wire.\nfunc Parse() {}\n")
+	writeFile(t, root, "logo.png", "\x89PNG\r\n\x1a\n\x00\x00")
+	writeFile(t, root, ".git/config", "deploy the server")
+	writeFile(t, root, "node_modules/x/index.js", "deploy the server")
+	store := filepath.Join(t.TempDir(), "index.gob")
+
+	ix, err := Open(root, store, HashEmbedder{Dims: DefaultHashDims})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, err := ix.Refresh()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Files != 2 || stats.Embedded != 2 || stats.Skipped != 1 {
+		t.Errorf("first refresh: %+v", stats)
+	}
+
+	results, err := ix.Search("deploy the server", 5, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].URI != "file:///docs/deploy.md" || results[0].StartLine != 1 || results[0].EndLine != 2 ||
+		results[0].Score <= results[1].Score || results[0].Score > 1.0001 {
+		t.Errorf("search: %+v", results)
+	}
+	if results, _ := ix.Search("deploy the server", 5, "src"); len(results) != 1 || results[0].Path != "src/parser.go" {
+		t.Errorf("search under src: %+v", results)
+	}
+	if results, _ := ix.Search("deploy", 5, "/doc"); len(results) != 0 {
+		t.Errorf("prefix must match whole path elements: %+v", results)
+	}
+
+	// Only changed files are embedded again, and deleted ones are dropped
+	writeFile(t, root, "src/parser.go", "package parser\n\n// Parse reads a message and deploys nothing.\nfunc Parse() {}\n")
+	os.Chtimes(filepath.Join(root, "src/parser.go"), time.Now(), time.Now().Add(time.Minute))
+	os.Remove(filepath.Join(root, "docs/deploy.md"))
+	if stats, err := ix.Refresh(); err != nil || stats.Files != 1 || stats.Embedded != 1 || stats.Removed != 1 {
+		t.Errorf("second refresh: %+v, %v", stats, err)
+	}
+
+	// The stored index is reused by the same embedder and discarded by another
+	reopened, err := Open(root, store, HashEmbedder{Dims: DefaultHashDims})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files, chunks, _ := reopened.Stats(); files != 1 || chunks != 1 {
+		t.Errorf("reopened index: %d files, %d chunks", files, chunks)
+	}
+	if stats, _ := reopened.Refresh(); stats.Embedded != 0 {
+		t.Errorf("refresh of an up-to-date index embedded %d files", stats.Embedded)
+	}
+	other, err := Open(root, store, HashEmbedder{Dims: 64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files, _, _ := other.Stats(); files != 0 {
+		t.Errorf("index of another embedder has %d files", files)
+	}
+}
+
+func TestOpenAIEmbedder(t *testing.T) {
+	var auth string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if r.URL.Path != "/v1/embeddings" || req.Model != "test-model" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"message":"bad request"}}`))
+			return
+		}
+		var data []map[string]interface{}
+		for i := len(req.Input) - 1; i >= 0; i-- { // Out of order, as the API allows
+			data = append(data, map[string]interface{}{"index": i, "embedding": []float32{float32(len(req.Input[i])), 0}})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	defer api.Close()
+
+	embedder, err := NewEmbedder("openai:test-model", api.URL+"/v1/", "secret", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	texts := make([]string, openAIBatchSize+1)
+	vectors, err := embedder.Embed(texts)
+	if err != nil || len(vectors) != len(texts) || auth != "Bearer secret" {
+		t.Fatalf("embed: %d vectors, %v, auth %q", len(vectors), err, auth)
+	}
+	if vectors, _ := embedder.Embed([]string{"abc"}); vectors[0][0] != 1 {
+		t.Errorf("vector not normalized: %v", vectors[0])
+	}
+
+	bad, _ := NewEmbedder("openai:other", api.URL+"/v1", "", time.Second)
+	if _, err := bad.Embed([]string{"x"}); err == nil || !strings.Contains(err.Error(), "bad request") {
+		t.Errorf("API error = %v", err)
+	}
+	for _, spec := range []string{"openai", "word2vec"} {
+		if _, err := NewEmbedder(spec, "", "", time.Second); err == nil {
+			t.Errorf("NewEmbedder(%q) succeeded", spec)
+		}
+	}
+}
@@ -0,0 +1,395 @@
+// Package journal records the requests a server handles, with their responses,
+// in a SQLite database for debugging production incidents after the fact.
+// The database is written and queried through the sqlite3 command line shell,
+// which must be on the PATH, so the server needs no cgo driver.
+package journal
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"sqirvy/mcp/pkg/utils"
+)
+
+// Command is the SQLite shell the journal runs.
+const Command = "sqlite3"
+
+const (
+	// MaxLimit is the most entries one Query returns.
+	MaxLimit = 1000
+	// flushInterval is how long a recorded entry may wait to be written.
+	flushInterval = 500 * time.Millisecond
+	// maxBatch is the most entries written by one sqlite3 run.
+	maxBatch = 500
+	// commandTimeout bounds one sqlite3 run.
+	commandTimeout = 30 * time.Second
+	// busyTimeout is how long sqlite3 waits for a lock held by another run, in milliseconds.
+	busyTimeout = 5000
+	// timeLayout sorts in time order as text.
+	timeLayout = "2006-01-02T15:04:05.000000Z"
+	// sealedPrefix marks a payload column written by a Journal with a Sealer.
+	sealedPrefix = "sealed:"
+)
+
+// schema creates the requests table and its indexes. WAL mode lets queries
+// run while entries are written.
+const schema = `PRAGMA journal_mode=WAL;
+CREATE TABLE IF NOT EXISTS requests (
+	time        TEXT NOT NULL,
+	session     INTEGER NOT NULL,
+	client      TEXT NOT NULL,
+	request_id  TEXT NOT NULL,
+	method      TEXT NOT NULL,
+	duration_ms REAL NOT NULL,
+	error_code  INTEGER,
+	request     TEXT,
+	response    TEXT
+);
+CREATE INDEX IF NOT EXISTS requests_time ON requests(time);
+CREATE INDEX IF NOT EXISTS requests_method ON requests(method, time);
+CREATE INDEX IF NOT EXISTS requests_session ON requests(session, time);
+`
+
+// Entry is one journaled request.
+type Entry struct {
+	Time      time.Time     `json:"time"`
+	Session   int64         `json:"session"`
+	Client    string        `json:"client"`    // Client name from initialize
+	RequestID string        `json:"requestId"` // The request ID as JSON, e.g. 7 or "a"
+	Method    string        `json:"method"`
+	Duration  time.Duration `json:"duration"`
+	ErrorCode int           `json:"errorCode,omitempty"` // 0 for a successful response
+	Request   string        `json:"request,omitempty"`   // Empty unless bodies are journaled
+	Response  string        `json:"response,omitempty"`
+}
+
+// Filter selects entries for Query. Zero fields match everything.
+type Filter struct {
+	Method     string
+	Client     string
+	Session    int64
+	Since      time.Time
+	Until      time.Time
+	ErrorsOnly bool
+	Limit      int // Newest entries returned, at most MaxLimit; 0 means MaxLimit
+}
+
+// Options configure a Journal.
+type Options struct {
+	// Bodies keeps the request and response payloads; otherwise only their
+	// metadata is journaled, which keeps tool arguments and results out of it.
+	Bodies bool
+	// OnError is told about entries that could not be written.
+	OnError func(error)
+	// Sealer, if set, encrypts the request and response columns at rest. Query
+	// decrypts them; sealed payloads are returned as stored without a Sealer.
+	Sealer *utils.Sealer
+}
+
+// Journal writes entries to a SQLite database in the background. It is safe
+// for concurrent use.
+type Journal struct {
+	path    string
+	opts    Options
+	entries chan Entry
+	done    chan struct{}
+
+	mu          sync.Mutex
+	lastSession int64
+	closed      bool
+}
+
+// Open creates the database at path if needed and starts the writer. Sessions
+// are numbered on from the highest session already in the database.
+func Open(path string, opts Options) (*Journal, error) {
+	if _, err := exec.LookPath(Command); err != nil {
+		return nil, fmt.Errorf("the request journal needs %s: %w", Command, err)
+	}
+	if _, err := run(path, false, schema); err != nil {
+		return nil, fmt.Errorf("failed to create journal %s: %w", path, err)
+	}
+	out, err := run(path, true, "SELECT COALESCE(MAX(session), 0) AS last FROM requests;")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal %s: %w", path, err)
+	}
+	var rows []struct{ Last int64 }
+	if err := decodeRows(out, &rows); err != nil || len(rows) != 1 {
+		return nil, fmt.Errorf("failed to read journal %s: unexpected output %q", path, out)
+	}
+	j := &Journal{path: path, opts: opts, entries: make(chan Entry, 4*maxBatch), done: make(chan struct{}), lastSession: rows[0].Last}
+	go j.writer()
+	return j, nil
+}
+
+// Bodies reports whether payloads are journaled.
+func (j *Journal) Bodies() bool {
+	return j.opts.Bodies
+}
+
+// NewSession returns the number of a new session.
+func (j *Journal) NewSession() int64 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.lastSession++
+	return j.lastSession
+}
+
+// Record queues e to be written. It never blocks a request: if the writer has
+// fallen behind, the entry is dropped and reported to OnError.
+func (j *Journal) Record(e Entry) {
+	if !j.opts.Bodies {
+		e.Request, e.Response = "", ""
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.closed {
+		return
+	}
+	select {
+	case j.entries <- e:
+	default:
+		j.report(fmt.Errorf("journal writer behind, dropped %s request %s", e.Method, e.RequestID))
+	}
+}
+
+// Close writes the queued entries and stops the writer.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	if !j.closed {
+		j.closed = true
+		close(j.entries)
+	}
+	j.mu.Unlock()
+	<-j.done
+	return nil
+}
+
+// writer writes queued entries in batches, one transaction each.
+func (j *Journal) writer() {
+	defer close(j.done)
+	for e := range j.entries {
+		batch := []Entry{e}
+		flush := time.NewTimer(flushInterval)
+	collect:
+		for len(batch) < maxBatch {
+			select {
+			case e, ok := <-j.entries:
+				if !ok {
+					break collect
+				}
+				batch = append(batch, e)
+			case <-flush.C:
+				break collect
+			}
+		}
+		flush.Stop()
+		if err := j.write(batch); err != nil {
+			j.report(fmt.Errorf("failed to journal %d requests: %w", len(batch), err))
+		}
+	}
+}
+
+// write inserts batch in one transaction.
+func (j *Journal) write(batch []Entry) error {
+	var sql strings.Builder
+	sql.WriteString("BEGIN;\n")
+	for _, e := range batch {
+		errorCode := "NULL"
+		if e.ErrorCode != 0 {
+			errorCode = strconv.Itoa(e.ErrorCode)
+		}
+		var err error
+		if e.Request, err = j.seal(e.Request, e.Session, e.RequestID, "request"); err != nil {
+			return err
+		}
+		if e.Response, err = j.seal(e.Response, e.Session, e.RequestID, "response"); err != nil {
+			return err
+		}
+		fmt.Fprintf(&sql, "INSERT INTO requests VALUES (%s, %d, %s, %s, %s, %s, %s, %s, %s);\n",
+			quote(e.Time.UTC().Format(timeLayout)), e.Session, quote(e.Client), quote(e.RequestID), quote(e.Method),
+			strconv.FormatFloat(float64(e.Duration)/float64(time.Millisecond), 'f', 3, 64), errorCode,
+			nullable(e.Request), nullable(e.Response))
+	}
+	sql.WriteString("COMMIT;\n")
+	_, err := run(j.path, false, sql.String())
+	return err
+}
+
+// seal encrypts the payload column of an entry with the Sealer, if there is
+// one. The entry's session, request ID and column are authenticated with it,
+// so a sealed payload cannot be moved to another row or column.
+func (j *Journal) seal(payload string, session int64, requestID, column string) (string, error) {
+	if j.opts.Sealer == nil || payload == "" {
+		return payload, nil
+	}
+	sealed, err := j.opts.Sealer.Seal([]byte(payload), sealedData(session, requestID, column))
+	if err != nil {
+		return "", err
+	}
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// open decrypts a payload column written by seal. Columns written without a
+// Sealer are returned as they are.
+func (j *Journal) open(stored string, session int64, requestID, column string) (string, error) {
+	encoded, ok := strings.CutPrefix(stored, sealedPrefix)
+	if !ok || j.opts.Sealer == nil {
+		return stored, nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("bad %s of request %s in journal: %w", column, requestID, utils.ErrSealedData)
+	}
+	payload, err := j.opts.Sealer.Open(sealed, sealedData(session, requestID, column))
+	if err != nil {
+		return "", fmt.Errorf("bad %s of request %s in journal: %w", column, requestID, err)
+	}
+	return string(payload), nil
+}
+
+// sealedData is the additional data a payload column is sealed with.
+func sealedData(session int64, requestID, column string) []byte {
+	return []byte(fmt.Sprintf("%d/%s/%s", session, requestID, column))
+}
+
+// report passes err to OnError, if set.
+func (j *Journal) report(err error) {
+	if j.opts.OnError != nil {
+		j.opts.OnError(err)
+	}
+}
+
+// row is an entry as sqlite3 prints it.
+type row struct {
+	Time       string  `json:"time"`
+	Session    int64   `json:"session"`
+	Client     string  `json:"client"`
+	RequestID  string  `json:"request_id"`
+	Method     string  `json:"method"`
+	DurationMS float64 `json:"duration_ms"`
+	ErrorCode  int     `json:"error_code"`
+	Request    string  `json:"request"`
+	Response   string  `json:"response"`
+}
+
+// Query returns the newest entries matching f, newest first. Entries still
+// queued for writing are not seen.
+func (j *Journal) Query(f Filter) ([]Entry, error) {
+	var where []string
+	if f.Method != "" {
+		where = append(where, "method = "+quote(f.Method))
+	}
+	if f.Client != "" {
+		where = append(where, "client = "+quote(f.Client))
+	}
+	if f.Session != 0 {
+		where = append(where, fmt.Sprintf("session = %d", f.Session))
+	}
+	if !f.Since.IsZero() {
+		where = append(where, "time >= "+quote(f.Since.UTC().Format(timeLayout)))
+	}
+	if !f.Until.IsZero() {
+		where = append(where, "time < "+quote(f.Until.UTC().Format(timeLayout)))
+	}
+	if f.ErrorsOnly {
+		where = append(where, "error_code IS NOT NULL")
+	}
+	limit := f.Limit
+	if limit <= 0 || limit > MaxLimit {
+		limit = MaxLimit
+	}
+	sql := "SELECT * FROM requests"
+	if len(where) > 0 {
+		sql += " WHERE " + strings.Join(where, " AND ")
+	}
+	sql += fmt.Sprintf(" ORDER BY time DESC, rowid DESC LIMIT %d;", limit)
+
+	out, err := run(j.path, true, sql)
+	if err != nil {
+		return nil, err
+	}
+	var rows []row
+	if err := decodeRows(out, &rows); err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, len(rows))
+	for i, r := range rows {
+		t, err := time.Parse(timeLayout, r.Time)
+		if err != nil {
+			return nil, fmt.Errorf("bad time in journal: %w", err)
+		}
+		request, err := j.open(r.Request, r.Session, r.RequestID, "request")
+		if err != nil {
+			return nil, err
+		}
+		response, err := j.open(r.Response, r.Session, r.RequestID, "response")
+		if err != nil {
+			return nil, err
+		}
+		entries[i] = Entry{Time: t, Session: r.Session, Client: r.Client, RequestID: r.RequestID, Method: r.Method,
+			Duration: time.Duration(r.DurationMS * float64(time.Millisecond)), ErrorCode: r.ErrorCode,
+			Request: request, Response: response}
+	}
+	return entries, nil
+}
+
+// run runs sql against the database at path and returns what sqlite3 printed,
+// as a JSON array when readonly is set.
+func run(path string, readonly bool, sql string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+	args := []string{"-bail", "-cmd", fmt.Sprintf(".timeout %d", busyTimeout)}
+	if readonly {
+		args = append(args, "-readonly", "-json")
+	}
+	cmd := exec.CommandContext(ctx, Command, append(args, path)...)
+	cmd.Stdin = strings.NewReader(sql)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return nil, fmt.Errorf("%s timed out after %v", Command, commandTimeout)
+	case err != nil:
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+		return nil, fmt.Errorf("%s failed: %s", Command, message)
+	}
+	return stdout.Bytes(), nil
+}
+
+// decodeRows decodes the -json output of a query; no rows print nothing.
+func decodeRows(out []byte, v interface{}) error {
+	if len(bytes.TrimSpace(out)) == 0 {
+		out = []byte("[]")
+	}
+	if err := json.Unmarshal(out, v); err != nil {
+		return fmt.Errorf("failed to decode %s output: %w", Command, err)
+	}
+	return nil
+}
+
+// quote returns s as an SQL string literal. NUL cannot pass through the shell
+// and is dropped.
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(strings.ReplaceAll(s, "\x00", ""), "'", "''") + "'"
+}
+
+// nullable quotes s, or returns NULL if it is empty.
+func nullable(s string) string {
+	if s == "" {
+		return "NULL"
+	}
+	return quote(s)
+}
@@ -0,0 +1,132 @@
+package journal
+
+import (
+	"bytes"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"sqirvy/mcp/pkg/utils"
+)
+
+func openTest(t *testing.T, opts Options) (*Journal, string) {
+	t.Helper()
+	if _, err := exec.LookPath(Command); err != nil {
+		t.Skipf("%s not installed", Command)
+	}
+	path := filepath.Join(t.TempDir(), "journal.db")
+	j, err := Open(path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return j, path
+}
+
+func TestJournalRecordAndQuery(t *testing.T) {
+	var errs []error
+	j, path := openTest(t, Options{Bodies: true, OnError: func(err error) { errs = append(errs, err) }})
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	session := j.NewSession()
+	j.Record(Entry{Time: start, Session: session, Client: "o'brien", RequestID: "1", Method: "tools/list",
+		Duration: 1500 * time.Microsecond, Request: `{"id":1}`, Response: `{"result":{}}`})
+	j.Record(Entry{Time: start.Add(time.Second), Session: session, Client: "o'brien", RequestID: `"b"`, Method: "tools/call",
+		ErrorCode: -32602, Request: `{"text":"it's"}`})
+	j.Record(Entry{Time: start.Add(2 * time.Second), Session: j.NewSession(), Client: "other", RequestID: "1", Method: "tools/list"})
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) > 0 {
+		t.Fatalf("write errors: %v", errs)
+	}
+
+	// Reopening continues the session numbers
+	j, err := Open(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	if got := j.NewSession(); got != session+2 {
+		t.Errorf("NewSession after reopening = %d, want %d", got, session+2)
+	}
+
+	all, err := j.Query(Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 || all[0].Client != "other" || all[2].Duration != 1500*time.Microsecond || all[2].Response != `{"result":{}}` {
+		t.Fatalf("Query = %+v", all)
+	}
+	tests := []struct {
+		name   string
+		filter Filter
+		want   int
+	}{
+		{"method", Filter{Method: "tools/list"}, 2},
+		{"client with a quote", Filter{Client: "o'brien"}, 2},
+		{"session", Filter{Session: session}, 2},
+		{"errors", Filter{ErrorsOnly: true}, 1},
+		{"time range", Filter{Since: start.Add(time.Second), Until: start.Add(2 * time.Second)}, 1},
+		{"limit", Filter{Limit: 1}, 1},
+		{"no match", Filter{Method: "'; DROP TABLE requests; --"}, 0},
+	}
+	for _, tt := range tests {
+		entries, err := j.Query(tt.filter)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(entries) != tt.want {
+			t.Errorf("%s: %d entries, want %d", tt.name, len(entries), tt.want)
+		}
+	}
+	if failed, _ := j.Query(Filter{ErrorsOnly: true}); len(failed) == 1 && (failed[0].ErrorCode != -32602 || failed[0].RequestID != `"b"`) {
+		t.Errorf("error entry = %+v", failed[0])
+	}
+}
+
+func TestJournalWithoutBodies(t *testing.T) {
+	j, _ := openTest(t, Options{})
+	j.Record(Entry{Time: time.Now(), Session: j.NewSession(), RequestID: "1", Method: "ping", Request: "secret", Response: "secret"})
+	j.Close()
+	entries, err := j.Query(Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Request != "" || entries[0].Response != "" {
+		t.Errorf("entries = %+v, want one without bodies", entries)
+	}
+	j.Record(Entry{Method: "after close"}) // Must not panic
+}
+
+func TestJournalSealsBodies(t *testing.T) {
+	sealer, err := utils.NewSealer(bytes.Repeat([]byte{1}, utils.SealKeySize), "journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, path := openTest(t, Options{Bodies: true, Sealer: sealer})
+	j.Record(Entry{Time: time.Now(), Session: j.NewSession(), RequestID: "1", Method: "tools/call", Request: `{"text":"secret"}`, Response: `{"result":"secret"}`})
+	j.Close()
+
+	raw, err := run(path, true, "SELECT request, response FROM requests;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(raw, []byte("secret")) || !bytes.Contains(raw, []byte(sealedPrefix)) {
+		t.Fatalf("stored bodies = %s, want them sealed", raw)
+	}
+	entries, err := j.Query(Filter{})
+	if err != nil || len(entries) != 1 || entries[0].Request != `{"text":"secret"}` || entries[0].Response != `{"result":"secret"}` {
+		t.Fatalf("Query = %+v, %v", entries, err)
+	}
+
+	// Without the key the bodies stay sealed
+	other, err := Open(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	if entries, err := other.Query(Filter{}); err != nil || len(entries) != 1 || !strings.HasPrefix(entries[0].Request, sealedPrefix) {
+		t.Errorf("Query without the key = %+v, %v", entries, err)
+	}
+}
@@ -0,0 +1,95 @@
+package mcp
+
+import "encoding/json"
+
+// IncludesAudience reports whether content with these annotations is meant for
+// role. Content without an audience is meant for everyone.
+func (a *Annotations) IncludesAudience(role Role) bool {
+	if a == nil || len(a.Audience) == 0 {
+		return true
+	}
+	for _, r := range a.Audience {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// ContentAnnotations returns the annotations of a content block, or nil if it
+// has none or is not a JSON object.
+func ContentAnnotations(content json.RawMessage) *Annotations {
+	var block struct {
+		Annotations *Annotations `json:"annotations"`
+	}
+	if err := json.Unmarshal(content, &block); err != nil {
+		return nil
+	}
+	return block.Annotations
+}
+
+// ContentIsFor reports whether a content block is meant for role, e.g. whether
+// RoleUser should be shown it or RoleAssistant (the model) should be sent it.
+func ContentIsFor(content json.RawMessage, role Role) bool {
+	return ContentAnnotations(content).IncludesAudience(role)
+}
+
+// WithAudience returns content with its annotations' audience set to audience.
+// Content that is not a JSON object is returned unchanged.
+func WithAudience(content json.RawMessage, audience ...Role) json.RawMessage {
+	var block map[string]json.RawMessage
+	if err := json.Unmarshal(content, &block); err != nil || block == nil {
+		return content
+	}
+	annotations := ContentAnnotations(content)
+	if annotations == nil {
+		annotations = &Annotations{}
+	}
+	annotations.Audience = audience
+	block["annotations"], _ = json.Marshal(annotations)
+	out, err := json.Marshal(block)
+	if err != nil {
+		return content
+	}
+	return out
+}
+
+// FilterPromptMessages returns the messages whose content is meant for
+// audience, e.g. RoleUser to strip assistant-only content before display. If
+// roles are given, only messages sent by one of them are kept.
+func FilterPromptMessages(messages []PromptMessage, audience Role, roles ...Role) []PromptMessage {
+	var kept []PromptMessage
+	for _, m := range messages {
+		if ContentIsFor(m.Content, audience) && hasRole(roles, m.Role) {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
+
+// FilterSamplingMessages returns the messages whose content is meant for
+// audience, e.g. RoleAssistant to strip user-only content before the messages
+// are sent to a model. If roles are given, only messages sent by one of them
+// are kept.
+func FilterSamplingMessages(messages []SamplingMessage, audience Role, roles ...Role) []SamplingMessage {
+	var kept []SamplingMessage
+	for _, m := range messages {
+		if ContentIsFor(m.Content, audience) && hasRole(roles, m.Role) {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
+
+// hasRole reports whether role is among roles, or roles is empty.
+func hasRole(roles []Role, role Role) bool {
+	if len(roles) == 0 {
+		return true
+	}
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package mcp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFilterPromptMessages(t *testing.T) {
+	messages := NewGetPromptResult("").
+		User(NewTextContent("for everyone")).
+		User(WithAudience(NewTextContent("for the model"), RoleAssistant)).
+		Assistant(WithAudience(NewTextContent("for the user"), RoleUser)).
+		Assistant(WithAudience(NewTextContent("for both"), RoleUser, RoleAssistant)).
+		result.Messages
+
+	text := func(messages []PromptMessage) []string {
+		var texts []string
+		for _, m := range messages {
+			var c TextContent
+			json.Unmarshal(m.Content, &c)
+			texts = append(texts, c.Text)
+		}
+		return texts
+	}
+	for _, tt := range []struct {
+		audience Role
+		roles    []Role
+		want     []string
+	}{
+		{RoleUser, nil, []string{"for everyone", "for the user", "for both"}},
+		{RoleAssistant, nil, []string{"for everyone", "for the model", "for both"}},
+		{RoleUser, []Role{RoleAssistant}, []string{"for the user", "for both"}},
+	} {
+		got := text(FilterPromptMessages(messages, tt.audience, tt.roles...))
+		if len(got) != len(tt.want) {
+			t.Errorf("FilterPromptMessages(%s, %v) = %q, want %q", tt.audience, tt.roles, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("FilterPromptMessages(%s, %v) = %q, want %q", tt.audience, tt.roles, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestFilterSamplingMessages(t *testing.T) {
+	messages := []SamplingMessage{
+		NewTextSamplingMessage(RoleUser, "question"),
+		{Role: RoleUser, Content: WithAudience(NewTextContent("note to self"), RoleUser)},
+	}
+	if got := FilterSamplingMessages(messages, RoleAssistant); len(got) != 1 || string(got[0].Content) != string(messages[0].Content) {
+		t.Errorf("FilterSamplingMessages = %+v, want only the question", got)
+	}
+}
+
+func TestWithAudience(t *testing.T) {
+	priority := 0.5
+	content, _ := json.Marshal(TextContent{Type: "text", Text: "x", Annotations: &Annotations{Priority: &priority}})
+	content = WithAudience(content, RoleAssistant)
+	a := ContentAnnotations(content)
+	if a == nil || len(a.Audience) != 1 || a.Audience[0] != RoleAssistant || a.Priority == nil || *a.Priority != 0.5 {
+		t.Errorf("annotations = %+v, want the audience added and the priority kept", a)
+	}
+	if !ContentIsFor(content, RoleAssistant) || ContentIsFor(content, RoleUser) {
+		t.Error("ContentIsFor ignores the audience")
+	}
+	if raw := json.RawMessage(`"text"`); string(WithAudience(raw, RoleUser)) != `"text"` {
+		t.Error("WithAudience changed a non-object")
+	}
+	var none *Annotations
+	if !none.IncludesAudience(RoleUser) {
+		t.Error("content without annotations is not for everyone")
+	}
+}
@@ -0,0 +1,116 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// MetaKeyCapabilityWarnings is the InitializeResult _meta key under which a server lists
+// what it could not provide of what the client asked for.
+const MetaKeyCapabilityWarnings = "capabilityWarnings"
+
+// ExperimentalRequiredServerCapabilities is the ClientCapabilities.Experimental key under
+// which a client may list the server capabilities it depends on, as paths accepted by
+// ServerCapabilities.Has (e.g. ["tools", "resources.subscribe"]). The protocol has no
+// standard way to ask for server features, so this lets a server report the mismatch
+// at initialize instead of the client failing later with MethodNotFound.
+const ExperimentalRequiredServerCapabilities = "requiredServerCapabilities"
+
+// CapabilityWarning describes a capability the client asked for that the server cannot provide.
+type CapabilityWarning struct {
+	// Capability is the capability path, e.g. "resources.subscribe" or "protocolVersion".
+	Capability string `json:"capability"`
+	// Reason explains what the server does instead.
+	Reason string `json:"reason"`
+}
+
+// String formats the warning for log messages.
+func (w CapabilityWarning) String() string {
+	return w.Capability + ": " + w.Reason
+}
+
+// Has reports whether the capabilities include path, a dot-separated capability name
+// such as "tools", "resources.subscribe", "prompts.listChanged", "logging" or "experimental.x".
+func (c ServerCapabilities) Has(path string) bool {
+	group, feature, _ := strings.Cut(path, ".")
+	switch group {
+	case "tools":
+		return c.Tools != nil && (feature == "" || (feature == "listChanged" && c.Tools.ListChanged))
+	case "resources":
+		if c.Resources == nil {
+			return false
+		}
+		switch feature {
+		case "":
+			return true
+		case "listChanged":
+			return c.Resources.ListChanged
+		case "subscribe":
+			return c.Resources.Subscribe
+		}
+	case "prompts":
+		return c.Prompts != nil && (feature == "" || (feature == "listChanged" && c.Prompts.ListChanged))
+	case "logging":
+		return c.Logging != nil && feature == ""
+	case "completions":
+		return c.Completions != nil && feature == ""
+	case "experimental":
+		return feature != "" && c.Experimental.Has(feature)
+	}
+	return false
+}
+
+// RequiredServerCapabilities returns the capability paths listed by the client under
+// ExperimentalRequiredServerCapabilities, or nil if there are none or the list is malformed.
+func (c ClientCapabilities) RequiredServerCapabilities() []string {
+	var paths []string
+	if _, err := c.Experimental.Decode(ExperimentalRequiredServerCapabilities, &paths); err != nil {
+		return nil
+	}
+	return paths
+}
+
+// CheckCapabilities compares what a client asked for in its initialize request with
+// what the server offers, and returns a warning for each mismatch:
+//   - a different protocol version, which the server answers with its own;
+//   - server capabilities the client lists as required but the server lacks;
+//   - experimental client capabilities the server does not implement.
+func CheckCapabilities(params InitializeParams, serverVersion string, server ServerCapabilities) []CapabilityWarning {
+	var warnings []CapabilityWarning
+	if params.ProtocolVersion != "" && params.ProtocolVersion != serverVersion {
+		warnings = append(warnings, CapabilityWarning{
+			Capability: "protocolVersion",
+			Reason:     fmt.Sprintf("client requested %s, server speaks %s", params.ProtocolVersion, serverVersion),
+		})
+	}
+	for _, path := range params.Capabilities.RequiredServerCapabilities() {
+		if !server.Has(path) {
+			warnings = append(warnings, CapabilityWarning{Capability: path, Reason: "not supported by this server"})
+		}
+	}
+	for _, name := range params.Capabilities.Experimental.Names() {
+		if name != ExperimentalRequiredServerCapabilities && !server.Experimental.Has(name) {
+			warnings = append(warnings, CapabilityWarning{Capability: "experimental." + name, Reason: "not supported by this server"})
+		}
+	}
+	return warnings
+}
+
+// CapabilityWarnings returns the warnings the server attached to the result, or nil if there are none.
+func (r *InitializeResult) CapabilityWarnings() []CapabilityWarning {
+	raw, ok := r.Meta[MetaKeyCapabilityWarnings]
+	if !ok {
+		return nil
+	}
+	// The map was decoded generically; round-trip it through JSON into the typed slice
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil
+	}
+	var warnings []CapabilityWarning
+	if err := json.Unmarshal(data, &warnings); err != nil {
+		return nil
+	}
+	return warnings
+}
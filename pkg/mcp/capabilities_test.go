@@ -0,0 +1,108 @@
+package mcp
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestServerCapabilitiesHas(t *testing.T) {
+	caps := ServerCapabilities{
+		Experimental: Experimental{"x": json.RawMessage(`{}`)},
+		Resources:    &ServerCapabilitiesResources{ListChanged: true},
+		Tools:        &ServerCapabilitiesTools{},
+	}
+	tests := map[string]bool{
+		"tools":                 true,
+		"tools.listChanged":     false,
+		"resources":             true,
+		"resources.listChanged": true,
+		"resources.subscribe":   false,
+		"resources.unknown":     false,
+		"prompts":               false,
+		"logging":               false,
+		"experimental.x":        true,
+		"experimental.y":        false,
+		"experimental":          false,
+		"completions":           false,
+	}
+	for path, want := range tests {
+		if got := caps.Has(path); got != want {
+			t.Errorf("Has(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestServerCapabilitiesJSON(t *testing.T) {
+	// Capabilities without settings are announced as empty objects
+	caps := ServerCapabilities{
+		Completions: &ServerCapabilitiesCompletions{},
+		Logging:     map[string]interface{}{},
+		Prompts:     &ServerCapabilitiesPrompts{},
+		Tools:       &ServerCapabilitiesTools{ListChanged: true},
+	}
+	data, err := json.Marshal(caps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"completions":{},"logging":{},"prompts":{},"tools":{"listChanged":true}}`; string(data) != want {
+		t.Errorf("capabilities = %s, want %s", data, want)
+	}
+	var decoded ServerCapabilities
+	if err := json.Unmarshal(data, &decoded); err != nil || !decoded.Has("completions") || !decoded.Has("logging") || decoded.Has("resources") {
+		t.Errorf("decoded capabilities = %+v, %v", decoded, err)
+	}
+	if data, err := json.Marshal(ServerCapabilities{}); err != nil || string(data) != "{}" {
+		t.Errorf("no capabilities = %s, %v; want {}", data, err)
+	}
+}
+
+func TestCheckCapabilities(t *testing.T) {
+	var params InitializeParams
+	data := `{"protocolVersion":"2025-03-26","clientInfo":{"name":"c","version":"1"},"capabilities":{"experimental":{
+		"requiredServerCapabilities":["tools","resources.subscribe"],"zeta":{},"alpha":{}}}}`
+	if err := json.Unmarshal([]byte(data), &params); err != nil {
+		t.Fatal(err)
+	}
+	server := ServerCapabilities{
+		Resources: &ServerCapabilitiesResources{},
+		Tools:     &ServerCapabilitiesTools{},
+	}
+
+	got := CheckCapabilities(params, "2024-11-05", server)
+	want := []CapabilityWarning{
+		{"protocolVersion", "client requested 2025-03-26, server speaks 2024-11-05"},
+		{"resources.subscribe", "not supported by this server"},
+		{"experimental.alpha", "not supported by this server"},
+		{"experimental.zeta", "not supported by this server"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CheckCapabilities() = %v, want %v", got, want)
+	}
+
+	params.ProtocolVersion = "2024-11-05"
+	params.Capabilities.Experimental = nil
+	if err := params.Capabilities.Experimental.Set(ExperimentalRequiredServerCapabilities, []string{"tools"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := CheckCapabilities(params, "2024-11-05", server); len(got) != 0 {
+		t.Errorf("CheckCapabilities() = %v, want no warnings", got)
+	}
+}
+
+func TestCapabilityWarningsFromResult(t *testing.T) {
+	data := []byte(`{"jsonrpc":"2.0","id":1,"result":{"_meta":{"capabilityWarnings":[{"capability":"logging","reason":"not supported by this server"}]},"capabilities":{},"protocolVersion":"2024-11-05","serverInfo":{"name":"s","version":"1"}}}`)
+	result, _, _, err := UnmarshalInitializeResponse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []CapabilityWarning{{"logging", "not supported by this server"}}
+	if got := result.CapabilityWarnings(); !reflect.DeepEqual(got, want) {
+		t.Errorf("CapabilityWarnings() = %v, want %v", got, want)
+	}
+
+	result.Meta = nil
+	if got := result.CapabilityWarnings(); got != nil {
+		t.Errorf("CapabilityWarnings() = %v, want nil without _meta", got)
+	}
+}
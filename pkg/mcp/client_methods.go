@@ -0,0 +1,8 @@
+package mcp
+
+// Method names for requests a server may send to a client.
+const (
+	MethodCreateMessage     = "sampling/createMessage"
+	MethodListRoots         = "roots/list"
+	MethodCreateElicitation = "elicitation/create"
+)
@@ -0,0 +1,107 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// MethodComplete asks the server for completions of a prompt or resource
+// template argument.
+const MethodComplete = "completion/complete"
+
+// Types of CompleteReference.
+const (
+	RefPrompt   = "ref/prompt"
+	RefResource = "ref/resource"
+)
+
+// MaxCompletionValues is the most values a completion may carry.
+const MaxCompletionValues = 100
+
+// CompleteReference names what is being completed: a prompt by Name or a
+// resource template by URI (its URI template).
+type CompleteReference struct {
+	// Type is RefPrompt or RefResource.
+	Type string `json:"type"`
+	// Name is the name of the prompt, for RefPrompt.
+	Name string `json:"name,omitempty"`
+	// URI is the URI template, for RefResource.
+	URI string `json:"uri,omitempty"`
+}
+
+// CompleteArgument is the argument being completed and what has been typed so far.
+type CompleteArgument struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// CompleteContext carries the values of arguments already filled in.
+type CompleteContext struct {
+	Arguments map[string]string `json:"arguments,omitempty"`
+}
+
+// CompleteParams defines the parameters for a "completion/complete" request.
+type CompleteParams struct {
+	Ref      CompleteReference `json:"ref"`
+	Argument CompleteArgument  `json:"argument"`
+	Context  *CompleteContext  `json:"context,omitempty"`
+}
+
+// Completion holds the suggested values for an argument.
+type Completion struct {
+	// Values holds at most MaxCompletionValues suggestions.
+	Values []string `json:"values"`
+	// Total is the number of values available, if known, which may exceed len(Values).
+	Total *int `json:"total,omitempty"`
+	// HasMore reports whether there are values beyond those returned.
+	HasMore bool `json:"hasMore,omitempty"`
+}
+
+// CompleteResult defines the result structure for a "completion/complete" response.
+type CompleteResult struct {
+	// Meta contains reserved protocol metadata.
+	Meta       map[string]interface{} `json:"_meta,omitempty"`
+	Completion Completion             `json:"completion"`
+}
+
+// NewCompletion returns a Completion of values, truncated to
+// MaxCompletionValues with Total and HasMore set accordingly.
+func NewCompletion(values []string) Completion {
+	if values == nil {
+		values = []string{}
+	}
+	if len(values) <= MaxCompletionValues {
+		return Completion{Values: values}
+	}
+	total := len(values)
+	return Completion{Values: values[:MaxCompletionValues], Total: &total, HasMore: true}
+}
+
+// MarshalCompleteRequest creates a JSON-RPC request for the completion/complete method.
+// The id can be a string or an integer.
+func MarshalCompleteRequest(id RequestID, params CompleteParams) ([]byte, error) {
+	return MarshalRequest(id, MethodComplete, params)
+}
+
+// UnmarshalCompleteResponse parses a JSON-RPC response for a completion/complete request.
+func UnmarshalCompleteResponse(data []byte) (*CompleteResult, RequestID, *RPCError, error) {
+	var resp RPCResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to unmarshal RPC response: %w", err)
+	}
+
+	if resp.Error != nil {
+		return nil, resp.ID, resp.Error, nil
+	}
+
+	if len(resp.Result) == 0 || string(resp.Result) == "null" {
+		return nil, resp.ID, nil, fmt.Errorf("received response with missing or null result field for method %s", MethodComplete)
+	}
+
+	var result CompleteResult
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		return nil, resp.ID, nil, fmt.Errorf("failed to unmarshal CompleteResult from response result: %w", err)
+	}
+
+	return &result, resp.ID, nil, nil
+}
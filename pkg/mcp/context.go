@@ -0,0 +1,64 @@
+package mcp
+
+import (
+	"context"
+)
+
+// Session is what a handler may learn about the session a request arrived on.
+type Session interface {
+	// ClientInfo returns the client's name and version from initialize.
+	ClientInfo() Implementation
+	// ClientCapabilities returns the capabilities the client declared in initialize.
+	ClientCapabilities() ClientCapabilities
+}
+
+// Logger is the leveled logger handlers get from LoggerFromContext, e.g.
+// Printf("DEBUG", "fetched %d rows", n). *utils.Logger implements it.
+type Logger interface {
+	Printf(level string, format string, v ...interface{})
+}
+
+// contextKey keys the values this package stores in a context.
+type contextKey int
+
+const (
+	sessionKey contextKey = iota
+	requestIDKey
+	loggerKey
+)
+
+// WithRequest returns a context carrying the session, request ID and logger of
+// a request, for SessionFromContext, RequestIDFromContext and LoggerFromContext.
+// Servers call it when they dispatch a request.
+func WithRequest(ctx context.Context, session Session, id RequestID, logger Logger) context.Context {
+	ctx = context.WithValue(ctx, sessionKey, session)
+	ctx = context.WithValue(ctx, requestIDKey, id)
+	return context.WithValue(ctx, loggerKey, logger)
+}
+
+// SessionFromContext returns the session of the request being handled, or
+// false if ctx does not belong to a request.
+func SessionFromContext(ctx context.Context) (Session, bool) {
+	s, ok := ctx.Value(sessionKey).(Session)
+	return s, ok && s != nil
+}
+
+// RequestIDFromContext returns the ID of the request being handled, or false
+// if ctx does not belong to a request.
+func RequestIDFromContext(ctx context.Context) (RequestID, bool) {
+	id := ctx.Value(requestIDKey)
+	return id, id != nil
+}
+
+// LoggerFromContext returns the logger of the request being handled. Outside a
+// request it returns a logger that discards everything, so callers need not check.
+func LoggerFromContext(ctx context.Context) Logger {
+	if l, ok := ctx.Value(loggerKey).(Logger); ok && l != nil {
+		return l
+	}
+	return discardLogger{}
+}
+
+type discardLogger struct{}
+
+func (discardLogger) Printf(string, string, ...interface{}) {}
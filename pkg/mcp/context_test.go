@@ -0,0 +1,44 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+type testSession struct{ name string }
+
+func (s testSession) ClientInfo() Implementation             { return Implementation{Name: s.name} }
+func (s testSession) ClientCapabilities() ClientCapabilities { return ClientCapabilities{} }
+
+type testLogger struct{ lines []string }
+
+func (l *testLogger) Printf(level string, format string, v ...interface{}) {
+	l.lines = append(l.lines, level+" "+fmt.Sprintf(format, v...))
+}
+
+func TestRequestContext(t *testing.T) {
+	logger := &testLogger{}
+	ctx := WithRequest(context.Background(), testSession{name: "host"}, "req-1", logger)
+
+	if s, ok := SessionFromContext(ctx); !ok || s.ClientInfo().Name != "host" {
+		t.Errorf("SessionFromContext = %v, %v", s, ok)
+	}
+	if id, ok := RequestIDFromContext(ctx); !ok || id != "req-1" {
+		t.Errorf("RequestIDFromContext = %v, %v", id, ok)
+	}
+	LoggerFromContext(ctx).Printf("DEBUG", "n=%d", 3)
+	if len(logger.lines) != 1 || logger.lines[0] != "DEBUG n=3" {
+		t.Errorf("logged %q", logger.lines)
+	}
+
+	// Outside a request the accessors report nothing and logging is a no-op
+	empty := context.Background()
+	if _, ok := SessionFromContext(empty); ok {
+		t.Error("SessionFromContext found a session in an empty context")
+	}
+	if _, ok := RequestIDFromContext(empty); ok {
+		t.Error("RequestIDFromContext found an ID in an empty context")
+	}
+	LoggerFromContext(empty).Printf("DEBUG", "dropped")
+}
@@ -0,0 +1,57 @@
+package mcp
+
+import (
+	"fmt"
+	"time"
+)
+
+// MetaDeprecation is the _meta key under which a tool or resource in a list
+// result carries its Deprecation.
+const MetaDeprecation = "deprecation"
+
+// Deprecation describes a tool or resource that is being phased out. Servers
+// list it under the item's _meta, e.g.
+//
+//	"_meta": {"deprecation": {"deprecated": true, "replacedBy": "query_table_v2", "sunset": "2026-12-31T00:00:00Z"}}
+type Deprecation struct {
+	// Deprecated is always true; it makes the marker self-describing.
+	Deprecated bool `json:"deprecated"`
+	// ReplacedBy names the tool, or the URI of the resource, to use instead.
+	ReplacedBy string `json:"replacedBy,omitempty"`
+	// Message says why, or what else to do.
+	Message string `json:"message,omitempty"`
+	// Sunset is when the item is removed; calls after it fail with ErrorCodeGone.
+	Sunset *time.Time `json:"sunset,omitempty"`
+}
+
+// Gone reports whether the item's sunset has passed at now.
+func (d Deprecation) Gone(now time.Time) bool {
+	return d.Sunset != nil && !now.Before(*d.Sunset)
+}
+
+// Notice returns a sentence for a description or log line, e.g.
+// "Deprecated: use query_table_v2 instead; removed on 2026-12-31."
+func (d Deprecation) Notice() string {
+	notice := "Deprecated"
+	if d.ReplacedBy != "" {
+		notice += fmt.Sprintf(": use %s instead", d.ReplacedBy)
+	}
+	if d.Sunset != nil {
+		notice += fmt.Sprintf("; removed on %s", d.Sunset.UTC().Format(time.DateOnly))
+	}
+	notice += "."
+	if d.Message != "" {
+		notice += " " + d.Message
+	}
+	return notice
+}
+
+// GoneData is the data of an ErrorCodeGone error.
+type GoneData struct {
+	// Tool is the name of the removed tool, or empty for a resource.
+	Tool string `json:"tool,omitempty"`
+	// URI is the URI of the removed resource, or empty for a tool.
+	URI        string    `json:"uri,omitempty"`
+	ReplacedBy string    `json:"replacedBy,omitempty"`
+	Sunset     time.Time `json:"sunset"`
+}
@@ -0,0 +1,26 @@
+package mcp
+
+// Actions a client reports in an ElicitResult.
+const (
+	ElicitAccept  = "accept"  // The user submitted the form
+	ElicitDecline = "decline" // The user explicitly refused
+	ElicitCancel  = "cancel"  // The user dismissed the request without choosing
+)
+
+// ElicitRequestParams defines the parameters for an "elicitation/create" request,
+// in which the server asks the client to collect information from the user.
+type ElicitRequestParams struct {
+	// Message is shown to the user.
+	Message string `json:"message"`
+	// RequestedSchema is a flat JSON Schema object describing the fields to collect.
+	// An object without properties asks for a plain confirmation.
+	RequestedSchema map[string]interface{} `json:"requestedSchema"`
+}
+
+// ElicitResult defines the result of an "elicitation/create" request.
+type ElicitResult struct {
+	// Action is ElicitAccept, ElicitDecline or ElicitCancel.
+	Action string `json:"action"`
+	// Content holds the submitted values when Action is ElicitAccept.
+	Content map[string]interface{} `json:"content,omitempty"`
+}
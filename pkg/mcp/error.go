@@ -3,6 +3,8 @@ package mcp
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 )
 
 // Standard JSON-RPC 2.0 Error codes
@@ -20,6 +22,22 @@ const (
 	// ErrorCodeInternalError indicates an internal JSON-RPC error.
 	ErrorCodeInternalError int = -32603
 	// -32000 to -32099 are reserved for implementation-defined server-errors.
+
+	// ErrorCodeServerNotReady indicates a request arrived before the client completed
+	// the initialize handshake with notifications/initialized.
+	ErrorCodeServerNotReady int = -32002
+	// ErrorCodeToolBusy indicates a tool call waited longer than the server's
+	// queue timeout for one of the tool's concurrency slots.
+	ErrorCodeToolBusy int = -32003
+	// ErrorCodeShuttingDown indicates the server is draining the session and no
+	// longer accepts new requests. Clients should reconnect.
+	ErrorCodeShuttingDown int = -32004
+	// ErrorCodeQuotaExceeded indicates the session has used up one of its budgets,
+	// e.g. its number of calls. The error data names the quota, its limit and the usage.
+	ErrorCodeQuotaExceeded int = -32005
+	// ErrorCodeGone indicates a deprecated tool or resource was used after its
+	// sunset. The error data is a GoneData naming the replacement, if any.
+	ErrorCodeGone int = -32006
 )
 
 // RPCError defines the structure for a JSON-RPC error object, according to the spec.
@@ -55,6 +73,24 @@ func MarshalErrorResponse(id RequestID, rpcErr *RPCError) ([]byte, error) {
 	return json.Marshal(resp)
 }
 
+// fallbackErrorResponse is the last-resort error response, used when nothing
+// naming the request can be marshalled.
+const fallbackErrorResponse = `{"jsonrpc":"2.0","error":{"code":-32603,"message":"Internal error"},"id":null}`
+
+// MustErrorResponse is like MarshalErrorResponse but always produces a response,
+// so a request is never left unanswered. If rpcErr cannot be marshalled (its Data,
+// typically), a generic InternalError for id is sent instead; if even that fails
+// because id itself is unmarshallable, a constant InternalError with a null id.
+func MustErrorResponse(id RequestID, rpcErr *RPCError) []byte {
+	if response, err := MarshalErrorResponse(id, rpcErr); err == nil {
+		return response
+	}
+	if response, err := MarshalErrorResponse(id, NewRPCError(ErrorCodeInternalError, "Failed to marshal error response", nil)); err == nil {
+		return response
+	}
+	return []byte(fallbackErrorResponse)
+}
+
 // UnmarshalErrorResponse attempts to parse a JSON-RPC error response.
 // It returns the RPCError details and the response ID if successful.
 // Returns nil error if parsing is successful, even if the response isn't an error response.
@@ -72,3 +108,90 @@ func UnmarshalErrorResponse(data []byte) (*RPCError, RequestID, error) {
 	// and the ID from the parsed response.
 	return resp.Error, resp.ID, nil
 }
+
+// errorHints suggest what to do about the well-known error codes.
+var errorHints = map[int]string{
+	ErrorCodeParseError:     "The message was not valid JSON or was too large.",
+	ErrorCodeInvalidRequest: "The message is not a valid JSON-RPC request.",
+	ErrorCodeMethodNotFound: "The server does not support this method; check the capabilities it announced.",
+	ErrorCodeInternalError:  "The server failed; its log may have details.",
+	ErrorCodeServerNotReady: "Complete the initialize handshake first.",
+	ErrorCodeToolBusy:       "Retry once the tool's other calls have finished.",
+	ErrorCodeShuttingDown:   "Reconnect to start a new session.",
+	ErrorCodeGone:           "It was removed; use its replacement, if the error names one.",
+	ErrorCodeInvalidParams:  "",
+}
+
+// FormatError renders an RPC error as text for a person, e.g.
+// "Tool 'ping': argument 'host' missing — expected string". subject names
+// what was asked for ("Tool 'ping'", "resources/read"), and may be empty.
+// Well-known fields of the structured data are worked into the text
+// ("argument", "expected"); the others are listed unless the message
+// already mentions them, and well-known codes get a hint on what to do.
+func FormatError(subject string, rpcErr *RPCError) string {
+	if rpcErr == nil {
+		return ""
+	}
+	data := errorData(rpcErr.Data)
+	message := strings.TrimSuffix(strings.TrimSpace(rpcErr.Message), ".")
+	if argument, ok := data["argument"]; ok {
+		if !strings.Contains(message, argument) {
+			message = fmt.Sprintf("argument '%s' %s", argument, message)
+		}
+		delete(data, "argument")
+	}
+	if expected, ok := data["expected"]; ok {
+		message += " — expected " + expected
+		delete(data, "expected")
+	}
+
+	var b strings.Builder
+	if subject != "" && !strings.HasPrefix(message, subject) {
+		b.WriteString(subject + ": ")
+	}
+	b.WriteString(message)
+	var details []string
+	for key, value := range data {
+		if !strings.Contains(message, value) {
+			details = append(details, key+": "+value)
+		}
+	}
+	if len(details) > 0 {
+		sort.Strings(details)
+		b.WriteString(" (" + strings.Join(details, ", ") + ")")
+	}
+	hint, known := errorHints[rpcErr.Code]
+	switch {
+	case !known:
+		fmt.Fprintf(&b, " [error %d]", rpcErr.Code)
+	case hint != "":
+		b.WriteString(". " + hint)
+	}
+	return b.String()
+}
+
+// errorData flattens the structured data of an error into strings. Data that
+// is not an object is returned under the key "data".
+func errorData(data interface{}) map[string]string {
+	out := make(map[string]string)
+	switch v := data.(type) {
+	case nil:
+	case map[string]string:
+		for key, value := range v {
+			out[key] = value
+		}
+	case map[string]interface{}:
+		for key, value := range v {
+			if s, ok := value.(string); ok {
+				out[key] = s
+			} else if encoded, err := json.Marshal(value); err == nil {
+				out[key] = string(encoded)
+			}
+		}
+	default:
+		if encoded, err := json.Marshal(v); err == nil {
+			out["data"] = string(encoded)
+		}
+	}
+	return out
+}
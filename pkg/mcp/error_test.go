@@ -288,3 +288,51 @@ func TestUnmarshalErrorResponse(t *testing.T) {
 		})
 	}
 }
+
+func TestFormatError(t *testing.T) {
+	tests := []struct {
+		subject string
+		err     *RPCError
+		want    string
+	}{
+		{"Tool 'ping'", NewRPCError(ErrorCodeInvalidParams, "missing", map[string]interface{}{"argument": "host", "expected": "string"}),
+			"Tool 'ping': argument 'host' missing — expected string"},
+		{"Tool 'ping'", NewRPCError(ErrorCodeToolBusy, "Tool 'ping' is busy: timed out", map[string]string{"tool": "ping"}),
+			"Tool 'ping' is busy: timed out. Retry once the tool's other calls have finished."},
+		{"tools/list", NewRPCError(ErrorCodeServerNotReady, "Server not ready", map[string]interface{}{"state": "awaiting_initialize"}),
+			"tools/list: Server not ready (state: awaiting_initialize). Complete the initialize handshake first."},
+		{"", NewRPCError(-32050, "Quota exceeded.", []interface{}{1, 2}),
+			"Quota exceeded (data: [1,2]) [error -32050]"},
+		{"Resource 'file:///x'", NewRPCError(ErrorCodeInvalidParams, "resource file:///x not found", map[string]string{"uri": "file:///x"}),
+			"Resource 'file:///x': resource file:///x not found"},
+	}
+	for _, tt := range tests {
+		if got := FormatError(tt.subject, tt.err); got != tt.want {
+			t.Errorf("FormatError(%q, %+v) =\n%q, want\n%q", tt.subject, tt.err, got, tt.want)
+		}
+	}
+	if got := FormatError("x", nil); got != "" {
+		t.Errorf("FormatError(nil) = %q", got)
+	}
+}
+
+func TestMustErrorResponse(t *testing.T) {
+	tests := []struct {
+		name   string
+		id     RequestID
+		rpcErr *RPCError
+		want   string
+	}{
+		{"marshallable", "a", NewRPCError(ErrorCodeInvalidParams, "bad", nil),
+			`{"jsonrpc":"2.0","error":{"code":-32602,"message":"bad"},"id":"a"}`},
+		{"bad data", 7, NewRPCError(ErrorCodeInvalidParams, "bad", func() {}),
+			`{"jsonrpc":"2.0","error":{"code":-32603,"message":"Failed to marshal error response"},"id":7}`},
+		{"bad id", make(chan int), NewRPCError(ErrorCodeInvalidParams, "bad", nil),
+			`{"jsonrpc":"2.0","error":{"code":-32603,"message":"Internal error"},"id":null}`},
+	}
+	for _, tt := range tests {
+		if got := string(MustErrorResponse(tt.id, tt.rpcErr)); got != tt.want {
+			t.Errorf("%s: MustErrorResponse() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
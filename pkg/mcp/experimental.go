@@ -0,0 +1,63 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Experimental holds non-standard capabilities, keyed by feature name, as found in
+// ClientCapabilities.Experimental and ServerCapabilities.Experimental.
+//
+// Values are kept as raw JSON, so features this package knows nothing about pass
+// through unchanged; extensions decode their own settings with Decode.
+type Experimental map[string]json.RawMessage
+
+// Has reports whether the feature is declared.
+func (e Experimental) Has(name string) bool {
+	_, ok := e[name]
+	return ok
+}
+
+// Decode unmarshals the settings of the feature into v. It returns false if the
+// feature is not declared.
+func (e Experimental) Decode(name string, v interface{}) (bool, error) {
+	raw, ok := e[name]
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return true, fmt.Errorf("invalid settings for experimental capability %q: %w", name, err)
+	}
+	return true, nil
+}
+
+// Set declares the feature with the given settings, which are marshalled to JSON.
+// Use an empty struct for a feature without settings.
+func (e *Experimental) Set(name string, settings interface{}) error {
+	raw, err := json.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("failed to marshal experimental capability %q: %w", name, err)
+	}
+	if *e == nil {
+		*e = make(Experimental)
+	}
+	(*e)[name] = raw
+	return nil
+}
+
+// Names returns the declared feature names in sorted order.
+func (e Experimental) Names() []string {
+	names := make([]string, 0, len(e))
+	for name := range e {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ExperimentalNegotiator decides the server side of one experimental capability.
+// It receives the client's settings for the feature, or nil if the client did not
+// declare it, and returns the settings the server advertises. Returning ok=false
+// leaves the feature out of the server's capabilities.
+type ExperimentalNegotiator func(client json.RawMessage) (settings interface{}, ok bool)
@@ -0,0 +1,58 @@
+package mcp
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestExperimentalPassthrough(t *testing.T) {
+	// Unknown feature settings survive a decode/encode round trip byte for byte
+	// (keys are in sorted order, as encoding/json writes maps)
+	data := `{"experimental":{"flag":{},"vendor.trace":{"level":3,"nested":{"a":[1,2]}}}}`
+	var caps ClientCapabilities
+	if err := json.Unmarshal([]byte(data), &caps); err != nil {
+		t.Fatal(err)
+	}
+	out, err := json.Marshal(caps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != data {
+		t.Errorf("round trip = %s, want %s", out, data)
+	}
+	if got := caps.Experimental.Names(); !reflect.DeepEqual(got, []string{"flag", "vendor.trace"}) {
+		t.Errorf("Names() = %v", got)
+	}
+}
+
+func TestExperimentalAccessors(t *testing.T) {
+	var e Experimental
+	if e.Has("x") {
+		t.Error("Has() on nil map = true")
+	}
+	type settings struct {
+		Level int `json:"level"`
+	}
+	if err := e.Set("x", settings{Level: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if !e.Has("x") {
+		t.Error("Has() after Set = false")
+	}
+
+	var got settings
+	if ok, err := e.Decode("x", &got); !ok || err != nil || got.Level != 2 {
+		t.Errorf("Decode() = %v, %v, %+v", ok, err, got)
+	}
+	if ok, err := e.Decode("missing", &got); ok || err != nil {
+		t.Errorf("Decode(missing) = %v, %v, want false, nil", ok, err)
+	}
+	var wrong []string
+	if ok, err := e.Decode("x", &wrong); !ok || err == nil {
+		t.Errorf("Decode() into wrong type = %v, %v, want an error", ok, err)
+	}
+	if err := e.Set("bad", make(chan int)); err == nil {
+		t.Error("Set() with unmarshalable settings succeeded")
+	}
+}
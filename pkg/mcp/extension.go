@@ -0,0 +1,36 @@
+package mcp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ExtensionMethodPrefix starts the name of every non-standard method, which
+// is x-<vendor>/<method>, e.g. "x-acme/reindex". The prefix keeps extensions
+// clear of the methods later protocol revisions may add.
+const ExtensionMethodPrefix = "x-"
+
+// ExperimentalMethods is the ServerCapabilities.Experimental key under which a
+// server lists the extension methods it answers, e.g.
+// {"methods": ["x-acme/reindex"]}.
+const ExperimentalMethods = "methods"
+
+// ValidateExtensionMethod reports whether method is named x-<vendor>/<method>,
+// with a vendor and a method name that are both non-empty.
+func ValidateExtensionMethod(method string) error {
+	vendor, name, ok := strings.Cut(strings.TrimPrefix(method, ExtensionMethodPrefix), "/")
+	if !strings.HasPrefix(method, ExtensionMethodPrefix) || !ok || vendor == "" || name == "" {
+		return fmt.Errorf("extension method %q is not named %s<vendor>/<method>", method, ExtensionMethodPrefix)
+	}
+	return nil
+}
+
+// ExtensionMethods returns the extension methods the server advertises under
+// ExperimentalMethods, or nil if it lists none.
+func (c ServerCapabilities) ExtensionMethods() []string {
+	var methods []string
+	if _, err := c.Experimental.Decode(ExperimentalMethods, &methods); err != nil {
+		return nil
+	}
+	return methods
+}
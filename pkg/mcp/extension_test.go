@@ -0,0 +1,34 @@
+package mcp
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestValidateExtensionMethod(t *testing.T) {
+	for method, ok := range map[string]bool{
+		"x-acme/reindex":     true,
+		"x-acme/index/build": true,
+		"acme/reindex":       false,
+		"x-acme":             false,
+		"x-/reindex":         false,
+		"x-acme/":            false,
+		MethodPing:           false,
+	} {
+		if err := ValidateExtensionMethod(method); (err == nil) != ok {
+			t.Errorf("ValidateExtensionMethod(%q) = %v, want ok %v", method, err, ok)
+		}
+	}
+}
+
+func TestExtensionMethods(t *testing.T) {
+	caps := ServerCapabilities{Experimental: Experimental{ExperimentalMethods: json.RawMessage(`["x-acme/reindex"]`)}}
+	if got := caps.ExtensionMethods(); !reflect.DeepEqual(got, []string{"x-acme/reindex"}) {
+		t.Errorf("ExtensionMethods = %q", got)
+	}
+	caps.Experimental[ExperimentalMethods] = json.RawMessage(`"x-acme/reindex"`)
+	if got := caps.ExtensionMethods(); got != nil {
+		t.Errorf("ExtensionMethods of a malformed list = %q", got)
+	}
+}
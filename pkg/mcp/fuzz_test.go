@@ -0,0 +1,114 @@
+package mcp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// fuzzSeeds are valid messages of every kind; each is added to the corpus along with truncated copies.
+var fuzzSeeds = []string{
+	`{"jsonrpc":"2.0","method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"c","version":"1"}},"id":1}`,
+	`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+	`{"jsonrpc":"2.0","method":"tools/call","params":{"name":"ping","arguments":{}},"id":"abc"}`,
+	`{"jsonrpc":"2.0","result":{"protocolVersion":"2024-11-05","capabilities":{"tools":{}},"serverInfo":{"name":"s","version":"1"}},"id":1}`,
+	`{"jsonrpc":"2.0","result":{"tools":[{"name":"ping","inputSchema":{"type":"object"}}],"nextCursor":"n"},"id":2}`,
+	`{"jsonrpc":"2.0","result":{"content":[{"type":"text","text":"ok"}],"isError":true},"id":3}`,
+	`{"jsonrpc":"2.0","result":{"contents":[{"uri":"file:///a","text":"x"}]},"id":4}`,
+	`{"jsonrpc":"2.0","result":{"resources":[{"name":"a","uri":"file:///a","size":3}]},"id":5}`,
+	`{"jsonrpc":"2.0","result":{"resourceTemplates":[{"name":"t","uriTemplate":"data://x{y}"}]},"id":6}`,
+	`{"jsonrpc":"2.0","result":{"prompts":[{"name":"q","arguments":[{"name":"a","required":true}]}]},"id":7}`,
+	`{"jsonrpc":"2.0","result":{"messages":[{"role":"user","content":{"type":"text","text":"hi"}}]},"id":8}`,
+	`{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found","data":{"m":"x"}},"id":9}`,
+	`{"jsonrpc":"2.0","error":{"code":-32700,"message":"Parse error"},"id":null}`,
+	`{"jsonrpc":"2.0","result":null,"id":10}`,
+	`{"jsonrpc":"2.0","id":{"nested":true},"method":"x"}`,
+	`{"jsonrpc":"1.0","method":"ping","id":1}`,
+	`{}`,
+	`null`,
+}
+
+func addFuzzSeeds(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+		f.Add([]byte(seed[:len(seed)/2])) // Truncated in the middle
+		f.Add([]byte(seed[:len(seed)-1])) // Missing the closing brace
+		f.Add([]byte(seed + "\n" + seed)) // Two messages in one frame
+	}
+}
+
+func FuzzClassifyMessage(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, payload []byte) {
+		info, err := ClassifyMessage(payload)
+		if err != nil {
+			if info.Kind != KindInvalid {
+				t.Fatalf("ClassifyMessage(%q) returned error %v with kind %v", payload, err, info.Kind)
+			}
+			return
+		}
+
+		switch id := info.ID.(type) {
+		case nil, string, json.Number:
+		default:
+			t.Fatalf("ClassifyMessage(%q) returned id of type %T", payload, id)
+		}
+
+		switch info.Kind {
+		case KindRequest:
+			if info.ID == nil || info.Method == "" {
+				t.Fatalf("request without id or method: %q -> %+v", payload, info)
+			}
+		case KindNotification:
+			if info.ID != nil || info.Method == "" {
+				t.Fatalf("notification with id or without method: %q -> %+v", payload, info)
+			}
+		case KindResponse, KindErrorResponse:
+			if info.ID == nil {
+				t.Fatalf("response without id: %q -> %+v", payload, info)
+			}
+		default:
+			t.Fatalf("ClassifyMessage(%q) returned kind %v without error", payload, info.Kind)
+		}
+	})
+}
+
+func FuzzUnmarshalResponses(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Every typed unmarshaller must either fail, return an RPC error, or return a non-nil result.
+		check := func(name string, resultIsNil bool, rpcErr *RPCError, err error) {
+			if err == nil && rpcErr == nil && resultIsNil {
+				t.Fatalf("%s(%q) returned nil result without an error", name, data)
+			}
+		}
+
+		initResult, _, rpcErr, err := UnmarshalInitializeResponse(data)
+		check("UnmarshalInitializeResponse", initResult == nil, rpcErr, err)
+
+		toolsResult, _, rpcErr, err := UnmarshalListToolsResponse(data)
+		check("UnmarshalListToolsResponse", toolsResult == nil, rpcErr, err)
+
+		callResult, _, rpcErr, err := UnmarshalCallToolResponse(data)
+		check("UnmarshalCallToolResponse", callResult == nil, rpcErr, err)
+
+		resourcesResult, _, rpcErr, err := UnmarshalListResourcesResponse(data)
+		check("UnmarshalListResourcesResponse", resourcesResult == nil, rpcErr, err)
+
+		templatesResult, _, rpcErr, err := UnmarshalListResourceTemplatesResponse(data)
+		check("UnmarshalListResourceTemplatesResponse", templatesResult == nil, rpcErr, err)
+
+		readResult, _, rpcErr, err := UnmarshalReadResourcesResponse(data)
+		check("UnmarshalReadResourcesResponse", readResult == nil, rpcErr, err)
+
+		promptsResult, _, rpcErr, err := UnmarshalListPromptsResponse(data)
+		check("UnmarshalListPromptsResponse", promptsResult == nil, rpcErr, err)
+
+		promptResult, _, rpcErr, err := UnmarshalGetPromptResponse(data)
+		check("UnmarshalGetPromptResponse", promptResult == nil, rpcErr, err)
+
+		rpcErr, _, err = UnmarshalErrorResponse(data)
+		if err != nil && rpcErr == nil {
+			t.Fatalf("UnmarshalErrorResponse(%q) failed without returning a parse error: %v", data, err)
+		}
+	})
+}
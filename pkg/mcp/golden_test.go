@@ -0,0 +1,206 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Run `go test ./pkg/mcp -run TestGolden -update` to regenerate the fixtures after an intentional wire format change.
+var updateGolden = flag.Bool("update", false, "update golden files in testdata/golden")
+
+// goldenProtocolVersions lists the protocol revisions the fixtures are generated for.
+var goldenProtocolVersions = []string{"2024-11-05"}
+
+// goldenCase produces the wire bytes of one message for a given protocol version.
+type goldenCase struct {
+	name    string
+	marshal func(version string) ([]byte, error)
+}
+
+// marshalGoldenResult wraps a result in a JSON-RPC response envelope, as a server would send it.
+func marshalGoldenResult(id RequestID, result interface{}) ([]byte, error) {
+	resultBytes, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(RPCResponse{JSONRPC: JSONRPCVersion, Result: resultBytes, ID: id})
+}
+
+func goldenCases() []goldenCase {
+	priority := 0.5
+	size := 42
+	textContent, _ := json.Marshal(TextContent{Type: "text", Text: "hello"})
+	textResource, _ := json.Marshal(TextResourceContents{URI: "file:///documents/example.txt", MimeType: "text/plain", Text: "example"})
+	blobResource, _ := json.Marshal(BlobResourceContents{URI: "data://blob", MimeType: "application/octet-stream", Blob: "AAEC"})
+
+	return []goldenCase{
+		{"initialize_request", func(v string) ([]byte, error) {
+			return MarshalInitializeRequest(1, InitializeParams{
+				ProtocolVersion: v,
+				ClientInfo:      Implementation{Name: "client", Version: "1.0.0"},
+				Capabilities:    ClientCapabilities{Sampling: map[string]interface{}{}},
+			})
+		}},
+		{"initialize_response", func(v string) ([]byte, error) {
+			return marshalGoldenResult(1, InitializeResult{
+				ProtocolVersion: v,
+				ServerInfo:      Implementation{Name: "server", Version: "0.1.0"},
+				Capabilities: ServerCapabilities{
+					Prompts:   &ServerCapabilitiesPrompts{ListChanged: true},
+					Resources: &ServerCapabilitiesResources{Subscribe: true},
+					Tools:     &ServerCapabilitiesTools{},
+				},
+				Instructions: "instructions",
+			})
+		}},
+		{"initialize_response_with_warnings", func(v string) ([]byte, error) {
+			return marshalGoldenResult(1, InitializeResult{
+				Meta: map[string]interface{}{MetaKeyCapabilityWarnings: []CapabilityWarning{
+					{Capability: "resources.subscribe", Reason: "not supported by this server"},
+				}},
+				ProtocolVersion: v,
+				ServerInfo:      Implementation{Name: "server", Version: "0.1.0"},
+				Capabilities:    ServerCapabilities{Resources: &ServerCapabilitiesResources{}},
+			})
+		}},
+		{"list_tools_request", func(v string) ([]byte, error) {
+			return MarshalListToolsRequest("tools-1", &ListToolsParams{Cursor: "c1"})
+		}},
+		{"list_tools_request_nil_params", func(v string) ([]byte, error) {
+			return MarshalListToolsRequest(2, nil)
+		}},
+		{"list_tools_response", func(v string) ([]byte, error) {
+			return marshalGoldenResult(2, ListToolsResult{
+				Tools: []Tool{{
+					Name:        "ping",
+					Description: "Pings a host.",
+					InputSchema: ToolInputSchema{"type": "object", "properties": map[string]interface{}{}},
+				}},
+				NextCursor: "c2",
+			})
+		}},
+		{"call_tool_request", func(v string) ([]byte, error) {
+			return MarshalCallToolRequest(3, CallToolParams{Name: "ping", Arguments: map[string]interface{}{"host": "localhost"}})
+		}},
+		{"call_tool_response", func(v string) ([]byte, error) {
+			return marshalGoldenResult(3, CallToolResult{Content: []json.RawMessage{textContent}, IsError: true})
+		}},
+		{"call_tool_response_structured", func(v string) ([]byte, error) {
+			result, err := NewToolResultStructured(map[string]interface{}{"temperature": 22.5, "conditions": "Partly cloudy"})
+			if err != nil {
+				return nil, err
+			}
+			return marshalGoldenResult(3, result)
+		}},
+		{"list_resources_request", func(v string) ([]byte, error) {
+			return MarshalListResourcesRequest(4, nil)
+		}},
+		{"list_resources_response", func(v string) ([]byte, error) {
+			return marshalGoldenResult(4, ListResourcesResult{Resources: []Resource{{
+				Name:        "example.txt",
+				URI:         "file:///documents/example.txt",
+				Description: "An example text file.",
+				MimeType:    "text/plain",
+				Size:        &size,
+				Annotations: &Annotations{Audience: []Role{RoleUser}, Priority: &priority},
+			}}})
+		}},
+		{"list_resource_templates_request", func(v string) ([]byte, error) {
+			return MarshalListResourceTemplatesRequest(5, &ListResourceTemplatesParams{})
+		}},
+		{"list_resource_templates_response", func(v string) ([]byte, error) {
+			return marshalGoldenResult(5, ListResourceTemplatesResult{ResourceTemplates: []ResourceTemplate{{
+				Name:        "random_data",
+				URITemplate: "data://random_data?length={length}",
+				MimeType:    "text/plain",
+			}}})
+		}},
+		{"read_resource_request", func(v string) ([]byte, error) {
+			return MarshalReadResourcesRequest(6, ReadResourceParams{URI: "file:///documents/example.txt"})
+		}},
+		{"read_resource_response", func(v string) ([]byte, error) {
+			return marshalGoldenResult(6, ReadResourceResult{Contents: []json.RawMessage{textResource, blobResource}})
+		}},
+		{"list_prompts_request", func(v string) ([]byte, error) {
+			return MarshalListPromptsRequest(7, nil)
+		}},
+		{"list_prompts_response", func(v string) ([]byte, error) {
+			return marshalGoldenResult(7, ListPromptsResult{Prompts: []Prompt{{
+				Name:        "query",
+				Description: "A query prompt",
+				Arguments:   []PromptArgument{{Name: "q", Description: "The query", Required: true}},
+			}}})
+		}},
+		{"get_prompt_request", func(v string) ([]byte, error) {
+			return MarshalGetPromptRequest(8, GetPromptParams{Name: "query", Arguments: map[string]string{"q": "what"}})
+		}},
+		{"get_prompt_response", func(v string) ([]byte, error) {
+			return marshalGoldenResult(8, GetPromptResult{
+				Description: "A query prompt",
+				Messages:    []PromptMessage{{Role: RoleUser, Content: textContent}},
+			})
+		}},
+		{"error_response", func(v string) ([]byte, error) {
+			return MarshalErrorResponse(9, NewRPCError(ErrorCodeMethodNotFound, "Method 'x' not found", map[string]string{"method": "x"}))
+		}},
+		{"error_response_null_id", func(v string) ([]byte, error) {
+			return MarshalErrorResponse(nil, NewRPCError(ErrorCodeParseError, "Parse error", nil))
+		}},
+		{"resource_updated_notification", func(v string) ([]byte, error) {
+			return MarshalNotification(MethodResourceUpdated, ResourceUpdatedParams{URI: "file:///documents/example.txt"})
+		}},
+		{"tools_list_changed_notification", func(v string) ([]byte, error) {
+			return MarshalNotification(MethodToolListChanged, nil)
+		}},
+		{"shutdown_notification", func(v string) ([]byte, error) {
+			return MarshalNotification(MethodShutdown, ShutdownParams{Reason: "server shutting down"})
+		}},
+	}
+}
+
+// TestGolden asserts that every marshal helper still produces the wire format recorded in testdata/golden.
+// Comparison ignores key order and whitespace, so only semantic changes to the JSON fail the test.
+func TestGolden(t *testing.T) {
+	for _, version := range goldenProtocolVersions {
+		for _, tc := range goldenCases() {
+			t.Run(version+"/"+tc.name, func(t *testing.T) {
+				got, err := tc.marshal(version)
+				if err != nil {
+					t.Fatalf("marshal failed: %v", err)
+				}
+				path := filepath.Join("testdata", "golden", version, tc.name+".json")
+
+				if *updateGolden {
+					var indented bytes.Buffer
+					if err := json.Indent(&indented, got, "", "  "); err != nil {
+						t.Fatalf("failed to indent %s: %v", path, err)
+					}
+					indented.WriteByte('\n')
+					if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+						t.Fatalf("failed to create golden directory: %v", err)
+					}
+					if err := os.WriteFile(path, indented.Bytes(), 0644); err != nil {
+						t.Fatalf("failed to write golden file: %v", err)
+					}
+					return
+				}
+
+				want, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatalf("failed to read golden file (run with -update to create it): %v", err)
+				}
+				equal, err := jsonEqual(got, want)
+				if err != nil {
+					t.Fatalf("error comparing JSON: %v", err)
+				}
+				if !equal {
+					t.Errorf("wire format changed for %s\ngot:  %s\nwant: %s", path, got, want)
+				}
+			})
+		}
+	}
+}
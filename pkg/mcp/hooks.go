@@ -0,0 +1,114 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Hooks are called at points in the life of a session, so that embedders can
+// add telemetry and policy without wrapping the transport. Servers and clients
+// call the same hooks from their side of the session; any field may be nil.
+//
+// On a server ctx carries the session, the request ID of request hooks and the
+// session logger (see SessionFromContext); on a client it is a plain context
+// carrying the request ID of request hooks.
+type Hooks struct {
+	// OnInitialize is called with the initialize params before they are
+	// answered (server) or sent (client). A non-nil error rejects the
+	// initialize request, or fails the client's handshake.
+	OnInitialize func(ctx context.Context, params InitializeParams) *RPCError
+	// OnInitialized is called once the handshake has completed.
+	OnInitialized func(ctx context.Context)
+	// OnRequest is called before a request is handled (server) or sent
+	// (client). A non-nil error is the request's answer instead.
+	OnRequest func(ctx context.Context, method string, params json.RawMessage) *RPCError
+	// OnResponse is called with the response to a request, successful or not,
+	// and the time the request took.
+	OnResponse func(ctx context.Context, method string, response []byte, d time.Duration)
+	// OnNotification is called for every notification received.
+	OnNotification func(ctx context.Context, method string, params json.RawMessage)
+	// OnError is called for every error response: sent by a server, received
+	// by a client. method is empty if the request could not be read.
+	OnError func(ctx context.Context, method string, err *RPCError)
+	// OnShutdown is called when the session ends, with the reason.
+	OnShutdown func(ctx context.Context, reason string)
+}
+
+// ChainHooks returns Hooks that call each of hooks in order. OnInitialize and
+// OnRequest stop at the first error, which is returned.
+func ChainHooks(hooks ...Hooks) Hooks {
+	if len(hooks) == 1 {
+		return hooks[0]
+	}
+	return Hooks{
+		OnInitialize: func(ctx context.Context, params InitializeParams) *RPCError {
+			for _, h := range hooks {
+				if h.OnInitialize != nil {
+					if err := h.OnInitialize(ctx, params); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		OnInitialized: func(ctx context.Context) {
+			for _, h := range hooks {
+				if h.OnInitialized != nil {
+					h.OnInitialized(ctx)
+				}
+			}
+		},
+		OnRequest: func(ctx context.Context, method string, params json.RawMessage) *RPCError {
+			for _, h := range hooks {
+				if h.OnRequest != nil {
+					if err := h.OnRequest(ctx, method, params); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		OnResponse: func(ctx context.Context, method string, response []byte, d time.Duration) {
+			for _, h := range hooks {
+				if h.OnResponse != nil {
+					h.OnResponse(ctx, method, response, d)
+				}
+			}
+		},
+		OnNotification: func(ctx context.Context, method string, params json.RawMessage) {
+			for _, h := range hooks {
+				if h.OnNotification != nil {
+					h.OnNotification(ctx, method, params)
+				}
+			}
+		},
+		OnError: func(ctx context.Context, method string, err *RPCError) {
+			for _, h := range hooks {
+				if h.OnError != nil {
+					h.OnError(ctx, method, err)
+				}
+			}
+		},
+		OnShutdown: func(ctx context.Context, reason string) {
+			for _, h := range hooks {
+				if h.OnShutdown != nil {
+					h.OnShutdown(ctx, reason)
+				}
+			}
+		},
+	}
+}
+
+// MessageParams returns the params of a request or notification payload,
+// normalized so that a missing, null or empty params member is {} (see
+// NormalizeParams), or nil if the payload cannot be decoded.
+func MessageParams(payload []byte) json.RawMessage {
+	var msg struct {
+		Params json.RawMessage `json:"params"`
+	}
+	if json.Unmarshal(payload, &msg) != nil {
+		return nil
+	}
+	return NormalizeParams(msg.Params)
+}
@@ -0,0 +1,53 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestChainHooks(t *testing.T) {
+	var calls []string
+	first := Hooks{
+		OnRequest: func(context.Context, string, json.RawMessage) *RPCError {
+			calls = append(calls, "first")
+			return nil
+		},
+		OnShutdown: func(_ context.Context, reason string) { calls = append(calls, "first "+reason) },
+	}
+	second := Hooks{
+		OnRequest: func(_ context.Context, method string, _ json.RawMessage) *RPCError {
+			calls = append(calls, "second")
+			return NewRPCError(ErrorCodeInvalidRequest, method+" refused", nil)
+		},
+	}
+	third := Hooks{
+		OnRequest: func(context.Context, string, json.RawMessage) *RPCError {
+			calls = append(calls, "third")
+			return nil
+		},
+	}
+	h := ChainHooks(first, second, third)
+	if err := h.OnRequest(context.Background(), "tools/list", nil); err == nil || err.Message != "tools/list refused" {
+		t.Errorf("OnRequest = %v, want the second hook's error", err)
+	}
+	h.OnShutdown(context.Background(), "done")
+	h.OnInitialized(context.Background()) // No hook sets it
+	if len(calls) != 3 || calls[0] != "first" || calls[1] != "second" || calls[2] != "first done" {
+		t.Errorf("calls = %q", calls)
+	}
+}
+
+func TestMessageParams(t *testing.T) {
+	if got := MessageParams([]byte(`{"jsonrpc":"2.0","id":1,"method":"x","params":{"a":1}}`)); string(got) != `{"a":1}` {
+		t.Errorf("MessageParams = %s", got)
+	}
+	for _, payload := range []string{`{"jsonrpc":"2.0","method":"x"}`, `{"jsonrpc":"2.0","method":"x","params":null}`, `{"jsonrpc":"2.0","method":"x","params":{ }}`} {
+		if got := MessageParams([]byte(payload)); string(got) != `{}` {
+			t.Errorf("MessageParams(%s) = %s, want {}", payload, got)
+		}
+	}
+	if got := MessageParams([]byte(`not json`)); got != nil {
+		t.Errorf("MessageParams of garbage = %s", got)
+	}
+}
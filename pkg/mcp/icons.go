@@ -0,0 +1,91 @@
+package mcp
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// MaxIconBytes is the largest image an icon may embed as a data URI. Icons are
+// sent in every list response that carries them, so they are kept small.
+const MaxIconBytes = 64 << 10
+
+// Icon is an image a graphical host can show for a server, tool or prompt.
+type Icon struct {
+	// MimeType is the image type, e.g. "image/png"; optional for hosted icons.
+	MimeType string `json:"mimeType,omitempty"`
+	// Sizes lists the sizes the image suits, each "WxH" (e.g. "48x48") or "any"
+	// for scalable images.
+	Sizes []string `json:"sizes,omitempty"`
+	// Src is an https URL or a data: URI holding the image.
+	Src string `json:"src"`
+}
+
+// ErrIconTooLarge is returned for a data: URI icon over MaxIconBytes.
+var ErrIconTooLarge = errors.New("icon exceeds the maximum size")
+
+// NewDataIcon returns an icon embedding data as a base64 data: URI.
+func NewDataIcon(data []byte, mimeType string, sizes ...string) (Icon, error) {
+	icon := Icon{
+		MimeType: mimeType,
+		Sizes:    sizes,
+		Src:      "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data),
+	}
+	if err := icon.Validate(); err != nil {
+		return Icon{}, err
+	}
+	return icon, nil
+}
+
+// Validate checks that the icon is an https URL or a base64 image data: URI of
+// at most MaxIconBytes, and that its sizes are well formed.
+func (i Icon) Validate() error {
+	if i.MimeType != "" && !strings.HasPrefix(i.MimeType, "image/") {
+		return fmt.Errorf("icon MIME type %q is not an image", i.MimeType)
+	}
+	for _, size := range i.Sizes {
+		if !validIconSize(size) {
+			return fmt.Errorf("icon size %q is not WxH or any", size)
+		}
+	}
+	if data, ok := strings.CutPrefix(i.Src, "data:"); ok {
+		header, payload, ok := strings.Cut(data, ",")
+		mimeType, base64Encoded := strings.CutSuffix(header, ";base64")
+		if !ok || !base64Encoded || !strings.HasPrefix(mimeType, "image/") {
+			return fmt.Errorf("icon data URI is not a base64 image")
+		}
+		if base64.StdEncoding.DecodedLen(len(payload)) > MaxIconBytes+2 { // DecodedLen ignores padding
+			return fmt.Errorf("%w of %d bytes", ErrIconTooLarge, MaxIconBytes)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			return fmt.Errorf("icon data URI: %w", err)
+		}
+		if len(decoded) > MaxIconBytes {
+			return fmt.Errorf("%w of %d bytes", ErrIconTooLarge, MaxIconBytes)
+		}
+		return nil
+	}
+	u, err := url.Parse(i.Src)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("icon source %q is neither an https URL nor a data URI", i.Src)
+	}
+	return nil
+}
+
+// validIconSize reports whether size is "any" or "WxH" with positive W and H.
+func validIconSize(size string) bool {
+	if size == "any" {
+		return true
+	}
+	w, h, ok := strings.Cut(size, "x")
+	if !ok {
+		return false
+	}
+	width, errW := strconv.Atoi(w)
+	height, errH := strconv.Atoi(h)
+	return errW == nil && errH == nil && width > 0 && height > 0
+}
@@ -0,0 +1,44 @@
+package mcp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestIconValidate(t *testing.T) {
+	small, err := NewDataIcon([]byte("\x89PNG"), "image/png", "16x16")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if small.Src != "data:image/png;base64,iVBORw==" {
+		t.Errorf("Src = %q", small.Src)
+	}
+	if _, err := NewDataIcon(make([]byte, MaxIconBytes+1), "image/png"); !errors.Is(err, ErrIconTooLarge) {
+		t.Errorf("oversized icon error = %v, want ErrIconTooLarge", err)
+	}
+	if _, err := NewDataIcon(make([]byte, MaxIconBytes), "image/png"); err != nil {
+		t.Errorf("icon of MaxIconBytes: %v", err)
+	}
+
+	for _, tt := range []struct {
+		icon  Icon
+		valid bool
+	}{
+		{Icon{Src: "https://example.com/icon.svg", MimeType: "image/svg+xml", Sizes: []string{"any"}}, true},
+		{Icon{Src: "https://example.com/icon.png", Sizes: []string{"48x48", "96x96"}}, true},
+		{Icon{Src: "http://example.com/icon.png"}, false},
+		{Icon{Src: "file:///icon.png"}, false},
+		{Icon{Src: "https://example.com/icon.png", Sizes: []string{"48"}}, false},
+		{Icon{Src: "https://example.com/icon.png", Sizes: []string{"0x48"}}, false},
+		{Icon{Src: "https://example.com/icon.png", MimeType: "text/html"}, false},
+		{Icon{Src: "data:text/plain;base64,aGk="}, false},
+		{Icon{Src: "data:image/png,raw"}, false},
+		{Icon{Src: "data:image/png;base64,!!"}, false},
+		{Icon{Src: "data:image/png;base64," + strings.Repeat("A", MaxIconBytes*2)}, false},
+	} {
+		if err := tt.icon.Validate(); (err == nil) != tt.valid {
+			t.Errorf("Validate(%+v) = %v, want valid %v", tt.icon, err, tt.valid)
+		}
+	}
+}
@@ -8,23 +8,35 @@ import (
 // MethodInitialize is the method name for the initialize request.
 const MethodInitialize = "initialize"
 
+// MethodInitialized is the method name of the notification a client sends
+// once it has processed the InitializeResult.
+const MethodInitialized = "notifications/initialized"
+
+// MethodInitializedLegacy is the pre-spec name for MethodInitialized still
+// sent by some older clients.
+const MethodInitializedLegacy = "initialized"
+
 // Implementation describes the name and version of an MCP implementation (client or server).
 type Implementation struct {
+	Icons   []Icon `json:"icons,omitempty"` // Images a graphical host can show for the implementation
 	Name    string `json:"name"`
+	Title   string `json:"title,omitempty"` // For display; clients fall back to Name
 	Version string `json:"version"`
 }
 
 // ClientCapabilities defines the capabilities a client may support.
 // Using map[string]interface{} for flexibility with experimental and future capabilities.
 type ClientCapabilities struct {
-	// Experimental holds non-standard capabilities.
-	Experimental map[string]interface{} `json:"experimental,omitempty"`
+	// Experimental holds non-standard capabilities, see experimental.go.
+	Experimental Experimental `json:"experimental,omitempty"`
 	// Roots indicates support for listing roots.
 	Roots *struct {
 		ListChanged bool `json:"listChanged,omitempty"`
 	} `json:"roots,omitempty"`
 	// Sampling indicates support for LLM sampling.
-	Sampling map[string]interface{} `json:"sampling,omitempty"` // Use map for flexibility
+	Sampling map[string]interface{} `json:"sampling,omitzero"` // Use map for flexibility; omitzero keeps an empty {}
+	// Elicitation indicates support for elicitation/create requests.
+	Elicitation map[string]interface{} `json:"elicitation,omitzero"`
 }
 
 // InitializeParams defines the parameters for an "initialize" request.
@@ -32,15 +44,20 @@ type InitializeParams struct {
 	Capabilities    ClientCapabilities `json:"capabilities"`
 	ClientInfo      Implementation     `json:"clientInfo"`
 	ProtocolVersion string             `json:"protocolVersion"`
+	// Meta contains reserved protocol metadata, e.g. MetaKeyLocale.
+	Meta map[string]interface{} `json:"_meta,omitempty"`
 	// Add other optional fields from the spec like processId, rootUri, trace, workspaceFolders if needed.
 }
 
 // ServerCapabilities defines the capabilities a server may support.
 // Using map[string]interface{} for flexibility.
 type ServerCapabilities struct {
-	// Experimental holds non-standard capabilities.
-	Experimental map[string]interface{} `json:"experimental,omitempty"`
-	// Logging indicates support for sending log messages.
+	// Completions indicates support for completion/complete.
+	Completions *ServerCapabilitiesCompletions `json:"completions,omitempty"`
+	// Experimental holds non-standard capabilities, see experimental.go.
+	Experimental Experimental `json:"experimental,omitempty"`
+	// Logging indicates support for sending log messages. A non-nil empty map
+	// is announced as {}, see MarshalJSON.
 	Logging map[string]interface{} `json:"logging,omitempty"` // Use map for flexibility
 	// Prompts indicates support for prompt templates.
 	Prompts *ServerCapabilitiesPrompts `json:"prompts,omitempty"`
@@ -48,7 +65,27 @@ type ServerCapabilities struct {
 	Resources *ServerCapabilitiesResources `json:"resources,omitempty"`
 	// Tools indicates support for tools.
 	Tools *ServerCapabilitiesTools `json:"tools,omitempty"`
-	// Add other capabilities like completion if needed.
+}
+
+// ServerCapabilitiesCompletions announces completion/complete. It has no
+// settings: a non-nil value is marshalled as {}, which is the announcement.
+type ServerCapabilitiesCompletions struct{}
+
+// MarshalJSON writes the capabilities with a non-nil Logging map, even an
+// empty one, as "logging":{}: omitempty alone would leave the announcement out.
+func (c ServerCapabilities) MarshalJSON() ([]byte, error) {
+	out := struct {
+		Completions  *ServerCapabilitiesCompletions `json:"completions,omitempty"`
+		Experimental Experimental                   `json:"experimental,omitempty"`
+		Logging      *map[string]interface{}        `json:"logging,omitempty"`
+		Prompts      *ServerCapabilitiesPrompts     `json:"prompts,omitempty"`
+		Resources    *ServerCapabilitiesResources   `json:"resources,omitempty"`
+		Tools        *ServerCapabilitiesTools       `json:"tools,omitempty"`
+	}{c.Completions, c.Experimental, nil, c.Prompts, c.Resources, c.Tools}
+	if c.Logging != nil {
+		out.Logging = &c.Logging
+	}
+	return json.Marshal(out)
 }
 
 // ServerCapabilitiesPrompts defines specific capabilities related to prompts.
@@ -81,13 +118,7 @@ type InitializeResult struct {
 // MarshalInitializeRequest creates a JSON-RPC request for the initialize method.
 // The id can be a string or an integer.
 func MarshalInitializeRequest(id RequestID, params InitializeParams) ([]byte, error) {
-	req := RPCRequest{
-		JSONRPC: JSONRPCVersion,
-		Method:  MethodInitialize,
-		Params:  params,
-		ID:      id,
-	}
-	return json.Marshal(req)
+	return MarshalRequest(id, MethodInitialize, params)
 }
 
 // UnmarshalInitializeResponse parses a JSON-RPC response for an initialize request.
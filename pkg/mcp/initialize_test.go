@@ -23,7 +23,7 @@ func TestMarshalInitializeRequest(t *testing.T) {
 					Roots: &struct {
 						ListChanged bool `json:"listChanged,omitempty"`
 					}{ListChanged: true},
-					Sampling: map[string]interface{}{}, // Explicitly empty map
+					Sampling: map[string]interface{}{}, // Explicitly empty map: still announced
 				},
 				ClientInfo: Implementation{
 					Name:    "ExampleClient",
@@ -40,7 +40,8 @@ func TestMarshalInitializeRequest(t *testing.T) {
 					"capabilities": {
 						"roots": {
 							"listChanged": true
-						}
+						},
+						"sampling": {}
 					},
 					"clientInfo": {
 						"name": "ExampleClient",
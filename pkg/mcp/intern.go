@@ -0,0 +1,105 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// InternMethod returns the constant of this package equal to method, such as
+// MethodCallTool, or method itself if no constant matches. Interned names share
+// storage, so comparing them with the constants is a pointer check.
+func InternMethod(method string) string {
+	if known, ok := knownMethod([]byte(method)); ok {
+		return known
+	}
+	return method
+}
+
+// knownMethod returns the constant spelled by name without allocating.
+func knownMethod(name []byte) (string, bool) {
+	switch string(name) {
+	case MethodPing:
+		return MethodPing, true
+	case MethodInitialize:
+		return MethodInitialize, true
+	case MethodInitialized:
+		return MethodInitialized, true
+	case MethodInitializedLegacy:
+		return MethodInitializedLegacy, true
+	case MethodListTools:
+		return MethodListTools, true
+	case MethodCallTool:
+		return MethodCallTool, true
+	case MethodListPrompts:
+		return MethodListPrompts, true
+	case MethodGetPrompt:
+		return MethodGetPrompt, true
+	case MethodListResources:
+		return MethodListResources, true
+	case MethodReadResource:
+		return MethodReadResource, true
+	case MethodListResourceTemplates:
+		return MethodListResourceTemplates, true
+	case MethodComplete:
+		return MethodComplete, true
+	case MethodCreateMessage:
+		return MethodCreateMessage, true
+	case MethodListRoots:
+		return MethodListRoots, true
+	case MethodCreateElicitation:
+		return MethodCreateElicitation, true
+	case MethodResourceUpdated:
+		return MethodResourceUpdated, true
+	case MethodResourceListChanged:
+		return MethodResourceListChanged, true
+	case MethodToolListChanged:
+		return MethodToolListChanged, true
+	case MethodPromptListChanged:
+		return MethodPromptListChanged, true
+	case MethodLogMessage:
+		return MethodLogMessage, true
+	case MethodShutdown:
+		return MethodShutdown, true
+	}
+	return "", false
+}
+
+// internedString decodes the method and jsonrpc members of a message,
+// interning known values straight from the JSON so that they are not allocated.
+type internedString string
+
+func (m *internedString) UnmarshalJSON(data []byte) error {
+	if len(data) >= 2 && data[0] == '"' && data[len(data)-1] == '"' && bytes.IndexByte(data, '\\') < 0 {
+		name := data[1 : len(data)-1]
+		if string(name) == JSONRPCVersion {
+			*m = JSONRPCVersion
+			return nil
+		}
+		if known, ok := knownMethod(name); ok {
+			*m = internedString(known)
+			return nil
+		}
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*m = internedString(InternMethod(s))
+	return nil
+}
+
+// presence records whether a member holds something other than null, and its
+// first byte, without copying it.
+type presence struct {
+	set   bool
+	first byte
+}
+
+func (p *presence) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	p.set = len(data) > 0 && string(data) != "null"
+	if p.set {
+		p.first = data[0]
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package mcp
+
+import (
+	"encoding/json"
+	"testing"
+	"unsafe"
+)
+
+func TestInternMethod(t *testing.T) {
+	dynamic := string([]byte("tools/call")) // Not the constant's storage
+	if got := InternMethod(dynamic); got != MethodCallTool || unsafe.StringData(got) != unsafe.StringData(MethodCallTool) {
+		t.Errorf("InternMethod(%q) does not share the constant's storage", dynamic)
+	}
+	if got := InternMethod("vendor/thing"); got != "vendor/thing" {
+		t.Errorf("InternMethod(vendor/thing) = %q", got)
+	}
+}
+
+func TestClassifyMessageInternsMethods(t *testing.T) {
+	for payload, want := range map[string]MessageInfo{
+		`{"jsonrpc":"2.0","id":7,"method":"tools/call"}`:        {Kind: KindRequest, Method: MethodCallTool, ID: json.Number("7")},
+		`{"jsonrpc":"2.0","id":"a","method":"tools\/call"}`:     {Kind: KindRequest, Method: MethodCallTool, ID: "a"},
+		`{"jsonrpc":"2.0","id":"x\"y","method":"vendor/thing"}`: {Kind: KindRequest, Method: "vendor/thing", ID: `x"y`},
+		`{"jsonrpc":"2.0","id":1.5,"result":{}}`:                {Kind: KindResponse, ID: json.Number("1.5")},
+		`{"jsonrpc":"2.0","id":-3,"error":{"code":1}}`:          {Kind: KindErrorResponse, ID: json.Number("-3")},
+	} {
+		info, err := ClassifyMessage([]byte(payload))
+		if err != nil || info != want {
+			t.Errorf("ClassifyMessage(%s) = %+v, %v; want %+v", payload, info, err, want)
+			continue
+		}
+		if info.Method == MethodCallTool && unsafe.StringData(info.Method) != unsafe.StringData(MethodCallTool) {
+			t.Errorf("ClassifyMessage(%s) did not intern the method", payload)
+		}
+	}
+	if _, err := ClassifyMessage([]byte(`{"jsonrpc":"2.0","id":1,"error":"bad"}`)); err == nil {
+		t.Error("ClassifyMessage accepted an error that is not an object")
+	}
+}
+
+func TestClassifyMessageAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocations are not counted with the race detector")
+	}
+	// Known methods and the jsonrpc version are looked up in the raw bytes;
+	// only the id of a request, a string in an interface, is allocated
+	for payload, want := range map[string]float64{
+		`{"jsonrpc":"2.0","method":"notifications/initialized","params":{}}`:                        0,
+		`{"jsonrpc":"2.0","id":1234,"method":"tools/call","params":{"name":"ping","arguments":{}}}`: 2,
+	} {
+		message := []byte(payload)
+		if got := testing.AllocsPerRun(100, func() { ClassifyMessage(message) }); got > want {
+			t.Errorf("ClassifyMessage(%s) allocates %v times, want at most %v", payload, got, want)
+		}
+	}
+}
+
+func BenchmarkClassifyMessage(b *testing.B) {
+	payload := []byte(`{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"ping","arguments":{}}}`)
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := ClassifyMessage(payload); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
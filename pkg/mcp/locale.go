@@ -0,0 +1,18 @@
+package mcp
+
+// ExperimentalLocale is the experimental capability with which a client asks
+// for localized server strings (instructions, tool and prompt descriptions),
+// with LocaleSettings naming the user's locale. A server that has strings for
+// it answers with the locale it chose, which may be less specific ("fr" for
+// "fr-CA").
+const ExperimentalLocale = "locale"
+
+// MetaKeyLocale is the initialize _meta key with which a client may name the
+// user's locale instead of declaring ExperimentalLocale.
+const MetaKeyLocale = "locale"
+
+// LocaleSettings are the settings of the ExperimentalLocale capability.
+type LocaleSettings struct {
+	// Locale is a BCP 47 language tag, e.g. "fr-CA".
+	Locale string `json:"locale"`
+}
@@ -0,0 +1,227 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"unicode/utf8"
+)
+
+// MessageKind identifies the type of a JSON-RPC 2.0 message.
+type MessageKind int
+
+const (
+	// KindInvalid is returned for payloads that are not valid JSON-RPC 2.0 messages.
+	KindInvalid MessageKind = iota
+	// KindRequest is a message with a method and an id.
+	KindRequest
+	// KindNotification is a message with a method and no id.
+	KindNotification
+	// KindResponse is a successful response (id and result).
+	KindResponse
+	// KindErrorResponse is an error response (id and error).
+	KindErrorResponse
+)
+
+// String returns a human-readable name for the message kind.
+func (k MessageKind) String() string {
+	switch k {
+	case KindRequest:
+		return "request"
+	case KindNotification:
+		return "notification"
+	case KindResponse:
+		return "response"
+	case KindErrorResponse:
+		return "error response"
+	default:
+		return "invalid"
+	}
+}
+
+// MessageInfo holds the routing fields extracted from a JSON-RPC message without decoding params or result.
+type MessageInfo struct {
+	Kind   MessageKind
+	Method string
+	ID     RequestID // nil for notifications
+	// HasParams reports whether the params member holds something. A missing,
+	// null or empty ({} or []) member counts as none, however the peer sent it.
+	HasParams bool
+}
+
+// envelope holds the members of a message that ClassifyMessage decodes.
+type envelope struct {
+	Method  internedString `json:"method"`  // Interned, see intern.go
+	ID      idField        `json:"id"`      // Can be string, number, or null/absent
+	Error   presence       `json:"error"`   // Check if non-null
+	Result  presence       `json:"result"`  // Check if non-null
+	JSONRPC internedString `json:"jsonrpc"` // Check for presence
+	Params  paramsPresence `json:"params"`  // Check if non-empty
+}
+
+// envelopes are reused, as a decoding target escapes to the heap and would
+// otherwise be allocated for every message.
+var envelopes = sync.Pool{New: func() interface{} { return new(envelope) }}
+
+// ClassifyMessage decodes just enough of a payload to determine its kind, method and id.
+// It returns KindInvalid and a non-nil error if the payload is not a JSON-RPC 2.0 message
+// or if the id is not a string, number or null as required by the specification.
+// Known methods are looked up in the raw bytes, so a message allocates only for
+// its id, which is stored as a string in an interface.
+func ClassifyMessage(payload []byte) (MessageInfo, error) {
+	base := envelopes.Get().(*envelope)
+	defer func() {
+		*base = envelope{}
+		envelopes.Put(base)
+	}()
+
+	if err := json.Unmarshal(payload, base); err != nil {
+		return MessageInfo{}, fmt.Errorf("failed to decode base JSON-RPC structure: %w", err)
+	}
+	if base.JSONRPC != JSONRPCVersion {
+		return MessageInfo{}, fmt.Errorf("invalid JSON-RPC version: %q", base.JSONRPC)
+	}
+
+	id, err := base.ID.id, base.ID.err
+	if err != nil {
+		return MessageInfo{}, err
+	}
+
+	// Determine message type based on fields present according to JSON-RPC 2.0 spec
+	hasID := id != nil
+	hasMethod := base.Method != ""
+	hasResult := base.Result.set
+	hasError := base.Error.set
+	if hasError && base.Error.first != '{' {
+		return MessageInfo{}, fmt.Errorf("error member must be an object")
+	}
+
+	info := MessageInfo{Method: string(base.Method), ID: id, HasParams: base.Params.set}
+	switch {
+	case hasID && hasError:
+		info.Kind = KindErrorResponse
+	case hasID && hasResult:
+		info.Kind = KindResponse
+	case hasID && hasMethod:
+		info.Kind = KindRequest
+	case !hasID && hasMethod:
+		info.Kind = KindNotification
+	default:
+		return MessageInfo{}, fmt.Errorf("message is not a valid request, notification or response")
+	}
+	return info, nil
+}
+
+// idField decodes the id member of a message straight from the JSON, without
+// keeping a copy of it.
+type idField struct {
+	id  RequestID
+	err error
+}
+
+func (f *idField) UnmarshalJSON(data []byte) error {
+	f.id, f.err = decodeRequestID(data)
+	return nil
+}
+
+// decodeRequestID converts a raw id field into a RequestID (string, json.Number or nil).
+// Numbers keep their literal text, so a response echoes the id exactly as the peer
+// wrote it (2 stays 2, and integers beyond 2^53 are not rounded).
+// Objects, arrays and booleans are rejected.
+func decodeRequestID(raw json.RawMessage) (RequestID, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	// Fast paths for the usual small integers and plain strings
+	if isPlainInteger(raw) {
+		return json.Number(raw), nil
+	}
+	if len(raw) >= 2 && raw[0] == '"' && raw[len(raw)-1] == '"' && !bytes.ContainsAny(raw[1:len(raw)-1], "\\\"") && utf8.Valid(raw) {
+		return string(raw[1 : len(raw)-1]), nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var id interface{}
+	if err := dec.Decode(&id); err != nil {
+		return nil, fmt.Errorf("failed to decode id: %w", err)
+	}
+	switch id.(type) {
+	case string, json.Number:
+		return id, nil
+	default:
+		return nil, fmt.Errorf("invalid id type %T: must be a string or number", id)
+	}
+}
+
+// isPlainInteger reports whether raw is a JSON integer: an optional minus and
+// digits without a leading zero.
+func isPlainInteger(raw []byte) bool {
+	digits := raw
+	if len(digits) > 0 && digits[0] == '-' {
+		digits = digits[1:]
+	}
+	if len(digits) == 0 || (digits[0] == '0' && len(digits) > 1) {
+		return false
+	}
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// SalvageRequestID returns the top-level id of a message of which only the
+// start is known, e.g. one too large to read in full, so that an error
+// response can still name the request. It returns nil if the id does not
+// appear, or is not complete, within prefix.
+func SalvageRequestID(prefix []byte) RequestID {
+	dec := json.NewDecoder(bytes.NewReader(prefix))
+	dec.UseNumber()
+	depth, expectKey := 0, false
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil
+		}
+		if delim, ok := tok.(json.Delim); ok {
+			switch delim {
+			case '{', '[':
+				if depth == 0 && delim != '{' {
+					return nil
+				}
+				depth++
+			case '}', ']':
+				depth--
+			}
+			if depth == 0 {
+				return nil
+			}
+			// After the top-level object opens or a nested value closes, a key follows
+			expectKey = depth == 1
+			continue
+		}
+		if depth != 1 {
+			continue
+		}
+		if !expectKey {
+			expectKey = true // A scalar value at the top level
+			continue
+		}
+		expectKey = false
+		if tok != "id" {
+			continue
+		}
+		value, err := dec.Token()
+		if err != nil {
+			return nil
+		}
+		switch value.(type) {
+		case string, json.Number:
+			return value
+		}
+		return nil
+	}
+}
@@ -0,0 +1,134 @@
+package mcp
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestClassifyMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    MessageInfo
+		wantErr bool
+	}{
+		{
+			name:    "request with int id",
+			payload: `{"jsonrpc":"2.0","method":"tools/list","params":{},"id":1}`,
+			want:    MessageInfo{Kind: KindRequest, Method: "tools/list", ID: json.Number("1")},
+		},
+		{
+			name:    "request with string id",
+			payload: `{"jsonrpc":"2.0","method":"ping","id":"p-1"}`,
+			want:    MessageInfo{Kind: KindRequest, Method: "ping", ID: "p-1"},
+		},
+		{
+			name:    "notification",
+			payload: `{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+			want:    MessageInfo{Kind: KindNotification, Method: "notifications/initialized"},
+		},
+		{
+			name:    "notification with null id",
+			payload: `{"jsonrpc":"2.0","method":"notifications/initialized","id":null}`,
+			want:    MessageInfo{Kind: KindNotification, Method: "notifications/initialized"},
+		},
+		{
+			name:    "notification with null params",
+			payload: `{"jsonrpc":"2.0","method":"notifications/initialized","params":null}`,
+			want:    MessageInfo{Kind: KindNotification, Method: "notifications/initialized"},
+		},
+		{
+			name:    "notification with params",
+			payload: `{"jsonrpc":"2.0","method":"notifications/progress","params":{"progress":1}}`,
+			want:    MessageInfo{Kind: KindNotification, Method: "notifications/progress", HasParams: true},
+		},
+		{
+			name:    "response",
+			payload: `{"jsonrpc":"2.0","result":{},"id":7}`,
+			want:    MessageInfo{Kind: KindResponse, ID: json.Number("7")},
+		},
+		{
+			name:    "error response",
+			payload: `{"jsonrpc":"2.0","error":{"code":-32601,"message":"nope"},"id":"x"}`,
+			want:    MessageInfo{Kind: KindErrorResponse, ID: "x"},
+		},
+		{name: "wrong version", payload: `{"jsonrpc":"1.0","method":"ping","id":1}`, wantErr: true},
+		{name: "object id", payload: `{"jsonrpc":"2.0","method":"ping","id":{"a":1}}`, wantErr: true},
+		{name: "boolean id", payload: `{"jsonrpc":"2.0","method":"ping","id":true}`, wantErr: true},
+		{name: "string error member", payload: `{"jsonrpc":"2.0","error":"bad","id":1}`, wantErr: true},
+		{name: "no method, no id", payload: `{"jsonrpc":"2.0"}`, wantErr: true},
+		{name: "response without id", payload: `{"jsonrpc":"2.0","result":{}}`, wantErr: true},
+		{name: "invalid json", payload: `{"jsonrpc":"2.0",`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ClassifyMessage([]byte(tt.payload))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ClassifyMessage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if got.Kind != KindInvalid {
+					t.Errorf("ClassifyMessage() kind = %v, want %v", got.Kind, KindInvalid)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ClassifyMessage() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSalvageRequestID(t *testing.T) {
+	for prefix, want := range map[string]RequestID{
+		`{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"arguments":{"text":"xxx`:  json.Number("7"),
+		`{"jsonrpc":"2.0","method":"x","params":{"id":1,"a":[{"id":2}]},"id":"req-1","more`: "req-1",
+		`{"method":"x","params":{"id":1,"text":"xxxxxxxx`:                                   nil,
+		`{"id":"unterminated`: nil,
+		`{"id":{"a":1},"x":"`: nil,
+		`[{"id":1}]`:          nil,
+		`not json`:            nil,
+	} {
+		if got := SalvageRequestID([]byte(prefix)); got != want {
+			t.Errorf("SalvageRequestID(%q) = %#v, want %#v", prefix, got, want)
+		}
+	}
+}
+
+func TestUnmarshalCancelledParams(t *testing.T) {
+	for params, want := range map[string]RequestID{
+		`{"requestId":7,"reason":"stop"}`: json.Number("7"),
+		`{"requestId":"req-1"}`:           "req-1",
+		`{"requestId":null}`:              nil,
+		`{"requestId":[1]}`:               nil,
+		`{"reason":"stop"}`:               nil,
+	} {
+		got, err := UnmarshalCancelledParams(json.RawMessage(params))
+		if got.RequestID != want || (err == nil) != (want != nil) {
+			t.Errorf("UnmarshalCancelledParams(%s) = %#v, %v; want %#v", params, got.RequestID, err, want)
+		}
+	}
+}
+
+func TestUnmarshalParams(t *testing.T) {
+	var p CallToolParams
+	if err := UnmarshalParams([]byte(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"ping","arguments":{"n":1}}}`), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "ping" || p.Arguments["n"] != float64(1) {
+		t.Errorf("params = %+v", p)
+	}
+
+	for _, payload := range []string{`{"jsonrpc":"2.0","id":1,"method":"ping"}`, `{"jsonrpc":"2.0","id":1,"method":"ping","params":null}`, `{"jsonrpc":"2.0","id":1,"method":"ping","params":{}}`, `{"jsonrpc":"2.0","id":1,"method":"ping","params":[]}`} {
+		p := CallToolParams{Name: "unchanged"}
+		if err := UnmarshalParams([]byte(payload), &p); err != nil || p.Name != "unchanged" {
+			t.Errorf("UnmarshalParams(%s) = %v, params %+v; want params untouched", payload, err, p)
+		}
+	}
+
+	if err := UnmarshalParams([]byte(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":"ping"}`), &p); err == nil {
+		t.Error("UnmarshalParams accepted string params")
+	}
+}
@@ -0,0 +1,78 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// requestParams maps each request method to the Go type of its params.
+// Methods without params (ping, roots/list) are absent.
+var requestParams = map[string]reflect.Type{
+	MethodInitialize:            reflect.TypeFor[InitializeParams](),
+	MethodListTools:             reflect.TypeFor[ListToolsParams](),
+	MethodCallTool:              reflect.TypeFor[CallToolParams](),
+	MethodListPrompts:           reflect.TypeFor[ListPromptsParams](),
+	MethodGetPrompt:             reflect.TypeFor[GetPromptParams](),
+	MethodListResources:         reflect.TypeFor[ListResourcesParams](),
+	MethodListResourceTemplates: reflect.TypeFor[ListResourceTemplatesParams](),
+	MethodReadResource:          reflect.TypeFor[ReadResourceParams](),
+	MethodCreateMessage:         reflect.TypeFor[CreateMessageParams](),
+	MethodCreateElicitation:     reflect.TypeFor[ElicitRequestParams](),
+	MethodComplete:              reflect.TypeFor[CompleteParams](),
+}
+
+var (
+	paramsSchemasOnce sync.Once
+	paramsSchemas     map[string]map[string]interface{}
+)
+
+// RequestParamsType returns the Go type of a request method's params.
+func RequestParamsType(method string) (reflect.Type, bool) {
+	t, ok := requestParams[method]
+	return t, ok
+}
+
+// RequestParamsSchema returns the JSON Schema generated for a request method's
+// params. Schemas are generated once and shared; callers must not modify them.
+func RequestParamsSchema(method string) (map[string]interface{}, bool) {
+	paramsSchemasOnce.Do(func() {
+		paramsSchemas = make(map[string]map[string]interface{}, len(requestParams))
+		for m, t := range requestParams {
+			paramsSchemas[m] = SchemaFor(t)
+		}
+	})
+	schema, ok := paramsSchemas[method]
+	return schema, ok
+}
+
+// ValidateRequestParams checks the params of a request against the schema for
+// its method. Pointers are rooted at the request, e.g. "/params/name". Methods
+// not in the registry are not checked. Absent params only fail when the
+// schema has required properties.
+func ValidateRequestParams(method string, params json.RawMessage) []SchemaViolation {
+	schema, ok := RequestParamsSchema(method)
+	if !ok {
+		return nil
+	}
+	if len(params) == 0 || string(params) == "null" {
+		if required, _ := schema["required"].([]string); len(required) > 0 {
+			return []SchemaViolation{{Pointer: "/params", Message: "is required"}}
+		}
+		return nil
+	}
+	return ValidateJSON(schema, params, "/params")
+}
+
+// NewInvalidParamsError returns an InvalidParams error describing violations,
+// listing them in the message and carrying them as data.errors.
+func NewInvalidParamsError(method string, violations []SchemaViolation) *RPCError {
+	problems := make([]string, len(violations))
+	for i, v := range violations {
+		problems[i] = v.String()
+	}
+	message := fmt.Sprintf("Invalid params for %s: %s", method, strings.Join(problems, "; "))
+	return NewRPCError(ErrorCodeInvalidParams, message, map[string]interface{}{"errors": violations})
+}
@@ -0,0 +1,97 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Method names for server-to-client change notifications.
+const (
+	MethodResourceUpdated     = "notifications/resources/updated"
+	MethodResourceListChanged = "notifications/resources/list_changed"
+	MethodToolListChanged     = "notifications/tools/list_changed"
+	MethodPromptListChanged   = "notifications/prompts/list_changed"
+)
+
+// MethodLogMessage is the notification a server sends to pass a log message to
+// the client, see LoggingMessageParams.
+const MethodLogMessage = "notifications/message"
+
+// MethodShutdown is the (non-standard) notification a server sends when it starts
+// draining a session: no new requests are accepted, in-flight requests still complete,
+// and the connection is closed afterwards.
+const MethodShutdown = "notifications/shutdown"
+
+// MethodCancelled is the notification either side sends to cancel a request it
+// sent earlier, see CancelledParams. The receiver stops working on the request
+// and does not answer it.
+const MethodCancelled = "notifications/cancelled"
+
+// RPCNotification defines the structure for a JSON-RPC notification (a request without an ID).
+type RPCNotification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// ResourceUpdatedParams defines the parameters for a "notifications/resources/updated" notification.
+type ResourceUpdatedParams struct {
+	// URI is the URI of the resource that has been updated.
+	URI string `json:"uri"`
+}
+
+// ShutdownParams defines the parameters for a "notifications/shutdown" notification.
+type ShutdownParams struct {
+	// Reason describes why the session is ending, e.g. "idle timeout".
+	Reason string `json:"reason"`
+}
+
+// CancelledParams defines the parameters for a "notifications/cancelled" notification.
+type CancelledParams struct {
+	// RequestID is the ID of the request to cancel.
+	RequestID RequestID `json:"requestId"`
+	// Reason optionally says why, e.g. "user pressed stop".
+	Reason string `json:"reason,omitempty"`
+}
+
+// UnmarshalCancelledParams decodes the params of a "notifications/cancelled"
+// notification. The request ID is decoded as in a message, a string or a
+// json.Number, so it matches the ID of the request it names.
+func UnmarshalCancelledParams(params json.RawMessage) (CancelledParams, error) {
+	var raw struct {
+		RequestID json.RawMessage `json:"requestId"`
+		Reason    string          `json:"reason"`
+	}
+	if err := json.Unmarshal(params, &raw); err != nil {
+		return CancelledParams{}, fmt.Errorf("failed to unmarshal cancelled params: %w", err)
+	}
+	id, err := decodeRequestID(raw.RequestID)
+	if err != nil {
+		return CancelledParams{}, err
+	}
+	if id == nil {
+		return CancelledParams{}, fmt.Errorf("cancelled notification without requestId")
+	}
+	return CancelledParams{RequestID: id, Reason: raw.Reason}, nil
+}
+
+// LoggingMessageParams defines the parameters for a "notifications/message" notification.
+type LoggingMessageParams struct {
+	// Level is the syslog severity, e.g. "info", "warning" or "error".
+	Level string `json:"level"`
+	// Logger optionally names the component that logged the message.
+	Logger string `json:"logger,omitempty"`
+	// Data is the message: a string or any JSON-serializable value.
+	Data interface{} `json:"data"`
+}
+
+// MarshalNotification creates a JSON-RPC notification for the given method.
+// params may be nil for notifications without parameters, such as the list_changed family;
+// they are then written without params.
+func MarshalNotification(method string, params interface{}) ([]byte, error) {
+	return json.Marshal(RPCNotification{
+		JSONRPC: JSONRPCVersion,
+		Method:  method,
+		Params:  params,
+	})
+}
@@ -0,0 +1,123 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// EmptyParams is how messages without params are written. Peers disagree:
+// some send no params member, others null or {}. All three are read alike
+// (see NormalizeParams), but a picky peer may accept only one of them. The
+// marshallers always make their own choice; a connection to such a peer
+// rewrites what it writes with Apply, see transport.NewEmptyParamsWriter.
+type EmptyParams int32
+
+const (
+	// EmptyParamsDefault keeps each marshaller's own choice: the list
+	// requests write {}, every other request and notification leaves params out.
+	EmptyParamsDefault EmptyParams = iota
+	// EmptyParamsOmit leaves the params member out.
+	EmptyParamsOmit
+	// EmptyParamsObject writes "params":{}.
+	EmptyParamsObject
+)
+
+// ParseEmptyParams parses "omit", "object", or "" or "default" for EmptyParamsDefault.
+func ParseEmptyParams(s string) (EmptyParams, error) {
+	switch s {
+	case "", "default":
+		return EmptyParamsDefault, nil
+	case "omit":
+		return EmptyParamsOmit, nil
+	case "object":
+		return EmptyParamsObject, nil
+	}
+	return 0, fmt.Errorf("unknown empty params style %q, want omit or object", s)
+}
+
+// Apply returns message with empty params written as style asks. Responses,
+// messages with params and anything that is not a JSON-RPC message are
+// returned unchanged, as is everything for EmptyParamsDefault.
+func (style EmptyParams) Apply(message []byte) []byte {
+	if style == EmptyParamsDefault {
+		return message
+	}
+	info, err := ClassifyMessage(message)
+	if err != nil || info.HasParams || (info.Kind != KindRequest && info.Kind != KindNotification) {
+		return message
+	}
+	var params json.RawMessage
+	if style == EmptyParamsObject {
+		params = json.RawMessage("{}")
+	}
+	var out []byte
+	if info.Kind == KindRequest {
+		out, err = json.Marshal(RPCRequest{JSONRPC: JSONRPCVersion, Method: info.Method, Params: params, ID: info.ID})
+	} else {
+		notification := RPCNotification{JSONRPC: JSONRPCVersion, Method: info.Method}
+		if params != nil {
+			notification.Params = params
+		}
+		out, err = json.Marshal(notification)
+	}
+	if err != nil {
+		return message
+	}
+	return out
+}
+
+// emptyParamsValue returns what the marshaller writes for nil params given
+// its own choice: nil to leave the member out, or an empty object.
+func emptyParamsValue(style EmptyParams) json.RawMessage {
+	if style == EmptyParamsObject {
+		return json.RawMessage("{}")
+	}
+	return nil
+}
+
+// NormalizeParams returns the params member of a message, as decoded into a
+// json.RawMessage, with the three ways of sending no params (no member, null
+// and {}) all given as {}, so handlers can always decode it into a struct.
+func NormalizeParams(raw json.RawMessage) json.RawMessage {
+	if isEmptyParams(raw) {
+		return json.RawMessage("{}")
+	}
+	return raw
+}
+
+// isEmptyParams reports whether raw is missing, null, or an empty object or array.
+func isEmptyParams(raw []byte) bool {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		return true
+	}
+	if raw[0] != '{' && raw[0] != '[' {
+		return false
+	}
+	return len(bytes.TrimSpace(raw[1:len(raw)-1])) == 0
+}
+
+// paramsPresence records whether a params member is something other than
+// empty, without copying it.
+type paramsPresence struct {
+	set bool
+}
+
+func (p *paramsPresence) UnmarshalJSON(data []byte) error {
+	p.set = !isEmptyParams(data)
+	return nil
+}
+
+// paramsTarget decodes a params member into v unless it is empty, so that
+// peers sending [] for "no params" are read like those sending {}.
+type paramsTarget struct {
+	v interface{}
+}
+
+func (p paramsTarget) UnmarshalJSON(data []byte) error {
+	if isEmptyParams(data) {
+		return nil
+	}
+	return json.Unmarshal(data, p.v)
+}
@@ -0,0 +1,60 @@
+package mcp
+
+import "testing"
+
+func TestEmptyParamsApply(t *testing.T) {
+	request, _ := MarshalRequest(1, "ping", nil)
+	list, _ := MarshalListToolsRequest(2, nil)
+	note, _ := MarshalNotification("notifications/initialized", nil)
+	response := []byte(`{"jsonrpc":"2.0","id":3,"result":{}}`)
+	withParams, _ := MarshalRequest(4, "tools/call", map[string]string{"name": "x"})
+
+	tests := []struct {
+		style               EmptyParams
+		request, list, note string
+	}{
+		{EmptyParamsDefault,
+			`{"jsonrpc":"2.0","method":"ping","id":1}`,
+			`{"jsonrpc":"2.0","method":"tools/list","params":{},"id":2}`,
+			`{"jsonrpc":"2.0","method":"notifications/initialized"}`},
+		{EmptyParamsOmit,
+			`{"jsonrpc":"2.0","method":"ping","id":1}`,
+			`{"jsonrpc":"2.0","method":"tools/list","id":2}`,
+			`{"jsonrpc":"2.0","method":"notifications/initialized"}`},
+		{EmptyParamsObject,
+			`{"jsonrpc":"2.0","method":"ping","params":{},"id":1}`,
+			`{"jsonrpc":"2.0","method":"tools/list","params":{},"id":2}`,
+			`{"jsonrpc":"2.0","method":"notifications/initialized","params":{}}`},
+	}
+	for _, tt := range tests {
+		if got := tt.style.Apply(request); string(got) != tt.request {
+			t.Errorf("style %d: request = %s, want %s", tt.style, got, tt.request)
+		}
+		if got := tt.style.Apply(list); string(got) != tt.list {
+			t.Errorf("style %d: list request = %s, want %s", tt.style, got, tt.list)
+		}
+		if got := tt.style.Apply(note); string(got) != tt.note {
+			t.Errorf("style %d: notification = %s, want %s", tt.style, got, tt.note)
+		}
+		for _, unchanged := range [][]byte{response, withParams, []byte("not json")} {
+			if got := tt.style.Apply(unchanged); string(got) != string(unchanged) {
+				t.Errorf("style %d: Apply(%s) = %s", tt.style, unchanged, got)
+			}
+		}
+	}
+
+	if _, err := ParseEmptyParams("null"); err == nil {
+		t.Error("ParseEmptyParams accepted null")
+	}
+}
+
+func TestNormalizeParams(t *testing.T) {
+	for raw, want := range map[string]string{
+		"": "{}", "null": "{}", " { } ": "{}", "[]": "{}",
+		`{"a":1}`: `{"a":1}`, `[1]`: `[1]`,
+	} {
+		if got := NormalizeParams([]byte(raw)); string(got) != want {
+			t.Errorf("NormalizeParams(%q) = %s, want %s", raw, got, want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Valid reports whether r is a role a prompt or sampling message may have.
+func (r Role) Valid() bool {
+	return r == RoleUser || r == RoleAssistant
+}
+
+// NewUserMessage returns one user message per content block, built with
+// NewTextContent, NewImageContent or NewEmbeddedResource. A prompt message
+// carries a single block, so a turn with several blocks is several messages.
+func NewUserMessage(content ...json.RawMessage) []PromptMessage {
+	return newPromptMessages(RoleUser, content)
+}
+
+// NewAssistantMessage returns one assistant message per content block, like NewUserMessage.
+func NewAssistantMessage(content ...json.RawMessage) []PromptMessage {
+	return newPromptMessages(RoleAssistant, content)
+}
+
+func newPromptMessages(role Role, content []json.RawMessage) []PromptMessage {
+	messages := make([]PromptMessage, len(content))
+	for i, c := range content {
+		messages[i] = PromptMessage{Role: role, Content: c}
+	}
+	return messages
+}
+
+// GetPromptResultBuilder assembles a GetPromptResult message by message. Invalid
+// messages are reported by Build, so calls can be chained without checking each one.
+type GetPromptResultBuilder struct {
+	result GetPromptResult
+	added  int // Messages passed to Add so far, valid or not
+	errs   []error
+}
+
+// NewGetPromptResult starts a prompts/get result with the given description.
+func NewGetPromptResult(description string) *GetPromptResultBuilder {
+	return &GetPromptResultBuilder{result: GetPromptResult{Description: description}}
+}
+
+// User appends a user message for each content block.
+func (b *GetPromptResultBuilder) User(content ...json.RawMessage) *GetPromptResultBuilder {
+	return b.Add(NewUserMessage(content...)...)
+}
+
+// Assistant appends an assistant message for each content block.
+func (b *GetPromptResultBuilder) Assistant(content ...json.RawMessage) *GetPromptResultBuilder {
+	return b.Add(NewAssistantMessage(content...)...)
+}
+
+// Add appends messages, recording an error for any with an unknown role or
+// content that is not a typed content block.
+func (b *GetPromptResultBuilder) Add(messages ...PromptMessage) *GetPromptResultBuilder {
+	for _, m := range messages {
+		b.added++
+		n := b.added
+		if !m.Role.Valid() {
+			b.errs = append(b.errs, fmt.Errorf("message %d: invalid role %q (want %q or %q)", n, m.Role, RoleUser, RoleAssistant))
+			continue
+		}
+		var block struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(m.Content, &block); err != nil || block.Type == "" {
+			b.errs = append(b.errs, fmt.Errorf("message %d: content is not a typed content block", n))
+			continue
+		}
+		b.result.Messages = append(b.result.Messages, m)
+	}
+	return b
+}
+
+// Build returns the result, or the errors recorded for invalid messages.
+func (b *GetPromptResultBuilder) Build() (GetPromptResult, error) {
+	if len(b.errs) > 0 {
+		return GetPromptResult{}, errors.Join(b.errs...)
+	}
+	if len(b.result.Messages) == 0 {
+		return GetPromptResult{}, errors.New("prompt has no messages")
+	}
+	return b.result, nil
+}
@@ -0,0 +1,44 @@
+package mcp
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestGetPromptResultBuilder(t *testing.T) {
+	result, err := NewGetPromptResult("review").
+		User(NewTextContent("Review this file:"), NewEmbeddedResource(NewTextResource("file:///a.go", "text/x-go", "package a"))).
+		Assistant(NewTextContent("Looking at it now.")).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Description != "review" || len(result.Messages) != 3 {
+		t.Fatalf("result = %+v", result)
+	}
+	for i, want := range []Role{RoleUser, RoleUser, RoleAssistant} {
+		if result.Messages[i].Role != want {
+			t.Errorf("message %d role = %s, want %s", i, result.Messages[i].Role, want)
+		}
+	}
+	var embedded EmbeddedResource
+	if err := json.Unmarshal(result.Messages[1].Content, &embedded); err != nil || embedded.Type != "resource" {
+		t.Errorf("message 1 = %s (%v), want an embedded resource", result.Messages[1].Content, err)
+	}
+}
+
+func TestGetPromptResultBuilderRejectsInvalidMessages(t *testing.T) {
+	_, err := NewGetPromptResult("").
+		Add(PromptMessage{Role: "system", Content: NewTextContent("x")}).
+		Add(PromptMessage{Role: RoleUser, Content: json.RawMessage(`"just a string"`)}).
+		User(NewTextContent("fine")).
+		Build()
+	if err == nil || !strings.Contains(err.Error(), `message 1: invalid role "system"`) || !strings.Contains(err.Error(), "message 2: content is not a typed content block") {
+		t.Errorf("Build() error = %v", err)
+	}
+
+	if _, err := NewGetPromptResult("empty").Build(); err == nil {
+		t.Error("Build() accepted a prompt without messages")
+	}
+}
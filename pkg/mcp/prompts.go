@@ -19,6 +19,8 @@ type PromptArgument struct {
 	Name string `json:"name"`
 	// Required indicates whether this argument must be provided.
 	Required bool `json:"required,omitempty"` // Defaults to false if omitted
+	// Title is a human-readable name for display; clients fall back to Name.
+	Title string `json:"title,omitempty"`
 }
 
 // Prompt represents a prompt or prompt template offered by the server.
@@ -27,8 +29,12 @@ type Prompt struct {
 	Arguments []PromptArgument `json:"arguments,omitempty"`
 	// Description is an optional description of what the prompt provides.
 	Description string `json:"description,omitempty"`
+	// Icons are images a graphical host can show for the prompt.
+	Icons []Icon `json:"icons,omitempty"`
 	// Name is the unique name of the prompt or prompt template.
 	Name string `json:"name"`
+	// Title is a human-readable name for display; clients fall back to Name.
+	Title string `json:"title,omitempty"`
 }
 
 // TextContent represents text content within a prompt message.
@@ -97,21 +103,10 @@ type GetPromptResult struct {
 // MarshalListPromptsRequest creates a JSON-RPC request for the prompts/list method.
 // The id can be a string or an integer. If params is nil, default empty params will be used.
 func MarshalListPromptsRequest(id RequestID, params *ListPromptsParams) ([]byte, error) {
-	// Use default empty params if nil is provided
-	var p interface{}
 	if params != nil {
-		p = params
-	} else {
-		p = struct{}{} // Empty object for params if none specified
+		return MarshalRequest(id, MethodListPrompts, params)
 	}
-
-	req := RPCRequest{
-		JSONRPC: JSONRPCVersion,
-		Method:  MethodListPrompts,
-		Params:  p,
-		ID:      id,
-	}
-	return json.Marshal(req)
+	return marshalRequest(id, MethodListPrompts, nil, EmptyParamsObject)
 }
 
 // UnmarshalListPromptsResponse parses a JSON-RPC response for a prompts/list request.
@@ -146,13 +141,7 @@ func UnmarshalListPromptsResponse(data []byte) (*ListPromptsResult, RequestID, *
 // MarshalGetPromptRequest creates a JSON-RPC request for the prompts/get method.
 // The id can be a string or an integer.
 func MarshalGetPromptRequest(id RequestID, params GetPromptParams) ([]byte, error) {
-	req := RPCRequest{
-		JSONRPC: JSONRPCVersion,
-		Method:  MethodGetPrompt,
-		Params:  params,
-		ID:      id,
-	}
-	return json.Marshal(req)
+	return MarshalRequest(id, MethodGetPrompt, params)
 }
 
 // UnmarshalGetPromptResponse parses a JSON-RPC response for a prompts/get request.
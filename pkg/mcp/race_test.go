@@ -0,0 +1,7 @@
+//go:build race
+
+package mcp
+
+// raceEnabled reports whether the tests run with the race detector, which
+// allocates on its own and drops pooled values.
+const raceEnabled = true
@@ -0,0 +1,24 @@
+package mcp
+
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
+// NewTextResource returns marshalled TextResourceContents for a resources/read
+// result or an embedded resource.
+func NewTextResource(uri, mimeType, text string) json.RawMessage {
+	contents, _ := json.Marshal(TextResourceContents{URI: uri, MimeType: mimeType, Text: text}) // Strings always marshal
+	return contents
+}
+
+// NewBlobResource returns marshalled BlobResourceContents, base64-encoding data.
+func NewBlobResource(uri, mimeType string, data []byte) json.RawMessage {
+	contents, _ := json.Marshal(BlobResourceContents{URI: uri, MimeType: mimeType, Blob: base64.StdEncoding.EncodeToString(data)})
+	return contents
+}
+
+// Add appends contents built with NewTextResource or NewBlobResource to the result.
+func (r *ReadResourceResult) Add(contents ...json.RawMessage) {
+	r.Contents = append(r.Contents, contents...)
+}
@@ -0,0 +1,22 @@
+package mcp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestResourceContentsBuilders(t *testing.T) {
+	var result ReadResourceResult
+	result.Add(NewTextResource("file:///a.txt", "text/plain", "hello"))
+	result.Add(NewBlobResource("file:///a.bin", "application/octet-stream", []byte{0, 1, 2}))
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"contents":[{"mimeType":"text/plain","text":"hello","uri":"file:///a.txt"},` +
+		`{"blob":"AAEC","mimeType":"application/octet-stream","uri":"file:///a.bin"}]}`
+	if string(data) != want {
+		t.Errorf("result = %s\nwant     %s", data, want)
+	}
+}
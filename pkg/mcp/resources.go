@@ -19,10 +19,14 @@ type Resource struct {
 	Description string `json:"description,omitempty"`
 	// MimeType is the MIME type of the resource, if known.
 	MimeType string `json:"mimeType,omitempty"`
+	// Meta contains reserved protocol metadata, such as MetaDeprecation.
+	Meta map[string]interface{} `json:"_meta,omitempty"`
 	// Name is a human-readable name for the resource.
 	Name string `json:"name"`
 	// Size is the raw size in bytes, if known.
 	Size *int `json:"size,omitempty"` // Use pointer for optional 0 value
+	// Title is a human-readable name for display; clients fall back to Name.
+	Title string `json:"title,omitempty"`
 	// URI is the unique identifier for the resource.
 	URI string `json:"uri"`
 }
@@ -36,6 +40,8 @@ type ResourceTemplate struct {
 	MimeType string `json:"mimeType,omitempty"`
 	// Name is a human-readable name for the type of resource this template refers to.
 	Name string `json:"name"`
+	// Title is a human-readable name for display; clients fall back to Name.
+	Title string `json:"title,omitempty"`
 	// URITemplate is an RFC 6570 URI template.
 	URITemplate string `json:"uriTemplate"`
 }
@@ -111,21 +117,10 @@ type ReadResourceResult struct {
 // MarshalListResourcesRequest creates a JSON-RPC request for the resources/list method.
 // The id can be a string or an integer. If params is nil, default empty params will be used.
 func MarshalListResourcesRequest(id RequestID, params *ListResourcesParams) ([]byte, error) {
-	// Use default empty params if nil is provided
-	var p interface{}
 	if params != nil {
-		p = params
-	} else {
-		p = struct{}{} // Empty object for params if none specified
-	}
-
-	req := RPCRequest{
-		JSONRPC: JSONRPCVersion,
-		Method:  MethodListResources,
-		Params:  p,
-		ID:      id,
+		return MarshalRequest(id, MethodListResources, params)
 	}
-	return json.Marshal(req)
+	return marshalRequest(id, MethodListResources, nil, EmptyParamsObject)
 }
 
 // UnmarshalListResourcesResponse parses a JSON-RPC response for a resources/list request.
@@ -162,20 +157,10 @@ func UnmarshalListResourcesResponse(data []byte) (*ListResourcesResult, RequestI
 // MarshalListResourceTemplatesRequest creates a JSON-RPC request for the resources/templates/list method.
 // The id can be a string or an integer. If params is nil, default empty params will be used.
 func MarshalListResourceTemplatesRequest(id RequestID, params *ListResourceTemplatesParams) ([]byte, error) {
-	var p interface{}
 	if params != nil {
-		p = params
-	} else {
-		p = struct{}{} // Empty object for params if none specified
+		return MarshalRequest(id, MethodListResourceTemplates, params)
 	}
-
-	req := RPCRequest{
-		JSONRPC: JSONRPCVersion,
-		Method:  MethodListResourceTemplates,
-		Params:  p,
-		ID:      id,
-	}
-	return json.Marshal(req)
+	return marshalRequest(id, MethodListResourceTemplates, nil, EmptyParamsObject)
 }
 
 // UnmarshalListResourceTemplatesResponse parses a JSON-RPC response for a resources/templates/list request.
@@ -204,13 +189,7 @@ func UnmarshalListResourceTemplatesResponse(data []byte) (*ListResourceTemplates
 // MarshalReadResourcesRequest creates a JSON-RPC request for the resources/read method.
 // The id can be a string or an integer.
 func MarshalReadResourcesRequest(id RequestID, params ReadResourceParams) ([]byte, error) {
-	req := RPCRequest{
-		JSONRPC: JSONRPCVersion,
-		Method:  MethodReadResource,
-		Params:  params,
-		ID:      id,
-	}
-	return json.Marshal(req)
+	return MarshalRequest(id, MethodReadResource, params)
 }
 
 // UnmarshalReadResourcesResponse parses a JSON-RPC response for a resources/read request.
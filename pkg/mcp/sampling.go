@@ -0,0 +1,60 @@
+package mcp
+
+import "encoding/json"
+
+// Values of CreateMessageParams.IncludeContext.
+const (
+	IncludeContextNone       = "none"
+	IncludeContextThisServer = "thisServer"
+	IncludeContextAllServers = "allServers"
+)
+
+// ModelHint suggests a model by (partial) name, e.g. "claude-3-5-haiku" or "haiku".
+type ModelHint struct {
+	Name string `json:"name,omitempty"`
+}
+
+// ModelPreferences tell the client what matters when it picks a model. The
+// priorities range from 0 to 1.
+type ModelPreferences struct {
+	Hints                []ModelHint `json:"hints,omitempty"`
+	CostPriority         *float64    `json:"costPriority,omitempty"`
+	SpeedPriority        *float64    `json:"speedPriority,omitempty"`
+	IntelligencePriority *float64    `json:"intelligencePriority,omitempty"`
+}
+
+// SamplingMessage is one message of a sampling conversation.
+type SamplingMessage struct {
+	Role Role `json:"role"`
+	// Content is a TextContent or ImageContent.
+	Content json.RawMessage `json:"content"`
+}
+
+// CreateMessageParams defines the parameters for a "sampling/createMessage" request,
+// in which the server asks the client to run an LLM completion on its behalf.
+type CreateMessageParams struct {
+	Messages         []SamplingMessage      `json:"messages"`
+	ModelPreferences *ModelPreferences      `json:"modelPreferences,omitempty"`
+	SystemPrompt     string                 `json:"systemPrompt,omitempty"`
+	IncludeContext   string                 `json:"includeContext,omitempty"`
+	Temperature      *float64               `json:"temperature,omitempty"`
+	MaxTokens        int                    `json:"maxTokens"`
+	StopSequences    []string               `json:"stopSequences,omitempty"`
+	Metadata         map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// CreateMessageResult defines the result of a "sampling/createMessage" request.
+type CreateMessageResult struct {
+	Role Role `json:"role"`
+	// Content is a TextContent or ImageContent.
+	Content json.RawMessage `json:"content"`
+	// Model is the name of the model that produced the message.
+	Model string `json:"model"`
+	// StopReason is e.g. "endTurn", "stopSequence" or "maxTokens".
+	StopReason string `json:"stopReason,omitempty"`
+}
+
+// NewTextSamplingMessage returns a sampling message with text content.
+func NewTextSamplingMessage(role Role, text string) SamplingMessage {
+	return SamplingMessage{Role: role, Content: NewTextContent(text)}
+}
@@ -0,0 +1,209 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// SchemaFor generates a JSON Schema for values of type t as encoding/json
+// would marshal them. Struct fields without omitempty/omitzero that are not
+// pointers are required. Types that decode themselves (json.Unmarshaler, such
+// as json.RawMessage) and interface types accept any value.
+func SchemaFor(t reflect.Type) map[string]interface{} {
+	if t.Implements(unmarshalerType) || reflect.PointerTo(t).Implements(unmarshalerType) {
+		return map[string]interface{}{}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return SchemaFor(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": SchemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": SchemaFor(t.Elem())}
+	case reflect.Struct:
+		properties := map[string]interface{}{}
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, optional, ok := jsonField(f)
+			if !ok {
+				continue
+			}
+			properties[name] = SchemaFor(f.Type)
+			if !optional && f.Type.Kind() != reflect.Pointer {
+				required = append(required, name)
+			}
+		}
+		schema := map[string]interface{}{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	}
+	return map[string]interface{}{}
+}
+
+var unmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+
+// jsonField returns the JSON name of a struct field and whether it may be omitted.
+// It reports false for unexported and json:"-" fields.
+func jsonField(f reflect.StructField) (name string, optional bool, ok bool) {
+	if !f.IsExported() {
+		return "", false, false
+	}
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false, false
+	}
+	name, opts, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = f.Name
+	}
+	for _, opt := range strings.Split(opts, ",") {
+		if opt == "omitempty" || opt == "omitzero" {
+			optional = true
+		}
+	}
+	return name, optional, true
+}
+
+// SchemaViolation is one place where a JSON document does not match a schema.
+// Pointer is an RFC 6901 JSON Pointer to the offending value.
+type SchemaViolation struct {
+	Pointer string `json:"pointer"`
+	Message string `json:"message"`
+}
+
+func (v SchemaViolation) String() string {
+	return v.Pointer + " " + v.Message
+}
+
+// ValidateJSON checks data against a schema produced by SchemaFor, understanding
+// the type, properties, required, items and additionalProperties keywords.
+// Pointers are prefixed with pointer, e.g. "/params". Unknown properties are allowed.
+func ValidateJSON(schema map[string]interface{}, data json.RawMessage, pointer string) []SchemaViolation {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return []SchemaViolation{{Pointer: pointer, Message: "is not valid JSON"}}
+	}
+	var v schemaValidator
+	v.check(schema, value, pointer)
+	return v.violations
+}
+
+type schemaValidator struct {
+	violations []SchemaViolation
+}
+
+func (v *schemaValidator) fail(pointer, format string, a ...interface{}) {
+	v.violations = append(v.violations, SchemaViolation{Pointer: pointer, Message: fmt.Sprintf(format, a...)})
+}
+
+func (v *schemaValidator) check(schema map[string]interface{}, value interface{}, pointer string) {
+	if t, ok := schema["type"].(string); ok && !schemaTypeMatches(t, value) {
+		v.fail(pointer, "must be %s, got %s", withArticle(t), jsonTypeName(value))
+		return
+	}
+	switch value := value.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		required, _ := schema["required"].([]string)
+		for _, name := range required {
+			if value[name] == nil {
+				v.fail(pointer+"/"+escapePointer(name), "is required")
+			}
+		}
+		names := make([]string, 0, len(value))
+		for name := range value {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if value[name] == nil {
+				continue // Absent-like; required fields were reported above
+			}
+			sub, ok := properties[name].(map[string]interface{})
+			if !ok {
+				sub, ok = schema["additionalProperties"].(map[string]interface{})
+			}
+			if ok {
+				v.check(sub, value[name], pointer+"/"+escapePointer(name))
+			}
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range value {
+				v.check(items, item, fmt.Sprintf("%s/%d", pointer, i))
+			}
+		}
+	}
+}
+
+// schemaTypeMatches reports whether a decoded JSON value has JSON Schema type t.
+func schemaTypeMatches(t string, value interface{}) bool {
+	switch t {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	}
+	return true
+}
+
+// jsonTypeName names the JSON type of a decoded value for error messages.
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func withArticle(t string) string {
+	if strings.ContainsRune("aeiou", rune(t[0])) {
+		return "an " + t
+	}
+	return "a " + t
+}
+
+// escapePointer escapes a property name for use as a JSON Pointer token.
+func escapePointer(name string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+}
@@ -0,0 +1,68 @@
+package mcp
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestSchemaFor(t *testing.T) {
+	type inner struct {
+		N int `json:"n"`
+	}
+	type sample struct {
+		Name     string            `json:"name"`
+		Optional string            `json:"optional,omitempty"`
+		Ptr      *inner            `json:"ptr"`
+		Raw      json.RawMessage   `json:"raw"`
+		Tags     []string          `json:"tags,omitzero"`
+		Labels   map[string]string `json:"labels,omitempty"`
+		Skipped  string            `json:"-"`
+		hidden   string
+	}
+	got, _ := json.Marshal(SchemaFor(reflect.TypeFor[sample]()))
+	want := `{"properties":{"labels":{"additionalProperties":{"type":"string"},"type":"object"},` +
+		`"name":{"type":"string"},"optional":{"type":"string"},` +
+		`"ptr":{"properties":{"n":{"type":"integer"}},"required":["n"],"type":"object"},` +
+		`"raw":{},"tags":{"items":{"type":"string"},"type":"array"}},` +
+		`"required":["name","raw"],"type":"object"}`
+	if string(got) != want {
+		t.Errorf("SchemaFor = %s\nwant %s", got, want)
+	}
+}
+
+func TestValidateRequestParams(t *testing.T) {
+	tests := []struct {
+		method string
+		params string
+		want   []SchemaViolation
+	}{
+		{MethodCallTool, `{"name":"echo","arguments":{"x":1}}`, nil},
+		{MethodCallTool, `{"name":null}`, []SchemaViolation{{"/params/name", "is required"}}},
+		{MethodCallTool, `{"name":"echo","arguments":"x"}`, []SchemaViolation{{"/params/arguments", "must be an object, got string"}}},
+		{MethodInitialize, `{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"c","version":1}}`,
+			[]SchemaViolation{{"/params/clientInfo/version", "must be a string, got number"}}},
+		{MethodCreateMessage, `{"maxTokens":1.5,"messages":[{"role":"user"}]}`, []SchemaViolation{
+			{"/params/maxTokens", "must be an integer, got number"},
+			{"/params/messages/0/content", "is required"},
+		}},
+		{MethodGetPrompt, `{"name":"p","arguments":{"a/b":2}}`, []SchemaViolation{{"/params/arguments/a~1b", "must be a string, got number"}}},
+		{MethodGetPrompt, `not json`, []SchemaViolation{{"/params", "is not valid JSON"}}},
+		{MethodGetPrompt, ``, []SchemaViolation{{"/params", "is required"}}},
+		{MethodListPrompts, `null`, nil},
+		{MethodPing, `{"anything":true}`, nil},
+	}
+	for _, tt := range tests {
+		got := ValidateRequestParams(tt.method, json.RawMessage(tt.params))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ValidateRequestParams(%s, %s) = %v, want %v", tt.method, tt.params, got, tt.want)
+		}
+	}
+}
+
+func TestNewInvalidParamsError(t *testing.T) {
+	err := NewInvalidParamsError(MethodCallTool, []SchemaViolation{{"/params/name", "is required"}})
+	if err.Code != ErrorCodeInvalidParams || err.Message != "Invalid params for tools/call: /params/name is required" {
+		t.Errorf("NewInvalidParamsError = %d %q", err.Code, err.Message)
+	}
+}
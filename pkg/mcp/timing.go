@@ -0,0 +1,65 @@
+package mcp
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// MetaKeyTiming is the _meta key under which servers report request timing.
+const MetaKeyTiming = "timing"
+
+// Timing is server-side latency information for a single request, attached to
+// results as _meta.timing. Durations are in milliseconds.
+type Timing struct {
+	// QueueMs is the time between the server reading the request and a handler starting on it.
+	QueueMs float64 `json:"queueMs"`
+	// ExecutionMs is the time the handler took to produce the result.
+	ExecutionMs float64 `json:"executionMs"`
+}
+
+// NewTiming builds a Timing from durations.
+func NewTiming(queue, execution time.Duration) Timing {
+	return Timing{
+		QueueMs:     float64(queue) / float64(time.Millisecond),
+		ExecutionMs: float64(execution) / float64(time.Millisecond),
+	}
+}
+
+// Queue returns the queue time as a duration.
+func (t Timing) Queue() time.Duration {
+	return time.Duration(t.QueueMs * float64(time.Millisecond))
+}
+
+// Execution returns the execution time as a duration.
+func (t Timing) Execution() time.Duration {
+	return time.Duration(t.ExecutionMs * float64(time.Millisecond))
+}
+
+// TimingFromMeta extracts the timing entry from a result's _meta map.
+// It returns nil if the server did not report timing.
+func TimingFromMeta(meta map[string]interface{}) *Timing {
+	raw, ok := meta[MetaKeyTiming]
+	if !ok {
+		return nil
+	}
+	// The map was decoded generically; round-trip it through JSON into the typed struct
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil
+	}
+	var t Timing
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil
+	}
+	return &t
+}
+
+// Timing returns the server-reported timing for the tool call, or nil if absent.
+func (r *CallToolResult) Timing() *Timing {
+	return TimingFromMeta(r.Meta)
+}
+
+// Timing returns the server-reported timing for the resource read, or nil if absent.
+func (r *ReadResourceResult) Timing() *Timing {
+	return TimingFromMeta(r.Meta)
+}
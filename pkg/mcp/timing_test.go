@@ -0,0 +1,37 @@
+package mcp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimingFromResult(t *testing.T) {
+	data := []byte(`{"jsonrpc":"2.0","id":1,"result":{"_meta":{"timing":{"queueMs":1.5,"executionMs":250}},"content":[]}}`)
+	result, _, rpcErr, err := UnmarshalCallToolResponse(data)
+	if err != nil || rpcErr != nil {
+		t.Fatalf("unmarshal failed: err=%v rpcErr=%v", err, rpcErr)
+	}
+	timing := result.Timing()
+	if timing == nil {
+		t.Fatal("Timing() = nil, want timing")
+	}
+	if timing.Queue() != 1500*time.Microsecond || timing.Execution() != 250*time.Millisecond {
+		t.Errorf("Timing() = %+v", timing)
+	}
+
+	data = []byte(`{"jsonrpc":"2.0","id":2,"result":{"contents":[]}}`)
+	readResult, _, _, err := UnmarshalReadResourcesResponse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if readResult.Timing() != nil {
+		t.Errorf("Timing() = %+v, want nil without _meta", readResult.Timing())
+	}
+}
+
+func TestNewTiming(t *testing.T) {
+	got := NewTiming(2*time.Millisecond, 1500*time.Microsecond)
+	if got.QueueMs != 2 || got.ExecutionMs != 1.5 {
+		t.Errorf("NewTiming = %+v", got)
+	}
+}
@@ -0,0 +1,27 @@
+package mcp
+
+// displayName returns title, or name if there is no title.
+func displayName(title, name string) string {
+	if title != "" {
+		return title
+	}
+	return name
+}
+
+// DisplayName returns the name to show a user for the tool: its title, or its name.
+func (t Tool) DisplayName() string { return displayName(t.Title, t.Name) }
+
+// DisplayName returns the name to show a user for the prompt: its title, or its name.
+func (p Prompt) DisplayName() string { return displayName(p.Title, p.Name) }
+
+// DisplayName returns the name to show a user for the argument: its title, or its name.
+func (a PromptArgument) DisplayName() string { return displayName(a.Title, a.Name) }
+
+// DisplayName returns the name to show a user for the resource: its title, or its name.
+func (r Resource) DisplayName() string { return displayName(r.Title, r.Name) }
+
+// DisplayName returns the name to show a user for the template: its title, or its name.
+func (r ResourceTemplate) DisplayName() string { return displayName(r.Title, r.Name) }
+
+// DisplayName returns the name to show a user for the implementation: its title, or its name.
+func (i Implementation) DisplayName() string { return displayName(i.Title, i.Name) }
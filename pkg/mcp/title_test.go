@@ -0,0 +1,25 @@
+package mcp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDisplayName(t *testing.T) {
+	if got := (Tool{Name: "query_table", Title: "Query Table"}).DisplayName(); got != "Query Table" {
+		t.Errorf("titled tool DisplayName() = %q", got)
+	}
+	if got := (Prompt{Name: "query"}).DisplayName(); got != "query" {
+		t.Errorf("untitled prompt DisplayName() = %q, want the name", got)
+	}
+
+	// Title is left out of the wire format when empty, for older clients
+	data, _ := json.Marshal(Resource{Name: "example.txt", URI: "file:///example.txt"})
+	if string(data) != `{"name":"example.txt","uri":"file:///example.txt"}` {
+		t.Errorf("untitled resource = %s", data)
+	}
+	var r ResourceTemplate
+	if err := json.Unmarshal([]byte(`{"name":"random_data","title":"Random Data","uriTemplate":"data://x"}`), &r); err != nil || r.DisplayName() != "Random Data" {
+		t.Errorf("decoded template = %+v (%v)", r, err)
+	}
+}
@@ -0,0 +1,134 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// NewTextContent returns a marshalled text content block for a tool result or prompt message.
+func NewTextContent(text string) json.RawMessage {
+	content, _ := json.Marshal(TextContent{Type: "text", Text: text}) // Strings always marshal
+	return content
+}
+
+// NewImageContent returns a marshalled image content block, base64-encoding data.
+func NewImageContent(data []byte, mimeType string) json.RawMessage {
+	content, _ := json.Marshal(ImageContent{Type: "image", MimeType: mimeType, Data: base64.StdEncoding.EncodeToString(data)})
+	return content
+}
+
+// NewEmbeddedResource returns a marshalled resource content block embedding
+// contents built with NewTextResource or NewBlobResource.
+func NewEmbeddedResource(contents json.RawMessage) json.RawMessage {
+	content, _ := json.Marshal(EmbeddedResource{Type: "resource", Resource: contents}) // contents is already valid JSON
+	return content
+}
+
+// NewResourceLink returns a marshalled resource_link content block pointing at
+// link.URI, for tools that refer to a resource instead of embedding its contents.
+func NewResourceLink(link ResourceLink) json.RawMessage {
+	link.Type = "resource_link"
+	content, _ := json.Marshal(link) // Strings and annotations always marshal
+	return content
+}
+
+// ResourceLinks returns the resource_link blocks of the result, in order. The
+// client reads the linked resources with resources/read when it needs them.
+func (r *CallToolResult) ResourceLinks() []ResourceLink {
+	var links []ResourceLink
+	for _, block := range r.Content {
+		var link ResourceLink
+		if json.Unmarshal(block, &link) == nil && link.Type == "resource_link" {
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+// NewToolResultText returns a tool result with a single text block.
+func NewToolResultText(text string) CallToolResult {
+	return CallToolResult{Content: []json.RawMessage{NewTextContent(text)}}
+}
+
+// NewToolResultError returns a tool result reporting err to the model. Tool
+// failures are results with IsError set, not JSON-RPC errors, so the model can
+// see what went wrong and react to it.
+func NewToolResultError(err error) CallToolResult {
+	return CallToolResult{Content: []json.RawMessage{NewTextContent(err.Error())}, IsError: true}
+}
+
+// NewToolResultImage returns a tool result with a single image block.
+func NewToolResultImage(data []byte, mimeType string) CallToolResult {
+	return CallToolResult{Content: []json.RawMessage{NewImageContent(data, mimeType)}}
+}
+
+// NewToolResultJSON returns a tool result with v, marshalled as indented JSON, as its text block.
+func NewToolResultJSON(v interface{}) (CallToolResult, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return CallToolResult{}, fmt.Errorf("failed to marshal tool result: %w", err)
+	}
+	return NewToolResultText(string(data)), nil
+}
+
+// NewToolResultStructured returns a tool result with v, which must marshal to
+// a JSON object, as its structured content and the same JSON as its text block.
+func NewToolResultStructured(v interface{}) (CallToolResult, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return CallToolResult{}, fmt.Errorf("failed to marshal tool result: %w", err)
+	}
+	if !isJSONObject(data) {
+		return CallToolResult{}, ErrStructuredContentNotObject
+	}
+	result := NewToolResultText(string(data))
+	result.StructuredContent = data
+	return result, nil
+}
+
+// Errors returned by CallToolResult.Validate, wrapped with the details.
+var (
+	ErrToolResultNoContent        = errors.New("tool result has no content")
+	ErrToolResultErrorUnexplained = errors.New("error tool result has no content explaining the error")
+	ErrStructuredContentNotObject = errors.New("structured content is not a JSON object")
+	ErrContentBlockInvalid        = errors.New("invalid content block")
+)
+
+// Validate reports whether r is a result the specification allows. Content is
+// required, even if empty, whether or not StructuredContent is set; an error
+// result must carry at least one content block saying what went wrong, since
+// the model may not read StructuredContent; and StructuredContent, when
+// present, must be a JSON object. Every content block must be an object with
+// a type.
+func (r *CallToolResult) Validate() error {
+	if r.Content == nil {
+		return ErrToolResultNoContent
+	}
+	if r.IsError && len(r.Content) == 0 {
+		return ErrToolResultErrorUnexplained
+	}
+	if len(r.StructuredContent) > 0 && !isJSONObject(r.StructuredContent) {
+		return ErrStructuredContentNotObject
+	}
+	for i, block := range r.Content {
+		var head struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(block, &head); err != nil {
+			return fmt.Errorf("%w %d: %v", ErrContentBlockInvalid, i, err)
+		}
+		if head.Type == "" {
+			return fmt.Errorf("%w %d: missing type", ErrContentBlockInvalid, i)
+		}
+	}
+	return nil
+}
+
+// isJSONObject reports whether data holds a JSON object.
+func isJSONObject(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	return len(data) > 0 && data[0] == '{' && json.Valid(data)
+}
@@ -0,0 +1,123 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestToolResultBuilders(t *testing.T) {
+	tests := []struct {
+		name    string
+		result  CallToolResult
+		want    string
+		isError bool
+	}{
+		{"text", NewToolResultText(`say "hi"`), `{"text":"say \"hi\"","type":"text"}`, false},
+		{"error", NewToolResultError(errors.New("boom")), `{"text":"boom","type":"text"}`, true},
+		{"image", NewToolResultImage([]byte("png"), "image/png"), `{"data":"cG5n","mimeType":"image/png","type":"image"}`, false},
+	}
+	for _, tt := range tests {
+		if len(tt.result.Content) != 1 || string(tt.result.Content[0]) != tt.want || tt.result.IsError != tt.isError {
+			t.Errorf("%s: result = %s (isError %v), want %s (isError %v)", tt.name, tt.result.Content, tt.result.IsError, tt.want, tt.isError)
+		}
+	}
+
+	result, err := NewToolResultJSON(map[string]int{"n": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var text TextContent
+	if err := json.Unmarshal(result.Content[0], &text); err != nil || text.Text != "{\n  \"n\": 1\n}" {
+		t.Errorf("NewToolResultJSON text = %q (%v)", text.Text, err)
+	}
+	if _, err := NewToolResultJSON(func() {}); err == nil {
+		t.Error("NewToolResultJSON accepted an unmarshallable value")
+	}
+}
+
+func TestNewToolResultStructured(t *testing.T) {
+	result, err := NewToolResultStructured(map[string]interface{}{"temperature": 22.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var text TextContent
+	if err := json.Unmarshal(result.Content[0], &text); err != nil || text.Text != string(result.StructuredContent) {
+		t.Errorf("text block %q does not match structured content %s (%v)", text.Text, result.StructuredContent, err)
+	}
+	if err := result.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+	if _, err := NewToolResultStructured([]int{1, 2}); !errors.Is(err, ErrStructuredContentNotObject) {
+		t.Errorf("NewToolResultStructured(array) error = %v", err)
+	}
+}
+
+// TestValidateFixtures checks Validate against the responses in
+// testdata/toolresults, modelled on the examples of the specification.
+func TestValidateFixtures(t *testing.T) {
+	want := map[string]error{
+		"text.json":                      nil,
+		"structured.json":                nil,
+		"tool_error.json":                nil,
+		"tool_error_structured.json":     nil,
+		"empty_content.json":             nil,
+		"invalid_missing_content.json":   ErrToolResultNoContent,
+		"invalid_error_unexplained.json": ErrToolResultErrorUnexplained,
+		"invalid_structured_array.json":  ErrStructuredContentNotObject,
+		"invalid_block_type.json":        ErrContentBlockInvalid,
+	}
+	files, err := filepath.Glob(filepath.Join("testdata", "toolresults", "*.json"))
+	if err != nil || len(files) != len(want) {
+		t.Fatalf("found fixtures %v (%v), want %d", files, err, len(want))
+	}
+	for _, file := range files {
+		wantErr, ok := want[filepath.Base(file)]
+		if !ok {
+			t.Errorf("no expectation for fixture %s", file)
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, _, rpcErr, err := UnmarshalCallToolResponse(data)
+		if err != nil || rpcErr != nil {
+			t.Fatalf("%s: %v %v", file, rpcErr, err)
+		}
+		if err := result.Validate(); !errors.Is(err, wantErr) || (wantErr == nil) != (err == nil) {
+			t.Errorf("%s: Validate() = %v, want %v", file, err, wantErr)
+		}
+	}
+}
+
+func TestValidateBuilders(t *testing.T) {
+	jsonResult, _ := NewToolResultJSON([]int{1})
+	for name, result := range map[string]CallToolResult{
+		"text":  NewToolResultText("ok"),
+		"error": NewToolResultError(errors.New("boom")),
+		"json":  jsonResult,
+	} {
+		if err := result.Validate(); err != nil {
+			t.Errorf("%s: Validate() = %v", name, err)
+		}
+	}
+	if err := (&CallToolResult{IsError: true}).Validate(); !errors.Is(err, ErrToolResultNoContent) {
+		t.Errorf("zero error result: Validate() = %v", err)
+	}
+}
+
+func TestResourceLinks(t *testing.T) {
+	link := ResourceLink{URI: "file:///big.txt", Name: "big.txt", MimeType: "text/plain", Description: "A large file"}
+	result := NewToolResultText("see the file")
+	result.Content = append(result.Content, NewResourceLink(link))
+	if err := result.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+	link.Type = "resource_link"
+	if links := result.ResourceLinks(); len(links) != 1 || links[0] != link {
+		t.Errorf("ResourceLinks() = %+v, want [%+v]", links, link)
+	}
+}
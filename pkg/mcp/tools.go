@@ -19,10 +19,16 @@ type ToolInputSchema map[string]interface{}
 type Tool struct {
 	// Description is a human-readable description of the tool.
 	Description string `json:"description,omitempty"`
+	// Icons are images a graphical host can show for the tool.
+	Icons []Icon `json:"icons,omitempty"`
 	// InputSchema is a JSON Schema object defining the expected parameters.
 	InputSchema ToolInputSchema `json:"inputSchema"`
+	// Meta contains reserved protocol metadata, such as MetaDeprecation.
+	Meta map[string]interface{} `json:"_meta,omitempty"`
 	// Name is the name of the tool.
 	Name string `json:"name"`
+	// Title is a human-readable name for display; clients fall back to Name.
+	Title string `json:"title,omitempty"`
 }
 
 // ListToolsParams defines the parameters for a "tools/list" request.
@@ -41,8 +47,19 @@ type ListToolsResult struct {
 	Tools []Tool `json:"tools"`
 }
 
+// MetaKeyIdempotencyKey is the tools/call _meta key under which a client may
+// send an idempotency key: a server that has already answered a call with the
+// same key (and the same tool and arguments) returns that result again instead
+// of running the tool twice. Replayed results carry MetaKeyIdempotentReplay.
+const (
+	MetaKeyIdempotencyKey   = "idempotencyKey"
+	MetaKeyIdempotentReplay = "idempotentReplay"
+)
+
 // CallToolParams defines the parameters for a "tools/call" request.
 type CallToolParams struct {
+	// Meta contains reserved protocol metadata, e.g. MetaKeyIdempotencyKey.
+	Meta map[string]interface{} `json:"_meta,omitempty"`
 	// Arguments are the parameters to pass to the tool.
 	// Using map[string]interface{} for flexibility as argument types can vary.
 	Arguments map[string]interface{} `json:"arguments,omitempty"`
@@ -58,36 +75,45 @@ type EmbeddedResource struct {
 	Type        string          `json:"type"`     // Should be "resource"
 }
 
+// ResourceLink points at a resource the client can read with resources/read,
+// without embedding its contents.
+type ResourceLink struct {
+	Annotations *Annotations `json:"annotations,omitempty"`
+	Description string       `json:"description,omitempty"`
+	MimeType    string       `json:"mimeType,omitempty"`
+	Name        string       `json:"name"`
+	Title       string       `json:"title,omitempty"`
+	Type        string       `json:"type"` // Should be "resource_link"
+	URI         string       `json:"uri"`
+}
+
 // CallToolResult defines the result structure for a "tools/call" response.
 type CallToolResult struct {
 	// Meta contains reserved protocol metadata.
 	Meta map[string]interface{} `json:"_meta,omitempty"`
-	// Content holds the tool's output data (TextContent, ImageContent, or EmbeddedResource).
+	// Content holds the tool's output data (TextContent, ImageContent, EmbeddedResource
+	// or ResourceLink).
 	// Each element needs to be unmarshaled into the specific type based on the "type" field
 	// after initial unmarshaling into json.RawMessage.
 	Content []json.RawMessage `json:"content"`
-	// IsError indicates if the tool call resulted in an error. Defaults to false.
+	// StructuredContent is the tool's output as a JSON object, for clients that
+	// want the data rather than a rendering of it. It may accompany Content; a
+	// tool that sets it should also put the serialized JSON in a text block for
+	// clients that only read Content.
+	StructuredContent json.RawMessage `json:"structuredContent,omitempty"`
+	// IsError reports that the tool ran and failed, as opposed to a JSON-RPC
+	// error for a call that could not be made. Content must then explain the
+	// failure to the model; StructuredContent, if any, describes it as data.
 	IsError bool `json:"isError,omitempty"`
 }
 
 // MarshalListToolsRequest creates a JSON-RPC request for the tools/list method.
 // The id can be a string or an integer. If params is nil, default empty params will be used.
 func MarshalListToolsRequest(id RequestID, params *ListToolsParams) ([]byte, error) {
-	// Use default empty params if nil is provided
-	var p interface{}
 	if params != nil {
-		p = params
-	} else {
-		p = struct{}{} // Empty object for params if none specified
+		return MarshalRequest(id, MethodListTools, params)
 	}
-
-	req := RPCRequest{
-		JSONRPC: JSONRPCVersion,
-		Method:  MethodListTools,
-		Params:  p,
-		ID:      id,
-	}
-	return json.Marshal(req)
+	return marshalRequest(id, MethodListTools, nil, EmptyParamsObject)
 }
 
 // UnmarshalListToolsResponse parses a JSON-RPC response for a tools/list request.
@@ -122,13 +148,7 @@ func UnmarshalListToolsResponse(data []byte) (*ListToolsResult, RequestID, *RPCE
 // MarshalCallToolRequest creates a JSON-RPC request for the tools/call method.
 // The id can be a string or an integer.
 func MarshalCallToolRequest(id RequestID, params CallToolParams) ([]byte, error) {
-	req := RPCRequest{
-		JSONRPC: JSONRPCVersion,
-		Method:  MethodCallTool,
-		Params:  params,
-		ID:      id,
-	}
-	return json.Marshal(req)
+	return MarshalRequest(id, MethodCallTool, params)
 }
 
 // UnmarshalCallToolResponse parses a JSON-RPC response for a tools/call request.
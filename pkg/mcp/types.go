@@ -2,6 +2,7 @@ package mcp
 
 import (
 	"encoding/json"
+	"fmt"
 )
 
 // MethodPing is the method name for the ping request.
@@ -14,11 +15,46 @@ const JSONRPCVersion = "2.0"
 type RequestID interface{}
 
 // RPCRequest defines the structure for a JSON-RPC request.
+// Params is kept raw so that handlers decode it once, straight into their typed params.
 type RPCRequest struct {
-	JSONRPC string      `json:"jsonrpc"`
-	Method  string      `json:"method"`
-	Params  interface{} `json:"params,omitempty"`
-	ID      RequestID   `json:"id"`
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      RequestID       `json:"id"`
+}
+
+// MarshalRequest creates a JSON-RPC request for method with the given id.
+// params may be nil for requests without parameters, which are then written
+// without params.
+func MarshalRequest(id RequestID, method string, params interface{}) ([]byte, error) {
+	return marshalRequest(id, method, params, EmptyParamsOmit)
+}
+
+// marshalRequest is MarshalRequest with empty as the marshaller's own choice
+// for nil params.
+func marshalRequest(id RequestID, method string, params interface{}, empty EmptyParams) ([]byte, error) {
+	req := RPCRequest{JSONRPC: JSONRPCVersion, Method: method, ID: id}
+	if params != nil {
+		raw, err := json.Marshal(params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s params: %w", method, err)
+		}
+		req.Params = raw
+	} else {
+		req.Params = emptyParamsValue(empty)
+	}
+	return json.Marshal(req)
+}
+
+// UnmarshalParams decodes the params member of a request payload into params,
+// which must be a pointer. The params are decoded in the same pass as the
+// envelope, without an intermediate copy. Missing, null or empty ({} or [])
+// params leave params untouched.
+func UnmarshalParams(payload []byte, params interface{}) error {
+	req := struct {
+		Params paramsTarget `json:"params"`
+	}{Params: paramsTarget{params}}
+	return json.Unmarshal(payload, &req)
 }
 
 // RPCResponse defines the structure for a JSON-RPC response.
@@ -0,0 +1,97 @@
+package mcp
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// URITemplate matches URIs against an RFC 6570 template of simple string
+// expansions, such as "data://random_data?length={length}" or
+// "file:///logs/{date}.log". Each variable matches one URI segment: the text up
+// to the next "/", "?", "&" or "#".
+type URITemplate struct {
+	template  string
+	variables []string
+	pattern   *regexp.Regexp
+}
+
+// ParseURITemplate parses template. Operator expressions such as {+path} or
+// {?query} are not supported.
+func ParseURITemplate(template string) (*URITemplate, error) {
+	var pattern strings.Builder
+	var variables []string
+	pattern.WriteString("^")
+	rest := template
+	for {
+		open := strings.IndexByte(rest, '{')
+		if open < 0 {
+			break
+		}
+		closing := strings.IndexByte(rest[open:], '}')
+		if closing < 0 {
+			return nil, fmt.Errorf("URI template %q: unterminated expression", template)
+		}
+		name := rest[open+1 : open+closing]
+		if !validTemplateVariable(name) {
+			return nil, fmt.Errorf("URI template %q: unsupported expression {%s}", template, name)
+		}
+		for _, v := range variables {
+			if v == name {
+				return nil, fmt.Errorf("URI template %q: variable %s appears twice", template, name)
+			}
+		}
+		pattern.WriteString(regexp.QuoteMeta(rest[:open]))
+		pattern.WriteString(`([^/?&#]*)`)
+		variables = append(variables, name)
+		rest = rest[open+closing+1:]
+	}
+	if strings.IndexByte(rest, '}') >= 0 {
+		return nil, fmt.Errorf("URI template %q: unmatched '}'", template)
+	}
+	pattern.WriteString(regexp.QuoteMeta(rest))
+	pattern.WriteString("$")
+	return &URITemplate{template: template, variables: variables, pattern: regexp.MustCompile(pattern.String())}, nil
+}
+
+// validTemplateVariable reports whether name is a plain variable name.
+func validTemplateVariable(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !(r == '_' || r == '.' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the template as parsed.
+func (t *URITemplate) String() string {
+	return t.template
+}
+
+// Variables returns the names of the template's variables in order.
+func (t *URITemplate) Variables() []string {
+	return append([]string(nil), t.variables...)
+}
+
+// Match reports whether uri matches the template and returns the values of
+// its variables, percent-decoded.
+func (t *URITemplate) Match(uri string) (map[string]string, bool) {
+	m := t.pattern.FindStringSubmatch(uri)
+	if m == nil {
+		return nil, false
+	}
+	vars := make(map[string]string, len(t.variables))
+	for i, name := range t.variables {
+		value, err := url.PathUnescape(m[i+1])
+		if err != nil {
+			return nil, false
+		}
+		vars[name] = value
+	}
+	return vars, true
+}
@@ -0,0 +1,43 @@
+package mcp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestURITemplateMatch(t *testing.T) {
+	tmpl, err := ParseURITemplate("file:///logs/{service}/{date}.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tmpl.Variables(); !reflect.DeepEqual(got, []string{"service", "date"}) {
+		t.Errorf("Variables() = %v", got)
+	}
+	tests := map[string]map[string]string{
+		"file:///logs/api/2025-06-01.log":      {"service": "api", "date": "2025-06-01"},
+		"file:///logs/my%20app/2025-06-01.log": {"service": "my app", "date": "2025-06-01"},
+		"file:///logs/api/x/2025-06-01.log":    nil, // A variable does not span segments
+		"file:///logs/api/2025-06-01.txt":      nil,
+		"xfile:///logs/api/2025-06-01.log":     nil,
+	}
+	for uri, want := range tests {
+		vars, ok := tmpl.Match(uri)
+		if ok != (want != nil) || (ok && !reflect.DeepEqual(vars, want)) {
+			t.Errorf("Match(%s) = %v, %v; want %v", uri, vars, ok, want)
+		}
+	}
+
+	query, _ := ParseURITemplate("data://random_data?length={length}")
+	if vars, ok := query.Match("data://random_data?length=16"); !ok || vars["length"] != "16" {
+		t.Errorf("query Match = %v, %v", vars, ok)
+	}
+	if _, ok := query.Match("data://random_data?length=16&x=1"); ok {
+		t.Error("query variable matched past '&'")
+	}
+
+	for _, bad := range []string{"a/{b", "a/{}", "a/{+path}", "a/{?q}", "a/{x}/{x}", "a/}"} {
+		if _, err := ParseURITemplate(bad); err == nil {
+			t.Errorf("ParseURITemplate(%q) succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package mcptest
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"sqirvy/mcp/pkg/mcp"
+)
+
+// Expectation describes one expected request and how the fake server reacts to it.
+// All methods return the receiver so calls can be chained.
+type Expectation struct {
+	server  *Server
+	method  string
+	matcher func(params json.RawMessage) bool
+
+	result        interface{}
+	rpcErr        *mcp.RPCError
+	delay         time.Duration
+	notifications []scriptedNotification
+
+	met bool // Guarded by server.mu
+}
+
+// scriptedNotification is a notification sent after the response to an expectation.
+type scriptedNotification struct {
+	method string
+	params interface{}
+	delay  time.Duration
+}
+
+// WithParams restricts the expectation to requests whose params satisfy match.
+func (e *Expectation) WithParams(match func(params json.RawMessage) bool) *Expectation {
+	e.matcher = match
+	return e
+}
+
+// Respond sets the result sent back for the matched request.
+func (e *Expectation) Respond(result interface{}) *Expectation {
+	e.result = result
+	e.rpcErr = nil
+	return e
+}
+
+// RespondError makes the matched request fail with the given JSON-RPC error.
+func (e *Expectation) RespondError(rpcErr *mcp.RPCError) *Expectation {
+	e.rpcErr = rpcErr
+	return e
+}
+
+// After delays the response to the matched request, e.g. to exercise client timeouts.
+func (e *Expectation) After(delay time.Duration) *Expectation {
+	e.delay = delay
+	return e
+}
+
+// Notify emits a notification delay after the response to the matched request has been sent.
+func (e *Expectation) Notify(method string, params interface{}, delay time.Duration) *Expectation {
+	e.notifications = append(e.notifications, scriptedNotification{method: method, params: params, delay: delay})
+	return e
+}
+
+// Met reports whether a matching request has been received.
+func (e *Expectation) Met() bool {
+	e.server.mu.Lock()
+	defer e.server.mu.Unlock()
+	return e.met
+}
+
+// reply sends the scripted response (and notifications) for msg.
+func (e *Expectation) reply(msg Message) {
+	send := func() {
+		if err := e.sendResponse(msg.ID); err != nil {
+			if e.server.isClosed() {
+				return // The test finished before the (delayed) response was due
+			}
+			e.server.fail("failed to respond to %q (ID: %v): %v", msg.Method, msg.ID, err)
+			return
+		}
+		for _, n := range e.notifications {
+			n := n
+			e.server.schedule(n.delay, func() {
+				// Errors after the test closed the connection are expected and ignored
+				_ = e.server.Notify(n.method, n.params)
+			})
+		}
+	}
+	if e.delay > 0 {
+		e.server.schedule(e.delay, send)
+		return
+	}
+	send()
+}
+
+// sendResponse marshals and writes the success or error response for id.
+func (e *Expectation) sendResponse(id mcp.RequestID) error {
+	var payload []byte
+	var err error
+	if e.rpcErr != nil {
+		payload, err = mcp.MarshalErrorResponse(id, e.rpcErr)
+	} else {
+		var resultBytes []byte
+		resultBytes, err = json.Marshal(e.result)
+		if err == nil {
+			payload, err = json.Marshal(mcp.RPCResponse{JSONRPC: mcp.JSONRPCVersion, Result: resultBytes, ID: id})
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to marshal response: %w", err)
+	}
+	return e.server.write(payload)
+}
@@ -0,0 +1,345 @@
+// Package mcptest provides a scriptable fake MCP server for testing MCP clients
+// without spawning a real server subprocess.
+//
+// A test registers the requests it expects and the responses to send back, then
+// connects its client to Server.Conn(), which is one end of an in-memory pipe
+// carrying newline-delimited JSON-RPC messages (the same framing as the stdio transport).
+//
+//	srv := mcptest.NewServer(t)
+//	srv.ExpectInitialize()
+//	srv.Expect(mcp.MethodListTools).Respond(mcp.ListToolsResult{Tools: tools}).
+//		Notify("notifications/tools/list_changed", nil, 10*time.Millisecond)
+//	client := newClient(srv.Conn())
+//
+// Unmet expectations and unexpected requests are reported as test failures when the test ends.
+package mcptest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"sqirvy/mcp/pkg/mcp"
+)
+
+// DefaultProtocolVersion is the protocol version reported by ExpectInitialize.
+const DefaultProtocolVersion = "2024-11-05"
+
+// Message is a message received by the fake server from the client under test.
+// Responses to requests sent with Server.Request have an empty Method and carry Result or Error.
+type Message struct {
+	Method string          `json:"method"`
+	ID     mcp.RequestID   `json:"id,omitempty"` // nil for notifications
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *mcp.RPCError   `json:"error,omitempty"`
+}
+
+// Server is a scriptable fake MCP server. Create one with NewServer.
+type Server struct {
+	t          testing.TB
+	serverConn net.Conn
+	clientConn net.Conn
+	outbox     chan []byte   // Messages queued for the client, written in order by writeLoop
+	stop       chan struct{} // Closed by Close to stop writeLoop
+
+	mu           sync.Mutex // Protects the fields below
+	expectations []*Expectation
+	received     []Message
+	failures     []string
+	timers       []*time.Timer
+	closed       bool
+
+	done chan struct{} // Receives once from each of the serve and write loops when they exit
+}
+
+// NewServer creates a fake server and starts serving on an in-memory connection.
+// The server is closed and its expectations are verified automatically when the test ends.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	serverConn, clientConn := net.Pipe()
+	s := &Server{
+		t:          t,
+		serverConn: serverConn,
+		clientConn: clientConn,
+		outbox:     make(chan []byte, 64),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}, 2),
+	}
+	go s.serve()
+	go s.writeLoop()
+	t.Cleanup(func() {
+		s.Close()
+		s.AssertExpectations()
+	})
+	return s
+}
+
+// Conn returns the client end of the in-memory connection.
+// Messages written to it are delivered to the fake server, one JSON object per line.
+func (s *Server) Conn() io.ReadWriteCloser {
+	return s.clientConn
+}
+
+// Expect registers an expectation for a request with the given method.
+// Expectations for the same method are matched in registration order, each at most once.
+// Without a Respond or RespondError call the request is answered with an empty result object.
+func (s *Server) Expect(method string) *Expectation {
+	e := &Expectation{server: s, method: method, result: struct{}{}}
+	s.mu.Lock()
+	s.expectations = append(s.expectations, e)
+	s.mu.Unlock()
+	return e
+}
+
+// ExpectInitialize registers an expectation for the initialize request answered with a
+// default InitializeResult advertising tools, resources and prompts.
+func (s *Server) ExpectInitialize() *Expectation {
+	return s.Expect(mcp.MethodInitialize).Respond(mcp.InitializeResult{
+		ProtocolVersion: DefaultProtocolVersion,
+		ServerInfo:      mcp.Implementation{Name: "mcptest", Version: "0.0.0"},
+		Capabilities: mcp.ServerCapabilities{
+			Prompts:   &mcp.ServerCapabilitiesPrompts{},
+			Resources: &mcp.ServerCapabilitiesResources{},
+			Tools:     &mcp.ServerCapabilitiesTools{},
+		},
+	})
+}
+
+// Notify sends a notification to the client immediately.
+func (s *Server) Notify(method string, params interface{}) error {
+	payload, err := mcp.MarshalNotification(method, params)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification %s: %w", method, err)
+	}
+	return s.write(payload)
+}
+
+// Request sends a server-initiated request (e.g. ping or roots/list) to the client immediately.
+// The client's reply can be awaited with WaitForResponse.
+func (s *Server) Request(id mcp.RequestID, method string, params interface{}) error {
+	payload, err := mcp.MarshalRequest(id, method, params)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request %s: %w", method, err)
+	}
+	return s.write(payload)
+}
+
+// Respond sends an unsolicited success response for id to the client immediately,
+// for testing how the client copes with late or duplicate replies.
+func (s *Server) Respond(id mcp.RequestID, result interface{}) error {
+	resultBytes, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to marshal result for %v: %w", id, err)
+	}
+	payload, err := json.Marshal(mcp.RPCResponse{JSONRPC: mcp.JSONRPCVersion, Result: resultBytes, ID: id})
+	if err != nil {
+		return fmt.Errorf("failed to marshal response for %v: %w", id, err)
+	}
+	return s.write(payload)
+}
+
+// Received returns a copy of every message received from the client so far, in arrival order.
+func (s *Server) Received() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.received...)
+}
+
+// WaitFor blocks until a message with the given method has been received or the timeout expires.
+// It returns the first matching message and whether one was found.
+func (s *Server) WaitFor(method string, timeout time.Duration) (Message, bool) {
+	deadline := time.Now().Add(timeout)
+	for {
+		for _, msg := range s.Received() {
+			if msg.Method == method {
+				return msg, true
+			}
+		}
+		if time.Now().After(deadline) {
+			return Message{}, false
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// WaitForResponse blocks until the client has answered the request with the given id or the timeout expires.
+func (s *Server) WaitForResponse(id mcp.RequestID, timeout time.Duration) (Message, bool) {
+	deadline := time.Now().Add(timeout)
+	for {
+		for _, msg := range s.Received() {
+			if msg.Method == "" && fmt.Sprintf("%v", msg.ID) == fmt.Sprintf("%v", id) {
+				return msg, true
+			}
+		}
+		if time.Now().After(deadline) {
+			return Message{}, false
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// AssertExpectations reports a test failure for every expectation that was not met
+// and every problem observed while serving (unexpected requests, malformed input).
+func (s *Server) AssertExpectations() {
+	s.t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.expectations {
+		if !e.met {
+			s.t.Errorf("mcptest: expected request %q was never received", e.method)
+		}
+	}
+	for _, failure := range s.failures {
+		s.t.Errorf("mcptest: %s", failure)
+	}
+	s.failures = nil
+}
+
+// Close stops the server, cancels pending delayed notifications and closes both ends of the connection.
+func (s *Server) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	for _, timer := range s.timers {
+		timer.Stop()
+	}
+	s.mu.Unlock()
+
+	close(s.stop)
+	s.serverConn.Close()
+	s.clientConn.Close()
+	<-s.done
+	<-s.done
+}
+
+// serve reads messages from the client until the connection is closed.
+func (s *Server) serve() {
+	defer func() { s.done <- struct{}{} }()
+	reader := bufio.NewReader(s.serverConn)
+	for {
+		line, err := reader.ReadBytes('\n')
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			s.handle(line)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// handle records one message and answers it if it is a request.
+func (s *Server) handle(payload []byte) {
+	info, err := mcp.ClassifyMessage(payload)
+	if err != nil {
+		s.fail("received invalid message %s: %v", payload, err)
+		return
+	}
+
+	var envelope struct {
+		Params json.RawMessage `json:"params"`
+		Result json.RawMessage `json:"result"`
+		Error  *mcp.RPCError   `json:"error"`
+	}
+	_ = json.Unmarshal(payload, &envelope) // Already validated by ClassifyMessage
+
+	msg := Message{Method: info.Method, ID: info.ID, Params: envelope.Params, Result: envelope.Result, Error: envelope.Error}
+	s.mu.Lock()
+	s.received = append(s.received, msg)
+	s.mu.Unlock()
+
+	if info.Kind != mcp.KindRequest {
+		return // Notifications and responses are only recorded
+	}
+
+	e := s.match(msg)
+	if e == nil {
+		s.fail("unexpected request %q (ID: %v) with params %s", msg.Method, msg.ID, msg.Params)
+		rpcErr := mcp.NewRPCError(mcp.ErrorCodeMethodNotFound, fmt.Sprintf("Method '%s' not found", msg.Method), nil)
+		_ = s.write(mcp.MustErrorResponse(msg.ID, rpcErr))
+		return
+	}
+	e.reply(msg)
+}
+
+// match returns the first unmet expectation accepting msg and marks it met.
+func (s *Server) match(msg Message) *Expectation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.expectations {
+		if e.met || e.method != msg.Method {
+			continue
+		}
+		if e.matcher != nil && !e.matcher(msg.Params) {
+			continue
+		}
+		e.met = true
+		return e
+	}
+	return nil
+}
+
+// write queues one message for the client. Queuing keeps the serve loop from blocking
+// on the synchronous in-memory pipe while the client is itself busy writing.
+func (s *Server) write(payload []byte) error {
+	select {
+	case <-s.stop:
+		return fmt.Errorf("server closed")
+	default:
+	}
+	select {
+	case s.outbox <- payload:
+		return nil
+	case <-s.stop:
+		return fmt.Errorf("server closed")
+	}
+}
+
+// writeLoop writes queued messages to the client, one JSON object per line, until the server is closed.
+func (s *Server) writeLoop() {
+	defer func() { s.done <- struct{}{} }()
+	for {
+		select {
+		case payload := <-s.outbox:
+			if _, err := s.serverConn.Write(append(payload, '\n')); err != nil {
+				return // Connection closed
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// fail records a failure to be reported from the test goroutine by AssertExpectations.
+func (s *Server) fail(format string, args ...interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, fmt.Sprintf(format, args...))
+}
+
+// isClosed reports whether Close has been called.
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// schedule runs fn after delay unless the server has been closed.
+func (s *Server) schedule(delay time.Duration, fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.timers = append(s.timers, time.AfterFunc(delay, fn))
+}
@@ -0,0 +1,155 @@
+package mcptest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"sqirvy/mcp/pkg/mcp"
+)
+
+// testConn is a minimal newline-delimited client used to drive the fake server.
+type testConn struct {
+	t      *testing.T
+	conn   io.ReadWriteCloser
+	reader *bufio.Reader
+}
+
+func newTestConn(t *testing.T, conn io.ReadWriteCloser) *testConn {
+	return &testConn{t: t, conn: conn, reader: bufio.NewReader(conn)}
+}
+
+func (c *testConn) send(payload []byte, err error) {
+	c.t.Helper()
+	if err != nil {
+		c.t.Fatalf("failed to marshal message: %v", err)
+	}
+	if _, err := c.conn.Write(append(payload, '\n')); err != nil {
+		c.t.Fatalf("failed to write message: %v", err)
+	}
+}
+
+func (c *testConn) read() []byte {
+	c.t.Helper()
+	line, err := c.reader.ReadBytes('\n')
+	if err != nil {
+		c.t.Fatalf("failed to read message: %v", err)
+	}
+	return bytes.TrimSpace(line)
+}
+
+func TestServerScriptedSession(t *testing.T) {
+	srv := NewServer(t)
+	srv.ExpectInitialize()
+	srv.Expect(mcp.MethodListTools).
+		Respond(mcp.ListToolsResult{Tools: []mcp.Tool{{Name: "echo", InputSchema: mcp.ToolInputSchema{"type": "object"}}}}).
+		Notify("notifications/tools/list_changed", nil, 5*time.Millisecond)
+	srv.Expect(mcp.MethodCallTool).RespondError(mcp.NewRPCError(mcp.ErrorCodeInvalidParams, "bad arguments", nil))
+
+	c := newTestConn(t, srv.Conn())
+
+	c.send(mcp.MarshalInitializeRequest(1, mcp.InitializeParams{ProtocolVersion: DefaultProtocolVersion}))
+	initResult, id, rpcErr, err := mcp.UnmarshalInitializeResponse(c.read())
+	if err != nil || rpcErr != nil {
+		t.Fatalf("initialize failed: err=%v rpcErr=%v", err, rpcErr)
+	}
+	if id != float64(1) || initResult.ServerInfo.Name != "mcptest" {
+		t.Errorf("unexpected initialize response: id=%v result=%+v", id, initResult)
+	}
+
+	c.send(mcp.MarshalNotification(mcp.MethodInitialized, nil))
+	if _, ok := srv.WaitFor("notifications/initialized", time.Second); !ok {
+		t.Fatalf("initialized notification was not recorded")
+	}
+
+	c.send(mcp.MarshalListToolsRequest(2, nil))
+	tools, _, rpcErr, err := mcp.UnmarshalListToolsResponse(c.read())
+	if err != nil || rpcErr != nil {
+		t.Fatalf("tools/list failed: err=%v rpcErr=%v", err, rpcErr)
+	}
+	if len(tools.Tools) != 1 || tools.Tools[0].Name != "echo" {
+		t.Errorf("unexpected tools: %+v", tools.Tools)
+	}
+
+	info, err := mcp.ClassifyMessage(c.read())
+	if err != nil || info.Kind != mcp.KindNotification || info.Method != "notifications/tools/list_changed" {
+		t.Errorf("expected list_changed notification, got %+v (err=%v)", info, err)
+	}
+
+	c.send(mcp.MarshalCallToolRequest("call-1", mcp.CallToolParams{Name: "echo"}))
+	_, id, rpcErr, err = mcp.UnmarshalCallToolResponse(c.read())
+	if err != nil {
+		t.Fatalf("tools/call response could not be parsed: %v", err)
+	}
+	if rpcErr == nil || rpcErr.Code != mcp.ErrorCodeInvalidParams || id != "call-1" {
+		t.Errorf("expected InvalidParams error for call-1, got id=%v rpcErr=%v", id, rpcErr)
+	}
+
+	if got := len(srv.Received()); got != 4 {
+		t.Errorf("Received() returned %d messages, want 4", got)
+	}
+}
+
+func TestServerWithParamsAndDelay(t *testing.T) {
+	srv := NewServer(t)
+	srv.Expect(mcp.MethodReadResource).
+		WithParams(func(params json.RawMessage) bool { return bytes.Contains(params, []byte("b.txt")) }).
+		Respond(mcp.ReadResourceResult{Contents: []json.RawMessage{json.RawMessage(`{"uri":"file:///b.txt","text":"b"}`)}})
+	slow := srv.Expect(mcp.MethodReadResource).
+		Respond(mcp.ReadResourceResult{Contents: []json.RawMessage{json.RawMessage(`{"uri":"file:///a.txt","text":"a"}`)}}).
+		After(20 * time.Millisecond)
+
+	c := newTestConn(t, srv.Conn())
+	start := time.Now()
+	c.send(mcp.MarshalReadResourcesRequest(1, mcp.ReadResourceParams{URI: "file:///a.txt"}))
+	result, _, _, err := mcp.UnmarshalReadResourcesResponse(c.read())
+	if err != nil {
+		t.Fatalf("resources/read failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("response arrived after %v, expected a delay of at least 20ms", elapsed)
+	}
+	if !bytes.Contains(result.Contents[0], []byte("a.txt")) || !slow.Met() {
+		t.Errorf("request for a.txt was matched by the wrong expectation: %s", result.Contents[0])
+	}
+
+	c.send(mcp.MarshalReadResourcesRequest(2, mcp.ReadResourceParams{URI: "file:///b.txt"}))
+	result, _, _, err = mcp.UnmarshalReadResourcesResponse(c.read())
+	if err != nil || !bytes.Contains(result.Contents[0], []byte("b.txt")) {
+		t.Errorf("unexpected response for b.txt: %v %v", result, err)
+	}
+}
+
+func TestServerReportsUnexpectedRequests(t *testing.T) {
+	recorder := &recordingTB{TB: t}
+	srv := NewServer(recorder)
+	srv.Expect(mcp.MethodListPrompts)
+
+	c := newTestConn(t, srv.Conn())
+	c.send(mcp.MarshalListToolsRequest(1, nil))
+	rpcErr, _, err := mcp.UnmarshalErrorResponse(c.read())
+	if err != nil || rpcErr == nil || rpcErr.Code != mcp.ErrorCodeMethodNotFound {
+		t.Fatalf("expected MethodNotFound for unexpected request, got %v (err=%v)", rpcErr, err)
+	}
+
+	srv.Close()
+	srv.AssertExpectations()
+	if len(recorder.errors) != 2 {
+		t.Errorf("expected 2 reported failures (unmet prompts/list, unexpected tools/list), got %d: %v", len(recorder.errors), recorder.errors)
+	}
+}
+
+// recordingTB captures Errorf calls instead of failing the test.
+type recordingTB struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recordingTB) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, format)
+}
+
+func (r *recordingTB) Helper() {}
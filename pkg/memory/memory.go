@@ -0,0 +1,331 @@
+// Package memory is a durable store of short notes that agents save and search
+// later. Notes are kept per namespace in a JSON file and found by keyword or,
+// with an embedder, by meaning.
+package memory
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"sqirvy/mcp/pkg/index"
+	"sqirvy/mcp/pkg/utils"
+)
+
+const (
+	// MaxTextLength is the longest note in characters.
+	MaxTextLength = 4000
+	// MaxTags is the most tags on one note.
+	MaxTags = 10
+	// MaxPerNamespace is the most notes kept in one namespace.
+	MaxPerNamespace = 5000
+)
+
+// ErrFull is returned (wrapped) by Add when a namespace holds MaxPerNamespace notes.
+var ErrFull = errors.New("memory full")
+
+// Memory is one stored note.
+type Memory struct {
+	ID      string    `json:"id"`
+	Text    string    `json:"text"`
+	Tags    []string  `json:"tags,omitempty"`
+	Created time.Time `json:"created"`
+	Vector  []float32 `json:"vector,omitempty"` // Embedding of Text, by the store's embedder
+}
+
+// Match is a note found by Search.
+type Match struct {
+	Memory
+	Score float64 `json:"score"` // Higher is better; 1 is a perfect match
+}
+
+// file is the on-disk form of the store.
+type file struct {
+	Embedder   string               `json:"embedder,omitempty"` // Name of the embedder that made the vectors
+	NextID     int                  `json:"nextId"`
+	Namespaces map[string][]*Memory `json:"namespaces"`
+}
+
+// Store holds the notes of all namespaces. It is safe for concurrent use, and
+// every change is written to disk before it returns.
+type Store struct {
+	path     string
+	embedder index.Embedder // nil for keyword search
+	sealer   *utils.Sealer  // Encrypts the file at rest, nil for plain JSON
+
+	mu   sync.Mutex
+	data file
+}
+
+// Open returns the store kept at path, creating it on the first Add. If
+// embedder is nil, Search matches keywords; otherwise notes are embedded, and
+// those embedded by a different embedder are embedded again when searched.
+// With a sealer the file is encrypted at rest; a plain file written before is
+// read as it is and sealed on the next change.
+func Open(path string, embedder index.Embedder, sealer *utils.Sealer) (*Store, error) {
+	st := &Store{path: path, embedder: embedder, sealer: sealer, data: file{NextID: 1, Namespaces: make(map[string][]*Memory)}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open memory store: %w", err)
+	}
+	if sealer != nil && !json.Valid(data) {
+		if data, err = sealer.Open(data, nil); err != nil {
+			return nil, fmt.Errorf("failed to read memory store %s: %w", path, err)
+		}
+	}
+	if err := json.Unmarshal(data, &st.data); err != nil {
+		return nil, fmt.Errorf("failed to read memory store %s: %w", path, err)
+	}
+	if st.data.Namespaces == nil {
+		st.data.Namespaces = make(map[string][]*Memory)
+	}
+	if embedder == nil || st.data.Embedder != embedder.Name() {
+		for _, memories := range st.data.Namespaces {
+			for _, m := range memories {
+				m.Vector = nil
+			}
+		}
+	}
+	return st, nil
+}
+
+// Mode describes how Search finds notes, for diagnostics.
+func (st *Store) Mode() string {
+	if st.embedder == nil {
+		return "keyword search"
+	}
+	return st.embedder.Name() + " embeddings"
+}
+
+// Count returns the number of notes in namespace.
+func (st *Store) Count(namespace string) int {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return len(st.data.Namespaces[namespace])
+}
+
+// Add stores a note in namespace and returns it.
+func (st *Store) Add(namespace, text string, tags []string) (Memory, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return Memory{}, fmt.Errorf("text is required")
+	}
+	if n := len([]rune(text)); n > MaxTextLength {
+		return Memory{}, fmt.Errorf("text is %d characters, the maximum is %d", n, MaxTextLength)
+	}
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return Memory{}, err
+	}
+	m := &Memory{Text: text, Tags: tags, Created: time.Now().UTC().Truncate(time.Second)}
+	if st.embedder != nil {
+		vectors, err := st.embedder.Embed([]string{text})
+		if err != nil {
+			return Memory{}, fmt.Errorf("failed to embed the note: %w", err)
+		}
+		m.Vector = vectors[0]
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if len(st.data.Namespaces[namespace]) >= MaxPerNamespace {
+		return Memory{}, fmt.Errorf("%w: %d notes stored", ErrFull, MaxPerNamespace)
+	}
+	m.ID = fmt.Sprintf("mem-%d", st.data.NextID)
+	st.data.NextID++
+	st.data.Namespaces[namespace] = append(st.data.Namespaces[namespace], m)
+	if err := st.save(); err != nil {
+		st.data.Namespaces[namespace] = st.data.Namespaces[namespace][:len(st.data.Namespaces[namespace])-1]
+		return Memory{}, err
+	}
+	return *m, nil
+}
+
+// normalizeTags lowercases and deduplicates tags.
+func normalizeTags(tags []string) ([]string, error) {
+	if len(tags) > MaxTags {
+		return nil, fmt.Errorf("%d tags given, the maximum is %d", len(tags), MaxTags)
+	}
+	var out []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		out = append(out, tag)
+	}
+	return out, nil
+}
+
+// Search returns up to k notes of namespace matching query, best first. A tag
+// limits the search to notes carrying it; with a tag the query may be empty,
+// which returns the newest notes with the tag.
+func (st *Store) Search(namespace, query, tag string, k int) ([]Match, error) {
+	query = strings.TrimSpace(query)
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if query == "" && tag == "" {
+		return nil, fmt.Errorf("query or tag is required")
+	}
+	var q []float32
+	if query != "" && st.embedder != nil {
+		vectors, err := st.embedder.Embed([]string{query})
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed the query: %w", err)
+		}
+		q = vectors[0]
+		if err := st.embedMissing(namespace); err != nil {
+			return nil, err
+		}
+	}
+	terms := keywords(query)
+
+	st.mu.Lock()
+	var matches []Match
+	memories := st.data.Namespaces[namespace]
+	for i := len(memories) - 1; i >= 0; i-- { // Newest first, which the stable sort keeps for equal scores
+		m := memories[i]
+		if tag != "" && !hasTag(m.Tags, tag) {
+			continue
+		}
+		score := 1.0
+		switch {
+		case query == "":
+		case q != nil:
+			score = index.Similarity(q, m.Vector)
+		default:
+			score = keywordScore(terms, m)
+			if score == 0 {
+				continue
+			}
+		}
+		matches = append(matches, Match{Memory: *m, Score: score})
+	}
+	st.mu.Unlock()
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	if len(matches) > k {
+		matches = matches[:k]
+	}
+	for i := range matches {
+		matches[i].Vector = nil
+	}
+	return matches, nil
+}
+
+// embedMissing embeds the notes of namespace that have no vector by the
+// store's embedder, after the store was opened with a different one.
+func (st *Store) embedMissing(namespace string) error {
+	st.mu.Lock()
+	var missing []*Memory
+	var texts []string
+	for _, m := range st.data.Namespaces[namespace] {
+		if m.Vector == nil {
+			missing = append(missing, m)
+			texts = append(texts, m.Text)
+		}
+	}
+	st.mu.Unlock()
+	if len(missing) == 0 {
+		return nil
+	}
+	vectors, err := st.embedder.Embed(texts)
+	if err != nil {
+		return fmt.Errorf("failed to embed stored notes: %w", err)
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for i, m := range missing {
+		m.Vector = vectors[i]
+	}
+	return st.save()
+}
+
+// keywords returns the distinct lowercase words of s.
+func keywords(s string) []string {
+	seen := make(map[string]bool)
+	var words []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if !seen[w] {
+			seen[w] = true
+			words = append(words, w)
+		}
+	}
+	return words
+}
+
+// keywordScore returns the fraction of terms found in the note's text or tags.
+func keywordScore(terms []string, m *Memory) float64 {
+	if len(terms) == 0 {
+		return 0
+	}
+	have := make(map[string]bool)
+	for _, w := range keywords(m.Text + " " + strings.Join(m.Tags, " ")) {
+		have[w] = true
+	}
+	found := 0
+	for _, t := range terms {
+		if have[t] {
+			found++
+		}
+	}
+	return float64(found) / float64(len(terms))
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// save writes the store to a temporary file and renames it into place. The
+// caller holds st.mu.
+func (st *Store) save() error {
+	if st.embedder != nil {
+		st.data.Embedder = st.embedder.Name()
+	} else {
+		st.data.Embedder = ""
+	}
+	data, err := json.Marshal(&st.data)
+	if err != nil {
+		return fmt.Errorf("failed to save memory store: %w", err)
+	}
+	if st.sealer != nil {
+		if data, err = st.sealer.Seal(data, nil); err != nil {
+			return fmt.Errorf("failed to save memory store: %w", err)
+		}
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(st.path), filepath.Base(st.path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to save memory store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save memory store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save memory store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), st.path); err != nil {
+		return fmt.Errorf("failed to save memory store: %w", err)
+	}
+	return nil
+}
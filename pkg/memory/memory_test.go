@@ -0,0 +1,130 @@
+package memory
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sqirvy/mcp/pkg/index"
+	"sqirvy/mcp/pkg/utils"
+)
+
+func TestStoreKeywordSearch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memory.json")
+	st, err := Open(path, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, note := range []struct {
+		text string
+		tags []string
+	}{
+		{"The user prefers tabs over spaces", []string{"Preferences", "preferences"}},
+		{"Deploys happen on Tuesdays", nil},
+		{"The user's editor is vim", []string{"preferences"}},
+	} {
+		if _, err := st.Add("alice", note.text, note.tags); err != nil {
+			t.Fatal(err)
+		}
+	}
+	st.Add("bob", "Bob prefers spaces", nil)
+
+	matches, err := st.Search("alice", "user spaces", "", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 2 || matches[0].ID != "mem-1" || matches[0].Score != 1 || matches[1].Score != 0.5 {
+		t.Errorf("search: %+v", matches)
+	}
+	if matches, _ := st.Search("alice", "", "PREFERENCES", 1); len(matches) != 1 || matches[0].ID != "mem-3" {
+		t.Errorf("tag search: %+v", matches)
+	}
+	if _, err := st.Search("alice", " ", "", 5); err == nil {
+		t.Error("search without query or tag succeeded")
+	}
+
+	// Notes survive a reopen, and namespaces stay apart
+	reopened, err := Open(path, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.Count("alice") != 3 || reopened.Count("bob") != 1 {
+		t.Errorf("reopened store: alice %d, bob %d", reopened.Count("alice"), reopened.Count("bob"))
+	}
+	if m, _ := reopened.Add("bob", "another", nil); m.ID != "mem-5" {
+		t.Errorf("ID after reopen = %s", m.ID)
+	}
+
+	for _, text := range []string{"  ", strings.Repeat("x", MaxTextLength+1)} {
+		if _, err := st.Add("alice", text, nil); err == nil {
+			t.Errorf("Add of %d characters succeeded", len(text))
+		}
+	}
+}
+
+func TestStoreEmbeddings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memory.json")
+	keyword, _ := Open(path, nil, nil)
+	keyword.Add("alice", "The staging database runs on port 5433", nil)
+
+	// Notes stored without an embedder are embedded when first searched
+	st, err := Open(path, index.HashEmbedder{Dims: index.DefaultHashDims}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.Add("alice", "Lunch is at noon", nil)
+	matches, err := st.Search("alice", "which port does the staging database use", "", 1)
+	if err != nil || len(matches) != 1 || matches[0].ID != "mem-1" || matches[0].Vector != nil {
+		t.Fatalf("search: %+v, %v", matches, err)
+	}
+	reopened, _ := Open(path, index.HashEmbedder{Dims: index.DefaultHashDims}, nil)
+	for _, m := range reopened.data.Namespaces["alice"] {
+		if len(m.Vector) != index.DefaultHashDims {
+			t.Errorf("%s has %d dimensions after reopen", m.ID, len(m.Vector))
+		}
+	}
+}
+
+func TestStoreFull(t *testing.T) {
+	st, _ := Open(filepath.Join(t.TempDir(), "memory.json"), nil, nil)
+	st.data.Namespaces["alice"] = make([]*Memory, MaxPerNamespace)
+	if _, err := st.Add("alice", "one more", nil); !errors.Is(err, ErrFull) {
+		t.Errorf("Add to a full namespace = %v", err)
+	}
+}
+
+func TestStoreSealed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memory.json")
+	plain, _ := Open(path, nil, nil)
+	plain.Add("alice", "Written before the key was set", nil)
+
+	sealer, err := utils.NewSealer(bytes.Repeat([]byte{2}, utils.SealKeySize), "memory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := Open(path, nil, sealer)
+	if err != nil {
+		t.Fatalf("Open of a plain store with a sealer: %v", err)
+	}
+	if _, err := st.Add("alice", "The secret is swordfish", nil); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if bytes.Contains(data, []byte("swordfish")) || bytes.Contains(data, []byte("before")) {
+		t.Fatalf("memory file is not sealed: %s", data)
+	}
+
+	reopened, err := Open(path, nil, sealer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.Count("alice") != 2 {
+		t.Errorf("reopened store has %d notes, want 2", reopened.Count("alice"))
+	}
+	if _, err := Open(path, nil, nil); err == nil {
+		t.Error("Open of a sealed store without the key succeeded")
+	}
+}
@@ -0,0 +1,143 @@
+package resources
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings" // Added for HasPrefix and TrimPrefix
+
+	"sqirvy/mcp/pkg/utils" // Import the custom logger
+)
+
+// projectRootPath defines the hardcoded root directory for file URIs.
+const projectRootPath = "/home/dmh2000/projects/mcp"
+
+// ProjectRoot returns the directory that file:// URIs are resolved against.
+func ProjectRoot() string {
+	return filepath.Clean(projectRootPath)
+}
+
+// Size limits for file resources.
+const (
+	// StreamThreshold is the size above which files are streamed and sent base64-encoded
+	// instead of being read into memory as text.
+	StreamThreshold int64 = 1 << 20 // 1 MiB
+	// MaxFileSize is the largest file that will be served at all.
+	MaxFileSize int64 = 32 << 20 // 32 MiB
+)
+
+// ErrFileTooLarge is returned (wrapped) for files larger than MaxFileSize.
+var ErrFileTooLarge = errors.New("file too large")
+
+// FileResource is an open file resolved from a file:// URI. The caller must Close it.
+type FileResource struct {
+	*os.File
+	Path     string // Resolved path within the project root
+	Size     int64  // Size in bytes at open time
+	MimeType string
+}
+
+// ReadFileResource reads the content of a file specified by a file:// URI.
+// It returns the content as bytes, the determined MIME type, and any error.
+// Files larger than MaxFileSize are rejected; callers serving files above
+// StreamThreshold should use OpenFileResource and stream instead.
+func ReadFileResource(uri string, logger *utils.Logger) ([]byte, string, error) {
+	file, err := OpenFileResource(uri, logger)
+	if err != nil {
+		return nil, "", err
+	}
+	defer file.Close()
+
+	// The limit guards against the file growing after the size check
+	content, err := io.ReadAll(io.LimitReader(file, MaxFileSize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading file %s: %w", file.Path, err)
+	}
+	if int64(len(content)) > MaxFileSize {
+		return nil, "", fmt.Errorf("%w: %s exceeds the maximum of %d bytes", ErrFileTooLarge, file.Path, MaxFileSize)
+	}
+	return content, file.MimeType, nil
+}
+
+// OpenFileResource resolves a file:// URI within the project root, opens the file and
+// checks its size against MaxFileSize.
+func OpenFileResource(uri string, logger *utils.Logger) (*FileResource, error) {
+	return OpenFileResourceIn(ProjectRoot(), uri, logger)
+}
+
+// OpenFileResourceIn is OpenFileResource with the URI resolved within root
+// instead, e.g. a directory of the project root set aside for one client.
+func OpenFileResourceIn(root, uri string, logger *utils.Logger) (*FileResource, error) {
+	parsedURI, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URI format: %w", err)
+	}
+
+	if parsedURI.Scheme != "file" {
+		return nil, fmt.Errorf("unsupported URI scheme: %s", parsedURI.Scheme)
+	}
+
+	// Convert file URI path to a system path.
+	// Handle potential differences in path separators and encoding.
+	// For file://hostname/path, Host is usually empty or localhost on Unix-like systems.
+	// For file:///path, Path starts with /.
+	filePath := parsedURI.Path
+	if parsedURI.Host != "" && parsedURI.Host != "localhost" {
+		// Handle UNC paths if necessary, though less common for typical file URIs
+		// For simplicity, we'll assume standard file paths here.
+		logger.Printf("DEBUG", "Warning: file URI host '%s' ignored, treating path as '%s'", parsedURI.Host, filePath)
+	}
+
+	root = filepath.Clean(root)
+	logger.Printf("DEBUG", "Using root directory: %s", root)
+
+	// Treat the URI path as relative to the root.
+	// Strip leading '/' from the URI path.
+	relativePath := strings.TrimPrefix(parsedURI.Path, "/")
+
+	// Join the root with the relative path and clean it.
+	filePath = filepath.Join(root, relativePath)
+	filePath = filepath.Clean(filePath) // Clean the combined path
+
+	// Security Check: Ensure the final path is still within the root.
+	// This helps prevent path traversal attacks (e.g., file:///../outside_project).
+	if filePath != root && !strings.HasPrefix(filePath, root+string(filepath.Separator)) {
+		logger.Printf("DEBUG", "Security Alert: Attempt to access file outside the root. Requested URI: %s, Resolved Path: %s", uri, filePath)
+		return nil, fmt.Errorf("permission denied: cannot access files outside project root")
+	}
+
+	logger.Printf("DEBUG", "Attempting to read file relative to the root: %s", filePath)
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("file not found: %s", filePath)
+		}
+		if os.IsPermission(err) {
+			return nil, fmt.Errorf("permission denied reading file: %s", filePath)
+		}
+		return nil, fmt.Errorf("error opening file %s: %w", filePath, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("error reading file %s: %w", filePath, err)
+	}
+	if info.IsDir() {
+		file.Close()
+		return nil, fmt.Errorf("invalid file resource: %s is a directory", filePath)
+	}
+	if info.Size() > MaxFileSize {
+		file.Close()
+		return nil, fmt.Errorf("%w: %s is %d bytes, the maximum is %d bytes", ErrFileTooLarge, filePath, info.Size(), MaxFileSize)
+	}
+
+	// Basic MIME type detection (can be improved with libraries like net/http.DetectContentType)
+	// For now, assume text/plain for simplicity.
+	mimeType := "text/plain"
+
+	return &FileResource{File: file, Path: filePath, Size: info.Size(), MimeType: mimeType}, nil
+}
@@ -0,0 +1,344 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/transport"
+	"sqirvy/mcp/pkg/utils"
+)
+
+// adminMaxErrors is how many recent error responses the admin surface keeps.
+const adminMaxErrors = 100
+
+// inflightRequest is a request whose handler is running.
+type inflightRequest struct {
+	ID      mcp.RequestID `json:"id"`
+	Method  string        `json:"method"`
+	Started time.Time     `json:"started"`
+}
+
+// sessionStatus is the part of a session the admin surface shows. The Run
+// goroutine owns the session state, so it publishes copies here for readers
+// on other goroutines.
+type sessionStatus struct {
+	mu       sync.Mutex
+	id       int64
+	client   string
+	state    string
+	started  time.Time
+	inflight map[string]inflightRequest // Keyed by requestIDKey
+}
+
+// setState publishes the session state and the client name.
+func (st *sessionStatus) setState(state, client string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.state, st.client = state, client
+}
+
+// begin records a request whose handler is starting.
+func (st *sessionStatus) begin(id mcp.RequestID, method string, started time.Time) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.inflight == nil {
+		st.inflight = make(map[string]inflightRequest)
+	}
+	st.inflight[requestIDKey(id)] = inflightRequest{ID: id, Method: method, Started: started}
+}
+
+// end removes a request recorded with begin.
+func (st *sessionStatus) end(id mcp.RequestID) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	delete(st.inflight, requestIDKey(id))
+}
+
+// sessionInfo is a session as listed by the admin surface.
+type sessionInfo struct {
+	ID       int64             `json:"id"`
+	Client   string            `json:"client,omitempty"`
+	State    string            `json:"state"`
+	Started  time.Time         `json:"started"`
+	InFlight []inflightRequest `json:"inFlight"`
+}
+
+// info returns a copy of the status, with requests oldest first.
+func (st *sessionStatus) info() sessionInfo {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	info := sessionInfo{ID: st.id, Client: st.client, State: st.state, Started: st.started, InFlight: []inflightRequest{}}
+	for _, r := range st.inflight {
+		info.InFlight = append(info.InFlight, r)
+	}
+	sort.Slice(info.InFlight, func(i, j int) bool { return info.InFlight[i].Started.Before(info.InFlight[j].Started) })
+	return info
+}
+
+// recentError is an error response sent to a client.
+type recentError struct {
+	Time    time.Time     `json:"time"`
+	Session int64         `json:"session"`
+	Client  string        `json:"client,omitempty"`
+	ID      mcp.RequestID `json:"id"`
+	Code    int           `json:"code"`
+	Message string        `json:"message"`
+}
+
+// adminRegistry tracks every running session and the most recent error
+// responses for the admin surface. It is shared by all sessions.
+type adminRegistry struct {
+	mu       sync.Mutex
+	sessions map[*Server]struct{}
+	nextID   int64
+	errors   []recentError // Oldest first, at most adminMaxErrors
+}
+
+func newAdminRegistry() *adminRegistry {
+	return &adminRegistry{sessions: make(map[*Server]struct{})}
+}
+
+// add registers a session that has started running.
+func (r *adminRegistry) add(s *Server) {
+	r.mu.Lock()
+	r.nextID++
+	id := r.nextID
+	r.sessions[s] = struct{}{}
+	r.mu.Unlock()
+
+	s.status.mu.Lock()
+	s.status.id, s.status.started = id, time.Now()
+	s.status.mu.Unlock()
+}
+
+// remove unregisters a session that has ended.
+func (r *adminRegistry) remove(s *Server) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.sessions, s)
+}
+
+// recordError remembers an error response sent by s.
+func (r *adminRegistry) recordError(s *Server, id mcp.RequestID, rpcErr *mcp.RPCError) {
+	s.status.mu.Lock()
+	e := recentError{Time: time.Now(), Session: s.status.id, Client: s.status.client, ID: id, Code: rpcErr.Code, Message: rpcErr.Message}
+	s.status.mu.Unlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.errors) == adminMaxErrors {
+		r.errors = append(r.errors[:0], r.errors[1:]...)
+	}
+	r.errors = append(r.errors, e)
+}
+
+// recordErrorResponse records response for the admin surface if it is an error.
+func (s *Server) recordErrorResponse(id mcp.RequestID, response []byte) {
+	if s.registry == nil {
+		return
+	}
+	var resp struct {
+		Error *mcp.RPCError `json:"error"`
+	}
+	if json.Unmarshal(response, &resp) == nil && resp.Error != nil {
+		s.registry.recordError(s, id, resp.Error)
+	}
+}
+
+// list returns the running sessions, oldest first.
+func (r *adminRegistry) list() []sessionInfo {
+	r.mu.Lock()
+	sessions := make([]*Server, 0, len(r.sessions))
+	for s := range r.sessions {
+		sessions = append(sessions, s)
+	}
+	r.mu.Unlock()
+
+	infos := make([]sessionInfo, 0, len(sessions))
+	for _, s := range sessions {
+		infos = append(infos, s.status.info())
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos
+}
+
+// recentErrors returns the recorded error responses, newest first.
+func (r *adminRegistry) recentErrors() []recentError {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]recentError, len(r.errors))
+	for i, e := range r.errors {
+		out[len(out)-1-i] = e
+	}
+	return out
+}
+
+// admin serves the admin surface: a JSON API for operating a long-running
+// server. It listens on a loopback address only and, with a token, requires
+// "Authorization: Bearer <token>". Requests naming another host or coming
+// from another origin are refused, so web pages cannot reach it through DNS
+// rebinding.
+//
+//	GET /admin/sessions             running sessions with their in-flight requests
+//	GET /admin/requests             in-flight requests of every session
+//	GET /admin/tools                registered tools
+//	GET /admin/resources            registered resources
+//	GET /admin/errors               recent error responses, newest first
+//	GET /admin/log-level            the log level; PUT ?level=DEBUG|INFO changes it
+//	GET /admin/features             feature flags; PUT ?name=...&enabled=on|off changes one
+type admin struct {
+	registry *adminRegistry
+	probe    *Server // Session without a client, for listing tools and resources
+	features *featureFlags
+	logger   *utils.Logger
+	token    string
+}
+
+// serveAdmin runs the admin surface on addr, which must be a loopback address.
+func serveAdmin(addr, token string, registry *adminRegistry, newSession func(transport.Transport) *Server, logger *utils.Logger) error {
+	if err := checkLoopback(addr); err != nil {
+		return err
+	}
+	probe := newSession(discardTransport{})
+	a := &admin{registry: registry, probe: probe, features: probe.features, logger: logger, token: token}
+	logger.Printf("DEBUG", "Admin surface listening on %s", addr)
+	return http.ListenAndServe(addr, a.handler())
+}
+
+// checkLoopback rejects listen addresses that other hosts could reach.
+func checkLoopback(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid admin address %q: %w", addr, err)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("admin address %q must be a loopback address such as localhost:9090", addr)
+}
+
+// checkAdminRequest refuses requests whose Host header is not a loopback name
+// or address, or whose Origin is not the admin surface itself.
+func checkAdminRequest(r *http.Request) error {
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host = strings.Trim(r.Host, "[]") // No port
+	}
+	if checkLoopback(net.JoinHostPort(strings.TrimSuffix(host, "."), "0")) != nil {
+		return fmt.Errorf("host %q not allowed", r.Host)
+	}
+	if !sameOrigin(r) {
+		return fmt.Errorf("origin %q not allowed", r.Header.Get("Origin"))
+	}
+	return nil
+}
+
+// handler returns the admin routes behind the access checks.
+func (a *admin) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /admin/sessions", func(w http.ResponseWriter, r *http.Request) {
+		writeGatewayJSON(w, http.StatusOK, a.registry.list())
+	})
+	mux.HandleFunc("GET /admin/requests", a.requests)
+	mux.HandleFunc("GET /admin/tools", func(w http.ResponseWriter, r *http.Request) {
+		writeGatewayJSON(w, http.StatusOK, a.probe.listTools())
+	})
+	mux.HandleFunc("GET /admin/resources", a.resources)
+	mux.HandleFunc("GET /admin/errors", func(w http.ResponseWriter, r *http.Request) {
+		writeGatewayJSON(w, http.StatusOK, a.registry.recentErrors())
+	})
+	mux.HandleFunc("GET /admin/log-level", a.logLevel)
+	mux.HandleFunc("PUT /admin/log-level", a.logLevel)
+	mux.HandleFunc("GET /admin/features", a.featureFlags)
+	mux.HandleFunc("PUT /admin/features", a.featureFlags)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err != nil || !net.ParseIP(host).IsLoopback() {
+			writeGatewayJSON(w, http.StatusForbidden, map[string]string{"error": "admin requests must come from this host"})
+			return
+		}
+		if err := checkAdminRequest(r); err != nil {
+			writeGatewayJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+			return
+		}
+		if a.token != "" {
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(a.token)) != 1 {
+				writeGatewayJSON(w, http.StatusUnauthorized, map[string]string{"error": "missing or wrong admin token"})
+				return
+			}
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// requests lists the in-flight requests of every session, oldest first.
+func (a *admin) requests(w http.ResponseWriter, r *http.Request) {
+	type sessionRequest struct {
+		Session int64 `json:"session"`
+		inflightRequest
+	}
+	requests := []sessionRequest{}
+	for _, s := range a.registry.list() {
+		for _, req := range s.InFlight {
+			requests = append(requests, sessionRequest{Session: s.ID, inflightRequest: req})
+		}
+	}
+	sort.Slice(requests, func(i, j int) bool { return requests[i].Started.Before(requests[j].Started) })
+	writeGatewayJSON(w, http.StatusOK, requests)
+}
+
+// resources lists the concrete resources through the resources/list handler.
+func (a *admin) resources(w http.ResponseWriter, r *http.Request) {
+	payload, _ := mcp.MarshalRequest(1, mcp.MethodListResources, nil)
+	var resp struct {
+		Result mcp.ListResourcesResult `json:"result"`
+		Error  *mcp.RPCError           `json:"error"`
+	}
+	if err := json.Unmarshal(a.probe.dispatch(1, mcp.MethodListResources, payload), &resp); err != nil || resp.Error != nil {
+		writeGatewayJSON(w, http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("resources/list failed: %v %v", err, resp.Error)})
+		return
+	}
+	writeGatewayJSON(w, http.StatusOK, resp.Result.Resources)
+}
+
+// logLevel reports or changes the log level of the whole server.
+func (a *admin) logLevel(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPut {
+		level := strings.ToUpper(r.URL.Query().Get("level"))
+		if level != utils.LevelDebug && level != utils.LevelInfo {
+			writeGatewayJSON(w, http.StatusBadRequest, map[string]string{"error": "level must be DEBUG or INFO"})
+			return
+		}
+		a.logger.SetLevel(level)
+		a.logger.Printf("INFO", "Admin: log level set to %s", level)
+	}
+	writeGatewayJSON(w, http.StatusOK, map[string]string{"level": a.logger.Level()})
+}
+
+// featureFlags reports or changes the feature flags, see features.go.
+func (a *admin) featureFlags(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPut {
+		query := r.URL.Query()
+		on, err := parseFeatureValue(query.Get("enabled"))
+		if err == nil {
+			err = a.features.set(query.Get("name"), on)
+		}
+		if err != nil {
+			writeGatewayJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		a.logger.Printf("INFO", "Admin: feature %s set to %t", query.Get("name"), on)
+	}
+	writeGatewayJSON(w, http.StatusOK, a.features.snapshot())
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/transport"
+	"sqirvy/mcp/pkg/utils"
+)
+
+func TestCheckLoopback(t *testing.T) {
+	for addr, ok := range map[string]bool{"localhost:9090": true, "127.0.0.1:9090": true, "[::1]:9090": true, ":9090": false, "0.0.0.0:9090": false, "example.com:9090": false, "9090": false} {
+		if err := checkLoopback(addr); (err == nil) != ok {
+			t.Errorf("checkLoopback(%q) = %v", addr, err)
+		}
+	}
+}
+
+func TestAdminAPI(t *testing.T) {
+	var logs bytes.Buffer
+	logger := utils.New(&logs, "", 0, utils.LevelInfo)
+	registry := newAdminRegistry()
+	gate := make(chan struct{})
+	slow := &toolModule{name: "slow", tools: []moduleTool{{
+		tool: mcp.Tool{Name: "slow"},
+		call: func(context.Context, *Server, map[string]interface{}) (mcp.CallToolResult, error) {
+			<-gate
+			return mcp.NewToolResultText("done"), nil
+		},
+	}}}
+	newSession := func(t transport.Transport) *Server {
+		s := NewServer(t, logger)
+		s.registry = registry
+		s.modules = []*toolModule{slow}
+		return s
+	}
+	a := &admin{registry: registry, probe: newSession(discardTransport{}), logger: logger, token: "secret"}
+	a.features = a.probe.features
+	api := httptest.NewServer(a.handler())
+	defer api.Close()
+	get := func(method, path string, v interface{}) int {
+		t.Helper()
+		req, _ := http.NewRequest(method, api.URL+path, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if v != nil {
+			json.NewDecoder(resp.Body).Decode(v)
+		}
+		return resp.StatusCode
+	}
+
+	tr := &chanTransport{captureTransport: captureTransport{written: make(chan []byte, 16)}, in: make(chan []byte)}
+	defer close(tr.in)
+	go newSession(tr).Run()
+	tr.in <- []byte(initializeRequest)
+	readWire(t, tr.written)
+	tr.in <- []byte(initializedNotify)
+	tr.in <- []byte(`{"jsonrpc":"2.0","id":2,"method":"nope"}`)
+	readWire(t, tr.written)
+	tr.in <- []byte(`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"slow","arguments":{}}}`)
+
+	var requests []struct {
+		Session int64
+		ID      float64
+		Method  string
+	}
+	for deadline := time.Now().Add(time.Second); len(requests) == 0 && time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		get("GET", "/admin/requests", &requests)
+	}
+	if len(requests) != 1 || requests[0].Session != 1 || requests[0].ID != 3 || requests[0].Method != mcp.MethodCallTool {
+		t.Errorf("/admin/requests = %+v", requests)
+	}
+	var sessions []sessionInfo
+	if get("GET", "/admin/sessions", &sessions); len(sessions) != 1 || sessions[0].Client != "test" || sessions[0].State != stateReady.String() {
+		t.Errorf("/admin/sessions = %+v", sessions)
+	}
+	close(gate)
+	readWire(t, tr.written)
+
+	var errors []recentError
+	if get("GET", "/admin/errors", &errors); len(errors) != 1 || errors[0].Code != mcp.ErrorCodeMethodNotFound || errors[0].Client != "test" {
+		t.Errorf("/admin/errors = %+v", errors)
+	}
+	var tools []mcp.Tool
+	if get("GET", "/admin/tools", &tools); len(tools) == 0 || tools[len(tools)-1].Name != "slow" {
+		t.Errorf("/admin/tools = %+v", tools)
+	}
+	var level map[string]string
+	if status := get("PUT", "/admin/log-level?level=debug", &level); status != http.StatusOK || level["level"] != utils.LevelDebug || logger.Level() != utils.LevelDebug {
+		t.Errorf("PUT /admin/log-level = %d %v", status, level)
+	}
+	if status := get("PUT", "/admin/log-level?level=loud", nil); status != http.StatusBadRequest {
+		t.Errorf("PUT /admin/log-level with a bad level = %d", status)
+	}
+	var flags map[string]bool
+	if status := get("PUT", "/admin/features?name=sampling&enabled=off", &flags); status != http.StatusOK || flags[featureSampling] {
+		t.Errorf("PUT /admin/features = %d %v", status, flags)
+	}
+
+	resp, err := http.Get(api.URL + "/admin/sessions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("request without the token = %d", resp.StatusCode)
+	}
+
+	// Requests a web page could send through DNS rebinding or from another site
+	for _, tc := range []struct {
+		name, host, origin string
+		want               int
+	}{
+		{"rebound host", "attacker.example", "", http.StatusForbidden},
+		{"foreign origin", "", "http://evil.example", http.StatusForbidden},
+		{"localhost", "localhost", "", http.StatusOK},
+		{"same origin", "", api.URL, http.StatusOK},
+	} {
+		req, _ := http.NewRequest("GET", api.URL+"/admin/sessions", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		if tc.host != "" {
+			req.Host = tc.host
+		}
+		if tc.origin != "" {
+			req.Header.Set("Origin", tc.origin)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.want {
+			t.Errorf("%s: status = %d, want %d", tc.name, resp.StatusCode, tc.want)
+		}
+	}
+}
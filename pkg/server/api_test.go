@@ -0,0 +1,102 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"sqirvy/mcp/pkg/client"
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/server"
+	"sqirvy/mcp/pkg/transport/mem"
+	"sqirvy/mcp/pkg/utils"
+)
+
+// TestExtensionMethodAndHooks drives a server the way an embedding program
+// does, through the exported API only: an extension method registered with
+// HandleMethod, called by a client with CallRaw, with hooks given to NewServer.
+func TestExtensionMethodAndHooks(t *testing.T) {
+	var mu sync.Mutex
+	var events []string
+	record := func(event string) {
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	}
+	hooks := mcp.Hooks{
+		OnInitialize: func(ctx context.Context, params mcp.InitializeParams) *mcp.RPCError {
+			record("initialize")
+			return nil
+		},
+		OnRequest: func(ctx context.Context, method string, params json.RawMessage) *mcp.RPCError {
+			record("request " + method)
+			if method == "x-example/denied" {
+				return mcp.NewRPCError(mcp.ErrorCodeInvalidRequest, "denied by policy", nil)
+			}
+			return nil
+		},
+		OnResponse: func(ctx context.Context, method string, response []byte, d time.Duration) {
+			record("response " + method)
+		},
+		OnShutdown: func(ctx context.Context, reason string) {
+			record("shutdown")
+		},
+	}
+
+	clientSide, serverSide := mem.NewPair()
+	s := server.NewServer(serverSide, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo), hooks)
+	s.HandleMethod("x-example/echo", func(ctx context.Context, params json.RawMessage) (interface{}, *mcp.RPCError) {
+		return params, nil
+	})
+	s.HandleMethod("x-example/denied", func(ctx context.Context, params json.RawMessage) (interface{}, *mcp.RPCError) {
+		t.Error("handler ran for a request the hook refused")
+		return nil, nil
+	})
+	done := make(chan error, 1)
+	go func() { done <- s.Run() }()
+
+	c := client.NewClient(clientSide, log.New(io.Discard, "", 0))
+	result, err := c.Initialize()
+	if err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	if got := result.Capabilities.ExtensionMethods(); strings.Join(got, ",") != "x-example/denied,x-example/echo" {
+		t.Errorf("advertised methods = %q", got)
+	}
+
+	echoed, err := c.CallRaw(context.Background(), "x-example/echo", map[string]int{"n": 1})
+	if err != nil || string(echoed) != `{"n":1}` {
+		t.Errorf("CallRaw = %s, %v", echoed, err)
+	}
+	var rpcErr *mcp.RPCError
+	if _, err := c.CallRaw(context.Background(), "x-example/denied", nil); !errors.As(err, &rpcErr) || rpcErr.Message != "denied by policy" {
+		t.Errorf("CallRaw of refused method = %v, want the hook's error", err)
+	}
+
+	clientSide.Close()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after the client closed")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{
+		"initialize",
+		"request x-example/echo",
+		"response x-example/echo",
+		"request x-example/denied",
+		"response x-example/denied",
+		"shutdown",
+	}
+	if got := strings.Join(events, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("events:\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+	}
+}
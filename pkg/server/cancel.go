@@ -0,0 +1,107 @@
+package server
+
+import (
+	"context"
+	"sync"
+
+	"sqirvy/mcp/pkg/mcp"
+)
+
+// Request cancellation
+//
+// Every dispatched request runs with a context of its own, registered here by
+// request ID until its handler returns. A notifications/cancelled from the
+// client cancels it: tools backed by a program stop it (see
+// pkg/tools/command.go), a handler awaiting the client's reply to a
+// sampling or elicitation request gives up and cancels that request in turn
+// (see requestClient), and the response is dropped, as the client no longer
+// expects one. Handlers that ignore their context run to completion. When the session
+// breaks, every request is canceled, see Server.abandon.
+
+// cancelableRequests holds the contexts of the requests being handled.
+type cancelableRequests struct {
+	mu      sync.Mutex
+	running map[string]*cancelableRequest
+}
+
+// cancelableRequest is the context of one request being handled.
+type cancelableRequest struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	canceled bool // By the client, see cancel
+}
+
+// begin registers a cancellable context for the request id. The caller must
+// call end when the handler returns.
+func (r *cancelableRequests) begin(id mcp.RequestID) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.running == nil {
+		r.running = make(map[string]*cancelableRequest)
+	}
+	r.running[requestIDKey(id)] = &cancelableRequest{ctx: ctx, cancel: cancel}
+}
+
+// end releases the context of request id and reports whether the client
+// canceled the request.
+func (r *cancelableRequests) end(id mcp.RequestID) (canceled bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	req, ok := r.running[requestIDKey(id)]
+	if !ok {
+		return false
+	}
+	delete(r.running, requestIDKey(id))
+	req.cancel()
+	return req.canceled
+}
+
+// context returns the context of request id, or nil if it is not running.
+func (r *cancelableRequests) context(id mcp.RequestID) context.Context {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if req, ok := r.running[requestIDKey(id)]; ok {
+		return req.ctx
+	}
+	return nil
+}
+
+// cancel cancels the context of request id for the client, and reports whether
+// the request was still running.
+func (r *cancelableRequests) cancel(id mcp.RequestID) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	req, ok := r.running[requestIDKey(id)]
+	if !ok {
+		return false
+	}
+	req.canceled = true
+	req.cancel()
+	return true
+}
+
+// cancelAll cancels the context of every request still running, whose
+// responses can no longer be delivered.
+func (r *cancelableRequests) cancelAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, req := range r.running {
+		req.cancel()
+	}
+}
+
+// handleCancelled processes a notifications/cancelled from the client. Unknown
+// and finished requests are ignored: the notification may cross the response.
+func (s *Server) handleCancelled(payload []byte) {
+	params, err := mcp.UnmarshalCancelledParams(mcp.MessageParams(payload))
+	if err != nil {
+		s.logger.Printf("DEBUG", "Ignoring invalid '%s' notification: %v", mcp.MethodCancelled, err)
+		return
+	}
+	if !s.requests.cancel(params.RequestID) {
+		s.logger.Printf("DEBUG", "Ignoring '%s' for request %v: not running", mcp.MethodCancelled, params.RequestID)
+		return
+	}
+	s.logger.Printf("DEBUG", "Request (ID: %v) canceled by the client: %s", params.RequestID, params.Reason)
+}
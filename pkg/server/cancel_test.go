@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/transport/mem"
+	"sqirvy/mcp/pkg/utils"
+)
+
+func TestCancelledNotificationStopsHandler(t *testing.T) {
+	clientSide, serverSide := mem.NewPair()
+	s := NewServer(serverSide, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	stopped := make(chan error, 1)
+	s.HandleMethod("x-test/wait", func(ctx context.Context, _ json.RawMessage) (interface{}, *mcp.RPCError) {
+		<-ctx.Done()
+		stopped <- ctx.Err()
+		return map[string]string{}, nil
+	})
+	go s.Run()
+	defer clientSide.Close()
+
+	for _, msg := range []string{initializeRequest, initializedNotify, `{"jsonrpc":"2.0","id":5,"method":"x-test/wait"}`} {
+		if err := clientSide.WriteMessage([]byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	readResponse(t, clientSide, "1")
+
+	// Cancelling an unknown request does nothing
+	if err := clientSide.WriteMessage([]byte(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":99}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := clientSide.WriteMessage([]byte(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":5,"reason":"test"}}`)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-stopped:
+		if err != context.Canceled {
+			t.Errorf("handler context ended with %v, want canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the handler was not canceled")
+	}
+
+	// The canceled request is not answered; the session goes on
+	if err := clientSide.WriteMessage([]byte(`{"jsonrpc":"2.0","id":6,"method":"ping"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if m := readMessage(t, clientSide); fmt.Sprint(m.ID) != "6" {
+		t.Errorf("message after the cancellation = %+v, want the ping response", m)
+	}
+}
+
+func TestCancelDuringSummarizeCancelsSampling(t *testing.T) {
+	clientSide, serverSide := mem.NewPair()
+	s := NewServer(serverSide, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	s.modules = []*toolModule{{
+		name: "test",
+		resources: []moduleResource{{
+			resource: mcp.Resource{URI: "test://notes", MimeType: "text/plain"},
+			read:     func(context.Context) (string, error) { return "The launch moved to May.", nil },
+		}},
+	}}
+	go s.Run()
+	defer clientSide.Close()
+
+	for _, msg := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{"sampling":{}},"clientInfo":{"name":"test","version":"0"}}}`,
+		initializedNotify,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"summarize","arguments":{"uri":"test://notes"}}}`,
+	} {
+		if err := clientSide.WriteMessage([]byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	readResponse(t, clientSide, "1")
+	var sampling struct {
+		ID     string `json:"id"`
+		Method string `json:"method"`
+	}
+	if payload, err := clientSide.ReadMessage(); err != nil || json.Unmarshal(payload, &sampling) != nil || sampling.Method != mcp.MethodCreateMessage {
+		t.Fatalf("message during summarize = %s, %v; want a sampling request", payload, err)
+	}
+
+	// Cancelling the tool call cancels the sampling request it awaits
+	if err := clientSide.WriteMessage([]byte(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":3}}`)); err != nil {
+		t.Fatal(err)
+	}
+	var cancelled struct {
+		Method string              `json:"method"`
+		Params mcp.CancelledParams `json:"params"`
+	}
+	if payload, err := clientSide.ReadMessage(); err != nil || json.Unmarshal(payload, &cancelled) != nil ||
+		cancelled.Method != mcp.MethodCancelled || cancelled.Params.RequestID != sampling.ID {
+		t.Fatalf("message after the cancellation = %s, %v; want %s for %s", payload, err, mcp.MethodCancelled, sampling.ID)
+	}
+
+	// The tool call is not answered; the session goes on
+	if err := clientSide.WriteMessage([]byte(`{"jsonrpc":"2.0","id":4,"method":"ping"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if m := readMessage(t, clientSide); fmt.Sprint(m.ID) != "4" {
+		t.Errorf("message after the cancellation = %+v, want the ping response", m)
+	}
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"sqirvy/mcp/pkg/mcp"
+)
+
+// Server-initiated requests
+//
+// A handler may need something from the client while it runs, such as the
+// user's consent (elicitation/create). requestClient sends the request at once,
+// ahead of the responses waiting in the outbox (the handler's own response is
+// one of them), and blocks until the processing loop routes the client's reply
+// back by ID, the request times out, the handler's request is canceled, or the
+// session ends or breaks. When it times out or is canceled, the client is sent
+// notifications/cancelled for it, so that it can stop asking the user or model.
+
+// errClientGone is returned for requests still pending when the session ends.
+var errClientGone = errors.New("the client disconnected before replying")
+
+// errSessionBroken is returned once a write to the client has failed, see Server.Run.
+var errSessionBroken = errors.New("session broken")
+
+// clientReply is the client's answer to a server-initiated request.
+type clientReply struct {
+	result json.RawMessage
+	err    *mcp.RPCError
+}
+
+// clientRequests tracks the server-initiated requests awaiting a reply.
+type clientRequests struct {
+	mu      sync.Mutex
+	next    int
+	pending map[string]chan clientReply
+}
+
+// requestClient sends method to the client and decodes the result into result.
+// An error response from the client is returned as *mcp.RPCError.
+func (s *Server) requestClient(ctx context.Context, method string, params, result interface{}, timeout time.Duration) error {
+	s.clientRequests.mu.Lock()
+	s.clientRequests.next++
+	id := fmt.Sprintf("srv-%d", s.clientRequests.next)
+	reply := make(chan clientReply, 1)
+	if s.clientRequests.pending == nil {
+		s.clientRequests.pending = make(map[string]chan clientReply)
+	}
+	s.clientRequests.pending[id] = reply
+	s.clientRequests.mu.Unlock()
+	defer func() {
+		s.clientRequests.mu.Lock()
+		delete(s.clientRequests.pending, id)
+		s.clientRequests.mu.Unlock()
+	}()
+
+	payload, err := mcp.MarshalRequest(id, method, params)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s request: %w", method, err)
+	}
+	s.logger.Printf("DEBUG", "Sending %s request to the client (ID: %s)", method, id)
+	s.out.sendNow(payload)
+
+	timer := s.clock.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case r := <-reply:
+		if r.err != nil {
+			return r.err
+		}
+		if err := json.Unmarshal(r.result, result); err != nil {
+			return fmt.Errorf("invalid %s result from the client: %w", method, err)
+		}
+		return nil
+	case <-timer.C():
+		s.cancelClientRequest(id, method, "timed out")
+		return fmt.Errorf("the client did not answer %s within %v", method, timeout)
+	case <-ctx.Done():
+		s.cancelClientRequest(id, method, "request canceled")
+		return ctx.Err()
+	case <-s.shutdown:
+		return errClientGone
+	case <-s.out.failed:
+		return errSessionBroken
+	}
+}
+
+// cancelClientRequest tells the client that the server no longer awaits the
+// reply to request id.
+func (s *Server) cancelClientRequest(id, method, reason string) {
+	payload, err := mcp.MarshalNotification(mcp.MethodCancelled, mcp.CancelledParams{RequestID: id, Reason: reason})
+	if err != nil {
+		s.logger.Printf("DEBUG", "Failed to marshal cancellation of %s: %v", id, err)
+		return
+	}
+	s.logger.Printf("DEBUG", "Cancelling %s request to the client (ID: %s): %s", method, id, reason)
+	s.out.sendNow(payload)
+}
+
+// handleClientReply routes a response or error response from the client to the
+// request waiting for it. It reports false for IDs the server never used.
+func (s *Server) handleClientReply(id mcp.RequestID, payload []byte) bool {
+	key, ok := id.(string)
+	if !ok {
+		return false
+	}
+	s.clientRequests.mu.Lock()
+	reply, ok := s.clientRequests.pending[key]
+	s.clientRequests.mu.Unlock()
+	if !ok {
+		return false
+	}
+	var resp mcp.RPCResponse
+	if err := json.Unmarshal(payload, &resp); err != nil {
+		resp.Error = mcp.NewRPCError(mcp.ErrorCodeParseError, err.Error(), nil)
+	}
+	select {
+	case reply <- clientReply{result: resp.Result, err: resp.Error}:
+	default: // Duplicate reply
+	}
+	return true
+}
+
+// elicit asks the client to collect information from the user. It fails if the
+// client did not announce the elicitation capability.
+func (s *Server) elicit(ctx context.Context, params mcp.ElicitRequestParams, timeout time.Duration) (mcp.ElicitResult, error) {
+	var result mcp.ElicitResult
+	if s.clientCapabilities.Elicitation == nil {
+		return result, errors.New("the client does not support elicitation")
+	}
+	if params.RequestedSchema == nil {
+		params.RequestedSchema = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+	}
+	err := s.requestClient(ctx, mcp.MethodCreateElicitation, params, &result, timeout)
+	return result, err
+}
+
+// createMessage asks the client to sample an LLM completion. It fails if the
+// client did not announce the sampling capability.
+func (s *Server) createMessage(ctx context.Context, params mcp.CreateMessageParams, timeout time.Duration) (mcp.CreateMessageResult, error) {
+	var result mcp.CreateMessageResult
+	if s.clientCapabilities.Sampling == nil {
+		return result, errors.New("the client does not support sampling")
+	}
+	if !s.features.enabled(featureSampling) {
+		return result, errors.New("sampling is disabled on this server")
+	}
+	err := s.requestClient(ctx, mcp.MethodCreateMessage, params, &result, timeout)
+	return result, err
+}
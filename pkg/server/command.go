@@ -0,0 +1,639 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath" // Added for path manipulation
+	"strings"
+	"syscall"
+	"time"
+
+	// Use the absolute module path
+	"sqirvy/mcp/pkg/clock"
+	"sqirvy/mcp/pkg/journal"
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/transport"
+	"sqirvy/mcp/pkg/utils" // Import the custom logger
+)
+
+// Main runs the mcp-server command with the process's arguments and flags.
+// It exits the process on errors, and returns once the server has stopped.
+func Main() {
+	// --- Command Line Flags ---
+	logFilePath := flag.String("log", "mcp-server.log", "Path to the log file")
+	logPayloadMax := flag.Int("log-payload-max", defaultPayloadLogMax, "Log at most this many bytes of each received and sent payload (0 = whole payloads)")
+	logPayloadSample := flag.Uint64("log-payload-sample", 1, "Log only one in this many received and sent payloads, to keep logging cheap at high message rates")
+	debugMode := flag.Bool("debug", false, "Enable debug logging and debug-only methods (server/info)")
+	legacyInit := flag.Bool("legacy-initialized", false, "Also accept the pre-spec \"initialized\" notification name from older clients")
+	notifyWindow := flag.Duration("notify-window", defaultNotifyWindow, "Coalesce resource/list change notifications raised within this window (0 disables)")
+	toolLimitSpec := flag.String("tool-limits", "", "Per-tool concurrency limits, e.g. ping=1,fetch=4 (0 = unlimited; merged over the defaults)")
+	idempotencyWindow := flag.Duration("idempotency-window", defaultIdempotencyWindow, "How long a tools/call result is replayed for a repeated _meta.idempotencyKey (0 disables)")
+	toolCacheSpec := flag.String("tool-cache", "", "Result cache TTLs for pure tools, e.g. summarize=10m (0 = not cached; merged over the defaults)")
+	toolCacheEntries := flag.Int("tool-cache-entries", defaultToolCacheEntries, "Results kept by the tool result cache (0 disables)")
+	toolArgMode := flag.String("tool-args", string(argsLenient), "How tool arguments are checked against inputSchema: lenient (fill in defaults and coerce compatible types), strict (defaults only) or off")
+	sanitizeSpec := flag.String("sanitize", "", "Sanitize tool results and resource text per tool name or URI pattern, e.g. *=secrets,fetch=secrets+html+max:4000,file://*=max:100000")
+	toolQueueTimeout := flag.Duration("tool-queue-timeout", defaultToolQueueTimeout, "How long a tool call waits for a free slot before failing")
+	featuresFile := flag.String("features", "", "JSON file of feature flags, e.g. {\"sampling\": false}; MCP_FEATURE_<NAME>=on|off overrides it")
+	featureAdmin := flag.Bool("feature-admin", false, "Enable the feature_flags tool, which switches feature flags at runtime for every session")
+	adminAddr := flag.String("admin", "", "Serve the admin API (sessions, in-flight requests, tools, recent errors, log level) on this loopback address, e.g. localhost:9090")
+	adminTokenFile := flag.String("admin-token-file", "", "Require the bearer token in this file for admin API requests")
+	iconsFile := flag.String("icons", "", "JSON file of icons (https URLs, or local images embedded at startup) for the server, tools and prompts")
+	tenantsMode := flag.String("tenants", "", "Give each client of -listen and -mcp-http its own file root, memory notes and cached results, by principal (-mcp-http bearer token) or client (name sent in initialize); not with -index, -journal-admin or -http")
+	profilesFile := flag.String("profiles", "", "JSON file of capability profiles that limit the tools, resources, prompts and quotas of sessions by transport or -mcp-http and -http bearer token")
+	localesFile := flag.String("locales", "", "JSON file of translated instructions and tool/prompt descriptions, chosen by the client's locale hint")
+	listenAddr := flag.String("listen", "", "Serve clients on a socket instead of stdio, e.g. unix:/tmp/mcp.sock or tcp:localhost:9000")
+	mcpHTTPAddr := flag.String("mcp-http", "", "Serve MCP sessions over Streamable HTTP at /mcp on this address instead of stdio, e.g. localhost:8081")
+	sessionTTL := flag.Duration("session-ttl", defaultSessionTTL, "How long an -mcp-http session that stopped running, e.g. on -idle-timeout, can be resumed with its Mcp-Session-Id (0 disables)")
+	sessionStoreFile := flag.String("session-store", "", "Keep resumable -mcp-http sessions in this JSON file, so that they survive a restart")
+	transportName := flag.String("transport", "stdio", "Transport of MCP sessions: stdio; http to serve Streamable HTTP (POST for client messages, an SSE event stream for server messages) at /mcp on -addr; tcp to serve newline-delimited JSON on -addr as a long-lived daemon, like -listen tcp:<addr>; or grpc to serve the MCPTransport gRPC service (pkg/transport/proto/transport.proto) on -addr")
+	addr := flag.String("addr", "localhost:8080", "Address of -transport=http, tcp or grpc, e.g. :8080 for every interface")
+	allowOrigins := flag.String("allow-origins", "", "Comma-separated browser origins allowed to use MCP over HTTP (CORS), e.g. https://app.example.com (\"*\" for any)")
+	httpAddr := flag.String("http", "", "Also serve tools, resources and prompts over an HTTP gateway (OpenAI function calling and REST) on this address, e.g. localhost:8080")
+	maxLifetime := flag.Duration("max-session-lifetime", 0, "Drain a session after it has been open this long (0 = no limit)")
+	idleTimeout := flag.Duration("idle-timeout", 0, "Drain a session after this long without a message from the client (0 = no limit)")
+	initTimeout := flag.Duration("init-timeout", 0, "End a session, and over stdio exit with status 0, if the client has not completed the initialize handshake after this long (0 = wait forever)")
+	hotPathInterval := flag.Duration("hotpath-interval", 0, "Log the request queue depth, oldest pending request and p95 handler latency this often (0 = off)")
+	hotPathMaxDepth := flag.Int("hotpath-max-depth", 0, "Log the hot path summary as soon as more than this many requests are pending (0 = no threshold)")
+	hotPathMaxAge := flag.Duration("hotpath-max-age", 0, "Log the hot path summary as soon as a request has been pending this long (0 = no threshold)")
+	sessionMaxCalls := flag.Int64("session-max-calls", 0, "Refuse requests once a session has made this many calls (ping excluded; 0 = no limit)")
+	sessionMaxBytes := flag.Int64("session-max-bytes", 0, "Refuse requests once a session has transferred this many bytes of requests and responses (0 = no limit)")
+	journalFile := flag.String("journal", "", "Record every handled request in this SQLite database (needs the sqlite3 shell)")
+	journalBodies := flag.Bool("journal-bodies", false, "Also journal request and response payloads, which may contain tool arguments and results")
+	journalAdmin := flag.Bool("journal-admin", false, "Enable the query_journal tool and the journal://recent resource over the -journal database")
+	sealKeyFile := flag.String("seal-key-file", "", "Encrypt the -log file, -journal payloads, -memory-file and -session-store at rest with the AES-256 key (hex or base64) in this file; $"+sealKeyEnv+" overrides it. Read the log with mcp-server unseal")
+	hmacSecretFile := flag.String("hmac-secret-file", "", "Sign and verify every -listen message with HMAC-SHA256 using the shared secret in this file")
+	enableK8s := flag.Bool("k8s", false, "Enable the read-only Kubernetes tools (k8s_get, k8s_describe, k8s_logs), which run kubectl")
+	kubeconfig := flag.String("kubeconfig", "", "Kubeconfig file for the Kubernetes tools (default: kubectl's own)")
+	kubeContext := flag.String("k8s-context", "", "Kubeconfig context for the Kubernetes tools (default: the current context)")
+	enableDocker := flag.Bool("docker", false, "Enable the read-only Docker tools (docker_ps, docker_inspect, docker_logs)")
+	dockerHost := flag.String("docker-host", defaultDockerHost(), "Docker Engine API address for the Docker tools, unix:// or tcp://")
+	dockerAllow := flag.String("docker-allow", "", "Comma-separated container name patterns the Docker tools may see, e.g. web-*,db (\"*\" for all)")
+	promURL := flag.String("prometheus-url", "", "Enable the promql_query tool against this Prometheus server, e.g. http://localhost:9090")
+	promToken := flag.String("prometheus-token-file", "", "File holding a bearer token sent to Prometheus")
+	webhookURL := flag.String("webhook-url", "", "Enable the notify tool, which posts messages to this Slack, Discord or generic JSON webhook")
+	webhookFmt := flag.String("webhook-format", "auto", "Payload format of -webhook-url: slack, discord, generic, or auto to detect it from the URL")
+	webhookRate := flag.Int("webhook-rate", defaultWebhookRate, "Most messages the notify tool sends per minute")
+	enableDesktop := flag.Bool("desktop", false, "Enable the clipboard_read, clipboard_write and screenshot tools for a local desktop assistant; each call asks the user for consent")
+	enableIndex := flag.Bool("index", false, "Enable the semantic_search tool over an embeddings index of the text files under the project root")
+	indexFile := flag.String("index-file", "mcp-index.gob", "File the semantic_search index is kept in between runs")
+	embeddings := flag.String("embeddings", "hash", "Embeddings provider of the index: hash (offline, lexical) or openai:<model>")
+	embedURL := flag.String("embeddings-url", "", "Base URL of an OpenAI-compatible embeddings API (default https://api.openai.com/v1)")
+	embedKey := flag.String("embeddings-key-file", "", "File holding the API key of the embeddings provider")
+	memoryFile := flag.String("memory-file", "", "Enable the memory_store and memory_search tools, keeping each client's notes in this file")
+	memoryEmbed := flag.Bool("memory-embeddings", false, "Search notes with the -embeddings provider instead of by keyword")
+	// new-tool inserts flags above this line
+	stdioTeeDir := flag.String("stdio-debug-tee", "", "Copy the raw bytes read from stdin and written to stdout to in.raw and out.raw in this directory, for debugging framing problems with a host")
+	framingName := flag.String("framing", "auto", "Message framing over stdio: auto to adopt the host's, newline, length, or content-length for LSP-style Content-Length headers")
+	maxMessageSize := flag.Int("max-message-size", transport.DefaultMaxLineSize, "Largest message in bytes read from the client over stdio, -listen, -mcp-http or gRPC; larger ones are answered with a parse error, over HTTP with 413, and end a gRPC stream with RESOURCE_EXHAUSTED")
+	chaosSpec := flag.String("chaos", "", "Inject transport faults for testing, e.g. latency=20ms,jitter=10ms,dup=0.05,reorder=0.2,disconnect=0.001,seed=1")
+	emptyParamsName := flag.String("empty-params", "default", "How to send notifications and requests without params, for picky hosts: omit the member, or object for \"params\":{}")
+	showVersion := flag.Bool("version", false, "Print version information and exit")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [doctor] [flags]\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s new-tool [-resource] <name>\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s unseal [-seal-key-file file] <log>\n\n", filepath.Base(os.Args[0]))
+		fmt.Fprintln(flag.CommandLine.Output(), "The doctor subcommand checks the configuration and environment and exits.")
+		fmt.Fprintln(flag.CommandLine.Output(), "The new-tool subcommand scaffolds a tool module in the server's source directory.")
+		fmt.Fprintln(flag.CommandLine.Output(), "The unseal subcommand prints a log written with -seal-key-file.")
+		flag.PrintDefaults()
+	}
+
+	// "mcp-server new-tool <name>" generates code and has flags of its own
+	if len(os.Args) > 1 && os.Args[1] == "new-tool" {
+		os.Exit(runNewTool(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "unseal" {
+		os.Exit(runUnseal(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
+	// "mcp-server doctor [flags]" validates the same flags instead of serving
+	args := os.Args[1:]
+	doctor := len(args) > 0 && args[0] == "doctor"
+	if doctor {
+		args = args[1:]
+	}
+	flag.CommandLine.Parse(args)
+
+	if *showVersion {
+		info := readBuildInfo()
+		fmt.Printf("mcp-server %s (go %s, %s/%s)\n", serverVersionString(info), info.GoVersion, info.GOOS, info.GOARCH)
+		if info.BuildDate != "" {
+			fmt.Printf("built %s\n", info.BuildDate)
+		}
+		return
+	}
+
+	// With a key, everything persisted is sealed, see seal.go
+	sealKey, err := utils.LoadSealKey(sealKeyEnv, *sealKeyFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid -seal-key-file: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Optional tool modules, shared by every session
+	modules, modulesErr := buildModules(moduleConfig{
+		k8s:         *enableK8s,
+		kubeconfig:  *kubeconfig,
+		kubeContext: *kubeContext,
+		docker:      *enableDocker,
+		dockerHost:  *dockerHost,
+		dockerAllow: *dockerAllow,
+		promURL:     *promURL,
+		promToken:   *promToken,
+		webhookURL:  *webhookURL,
+		webhookFmt:  *webhookFmt,
+		webhookRate: *webhookRate,
+		desktop:     *enableDesktop,
+		index:       *enableIndex,
+		indexFile:   *indexFile,
+		embeddings:  *embeddings,
+		embedURL:    *embedURL,
+		embedKey:    *embedKey,
+		memoryFile:  *memoryFile,
+		memoryEmbed: *memoryEmbed,
+		sealKey:     sealKey,
+		// new-tool inserts settings above this line
+	})
+
+	if doctor {
+		os.Exit(runDoctor(doctorConfig{
+			logFile:   *logFilePath,
+			toolLimit: *toolLimitSpec,
+			chaos:     *chaosSpec,
+			listen:    *listenAddr,
+			secret:    *hmacSecretFile,
+			modules:   modules,
+			moduleErr: modulesErr,
+		}, os.Stdout))
+	}
+
+	// --- Logger Setup ---
+	// Ensure the directory for the log file exists
+	logDir := filepath.Dir(*logFilePath)
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating log directory %s: %v\n", logDir, err)
+		os.Exit(1)
+	}
+
+	logFile, err := os.OpenFile(*logFilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening log file %s: %v\n", *logFilePath, err)
+		os.Exit(1)
+	}
+	defer logFile.Close()
+
+	var logWriter io.Writer = logFile
+	if logSealer, err := newSealer(sealKey, sealScopeLog); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid -seal-key-file: %v\n", err)
+		os.Exit(1)
+	} else if logSealer != nil {
+		logWriter = utils.NewSealedWriter(logFile, logSealer)
+	}
+
+	// Initialize the custom logger, DEBUG level only when requested
+	logLevel := utils.LevelInfo
+	if *debugMode {
+		logLevel = utils.LevelDebug
+	}
+	logger := utils.New(logWriter, "", log.LstdFlags|log.Lshortfile, logLevel)
+	logger.Println("DEBUG", "--------------------------------------------------") // Use INFO for separators
+	logger.Println("DEBUG", "MCP Server starting...")                             // Use INFO for startup message
+	logger.Printf("DEBUG", "Logging to file: %s", *logFilePath)
+	if sealKey != nil {
+		logger.Println("DEBUG", "Encryption at rest is on")
+	}
+	logger.Printf("DEBUG", "Version: %s", serverVersionString(readBuildInfo()))
+
+	// --- Server Initialization ---
+	var chaosConfig *transport.ChaosConfig
+	if *chaosSpec != "" {
+		cfg, err := transport.ParseChaosConfig(*chaosSpec)
+		if err != nil {
+			logger.Fatalf("DEBUG", "Invalid -chaos value: %v", err)
+		}
+		logger.Printf("DEBUG", "Chaos transport enabled: %+v", cfg)
+		chaosConfig = &cfg
+	}
+	if modulesErr != nil {
+		logger.Fatalf("DEBUG", "Invalid tool module configuration: %v", modulesErr)
+	}
+	features, err := loadFeatureFlags(*featuresFile, os.Environ())
+	if err != nil {
+		logger.Fatalf("DEBUG", "Invalid feature flags: %v", err)
+	}
+	if *featureAdmin {
+		modules = append(modules, featureAdminModule(features))
+	}
+	var requestJournal *journal.Journal
+	if *journalFile != "" {
+		sealer, err := newSealer(sealKey, sealScopeJournal)
+		if err != nil {
+			logger.Fatalf("DEBUG", "Invalid -seal-key-file: %v", err)
+		}
+		requestJournal, err = journal.Open(*journalFile, journal.Options{
+			Bodies:  *journalBodies,
+			OnError: func(err error) { logger.Printf("INFO", "WARNING: %v", err) },
+			Sealer:  sealer,
+		})
+		if err != nil {
+			logger.Fatalf("DEBUG", "Invalid -journal value: %v", err)
+		}
+		defer requestJournal.Close()
+		logger.Printf("DEBUG", "Journaling requests to %s", *journalFile)
+		if *journalAdmin {
+			modules = append(modules, journalModule(requestJournal))
+		}
+	} else if *journalAdmin {
+		logger.Fatalf("DEBUG", "-journal-admin needs -journal")
+	}
+	toolLimits, err := parseToolLimits(*toolLimitSpec)
+	if err != nil {
+		logger.Fatalf("DEBUG", "Invalid -tool-limits value: %v", err)
+	}
+	for _, defaults := range []map[string]int{defaultToolLimits, moduleToolLimits(modules)} {
+		for name, n := range defaults {
+			if _, ok := toolLimits[name]; !ok {
+				toolLimits[name] = n
+			}
+		}
+	}
+	var locales localeCatalog
+	if *localesFile != "" {
+		if locales, err = loadLocaleCatalog(*localesFile); err != nil {
+			logger.Fatalf("DEBUG", "Invalid -locales value: %v", err)
+		}
+	}
+	var profiles *profileSet
+	if *profilesFile != "" {
+		if profiles, err = loadProfiles(*profilesFile); err != nil {
+			logger.Fatalf("DEBUG", "Invalid -profiles value: %v", err)
+		}
+	}
+	var icons *iconSet
+	if *iconsFile != "" {
+		if icons, err = loadIcons(*iconsFile); err != nil {
+			logger.Fatalf("DEBUG", "Invalid -icons value: %v", err)
+		}
+	}
+	emptyParams, err := mcp.ParseEmptyParams(*emptyParamsName)
+	if err != nil {
+		logger.Fatalf("DEBUG", "Invalid -empty-params value: %v", err)
+	}
+	tenants, err := parseTenancy(*tenantsMode)
+	if err != nil {
+		logger.Fatalf("DEBUG", "Invalid -tenants value: %v", err)
+	}
+	if err := tenants.check(*enableIndex, *journalAdmin, *httpAddr != ""); err != nil {
+		logger.Fatalf("DEBUG", "Invalid -tenants value: %v", err)
+	}
+	argMode, err := parseArgumentMode(*toolArgMode)
+	if err != nil {
+		logger.Fatalf("DEBUG", "Invalid -tool-args value: %v", err)
+	}
+	toolCacheTTLs, err := parseToolCacheTTLs(*toolCacheSpec)
+	if err != nil {
+		logger.Fatalf("DEBUG", "Invalid -tool-cache value: %v", err)
+	}
+	for _, defaults := range []map[string]time.Duration{defaultToolCacheTTLs, moduleToolCacheTTLs(modules)} {
+		for name, ttl := range defaults {
+			if _, ok := toolCacheTTLs[name]; !ok {
+				toolCacheTTLs[name] = ttl
+			}
+		}
+	}
+	sanitizers, err := parseSanitizePolicy(*sanitizeSpec)
+	if err != nil {
+		logger.Fatalf("DEBUG", "Invalid -sanitize value: %v", err)
+	}
+	if len(sanitizers.rules) > 0 {
+		logger.Printf("DEBUG", "Sanitizing results for %s", sanitizers)
+	}
+	// Tool limits are process-wide, so they hold across socket sessions too
+	limiter := newToolLimiter(toolLimits, *toolQueueTimeout)
+	idempotency := newIdempotencyCache(*idempotencyWindow)
+	results := newResultCache(toolCacheTTLs, *toolCacheEntries)
+
+	// -transport=http and tcp are -mcp-http and -listen by other names, for hosts that configure servers that way
+	var grpcAddr string
+	switch *transportName {
+	case "stdio":
+	case "http":
+		if *mcpHTTPAddr == "" {
+			*mcpHTTPAddr = *addr
+		}
+	case "tcp":
+		if *listenAddr == "" {
+			*listenAddr = "tcp:" + *addr
+		}
+	case "grpc":
+		grpcAddr = *addr
+	default:
+		logger.Fatalf("DEBUG", "Invalid -transport value: %q (want stdio, http, tcp or grpc)", *transportName)
+	}
+
+	if *maxMessageSize <= 0 {
+		logger.Fatalf("DEBUG", "Invalid -max-message-size value: %d", *maxMessageSize)
+	}
+
+	var registry *adminRegistry
+	if *adminAddr != "" {
+		registry = newAdminRegistry()
+	}
+
+	payloads := newPayloadLog(*logPayloadMax, *logPayloadSample)
+
+	// The hot path monitor watches the requests of every session, see hotpath.go
+	var hotpath *hotPathMonitor
+	if *hotPathInterval > 0 || *hotPathMaxDepth > 0 || *hotPathMaxAge > 0 {
+		hotpath = newHotPathMonitor(clock.Real, logger, *hotPathInterval, *hotPathMaxDepth, *hotPathMaxAge)
+		defer hotpath.start()()
+	}
+
+	// newSession creates a configured server for one client connection
+	newSession := func(t transport.Transport) *Server {
+		if chaosConfig != nil {
+			t = transport.NewChaos(t, *chaosConfig)
+		}
+		if emptyParams != mcp.EmptyParamsDefault {
+			t = transport.NewEmptyParamsWriter(t, emptyParams)
+		}
+		server := NewServer(t, logger)
+		server.debug = *debugMode
+		server.payloads = payloads
+		server.legacyInit = *legacyInit
+		server.notifications.window = *notifyWindow
+		server.toolLimits = limiter
+		server.idempotency = idempotency
+		server.results = results
+		server.argMode = argMode
+		server.sanitizers = sanitizers
+		server.features = features
+		server.featureAdmin = *featureAdmin
+		server.registry = registry
+		server.hotpath = hotpath
+		if locales != nil {
+			server.locales = locales
+			server.RegisterExperimental(mcp.ExperimentalLocale, server.negotiateLocale)
+		}
+		if icons != nil {
+			server.icons = icons
+			server.serverInfo.Icons = icons.server
+		}
+		server.modules = modules
+		server.policy = sessionPolicy{maxLifetime: *maxLifetime, idleTimeout: *idleTimeout, initTimeout: *initTimeout}
+		server.quota = sessionQuota{maxCalls: *sessionMaxCalls, maxBytes: *sessionMaxBytes}
+		if requestJournal != nil {
+			server.journal = requestJournal
+			server.journalSession = requestJournal.NewSession()
+		}
+		return server
+	}
+	// profiled creates sessions restricted to the profile of a transport
+	profiled := func(transportName string) func(transport.Transport) *Server {
+		return func(t transport.Transport) *Server {
+			server := newSession(t)
+			server.applyProfile(profiles.choose(transportName, ""))
+			return server
+		}
+	}
+
+	if registry != nil {
+		var token string
+		if *adminTokenFile != "" {
+			data, err := os.ReadFile(*adminTokenFile)
+			if err != nil {
+				logger.Fatalf("DEBUG", "Invalid -admin-token-file: %v", err)
+			}
+			token = strings.TrimSpace(string(data))
+		}
+		if err := checkLoopback(*adminAddr); err != nil {
+			logger.Fatalf("DEBUG", "Invalid -admin value: %v", err)
+		}
+		go func() {
+			logger.Printf("DEBUG", "Admin API stopped: %v", serveAdmin(*adminAddr, token, registry, newSession, logger))
+		}()
+	}
+
+	switch {
+	case grpcAddr != "":
+		// MCP over gRPC, one session per Connect stream, see grpc.go
+		grpcSession := func(t transport.Transport) *Server {
+			server := profiled(profileTransportGRPC)(t)
+			server.setTenancy(tenants, "") // gRPC clients have no principal
+			return server
+		}
+		if *httpAddr != "" {
+			go func() {
+				logger.Printf("DEBUG", "HTTP gateway stopped: %v", serveGateway(*httpAddr, newSession, profiles, logger))
+			}()
+		}
+		// SIGTERM or an interrupt drains the sessions; a second one exits immediately.
+		stop := make(chan struct{})
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+		go func() {
+			sig := <-signals
+			signal.Reset(syscall.SIGTERM, os.Interrupt)
+			logger.Printf("DEBUG", "Received %v, draining sessions", sig)
+			close(stop)
+		}()
+		err = serveGRPC(grpcAddr, grpcSession, *maxMessageSize, stop, logger)
+	case *listenAddr != "":
+		// Profiles name the socket transports unix and tcp; tcp4 and tcp6 count as tcp
+		socketTransport := profileTransportTCP
+		if network, _, _ := transport.ParseAddress(*listenAddr); network == "unix" {
+			socketTransport = profileTransportUnix
+		}
+		socketSession := profiled(socketTransport)
+		// Socket sessions are signed when a shared secret is configured; stdio and the
+		// HTTP gateway are local or have their own transport security and are not signed.
+		if *hmacSecretFile != "" {
+			secret, err := transport.ReadSecretFile(*hmacSecretFile)
+			if err != nil {
+				logger.Fatalf("DEBUG", "Invalid -hmac-secret-file: %v", err)
+			}
+			logger.Printf("DEBUG", "Signing socket messages with the secret in %s", *hmacSecretFile)
+			nonces := transport.NewNonceStore() // One for all connections, so frames cannot be replayed across them
+			socketSession = func(t transport.Transport) *Server {
+				signed := transport.NewSigned(t, secret, transport.SignedServer)
+				signed.Nonces = nonces
+				return profiled(socketTransport)(signed)
+			}
+		}
+		sessionFor := socketSession
+		socketSession = func(t transport.Transport) *Server {
+			setMaxMessageSize(t, *maxMessageSize)
+			server := sessionFor(t)
+			server.setTenancy(tenants, "") // Socket clients have no principal
+			return server
+		}
+		if *httpAddr != "" {
+			go func() {
+				logger.Printf("DEBUG", "HTTP gateway stopped: %v", serveGateway(*httpAddr, newSession, profiles, logger))
+			}()
+		}
+		// Serve clients connecting over a socket, one session per connection.
+		// SIGTERM or an interrupt drains the sessions; a second one exits immediately.
+		stop := make(chan struct{})
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+		go func() {
+			sig := <-signals
+			signal.Reset(syscall.SIGTERM, os.Interrupt)
+			logger.Printf("DEBUG", "Received %v, draining sessions", sig)
+			close(stop)
+		}()
+		err = serveSocket(*listenAddr, socketSession, stop, logger)
+	case *mcpHTTPAddr != "":
+		// MCP over HTTP, mounted the way an application embedding the server would, see embed.go
+		if *httpAddr != "" {
+			go func() {
+				logger.Printf("DEBUG", "HTTP gateway stopped: %v", serveGateway(*httpAddr, newSession, profiles, logger))
+			}()
+		}
+		mux := http.NewServeMux()
+		endpoint := NewEndpoint(newSession, logger)
+		endpoint.profiles = profiles
+		endpoint.tenancy = tenants
+		endpoint.maxMessage = *maxMessageSize
+		if *sessionTTL > 0 {
+			if endpoint.store, err = openSessionStore(*sessionStoreFile, *sessionTTL, sealKey); err != nil {
+				logger.Fatalf("DEBUG", "Invalid -session-store value: %v", err)
+			}
+		} else if *sessionStoreFile != "" {
+			logger.Fatalf("DEBUG", "Invalid -session-store value: sessions are not resumable with -session-ttl 0")
+		}
+		if *allowOrigins != "" {
+			for _, origin := range strings.Split(*allowOrigins, ",") {
+				endpoint.origins = append(endpoint.origins, strings.TrimSpace(origin))
+			}
+		}
+		mux.Handle("/mcp", endpoint)
+		logger.Printf("DEBUG", "Serving MCP over HTTP on %s/mcp", *mcpHTTPAddr)
+		// SIGTERM or an interrupt drains the sessions, which are saved for
+		// resumption, then stops serving; a second one exits immediately.
+		srv := &http.Server{Addr: *mcpHTTPAddr, Handler: mux}
+		stopped := make(chan struct{})
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+		go func() {
+			defer close(stopped)
+			sig := <-signals
+			signal.Reset(syscall.SIGTERM, os.Interrupt)
+			logger.Printf("DEBUG", "Received %v, draining sessions", sig)
+			endpoint.Shutdown(context.Background(), "server shutting down")
+			srv.Shutdown(context.Background())
+		}()
+		if err = srv.ListenAndServe(); errors.Is(err, http.ErrServerClosed) {
+			<-stopped
+			logger.Println("DEBUG", "All sessions drained")
+			err = nil
+		}
+	case *httpAddr != "":
+		// Only the HTTP gateway, for hosts that do not speak MCP
+		err = serveGateway(*httpAddr, newSession, profiles, logger)
+	default:
+		// Use standard input and output. Only the transport may write to the real stdout;
+		// anything else printed is logged instead, see stdout.go.
+		// A host that closes our stdout must show up as a write error, which ends the
+		// session cleanly, rather than kill the process with SIGPIPE. Notify, unlike
+		// Ignore, is not inherited by the commands that tools run.
+		signal.Notify(make(chan os.Signal, 1), syscall.SIGPIPE)
+		guard, guardErr := guardStdout(logger)
+		if guardErr != nil {
+			logger.Fatalf("DEBUG", "Failed to guard stdout: %v", guardErr)
+		}
+		framing, framingErr := transport.ParseFraming(*framingName)
+		if framingErr != nil {
+			logger.Fatalf("DEBUG", "Invalid -framing value: %v", framingErr)
+		}
+		stream, framingErr := transport.NewFramed(os.Stdin, guard.protocol, framing)
+		if framingErr != nil {
+			logger.Fatalf("DEBUG", "Invalid -framing value: %v", framingErr)
+		}
+		setMaxMessageSize(stream, *maxMessageSize)
+		if auto, ok := stream.(*transport.AutoFramed); ok {
+			auto.Detected = func(f transport.Framing) {
+				logger.Printf("DEBUG", "Detected %s framing on stdin", f)
+			}
+		}
+		var wire transport.Transport = stream
+		var tee *transport.Tee
+		if *stdioTeeDir != "" {
+			in, out, err := openTeeFiles(*stdioTeeDir)
+			if err != nil {
+				logger.Fatalf("DEBUG", "Invalid -stdio-debug-tee value: %v", err)
+			}
+			defer in.Close()
+			defer out.Close()
+			tee = transport.NewTee(stream, in, out)
+			wire = tee
+		}
+		stdio := transport.NewGuard(wire, func(payload []byte, err error) {
+			logger.Printf("INFO", "WARNING: refusing to write a non-protocol message to stdout: %v: %.200q", err, payload)
+		})
+		err = profiled(profileTransportStdio)(stdio).Run()
+		if strays := guard.release(); strays > 0 {
+			logger.Printf("INFO", "WARNING: %d line(s) of stray stdout output were intercepted", strays)
+		}
+		if tee != nil && tee.Err() != nil {
+			logger.Printf("INFO", "WARNING: -stdio-debug-tee copies are incomplete: %v", tee.Err())
+		}
+	}
+
+	// --- Shutdown ---
+	if err != nil {
+		// Use Fatalf which always logs and exits
+		logger.Fatalf("DEBUG", "Server exited with error: %v", err)
+		// fmt.Fprintf(os.Stderr, "Server exited with error: %v\n", err) // Fatalf logs and exits
+		// logger.Println("DEBUG", "--------------------------------------------------") // Not reached after Fatalf
+		// os.Exit(1) // Not needed, Fatalf exits
+	}
+
+	logger.Println("DEBUG", "Server exited normally.")
+	logger.Println("DEBUG", "--------------------------------------------------")
+}
+
+// Helper function to create a standard MethodNotFound error response
+func createMethodNotFoundResponse(id mcp.RequestID, method string) []byte {
+	rpcErr := mcp.NewRPCError(mcp.ErrorCodeMethodNotFound, fmt.Sprintf("Method '%s' not found", method), nil)
+	return mcp.MustErrorResponse(id, rpcErr)
+}
+
+// openTeeFiles creates in.raw and out.raw in dir for -stdio-debug-tee,
+// replacing the copies of an earlier run.
+func openTeeFiles(dir string) (in, out *os.File, err error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, nil, err
+	}
+	if in, err = os.Create(filepath.Join(dir, "in.raw")); err != nil {
+		return nil, nil, err
+	}
+	if out, err = os.Create(filepath.Join(dir, "out.raw")); err != nil {
+		in.Close()
+		return nil, nil, err
+	}
+	return in, out, nil
+}
+
+// setMaxMessageSize applies the -max-message-size limit to a stdio or socket
+// transport of any framing.
+func setMaxMessageSize(t transport.Transport, n int) {
+	switch t := t.(type) {
+	case *transport.Stream:
+		t.MaxLineSize = n
+	case *transport.LengthPrefixed:
+		t.MaxFrameSize = n
+	case *transport.ContentLength:
+		t.MaxFrameSize = n
+	case *transport.AutoFramed:
+		t.MaxMessageSize = n
+	}
+}
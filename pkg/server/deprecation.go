@@ -0,0 +1,143 @@
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"sqirvy/mcp/pkg/mcp"
+)
+
+// deprecations holds the tools and resources deprecated with DeprecateTool and
+// DeprecateResource. Module tools and resources may also carry their own.
+type deprecations struct {
+	tools     map[string]mcp.Deprecation // Tool name -> deprecation
+	resources map[string]mcp.Deprecation // Resource URI -> deprecation
+}
+
+// DeprecateTool marks the named tool deprecated. tools/list keeps listing it,
+// with the deprecation under _meta and a notice ahead of its description, and
+// each call is logged. Once d.Sunset has passed the tool is no longer listed
+// and calls fail with mcp.ErrorCodeGone. It must be called before Run.
+func (s *Server) DeprecateTool(name string, d mcp.Deprecation) {
+	d.Deprecated = true
+	if s.deprecations.tools == nil {
+		s.deprecations.tools = make(map[string]mcp.Deprecation)
+	}
+	s.deprecations.tools[name] = d
+}
+
+// DeprecateResource marks the resource with the given URI deprecated, as
+// DeprecateTool does for tools. It must be called before Run.
+func (s *Server) DeprecateResource(uri string, d mcp.Deprecation) {
+	d.Deprecated = true
+	if s.deprecations.resources == nil {
+		s.deprecations.resources = make(map[string]mcp.Deprecation)
+	}
+	s.deprecations.resources[uri] = d
+}
+
+// toolDeprecation returns the deprecation of the named tool, if it has one.
+func (s *Server) toolDeprecation(name string) (mcp.Deprecation, bool) {
+	if d, ok := s.deprecations.tools[name]; ok {
+		return d, true
+	}
+	if t, ok := s.moduleTool(name); ok && t.deprecated != nil {
+		return *t.deprecated, true
+	}
+	return mcp.Deprecation{}, false
+}
+
+// resourceDeprecation returns the deprecation of the resource with the given URI, if it has one.
+func (s *Server) resourceDeprecation(uri string) (mcp.Deprecation, bool) {
+	if d, ok := s.deprecations.resources[uri]; ok {
+		return d, true
+	}
+	if r, ok := s.moduleResource(uri); ok && r.deprecated != nil {
+		return *r.deprecated, true
+	}
+	return mcp.Deprecation{}, false
+}
+
+// withToolDeprecations marks deprecated tools and drops those past their sunset.
+func (s *Server) withToolDeprecations(tools []mcp.Tool) []mcp.Tool {
+	now := s.clock.Now()
+	listed := tools[:0]
+	for _, t := range tools {
+		if d, ok := s.toolDeprecation(t.Name); ok {
+			if d.Gone(now) {
+				continue
+			}
+			t.Meta = withDeprecationMeta(t.Meta, d)
+			t.Description = joinNotice(d.Notice(), t.Description)
+		}
+		listed = append(listed, t)
+	}
+	return listed
+}
+
+// withResourceDeprecations marks deprecated resources and drops those past their sunset.
+func (s *Server) withResourceDeprecations(resources []mcp.Resource) []mcp.Resource {
+	now := s.clock.Now()
+	listed := resources[:0]
+	for _, r := range resources {
+		if d, ok := s.resourceDeprecation(r.URI); ok {
+			if d.Gone(now) {
+				continue
+			}
+			r.Meta = withDeprecationMeta(r.Meta, d)
+			r.Description = joinNotice(d.Notice(), r.Description)
+		}
+		listed = append(listed, r)
+	}
+	return listed
+}
+
+// withDeprecationMeta returns a copy of meta with d added, leaving the
+// registered definition untouched.
+func withDeprecationMeta(meta map[string]interface{}, d mcp.Deprecation) map[string]interface{} {
+	marked := make(map[string]interface{}, len(meta)+1)
+	for k, v := range meta {
+		marked[k] = v
+	}
+	marked[mcp.MetaDeprecation] = d
+	return marked
+}
+
+// joinNotice puts a deprecation notice ahead of a description.
+func joinNotice(notice, description string) string {
+	if description == "" {
+		return notice
+	}
+	return notice + " " + description
+}
+
+// checkToolDeprecation logs a call of a deprecated tool, and returns the error
+// to refuse it with once the tool is past its sunset.
+func (s *Server) checkToolDeprecation(id mcp.RequestID, name string) *mcp.RPCError {
+	d, ok := s.toolDeprecation(name)
+	if !ok {
+		return nil
+	}
+	if d.Gone(s.clock.Now()) {
+		s.logger.Printf("INFO", "Tool '%s' call (ID: %v) refused: removed on %s (client %q)", name, id, d.Sunset.UTC().Format(time.DateOnly), s.clientInfo.Name)
+		data := mcp.GoneData{Tool: name, ReplacedBy: d.ReplacedBy, Sunset: *d.Sunset}
+		return mcp.NewRPCError(mcp.ErrorCodeGone, fmt.Sprintf("Tool '%s' has been removed", name), data)
+	}
+	s.logger.Printf("INFO", "Deprecated tool '%s' called (ID: %v, client %q): %s", name, id, s.clientInfo.Name, d.Notice())
+	return nil
+}
+
+// checkResourceDeprecation does for resources/read what checkToolDeprecation does for tools/call.
+func (s *Server) checkResourceDeprecation(id mcp.RequestID, uri string) *mcp.RPCError {
+	d, ok := s.resourceDeprecation(uri)
+	if !ok {
+		return nil
+	}
+	if d.Gone(s.clock.Now()) {
+		s.logger.Printf("INFO", "Read of resource '%s' (ID: %v) refused: removed on %s (client %q)", uri, id, d.Sunset.UTC().Format(time.DateOnly), s.clientInfo.Name)
+		data := mcp.GoneData{URI: uri, ReplacedBy: d.ReplacedBy, Sunset: *d.Sunset}
+		return mcp.NewRPCError(mcp.ErrorCodeGone, fmt.Sprintf("Resource '%s' has been removed", uri), data)
+	}
+	s.logger.Printf("INFO", "Deprecated resource '%s' read (ID: %v, client %q): %s", uri, id, s.clientInfo.Name, d.Notice())
+	return nil
+}
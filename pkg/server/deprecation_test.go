@@ -0,0 +1,93 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"sqirvy/mcp/pkg/clock"
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/utils"
+)
+
+func TestDeprecation(t *testing.T) {
+	var logs bytes.Buffer
+	s := NewServer(&captureTransport{}, utils.New(&logs, "", log.LstdFlags, utils.LevelInfo))
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(now)
+	s.setClock(fake)
+	sunset := now.Add(24 * time.Hour)
+	s.DeprecateTool(pingToolName, mcp.Deprecation{ReplacedBy: "ping_v2", Sunset: &sunset})
+	s.DeprecateResource(exampleFileResource.URI, mcp.Deprecation{Message: "Read the docs instead."})
+
+	listTools := func() map[string]mcp.Tool {
+		var list struct {
+			Result mcp.ListToolsResult `json:"result"`
+		}
+		json.Unmarshal(s.dispatch(1, mcp.MethodListTools, []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)), &list)
+		tools := make(map[string]mcp.Tool)
+		for _, tool := range list.Result.Tools {
+			tools[tool.Name] = tool
+		}
+		return tools
+	}
+
+	// Before the sunset the tool is listed with its deprecation...
+	ping, ok := listTools()[pingToolName]
+	if !ok {
+		t.Fatal("deprecated ping not listed")
+	}
+	if !strings.HasPrefix(ping.Description, "Deprecated: use ping_v2 instead; removed on 2026-06-02. Pings") {
+		t.Errorf("description = %q", ping.Description)
+	}
+	meta, _ := json.Marshal(ping.Meta)
+	if want := `{"deprecation":{"deprecated":true,"replacedBy":"ping_v2","sunset":"2026-06-02T00:00:00Z"}}`; string(meta) != want {
+		t.Errorf("_meta = %s, want %s", meta, want)
+	}
+	if _, ok := listTools()[diffToolName]; !ok {
+		t.Error("diff tool not listed")
+	}
+
+	// ...and calls are logged
+	if rpcErr := s.checkToolDeprecation(2, pingToolName); rpcErr != nil {
+		t.Fatalf("call before sunset refused: %v", rpcErr)
+	}
+	if !strings.Contains(logs.String(), "Deprecated tool 'ping' called") {
+		t.Errorf("call not logged: %s", logs.String())
+	}
+
+	// After it the tool is gone
+	fake.Advance(24 * time.Hour)
+	if _, ok := listTools()[pingToolName]; ok {
+		t.Error("ping listed after its sunset")
+	}
+	var call struct {
+		Error *mcp.RPCError `json:"error"`
+	}
+	json.Unmarshal(s.dispatch(3, mcp.MethodCallTool, []byte(`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"ping"}}`)), &call)
+	if call.Error == nil || call.Error.Code != mcp.ErrorCodeGone {
+		t.Fatalf("call after sunset = %+v, want gone", call.Error)
+	}
+	data, _ := json.Marshal(call.Error.Data)
+	if want := `{"replacedBy":"ping_v2","sunset":"2026-06-02T00:00:00Z","tool":"ping"}`; string(data) != want {
+		t.Errorf("gone data = %s, want %s", data, want)
+	}
+
+	// Resources carry theirs the same way; without a sunset they stay readable
+	var resources struct {
+		Result mcp.ListResourcesResult `json:"result"`
+	}
+	json.Unmarshal(s.dispatch(4, mcp.MethodListResources, []byte(`{"jsonrpc":"2.0","id":4,"method":"resources/list"}`)), &resources)
+	if len(resources.Result.Resources) == 0 || resources.Result.Resources[0].Meta[mcp.MetaDeprecation] == nil {
+		t.Fatalf("resources = %+v", resources.Result.Resources)
+	}
+	if got := resources.Result.Resources[0].Description; got != "Deprecated. Read the docs instead. An example text file." {
+		t.Errorf("resource description = %q", got)
+	}
+	if exampleFileResource.Meta != nil {
+		t.Error("listing changed the registered resource")
+	}
+}
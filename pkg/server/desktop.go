@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/tools"
+)
+
+const (
+	desktopTimeout        = 15 * time.Second // Per clipboard or screenshot command
+	desktopConsentTimeout = 2 * time.Minute  // How long the user has to answer a consent prompt
+	desktopPreviewLength  = 200              // Characters of clipboard text shown in the consent prompt
+)
+
+// askConsent asks the user, through the client's elicitation support, to allow
+// an action. Without an explicit accept the action must not happen.
+func askConsent(ctx context.Context, session *Server, message string) error {
+	result, err := session.elicit(ctx, mcp.ElicitRequestParams{Message: message}, desktopConsentTimeout)
+	if err != nil {
+		return fmt.Errorf("cannot ask for the user's consent: %w", err)
+	}
+	if result.Action != mcp.ElicitAccept {
+		return fmt.Errorf("the user did not allow it (%s)", result.Action)
+	}
+	return nil
+}
+
+// desktopModule returns the clipboard and screenshot tools. Every call asks the
+// user for consent first, so the tools only work with clients that support elicitation.
+func desktopModule(desktop tools.Desktop) *toolModule {
+	return &toolModule{
+		name: "desktop",
+		tools: []moduleTool{
+			{
+				tool: mcp.Tool{
+					Name:        "clipboard_read",
+					Title:       "Read Clipboard",
+					Description: "Returns the text on the user's clipboard. The user is asked to allow each read.",
+					InputSchema: mcp.ToolInputSchema{"type": "object", "properties": map[string]interface{}{}},
+				},
+				limit: 1,
+				call: func(ctx context.Context, session *Server, _ map[string]interface{}) (mcp.CallToolResult, error) {
+					if err := askConsent(ctx, session, "Allow the assistant to read your clipboard?"); err != nil {
+						return mcp.CallToolResult{}, err
+					}
+					text, err := desktop.ReadClipboard(ctx)
+					return mcp.NewToolResultText(text), err
+				},
+			},
+			{
+				tool: mcp.Tool{
+					Name:        "clipboard_write",
+					Title:       "Write Clipboard",
+					Description: "Replaces the contents of the user's clipboard with text. The user is asked to allow each write.",
+					InputSchema: mcp.ToolInputSchema{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"text": map[string]interface{}{"type": "string", "description": "Text to put on the clipboard"},
+						},
+						"required": []string{"text"},
+					},
+				},
+				limit:       1,
+				destructive: true,
+				call: func(ctx context.Context, session *Server, raw map[string]interface{}) (mcp.CallToolResult, error) {
+					var args struct {
+						Text string `json:"text"`
+					}
+					if err := decodeToolArgs(raw, &args); err != nil {
+						return mcp.CallToolResult{}, err
+					}
+					preview, _ := tools.TruncateText(args.Text, desktopPreviewLength)
+					prompt := fmt.Sprintf("Allow the assistant to replace your clipboard with this text (%d characters)?\n\n%s",
+						utf8.RuneCountInString(args.Text), preview)
+					if err := askConsent(ctx, session, prompt); err != nil {
+						return mcp.CallToolResult{}, err
+					}
+					if err := desktop.WriteClipboard(ctx, args.Text); err != nil {
+						return mcp.CallToolResult{}, err
+					}
+					return mcp.NewToolResultText("Clipboard updated."), nil
+				},
+			},
+			{
+				tool: mcp.Tool{
+					Name:        "screenshot",
+					Title:       "Take Screenshot",
+					Description: "Captures the user's screen and returns it as a PNG image. The user is asked to allow each capture.",
+					InputSchema: mcp.ToolInputSchema{"type": "object", "properties": map[string]interface{}{}},
+				},
+				limit: 1,
+				call: func(ctx context.Context, session *Server, _ map[string]interface{}) (mcp.CallToolResult, error) {
+					if err := askConsent(ctx, session, "Allow the assistant to take a screenshot of your screen?"); err != nil {
+						return mcp.CallToolResult{}, err
+					}
+					png, err := desktop.Screenshot(ctx)
+					if err != nil {
+						return mcp.CallToolResult{}, err
+					}
+					return mcp.NewToolResultImage(png, "image/png"), nil
+				},
+			},
+		},
+		check: desktop.Check,
+	}
+}
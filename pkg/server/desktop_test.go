@@ -0,0 +1,118 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/tools"
+	"sqirvy/mcp/pkg/utils"
+)
+
+// fakeDesktop puts Wayland clipboard and screenshot commands on the PATH. The
+// clipboard is the file "clipboard" in the returned directory.
+func fakeDesktop(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	scripts := map[string]string{
+		"wl-paste": "#!/bin/sh\ncat \"$(dirname \"$0\")/clipboard\"\n",
+		"wl-copy":  "#!/bin/sh\ncat > \"$(dirname \"$0\")/clipboard\"\n",
+		"grim":     "#!/bin/sh\nprintf '\\211PNG\\r\\n\\032\\nfake' > \"$1\"\n",
+	}
+	for name, script := range scripts {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.WriteFile(filepath.Join(dir, "clipboard"), []byte("copied text"), 0644)
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("WAYLAND_DISPLAY", "wayland-0")
+	return dir
+}
+
+// answerElicitations replies to each elicitation/create the server writes with
+// the next of actions, and returns the messages the user was shown.
+func answerElicitations(t *testing.T, s *Server, out *captureTransport, actions ...string) <-chan string {
+	prompts := make(chan string, len(actions))
+	go func() {
+		for _, action := range actions {
+			var req struct {
+				ID     string                  `json:"id"`
+				Method string                  `json:"method"`
+				Params mcp.ElicitRequestParams `json:"params"`
+			}
+			json.Unmarshal(<-out.written, &req)
+			if req.Method != mcp.MethodCreateElicitation {
+				t.Errorf("server sent %s, want %s", req.Method, mcp.MethodCreateElicitation)
+			}
+			prompts <- req.Params.Message
+			result, _ := json.Marshal(mcp.ElicitResult{Action: action})
+			reply, _ := json.Marshal(mcp.RPCResponse{JSONRPC: mcp.JSONRPCVersion, ID: req.ID, Result: result})
+			s.handleClientReply(req.ID, reply)
+		}
+	}()
+	return prompts
+}
+
+func TestDesktopToolsAskForConsent(t *testing.T) {
+	dir := fakeDesktop(t)
+	out := &captureTransport{written: make(chan []byte, 10)}
+	s := NewServer(out, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	s.modules = []*toolModule{desktopModule(tools.Desktop{Timeout: desktopTimeout})}
+	s.clientCapabilities.Elicitation = map[string]interface{}{}
+
+	prompts := answerElicitations(t, s, out, mcp.ElicitAccept, mcp.ElicitAccept, mcp.ElicitDecline, mcp.ElicitAccept)
+
+	if text := mustText(callTool(t, s, "clipboard_read", nil)); text != "copied text" {
+		t.Errorf("clipboard_read = %q", text)
+	}
+	if prompt := <-prompts; !strings.Contains(prompt, "read your clipboard") {
+		t.Errorf("consent prompt = %q", prompt)
+	}
+
+	if result := callTool(t, s, "clipboard_write", map[string]interface{}{"text": "new text"}); result.IsError {
+		t.Errorf("clipboard_write = %q", mustText(result))
+	}
+	if prompt := <-prompts; !strings.Contains(prompt, "new text") {
+		t.Errorf("consent prompt %q does not show the text", prompt)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "clipboard")); string(data) != "new text" {
+		t.Errorf("clipboard = %q after clipboard_write", data)
+	}
+
+	// Declined: nothing is written
+	result := callTool(t, s, "clipboard_write", map[string]interface{}{"text": "unwanted"})
+	<-prompts
+	if !result.IsError || !strings.Contains(mustText(result), "did not allow") {
+		t.Errorf("declined clipboard_write = %q, want a tool error", mustText(result))
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "clipboard")); string(data) != "new text" {
+		t.Errorf("declined write changed the clipboard to %q", data)
+	}
+
+	result = callTool(t, s, "screenshot", nil)
+	<-prompts
+	var image mcp.ImageContent
+	json.Unmarshal(result.Content[0], &image)
+	png, _ := base64.StdEncoding.DecodeString(image.Data)
+	if image.Type != "image" || image.MimeType != "image/png" || !strings.HasSuffix(string(png), "fake") {
+		t.Errorf("screenshot = %+v", image)
+	}
+}
+
+func TestDesktopToolsNeedElicitation(t *testing.T) {
+	fakeDesktop(t)
+	s := NewServer(&captureTransport{}, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	s.modules = []*toolModule{desktopModule(tools.Desktop{Timeout: desktopTimeout})}
+
+	result := callTool(t, s, "clipboard_read", nil)
+	if !result.IsError || !strings.Contains(mustText(result), "does not support elicitation") {
+		t.Errorf("clipboard_read without elicitation = %q, want a tool error", mustText(result))
+	}
+}
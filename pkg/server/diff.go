@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/tools"
+)
+
+const (
+	diffToolName       = "diff"
+	diffDefaultContext = 3
+	diffMaxContext     = 100
+	diffMaxLines       = 5000 // Diff lines returned by one call; later hunks are dropped
+)
+
+// diffArgs are the arguments of the diff tool. Each side is a resource URI or inline text.
+type diffArgs struct {
+	OldURI  string  `json:"old_uri"`
+	OldText *string `json:"old_text"`
+	NewURI  string  `json:"new_uri"`
+	NewText *string `json:"new_text"`
+	Context *int    `json:"context"`
+}
+
+// diffResult is the structured part of a diff result.
+type diffResult struct {
+	Old       string       `json:"old"`
+	New       string       `json:"new"`
+	Hunks     []tools.Hunk `json:"hunks"`
+	Added     int          `json:"added"`
+	Removed   int          `json:"removed"`
+	Truncated bool         `json:"truncated,omitempty"`
+}
+
+// diffTool describes the diff tool for tools/list.
+func diffTool() mcp.Tool {
+	stringProp := func(description string) map[string]interface{} {
+		return map[string]interface{}{"type": "string", "description": description}
+	}
+	return mcp.Tool{
+		Name:  diffToolName,
+		Title: "Diff Texts",
+		Description: "Compares two texts line by line, each given as a resource URI (e.g. file:///src/main.go) or inline. " +
+			"Returns a unified diff followed by the hunks as JSON.",
+		InputSchema: mcp.ToolInputSchema{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"old_uri":  stringProp("URI of the original text"),
+				"old_text": stringProp("The original text, instead of old_uri"),
+				"new_uri":  stringProp("URI of the changed text"),
+				"new_text": stringProp("The changed text, instead of new_uri"),
+				"context":  map[string]interface{}{"type": "integer", "default": diffDefaultContext, "description": fmt.Sprintf("Context lines around changes (default %d)", diffDefaultContext)},
+			},
+		},
+	}
+}
+
+// handleDiffTool handles the "tools/call" request for the "diff" tool. Resources are
+// read through readTextResource, so the same access rules apply as for resources/read,
+// the session's profile included.
+func (s *Server) handleDiffTool(ctx context.Context, id mcp.RequestID, params mcp.CallToolParams) ([]byte, error) {
+	s.logger.Printf("DEBUG", "Handle  : tools/call request for '%s' (ID: %v)", params.Name, id)
+
+	var args diffArgs
+	argBytes, _ := json.Marshal(params.Arguments) // Arguments came from JSON
+	if err := json.Unmarshal(argBytes, &args); err != nil {
+		rpcErr := mcp.NewRPCError(mcp.ErrorCodeInvalidParams, fmt.Sprintf("invalid arguments for %s: %v", diffToolName, err), nil)
+		return s.marshalErrorResponse(id, rpcErr)
+	}
+	contextLines := diffDefaultContext
+	if args.Context != nil {
+		if *args.Context < 0 || *args.Context > diffMaxContext {
+			rpcErr := mcp.NewRPCError(mcp.ErrorCodeInvalidParams, fmt.Sprintf("%s context must be between 0 and %d", diffToolName, diffMaxContext), nil)
+			return s.marshalErrorResponse(id, rpcErr)
+		}
+		contextLines = *args.Context
+	}
+
+	oldName, oldText, err := s.diffInput(ctx, "old", args.OldURI, args.OldText)
+	if err == nil {
+		var newName, newText string
+		if newName, newText, err = s.diffInput(ctx, "new", args.NewURI, args.NewText); err == nil {
+			return s.marshalDiff(id, oldName, oldText, newName, newText, contextLines)
+		}
+	}
+	s.logger.Printf("DEBUG", "diff failed: %v", err)
+	return s.marshalResponse(id, mcp.NewToolResultError(fmt.Errorf("diff: %w", err)))
+}
+
+// diffInput returns the name and text of one side of a diff.
+func (s *Server) diffInput(ctx context.Context, side, uri string, text *string) (string, string, error) {
+	switch {
+	case uri != "" && text != nil:
+		return "", "", fmt.Errorf("give either %s_uri or %s_text, not both", side, side)
+	case text != nil:
+		return side, *text, nil
+	case uri == "":
+		return "", "", fmt.Errorf("%s_uri or %s_text is required", side, side)
+	}
+	content, err := s.readTextResource(ctx, uri)
+	return uri, content, err
+}
+
+// marshalDiff diffs the texts and marshals the tool result.
+func (s *Server) marshalDiff(id mcp.RequestID, oldName, oldText, newName, newText string, contextLines int) ([]byte, error) {
+	result := diffResult{Old: oldName, New: newName, Hunks: []tools.Hunk{}}
+	lines := 0
+	for _, h := range tools.Diff(oldText, newText, contextLines) {
+		if lines += len(h.Lines); lines > diffMaxLines {
+			result.Truncated = true
+			break
+		}
+		result.Hunks = append(result.Hunks, h)
+		for _, line := range h.Lines {
+			switch line[0] {
+			case '+':
+				result.Added++
+			case '-':
+				result.Removed++
+			}
+		}
+	}
+
+	text := "No differences."
+	if len(result.Hunks) > 0 || result.Truncated {
+		text = tools.FormatUnified(oldName, newName, result.Hunks)
+		if result.Truncated {
+			text += fmt.Sprintf("[diff truncated after %d hunk(s); the rest exceeds %d lines]\n", len(result.Hunks), diffMaxLines)
+		}
+		text += fmt.Sprintf("%d line(s) added, %d removed", result.Added, result.Removed)
+	}
+	toolResult, err := structuredResult(text, "diff:"+oldName+".."+newName, result)
+	if err != nil {
+		rpcErr := mcp.NewRPCError(mcp.ErrorCodeInternalError, err.Error(), nil)
+		return s.marshalErrorResponse(id, rpcErr)
+	}
+	return s.marshalResponse(id, toolResult)
+}
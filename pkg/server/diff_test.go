@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"strings"
+	"testing"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/utils"
+)
+
+func TestDiffTool(t *testing.T) {
+	s := NewServer(&captureTransport{}, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	s.modules = []*toolModule{{
+		name: "test",
+		resources: []moduleResource{{
+			resource: mcp.Resource{URI: "test://config", MimeType: "text/plain"},
+			read:     func(context.Context) (string, error) { return "port: 80\nhost: a\n", nil },
+		}},
+	}}
+
+	result := callTool(t, s, diffToolName, map[string]interface{}{"old_uri": "test://config", "new_text": "port: 8080\nhost: a\n"})
+	want := "--- test://config\n+++ new\n@@ -1,2 +1,2 @@\n-port: 80\n+port: 8080\n host: a\n1 line(s) added, 1 removed"
+	if text := mustText(result); result.IsError || text != want {
+		t.Errorf("diff text = %q, want %q", text, want)
+	}
+	var embedded struct {
+		Resource mcp.TextResourceContents `json:"resource"`
+	}
+	json.Unmarshal(result.Content[1], &embedded)
+	var structured diffResult
+	json.Unmarshal([]byte(embedded.Resource.Text), &structured)
+	if len(structured.Hunks) != 1 || structured.Hunks[0].OldStart != 1 || structured.Added != 1 || structured.Removed != 1 {
+		t.Errorf("structured = %+v", structured)
+	}
+
+	if text := mustText(callTool(t, s, diffToolName, map[string]interface{}{"old_text": "x", "new_text": "x"})); text != "No differences." {
+		t.Errorf("equal texts = %q", text)
+	}
+
+	for _, args := range []map[string]interface{}{
+		{"old_uri": "file:///../../etc/passwd", "new_text": ""},
+		{"old_uri": "test://config", "old_text": "x", "new_text": "y"},
+		{"old_text": "x"},
+		{"old_uri": "ftp://host/file", "new_text": "y"},
+	} {
+		if result := callTool(t, s, diffToolName, args); !result.IsError {
+			t.Errorf("diff %v = %q, want a tool error", args, mustText(result))
+		}
+	}
+	if result := callTool(t, s, diffToolName, map[string]interface{}{"old_uri": "file:///../../etc/passwd", "new_text": ""}); !strings.Contains(mustText(result), "outside project root") {
+		t.Errorf("path traversal error = %q", mustText(result))
+	}
+}
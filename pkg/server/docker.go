@@ -0,0 +1,264 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/tools"
+)
+
+const (
+	dockerTimeout          = 15 * time.Second // Per API call
+	dockerDefaultTailLines = 200
+	dockerMaxTailLines     = 5000
+	dockerMinIDPrefix      = 4 // Shortest container ID prefix accepted in place of a name
+)
+
+// defaultDockerHost returns $DOCKER_HOST if set, the local socket otherwise.
+func defaultDockerHost() string {
+	if host := os.Getenv("DOCKER_HOST"); host != "" {
+		return host
+	}
+	return tools.DefaultDockerHost
+}
+
+// dockerAllowlist decides which containers the Docker tools may see. Patterns
+// are shell globs (path.Match) matched against container names; "*" allows all.
+type dockerAllowlist []string
+
+// parseDockerAllowlist parses a comma-separated list of name patterns.
+func parseDockerAllowlist(spec string) (dockerAllowlist, error) {
+	var patterns dockerAllowlist
+	for _, p := range strings.Split(spec, ",") {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid container pattern %q: %w", p, err)
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, nil
+}
+
+// allows reports whether the container may be seen.
+func (a dockerAllowlist) allows(c tools.DockerContainer) bool {
+	for _, pattern := range a {
+		for _, name := range c.Names {
+			if ok, _ := path.Match(pattern, strings.TrimPrefix(name, "/")); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// dockerContainerSummary is a container in the structured docker_ps result.
+type dockerContainerSummary struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Image   string `json:"image"`
+	State   string `json:"state"`
+	Status  string `json:"status"`
+	Created string `json:"created"`
+}
+
+// dockerModule returns the Docker tools, which make read-only Docker Engine API
+// calls and only ever see the containers the allowlist admits.
+func dockerModule(client *tools.DockerClient, allow dockerAllowlist) *toolModule {
+	containerProp := map[string]interface{}{"type": "string", "description": "Container name or ID (prefix)"}
+
+	// find resolves a name or ID prefix to an allowed container. Disallowed
+	// containers are reported as not found, so their existence is not revealed.
+	find := func(ref string) (tools.DockerContainer, error) {
+		if ref == "" {
+			return tools.DockerContainer{}, fmt.Errorf("container is required")
+		}
+		containers, err := client.Containers(true)
+		if err != nil {
+			return tools.DockerContainer{}, err
+		}
+		for _, c := range containers {
+			if !allow.allows(c) {
+				continue
+			}
+			if c.Name() == ref || (len(ref) >= dockerMinIDPrefix && strings.HasPrefix(c.ID, ref)) {
+				return c, nil
+			}
+		}
+		return tools.DockerContainer{}, fmt.Errorf("no allowed container %q", ref)
+	}
+
+	return &toolModule{
+		name: "docker",
+		tools: []moduleTool{
+			{
+				tool: mcp.Tool{
+					Name:        "docker_ps",
+					Title:       "List Docker Containers",
+					Description: "Lists the Docker containers this server is allowed to inspect.",
+					InputSchema: mcp.ToolInputSchema{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"all": map[string]interface{}{"type": "boolean", "description": "Include stopped containers"},
+						},
+					},
+				},
+				call: func(_ context.Context, _ *Server, raw map[string]interface{}) (mcp.CallToolResult, error) {
+					var args struct {
+						All bool `json:"all"`
+					}
+					if err := decodeToolArgs(raw, &args); err != nil {
+						return mcp.CallToolResult{}, err
+					}
+					containers, err := client.Containers(args.All)
+					if err != nil {
+						return mcp.CallToolResult{}, err
+					}
+					summaries := []dockerContainerSummary{}
+					table := &tools.Table{Columns: []string{"CONTAINER ID", "NAME", "IMAGE", "STATE", "STATUS"}}
+					for _, c := range containers {
+						if !allow.allows(c) {
+							continue
+						}
+						summary := dockerContainerSummary{
+							ID: c.ID, Name: c.Name(), Image: c.Image, State: c.State, Status: c.Status,
+							Created: time.Unix(c.Created, 0).UTC().Format(time.RFC3339),
+						}
+						summaries = append(summaries, summary)
+						table.Rows = append(table.Rows, []tools.Value{
+							{Text: c.ID[:min(12, len(c.ID))]}, {Text: summary.Name}, {Text: c.Image}, {Text: c.State}, {Text: c.Status},
+						})
+					}
+					return structuredResult(table.Render()+fmt.Sprintf("%d container(s)", len(summaries)), "docker://containers", summaries)
+				},
+			},
+			{
+				tool: mcp.Tool{
+					Name:        "docker_inspect",
+					Title:       "Inspect Docker Container",
+					Description: "Returns a container's configuration and state (docker inspect). Environment variable values are redacted.",
+					InputSchema: mcp.ToolInputSchema{
+						"type":       "object",
+						"properties": map[string]interface{}{"container": containerProp},
+						"required":   []string{"container"},
+					},
+				},
+				call: func(_ context.Context, _ *Server, raw map[string]interface{}) (mcp.CallToolResult, error) {
+					var args struct {
+						Container string `json:"container"`
+					}
+					if err := decodeToolArgs(raw, &args); err != nil {
+						return mcp.CallToolResult{}, err
+					}
+					c, err := find(args.Container)
+					if err != nil {
+						return mcp.CallToolResult{}, err
+					}
+					data, err := client.Inspect(c.ID)
+					if err != nil {
+						return mcp.CallToolResult{}, err
+					}
+					var info map[string]interface{}
+					if err := json.Unmarshal(data, &info); err != nil {
+						return mcp.CallToolResult{}, fmt.Errorf("invalid inspect response: %w", err)
+					}
+					redactDockerEnv(info)
+					return structuredResult(dockerInspectSummary(c, info), "docker://containers/"+c.ID, info)
+				},
+			},
+			{
+				tool: mcp.Tool{
+					Name:        "docker_logs",
+					Title:       "Docker Container Logs",
+					Description: "Returns the most recent log lines (stdout and stderr) of a container.",
+					InputSchema: mcp.ToolInputSchema{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"container":  containerProp,
+							"tail_lines": map[string]interface{}{"type": "integer", "description": fmt.Sprintf("Number of lines (default %d, at most %d)", dockerDefaultTailLines, dockerMaxTailLines)},
+							"since":      map[string]interface{}{"type": "string", "description": "Only lines newer than this duration, e.g. 10m"},
+							"timestamps": map[string]interface{}{"type": "boolean", "description": "Prefix lines with their timestamps"},
+						},
+						"required": []string{"container"},
+					},
+				},
+				call: func(_ context.Context, _ *Server, raw map[string]interface{}) (mcp.CallToolResult, error) {
+					var args struct {
+						Container  string `json:"container"`
+						TailLines  int    `json:"tail_lines"`
+						Since      string `json:"since"`
+						Timestamps bool   `json:"timestamps"`
+					}
+					if err := decodeToolArgs(raw, &args); err != nil {
+						return mcp.CallToolResult{}, err
+					}
+					if args.Since != "" {
+						if _, err := time.ParseDuration(args.Since); err != nil {
+							return mcp.CallToolResult{}, fmt.Errorf("invalid since %q: %v", args.Since, err)
+						}
+					}
+					c, err := find(args.Container)
+					if err != nil {
+						return mcp.CallToolResult{}, err
+					}
+					tail := args.TailLines
+					if tail <= 0 {
+						tail = dockerDefaultTailLines
+					}
+					logs, err := client.Logs(c.ID, tools.DockerLogOptions{Tail: min(tail, dockerMaxTailLines), Since: args.Since, Timestamps: args.Timestamps})
+					return mcp.NewToolResultText(logs), err
+				},
+			},
+		},
+		check: func() (string, error) {
+			version, err := client.Ping()
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%s, %d container pattern(s) allowed", version, len(allow)), nil
+		},
+	}
+}
+
+// redactDockerEnv replaces the values of Config.Env entries, which often hold credentials.
+func redactDockerEnv(info map[string]interface{}) {
+	config, _ := info["Config"].(map[string]interface{})
+	env, _ := config["Env"].([]interface{})
+	for i, entry := range env {
+		if s, ok := entry.(string); ok {
+			name, _, _ := strings.Cut(s, "=")
+			env[i] = name + "=[redacted]"
+		}
+	}
+}
+
+// dockerInspectSummary describes the most useful parts of an inspect result in a few lines.
+func dockerInspectSummary(c tools.DockerContainer, info map[string]interface{}) string {
+	field := func(v interface{}, keys ...string) interface{} {
+		for _, k := range keys {
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			v = m[k]
+		}
+		return v
+	}
+	lines := []string{
+		fmt.Sprintf("Container: %s (%s)", c.Name(), c.ID[:min(12, len(c.ID))]),
+		fmt.Sprintf("Image:     %s", c.Image),
+		fmt.Sprintf("State:     %v (exit code %v, started %v)", field(info, "State", "Status"), field(info, "State", "ExitCode"), field(info, "State", "StartedAt")),
+		fmt.Sprintf("Restarts:  %v", field(info, "RestartCount")),
+	}
+	if health := field(info, "State", "Health", "Status"); health != nil {
+		lines = append(lines, fmt.Sprintf("Health:    %v", health))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/tools"
+	"sqirvy/mcp/pkg/utils"
+)
+
+// fakeDockerAPI serves a minimal Docker Engine API on a unix socket and returns its host address.
+func fakeDockerAPI(t *testing.T) string {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1.41/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `[{"Id":"aaaa1111bbbb2222","Names":["/web-1"],"Image":"nginx","State":"running","Status":"Up 1 hour"},
+			{"Id":"cccc3333dddd4444","Names":["/vault"],"Image":"vault","State":"running","Status":"Up 2 hours"}]`)
+	})
+	mux.HandleFunc("GET /v1.41/containers/aaaa1111bbbb2222/json", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"Id":"aaaa1111bbbb2222","RestartCount":2,"State":{"Status":"running","ExitCode":0},"Config":{"Env":["PASSWORD=hunter2","PATH=/bin"]}}`)
+	})
+	mux.HandleFunc("GET /v1.41/containers/aaaa1111bbbb2222/logs", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("tail") != "10" {
+			http.Error(w, `{"message":"unexpected tail"}`, http.StatusBadRequest)
+			return
+		}
+		for stream, line := range []string{"", "out line\n", "err line\n"} {
+			if line == "" {
+				continue
+			}
+			header := []byte{byte(stream), 0, 0, 0, 0, 0, 0, 0}
+			binary.BigEndian.PutUint32(header[4:], uint32(len(line)))
+			w.Write(append(header, line...))
+		}
+	})
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+	return "unix://" + socket
+}
+
+func TestDockerTools(t *testing.T) {
+	client, err := tools.NewDockerClient(fakeDockerAPI(t), dockerTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(&captureTransport{}, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	s.modules = []*toolModule{dockerModule(client, dockerAllowlist{"web-*"})}
+
+	text := func(result mcp.CallToolResult, i int) string {
+		var content mcp.TextContent
+		json.Unmarshal(result.Content[i], &content)
+		return content.Text
+	}
+
+	ps := callTool(t, s, "docker_ps", nil)
+	if ps.IsError || !strings.Contains(text(ps, 0), "web-1") || strings.Contains(text(ps, 0), "vault") {
+		t.Errorf("docker_ps = %q, want only the allowed web-1", text(ps, 0))
+	}
+	var embedded struct {
+		Resource mcp.TextResourceContents `json:"resource"`
+	}
+	json.Unmarshal(ps.Content[1], &embedded)
+	var summaries []dockerContainerSummary
+	if err := json.Unmarshal([]byte(embedded.Resource.Text), &summaries); err != nil || len(summaries) != 1 || summaries[0].Name != "web-1" {
+		t.Errorf("docker_ps structured = %s", embedded.Resource.Text)
+	}
+
+	inspect := callTool(t, s, "docker_inspect", map[string]interface{}{"container": "aaaa1111"})
+	json.Unmarshal(inspect.Content[1], &embedded)
+	if inspect.IsError || !strings.Contains(text(inspect, 0), "Restarts:  2") ||
+		strings.Contains(embedded.Resource.Text, "hunter2") || !strings.Contains(embedded.Resource.Text, "PASSWORD=[redacted]") {
+		t.Errorf("docker_inspect = %q / %s", text(inspect, 0), embedded.Resource.Text)
+	}
+
+	logs := callTool(t, s, "docker_logs", map[string]interface{}{"container": "web-1", "tail_lines": 10})
+	if logs.IsError || text(logs, 0) != "out line\nerr line\n" {
+		t.Errorf("docker_logs = %q, want the demultiplexed lines", text(logs, 0))
+	}
+
+	for _, ref := range []string{"vault", "cccc3333"} {
+		if result := callTool(t, s, "docker_inspect", map[string]interface{}{"container": ref}); !result.IsError {
+			t.Errorf("docker_inspect %s succeeded for a container outside the allowlist", ref)
+		}
+	}
+}
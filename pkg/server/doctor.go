@@ -0,0 +1,253 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"sqirvy/mcp/pkg/mcp"
+	resources "sqirvy/mcp/pkg/resources"
+	"sqirvy/mcp/pkg/tools"
+	"sqirvy/mcp/pkg/transport"
+	"sqirvy/mcp/pkg/utils"
+)
+
+// doctorHandshakeTimeout bounds the in-process handshake check.
+const doctorHandshakeTimeout = 5 * time.Second
+
+// doctorConfig holds the command line values checked by the doctor subcommand.
+type doctorConfig struct {
+	logFile   string
+	toolLimit string
+	chaos     string
+	listen    string
+	secret    string
+	modules   []*toolModule
+	moduleErr error // From buildModules
+}
+
+// doctorCheck is one named check of the doctor report. run returns a short
+// description of what was found, or an error if the check failed.
+type doctorCheck struct {
+	name string
+	run  func() (string, error)
+}
+
+// runDoctor runs every check, writes a pass/fail report to w and returns the
+// process exit code: 0 if all checks passed, 1 otherwise.
+//
+// It is meant to be run as "mcp-server doctor [flags]" with the same flags the
+// host will use, before registering the server with the host.
+func runDoctor(cfg doctorConfig, w io.Writer) int {
+	checks := []doctorCheck{
+		{"log file", func() (string, error) { return checkLogFile(cfg.logFile) }},
+		{"tool limits", func() (string, error) {
+			limits, err := parseToolLimits(cfg.toolLimit)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%d override(s)", len(limits)), nil
+		}},
+		{"chaos config", func() (string, error) {
+			if cfg.chaos == "" {
+				return "disabled", nil
+			}
+			_, err := transport.ParseChaosConfig(cfg.chaos)
+			return cfg.chaos, err
+		}},
+		{"listen address", func() (string, error) {
+			if cfg.listen == "" {
+				return "stdio", nil
+			}
+			return checkListenAddress(cfg.listen)
+		}},
+		{"hmac secret", func() (string, error) {
+			if cfg.secret == "" {
+				return "messages unsigned", nil
+			}
+			if _, err := transport.ReadSecretFile(cfg.secret); err != nil {
+				return "", err
+			}
+			return cfg.secret, nil
+		}},
+		{"resource root", checkResourceRoot},
+		{"resource " + exampleFileResource.URI, func() (string, error) { return checkFileResource(exampleFileResource.URI) }},
+		{"tool " + pingToolName, func() (string, error) { return exec.LookPath(tools.PingCommand) }},
+		{"handshake", checkHandshake},
+	}
+	if cfg.moduleErr != nil {
+		checks = append(checks, doctorCheck{"tool modules", func() (string, error) { return "", cfg.moduleErr }})
+	}
+	for _, m := range cfg.modules {
+		checks = append(checks, doctorCheck{"module " + m.name, m.check})
+	}
+
+	fmt.Fprintf(w, "mcp-server doctor (%s)\n", serverVersionString(readBuildInfo()))
+	failed := 0
+	for _, c := range checks {
+		detail, err := c.run()
+		if err != nil {
+			failed++
+			fmt.Fprintf(w, "  [FAIL] %s: %v\n", c.name, err)
+			continue
+		}
+		fmt.Fprintf(w, "  [PASS] %s: %s\n", c.name, detail)
+	}
+	if failed > 0 {
+		fmt.Fprintf(w, "%d of %d checks failed\n", failed, len(checks))
+		return 1
+	}
+	fmt.Fprintf(w, "all %d checks passed\n", len(checks))
+	return 0
+}
+
+// checkLogFile verifies that the log file can be opened for appending.
+func checkLogFile(path string) (string, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", err
+	}
+	f.Close()
+	return path + " is writable", nil
+}
+
+// checkListenAddress verifies that the server could listen on spec. TCP addresses are
+// bound and released again; for unix sockets the directory must exist.
+func checkListenAddress(spec string) (string, error) {
+	network, address, err := transport.ParseAddress(spec)
+	if err != nil {
+		return "", err
+	}
+	if network == "unix" {
+		dir := filepath.Dir(address)
+		if info, err := os.Stat(dir); err != nil {
+			return "", err
+		} else if !info.IsDir() {
+			return "", fmt.Errorf("%s is not a directory", dir)
+		}
+		return "unix " + address, nil
+	}
+	ln, err := net.Listen(network, address)
+	if err != nil {
+		return "", err
+	}
+	ln.Close()
+	return network + " " + address, nil
+}
+
+// checkResourceRoot verifies that the project root exists and can be listed.
+func checkResourceRoot() (string, error) {
+	root := resources.ProjectRoot()
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s (%d entries)", root, len(entries)), nil
+}
+
+// checkFileResource verifies that a listed file resource can be opened.
+func checkFileResource(uri string) (string, error) {
+	f, err := resources.OpenFileResource(uri, utils.New(io.Discard, "", 0, utils.LevelInfo))
+	if err != nil {
+		return "", err
+	}
+	f.Close()
+	return fmt.Sprintf("%s (%d bytes)", f.Path, f.Size), nil
+}
+
+// checkHandshake runs a server in-process over a pipe and performs the
+// initialize handshake followed by tools/list, as a host would.
+func checkHandshake() (string, error) {
+	clientIn, serverOut := io.Pipe()
+	serverIn, clientOut := io.Pipe()
+	server := NewServer(transport.NewStream(serverIn, serverOut), utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	done := make(chan error, 1)
+	go func() { done <- server.Run() }()
+
+	client := transport.NewStream(clientIn, clientOut)
+	type outcome struct {
+		detail string
+		err    error
+	}
+	result := make(chan outcome, 1)
+	go func() {
+		detail, err := doctorHandshake(client)
+		result <- outcome{detail, err}
+	}()
+
+	var res outcome
+	select {
+	case res = <-result:
+	case <-time.After(doctorHandshakeTimeout):
+		res.err = fmt.Errorf("no response within %v", doctorHandshakeTimeout)
+	}
+	// Closing the client side ends the session
+	clientOut.Close()
+	clientIn.Close()
+	if err := <-done; err != nil && res.err == nil {
+		res.err = err
+	}
+	return res.detail, res.err
+}
+
+// doctorHandshake performs the client side of checkHandshake.
+func doctorHandshake(client transport.Transport) (string, error) {
+	request, err := mcp.MarshalInitializeRequest(1, mcp.InitializeParams{
+		ProtocolVersion: "2024-11-05",
+		ClientInfo:      mcp.Implementation{Name: "mcp-server-doctor", Version: serverVersionString(readBuildInfo())},
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := client.WriteMessage(request); err != nil {
+		return "", err
+	}
+	response, err := client.ReadMessage()
+	if err != nil {
+		return "", err
+	}
+	initResult, _, rpcErr, err := mcp.UnmarshalInitializeResponse(response)
+	if err != nil {
+		return "", err
+	}
+	if rpcErr != nil {
+		return "", fmt.Errorf("initialize failed: %s", rpcErr.Message)
+	}
+
+	notification, err := mcp.MarshalNotification(mcp.MethodInitialized, nil)
+	if err != nil {
+		return "", err
+	}
+	if err := client.WriteMessage(notification); err != nil {
+		return "", err
+	}
+
+	request, err = mcp.MarshalListToolsRequest(2, nil)
+	if err != nil {
+		return "", err
+	}
+	if err := client.WriteMessage(request); err != nil {
+		return "", err
+	}
+	response, err = client.ReadMessage()
+	if err != nil {
+		return "", err
+	}
+	toolsResult, _, rpcErr, err := mcp.UnmarshalListToolsResponse(response)
+	if err != nil {
+		return "", err
+	}
+	if rpcErr != nil {
+		return "", fmt.Errorf("tools/list failed: %s", rpcErr.Message)
+	}
+	if toolsResult == nil || len(toolsResult.Tools) == 0 {
+		return "", errors.New("tools/list returned no tools")
+	}
+	return fmt.Sprintf("protocol %s, %d tool(s)", initResult.ProtocolVersion, len(toolsResult.Tools)), nil
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDoctorHandshake(t *testing.T) {
+	detail, err := checkHandshake()
+	if err != nil {
+		t.Fatalf("checkHandshake() error = %v", err)
+	}
+	if !strings.Contains(detail, "2024-11-05") {
+		t.Errorf("checkHandshake() = %q, want the negotiated protocol version", detail)
+	}
+}
+
+func TestDoctorReportsConfigErrors(t *testing.T) {
+	var out bytes.Buffer
+	code := runDoctor(doctorConfig{
+		logFile:   filepath.Join(t.TempDir(), "server.log"),
+		toolLimit: "ping=x",
+		chaos:     "latency=fast",
+		listen:    "udp:localhost:1",
+		secret:    filepath.Join(t.TempDir(), "missing"),
+	}, &out)
+	if code != 1 {
+		t.Errorf("runDoctor() = %d, want 1", code)
+	}
+	report := out.String()
+	for _, want := range []string{"[PASS] log file", "[FAIL] tool limits", "[FAIL] chaos config", "[FAIL] listen address", "[FAIL] hmac secret", "[PASS] handshake"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report does not contain %q:\n%s", want, report)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"time"
+
+	"sqirvy/mcp/pkg/clock"
+	"sqirvy/mcp/pkg/mcp"
+)
+
+// Draining
+//
+// A session drains instead of stopping abruptly, so that an operator can roll
+// a deployment without failing requests that are already running:
+//
+//  1. the server sends notifications/shutdown with the reason (queued behind
+//     responses already pending, as usual; see outbox.go);
+//  2. the session enters stateDraining, and every new request is refused with
+//     ErrorCodeShuttingDown so the client knows to reconnect elsewhere;
+//  3. once the in-flight handlers have finished and their responses have been
+//     written, Run returns and the caller closes the connection.
+//
+// A drain is started by Drain (e.g. when the listener receives SIGTERM) or by
+// the session policy below.
+
+// sessionPolicy limits how long a session may stay open. Zero disables a limit.
+type sessionPolicy struct {
+	maxLifetime time.Duration // Drain this long after the session started
+	idleTimeout time.Duration // Drain after this long without a message from the client
+	initTimeout time.Duration // Close the session if the handshake is not complete after this long
+}
+
+// Drain asks the session to drain with the given reason. It may be called from
+// any goroutine, also before Run starts; only the first call has an effect.
+func (s *Server) Drain(reason string) {
+	select {
+	case s.drainRequests <- reason:
+	default: // A drain has already been requested
+	}
+}
+
+// beginDrain moves the session to stateDraining, tells the client, and returns
+// a channel that is closed once every in-flight handler has finished.
+// It must be called from the processing loop, which is what guarantees that no
+// handler is started after the state change.
+func (s *Server) beginDrain(reason string) <-chan struct{} {
+	s.logger.Printf("DEBUG", "Draining session: %s", reason)
+	s.setState(stateDraining)
+	s.notify(mcp.MethodShutdown, mcp.ShutdownParams{Reason: reason})
+
+	drained := make(chan struct{})
+	go func() {
+		s.handlers.Wait()
+		close(drained)
+	}()
+	return drained
+}
+
+// policyTimer returns a timer for d and its channel, or nils if d is zero.
+func policyTimer(c clock.Clock, d time.Duration) (clock.Timer, <-chan time.Time) {
+	if d <= 0 {
+		return nil, nil
+	}
+	t := c.NewTimer(d)
+	return t, t.C()
+}
@@ -0,0 +1,205 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"sqirvy/mcp/pkg/clock"
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/utils"
+)
+
+// chanTransport feeds the server messages from in and blocks reads until in is closed.
+type chanTransport struct {
+	captureTransport
+	in chan []byte
+}
+
+func (c *chanTransport) ReadMessage() ([]byte, error) {
+	payload, ok := <-c.in
+	if !ok {
+		return nil, io.EOF
+	}
+	return payload, nil
+}
+
+// wireMessage is the part of a written message the drain tests look at.
+type wireMessage struct {
+	ID     mcp.RequestID      `json:"id"`
+	Method string             `json:"method"`
+	Params mcp.ShutdownParams `json:"params"`
+	Error  *mcp.RPCError      `json:"error"`
+}
+
+func readWire(t *testing.T, written chan []byte) wireMessage {
+	t.Helper()
+	select {
+	case out := <-written:
+		var m wireMessage
+		if err := json.Unmarshal(out, &m); err != nil {
+			t.Fatalf("bad message %s: %v", out, err)
+		}
+		return m
+	case <-time.After(time.Second):
+		t.Fatal("no message written")
+	}
+	return wireMessage{}
+}
+
+func TestDrainFinishesInFlightRequests(t *testing.T) {
+	tr := &captureTransport{written: make(chan []byte, 16)}
+	s := NewServer(tr, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	s.toolLimits = newToolLimiter(map[string]int{"slow": 1}, time.Second)
+
+	s.processMessage([]byte(initializeRequest))
+	s.processMessage([]byte(initializedNotify))
+	readWire(t, tr.written)
+
+	// Hold the tool's only slot so the call stays in flight
+	release, err := s.toolLimits.acquire("slow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.processMessage([]byte(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"slow"}}`))
+
+	drained := s.beginDrain("test")
+	if s.state != stateDraining {
+		t.Fatalf("state = %s, want %s", s.state, stateDraining)
+	}
+	s.processMessage([]byte(pingRequest))
+
+	select {
+	case <-drained:
+		t.Fatal("drained while a request was in flight")
+	case <-time.After(20 * time.Millisecond):
+	}
+	release()
+	select {
+	case <-drained:
+	case <-time.After(time.Second):
+		t.Fatal("session did not drain")
+	}
+	s.finish()
+
+	// The in-flight call is answered first; the notification and the refusal queue behind it
+	if m := readWire(t, tr.written); m.ID != float64(2) || m.Error == nil || m.Error.Code != mcp.ErrorCodeMethodNotFound {
+		t.Errorf("first message = %+v, want the tools/call reply", m)
+	}
+	if m := readWire(t, tr.written); m.Method != mcp.MethodShutdown || m.Params.Reason != "test" {
+		t.Errorf("second message = %+v, want a shutdown notification", m)
+	}
+	if m := readWire(t, tr.written); m.ID != float64(3) || m.Error == nil || m.Error.Code != mcp.ErrorCodeShuttingDown {
+		t.Errorf("third message = %+v, want ErrorCodeShuttingDown", m)
+	}
+}
+
+func TestIdleTimeoutDrainsSession(t *testing.T) {
+	tr := &chanTransport{captureTransport: captureTransport{written: make(chan []byte, 16)}, in: make(chan []byte)}
+	defer close(tr.in)
+	s := NewServer(tr, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	s.policy = sessionPolicy{idleTimeout: 50 * time.Millisecond}
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+	s.setClock(fake)
+
+	done := make(chan error, 1)
+	go func() { done <- s.Run() }()
+	for fake.Timers() == 0 {
+		time.Sleep(time.Millisecond) // Wait for Run to start the idle timer
+	}
+
+	// Activity keeps the session open
+	for i := 0; i < 3; i++ {
+		fake.Advance(40 * time.Millisecond)
+		tr.in <- []byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"ping"}`, i+1))
+		readWire(t, tr.written)
+	}
+	select {
+	case <-done:
+		t.Fatal("active session was drained")
+	default:
+	}
+	fake.Advance(50 * time.Millisecond)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("idle session was not drained")
+	}
+	if m := readWire(t, tr.written); m.Method != mcp.MethodShutdown || m.Params.Reason != "idle timeout" {
+		t.Errorf("message = %+v, want an idle timeout shutdown notification", m)
+	}
+}
+
+func TestInitTimeoutEndsSession(t *testing.T) {
+	for _, handshake := range []bool{false, true} {
+		tr := &chanTransport{captureTransport: captureTransport{written: make(chan []byte, 16)}, in: make(chan []byte)}
+		var logs bytes.Buffer
+		s := NewServer(tr, utils.New(&logs, "", 0, utils.LevelInfo))
+		s.policy = sessionPolicy{initTimeout: 50 * time.Millisecond}
+		fake := clock.NewFake(time.Unix(1700000000, 0))
+		s.setClock(fake)
+
+		done := make(chan error, 1)
+		go func() { done <- s.Run() }()
+		for fake.Timers() == 0 {
+			time.Sleep(time.Millisecond) // Wait for Run to start the timer
+		}
+		if handshake {
+			tr.in <- []byte(initializeRequest)
+			readWire(t, tr.written)
+			tr.in <- []byte(initializedNotify)
+			tr.in <- []byte(`{"jsonrpc":"2.0","id":2,"method":"ping"}`)
+			readWire(t, tr.written) // The notification has been handled too
+		}
+		fake.Advance(50 * time.Millisecond)
+
+		select {
+		case err := <-done:
+			if handshake {
+				t.Fatalf("initialized session ended: %v", err)
+			}
+			if err != nil {
+				t.Fatalf("Run = %v", err)
+			}
+			if !strings.Contains(logs.String(), "No MCP handshake from the client within 50ms") {
+				t.Errorf("no diagnostic logged: %s", logs.String())
+			}
+		case <-time.After(100 * time.Millisecond):
+			if !handshake {
+				t.Fatal("session without a handshake did not end")
+			}
+		}
+		close(tr.in)
+	}
+}
+
+func TestSessionSetDrainsLateSessions(t *testing.T) {
+	logger := utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo)
+	ss := &sessionSet{sessions: make(map[*Server]struct{})}
+	early := NewServer(&captureTransport{}, logger)
+	ss.add(early)
+
+	ss.drainAll("rolling restart")
+	late := NewServer(&captureTransport{}, logger)
+	ss.add(late)
+
+	for name, s := range map[string]*Server{"early": early, "late": late} {
+		select {
+		case reason := <-s.drainRequests:
+			if reason != "rolling restart" {
+				t.Errorf("%s session drain reason = %q", name, reason)
+			}
+		default:
+			t.Errorf("%s session was not drained", name)
+		}
+	}
+}
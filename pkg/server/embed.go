@@ -0,0 +1,740 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/transport"
+	"sqirvy/mcp/pkg/utils"
+)
+
+// httpEventBuffer bounds the server-initiated messages held for an HTTP session
+// while no GET event stream is open to take them.
+const httpEventBuffer = 64
+
+// httpReplayBuffer bounds the events an HTTP session keeps for clients that
+// resume a broken event stream with Last-Event-ID.
+const httpReplayBuffer = 256
+
+// Endpoint serves MCP sessions inside another program, which hands it
+// connections (ServeConn) or mounts it on its own HTTP mux (ServeHTTP), instead
+// of running the standalone binary. Every session is created by newSession,
+// just like the sessions of -listen.
+type Endpoint struct {
+	newSession func(transport.Transport) *Server
+	logger     *utils.Logger
+	sessions   *sessionSet
+	profiles   *profileSet   // Chooses HTTP sessions' profiles by bearer token, nil for none; see profiles.go
+	origins    []string      // Browser origins allowed by CORS, "*" for any; see allowOrigin
+	tenancy    tenancy       // How sessions are scoped per tenant, see tenants.go
+	store      *sessionStore // Keeps HTTP sessions for resumption, nil for none; see sessions.go
+	maxMessage int           // Largest message in bytes a client may send, transport.DefaultMaxLineSize if 0
+
+	mu   sync.Mutex
+	http map[string]*httpSession // Streamable HTTP sessions by Mcp-Session-Id
+}
+
+// NewEndpoint returns an endpoint whose sessions are created by newSession.
+func NewEndpoint(newSession func(transport.Transport) *Server, logger *utils.Logger) *Endpoint {
+	return &Endpoint{
+		newSession: newSession,
+		logger:     logger,
+		sessions:   &sessionSet{sessions: make(map[*Server]struct{})},
+		http:       make(map[string]*httpSession),
+	}
+}
+
+// ServeConn runs one session over conn with newline-delimited JSON and closes
+// conn when it ends. The session ends when the client disconnects, or drains
+// when ctx is canceled: requests in flight complete, new ones are refused.
+func (e *Endpoint) ServeConn(ctx context.Context, conn io.ReadWriteCloser) error {
+	t := transport.NewStream(conn, conn)
+	if e.maxMessage > 0 {
+		t.MaxLineSize = e.maxMessage
+	}
+	defer t.Close()
+	server := e.newSession(t)
+	server.setTenancy(e.tenancy, "")
+	e.sessions.add(server)
+	defer e.sessions.remove(server)
+	stop := context.AfterFunc(ctx, func() { server.Drain(context.Cause(ctx).Error()) })
+	defer stop()
+	return server.Run()
+}
+
+// Drain drains every open session, and every session started later.
+func (e *Endpoint) Drain(reason string) {
+	e.sessions.drainAll(reason)
+}
+
+// Shutdown drains every session, as Drain, and waits until the Streamable
+// HTTP sessions have ended or ctx is done. Keep serving HTTP until it returns:
+// draining sessions still answer the requests they have.
+func (e *Endpoint) Shutdown(ctx context.Context, reason string) error {
+	e.Drain(reason)
+	poll := time.NewTicker(10 * time.Millisecond)
+	defer poll.Stop()
+	for {
+		e.mu.Lock()
+		running := len(e.http) // Sessions leave it once saved, see runHTTPSession
+		e.mu.Unlock()
+		if running == 0 {
+			return nil
+		}
+		select {
+		case <-poll.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// maxMessageSize returns the largest message a client may send.
+func (e *Endpoint) maxMessageSize() int {
+	if e.maxMessage > 0 {
+		return e.maxMessage
+	}
+	return transport.DefaultMaxLineSize
+}
+
+// ServeHTTP serves the MCP Streamable HTTP transport, see transport.HTTP:
+//
+//	POST    a message or a JSON-RPC batch; requests are answered with their
+//	        JSON responses or an event stream (see answer), notifications
+//	        and responses with 202 Accepted
+//	GET     an event stream of server-initiated messages
+//	DELETE  ends the session
+//
+// The initialize request starts a session, whose ID is returned in the
+// Mcp-Session-Id header and must be sent with every later request. With a
+// session store, a session that stopped running is resumed by the next request
+// naming it (see sessions.go), until DELETE ends it for good.
+//
+// A request carrying an Origin header that is not allowed is refused with 403,
+// so that a web page cannot drive a local server through DNS rebinding.
+func (e *Endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !e.allowOrigin(w, r) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+	if r.Method == http.MethodOptions && r.Header.Get("Origin") != "" {
+		w.WriteHeader(http.StatusNoContent) // CORS preflight
+		return
+	}
+	switch r.Method {
+	case http.MethodPost:
+		e.post(w, r)
+	case http.MethodGet:
+		if session := e.lookup(w, r); session != nil {
+			session.stream(w, r)
+		}
+	case http.MethodDelete:
+		if session := e.lookup(w, r); session != nil {
+			session.deleted.Store(true)
+			session.Close() // Run sees EOF and the session ends
+			if e.store != nil {
+				if err := e.store.remove(session.id); err != nil {
+					e.logger.Printf("INFO", "HTTP session %s: %v", session.id, err)
+				}
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// allowOrigin reports whether the request may be served: it has no Origin
+// header, as with clients other than browsers, or comes from an allowed
+// origin. For those it sets the CORS headers that let the page use the
+// endpoint.
+func (e *Endpoint) allowOrigin(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if !slices.ContainsFunc(e.origins, func(o string) bool { return o == "*" || o == origin }) {
+		return false
+	}
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	w.Header().Add("Vary", "Origin")
+	w.Header().Set("Access-Control-Expose-Headers", transport.HeaderSessionID)
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE")
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join([]string{"Content-Type", "Authorization", transport.HeaderSessionID, transport.HeaderLastEventID}, ", "))
+	}
+	return true
+}
+
+// post delivers a POSTed message, or a batch of them, to its session, starting
+// one for initialize.
+func (e *Endpoint) post(w http.ResponseWriter, r *http.Request) {
+	limit := e.maxMessageSize()
+	payload, err := io.ReadAll(io.LimitReader(r.Body, int64(limit)+1))
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+	if len(payload) > limit {
+		http.Error(w, fmt.Sprintf("message exceeds the maximum of %d bytes", limit), http.StatusRequestEntityTooLarge)
+		return
+	}
+	messages, batch, err := splitBatch(payload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var ids []mcp.RequestID // Of the requests among the messages
+	initialize := false
+	for _, message := range messages {
+		info, err := mcp.ClassifyMessage(message)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if info.Kind == mcp.KindRequest {
+			if slices.ContainsFunc(ids, func(id mcp.RequestID) bool { return requestIDKey(id) == requestIDKey(info.ID) }) {
+				http.Error(w, fmt.Sprintf("duplicate request ID %v in batch", info.ID), http.StatusBadRequest)
+				return
+			}
+			ids = append(ids, info.ID)
+			initialize = info.Method == mcp.MethodInitialize
+		}
+	}
+
+	var session *httpSession
+	if r.Header.Get(transport.HeaderSessionID) == "" && initialize && !batch {
+		principal, err := e.profiles.authenticate(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if session, err = e.startHTTPSession(principal); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	} else if session = e.lookup(w, r); session == nil {
+		return
+	}
+	w.Header().Set(transport.HeaderSessionID, session.id)
+
+	if len(ids) == 0 {
+		for _, message := range messages {
+			if !session.deliver(r.Context(), message) {
+				http.Error(w, "session closed", http.StatusNotFound)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	session.answer(w, r, messages, ids, batch)
+}
+
+// splitBatch returns the messages of a POST body holding one message or a
+// JSON-RPC batch, and whether it was a batch.
+func splitBatch(payload []byte) ([][]byte, bool, error) {
+	trimmed := bytes.TrimSpace(payload)
+	if len(trimmed) == 0 || trimmed[0] != '[' {
+		return [][]byte{payload}, false, nil
+	}
+	var batch []json.RawMessage
+	if err := json.Unmarshal(trimmed, &batch); err != nil {
+		return nil, true, fmt.Errorf("invalid JSON-RPC batch: %w", err)
+	}
+	if len(batch) == 0 {
+		return nil, true, errors.New("empty JSON-RPC batch")
+	}
+	messages := make([][]byte, len(batch))
+	for i, message := range batch {
+		messages[i] = message
+	}
+	return messages, true, nil
+}
+
+// acceptsEventStream reports whether the client may be answered with an event stream.
+func acceptsEventStream(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, mediaRange := range strings.Split(accept, ",") {
+			if mediaType, _, _ := mime.ParseMediaType(mediaRange); mediaType == "text/event-stream" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// lookup returns the session named by the request's Mcp-Session-Id header, or
+// answers the request with an error and returns nil.
+func (e *Endpoint) lookup(w http.ResponseWriter, r *http.Request) *httpSession {
+	id := r.Header.Get(transport.HeaderSessionID)
+	if id == "" {
+		http.Error(w, "missing "+transport.HeaderSessionID+" header; send initialize first", http.StatusBadRequest)
+		return nil
+	}
+	principal, authErr := e.profiles.authenticate(r)
+	e.mu.Lock()
+	session := e.http[id]
+	if session == nil && authErr == nil {
+		session = e.resumeHTTPSession(id, principal)
+	}
+	e.mu.Unlock()
+	if session == nil {
+		// The client must initialize again, see transport.ErrSessionExpired
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return nil
+	}
+	// A session stays with the principal that started it
+	if authErr != nil || principal != session.principal {
+		http.Error(w, "session belongs to another principal", http.StatusForbidden)
+		return nil
+	}
+	return session
+}
+
+// resumeHTTPSession runs the saved session id again, if the store has it and
+// principal started it, and returns it; otherwise it returns nil. The caller
+// holds e.mu.
+func (e *Endpoint) resumeHTTPSession(id, principal string) *httpSession {
+	if e.store == nil {
+		return nil
+	}
+	saved, ok := e.store.get(id)
+	if !ok || saved.Principal != principal {
+		return nil
+	}
+	e.logger.Printf("DEBUG", "HTTP session %s resumed", id)
+	return e.runHTTPSession(id, principal, &saved)
+}
+
+// startHTTPSession creates a session for principal ("" for an anonymous
+// client) and runs it until it ends.
+func (e *Endpoint) startHTTPSession(principal string) (*httpSession, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to create session ID: %w", err)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	session := e.runHTTPSession(hex.EncodeToString(id), principal, nil)
+	e.logger.Printf("DEBUG", "HTTP session %s started", session.id)
+	return session, nil
+}
+
+// runHTTPSession creates the session id for principal, restored from saved
+// unless it is nil, and runs it until it ends. The caller holds e.mu.
+func (e *Endpoint) runHTTPSession(id, principal string, saved *savedSession) *httpSession {
+	session := &httpSession{
+		id:        id,
+		principal: principal,
+		logger:    e.logger,
+		incoming:  make(chan []byte),
+		events:    make(chan []byte, httpEventBuffer),
+		done:      make(chan struct{}),
+		waiting:   make(map[string]chan []byte),
+		open:      make(map[int]bool),
+		logged:    make(chan struct{}),
+	}
+	server := e.newSession(session)
+	server.applyProfile(e.profiles.choose(profileTransportHTTP, principal))
+	server.setTenancy(e.tenancy, principal)
+	server.resumption = resumption{store: e.store, id: id, principal: principal}
+	if saved != nil {
+		server.restoreSession(*saved)
+	}
+	e.http[session.id] = session
+	e.sessions.add(server)
+
+	go func() {
+		if err := server.Run(); err != nil {
+			e.logger.Printf("DEBUG", "HTTP session %s ended with error: %v", session.id, err)
+		}
+		if server.resumption.saved && !session.deleted.Load() {
+			server.saveSession() // Resumable for the store's TTL from now on
+		}
+		e.sessions.remove(server)
+		e.mu.Lock()
+		delete(e.http, session.id)
+		e.mu.Unlock()
+		session.Close()
+		e.logger.Printf("DEBUG", "HTTP session %s closed", session.id)
+	}()
+	return session
+}
+
+// httpSession is the transport of a session served over Streamable HTTP.
+// POSTed messages are read by the server; responses go back to the POST that
+// carried their request, everything else to the GET event stream, or while
+// none is open, to a POST that is upgraded to an event stream.
+//
+// Every event carries an ID, "<stream>-<seq>": stream 0 is the GET stream,
+// and each POST upgraded to an event stream gets its own number. The last
+// httpReplayBuffer events are kept, so a client whose stream breaks can GET
+// with Last-Event-ID and be sent what it missed (see resume). A POST stream
+// whose client disconnects keeps collecting its responses for the resumption.
+type httpSession struct {
+	id        string
+	principal string // Bearer token holder that started the session, see profiles.go
+	logger    *utils.Logger
+	incoming  chan []byte   // POSTed messages, read by ReadMessage
+	events    chan []byte   // Server-initiated messages for the event stream
+	done      chan struct{} // Closed by Close
+	once      sync.Once
+	deleted   atomic.Bool // Ended by the client with DELETE, so not resumable
+
+	mu        sync.Mutex
+	waiting   map[string]chan []byte // POSTs awaiting the response to their request, by ID
+	listeners int                    // Open GET event streams
+	streams   int                    // POST event streams started, numbered from 1
+	open      map[int]bool           // POST event streams still awaiting responses
+	sent      []sentEvent            // The last httpReplayBuffer events, oldest first
+	seq       uint64                 // Of the last event sent
+	evicted   uint64                 // Seq of the last event dropped from sent
+	logged    chan struct{}          // Closed and replaced when an event is sent or a POST stream completes
+}
+
+// sentEvent is an event kept for replay.
+type sentEvent struct {
+	stream  int
+	seq     uint64
+	payload []byte
+}
+
+func (ev sentEvent) id() string {
+	return fmt.Sprintf("%d-%d", ev.stream, ev.seq)
+}
+
+// parseEventID parses a Last-Event-ID sent back by a client.
+func parseEventID(id string) (stream int, seq uint64, err error) {
+	s, q, ok := strings.Cut(id, "-")
+	if ok {
+		if stream, err = strconv.Atoi(s); err == nil && stream >= 0 {
+			if seq, err = strconv.ParseUint(q, 10, 64); err == nil {
+				return stream, seq, nil
+			}
+		}
+	}
+	return 0, 0, fmt.Errorf("invalid %s %q", transport.HeaderLastEventID, id)
+}
+
+// record keeps an event sent on stream for replay and returns it.
+func (h *httpSession) record(stream int, payload []byte) sentEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.seq++
+	ev := sentEvent{stream: stream, seq: h.seq, payload: payload}
+	h.sent = append(h.sent, ev)
+	if len(h.sent) > httpReplayBuffer {
+		h.evicted = h.sent[0].seq
+		h.sent = slices.Delete(h.sent, 0, 1)
+	}
+	h.notify()
+	return ev
+}
+
+// notify wakes the resumptions waiting for events. Called with mu held.
+func (h *httpSession) notify() {
+	close(h.logged)
+	h.logged = make(chan struct{})
+}
+
+// startPostStream numbers a POST upgraded to an event stream; end must be
+// called once all its responses are sent.
+func (h *httpSession) startPostStream() (stream int, end func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.streams++
+	stream = h.streams
+	h.open[stream] = true
+	return stream, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.open, stream)
+		h.notify()
+	}
+}
+
+// deliver passes a POSTed message to the server. It reports false if the
+// session or the request ended first.
+func (h *httpSession) deliver(ctx context.Context, payload []byte) bool {
+	select {
+	case h.incoming <- payload:
+		return true
+	case <-h.done:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// answer delivers messages and writes the responses to the requests among
+// them, identified by ids. The responses are sent as JSON, a batch as an array,
+// unless a server-initiated message turns up while they are awaited and no
+// GET event stream is open to take it. If the client accepts one, the response
+// is then upgraded to an event stream carrying such messages and, last, the
+// responses, so that e.g. an elicitation during a tool call reaches a client
+// that never opened a GET stream.
+//
+// A request whose ID another POST still awaits is not delivered, as the two
+// responses could not be told apart; it is answered with an invalid request
+// error instead.
+func (h *httpSession) answer(w http.ResponseWriter, r *http.Request, messages [][]byte, ids []mcp.RequestID, batch bool) {
+	replies := make(chan []byte, len(ids))
+	var responses []json.RawMessage
+	refused := make(map[string]bool)
+	h.mu.Lock()
+	for _, id := range ids {
+		if _, ok := h.waiting[requestIDKey(id)]; ok {
+			rpcErr := mcp.NewRPCError(mcp.ErrorCodeInvalidRequest, fmt.Sprintf("Duplicate request ID %v", id), nil)
+			responses = append(responses, mcp.MustErrorResponse(id, rpcErr))
+			refused[requestIDKey(id)] = true
+			continue
+		}
+		h.waiting[requestIDKey(id)] = replies
+	}
+	var events <-chan []byte
+	if h.listeners == 0 && acceptsEventStream(r) {
+		events = h.events
+	}
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		for _, id := range ids {
+			if h.waiting[requestIDKey(id)] == replies {
+				delete(h.waiting, requestIDKey(id))
+			}
+		}
+		h.mu.Unlock()
+	}()
+
+	for _, message := range messages {
+		if info, err := mcp.ClassifyMessage(message); err == nil && info.Kind == mcp.KindRequest && refused[requestIDKey(info.ID)] {
+			continue
+		}
+		if !h.deliver(r.Context(), message) {
+			http.Error(w, "session closed before answering", http.StatusServiceUnavailable)
+			return
+		}
+	}
+	stream := 0 // Of the event stream, once the response is upgraded to one
+	gone := r.Context().Done()
+	send := func(payload []byte) {
+		ev := h.record(stream, payload)
+		if gone != nil && writeEvent(w, ev.id(), payload) != nil {
+			gone, events = nil, nil // Kept for the client to resume
+		}
+	}
+	for len(responses) < len(ids) {
+		select {
+		case response := <-replies:
+			responses = append(responses, response)
+			if stream != 0 {
+				send(response)
+			}
+		case event := <-events:
+			if stream == 0 {
+				var end func()
+				stream, end = h.startPostStream()
+				defer end()
+				startEventStream(w)
+			}
+			send(event)
+		case <-h.done:
+			if stream == 0 {
+				http.Error(w, "session closed before answering", http.StatusServiceUnavailable)
+			}
+			return
+		case <-gone:
+			if stream == 0 {
+				// Nothing to resume: the responses so far, and the rest once
+				// they are no longer awaited, go to the GET stream instead
+				for _, response := range responses {
+					h.queue(response)
+				}
+				return
+			}
+			// Keep collecting the responses for the client to resume
+			gone, events = nil, nil
+		}
+	}
+	if stream != 0 {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if !batch {
+		w.Write(responses[0])
+		return
+	}
+	body, _ := json.Marshal(responses)
+	w.Write(body)
+}
+
+// startEventStream sends the headers of an event stream.
+func startEventStream(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// writeEvent sends one message as an event and flushes it to the client.
+func writeEvent(w http.ResponseWriter, id string, payload []byte) error {
+	if _, err := fmt.Fprintf(w, "id: %s\ndata: %s\n\n", id, payload); err != nil {
+		return err
+	}
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// stream sends server-initiated messages as an event stream until the client
+// disconnects or the session ends. With Last-Event-ID, the events the client
+// missed are replayed first; resuming a POST's event stream ends once its
+// responses have been sent.
+func (h *httpSession) stream(w http.ResponseWriter, r *http.Request) {
+	resumed, after, resuming := 0, uint64(0), false
+	if last := r.Header.Get(transport.HeaderLastEventID); last != "" {
+		var err error
+		if resumed, after, err = parseEventID(last); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.mu.Lock()
+		unknown := resumed > h.streams || after > h.seq
+		h.mu.Unlock()
+		if unknown {
+			http.Error(w, "unknown "+transport.HeaderLastEventID+" "+last, http.StatusBadRequest)
+			return
+		}
+		resuming = true
+	}
+	startEventStream(w)
+	if resuming {
+		if h.resume(w, r, resumed, after) != nil || resumed != 0 {
+			return
+		}
+	}
+
+	h.mu.Lock()
+	h.listeners++
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		h.listeners--
+		h.mu.Unlock()
+	}()
+	for {
+		select {
+		case payload := <-h.events:
+			if writeEvent(w, h.record(0, payload).id(), payload) != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		case <-h.done:
+			return
+		}
+	}
+}
+
+// resume replays the events of stream sent after seq and, while it is a POST
+// stream awaiting responses, follows it until it completes.
+func (h *httpSession) resume(w http.ResponseWriter, r *http.Request, stream int, seq uint64) error {
+	h.mu.Lock()
+	if seq < h.evicted {
+		h.logger.Printf("DEBUG", "HTTP session %s: events after %d-%d are partly gone from the replay buffer", h.id, stream, seq)
+	}
+	h.mu.Unlock()
+	for {
+		h.mu.Lock()
+		var missed []sentEvent
+		for _, ev := range h.sent {
+			if ev.stream == stream && ev.seq > seq {
+				missed = append(missed, ev)
+			}
+		}
+		open, logged := h.open[stream], h.logged
+		h.mu.Unlock()
+		for _, ev := range missed {
+			if err := writeEvent(w, ev.id(), ev.payload); err != nil {
+				return err
+			}
+			seq = ev.seq
+		}
+		if !open {
+			return nil
+		}
+		select {
+		case <-logged:
+		case <-r.Context().Done():
+			return r.Context().Err()
+		case <-h.done:
+			return io.EOF
+		}
+	}
+}
+
+// ReadMessage returns the next POSTed message, or io.EOF once the session is closed.
+func (h *httpSession) ReadMessage() ([]byte, error) {
+	select {
+	case payload := <-h.incoming:
+		return payload, nil
+	case <-h.done:
+		return nil, io.EOF
+	}
+}
+
+// WriteMessage routes a message from the server: a response to the POST
+// waiting for it, anything else to the event stream. Messages for an event
+// stream that nobody has read for httpEventBuffer messages are dropped.
+func (h *httpSession) WriteMessage(payload []byte) error {
+	info, err := mcp.ClassifyMessage(payload)
+	if err == nil && (info.Kind == mcp.KindResponse || info.Kind == mcp.KindErrorResponse) {
+		// Each awaited response is taken once; a duplicate request ID's goes to the event stream
+		h.mu.Lock()
+		reply, ok := h.waiting[requestIDKey(info.ID)]
+		delete(h.waiting, requestIDKey(info.ID))
+		h.mu.Unlock()
+		if ok {
+			reply <- payload // Buffered for every awaited response
+			return nil
+		}
+	}
+	h.queue(payload)
+	return nil
+}
+
+// queue passes a message to the event stream, dropping it if nobody has read
+// the stream for httpEventBuffer messages.
+func (h *httpSession) queue(payload []byte) {
+	select {
+	case h.events <- payload:
+	default:
+		h.logger.Printf("DEBUG", "HTTP session %s: no event stream is reading, dropping %.200s", h.id, payload)
+	}
+}
+
+// Close ends the session: ReadMessage returns io.EOF and waiting POSTs give up.
+func (h *httpSession) Close() error {
+	h.once.Do(func() { close(h.done) })
+	return nil
+}
@@ -0,0 +1,444 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/transport"
+	"sqirvy/mcp/pkg/utils"
+)
+
+func newTestEndpoint() *Endpoint {
+	logger := utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo)
+	return NewEndpoint(func(t transport.Transport) *Server { return NewServer(t, logger) }, logger)
+}
+
+func TestServeConnDrainsOnCancel(t *testing.T) {
+	client, conn := net.Pipe()
+	defer client.Close()
+	ctx, cancel := context.WithCancelCause(context.Background())
+	done := make(chan error)
+	go func() { done <- newTestEndpoint().ServeConn(ctx, conn) }()
+
+	lines := bufio.NewScanner(client)
+	fmt.Fprintln(client, initializeRequest)
+	if !lines.Scan() || !strings.Contains(lines.Text(), `"serverInfo"`) {
+		t.Fatalf("initialize response = %q", lines.Text())
+	}
+	fmt.Fprintln(client, initializedNotify)
+
+	cancel(errors.New("application stopping"))
+	if !lines.Scan() {
+		t.Fatal("no shutdown notification")
+	}
+	var note wireMessage
+	if json.Unmarshal(lines.Bytes(), &note); note.Method != mcp.MethodShutdown || note.Params.Reason != "application stopping" {
+		t.Errorf("notification = %s", lines.Text())
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("ServeConn = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ServeConn did not return after the drain")
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	e := newTestEndpoint()
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+
+	client, err := transport.NewHTTP(srv.URL, transport.HTTPOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.WriteMessage([]byte(initializeRequest)); err != nil {
+		t.Fatal(err)
+	}
+	if m := readMessage(t, client); fmt.Sprint(m.ID) != "1" || m.Error != nil {
+		t.Fatalf("initialize = %+v", m)
+	}
+	session := client.SessionID()
+	if session == "" {
+		t.Fatal("no session ID")
+	}
+	if err := client.WriteMessage([]byte(initializedNotify)); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.WriteMessage([]byte(`{"jsonrpc":"2.0","id":"p","method":"ping"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if m := readMessage(t, client); m.ID != "p" || m.Error != nil {
+		t.Errorf("ping = %+v", m)
+	}
+
+	// Requests need the session; a closed session is gone
+	resp, err := http.Post(srv.URL, "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":2,"method":"ping"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("POST without a session = %s", resp.Status)
+	}
+	client.Close() // DELETEs the session
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(5 * time.Millisecond) {
+		e.mu.Lock()
+		open := len(e.http)
+		e.mu.Unlock()
+		if open == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("session %s still open after DELETE", session)
+		}
+	}
+}
+
+// readMessage reads the next message from tr.
+func readMessage(t *testing.T, tr transport.Transport) wireMessage {
+	t.Helper()
+	payload, err := tr.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m wireMessage
+	if err := json.Unmarshal(payload, &m); err != nil {
+		t.Fatalf("bad message %s: %v", payload, err)
+	}
+	return m
+}
+
+func TestServeHTTPAllowedOrigins(t *testing.T) {
+	e := newTestEndpoint()
+	e.origins = []string{"https://app.example.com"}
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+
+	do := func(method, origin, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, srv.URL, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Origin", origin)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := do(http.MethodOptions, "https://app.example.com", "")
+	if resp.StatusCode != http.StatusNoContent || resp.Header.Get("Access-Control-Allow-Origin") != "https://app.example.com" ||
+		!strings.Contains(resp.Header.Get("Access-Control-Allow-Headers"), transport.HeaderSessionID) {
+		t.Errorf("preflight = %s %v", resp.Status, resp.Header)
+	}
+	resp = do(http.MethodPost, "https://app.example.com", initializeRequest)
+	if resp.Header.Get("Access-Control-Expose-Headers") != transport.HeaderSessionID || resp.Header.Get(transport.HeaderSessionID) == "" {
+		t.Errorf("initialize from an allowed origin = %s %v", resp.Status, resp.Header)
+	}
+	if resp := do(http.MethodOptions, "https://evil.example.com", ""); resp.StatusCode != http.StatusForbidden || resp.Header.Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("preflight from another origin = %s %v, want 403", resp.Status, resp.Header)
+	}
+	// Other origins are refused before anything is dispatched, against DNS rebinding
+	for _, method := range []string{http.MethodPost, http.MethodGet, http.MethodDelete} {
+		if resp := do(method, "http://evil.example", initializeRequest); resp.StatusCode != http.StatusForbidden || resp.Header.Get(transport.HeaderSessionID) != "" {
+			t.Errorf("%s from another origin = %s, want 403", method, resp.Status)
+		}
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.http) != 1 {
+		t.Errorf("%d HTTP sessions, want only the one of the allowed origin", len(e.http))
+	}
+}
+
+func TestServeHTTPMaxMessageSize(t *testing.T) {
+	e := newTestEndpoint()
+	e.maxMessage = len(initializeRequest)
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+
+	if resp := postMCP(t, srv.URL, "", initializeRequest); resp.StatusCode != http.StatusOK {
+		t.Errorf("initialize of the maximum size = %s", resp.Status)
+	}
+	padded := strings.Replace(initializeRequest, `"test"`, `"test-client"`, 1)
+	if resp := postMCP(t, srv.URL, "", padded); resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("initialize over the maximum size = %s, want 413", resp.Status)
+	}
+}
+
+func TestEndpointShutdown(t *testing.T) {
+	e := newTestEndpoint()
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+	client, err := transport.NewHTTP(srv.URL, transport.HTTPOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if err := client.WriteMessage([]byte(initializeRequest)); err != nil {
+		t.Fatal(err)
+	}
+	readResponse(t, client, "1")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := e.Shutdown(ctx, "test"); err != nil {
+		t.Fatalf("Shutdown = %v", err)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.http) != 0 {
+		t.Errorf("%d HTTP sessions still running after Shutdown", len(e.http))
+	}
+}
+
+// postMCP POSTs body to an MCP endpoint in session, accepting JSON and event streams.
+func postMCP(t *testing.T, url, session, body string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if session != "" {
+		req.Header.Set(transport.HeaderSessionID, session)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestServeHTTPBatch(t *testing.T) {
+	srv := httptest.NewServer(newTestEndpoint())
+	defer srv.Close()
+	session := postMCP(t, srv.URL, "", initializeRequest).Header.Get(transport.HeaderSessionID)
+	postMCP(t, srv.URL, session, initializedNotify)
+
+	resp := postMCP(t, srv.URL, session, `[{"jsonrpc":"2.0","id":"a","method":"ping"},
+		{"jsonrpc":"2.0","method":"notifications/roots/list_changed"},
+		{"jsonrpc":"2.0","id":"b","method":"ping"}]`)
+	var batch []wireMessage
+	if err := json.NewDecoder(resp.Body).Decode(&batch); err != nil {
+		t.Fatalf("batch response: %v", err)
+	}
+	ids := map[string]bool{}
+	for _, m := range batch {
+		ids[fmt.Sprint(m.ID)] = m.Error == nil
+	}
+	if len(batch) != 2 || !ids["a"] || !ids["b"] {
+		t.Errorf("batch responses = %+v, want answers to a and b", batch)
+	}
+
+	if resp := postMCP(t, srv.URL, session, `[{"jsonrpc":"2.0","method":"notifications/roots/list_changed"}]`); resp.StatusCode != http.StatusAccepted {
+		t.Errorf("batch of notifications = %s, want 202", resp.Status)
+	}
+	for _, body := range []string{`[]`, `[{"jsonrpc":"2.0","id":1,"method":"ping"},{"jsonrpc":"2.0","id":1,"method":"ping"}]`} {
+		if resp := postMCP(t, srv.URL, session, body); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("batch %s = %s, want 400", body, resp.Status)
+		}
+	}
+}
+
+func TestServeHTTPDuplicateRequestID(t *testing.T) {
+	logger := utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo)
+	started, release := make(chan struct{}), make(chan struct{})
+	srv := httptest.NewServer(NewEndpoint(func(tr transport.Transport) *Server {
+		s := NewServer(tr, logger)
+		s.HandleMethod("x-test/wait", func(ctx context.Context, _ json.RawMessage) (interface{}, *mcp.RPCError) {
+			close(started)
+			<-release
+			return map[string]string{}, nil
+		})
+		return s
+	}, logger))
+	defer srv.Close()
+	unblock := sync.OnceFunc(func() { close(release) })
+	defer unblock() // Before srv.Close, which waits for the handlers
+	session := postMCP(t, srv.URL, "", initializeRequest).Header.Get(transport.HeaderSessionID)
+	postMCP(t, srv.URL, session, initializedNotify)
+
+	first := make(chan wireMessage, 1)
+	go func() {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader(`{"jsonrpc":"2.0","id":7,"method":"x-test/wait"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(transport.HeaderSessionID, session)
+		var m wireMessage
+		if resp, err := (&http.Client{Timeout: 5 * time.Second}).Do(req); err == nil {
+			json.NewDecoder(resp.Body).Decode(&m)
+			resp.Body.Close()
+		}
+		first <- m
+	}()
+	<-started
+
+	// The same ID while the first POST awaits its response
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader(`{"jsonrpc":"2.0","id":7,"method":"ping"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(transport.HeaderSessionID, session)
+	resp, err := (&http.Client{Timeout: 5 * time.Second}).Do(req)
+	if err != nil {
+		t.Fatalf("duplicate request: %v", err)
+	}
+	defer resp.Body.Close()
+	var dup wireMessage
+	if err := json.NewDecoder(resp.Body).Decode(&dup); err != nil || dup.Error == nil || dup.Error.Code != mcp.ErrorCodeInvalidRequest {
+		t.Errorf("duplicate request = %+v, %v; want an invalid request error", dup, err)
+	}
+	unblock()
+	select {
+	case m := <-first:
+		if m.ID != float64(7) || m.Error != nil {
+			t.Errorf("first request = %+v, want its result", m)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("the first POST was not answered")
+	}
+}
+
+// newConfirmEndpoint returns an endpoint whose sessions have a confirm tool
+// that asks the client for confirmation before it returns.
+func newConfirmEndpoint() *Endpoint {
+	logger := utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo)
+	confirm := &toolModule{name: "confirm", tools: []moduleTool{{
+		tool: mcp.Tool{Name: "confirm", InputSchema: mcp.ToolInputSchema{"type": "object"}},
+		call: func(ctx context.Context, s *Server, args map[string]interface{}) (mcp.CallToolResult, error) {
+			result, err := s.elicit(ctx, mcp.ElicitRequestParams{Message: "Proceed?"}, time.Second)
+			if err != nil {
+				return mcp.CallToolResult{}, err
+			}
+			return mcp.NewToolResultText(result.Action), nil
+		},
+	}}}
+	return NewEndpoint(func(t transport.Transport) *Server {
+		s := NewServer(t, logger)
+		s.modules = []*toolModule{confirm}
+		return s
+	}, logger)
+}
+
+// sseEvent is an event read by readEvents.
+type sseEvent struct {
+	id      string
+	message wireMessage
+}
+
+// readEvents returns a function reading the next message event from body.
+func readEvents(t *testing.T, body io.Reader) func() sseEvent {
+	events := bufio.NewScanner(body)
+	return func() sseEvent {
+		t.Helper()
+		var ev sseEvent
+		for events.Scan() {
+			if id, ok := strings.CutPrefix(events.Text(), "id: "); ok {
+				ev.id = id
+			}
+			if data, ok := strings.CutPrefix(events.Text(), "data: "); ok {
+				if err := json.Unmarshal([]byte(data), &ev.message); err != nil {
+					t.Fatal(err)
+				}
+				return ev
+			}
+		}
+		t.Fatalf("event stream ended: %v", events.Err())
+		return ev
+	}
+}
+
+func TestServeHTTPUpgradesToEventStream(t *testing.T) {
+	srv := httptest.NewServer(newConfirmEndpoint())
+	defer srv.Close()
+
+	init := strings.Replace(initializeRequest, `"capabilities":{}`, `"capabilities":{"elicitation":{}}`, 1)
+	session := postMCP(t, srv.URL, "", init).Header.Get(transport.HeaderSessionID)
+	postMCP(t, srv.URL, session, initializedNotify)
+
+	// Without a GET stream the elicitation arrives on the tool call's own response
+	resp := postMCP(t, srv.URL, session, `{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"confirm","arguments":{}}}`)
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("tools/call Content-Type = %q, want an event stream", ct)
+	}
+	next := readEvents(t, resp.Body)
+	elicitation := next().message
+	if elicitation.Method != mcp.MethodCreateElicitation {
+		t.Fatalf("first event = %+v, want an elicitation", elicitation)
+	}
+	reply := fmt.Sprintf(`{"jsonrpc":"2.0","id":%q,"result":{"action":"accept"}}`, elicitation.ID)
+	if resp := postMCP(t, srv.URL, session, reply); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("elicitation reply = %s", resp.Status)
+	}
+	if m := next().message; fmt.Sprint(m.ID) != "7" || m.Error != nil {
+		t.Errorf("second event = %+v, want the tool result", m)
+	}
+}
+
+func TestServeHTTPResumesEventStream(t *testing.T) {
+	srv := httptest.NewServer(newConfirmEndpoint())
+	defer srv.Close()
+	init := strings.Replace(initializeRequest, `"capabilities":{}`, `"capabilities":{"elicitation":{}}`, 1)
+	session := postMCP(t, srv.URL, "", init).Header.Get(transport.HeaderSessionID)
+	postMCP(t, srv.URL, session, initializedNotify)
+
+	// The connection drops after the elicitation, before the tool result
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL, strings.NewReader(`{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"confirm","arguments":{}}}`))
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	req.Header.Set(transport.HeaderSessionID, session)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	elicitation := readEvents(t, resp.Body)()
+	if elicitation.id == "" || elicitation.message.Method != mcp.MethodCreateElicitation {
+		t.Fatalf("first event = %+v, want an elicitation with an ID", elicitation)
+	}
+	cancel()
+	resp.Body.Close()
+	reply := fmt.Sprintf(`{"jsonrpc":"2.0","id":%q,"result":{"action":"accept"}}`, elicitation.message.ID)
+	if resp := postMCP(t, srv.URL, session, reply); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("elicitation reply = %s", resp.Status)
+	}
+
+	resume := func(lastEventID string) *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		req.Header.Set("Accept", "text/event-stream")
+		req.Header.Set(transport.HeaderSessionID, session)
+		req.Header.Set(transport.HeaderLastEventID, lastEventID)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+	resp = resume(elicitation.id)
+	if result := readEvents(t, resp.Body)(); fmt.Sprint(result.message.ID) != "7" || result.message.Error != nil {
+		t.Errorf("resumed event = %+v, want the tool result", result)
+	}
+	if rest, err := io.ReadAll(resp.Body); err != nil || len(rest) != 0 {
+		t.Errorf("resumed POST stream did not end after its response: %q, %v", rest, err)
+	}
+
+	for _, id := range []string{"bogus", "99-1"} {
+		if resp := resume(id); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Last-Event-ID %s = %s, want 400", id, resp.Status)
+		}
+	}
+}
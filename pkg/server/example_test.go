@@ -0,0 +1,51 @@
+package server_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+
+	"sqirvy/mcp/pkg/server"
+	"sqirvy/mcp/pkg/transport"
+	"sqirvy/mcp/pkg/utils"
+)
+
+// A program embeds the server by creating an Endpoint, then hands it
+// connections or mounts it on its own HTTP mux.
+func ExampleEndpoint() {
+	logger := utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo)
+	endpoint := server.NewEndpoint(func(t transport.Transport) *server.Server {
+		return server.NewServer(t, logger)
+	}, logger)
+
+	// Streamable HTTP for the program's own clients
+	mux := http.NewServeMux()
+	mux.Handle("/mcp", endpoint)
+
+	// A session over a connection, here one end of a pipe whose other end is the client
+	client, conn := net.Pipe()
+	ctx, cancel := context.WithCancelCause(context.Background())
+	done := make(chan error)
+	go func() { done <- endpoint.ServeConn(ctx, conn) }()
+
+	replies := bufio.NewScanner(client)
+	fmt.Fprintln(client, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"example","version":"1"}}}`)
+	replies.Scan()
+	fmt.Fprintln(client, `{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+	fmt.Fprintln(client, `{"jsonrpc":"2.0","id":2,"method":"ping"}`)
+	replies.Scan()
+	fmt.Println(replies.Text())
+
+	// Stopping the program drains the session, which then ends
+	go io.Copy(io.Discard, client)
+	cancel(errors.New("program stopping"))
+	fmt.Println(<-done)
+	// Output:
+	// {"jsonrpc":"2.0","result":{},"id":2}
+	// <nil>
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"sort"
+
+	"sqirvy/mcp/pkg/mcp"
+)
+
+// RegisterExperimental installs the negotiator for an experimental capability.
+// During initialize each registered negotiator sees the client's settings for its
+// feature and decides what, if anything, the server advertises under
+// capabilities.experimental; see mcp.ExperimentalNegotiator. Extensions use this to
+// add custom features without changing the core capability types.
+// It must be called before Run.
+func (s *Server) RegisterExperimental(name string, negotiate mcp.ExperimentalNegotiator) {
+	s.experimental[name] = negotiate
+}
+
+// negotiateExperimental runs the registered negotiators against the client's
+// experimental capabilities and returns the server's side, or nil if empty.
+func (s *Server) negotiateExperimental(client mcp.Experimental) mcp.Experimental {
+	names := make([]string, 0, len(s.experimental))
+	for name := range s.experimental {
+		names = append(names, name)
+	}
+	sort.Strings(names) // Deterministic log output
+
+	var server mcp.Experimental
+	for _, name := range names {
+		settings, ok := s.experimental[name](client[name])
+		if !ok {
+			s.logger.Printf("DEBUG", "Experimental capability '%s' not enabled for this session", name)
+			continue
+		}
+		if err := server.Set(name, settings); err != nil {
+			s.logger.Printf("DEBUG", "Dropping experimental capability '%s': %v", name, err)
+			continue
+		}
+		s.logger.Printf("DEBUG", "Experimental capability '%s' enabled: %s", name, server[name])
+	}
+	return server
+}
@@ -0,0 +1,239 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"sqirvy/mcp/pkg/mcp"
+)
+
+// Feature flags gate optional subsystems at runtime.
+const (
+	// featureSampling allows sampling requests to the client and the tools that
+	// rely on them (summarize).
+	featureSampling = "sampling"
+	// featureDestructiveTools allows tools that change something outside the
+	// server, such as sending a webhook message or writing the clipboard.
+	featureDestructiveTools = "destructive_tools"
+)
+
+// defaultFeatures lists every feature flag with its default.
+var defaultFeatures = map[string]bool{
+	featureSampling:         true,
+	featureDestructiveTools: true,
+}
+
+// featureEnvPrefix is prepended to the upper-cased flag name to override a
+// flag from the environment, e.g. MCP_FEATURE_SAMPLING=off.
+const featureEnvPrefix = "MCP_FEATURE_"
+
+// featureFlags holds the current feature flags. Like the tool limits they are
+// process-wide: a change applies to every session, and each session is told
+// through its watcher so it can announce the changed tool list.
+type featureFlags struct {
+	mu       sync.Mutex
+	flags    map[string]bool
+	watchers map[int]func()
+	next     int // Key of the next watcher
+}
+
+// newFeatureFlags creates flags with the defaults.
+func newFeatureFlags() *featureFlags {
+	f := &featureFlags{flags: make(map[string]bool, len(defaultFeatures)), watchers: make(map[int]func())}
+	for name, on := range defaultFeatures {
+		f.flags[name] = on
+	}
+	return f
+}
+
+// loadFeatureFlags returns the defaults overridden by the JSON object in path
+// (e.g. {"sampling": false}), if path is not empty, and then by the
+// environment.
+func loadFeatureFlags(path string, environ []string) (*featureFlags, error) {
+	f := newFeatureFlags()
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read feature flags: %w", err)
+		}
+		var config map[string]bool
+		if err := json.Unmarshal(data, &config); err != nil {
+			return nil, fmt.Errorf("invalid feature flags file %s: %w", path, err)
+		}
+		for name, on := range config {
+			if _, ok := f.flags[name]; !ok {
+				return nil, fmt.Errorf("unknown feature %q in %s (known: %s)", name, path, strings.Join(sortedFeatureNames(), ", "))
+			}
+			f.flags[name] = on
+		}
+	}
+	for _, kv := range environ {
+		key, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(key, featureEnvPrefix) {
+			continue
+		}
+		name := strings.ToLower(strings.TrimPrefix(key, featureEnvPrefix))
+		if _, ok := f.flags[name]; !ok {
+			return nil, fmt.Errorf("unknown feature %q in %s", name, key)
+		}
+		on, err := parseFeatureValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", key, err)
+		}
+		f.flags[name] = on
+	}
+	return f, nil
+}
+
+// parseFeatureValue parses on/off, true/false or 1/0.
+func parseFeatureValue(s string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "1", "on", "true", "yes":
+		return true, nil
+	case "0", "off", "false", "no":
+		return false, nil
+	}
+	return false, fmt.Errorf("%q is not on or off", s)
+}
+
+// enabled reports whether the named feature is on.
+func (f *featureFlags) enabled(name string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.flags[name]
+}
+
+// set turns a feature on or off and tells the watchers if that changed it.
+func (f *featureFlags) set(name string, on bool) error {
+	f.mu.Lock()
+	current, ok := f.flags[name]
+	if !ok {
+		f.mu.Unlock()
+		return fmt.Errorf("unknown feature %q (known: %s)", name, strings.Join(sortedFeatureNames(), ", "))
+	}
+	f.flags[name] = on
+	var watchers []func()
+	if current != on {
+		for _, w := range f.watchers {
+			watchers = append(watchers, w)
+		}
+	}
+	f.mu.Unlock()
+	for _, w := range watchers {
+		w()
+	}
+	return nil
+}
+
+// snapshot returns a copy of the flags.
+func (f *featureFlags) snapshot() map[string]bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make(map[string]bool, len(f.flags))
+	for name, on := range f.flags {
+		out[name] = on
+	}
+	return out
+}
+
+// watch calls fn after every change to a flag, until the returned function
+// is called. fn must not block.
+func (f *featureFlags) watch(fn func()) (cancel func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := f.next
+	f.next++
+	f.watchers[key] = fn
+	return func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		delete(f.watchers, key)
+	}
+}
+
+// toolFeature returns the feature flag that gates the named tool, or "".
+func (s *Server) toolFeature(name string) string {
+	if name == summarizeToolName {
+		return featureSampling
+	}
+	if t, ok := s.moduleTool(name); ok && t.destructive {
+		return featureDestructiveTools
+	}
+	return ""
+}
+
+// toolEnabled reports whether the feature flags allow the named tool.
+func (s *Server) toolEnabled(name string) bool {
+	if !s.profile.allowsTool(name) {
+		return false
+	}
+	feature := s.toolFeature(name)
+	return feature == "" || s.features.enabled(feature)
+}
+
+// featureAdminModule returns the feature_flags tool, which lists the feature
+// flags and switches them for every session.
+func featureAdminModule(features *featureFlags) *toolModule {
+	return &toolModule{
+		name: "features",
+		tools: []moduleTool{
+			{
+				tool: mcp.Tool{
+					Name:  "feature_flags",
+					Title: "Feature Flags",
+					Description: "Lists the server's feature flags, or with name and enabled, turns one on or off for " +
+						"every session. Clients are told when this changes the tool list.",
+					InputSchema: mcp.ToolInputSchema{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"name":    map[string]interface{}{"type": "string", "description": "Feature to change", "enum": sortedFeatureNames()},
+							"enabled": map[string]interface{}{"type": "boolean", "description": "New setting of the feature"},
+						},
+					},
+				},
+				call: func(_ context.Context, _ *Server, raw map[string]interface{}) (mcp.CallToolResult, error) {
+					var args struct {
+						Name    string `json:"name"`
+						Enabled *bool  `json:"enabled"`
+					}
+					if err := decodeToolArgs(raw, &args); err != nil {
+						return mcp.CallToolResult{}, err
+					}
+					if args.Name != "" {
+						if args.Enabled == nil {
+							return mcp.CallToolResult{}, fmt.Errorf("enabled is required with name")
+						}
+						if err := features.set(args.Name, *args.Enabled); err != nil {
+							return mcp.CallToolResult{}, err
+						}
+					}
+					flags := features.snapshot()
+					lines := make([]string, 0, len(flags))
+					for _, name := range sortedFeatureNames() {
+						state := "off"
+						if flags[name] {
+							state = "on"
+						}
+						lines = append(lines, name+": "+state)
+					}
+					return mcp.NewToolResultText(strings.Join(lines, "\n")), nil
+				},
+			},
+		},
+	}
+}
+
+// sortedFeatureNames returns the names of all feature flags in sorted order.
+func sortedFeatureNames() []string {
+	names := make([]string, 0, len(defaultFeatures))
+	for name := range defaultFeatures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
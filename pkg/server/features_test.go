@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/utils"
+)
+
+func TestLoadFeatureFlags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "features.json")
+	os.WriteFile(path, []byte(`{"sampling": false, "destructive_tools": false}`), 0o644)
+	f, err := loadFeatureFlags(path, []string{"HOME=/root", "MCP_FEATURE_DESTRUCTIVE_TOOLS=on"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.enabled(featureSampling) || !f.enabled(featureDestructiveTools) {
+		t.Errorf("flags = %v, want sampling off from the file and destructive_tools on from the environment", f.snapshot())
+	}
+	os.WriteFile(path, []byte(`{"subscriptions": true}`), 0o644)
+	if _, err := loadFeatureFlags(path, nil); err == nil {
+		t.Error("unknown feature in the file accepted")
+	}
+	for _, env := range []string{"MCP_FEATURE_NOPE=on", "MCP_FEATURE_SAMPLING=maybe"} {
+		if _, err := loadFeatureFlags("", []string{env}); err == nil {
+			t.Errorf("%s accepted", env)
+		}
+	}
+}
+
+func TestFeatureFlagsGateTools(t *testing.T) {
+	tr := &chanTransport{captureTransport: captureTransport{written: make(chan []byte, 16)}, in: make(chan []byte)}
+	defer close(tr.in)
+	features := newFeatureFlags()
+	s := NewServer(tr, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	s.features = features
+	s.featureAdmin = true
+	s.modules = []*toolModule{featureAdminModule(features), {name: "writer", tools: []moduleTool{{
+		tool:        mcp.Tool{Name: "write"},
+		destructive: true,
+		call: func(context.Context, *Server, map[string]interface{}) (mcp.CallToolResult, error) {
+			return mcp.NewToolResultText("written"), nil
+		},
+	}}}}
+	go s.Run()
+	tr.in <- []byte(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{"sampling":{}},"clientInfo":{"name":"test","version":"0"}}}`)
+	readWire(t, tr.written)
+	tr.in <- []byte(initializedNotify)
+
+	listed := func() map[string]bool {
+		names := make(map[string]bool)
+		for _, tool := range s.listTools() {
+			names[tool.Name] = true
+		}
+		return names
+	}
+	if names := listed(); !names["write"] || !names[summarizeToolName] {
+		t.Errorf("tools with every feature on = %v", names)
+	}
+
+	tr.in <- []byte(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"feature_flags","arguments":{"name":"destructive_tools","enabled":false}}}`)
+	first, second := readWire(t, tr.written), readWire(t, tr.written)
+	if first.ID == nil {
+		first, second = second, first // The notification may overtake the response
+	}
+	if second.Method != mcp.MethodToolListChanged {
+		t.Errorf("after a flag change got %+v, want tools/list_changed", second)
+	}
+	if names := listed(); names["write"] || !names[summarizeToolName] {
+		t.Errorf("tools with destructive_tools off = %v", names)
+	}
+	tr.in <- []byte(`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"write","arguments":{}}}`)
+	if m := readWire(t, tr.written); m.Error == nil || m.Error.Code != mcp.ErrorCodeMethodNotFound {
+		t.Errorf("disabled tool call = %+v, want method not found", m)
+	}
+
+	// Setting a flag to its current value changes nothing and is not announced
+	if err := features.set(featureDestructiveTools, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := features.set("nope", true); err == nil {
+		t.Error("set accepted an unknown feature")
+	}
+	features.set(featureSampling, false)
+	if m := readWire(t, tr.written); m.Method != mcp.MethodToolListChanged || listed()[summarizeToolName] {
+		t.Errorf("turning sampling off = %+v", m)
+	}
+	if _, err := s.createMessage(context.Background(), mcp.CreateMessageParams{}, 0); err == nil || err.Error() != "sampling is disabled on this server" {
+		t.Errorf("createMessage with sampling off = %v", err)
+	}
+}
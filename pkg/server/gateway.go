@@ -0,0 +1,347 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/transport"
+	"sqirvy/mcp/pkg/utils"
+)
+
+// gatewayMaxBody bounds the size of HTTP request bodies accepted by the gateway.
+const gatewayMaxBody = 1 << 20
+
+// gateway exposes the server's tools, resources and prompts over plain HTTP for
+// hosts that do not speak MCP yet. Every HTTP request is translated into the
+// equivalent MCP request and handled by the same dispatch path as a session, so
+// the listings and results are generated from the MCP handlers themselves.
+//
+//	GET  /openai/tools        tools in the OpenAI function-calling "tools" format
+//	POST /openai/tool_calls   run an OpenAI tool call; returns the "tool" message to send back
+//	GET  /rest/tools          tools/list result
+//	POST /rest/tools/{name}   call a tool with a JSON object of arguments; returns the tools/call result
+//	GET  /rest/resources      resources/list result
+//	GET  /rest/resource?uri=  resources/read result
+//	GET  /rest/prompts        prompts/list result
+//
+// MCP errors are returned as {"error": {...}} with a matching HTTP status.
+//
+// Callers authenticate as in -mcp-http, with the bearer token of a -profiles
+// principal, and each principal gets a session restricted to its profile;
+// callers without a token share one with the gateway's transport profile.
+// Web pages cannot use a local gateway: requests must name this host (see
+// checkRequestHost), and a POST must be JSON, which a page cannot send to
+// another origin without a CORS preflight the gateway does not answer.
+type gateway struct {
+	newSession func(transport.Transport) *Server // An unrestricted session, see session
+	profiles   *profileSet                       // nil without -profiles
+	listenHost string                            // Host of the listen address, "" for every interface
+	logger     *utils.Logger
+	nextID     atomic.Int64
+
+	mu       sync.Mutex
+	sessions map[string]*Server // By principal, "" for callers without a token
+}
+
+// discardTransport is the transport of the gateway's server, which never runs a session.
+type discardTransport struct{}
+
+func (discardTransport) ReadMessage() ([]byte, error) { return nil, io.EOF }
+func (discardTransport) WriteMessage([]byte) error    { return nil }
+func (discardTransport) Close() error                 { return nil }
+
+// newGateway returns a gateway for listen address addr, whose sessions are
+// created by newSession and restricted by profiles.
+func newGateway(addr string, newSession func(transport.Transport) *Server, profiles *profileSet, logger *utils.Logger) *gateway {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = ""
+	}
+	return &gateway{newSession: newSession, profiles: profiles, listenHost: host, logger: logger, sessions: make(map[string]*Server)}
+}
+
+// serveGateway serves the HTTP gateway on addr until the listener fails.
+func serveGateway(addr string, newSession func(transport.Transport) *Server, profiles *profileSet, logger *utils.Logger) error {
+	g := newGateway(addr, newSession, profiles, logger)
+	logger.Printf("DEBUG", "HTTP gateway listening on %s", addr)
+	return http.ListenAndServe(addr, transport.CompressHandler(g.handler(), transport.DefaultCompressionThreshold))
+}
+
+// session returns the session of principal, "" for callers without a token.
+func (g *gateway) session(principal string) *Server {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	s, ok := g.sessions[principal]
+	if !ok {
+		s = g.newSession(discardTransport{})
+		s.applyProfile(g.profiles.choose(profileTransportGateway, principal))
+		g.sessions[principal] = s
+	}
+	return s
+}
+
+// gatewaySessionKey is the context key of the caller's session.
+type gatewaySessionKey struct{}
+
+// callerSession returns the session of the caller of r, set by handler.
+func callerSession(r *http.Request) *Server {
+	return r.Context().Value(gatewaySessionKey{}).(*Server)
+}
+
+// handler returns the gateway's routes behind the access checks.
+func (g *gateway) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /openai/tools", g.openAITools)
+	mux.HandleFunc("POST /openai/tool_calls", g.runOpenAIToolCall)
+	mux.HandleFunc("GET /rest/tools", g.passthrough(mcp.MethodListTools))
+	mux.HandleFunc("POST /rest/tools/{name}", g.restCallTool)
+	mux.HandleFunc("GET /rest/resources", g.passthrough(mcp.MethodListResources))
+	mux.HandleFunc("GET /rest/resource", g.restReadResource)
+	mux.HandleFunc("GET /rest/prompts", g.passthrough(mcp.MethodListPrompts))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := checkRequestHost(r, g.listenHost); err != nil {
+			writeGatewayJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+			return
+		}
+		if r.Method == http.MethodPost {
+			if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+				writeGatewayJSON(w, http.StatusUnsupportedMediaType, map[string]string{"error": "request body must be application/json"})
+				return
+			}
+		}
+		principal, err := g.profiles.authenticate(r)
+		if err != nil {
+			writeGatewayJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+			return
+		}
+		mux.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), gatewaySessionKey{}, g.session(principal))))
+	})
+}
+
+// checkRequestHost refuses requests a web page may have sent through DNS
+// rebinding or from another origin: the Host header must be an IP address,
+// localhost, or listenHost if that is a name, and an Origin header must be
+// that of the host itself.
+func checkRequestHost(r *http.Request, listenHost string) error {
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host = r.Host // No port
+	}
+	host = strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+	if net.ParseIP(host) == nil && !strings.EqualFold(host, "localhost") && (listenHost == "" || !strings.EqualFold(host, listenHost)) {
+		return fmt.Errorf("host %q not allowed", r.Host)
+	}
+	if !sameOrigin(r) {
+		return fmt.Errorf("origin %q not allowed", r.Header.Get("Origin"))
+	}
+	return nil
+}
+
+// sameOrigin reports whether r has no Origin header or one naming r's host.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	return origin == "" || origin == "http://"+r.Host || origin == "https://"+r.Host
+}
+
+// call runs an MCP request through the dispatch path of the session of the
+// caller of r and decodes the result into v. It returns the RPC error if the
+// server answered with one.
+func (g *gateway) call(r *http.Request, method string, params interface{}, v interface{}) *mcp.RPCError {
+	server := callerSession(r)
+	id := g.nextID.Add(1)
+	payload, err := mcp.MarshalRequest(id, method, params)
+	if err != nil {
+		return mcp.NewRPCError(mcp.ErrorCodeInternalError, err.Error(), nil)
+	}
+	g.logger.Printf("DEBUG", "Gateway: %s (ID: %d)", method, id)
+	response := timeHandler(server.clock, method, server.clock.Now(), func() []byte {
+		return server.dispatch(id, method, payload)
+	})
+
+	var resp mcp.RPCResponse
+	if err := json.Unmarshal(response, &resp); err != nil {
+		return mcp.NewRPCError(mcp.ErrorCodeInternalError, fmt.Sprintf("invalid response from %s: %v", method, err), nil)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if err := json.Unmarshal(resp.Result, v); err != nil {
+		return mcp.NewRPCError(mcp.ErrorCodeInternalError, fmt.Sprintf("invalid %s result: %v", method, err), nil)
+	}
+	return nil
+}
+
+// passthrough serves the result of a parameterless MCP list method as is.
+func (g *gateway) passthrough(method string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var result json.RawMessage
+		if rpcErr := g.call(r, method, nil, &result); rpcErr != nil {
+			writeGatewayError(w, rpcErr)
+			return
+		}
+		writeGatewayJSON(w, http.StatusOK, result)
+	}
+}
+
+// restCallTool calls the tool named in the path with the JSON object in the body as arguments.
+func (g *gateway) restCallTool(w http.ResponseWriter, r *http.Request) {
+	var args map[string]interface{}
+	if err := decodeGatewayBody(r, &args); err != nil {
+		writeGatewayError(w, mcp.NewRPCError(mcp.ErrorCodeInvalidParams, err.Error(), nil))
+		return
+	}
+	var result json.RawMessage
+	if rpcErr := g.call(r, mcp.MethodCallTool, mcp.CallToolParams{Name: r.PathValue("name"), Arguments: args}, &result); rpcErr != nil {
+		writeGatewayError(w, rpcErr)
+		return
+	}
+	writeGatewayJSON(w, http.StatusOK, result)
+}
+
+// restReadResource reads the resource given by the uri query parameter.
+func (g *gateway) restReadResource(w http.ResponseWriter, r *http.Request) {
+	uri := r.URL.Query().Get("uri")
+	if uri == "" {
+		writeGatewayError(w, mcp.NewRPCError(mcp.ErrorCodeInvalidParams, "missing uri query parameter", nil))
+		return
+	}
+	var result json.RawMessage
+	if rpcErr := g.call(r, mcp.MethodReadResource, mcp.ReadResourceParams{URI: uri}, &result); rpcErr != nil {
+		writeGatewayError(w, rpcErr)
+		return
+	}
+	writeGatewayJSON(w, http.StatusOK, result)
+}
+
+// openAITool is a tool in the OpenAI function-calling format.
+type openAITool struct {
+	Type     string         `json:"type"` // Always "function"
+	Function openAIFunction `json:"function"`
+}
+
+// openAIFunction describes a function; Parameters is the tool's JSON Schema.
+type openAIFunction struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description,omitempty"`
+	Parameters  mcp.ToolInputSchema `json:"parameters"`
+}
+
+// openAIToolCall is a tool call as produced by an OpenAI-style model. Arguments is a JSON-encoded object.
+type openAIToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// openAIToolMessage is the message carrying a tool result back to the model.
+type openAIToolMessage struct {
+	Role       string `json:"role"` // Always "tool"
+	ToolCallID string `json:"tool_call_id"`
+	Content    string `json:"content"`
+}
+
+// openAITools lists the server's tools as OpenAI function definitions.
+func (g *gateway) openAITools(w http.ResponseWriter, r *http.Request) {
+	var result mcp.ListToolsResult
+	if rpcErr := g.call(r, mcp.MethodListTools, nil, &result); rpcErr != nil {
+		writeGatewayError(w, rpcErr)
+		return
+	}
+	tools := make([]openAITool, 0, len(result.Tools))
+	for _, t := range result.Tools {
+		tools = append(tools, openAITool{
+			Type:     "function",
+			Function: openAIFunction{Name: t.Name, Description: t.Description, Parameters: t.InputSchema},
+		})
+	}
+	writeGatewayJSON(w, http.StatusOK, map[string]interface{}{"tools": tools})
+}
+
+// runOpenAIToolCall runs a model's tool call and returns the tool message for the conversation.
+// A tool that runs but reports an error still produces a message, so the model can react to it.
+func (g *gateway) runOpenAIToolCall(w http.ResponseWriter, r *http.Request) {
+	var call openAIToolCall
+	if err := decodeGatewayBody(r, &call); err != nil {
+		writeGatewayError(w, mcp.NewRPCError(mcp.ErrorCodeInvalidParams, err.Error(), nil))
+		return
+	}
+	var args map[string]interface{}
+	if strings.TrimSpace(call.Function.Arguments) != "" {
+		if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil {
+			writeGatewayError(w, mcp.NewRPCError(mcp.ErrorCodeInvalidParams, fmt.Sprintf("arguments are not a JSON object: %v", err), nil))
+			return
+		}
+	}
+	var result mcp.CallToolResult
+	if rpcErr := g.call(r, mcp.MethodCallTool, mcp.CallToolParams{Name: call.Function.Name, Arguments: args}, &result); rpcErr != nil {
+		writeGatewayError(w, rpcErr)
+		return
+	}
+	writeGatewayJSON(w, http.StatusOK, openAIToolMessage{Role: "tool", ToolCallID: call.ID, Content: contentText(result.Content)})
+}
+
+// contentText flattens tool result content into a single string: text items as
+// is, anything else (images, embedded resources) as its JSON.
+func contentText(content []json.RawMessage) string {
+	parts := make([]string, 0, len(content))
+	for _, raw := range content {
+		var text mcp.TextContent
+		if json.Unmarshal(raw, &text) == nil && text.Type == "text" {
+			parts = append(parts, text.Text)
+			continue
+		}
+		parts = append(parts, string(raw))
+	}
+	return strings.Join(parts, "\n")
+}
+
+// decodeGatewayBody decodes a JSON request body into v. An empty body leaves v unchanged.
+func decodeGatewayBody(r *http.Request, v interface{}) error {
+	body, err := io.ReadAll(io.LimitReader(r.Body, gatewayMaxBody+1))
+	if err != nil {
+		return err
+	}
+	if len(body) > gatewayMaxBody {
+		return fmt.Errorf("request body larger than %d bytes", gatewayMaxBody)
+	}
+	if len(strings.TrimSpace(string(body))) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("invalid JSON body: %w", err)
+	}
+	return nil
+}
+
+// writeGatewayJSON writes v as a JSON response.
+func writeGatewayJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v) // Too late to change the status if this fails
+}
+
+// writeGatewayError writes an MCP error with the closest HTTP status.
+func writeGatewayError(w http.ResponseWriter, rpcErr *mcp.RPCError) {
+	status := http.StatusInternalServerError
+	switch rpcErr.Code {
+	case mcp.ErrorCodeInvalidParams, mcp.ErrorCodeInvalidRequest, mcp.ErrorCodeParseError:
+		status = http.StatusBadRequest
+	case mcp.ErrorCodeMethodNotFound:
+		status = http.StatusNotFound
+	case mcp.ErrorCodeToolBusy, mcp.ErrorCodeShuttingDown:
+		status = http.StatusServiceUnavailable
+	}
+	writeGatewayJSON(w, status, map[string]*mcp.RPCError{"error": rpcErr})
+}
@@ -0,0 +1,197 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/transport"
+	"sqirvy/mcp/pkg/utils"
+)
+
+func newTestGateway(t *testing.T) *httptest.Server {
+	return newTestGatewayWithProfiles(t, nil)
+}
+
+// newTestGatewayWithProfiles serves a gateway whose callers get the sessions of profiles.
+func newTestGatewayWithProfiles(t *testing.T, profiles *profileSet) *httptest.Server {
+	t.Helper()
+	logger := utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo)
+	newSession := func(tr transport.Transport) *Server { return NewServer(tr, logger) }
+	g := newGateway("localhost:0", newSession, profiles, logger)
+	ts := httptest.NewServer(g.handler())
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// gatewayDo sends a request and decodes the JSON response into v.
+func gatewayDo(t *testing.T, method, url, body string, v interface{}) int {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if method == http.MethodPost {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("%s %s: bad JSON response: %v", method, url, err)
+	}
+	return resp.StatusCode
+}
+
+func TestGatewayOpenAITools(t *testing.T) {
+	ts := newTestGateway(t)
+	var listing struct {
+		Tools []openAITool `json:"tools"`
+	}
+	if status := gatewayDo(t, "GET", ts.URL+"/openai/tools", "", &listing); status != http.StatusOK {
+		t.Fatalf("status = %d", status)
+	}
+	if len(listing.Tools) != 4 {
+		t.Fatalf("tools = %+v, want the ping, query_table, diff and summarize tools", listing.Tools)
+	}
+	tool := listing.Tools[0]
+	if tool.Type != "function" || tool.Function.Name != pingToolName || tool.Function.Parameters["type"] != "object" {
+		t.Errorf("tool = %+v", tool)
+	}
+}
+
+func TestGatewayOpenAIToolCall(t *testing.T) {
+	ts := newTestGateway(t)
+
+	var errResp struct {
+		Error *mcp.RPCError `json:"error"`
+	}
+	call := `{"id":"call_1","type":"function","function":{"name":"missing","arguments":"{}"}}`
+	if status := gatewayDo(t, "POST", ts.URL+"/openai/tool_calls", call, &errResp); status != http.StatusNotFound {
+		t.Errorf("unknown tool status = %d, want 404", status)
+	}
+	if errResp.Error == nil || errResp.Error.Code != mcp.ErrorCodeMethodNotFound {
+		t.Errorf("unknown tool error = %+v", errResp.Error)
+	}
+
+	call = `{"id":"call_2","type":"function","function":{"name":"ping","arguments":"not json"}}`
+	if status := gatewayDo(t, "POST", ts.URL+"/openai/tool_calls", call, &errResp); status != http.StatusBadRequest {
+		t.Errorf("bad arguments status = %d, want 400", status)
+	}
+}
+
+func TestGatewayREST(t *testing.T) {
+	ts := newTestGateway(t)
+
+	var resources mcp.ListResourcesResult
+	if status := gatewayDo(t, "GET", ts.URL+"/rest/resources", "", &resources); status != http.StatusOK || len(resources.Resources) == 0 {
+		t.Errorf("GET /rest/resources = %d, %+v", status, resources)
+	}
+	var prompts mcp.ListPromptsResult
+	if status := gatewayDo(t, "GET", ts.URL+"/rest/prompts", "", &prompts); status != http.StatusOK || len(prompts.Prompts) == 0 {
+		t.Errorf("GET /rest/prompts = %d, %+v", status, prompts)
+	}
+
+	var read mcp.ReadResourceResult
+	if status := gatewayDo(t, "GET", ts.URL+"/rest/resource?uri=data://random_data?length=8", "", &read); status != http.StatusOK || len(read.Contents) != 1 {
+		t.Errorf("GET /rest/resource = %d, %+v", status, read)
+	}
+	if read.Timing() == nil {
+		t.Error("resource read result has no timing, want the same _meta as over MCP")
+	}
+
+	var errResp struct {
+		Error *mcp.RPCError `json:"error"`
+	}
+	if status := gatewayDo(t, "GET", ts.URL+"/rest/resource", "", &errResp); status != http.StatusBadRequest {
+		t.Errorf("GET /rest/resource without uri = %d, want 400", status)
+	}
+	if status := gatewayDo(t, "POST", ts.URL+"/rest/tools/missing", `{}`, &errResp); status != http.StatusNotFound {
+		t.Errorf("POST /rest/tools/missing = %d, want 404", status)
+	}
+}
+
+func TestContentText(t *testing.T) {
+	content := []json.RawMessage{
+		json.RawMessage(`{"type":"text","text":"first"}`),
+		json.RawMessage(`{"type":"image","data":"AA==","mimeType":"image/png"}`),
+	}
+	want := "first\n" + `{"type":"image","data":"AA==","mimeType":"image/png"}`
+	if got := contentText(content); got != want {
+		t.Errorf("contentText() = %q, want %q", got, want)
+	}
+}
+
+func TestGatewayRefusesForeignRequests(t *testing.T) {
+	profiles, err := loadProfiles(writeProfiles(t, `{
+		"profiles": {"ping-only": {"tools": ["ping"]}, "internal": {}},
+		"transports": {"gateway": "ping-only"},
+		"principals": {"ci-bot": {"tokenFile": "ci.token", "profile": "internal"}}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := newTestGatewayWithProfiles(t, profiles)
+	call := `{"message": "hi"}`
+
+	for _, tt := range []struct {
+		name   string
+		host   string
+		header map[string]string
+		want   int
+	}{
+		{"text/plain body", "", map[string]string{"Content-Type": "text/plain"}, http.StatusUnsupportedMediaType},
+		{"no content type", "", map[string]string{}, http.StatusUnsupportedMediaType},
+		{"foreign origin", "", map[string]string{"Content-Type": "application/json", "Origin": "http://evil.example"}, http.StatusForbidden},
+		{"rebound host", "evil.example", map[string]string{"Content-Type": "application/json"}, http.StatusForbidden},
+		{"unknown token", "", map[string]string{"Content-Type": "application/json", "Authorization": "Bearer guess"}, http.StatusUnauthorized},
+		{"same origin", "", map[string]string{"Content-Type": "application/json; charset=utf-8", "Origin": ts.URL}, http.StatusOK},
+		{"localhost", "localhost", map[string]string{"Content-Type": "application/json"}, http.StatusOK},
+	} {
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+"/rest/tools/ping", strings.NewReader(call))
+		for name, value := range tt.header {
+			req.Header.Set(name, value)
+		}
+		if tt.host != "" {
+			req.Host = tt.host
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, resp.StatusCode, tt.want)
+		}
+	}
+
+	// Callers get the session of their principal's profile, else the gateway's
+	list := func(token string) int {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/rest/tools", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var result mcp.ListToolsResult
+		json.NewDecoder(resp.Body).Decode(&result)
+		return len(result.Tools)
+	}
+	if n := list(""); n != 1 {
+		t.Errorf("tools without a token = %d, want ping only", n)
+	}
+	if n := list("s3cret"); n != 4 {
+		t.Errorf("tools of ci-bot = %d, want all 4", n)
+	}
+}
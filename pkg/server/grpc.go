@@ -0,0 +1,94 @@
+package server
+
+import (
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+
+	"sqirvy/mcp/pkg/transport"
+	"sqirvy/mcp/pkg/utils"
+)
+
+// MCP over gRPC
+//
+// -transport=grpc serves the MCPTransport service of
+// pkg/transport/proto/transport.proto on -addr: each Connect stream is one
+// session, one JSON-RPC message per Frame (see pkg/transport/grpc.go). The
+// server always uses transport.FrameCodec, so clients built from the
+// generated code of transport.proto interoperate with it as well as
+// mcp-client -transport=grpc. Connections are not encrypted; like
+// -transport=tcp it is meant for loopback or a trusted network.
+
+// grpcServiceDesc describes MCPTransport for a server without generated code.
+func grpcServiceDesc(connect grpc.StreamHandler) *grpc.ServiceDesc {
+	return &grpc.ServiceDesc{
+		ServiceName: "mcp.transport.v1.MCPTransport",
+		HandlerType: (*interface{})(nil),
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "Connect",
+			Handler:       connect,
+			ServerStreams: true,
+			ClientStreams: true,
+		}},
+		Metadata: "transport.proto",
+	}
+}
+
+// serveGRPC listens on addr and runs one server session per Connect stream.
+// gRPC refuses messages larger than maxMessage bytes.
+//
+// When stop is closed, serveGRPC stops accepting streams, drains every open
+// session (see drain.go) and returns nil once they have all closed. Otherwise
+// it returns only if the listener fails.
+func serveGRPC(addr string, newSession func(transport.Transport) *Server, maxMessage int, stop <-chan struct{}, logger *utils.Logger) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return serveGRPCListener(ln, newSession, maxMessage, stop, logger)
+}
+
+// serveGRPCListener is serveGRPC on a listener of the caller; it closes ln.
+func serveGRPCListener(ln net.Listener, newSession func(transport.Transport) *Server, maxMessage int, stop <-chan struct{}, logger *utils.Logger) error {
+	logger.Printf("DEBUG", "Serving MCP over gRPC on %s", ln.Addr())
+
+	sessions := &sessionSet{sessions: make(map[*Server]struct{})}
+	srv := grpc.NewServer(grpc.ForceServerCodec(transport.FrameCodec{}), grpc.MaxRecvMsgSize(maxMessage))
+	srv.RegisterService(grpcServiceDesc(func(_ interface{}, stream grpc.ServerStream) error {
+		serveGRPCStream(stream, newSession, sessions, logger)
+		return nil
+	}), nil)
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-stop
+		logger.Println("DEBUG", "Draining: no longer accepting streams")
+		sessions.drainAll("server shutting down")
+		srv.GracefulStop() // Returns once every stream has ended
+	}()
+	if err := srv.Serve(ln); err != nil {
+		return err
+	}
+	<-stopped
+	logger.Println("DEBUG", "All sessions drained")
+	return nil
+}
+
+// serveGRPCStream runs a single session on a Connect stream. Returning ends
+// the stream, once the client has closed its side or the session has drained.
+func serveGRPCStream(stream grpc.ServerStream, newSession func(transport.Transport) *Server, sessions *sessionSet, logger *utils.Logger) {
+	var remote net.Addr
+	if p, ok := peer.FromContext(stream.Context()); ok {
+		remote = p.Addr
+	}
+	logger.Printf("DEBUG", "Session started for %v (gRPC)", remote)
+	server := newSession(transport.NewMessageStream(stream, nil))
+	sessions.add(server)
+	defer sessions.remove(server)
+	if err := server.Run(); err != nil {
+		logger.Printf("DEBUG", "Session for %v ended with error: %v", remote, err)
+	}
+	logger.Printf("DEBUG", "Session for %v closed", remote)
+}
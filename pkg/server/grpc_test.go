@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/transport"
+	"sqirvy/mcp/pkg/utils"
+)
+
+// startGRPC serves sessions over gRPC on a loopback port and returns its
+// address, the channel that stops it and the result of serveGRPCListener.
+func startGRPC(t *testing.T, maxMessage int) (string, chan struct{}, <-chan error) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo)
+	stop := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- serveGRPCListener(ln, func(t transport.Transport) *Server { return NewServer(t, logger) }, maxMessage, stop, logger)
+	}()
+	return ln.Addr().String(), stop, done
+}
+
+// connectGRPC opens a Connect stream to addr, as mcp-client -transport=grpc does.
+func connectGRPC(t *testing.T, addr string) transport.Transport {
+	t.Helper()
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	desc := &grpc.StreamDesc{StreamName: "Connect", ClientStreams: true, ServerStreams: true}
+	stream, err := conn.NewStream(context.Background(), desc, transport.GRPCConnectMethod, grpc.ForceCodec(transport.FrameCodec{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return transport.NewMessageStream(stream, stream.CloseSend)
+}
+
+func TestServeGRPCDrainsOnStop(t *testing.T) {
+	addr, stop, done := startGRPC(t, transport.DefaultMaxLineSize)
+	client := connectGRPC(t, addr)
+
+	for _, msg := range []string{initializeRequest, initializedNotify, listToolsRequest} {
+		if err := client.WriteMessage([]byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if m := readMessage(t, client); m.ID != float64(1) || m.Error != nil {
+		t.Fatalf("initialize response = %+v", m)
+	}
+	if m := readMessage(t, client); m.ID != float64(2) || m.Error != nil {
+		t.Fatalf("tools/list response = %+v", m)
+	}
+
+	close(stop)
+	if m := readMessage(t, client); m.Method != mcp.MethodShutdown {
+		t.Errorf("message after stop = %+v, want %s", m, mcp.MethodShutdown)
+	}
+	// The drained session ends the stream, and the server returns
+	if _, err := client.ReadMessage(); err != io.EOF {
+		t.Errorf("read after drain = %v, want EOF", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("serveGRPCListener = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the server did not stop")
+	}
+}
+
+func TestServeGRPCMaxMessageSize(t *testing.T) {
+	addr, stop, done := startGRPC(t, 1000)
+	defer func() { close(stop); <-done }()
+	client := connectGRPC(t, addr)
+
+	huge := `{"jsonrpc":"2.0","id":1,"method":"ping","params":{"x":"` + strings.Repeat("x", 2000) + `"}}`
+	if err := client.WriteMessage([]byte(huge)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ReadMessage(); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("read after an oversized message = %v, want %v", err, codes.ResourceExhausted)
+	}
+}
@@ -1,6 +1,7 @@
-package main
+package server
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 
@@ -28,8 +29,8 @@ func (s *Server) handleInitializeRequest(id mcp.RequestID, payload []byte) ([]by
 		return errorBytes, err // Return marshalled error and the original error
 	}
 
-	// Check if Params field is present and is a valid JSON object/array
-	if req.Params == nil {
+	// Check if Params field is present
+	if len(req.Params) == 0 || string(req.Params) == "null" {
 		err := fmt.Errorf("initialize request missing 'params' field")
 		s.logger.Println("DEBUG", err.Error())
 		rpcErr := mcp.NewRPCError(mcp.ErrorCodeInvalidRequest, err.Error(), nil)
@@ -40,28 +41,9 @@ func (s *Server) handleInitializeRequest(id mcp.RequestID, payload []byte) ([]by
 		return errorBytes, err
 	}
 
-	// Ensure req.Params is json.RawMessage before unmarshalling into specific type
-	paramsRaw, ok := req.Params.(json.RawMessage)
-	if !ok {
-		// This might happen if params is not an object/array in the JSON
-		// Attempt to remarshal and then treat as RawMessage if needed, or handle error
-		tempParamsBytes, err := json.Marshal(req.Params)
-		if err != nil {
-			err = fmt.Errorf("initialize request 'params' field is not a valid JSON object/array (marshal check failed): %w", err)
-			s.logger.Println("DEBUG", err.Error())
-			rpcErr := mcp.NewRPCError(mcp.ErrorCodeInvalidParams, err.Error(), nil)
-			errorBytes, marshalErr := s.marshalErrorResponse(id, rpcErr)
-			if marshalErr != nil {
-				return nil, marshalErr
-			}
-			return errorBytes, err
-		}
-		paramsRaw = json.RawMessage(tempParamsBytes)
-	}
-
 	// Now unmarshal params specifically into InitializeParams
 	var params mcp.InitializeParams
-	if err := json.Unmarshal(paramsRaw, &params); err != nil {
+	if err := json.Unmarshal(req.Params, &params); err != nil {
 		err = fmt.Errorf("failed to unmarshal initialize params object: %w", err)
 		s.logger.Println("DEBUG", err.Error())
 		rpcErr := mcp.NewRPCError(mcp.ErrorCodeInvalidParams, err.Error(), nil)
@@ -88,7 +70,13 @@ func (s *Server) handleInitializeRequest(id mcp.RequestID, payload []byte) ([]by
 		s.logger.Printf("DEBUG", "Client requested protocol version '%s', server using '%s'", params.ProtocolVersion, s.serverVersion)
 	}
 	// TODO: Add more robust version negotiation if needed.
-	// TODO: Inspect params.Capabilities and potentially enable/disable server features.
+
+	// Pick the locale first: the experimental capabilities announce it
+	s.locale = s.locales.selectLocale(params)
+	instructions := serverInstructions
+	if localized := s.localized().Instructions; localized != "" {
+		instructions = localized
+	}
 
 	// --- Prepare Response ---
 	result := mcp.InitializeResult{
@@ -98,11 +86,28 @@ func (s *Server) handleInitializeRequest(id mcp.RequestID, payload []byte) ([]by
 			// Explicitly state no capabilities initially.
 			// Explicitly state capabilities.
 			// Logging:   map[string]interface{}{}, // Example: Empty object indicates basic support
-			Prompts:   &mcp.ServerCapabilitiesPrompts{ListChanged: false},
-			Resources: &mcp.ServerCapabilitiesResources{ListChanged: false, Subscribe: false}, // Announce resource support
-			Tools:     &mcp.ServerCapabilitiesTools{ListChanged: false},                       // Announce tool support (ping tool added)
+			Completions: &mcp.ServerCapabilitiesCompletions{}, // Resource template variables, see templates.go
+			Prompts:     &mcp.ServerCapabilitiesPrompts{ListChanged: false},
+			Resources:   &mcp.ServerCapabilitiesResources{ListChanged: false, Subscribe: false}, // Announce resource support
+			Tools:       &mcp.ServerCapabilitiesTools{ListChanged: s.featureAdmin},              // Announce tool support (ping tool added)
 		},
-		Instructions: "Welcome to the Go MCP Example Server! The 'random_data' resource, 'ping' tool, and 'query' prompt are available.", // Optional, updated instructions
+		Instructions: instructions,
+	}
+	s.clientCapabilities = params.Capabilities
+	s.clientInfo = params.ClientInfo
+	if s.tenancy == tenancyClient {
+		s.setTenant(params.ClientInfo.Name)
+	}
+	s.negotiatedExperimental = s.negotiateExperimental(params.Capabilities.Experimental)
+	result.Capabilities.Experimental = s.negotiatedExperimental
+
+	// Report what the client asked for but will not get, so the mismatch shows up now
+	// rather than as MethodNotFound later
+	if warnings := mcp.CheckCapabilities(params, s.serverVersion, result.Capabilities); len(warnings) > 0 {
+		for _, w := range warnings {
+			s.logger.Printf("INFO", "WARNING: capability downgrade for client %s: %s", params.ClientInfo.Name, w)
+		}
+		result.Meta = map[string]interface{}{mcp.MetaKeyCapabilityWarnings: warnings}
 	}
 
 	// Marshal the successful response using the server's helper
@@ -122,9 +127,21 @@ func (s *Server) handleInitializeRequest(id mcp.RequestID, payload []byte) ([]by
 func (s *Server) handleListTools(id mcp.RequestID) ([]byte, error) {
 	s.logger.Printf("DEBUG", "Handle  : tools/list request (ID: %v)", id)
 
+	result := mcp.ListToolsResult{
+		Tools: s.withToolDeprecations(s.localizeTools(s.withToolIcons(s.listTools()))),
+		// NextCursor: "", // Omit if no pagination needed yet
+	}
+	// Marshal the success response
+	return s.marshalResponse(id, result)
+}
+
+// listTools returns the definitions of the core tools and the module tools
+// that the feature flags allow.
+func (s *Server) listTools() []mcp.Tool {
 	// Define the ping tool
 	pingTool := mcp.Tool{
 		Name:        pingToolName, // Use constant from ping.go
+		Title:       "Ping",
 		Description: fmt.Sprintf("Pings the hardcoded network address %s once.", pingTargetIP),
 		InputSchema: mcp.ToolInputSchema{ // No input arguments needed
 			"type":       "object",
@@ -132,60 +149,128 @@ func (s *Server) handleListTools(id mcp.RequestID) ([]byte, error) {
 		},
 	}
 
-	// TODO: Add other tools here if needed.
-	tools := []mcp.Tool{pingTool}
+	tools := []mcp.Tool{}
+	for _, t := range []mcp.Tool{pingTool, queryTableTool(), diffTool(), summarizeTool()} {
+		if s.toolEnabled(t.Name) {
+			tools = append(tools, t)
+		}
+	}
+	for _, m := range s.modules {
+		for _, t := range m.tools {
+			if s.toolEnabled(t.tool.Name) {
+				tools = append(tools, t.tool)
+			}
+		}
+	}
+	return tools
+}
 
-	result := mcp.ListToolsResult{
-		Tools: tools,
-		// NextCursor: "", // Omit if no pagination needed yet
+// toolSchema returns the inputSchema of the named tool, or nil for an unknown tool.
+func (s *Server) toolSchema(name string) mcp.ToolInputSchema {
+	for _, t := range s.listTools() {
+		if t.Name == name {
+			return t.InputSchema
+		}
 	}
-	// Marshal the success response
-	return s.marshalResponse(id, result)
+	return nil
 }
 
 // handleCallTool parses the tool call request and routes to the specific tool handler.
 // Note: This function is now primarily responsible for parsing and routing.
 // The actual tool logic is delegated (e.g., to handlePingTool).
-func (s *Server) handleCallTool(id mcp.RequestID, payload []byte) ([]byte, error) {
+func (s *Server) handleCallTool(ctx context.Context, id mcp.RequestID, payload []byte) ([]byte, error) {
 	s.logger.Printf("DEBUG", "Handle  : tools/call request (ID: %v)", id)
 
-	var req mcp.RPCRequest
+	// Decode the params straight from the payload in one pass
 	var params mcp.CallToolParams
-
-	// Unmarshal the base request to access params
-	if err := json.Unmarshal(payload, &req); err != nil {
-		err = fmt.Errorf("failed to unmarshal base tool call request: %w", err)
+	if err := mcp.UnmarshalParams(payload, &params); err != nil {
+		err = fmt.Errorf("failed to unmarshal tool call params: %w", err)
 		s.logger.Println("DEBUG", err.Error())
-		rpcErr := mcp.NewRPCError(mcp.ErrorCodeParseError, err.Error(), nil)
+		rpcErr := mcp.NewRPCError(mcp.ErrorCodeInvalidParams, err.Error(), nil)
 		return s.marshalErrorResponse(id, rpcErr)
 	}
 
-	// Marshal params back to bytes
-	paramsBytes, err := json.Marshal(req.Params)
+	key, err := idempotencyKey(params.Meta)
 	if err != nil {
-		err = fmt.Errorf("failed to re-marshal tool call params: %w", err)
-		s.logger.Println("DEBUG", err.Error())
 		rpcErr := mcp.NewRPCError(mcp.ErrorCodeInvalidParams, err.Error(), nil)
 		return s.marshalErrorResponse(id, rpcErr)
 	}
+	if key != "" && s.idempotency.window > 0 {
+		return s.callToolIdempotent(ctx, id, params, key)
+	}
+	return s.callTool(ctx, id, params)
+}
 
-	// Unmarshal into the specific CallToolParams struct
-	if err := json.Unmarshal(paramsBytes, &params); err != nil {
-		err = fmt.Errorf("failed to unmarshal specific tool call params: %w", err)
-		s.logger.Println("DEBUG", err.Error())
-		rpcErr := mcp.NewRPCError(mcp.ErrorCodeInvalidParams, err.Error(), nil)
+// callTool runs a tools/call once its parameters are decoded, answering
+// from the result cache for pure tools.
+func (s *Server) callTool(ctx context.Context, id mcp.RequestID, params mcp.CallToolParams) ([]byte, error) {
+	if !s.profile.allowsTool(params.Name) {
+		// Tools outside the session's profile are not listed, so they are unknown to it
+		s.logger.Printf("DEBUG", "Tool '%s' call (ID: %v) refused by profile '%s'", params.Name, id, s.profile.name)
+		rpcErr := mcp.NewRPCError(mcp.ErrorCodeMethodNotFound, fmt.Sprintf("Tool '%s' not found", params.Name), nil)
+		return s.marshalErrorResponse(id, rpcErr)
+	}
+	if !s.toolEnabled(params.Name) {
+		feature := s.toolFeature(params.Name)
+		s.logger.Printf("DEBUG", "Tool '%s' call (ID: %v) refused: feature '%s' is off", params.Name, id, feature)
+		rpcErr := mcp.NewRPCError(mcp.ErrorCodeMethodNotFound, fmt.Sprintf("Tool '%s' is disabled on this server", params.Name), map[string]string{"tool": params.Name, "feature": feature})
 		return s.marshalErrorResponse(id, rpcErr)
 	}
+	if rpcErr := s.checkToolDeprecation(id, params.Name); rpcErr != nil {
+		return s.marshalErrorResponse(id, rpcErr)
+	}
+	// Fill in defaults and coerce mistyped arguments before anything looks at them
+	arguments, err := prepareArguments(s.toolSchema(params.Name), params.Arguments, s.argMode)
+	if err != nil {
+		s.logger.Printf("DEBUG", "Tool '%s' call (ID: %v) rejected: %v", params.Name, id, err)
+		return s.marshalResponse(id, mcp.NewToolResultError(fmt.Errorf("Tool '%s': %w", params.Name, err)))
+	}
+	params.Arguments = arguments
+
+	key, cacheable := s.results.key(s.tenant, params)
+	if cacheable {
+		if result, ok := s.results.get(key); ok {
+			s.logger.Printf("DEBUG", "Tool '%s' call (ID: %v) answered from the result cache", params.Name, id)
+			return s.marshalResponse(id, result)
+		}
+	}
+	response, err := s.runTool(ctx, id, params)
+	response = s.sanitizers.toolResponse(params.Name, response) // Before caching, so replays are sanitized too
+	if cacheable && err == nil {
+		s.results.store(key, params.Name, response)
+	}
+	return response, err
+}
+
+// runTool routes a tools/call to the tool's handler.
+func (s *Server) runTool(ctx context.Context, id mcp.RequestID, params mcp.CallToolParams) ([]byte, error) {
+	// Respect the tool's concurrency limit, queueing if all its slots are busy
+	release, err := s.toolLimits.acquireUntil(params.Name, s.out.failed)
+	if err != nil {
+		s.logger.Printf("DEBUG", "Tool '%s' call (ID: %v) failed: %v", params.Name, id, err)
+		rpcErr := mcp.NewRPCError(mcp.ErrorCodeToolBusy, fmt.Sprintf("Tool '%s' is busy: %v", params.Name, err), map[string]string{"tool": params.Name})
+		return s.marshalErrorResponse(id, rpcErr)
+	}
+	defer release()
 
 	// Route based on the tool name
 	switch params.Name {
 	case pingToolName:
 		// Delegate to the specific handler in ping.go
-		return s.handlePingTool(id, params)
+		return s.handlePingTool(ctx, id, params)
+	case queryTableToolName:
+		return s.handleQueryTableTool(id, params)
+	case diffToolName:
+		return s.handleDiffTool(ctx, id, params)
+	case summarizeToolName:
+		return s.handleSummarizeTool(ctx, id, params)
 	// Add cases for other tools here
 	// case "another_tool":
 	//     return s.handleAnotherTool(id, params)
 	default:
+		if t, ok := s.moduleTool(params.Name); ok {
+			return s.handleModuleTool(ctx, id, t, params)
+		}
 		s.logger.Printf("DEBUG", "Received call for unknown tool '%s' (ID: %v)", params.Name, id)
 		rpcErr := mcp.NewRPCError(mcp.ErrorCodeMethodNotFound, fmt.Sprintf("Tool '%s' not found", params.Name), nil)
 		return s.marshalErrorResponse(id, rpcErr)
@@ -198,6 +283,7 @@ func (s *Server) handleListPrompts(id mcp.RequestID) ([]byte, error) {
 	// Define the query prompt
 	sqirvyQueryPrompt := mcp.Prompt{
 		Name:        QueryPromptName,
+		Title:       "Sqirvy Query",
 		Description: "A prompt for querying information using the Sqirvy system",
 		Arguments: []mcp.PromptArgument{
 			{Name: "A", Description: "The user's query", Required: false},
@@ -206,9 +292,16 @@ func (s *Server) handleListPrompts(id mcp.RequestID) ([]byte, error) {
 		},
 	}
 
+	prompts := []mcp.Prompt{}
+	for _, p := range []mcp.Prompt{sqirvyQueryPrompt} {
+		if s.profile.allowsPrompt(p.Name) {
+			prompts = append(prompts, p)
+		}
+	}
+
 	// Add prompts to the result
 	result := mcp.ListPromptsResult{
-		Prompts: []mcp.Prompt{sqirvyQueryPrompt},
+		Prompts: s.localizePrompts(s.withPromptIcons(prompts)),
 		// NextCursor: "",
 	}
 	return s.marshalResponse(id, result)
@@ -217,37 +310,22 @@ func (s *Server) handleListPrompts(id mcp.RequestID) ([]byte, error) {
 func (s *Server) handleGetPrompt(id mcp.RequestID, payload []byte) ([]byte, error) {
 	s.logger.Printf("DEBUG", "Handle  : prompts/get request (ID: %v)", id)
 
-	var req mcp.RPCRequest
+	// Decode the params straight from the payload in one pass
 	var params mcp.GetPromptParams
-
-	// Unmarshal the base request to access params
-	if err := json.Unmarshal(payload, &req); err != nil {
-		err = fmt.Errorf("failed to unmarshal base get prompt request: %w", err)
-		s.logger.Println("DEBUG", err.Error())
-		rpcErr := mcp.NewRPCError(mcp.ErrorCodeParseError, err.Error(), nil)
-		return s.marshalErrorResponse(id, rpcErr)
-	}
-
-	// Marshal params back to bytes
-	paramsBytes, err := json.Marshal(req.Params)
-	if err != nil {
-		err = fmt.Errorf("failed to re-marshal get prompt params: %w", err)
+	if err := mcp.UnmarshalParams(payload, &params); err != nil {
+		err = fmt.Errorf("failed to unmarshal get prompt params: %w", err)
 		s.logger.Println("DEBUG", err.Error())
 		rpcErr := mcp.NewRPCError(mcp.ErrorCodeInvalidParams, err.Error(), nil)
 		return s.marshalErrorResponse(id, rpcErr)
 	}
 
-	// Unmarshal into the specific GetPromptParams struct
-	if err := json.Unmarshal(paramsBytes, &params); err != nil {
-		err = fmt.Errorf("failed to unmarshal specific get prompt params: %w", err)
-		s.logger.Println("DEBUG", err.Error())
-		rpcErr := mcp.NewRPCError(mcp.ErrorCodeInvalidParams, err.Error(), nil)
+	// Route based on the prompt name; prompts outside the session's profile are unknown to it
+	switch {
+	case !s.profile.allowsPrompt(params.Name):
+		s.logger.Printf("DEBUG", "Get request for prompt '%s' (ID: %v) refused by profile '%s'", params.Name, id, s.profile.name)
+		rpcErr := mcp.NewRPCError(mcp.ErrorCodeMethodNotFound, fmt.Sprintf("Prompt '%s' not found", params.Name), nil)
 		return s.marshalErrorResponse(id, rpcErr)
-	}
-
-	// Route based on the prompt name
-	switch params.Name {
-	case QueryPromptName:
+	case params.Name == QueryPromptName:
 		// Delegate to the specific handler in sqirvy_query.go
 		return s.handleQueryPrompt(id, params)
 	default:
@@ -263,10 +341,22 @@ func (s *Server) handleListResources(id mcp.RequestID) ([]byte, error) {
 	// This method lists *concrete* resources. Templates are listed via resources/templates/list.
 	// Use the example file resource defined in resources.go
 	// In a real server, this list might be dynamically generated by scanning directories, etc.
-	resourcesList := []mcp.Resource{exampleFileResource} // Use the package-level variable
+	resourcesList := []mcp.Resource{}
+	for _, r := range []mcp.Resource{exampleFileResource} { // Use the package-level variable
+		if s.profile.allowsResource(r.URI) {
+			resourcesList = append(resourcesList, r)
+		}
+	}
+	for _, m := range s.modules {
+		for _, r := range m.resources {
+			if s.profile.allowsResource(r.resource.URI) {
+				resourcesList = append(resourcesList, r.resource)
+			}
+		}
+	}
 
 	result := mcp.ListResourcesResult{
-		Resources: resourcesList,
+		Resources: s.withResourceDeprecations(resourcesList),
 		// NextCursor: "", // Implement pagination if needed
 	}
 	return s.marshalResponse(id, result)
@@ -276,8 +366,13 @@ func (s *Server) handleListResources(id mcp.RequestID) ([]byte, error) {
 func (s *Server) handleListResourceTemplates(id mcp.RequestID) ([]byte, error) {
 	s.logger.Printf("DEBUG", "Handle  : resources/templates/list request (ID: %v)", id)
 
-	// TODO: Add other resource templates here if needed
-	templates := []mcp.ResourceTemplate{RandomDataTemplate}
+	// Templates are added with RegisterResourceTemplate, see templates.go
+	templates := []mcp.ResourceTemplate{}
+	for _, t := range s.templates {
+		if s.profile.allowsResource(t.template.URITemplate) {
+			templates = append(templates, t.template)
+		}
+	}
 
 	result := mcp.ListResourceTemplatesResult{
 		ResourceTemplates: templates,
@@ -0,0 +1,328 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/utils"
+)
+
+func TestInitializeReportsCapabilityWarnings(t *testing.T) {
+	s := NewServer(&captureTransport{}, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+
+	request := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","clientInfo":{"name":"test","version":"0"},
+		"capabilities":{"experimental":{"requiredServerCapabilities":["tools","resources.subscribe"]}}}}`
+	response, err := s.handleInitializeRequest(1, []byte(request))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, _, rpcErr, err := mcp.UnmarshalInitializeResponse(response)
+	if err != nil || rpcErr != nil {
+		t.Fatalf("unmarshal failed: err=%v rpcErr=%v", err, rpcErr)
+	}
+	warnings := result.CapabilityWarnings()
+	if len(warnings) != 1 || warnings[0].Capability != "resources.subscribe" {
+		t.Errorf("CapabilityWarnings() = %v, want a resources.subscribe warning", warnings)
+	}
+
+	response, err = s.handleInitializeRequest(2, []byte(initializeRequest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result, _, _, _ := mcp.UnmarshalInitializeResponse(response); result.Meta != nil {
+		t.Errorf("_meta = %v, want none when nothing was downgraded", result.Meta)
+	}
+}
+
+func TestInitializeAdvertisesCompletions(t *testing.T) {
+	s := NewServer(&captureTransport{}, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	response, err := s.handleInitializeRequest(1, []byte(initializeRequest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(response), `"completions":{}`) {
+		t.Errorf("initialize response = %s, want a completions capability", response)
+	}
+}
+
+func TestInitializeNegotiatesExperimental(t *testing.T) {
+	s := NewServer(&captureTransport{}, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	// Offered only to clients that ask for it, echoing the requested level
+	s.RegisterExperimental("trace", func(client json.RawMessage) (interface{}, bool) {
+		var req struct {
+			Level int `json:"level"`
+		}
+		if client == nil || json.Unmarshal(client, &req) != nil {
+			return nil, false
+		}
+		return req, true
+	})
+	// Always offered
+	s.RegisterExperimental("batch", func(json.RawMessage) (interface{}, bool) {
+		return struct{}{}, true
+	})
+
+	request := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","clientInfo":{"name":"test","version":"0"},
+		"capabilities":{"experimental":{"trace":{"level":2}}}}}`
+	response, err := s.handleInitializeRequest(1, []byte(request))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, _, _, err := mcp.UnmarshalInitializeResponse(response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(result.Capabilities.Experimental["trace"]); got != `{"level":2}` {
+		t.Errorf("experimental.trace = %s", got)
+	}
+	if !result.Capabilities.Experimental.Has("batch") {
+		t.Error("experimental.batch not advertised")
+	}
+	if warnings := result.CapabilityWarnings(); len(warnings) != 0 {
+		t.Errorf("CapabilityWarnings() = %v, want none for a negotiated feature", warnings)
+	}
+
+	response, _ = s.handleInitializeRequest(2, []byte(initializeRequest))
+	result, _, _, _ = mcp.UnmarshalInitializeResponse(response)
+	if got := result.Capabilities.Experimental.Names(); !reflect.DeepEqual(got, []string{"batch"}) {
+		t.Errorf("experimental without client request = %v, want [batch]", got)
+	}
+}
+
+func TestQueryTableReportsToolErrors(t *testing.T) {
+	s := NewServer(&captureTransport{}, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	for _, args := range []map[string]interface{}{
+		{"file": "../../etc/passwd.csv"},
+		{"file": "data/missing.csv"},
+		{"file": "data/table.txt"},
+	} {
+		response, err := s.handleQueryTableTool(1, mcp.CallToolParams{Name: queryTableToolName, Arguments: args})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var resp struct {
+			Result mcp.CallToolResult `json:"result"`
+			Error  *mcp.RPCError      `json:"error"`
+		}
+		if err := json.Unmarshal(response, &resp); err != nil || resp.Error != nil || !resp.Result.IsError {
+			t.Errorf("query_table %v = %s, want a tool error result", args, response)
+		}
+	}
+
+	response, _ := s.handleQueryTableTool(2, mcp.CallToolParams{Name: queryTableToolName, Arguments: map[string]interface{}{"limit": "ten"}})
+	var resp mcp.RPCResponse
+	if json.Unmarshal(response, &resp); resp.Error == nil || resp.Error.Code != mcp.ErrorCodeInvalidParams {
+		t.Errorf("query_table with bad arguments = %s, want invalid params", response)
+	}
+}
+
+func BenchmarkHandleCallTool(b *testing.B) {
+	s := NewServer(&captureTransport{}, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	payload, _ := mcp.MarshalCallToolRequest(1, mcp.CallToolParams{Name: "ping", Arguments: map[string]interface{}{}})
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := s.handleCallTool(s.requestContext(1), 1, payload); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkHandleGetPrompt(b *testing.B) {
+	s := NewServer(&captureTransport{}, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	payload, _ := mcp.MarshalGetPromptRequest(1, mcp.GetPromptParams{Name: QueryPromptName, Arguments: map[string]string{"query": "what is MCP?"}})
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := s.handleGetPrompt(1, payload); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestDispatchPopulatesRequestContext(t *testing.T) {
+	s := NewServer(&captureTransport{}, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	s.clientInfo = mcp.Implementation{Name: "host"}
+	s.modules = []*toolModule{{name: "whoami", tools: []moduleTool{{
+		tool: mcp.Tool{Name: "whoami"},
+		call: func(ctx context.Context, _ *Server, _ map[string]interface{}) (mcp.CallToolResult, error) {
+			session, _ := mcp.SessionFromContext(ctx)
+			id, _ := mcp.RequestIDFromContext(ctx)
+			mcp.LoggerFromContext(ctx).Printf("DEBUG", "whoami called")
+			return mcp.NewToolResultText(fmt.Sprintf("%s %v", session.ClientInfo().Name, id)), nil
+		},
+	}}}}
+
+	payload, _ := mcp.MarshalCallToolRequest("call-7", mcp.CallToolParams{Name: "whoami"})
+	var resp struct {
+		Result mcp.CallToolResult `json:"result"`
+	}
+	if err := json.Unmarshal(s.dispatch("call-7", mcp.MethodCallTool, payload), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustText(resp.Result); got != "host call-7" {
+		t.Errorf("whoami = %q, want the session's client and the request ID", got)
+	}
+}
+
+func TestRandomDataResourceLength(t *testing.T) {
+	s := NewServer(&captureTransport{}, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	for uri, wantCode := range map[string]int{
+		"data://random_data?length=16":   0,
+		"data://random_data?length=0":    mcp.ErrorCodeInvalidParams,
+		"data://random_data?length=5000": mcp.ErrorCodeInvalidParams,
+	} {
+		request := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"resources/read","params":{"uri":%q}}`, uri)
+		response, _ := s.handleReadResource(s.requestContext(1), 1, []byte(request))
+		var resp struct {
+			Result *mcp.ReadResourceResult `json:"result"`
+			Error  *mcp.RPCError           `json:"error"`
+		}
+		if err := json.Unmarshal(response, &resp); err != nil {
+			t.Fatalf("%s: %v", uri, err)
+		}
+		switch {
+		case wantCode == 0 && (resp.Error != nil || resp.Result == nil || len(resp.Result.Contents) != 1):
+			t.Errorf("%s = %s, want 16 random characters", uri, response)
+		case wantCode != 0 && (resp.Error == nil || resp.Error.Code != wantCode):
+			t.Errorf("%s = %s, want error %d", uri, response, wantCode)
+		}
+	}
+}
+
+func TestRegisterResourceTemplate(t *testing.T) {
+	s := NewServer(&captureTransport{}, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	s.RegisterResourceTemplate(mcp.ResourceTemplate{Name: "greeting", URITemplate: "greet://{lang}/{name}"}, ResourceTemplateHandler{
+		Read: func(ctx context.Context, uri string, vars map[string]string) (mcp.ReadResourceResult, error) {
+			var result mcp.ReadResourceResult
+			result.Add(mcp.NewTextResource(uri, "text/plain", vars["lang"]+": hello "+vars["name"]))
+			return result, nil
+		},
+		Complete: map[string]func(string, map[string]string) []string{
+			"name": func(value string, vars map[string]string) []string {
+				return completePrefix([]string{vars["lang"] + "-ann", vars["lang"] + "-bob"}, value)
+			},
+		},
+	})
+
+	// The template is listed...
+	var list struct {
+		Result mcp.ListResourceTemplatesResult `json:"result"`
+	}
+	json.Unmarshal(s.dispatch(1, mcp.MethodListResourceTemplates, []byte(`{"jsonrpc":"2.0","id":1,"method":"resources/templates/list"}`)), &list)
+	var listed []string
+	for _, tmpl := range list.Result.ResourceTemplates {
+		listed = append(listed, tmpl.URITemplate)
+	}
+	if !reflect.DeepEqual(listed, []string{RandomDataTemplate.URITemplate, "greet://{lang}/{name}"}) {
+		t.Errorf("templates = %v", listed)
+	}
+
+	// ...routed...
+	var read struct {
+		Result mcp.ReadResourceResult `json:"result"`
+	}
+	json.Unmarshal(s.dispatch(2, mcp.MethodReadResource, []byte(`{"jsonrpc":"2.0","id":2,"method":"resources/read","params":{"uri":"greet://en/ann"}}`)), &read)
+	if len(read.Result.Contents) != 1 || !strings.Contains(string(read.Result.Contents[0]), "en: hello ann") {
+		t.Errorf("read = %+v", read.Result)
+	}
+
+	// ...and completed
+	complete := func(request string) (*mcp.CompleteResult, *mcp.RPCError) {
+		t.Helper()
+		result, _, rpcErr, err := mcp.UnmarshalCompleteResponse(s.dispatch(3, mcp.MethodComplete, []byte(request)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result, rpcErr
+	}
+	result, rpcErr := complete(`{"jsonrpc":"2.0","id":3,"method":"completion/complete","params":{"ref":{"type":"ref/resource","uri":"greet://{lang}/{name}"},
+		"argument":{"name":"name","value":"fr-b"},"context":{"arguments":{"lang":"fr"}}}}`)
+	if rpcErr != nil || !reflect.DeepEqual(result.Completion.Values, []string{"fr-bob"}) {
+		t.Errorf("name completion = %+v, %v", result, rpcErr)
+	}
+	result, rpcErr = complete(`{"jsonrpc":"2.0","id":3,"method":"completion/complete","params":{"ref":{"type":"ref/resource","uri":"data://random_data?length={length}"},
+		"argument":{"name":"length","value":"1"}}}`)
+	if rpcErr != nil || !reflect.DeepEqual(result.Completion.Values, []string{"16", "128", "1024"}) {
+		t.Errorf("length completion = %+v, %v", result, rpcErr)
+	}
+	if _, rpcErr = complete(`{"jsonrpc":"2.0","id":3,"method":"completion/complete","params":{"ref":{"type":"ref/resource","uri":"nope://{x}"},"argument":{"name":"x","value":""}}}`); rpcErr == nil || rpcErr.Code != mcp.ErrorCodeInvalidParams {
+		t.Errorf("unknown template = %v, want invalid params", rpcErr)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("completer for a missing variable did not panic")
+		}
+	}()
+	s.RegisterResourceTemplate(mcp.ResourceTemplate{Name: "bad", URITemplate: "bad://{a}"}, ResourceTemplateHandler{
+		Complete: map[string]func(string, map[string]string) []string{"b": nil},
+	})
+}
+
+func TestExtensionMethods(t *testing.T) {
+	s := NewServer(&captureTransport{}, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	s.HandleMethod("x-test/echo", func(ctx context.Context, params json.RawMessage) (interface{}, *mcp.RPCError) {
+		if string(params) == "{}" { // Also sent for missing or null params
+			return nil, mcp.NewRPCError(mcp.ErrorCodeInvalidParams, "params required", nil)
+		}
+		return params, nil
+	})
+
+	for request, want := range map[string]string{
+		`{"jsonrpc":"2.0","id":1,"method":"x-test/echo","params":{"a":1}}`: `"result":{"a":1}`,
+		`{"jsonrpc":"2.0","id":2,"method":"x-test/echo"}`:                  `"message":"params required"`,
+		`{"jsonrpc":"2.0","id":5,"method":"x-test/echo","params":null}`:    `"message":"params required"`,
+		`{"jsonrpc":"2.0","id":3,"method":"ping"}`:                         `"result":{}`,
+		`{"jsonrpc":"2.0","id":4,"method":"x-test/other"}`:                 fmt.Sprintf(`"code":%d`, mcp.ErrorCodeMethodNotFound),
+	} {
+		info, _ := mcp.ClassifyMessage([]byte(request))
+		if response := string(s.dispatch(info.ID, info.Method, []byte(request))); !strings.Contains(response, want) {
+			t.Errorf("%s answered %s, want %s", request, response, want)
+		}
+	}
+
+	// Registered methods are advertised
+	server := s.negotiateExperimental(nil)
+	if got := (mcp.ServerCapabilities{Experimental: server}).ExtensionMethods(); !reflect.DeepEqual(got, []string{"x-test/echo"}) {
+		t.Errorf("advertised methods = %q", got)
+	}
+
+	// Standard methods, and names outside the convention, cannot be taken over
+	for _, method := range []string{mcp.MethodPing, "vendor/echo", "x-/echo", "x-test/"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("HandleMethod(%q) did not panic", method)
+				}
+			}()
+			s.HandleMethod(method, func(context.Context, json.RawMessage) (interface{}, *mcp.RPCError) {
+				return "hijacked", nil
+			})
+		}()
+	}
+}
+
+func TestListedItemsHaveTitles(t *testing.T) {
+	s := NewServer(&captureTransport{}, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	for _, tool := range s.listTools() {
+		if tool.Title == "" {
+			t.Errorf("tool %s has no title", tool.Name)
+		}
+	}
+	response, _ := s.handleListPrompts(1)
+	var resp struct {
+		Result mcp.ListPromptsResult `json:"result"`
+	}
+	if err := json.Unmarshal(response, &resp); err != nil || len(resp.Result.Prompts) == 0 || resp.Result.Prompts[0].Title == "" {
+		t.Errorf("prompts/list = %s (%v), want titled prompts", response, err)
+	}
+	if exampleFileResource.Title == "" || RandomDataTemplate.Title == "" || s.serverInfo.Title == "" {
+		t.Error("resource, template or server info has no title")
+	}
+}
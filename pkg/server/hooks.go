@@ -0,0 +1,64 @@
+package server
+
+import (
+	"encoding/json"
+	"time"
+
+	"sqirvy/mcp/pkg/mcp"
+)
+
+// The methods below call the session's lifecycle hooks, see mcp.Hooks. Hooks
+// that run outside a request get a context without a request ID.
+
+// hookInitialize lets OnInitialize reject an initialize request whose params
+// have already been validated.
+func (s *Server) hookInitialize(id mcp.RequestID, payload []byte) *mcp.RPCError {
+	if s.hooks.OnInitialize == nil {
+		return nil
+	}
+	var params mcp.InitializeParams
+	json.Unmarshal(mcp.MessageParams(payload), &params) // Validated by the caller
+	return s.hooks.OnInitialize(s.requestContext(id), params)
+}
+
+func (s *Server) hookInitialized() {
+	if s.hooks.OnInitialized != nil {
+		s.hooks.OnInitialized(s.requestContext(nil))
+	}
+}
+
+func (s *Server) hookNotification(method string, payload []byte) {
+	if s.hooks.OnNotification != nil {
+		s.hooks.OnNotification(s.requestContext(nil), method, mcp.MessageParams(payload))
+	}
+}
+
+// hookResponse reports the response to a dispatched request, and its error if
+// it is an error response.
+func (s *Server) hookResponse(id mcp.RequestID, method string, response []byte, d time.Duration) {
+	if s.hooks.OnResponse == nil && s.hooks.OnError == nil {
+		return
+	}
+	ctx := s.requestContext(id)
+	if s.hooks.OnResponse != nil {
+		s.hooks.OnResponse(ctx, method, response, d)
+	}
+	var resp struct {
+		Error *mcp.RPCError `json:"error"`
+	}
+	if s.hooks.OnError != nil && json.Unmarshal(response, &resp) == nil && resp.Error != nil {
+		s.hooks.OnError(ctx, method, resp.Error)
+	}
+}
+
+func (s *Server) hookError(id mcp.RequestID, method string, rpcErr *mcp.RPCError) {
+	if s.hooks.OnError != nil {
+		s.hooks.OnError(s.requestContext(id), method, rpcErr)
+	}
+}
+
+func (s *Server) hookShutdown(reason string) {
+	if s.hooks.OnShutdown != nil {
+		s.hooks.OnShutdown(s.requestContext(nil), reason)
+	}
+}
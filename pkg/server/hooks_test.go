@@ -0,0 +1,107 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/utils"
+)
+
+func TestHooksSeeTheSessionLifecycle(t *testing.T) {
+	var mu sync.Mutex
+	var events []string
+	record := func(ctx context.Context, format string, v ...interface{}) {
+		event := fmt.Sprintf(format, v...)
+		if id, ok := mcp.RequestIDFromContext(ctx); ok {
+			event += fmt.Sprintf(" id=%v", id)
+		}
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	}
+	hooks := mcp.Hooks{
+		OnInitialize: func(ctx context.Context, params mcp.InitializeParams) *mcp.RPCError {
+			record(ctx, "initialize %s", params.ClientInfo.Name)
+			return nil
+		},
+		OnInitialized: func(ctx context.Context) {
+			session, _ := mcp.SessionFromContext(ctx)
+			record(ctx, "initialized %s", session.ClientInfo().Name)
+		},
+		OnRequest: func(ctx context.Context, method string, params json.RawMessage) *mcp.RPCError {
+			record(ctx, "request %s", method)
+			if method == mcp.MethodListPrompts {
+				return mcp.NewRPCError(mcp.ErrorCodeInvalidRequest, "prompts are off", nil)
+			}
+			return nil
+		},
+		OnResponse: func(ctx context.Context, method string, response []byte, d time.Duration) {
+			record(ctx, "response %s", method)
+		},
+		OnNotification: func(ctx context.Context, method string, params json.RawMessage) {
+			record(ctx, "notification %s", method)
+		},
+		OnError: func(ctx context.Context, method string, err *mcp.RPCError) {
+			record(ctx, "error %s %d", method, err.Code)
+		},
+		OnShutdown: func(ctx context.Context, reason string) {
+			record(ctx, "shutdown %s", reason)
+		},
+	}
+	tr := &chanTransport{captureTransport: captureTransport{written: make(chan []byte, 16)}, in: make(chan []byte)}
+	s := NewServer(tr, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo), hooks)
+	done := make(chan error)
+	go func() { done <- s.Run() }()
+
+	tr.in <- []byte(initializeRequest)
+	readWire(t, tr.written)
+	tr.in <- []byte(`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`) // Before initialized: refused
+	readWire(t, tr.written)
+	tr.in <- []byte(initializedNotify)
+	tr.in <- []byte(`{"jsonrpc":"2.0","id":3,"method":"prompts/list"}`)
+	if m := readWire(t, tr.written); m.Error == nil || m.Error.Message != "prompts are off" {
+		t.Errorf("prompts/list = %+v, want the hook's error", m)
+	}
+	close(tr.in)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"initialize test id=1",
+		fmt.Sprintf("error tools/list %d id=2", mcp.ErrorCodeServerNotReady),
+		"notification notifications/initialized",
+		"initialized test",
+		"request prompts/list id=3",
+		"response prompts/list id=3",
+		fmt.Sprintf("error prompts/list %d id=3", mcp.ErrorCodeInvalidRequest),
+		"shutdown connection closed",
+	}
+	if got := strings.Join(events, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("events:\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+	}
+}
+
+func TestInitializeHookRejects(t *testing.T) {
+	tr := &captureTransport{written: make(chan []byte, 16)}
+	s := NewServer(tr, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo), mcp.Hooks{
+		OnInitialize: func(_ context.Context, params mcp.InitializeParams) *mcp.RPCError {
+			return mcp.NewRPCError(mcp.ErrorCodeInvalidRequest, "unknown client "+params.ClientInfo.Name, nil)
+		},
+	})
+	s.processMessage([]byte(initializeRequest))
+	if m := readWire(t, tr.written); m.Error == nil || m.Error.Message != "unknown client test" {
+		t.Errorf("initialize = %+v, want the hook's error", m)
+	}
+	if s.state != stateAwaitingInitialize {
+		t.Errorf("state = %s, want the session still awaiting initialize", s.state)
+	}
+}
@@ -0,0 +1,180 @@
+package server
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"sqirvy/mcp/pkg/clock"
+	"sqirvy/mcp/pkg/utils"
+)
+
+// Hot path watermarks
+//
+// Every session answers in arrival order (see outbox.go), so one slow tool
+// call holds back the responses behind it. The hot path monitor counts the
+// requests that have been received but not yet answered, across all sessions,
+// and logs a summary of the queue depth, the age of the oldest pending request
+// and the p95 handler latency:
+//
+//   - every -hotpath-interval, when set;
+//   - as soon as the depth exceeds -hotpath-max-depth or the oldest request
+//     gets older than -hotpath-max-age, once per crossing.
+
+const (
+	hotPathSamples       = 512         // Recent handler latencies the p95 is computed over
+	hotPathCheckInterval = time.Second // How often thresholds are checked without -hotpath-interval
+)
+
+// hotPathSummary is what the monitor logs.
+type hotPathSummary struct {
+	depth     int           // Requests pending now
+	maxDepth  int           // Highest depth since the previous summary
+	oldest    time.Duration // Age of the oldest pending request
+	p95       time.Duration // Over the last hotPathSamples handlers
+	completed int64         // Requests answered since the previous summary
+}
+
+// hotPathMonitor tracks the pending requests of every session. The methods of
+// a nil monitor do nothing.
+type hotPathMonitor struct {
+	clock    clock.Clock
+	logger   *utils.Logger
+	interval time.Duration // Summary period, 0 for summaries only when over a threshold
+	maxDepth int           // 0 = no depth threshold
+	maxAge   time.Duration // 0 = no age threshold
+
+	mu        sync.Mutex
+	next      uint64
+	pending   map[uint64]time.Time // Ticket -> time received
+	latencies []time.Duration      // Ring of recent handler latencies
+	ring      int                  // Next index in latencies once it is full
+	watermark int
+	completed int64
+	over      bool // A threshold was crossed and has not been cleared since
+	lastLog   time.Time
+	timer     clock.Timer
+}
+
+func newHotPathMonitor(c clock.Clock, logger *utils.Logger, interval time.Duration, maxDepth int, maxAge time.Duration) *hotPathMonitor {
+	return &hotPathMonitor{
+		clock:    c,
+		logger:   logger,
+		interval: interval,
+		maxDepth: maxDepth,
+		maxAge:   maxAge,
+		pending:  make(map[uint64]time.Time),
+		lastLog:  c.Now(),
+	}
+}
+
+// begin records a request received at the given time and returns the ticket
+// to pass to end.
+func (m *hotPathMonitor) begin(received time.Time) uint64 {
+	if m == nil {
+		return 0
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.next++
+	m.pending[m.next] = received
+	m.watermark = max(m.watermark, len(m.pending))
+	if m.maxDepth > 0 && len(m.pending) > m.maxDepth {
+		m.checkLocked()
+	}
+	return m.next
+}
+
+// end records that the request of ticket has been answered.
+func (m *hotPathMonitor) end(ticket uint64) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	received, ok := m.pending[ticket]
+	if !ok {
+		return
+	}
+	delete(m.pending, ticket)
+	m.completed++
+	latency := m.clock.Since(received)
+	if len(m.latencies) < hotPathSamples {
+		m.latencies = append(m.latencies, latency)
+	} else {
+		m.latencies[m.ring] = latency
+		m.ring = (m.ring + 1) % hotPathSamples
+	}
+}
+
+// start runs the periodic checks until the returned function is called.
+func (m *hotPathMonitor) start() (stop func()) {
+	if m == nil {
+		return func() {}
+	}
+	period := m.interval
+	if period <= 0 || (m.maxAge > 0 && m.maxAge < period) {
+		period = hotPathCheckInterval
+	}
+	var tick func()
+	tick = func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.checkLocked()
+		if m.interval > 0 && m.clock.Since(m.lastLog) >= m.interval {
+			m.logLocked("Hot path")
+		}
+		if m.timer != nil {
+			m.timer = m.clock.AfterFunc(period, tick)
+		}
+	}
+	m.mu.Lock()
+	m.timer = m.clock.AfterFunc(period, tick)
+	m.mu.Unlock()
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.timer.Stop()
+		m.timer = nil
+	}
+}
+
+// checkLocked logs a summary when a threshold is first crossed.
+func (m *hotPathMonitor) checkLocked() {
+	s := m.summaryLocked()
+	over := (m.maxDepth > 0 && s.depth > m.maxDepth) || (m.maxAge > 0 && s.oldest > m.maxAge)
+	if over && !m.over {
+		m.logLocked("Hot path over threshold")
+	}
+	m.over = over
+}
+
+// summary returns the current watermarks without resetting them.
+func (m *hotPathMonitor) summary() hotPathSummary {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.summaryLocked()
+}
+
+func (m *hotPathMonitor) summaryLocked() hotPathSummary {
+	s := hotPathSummary{depth: len(m.pending), maxDepth: m.watermark, completed: m.completed}
+	now := m.clock.Now()
+	for _, received := range m.pending {
+		s.oldest = max(s.oldest, now.Sub(received))
+	}
+	if len(m.latencies) > 0 {
+		sorted := append([]time.Duration(nil), m.latencies...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		s.p95 = sorted[(len(sorted)*95+99)/100-1]
+	}
+	return s
+}
+
+// logLocked logs a summary and starts a new watermark period.
+func (m *hotPathMonitor) logLocked(prefix string) {
+	s := m.summaryLocked()
+	m.logger.Printf("INFO", "%s: queue depth %d (max %d), oldest pending %s, p95 latency %s, %d completed",
+		prefix, s.depth, s.maxDepth, s.oldest.Round(time.Millisecond), s.p95.Round(time.Millisecond), s.completed)
+	m.watermark, m.completed = len(m.pending), 0
+	m.lastLog = m.clock.Now()
+}
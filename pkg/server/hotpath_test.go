@@ -0,0 +1,66 @@
+package server
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"sqirvy/mcp/pkg/clock"
+	"sqirvy/mcp/pkg/utils"
+)
+
+func TestHotPathSummary(t *testing.T) {
+	c := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	m := newHotPathMonitor(c, utils.New(&bytes.Buffer{}, "", 0, utils.LevelInfo), 0, 0, 0)
+
+	// 100 requests taking 1ms to 100ms and one taking 2s: the p95 is the 96th of 101
+	for i := 1; i <= 100; i++ {
+		ticket := m.begin(c.Now().Add(-time.Duration(i) * time.Millisecond))
+		m.end(ticket)
+	}
+	slow := m.begin(c.Now())
+	m.begin(c.Now())
+	c.Advance(2 * time.Second)
+	m.end(slow)
+
+	s := m.summary()
+	if s.depth != 1 || s.maxDepth != 2 || s.completed != 101 {
+		t.Errorf("depth %d, max %d, completed %d; want 1, 2, 101", s.depth, s.maxDepth, s.completed)
+	}
+	if s.oldest != 2*time.Second {
+		t.Errorf("oldest = %s, want 2s", s.oldest)
+	}
+	if s.p95 != 96*time.Millisecond {
+		t.Errorf("p95 = %s, want 96ms", s.p95)
+	}
+
+	var nilMonitor *hotPathMonitor
+	nilMonitor.end(nilMonitor.begin(c.Now()))
+	nilMonitor.start()()
+}
+
+func TestHotPathLogging(t *testing.T) {
+	c := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	var logs bytes.Buffer
+	m := newHotPathMonitor(c, utils.New(&logs, "", 0, utils.LevelInfo), time.Minute, 2, 5*time.Second)
+	stop := m.start()
+	defer stop()
+
+	// The depth threshold is reported as it is crossed, and only once
+	for i := 0; i < 4; i++ {
+		m.begin(c.Now())
+	}
+	if n := strings.Count(logs.String(), "over threshold"); n != 1 {
+		t.Fatalf("%d threshold reports after crossing the depth, want 1:\n%s", n, logs.String())
+	}
+	if !strings.Contains(logs.String(), "queue depth 3 (max 3)") {
+		t.Errorf("threshold report = %s", logs.String())
+	}
+
+	// A summary follows every interval
+	c.Advance(time.Minute)
+	if !strings.Contains(logs.String(), "Hot path: queue depth 4 (max 4), oldest pending 1m0s") {
+		t.Errorf("no periodic summary:\n%s", logs.String())
+	}
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/gif" // Registered for image.DecodeConfig, to read icon sizes
+	_ "image/jpeg"
+	_ "image/png"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"sqirvy/mcp/pkg/mcp"
+)
+
+// iconFile is the -icons file, e.g.
+//
+//	{"server": [{"file": "logo.png"}],
+//	 "tools": {"ping": [{"src": "https://example.com/ping.svg", "mimeType": "image/svg+xml", "sizes": ["any"]}]},
+//	 "prompts": {"query": [{"file": "query.png"}]}}
+type iconFile struct {
+	Server  []iconEntry            `json:"server"`
+	Tools   map[string][]iconEntry `json:"tools"`   // Tool name -> icons
+	Prompts map[string][]iconEntry `json:"prompts"` // Prompt name -> icons
+}
+
+// iconEntry is an icon as written in the file: a hosted icon, or a local
+// image file that is embedded as a data URI when the file is loaded.
+type iconEntry struct {
+	mcp.Icon
+	File string `json:"file"` // Relative to the -icons file
+}
+
+// iconSet holds the validated icons of the server and its tools and prompts.
+type iconSet struct {
+	server  []mcp.Icon
+	tools   map[string][]mcp.Icon
+	prompts map[string][]mcp.Icon
+}
+
+// loadIcons reads an icons file, embedding local images, and validates every icon.
+func loadIcons(path string) (*iconSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read icons: %w", err)
+	}
+	var file iconFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid icons file %s: %w", path, err)
+	}
+	dir := filepath.Dir(path)
+	set := &iconSet{tools: make(map[string][]mcp.Icon), prompts: make(map[string][]mcp.Icon)}
+	if set.server, err = resolveIcons(dir, file.Server); err != nil {
+		return nil, fmt.Errorf("invalid icons file %s: server: %w", path, err)
+	}
+	for name, entries := range file.Tools {
+		if set.tools[name], err = resolveIcons(dir, entries); err != nil {
+			return nil, fmt.Errorf("invalid icons file %s: tool %s: %w", path, name, err)
+		}
+	}
+	for name, entries := range file.Prompts {
+		if set.prompts[name], err = resolveIcons(dir, entries); err != nil {
+			return nil, fmt.Errorf("invalid icons file %s: prompt %s: %w", path, name, err)
+		}
+	}
+	return set, nil
+}
+
+// resolveIcons embeds the local files among entries and validates the icons.
+func resolveIcons(dir string, entries []iconEntry) ([]mcp.Icon, error) {
+	icons := make([]mcp.Icon, 0, len(entries))
+	for _, entry := range entries {
+		icon := entry.Icon
+		if entry.File != "" {
+			path := entry.File
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(dir, path)
+			}
+			var err error
+			if icon, err = iconFromFile(path, entry.Sizes); err != nil {
+				return nil, err
+			}
+		}
+		if err := icon.Validate(); err != nil {
+			return nil, err
+		}
+		icons = append(icons, icon)
+	}
+	return icons, nil
+}
+
+// iconFromFile embeds the image at path. Without sizes, the size of a PNG,
+// JPEG or GIF is read from the image, and an SVG suits any size.
+func iconFromFile(path string, sizes []string) (mcp.Icon, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return mcp.Icon{}, err
+	}
+	if info.Size() > mcp.MaxIconBytes {
+		return mcp.Icon{}, fmt.Errorf("%s: %w of %d bytes", path, mcp.ErrIconTooLarge, mcp.MaxIconBytes)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return mcp.Icon{}, err
+	}
+	mimeType, _, _ := strings.Cut(mime.TypeByExtension(filepath.Ext(path)), ";")
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	if len(sizes) == 0 {
+		if config, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+			sizes = []string{fmt.Sprintf("%dx%d", config.Width, config.Height)}
+		} else if mimeType == "image/svg+xml" {
+			sizes = []string{"any"}
+		}
+	}
+	icon, err := mcp.NewDataIcon(data, mimeType, sizes...)
+	if err != nil {
+		return mcp.Icon{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return icon, nil
+}
+
+// withToolIcons sets the configured icons on tools.
+func (s *Server) withToolIcons(tools []mcp.Tool) []mcp.Tool {
+	if s.icons == nil {
+		return tools
+	}
+	for i, t := range tools {
+		if icons, ok := s.icons.tools[t.Name]; ok {
+			tools[i].Icons = icons
+		}
+	}
+	return tools
+}
+
+// withPromptIcons sets the configured icons on prompts.
+func (s *Server) withPromptIcons(prompts []mcp.Prompt) []mcp.Prompt {
+	if s.icons == nil {
+		return prompts
+	}
+	for i, p := range prompts {
+		if icons, ok := s.icons.prompts[p.Name]; ok {
+			prompts[i].Icons = icons
+		}
+	}
+	return prompts
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/utils"
+)
+
+// writeIconFiles writes the files of an icons config into a temporary directory
+// and returns the path of icons.json.
+func writeIconFiles(t *testing.T, config string, files map[string][]byte) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(dir, "icons.json")
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadIcons(t *testing.T) {
+	var logo bytes.Buffer
+	if err := png.Encode(&logo, image.NewRGBA(image.Rect(0, 0, 32, 16))); err != nil {
+		t.Fatal(err)
+	}
+	path := writeIconFiles(t, `{
+		"server": [{"file": "logo.png"}],
+		"tools": {"ping": [{"src": "https://example.com/ping.svg", "mimeType": "image/svg+xml", "sizes": ["any"]}]},
+		"prompts": {"query": [{"file": "query.svg"}]}
+	}`, map[string][]byte{
+		"logo.png":  logo.Bytes(),
+		"query.svg": []byte(`<svg xmlns="http://www.w3.org/2000/svg"/>`),
+	})
+	icons, err := loadIcons(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(icons.server) != 1 || icons.server[0].MimeType != "image/png" || !strings.HasPrefix(icons.server[0].Src, "data:image/png;base64,") ||
+		len(icons.server[0].Sizes) != 1 || icons.server[0].Sizes[0] != "32x16" {
+		t.Errorf("server icons = %+v, want the embedded 32x16 PNG", icons.server)
+	}
+	if p := icons.prompts["query"]; len(p) != 1 || p[0].MimeType != "image/svg+xml" || p[0].Sizes[0] != "any" {
+		t.Errorf("prompt icons = %+v, want the embedded SVG for any size", p)
+	}
+
+	s := NewServer(&captureTransport{}, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	s.icons = icons
+	response, _ := s.handleListTools(1)
+	var resp struct {
+		Result mcp.ListToolsResult `json:"result"`
+	}
+	if err := json.Unmarshal(response, &resp); err != nil {
+		t.Fatal(err)
+	}
+	for _, tool := range resp.Result.Tools {
+		if (len(tool.Icons) == 1) != (tool.Name == pingToolName) {
+			t.Errorf("tool %s icons = %+v", tool.Name, tool.Icons)
+		}
+	}
+}
+
+func TestLoadIconsRejectsBadIcons(t *testing.T) {
+	for name, tt := range map[string]struct {
+		config string
+		files  map[string][]byte
+	}{
+		"too large":    {`{"server": [{"file": "big.png"}]}`, map[string][]byte{"big.png": make([]byte, mcp.MaxIconBytes+1)}},
+		"missing":      {`{"server": [{"file": "nope.png"}]}`, nil},
+		"not https":    {`{"tools": {"ping": [{"src": "http://example.com/ping.png"}]}}`, nil},
+		"bad size":     {`{"prompts": {"query": [{"src": "https://example.com/q.png", "sizes": ["big"]}]}}`, nil},
+		"not an image": {`{"server": [{"file": "notes.txt"}]}`, map[string][]byte{"notes.txt": []byte("hello")}},
+	} {
+		if _, err := loadIcons(writeIconFiles(t, tt.config, tt.files)); err == nil {
+			t.Errorf("%s: loadIcons succeeded", name)
+		} else if name == "too large" && !errors.Is(err, mcp.ErrIconTooLarge) {
+			t.Errorf("too large: error = %v, want ErrIconTooLarge", err)
+		}
+	}
+}
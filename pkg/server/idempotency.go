@@ -0,0 +1,161 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"sqirvy/mcp/pkg/clock"
+	"sqirvy/mcp/pkg/mcp"
+)
+
+const (
+	defaultIdempotencyWindow = 10 * time.Minute // How long a result is replayed for its key
+	idempotencyMaxEntries    = 10000
+	idempotencyMaxKeyLength  = 256
+)
+
+// errIdempotencyKeyReused is returned for a key already used for a different call.
+var errIdempotencyKeyReused = errors.New("idempotency key was already used for a different tool call")
+
+// idempotentCall is a tools/call seen with an idempotency key.
+type idempotentCall struct {
+	fingerprint string          // Tool name and arguments the key was first used with
+	done        chan struct{}   // Closed when the call has finished
+	result      json.RawMessage // Set before done is closed; nil if the call failed with an RPC error
+	expires     time.Time       // Set with result
+}
+
+// idempotencyCache remembers tools/call results by client-supplied idempotency
+// key for a window, so that a host retrying a side-effecting call after a
+// timeout gets the first result instead of running the tool again. It is
+// shared by every session, like the tool limits, since a retry may arrive
+// on a new connection.
+type idempotencyCache struct {
+	mu      sync.Mutex
+	window  time.Duration // 0 disables the cache
+	entries map[string]*idempotentCall
+	clock   clock.Clock
+}
+
+func newIdempotencyCache(window time.Duration) *idempotencyCache {
+	return &idempotencyCache{window: window, entries: make(map[string]*idempotentCall), clock: clock.Real}
+}
+
+// claim returns the call recorded for key, and whether the caller owns it and
+// must run the call and then finish it. A call recorded with a different
+// fingerprint is an error.
+func (c *idempotencyCache) claim(key, fingerprint string) (call *idempotentCall, owner bool, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.clock.Now()
+	if call, ok := c.entries[key]; ok && (call.expires.IsZero() || now.Before(call.expires)) {
+		if call.fingerprint != fingerprint {
+			return nil, false, errIdempotencyKeyReused
+		}
+		return call, false, nil
+	}
+	c.prune(now)
+	call = &idempotentCall{fingerprint: fingerprint, done: make(chan struct{})}
+	c.entries[key] = call
+	return call, true, nil
+}
+
+// finish records the result of an owned call. A nil result (an RPC error such
+// as a busy tool) is not cached, so that a retry runs the call.
+func (c *idempotencyCache) finish(key string, call *idempotentCall, result json.RawMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	call.result = result
+	call.expires = c.clock.Now().Add(c.window)
+	if result == nil && c.entries[key] == call {
+		delete(c.entries, key)
+	}
+	close(call.done)
+}
+
+// prune drops expired results and, if the cache is still full, the finished
+// result that expires first. The caller holds c.mu.
+func (c *idempotencyCache) prune(now time.Time) {
+	var oldestKey string
+	var oldest time.Time
+	for key, call := range c.entries {
+		switch {
+		case call.expires.IsZero(): // Still running
+		case !now.Before(call.expires):
+			delete(c.entries, key)
+		case oldestKey == "" || call.expires.Before(oldest):
+			oldestKey, oldest = key, call.expires
+		}
+	}
+	if len(c.entries) >= idempotencyMaxEntries && oldestKey != "" {
+		delete(c.entries, oldestKey)
+	}
+}
+
+// idempotencyKey returns the idempotency key from the _meta of a tools/call,
+// or "" if there is none.
+func idempotencyKey(meta map[string]interface{}) (string, error) {
+	raw, ok := meta[mcp.MetaKeyIdempotencyKey]
+	if !ok || raw == nil {
+		return "", nil
+	}
+	key, ok := raw.(string)
+	if !ok || key == "" || len(key) > idempotencyMaxKeyLength {
+		return "", fmt.Errorf("_meta.%s must be a non-empty string of at most %d bytes", mcp.MetaKeyIdempotencyKey, idempotencyMaxKeyLength)
+	}
+	return key, nil
+}
+
+// callToolIdempotent runs a tools/call that carries an idempotency key. The
+// first call with the key runs; calls repeating it within the window, even
+// while the first is still running, get its result with
+// MetaKeyIdempotentReplay set. Keys are scoped to the tenant and client name.
+func (s *Server) callToolIdempotent(ctx context.Context, id mcp.RequestID, params mcp.CallToolParams, key string) ([]byte, error) {
+	arguments, err := json.Marshal(params.Arguments) // Map keys are sorted, so equal arguments match
+	if err != nil {
+		rpcErr := mcp.NewRPCError(mcp.ErrorCodeInvalidParams, err.Error(), nil)
+		return s.marshalErrorResponse(id, rpcErr)
+	}
+	scoped := s.tenant + "\x00" + s.clientInfo.Name + "\x00" + key
+	for {
+		call, owner, err := s.idempotency.claim(scoped, params.Name+"\x00"+string(arguments))
+		if err != nil {
+			rpcErr := mcp.NewRPCError(mcp.ErrorCodeInvalidParams, err.Error(), map[string]string{"idempotencyKey": key})
+			return s.marshalErrorResponse(id, rpcErr)
+		}
+		if owner {
+			response, err := s.callTool(ctx, id, params)
+			var resp mcp.RPCResponse
+			if json.Unmarshal(response, &resp) == nil && resp.Error == nil {
+				s.idempotency.finish(scoped, call, resp.Result)
+			} else {
+				s.idempotency.finish(scoped, call, nil)
+			}
+			return response, err
+		}
+
+		select {
+		case <-call.done:
+		case <-s.out.failed:
+			return nil, errSessionBroken
+		}
+		if call.result == nil {
+			continue // The first call failed without a result; run it again
+		}
+		s.logger.Printf("DEBUG", "Replaying the result of tools/call '%s' for idempotency key %q (ID: %v)", params.Name, key, id)
+		var result mcp.CallToolResult
+		if err := json.Unmarshal(call.result, &result); err != nil {
+			rpcErr := mcp.NewRPCError(mcp.ErrorCodeInternalError, err.Error(), nil)
+			return s.marshalErrorResponse(id, rpcErr)
+		}
+		if result.Meta == nil {
+			result.Meta = make(map[string]interface{})
+		}
+		result.Meta[mcp.MetaKeyIdempotentReplay] = true
+		return s.marshalResponse(id, result)
+	}
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"sqirvy/mcp/pkg/clock"
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/utils"
+)
+
+// callWithKey sends a tools/call carrying an idempotency key and decodes the response.
+func callWithKey(t *testing.T, s *Server, id int, args map[string]interface{}, key interface{}) (mcp.CallToolResult, *mcp.RPCError) {
+	t.Helper()
+	params := mcp.CallToolParams{Name: "counter", Arguments: args, Meta: map[string]interface{}{mcp.MetaKeyIdempotencyKey: key}}
+	payload, _ := mcp.MarshalCallToolRequest(id, params)
+	response, err := s.handleCallTool(s.requestContext(id), id, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resp struct {
+		ID     int                `json:"id"`
+		Result mcp.CallToolResult `json:"result"`
+		Error  *mcp.RPCError      `json:"error"`
+	}
+	if err := json.Unmarshal(response, &resp); err != nil || resp.ID != id {
+		t.Fatalf("tools/call = %s", response)
+	}
+	return resp.Result, resp.Error
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	var runs atomic.Int32
+	gate := make(chan struct{})
+	counter := &toolModule{name: "counter", tools: []moduleTool{{
+		tool: mcp.Tool{Name: "counter"},
+		call: func(_ context.Context, _ *Server, args map[string]interface{}) (mcp.CallToolResult, error) {
+			if args["wait"] == true {
+				<-gate
+			}
+			return mcp.NewToolResultText(string(rune('0' + runs.Add(1)))), nil
+		},
+	}}}
+	cache := newIdempotencyCache(time.Minute)
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+	cache.clock = fake
+	session := func(client string) *Server {
+		s := NewServer(&captureTransport{}, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+		s.modules = []*toolModule{counter}
+		s.idempotency = cache
+		s.clientInfo = mcp.Implementation{Name: client}
+		return s
+	}
+	alice := session("alice")
+
+	first, _ := callWithKey(t, alice, 1, nil, "k1")
+	again, _ := callWithKey(t, session("alice"), 2, nil, "k1")
+	if mustText(first) != "1" || mustText(again) != "1" || again.Meta[mcp.MetaKeyIdempotentReplay] != true || first.Meta != nil {
+		t.Errorf("replay = %q %v, first = %q %v", mustText(again), again.Meta, mustText(first), first.Meta)
+	}
+	if result, _ := callWithKey(t, session("bob"), 3, nil, "k1"); mustText(result) != "2" {
+		t.Errorf("another client's key = %q, want a new run", mustText(result))
+	}
+	if _, rpcErr := callWithKey(t, alice, 4, map[string]interface{}{"x": 1}, "k1"); rpcErr == nil || rpcErr.Code != mcp.ErrorCodeInvalidParams {
+		t.Errorf("key reused with other arguments: %v", rpcErr)
+	}
+	if _, rpcErr := callWithKey(t, alice, 5, nil, 42); rpcErr == nil || rpcErr.Code != mcp.ErrorCodeInvalidParams {
+		t.Errorf("non-string key: %v", rpcErr)
+	}
+	fake.Advance(2 * time.Minute)
+	if result, _ := callWithKey(t, alice, 6, nil, "k1"); mustText(result) != "3" {
+		t.Errorf("expired key = %q, want a new run", mustText(result))
+	}
+
+	// A duplicate of a call still running waits for its result.
+	var wg sync.WaitGroup
+	results := make([]string, 2)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, _ := callWithKey(t, session("alice"), 10+i, map[string]interface{}{"wait": true}, "k2")
+			results[i] = mustText(result)
+		}()
+		time.Sleep(20 * time.Millisecond)
+	}
+	close(gate)
+	wg.Wait()
+	if results[0] != "4" || results[1] != "4" || runs.Load() != 4 {
+		t.Errorf("concurrent duplicates = %v after %d runs", results, runs.Load())
+	}
+
+	alice.idempotency = newIdempotencyCache(0)
+	callWithKey(t, alice, 20, nil, "k3")
+	if result, _ := callWithKey(t, alice, 21, nil, "k3"); mustText(result) != "6" {
+		t.Errorf("disabled cache = %q, want a new run", mustText(result))
+	}
+}
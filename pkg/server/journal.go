@@ -0,0 +1,161 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"sqirvy/mcp/pkg/journal"
+	"sqirvy/mcp/pkg/mcp"
+)
+
+const (
+	journalDefaultLimit = 50
+	// journalRecentURI is the resource with the newest journal entries.
+	journalRecentURI = "journal://recent"
+)
+
+// journalRequest records a handled request in the request journal, if there is one.
+func (s *Server) journalRequest(id mcp.RequestID, method string, received time.Time, payload, response []byte) {
+	if s.journal == nil {
+		return
+	}
+	requestID, _ := json.Marshal(id) // IDs came from JSON
+	var resp struct {
+		Error *mcp.RPCError `json:"error"`
+	}
+	json.Unmarshal(response, &resp)
+	e := journal.Entry{
+		Time:      received,
+		Session:   s.journalSession,
+		Client:    s.clientInfo.Name,
+		RequestID: string(requestID),
+		Method:    method,
+		Duration:  s.clock.Since(received),
+		Request:   string(payload),
+		Response:  string(response),
+	}
+	if resp.Error != nil {
+		e.ErrorCode = resp.Error.Code
+	}
+	s.journal.Record(e)
+}
+
+// journalModule returns the query_journal tool and the journal://recent
+// resource over j, for debugging from an MCP client.
+func journalModule(j *journal.Journal) *toolModule {
+	return &toolModule{
+		name: "journal",
+		tools: []moduleTool{
+			{
+				tool: mcp.Tool{
+					Name:  "query_journal",
+					Title: "Query Request Journal",
+					Description: "Searches the server's request journal, newest first: which requests each session made, " +
+						"how long they took and which failed.",
+					InputSchema: mcp.ToolInputSchema{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"method":      map[string]interface{}{"type": "string", "description": "Only requests for this method, e.g. tools/call"},
+							"client":      map[string]interface{}{"type": "string", "description": "Only requests from clients with this name"},
+							"session":     map[string]interface{}{"type": "integer", "description": "Only requests of this session"},
+							"since":       map[string]interface{}{"type": "string", "description": "Only requests received after this time (RFC 3339) or this long ago, e.g. 15m"},
+							"errors_only": map[string]interface{}{"type": "boolean", "description": "Only requests answered with an error"},
+							"limit": map[string]interface{}{
+								"type": "integer", "default": journalDefaultLimit,
+								"description": fmt.Sprintf("Maximum entries to return (default %d, at most %d)", journalDefaultLimit, journal.MaxLimit),
+							},
+						},
+					},
+				},
+				call: func(_ context.Context, session *Server, raw map[string]interface{}) (mcp.CallToolResult, error) {
+					var args struct {
+						Method     string `json:"method"`
+						Client     string `json:"client"`
+						Session    int64  `json:"session"`
+						Since      string `json:"since"`
+						ErrorsOnly bool   `json:"errors_only"`
+						Limit      int    `json:"limit"`
+					}
+					if err := decodeToolArgs(raw, &args); err != nil {
+						return mcp.CallToolResult{}, err
+					}
+					filter := journal.Filter{Method: args.Method, Client: args.Client, Session: args.Session, ErrorsOnly: args.ErrorsOnly, Limit: args.Limit}
+					if filter.Limit == 0 {
+						filter.Limit = journalDefaultLimit
+					}
+					if filter.Limit < 1 || filter.Limit > journal.MaxLimit {
+						return mcp.CallToolResult{}, fmt.Errorf("limit must be between 1 and %d", journal.MaxLimit)
+					}
+					if args.Since != "" {
+						since, err := parseSince(args.Since, session.clock.Now())
+						if err != nil {
+							return mcp.CallToolResult{}, err
+						}
+						filter.Since = since
+					}
+					entries, err := j.Query(filter)
+					if err != nil {
+						return mcp.CallToolResult{}, err
+					}
+					if len(entries) == 0 {
+						return mcp.NewToolResultText("No matching requests."), nil
+					}
+					return structuredResult(formatJournal(entries), journalRecentURI, entries)
+				},
+			},
+		},
+		resources: []moduleResource{
+			{
+				resource: mcp.Resource{
+					URI:         journalRecentURI,
+					Name:        "Recent requests",
+					Description: fmt.Sprintf("The %d newest entries of the request journal, as JSON", journalDefaultLimit),
+					MimeType:    "application/json",
+				},
+				read: func(context.Context) (string, error) {
+					entries, err := j.Query(journal.Filter{Limit: journalDefaultLimit})
+					if err != nil {
+						return "", err
+					}
+					data, err := json.MarshalIndent(entries, "", "  ")
+					return string(data), err
+				},
+			},
+		},
+		check: func() (string, error) {
+			if j.Bodies() {
+				return "journaling requests with their payloads", nil
+			}
+			return "journaling requests without their payloads", nil
+		},
+	}
+}
+
+// parseSince parses a point in time given as RFC 3339 or as a duration before now.
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("since must be an RFC 3339 time or a duration such as 15m, got %q", value)
+	}
+	return t, nil
+}
+
+// formatJournal renders entries one per line for the model.
+func formatJournal(entries []journal.Entry) string {
+	var text strings.Builder
+	for _, e := range entries {
+		status := "ok"
+		if e.ErrorCode != 0 {
+			status = fmt.Sprintf("error %d", e.ErrorCode)
+		}
+		fmt.Fprintf(&text, "%s session %d (%s) %s id=%s %v %s\n",
+			e.Time.Format(time.RFC3339Nano), e.Session, e.Client, e.Method, e.RequestID, e.Duration.Round(time.Microsecond), status)
+	}
+	return text.String()
+}
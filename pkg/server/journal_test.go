@@ -0,0 +1,68 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"sqirvy/mcp/pkg/journal"
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/utils"
+)
+
+func TestJournalRecordsRequests(t *testing.T) {
+	if _, err := exec.LookPath(journal.Command); err != nil {
+		t.Skipf("%s not installed", journal.Command)
+	}
+	j, err := journal.Open(filepath.Join(t.TempDir(), "journal.db"), journal.Options{Bodies: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := &captureTransport{written: make(chan []byte, 16)}
+	s := NewServer(tr, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	s.journal, s.journalSession = j, j.NewSession()
+	s.modules = []*toolModule{journalModule(j)}
+
+	s.processMessage([]byte(initializeRequest))
+	s.processMessage([]byte(initializedNotify))
+	readWire(t, tr.written)
+	s.processMessage([]byte(`{"jsonrpc":"2.0","id":"a","method":"tools/list"}`))
+	s.processMessage([]byte(`{"jsonrpc":"2.0","id":3,"method":"no/such"}`))
+	readWire(t, tr.written)
+	readWire(t, tr.written)
+	s.handlers.Wait()
+	j.Close() // Writes the queued entries
+
+	result := callTool(t, s, "query_journal", map[string]interface{}{"errors_only": true})
+	var content mcp.TextContent
+	json.Unmarshal(result.Content[0], &content)
+	if text := content.Text; !strings.Contains(text, "session 1 (test) no/such id=3 ") || !strings.Contains(text, "error -32601") || strings.Contains(text, "tools/list") {
+		t.Errorf("query_journal = %q", content.Text)
+	}
+	recent, err := journalModule(j).resources[0].read(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(recent, `"requestId": "\"a\""`) || !strings.Contains(recent, `"request": "{\"jsonrpc\":\"2.0\",\"id\":\"a\"`) {
+		t.Errorf("journal://recent = %s", recent)
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	if got, err := parseSince("15m", now); err != nil || !got.Equal(now.Add(-15*time.Minute)) {
+		t.Errorf("parseSince(15m) = %v, %v", got, err)
+	}
+	if got, err := parseSince("2026-05-01T10:00:00Z", now); err != nil || got.Hour() != 10 {
+		t.Errorf("parseSince(RFC 3339) = %v, %v", got, err)
+	}
+	if _, err := parseSince("yesterday", now); err == nil {
+		t.Error("parseSince accepted yesterday")
+	}
+}
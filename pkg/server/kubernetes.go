@@ -0,0 +1,267 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/tools"
+)
+
+const (
+	k8sTimeout           = 30 * time.Second // Per kubectl invocation
+	k8sDefaultTailLines  = 200
+	k8sMaxTailLines      = 5000
+	k8sClusterURI        = "k8s://cluster"
+	k8sNamespacesURI     = "k8s://namespaces"
+	k8sConcurrentKubectl = 4 // Default concurrency limit of each tool; each call is a process
+)
+
+var (
+	// k8sNamePattern matches Kubernetes object and namespace names (DNS subdomains).
+	k8sNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`)
+	// k8sKindPattern matches resource types as kubectl accepts them: pods, deploy, deployments.apps, ...
+	k8sKindPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9.]*$`)
+)
+
+// k8sHiddenKinds are resource types the module refuses to show, since their
+// contents are credentials rather than state worth debugging.
+var k8sHiddenKinds = map[string]bool{"secret": true, "secrets": true}
+
+// k8sObjectArgs are the arguments shared by the Kubernetes tools.
+type k8sObjectArgs struct {
+	Resource      string `json:"resource"`
+	Name          string `json:"name"`
+	Namespace     string `json:"namespace"`
+	AllNamespaces bool   `json:"all_namespaces"`
+	LabelSelector string `json:"label_selector"`
+}
+
+// kubectlArgs validates the arguments and returns them as kubectl flags. Every
+// value is passed as part of a single "--flag=value" or positional argument that
+// has been checked against a pattern, so arguments cannot smuggle in other flags.
+func (a k8sObjectArgs) kubectlArgs(requireName bool) ([]string, error) {
+	kind := strings.ToLower(a.Resource)
+	if !k8sKindPattern.MatchString(a.Resource) {
+		return nil, fmt.Errorf("invalid resource type %q", a.Resource)
+	}
+	if k8sHiddenKinds[strings.SplitN(kind, ".", 2)[0]] {
+		return nil, fmt.Errorf("resource type %q is not available through this server", a.Resource)
+	}
+	args := []string{a.Resource}
+	switch {
+	case a.Name != "":
+		if !k8sNamePattern.MatchString(a.Name) {
+			return nil, fmt.Errorf("invalid name %q", a.Name)
+		}
+		args = append(args, a.Name)
+	case requireName:
+		return nil, fmt.Errorf("name is required")
+	}
+	switch {
+	case a.AllNamespaces:
+		args = append(args, "--all-namespaces")
+	case a.Namespace != "":
+		if !k8sNamePattern.MatchString(a.Namespace) {
+			return nil, fmt.Errorf("invalid namespace %q", a.Namespace)
+		}
+		args = append(args, "--namespace="+a.Namespace)
+	}
+	if a.LabelSelector != "" {
+		args = append(args, "--selector="+a.LabelSelector)
+	}
+	return args, nil
+}
+
+// kubernetesModule returns the Kubernetes tools and resources, which run kubectl
+// read operations (get, describe, logs) against the cluster selected by k.
+// Nothing in the module can change the cluster.
+func kubernetesModule(k tools.Kubectl) *toolModule {
+	if k.Timeout == 0 {
+		k.Timeout = k8sTimeout
+	}
+	stringProp := func(description string) map[string]interface{} {
+		return map[string]interface{}{"type": "string", "description": description}
+	}
+	objectProps := map[string]interface{}{
+		"resource":       stringProp("Resource type, e.g. pods, deployments, services, nodes, events"),
+		"name":           stringProp("Object name; all objects of the type if omitted"),
+		"namespace":      stringProp("Namespace; the context's default if omitted"),
+		"all_namespaces": map[string]interface{}{"type": "boolean", "description": "List across all namespaces"},
+		"label_selector": stringProp("Label selector, e.g. app=web,tier!=cache"),
+	}
+
+	return &toolModule{
+		name: "kubernetes",
+		tools: []moduleTool{
+			{
+				tool: mcp.Tool{
+					Name:        "k8s_get",
+					Title:       "Get Kubernetes Resources",
+					Description: "Lists or shows Kubernetes objects (kubectl get). Secrets are not available.",
+					InputSchema: mcp.ToolInputSchema{
+						"type": "object",
+						"properties": mergeProps(objectProps, map[string]interface{}{
+							"output": map[string]interface{}{"type": "string", "enum": []string{"table", "wide", "yaml", "json"}, "description": "Output format (default table)"},
+						}),
+						"required": []string{"resource"},
+					},
+				},
+				limit: k8sConcurrentKubectl,
+				call: func(ctx context.Context, _ *Server, raw map[string]interface{}) (mcp.CallToolResult, error) {
+					var args struct {
+						k8sObjectArgs
+						Output string `json:"output"`
+					}
+					if err := decodeToolArgs(raw, &args); err != nil {
+						return mcp.CallToolResult{}, err
+					}
+					kubectlArgs, err := args.kubectlArgs(false)
+					if err != nil {
+						return mcp.CallToolResult{}, err
+					}
+					switch args.Output {
+					case "", "table":
+					case "wide", "yaml", "json":
+						kubectlArgs = append(kubectlArgs, "--output="+args.Output)
+					default:
+						return mcp.CallToolResult{}, fmt.Errorf("invalid output %q (want table, wide, yaml or json)", args.Output)
+					}
+					output, err := k.Run(ctx, append([]string{"get"}, kubectlArgs...)...)
+					return mcp.NewToolResultText(output), err
+				},
+			},
+			{
+				tool: mcp.Tool{
+					Name:        "k8s_describe",
+					Title:       "Describe Kubernetes Resource",
+					Description: "Describes a Kubernetes object, including its recent events (kubectl describe).",
+					InputSchema: mcp.ToolInputSchema{
+						"type":       "object",
+						"properties": objectProps,
+						"required":   []string{"resource"},
+					},
+				},
+				limit: k8sConcurrentKubectl,
+				call: func(ctx context.Context, _ *Server, raw map[string]interface{}) (mcp.CallToolResult, error) {
+					var args k8sObjectArgs
+					if err := decodeToolArgs(raw, &args); err != nil {
+						return mcp.CallToolResult{}, err
+					}
+					kubectlArgs, err := args.kubectlArgs(false)
+					if err != nil {
+						return mcp.CallToolResult{}, err
+					}
+					output, err := k.Run(ctx, append([]string{"describe"}, kubectlArgs...)...)
+					return mcp.NewToolResultText(output), err
+				},
+			},
+			{
+				tool: mcp.Tool{
+					Name:        "k8s_logs",
+					Title:       "Kubernetes Pod Logs",
+					Description: "Returns the most recent log lines of a pod's container (kubectl logs).",
+					InputSchema: mcp.ToolInputSchema{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"pod":        stringProp("Pod name"),
+							"namespace":  stringProp("Namespace; the context's default if omitted"),
+							"container":  stringProp("Container name; required for pods with several containers"),
+							"tail_lines": map[string]interface{}{"type": "integer", "description": fmt.Sprintf("Number of lines (default %d, at most %d)", k8sDefaultTailLines, k8sMaxTailLines)},
+							"since":      stringProp("Only lines newer than this duration, e.g. 10m or 2h"),
+							"previous":   map[string]interface{}{"type": "boolean", "description": "Logs of the previous, terminated container instance"},
+						},
+						"required": []string{"pod"},
+					},
+				},
+				limit: k8sConcurrentKubectl,
+				call: func(ctx context.Context, _ *Server, raw map[string]interface{}) (mcp.CallToolResult, error) {
+					var args struct {
+						Pod       string `json:"pod"`
+						Namespace string `json:"namespace"`
+						Container string `json:"container"`
+						TailLines int    `json:"tail_lines"`
+						Since     string `json:"since"`
+						Previous  bool   `json:"previous"`
+					}
+					if err := decodeToolArgs(raw, &args); err != nil {
+						return mcp.CallToolResult{}, err
+					}
+					kubectlArgs, err := k8sObjectArgs{Resource: "pod", Name: args.Pod, Namespace: args.Namespace}.kubectlArgs(true)
+					if err != nil {
+						return mcp.CallToolResult{}, err
+					}
+					kubectlArgs = append([]string{"logs"}, kubectlArgs[1:]...) // "kubectl logs NAME", not "logs pod NAME"
+					if args.Container != "" {
+						if !k8sNamePattern.MatchString(args.Container) {
+							return mcp.CallToolResult{}, fmt.Errorf("invalid container %q", args.Container)
+						}
+						kubectlArgs = append(kubectlArgs, "--container="+args.Container)
+					}
+					tail := args.TailLines
+					if tail <= 0 {
+						tail = k8sDefaultTailLines
+					}
+					kubectlArgs = append(kubectlArgs, "--tail="+strconv.Itoa(min(tail, k8sMaxTailLines)))
+					if args.Since != "" {
+						if _, err := time.ParseDuration(args.Since); err != nil {
+							return mcp.CallToolResult{}, fmt.Errorf("invalid since %q: %v", args.Since, err)
+						}
+						kubectlArgs = append(kubectlArgs, "--since="+args.Since)
+					}
+					if args.Previous {
+						kubectlArgs = append(kubectlArgs, "--previous")
+					}
+					output, err := k.Run(ctx, kubectlArgs...)
+					return mcp.NewToolResultText(output), err
+				},
+			},
+		},
+		resources: []moduleResource{
+			{
+				resource: mcp.Resource{Name: "Kubernetes cluster", URI: k8sClusterURI, MimeType: "text/plain",
+					Description: "The current kubectl context and the cluster's control plane endpoints."},
+				read: func(ctx context.Context) (string, error) {
+					current, err := k.Run(ctx, "config", "current-context")
+					if err != nil {
+						return "", err
+					}
+					info, err := k.Run(ctx, "cluster-info")
+					return "Context: " + strings.TrimSpace(current) + "\n\n" + info, err
+				},
+			},
+			{
+				resource: mcp.Resource{Name: "Kubernetes namespaces", URI: k8sNamespacesURI, MimeType: "text/plain",
+					Description: "The namespaces of the cluster with their status and age."},
+				read: func(ctx context.Context) (string, error) { return k.Run(ctx, "get", "namespaces") },
+			},
+		},
+		check: func() (string, error) {
+			path, err := exec.LookPath(tools.KubectlCommand)
+			if err != nil {
+				return "", err
+			}
+			current, err := k.Run(context.Background(), "config", "current-context")
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%s, context %s", path, strings.TrimSpace(current)), nil
+		},
+	}
+}
+
+// mergeProps returns the union of JSON Schema property maps; later maps win.
+func mergeProps(props ...map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{})
+	for _, p := range props {
+		for name, schema := range p {
+			merged[name] = schema
+		}
+	}
+	return merged
+}
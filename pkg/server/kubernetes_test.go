@@ -0,0 +1,104 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/tools"
+	"sqirvy/mcp/pkg/utils"
+)
+
+// fakeKubectl puts a kubectl on the PATH that prints its arguments.
+func fakeKubectl(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	script := "#!/bin/sh\necho \"kubectl $*\"\n"
+	if err := os.WriteFile(filepath.Join(dir, tools.KubectlCommand), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// callTool runs a tools/call through the server and returns the result.
+func callTool(t *testing.T, s *Server, name string, args map[string]interface{}) mcp.CallToolResult {
+	t.Helper()
+	payload, _ := mcp.MarshalCallToolRequest(1, mcp.CallToolParams{Name: name, Arguments: args})
+	response, err := s.handleCallTool(s.requestContext(1), 1, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resp struct {
+		Result mcp.CallToolResult `json:"result"`
+		Error  *mcp.RPCError      `json:"error"`
+	}
+	if err := json.Unmarshal(response, &resp); err != nil || resp.Error != nil {
+		t.Fatalf("tools/call %s = %s", name, response)
+	}
+	return resp.Result
+}
+
+func TestKubernetesTools(t *testing.T) {
+	fakeKubectl(t)
+	s := NewServer(&captureTransport{}, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	s.modules = []*toolModule{kubernetesModule(tools.Kubectl{Kubeconfig: "/etc/kube.conf"})}
+
+	tests := []struct {
+		tool    string
+		args    map[string]interface{}
+		want    string // Expected command line, or error text if isError
+		isError bool
+	}{
+		{"k8s_get", map[string]interface{}{"resource": "pods", "namespace": "web", "label_selector": "app=api", "output": "wide"},
+			"kubectl --kubeconfig=/etc/kube.conf get pods --namespace=web --selector=app=api --output=wide", false},
+		{"k8s_describe", map[string]interface{}{"resource": "deployment", "name": "api", "all_namespaces": true},
+			"kubectl --kubeconfig=/etc/kube.conf describe deployment api --all-namespaces", false},
+		{"k8s_logs", map[string]interface{}{"pod": "api-1", "container": "app", "since": "5m"},
+			"kubectl --kubeconfig=/etc/kube.conf logs api-1 --container=app --tail=200 --since=5m", false},
+		{"k8s_get", map[string]interface{}{"resource": "secrets"}, "not available", true},
+		{"k8s_get", map[string]interface{}{"resource": "pods", "name": "--help"}, "invalid name", true},
+		{"k8s_get", map[string]interface{}{"resource": "delete pods"}, "invalid resource type", true},
+		{"k8s_logs", map[string]interface{}{}, "argument 'pod' is missing", true},
+	}
+	for _, tt := range tests {
+		result := callTool(t, s, tt.tool, tt.args)
+		var text mcp.TextContent
+		json.Unmarshal(result.Content[0], &text)
+		if result.IsError != tt.isError || !strings.Contains(text.Text, tt.want) {
+			t.Errorf("%s %v = %q (isError %v), want %q (isError %v)", tt.tool, tt.args, text.Text, result.IsError, tt.want, tt.isError)
+		}
+	}
+
+	response, _ := s.handleReadResource(s.requestContext(2), 2, []byte(`{"jsonrpc":"2.0","id":2,"method":"resources/read","params":{"uri":"k8s://namespaces"}}`))
+	if !strings.Contains(string(response), "kubectl --kubeconfig=/etc/kube.conf get namespaces") {
+		t.Errorf("resources/read k8s://namespaces = %s", response)
+	}
+}
+
+func TestKubernetesToolTimeoutKeepsOutput(t *testing.T) {
+	dir := t.TempDir()
+	script := "#!/bin/sh\necho \"NAME READY\"\nsleep 10\n"
+	if err := os.WriteFile(filepath.Join(dir, tools.KubectlCommand), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	s := NewServer(&captureTransport{}, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	s.modules = []*toolModule{kubernetesModule(tools.Kubectl{Timeout: 300 * time.Millisecond})}
+
+	result := callTool(t, s, "k8s_get", map[string]interface{}{"resource": "pods"})
+	if !result.IsError || len(result.Content) != 2 {
+		t.Fatalf("result = %+v, want an error with the partial output", result)
+	}
+	var message, partial mcp.TextContent
+	json.Unmarshal(result.Content[0], &message)
+	json.Unmarshal(result.Content[1], &partial)
+	if !strings.Contains(message.Text, "kubectl timed out after 300ms") || !strings.Contains(partial.Text, "NAME READY") {
+		t.Errorf("content = %q, %q", message.Text, partial.Text)
+	}
+}
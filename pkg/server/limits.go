@@ -0,0 +1,119 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"sqirvy/mcp/pkg/clock"
+)
+
+// defaultToolQueueTimeout is how long a tool call waits for a free slot before failing.
+const defaultToolQueueTimeout = 30 * time.Second
+
+// defaultToolLimits caps concurrent calls for tools that must not run in parallel.
+// Tools without an entry are unlimited. Override with the -tool-limits flag.
+var defaultToolLimits = map[string]int{
+	pingToolName: 1, // Spawns an external process
+}
+
+// errToolQueueTimeout is returned when a call waited too long for a free slot.
+var errToolQueueTimeout = errors.New("timed out waiting for a free tool slot")
+
+// toolLimiter bounds the number of concurrent calls per tool with one
+// semaphore per limited tool. Calls over the limit queue until a slot frees
+// up or the queue timeout expires.
+type toolLimiter struct {
+	mu           sync.Mutex
+	limits       map[string]int
+	slots        map[string]chan struct{} // Created lazily, capacity = limit
+	queueTimeout time.Duration
+	clock        clock.Clock
+}
+
+// newToolLimiter creates a limiter. A limit <= 0 means unlimited.
+func newToolLimiter(limits map[string]int, queueTimeout time.Duration) *toolLimiter {
+	l := &toolLimiter{
+		limits:       make(map[string]int, len(limits)),
+		slots:        make(map[string]chan struct{}),
+		queueTimeout: queueTimeout,
+		clock:        clock.Real,
+	}
+	for name, n := range limits {
+		l.limits[name] = n
+	}
+	return l
+}
+
+// semaphore returns the slot channel for tool, or nil if the tool is unlimited.
+func (l *toolLimiter) semaphore(tool string) chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := l.limits[tool]
+	if n <= 0 {
+		return nil
+	}
+	sem, ok := l.slots[tool]
+	if !ok {
+		sem = make(chan struct{}, n)
+		l.slots[tool] = sem
+	}
+	return sem
+}
+
+// acquire waits for a slot for tool and returns the function that frees it.
+func (l *toolLimiter) acquire(tool string) (release func(), err error) {
+	return l.acquireUntil(tool, nil)
+}
+
+// acquireUntil is acquire, but gives up with errSessionBroken once cancel is
+// closed. A nil cancel never fires.
+func (l *toolLimiter) acquireUntil(tool string, cancel <-chan struct{}) (release func(), err error) {
+	sem := l.semaphore(tool)
+	if sem == nil {
+		return func() {}, nil
+	}
+	release = func() { <-sem }
+
+	// Fast path without a timer
+	select {
+	case sem <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	timer := l.clock.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+	select {
+	case sem <- struct{}{}:
+		return release, nil
+	case <-timer.C():
+		return nil, errToolQueueTimeout
+	case <-cancel:
+		return nil, errSessionBroken
+	}
+}
+
+// parseToolLimits parses a -tool-limits value such as "ping=1,fetch=4".
+func parseToolLimits(spec string) (map[string]int, error) {
+	limits := make(map[string]int)
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(field, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid tool limit %q, want name=count", field)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid count in tool limit %q", field)
+		}
+		limits[strings.TrimSpace(name)] = n
+	}
+	return limits, nil
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"sqirvy/mcp/pkg/clock"
+)
+
+func TestParseToolLimits(t *testing.T) {
+	got, err := parseToolLimits(" ping=1, fetch = 4 ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got["ping"] != 1 || got["fetch"] != 4 {
+		t.Errorf("parseToolLimits = %v", got)
+	}
+	if got, err := parseToolLimits(""); err != nil || len(got) != 0 {
+		t.Errorf("parseToolLimits(\"\") = %v, %v", got, err)
+	}
+	for _, bad := range []string{"ping", "=1", "ping=x", "ping=-1"} {
+		if _, err := parseToolLimits(bad); err == nil {
+			t.Errorf("parseToolLimits(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestToolLimiterBoundsConcurrency(t *testing.T) {
+	l := newToolLimiter(map[string]int{"exec": 2}, time.Second)
+
+	var running, peak atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := l.acquire("exec")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer release()
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(2 * time.Millisecond)
+			running.Add(-1)
+		}()
+	}
+	wg.Wait()
+	if p := peak.Load(); p != 2 {
+		t.Errorf("peak concurrency = %d, want 2", p)
+	}
+}
+
+func TestToolLimiterQueueTimeout(t *testing.T) {
+	l := newToolLimiter(map[string]int{"exec": 1, "free": 0}, 10*time.Millisecond)
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+	l.clock = fake
+
+	release, err := l.acquire("exec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	queued := make(chan error, 1)
+	go func() {
+		_, err := l.acquire("exec")
+		queued <- err
+	}()
+	for fake.Timers() == 0 {
+		time.Sleep(time.Millisecond) // Wait for the call to queue
+	}
+	fake.Advance(9 * time.Millisecond)
+	select {
+	case err := <-queued:
+		t.Fatalf("second acquire returned %v before the queue timeout", err)
+	default:
+	}
+	fake.Advance(time.Millisecond)
+	if err := <-queued; err != errToolQueueTimeout {
+		t.Fatalf("second acquire error = %v, want errToolQueueTimeout", err)
+	}
+	release()
+	release, err = l.acquire("exec")
+	if err != nil {
+		t.Fatalf("acquire after release failed: %v", err)
+	}
+	release()
+
+	// Unlimited and unknown tools never block
+	for i := 0; i < 100; i++ {
+		if _, err := l.acquire("free"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := l.acquire("other"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"net"
+	"os"
+	"sync"
+
+	"sqirvy/mcp/pkg/transport"
+	"sqirvy/mcp/pkg/utils"
+)
+
+// serveSocket listens on spec (see transport.ParseAddress) and runs one server session
+// per accepted connection. Each client selects its framing when it connects: newline-
+// delimited JSON by default, or length-prefixed frames (see transport.Accept).
+//
+// When stop is closed, serveSocket stops accepting connections, drains every open
+// session (see drain.go) and returns nil once they have all closed. Otherwise it
+// returns only if the listener fails.
+func serveSocket(spec string, newSession func(transport.Transport) *Server, stop <-chan struct{}, logger *utils.Logger) error {
+	network, address, err := transport.ParseAddress(spec)
+	if err != nil {
+		return err
+	}
+	if network == "unix" {
+		// Remove a stale socket left behind by a previous run
+		if info, err := os.Stat(address); err == nil && info.Mode()&os.ModeSocket != 0 {
+			os.Remove(address)
+		}
+	}
+	ln, err := net.Listen(network, address)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+	logger.Printf("DEBUG", "Listening on %s %s", network, ln.Addr())
+
+	sessions := &sessionSet{sessions: make(map[*Server]struct{})}
+	go func() {
+		<-stop
+		logger.Println("DEBUG", "Draining: no longer accepting connections")
+		ln.Close()
+		sessions.drainAll("server shutting down")
+	}()
+
+	var wg sync.WaitGroup
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			select {
+			case <-stop:
+				wg.Wait()
+				logger.Println("DEBUG", "All sessions drained")
+				return nil
+			default:
+				return err
+			}
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			serveConn(conn, newSession, sessions, logger)
+		}()
+	}
+}
+
+// sessionSet tracks the open sessions of a listener so that they can be drained together.
+type sessionSet struct {
+	mu       sync.Mutex
+	sessions map[*Server]struct{}
+	draining string // Drain reason once drainAll has been called
+}
+
+// add registers s. A session added after drainAll starts out draining.
+func (ss *sessionSet) add(s *Server) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	if ss.draining != "" {
+		s.Drain(ss.draining)
+	}
+	ss.sessions[s] = struct{}{}
+}
+
+// remove unregisters s.
+func (ss *sessionSet) remove(s *Server) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	delete(ss.sessions, s)
+}
+
+// drainAll drains every registered session and every session added later.
+func (ss *sessionSet) drainAll(reason string) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.draining = reason
+	for s := range ss.sessions {
+		s.Drain(reason)
+	}
+}
+
+// serveConn runs a single session on conn and closes it when the client disconnects
+// or the session has drained.
+func serveConn(conn net.Conn, newSession func(transport.Transport) *Server, sessions *sessionSet, logger *utils.Logger) {
+	remote := conn.RemoteAddr()
+	t, framing, err := transport.Accept(conn)
+	if err != nil {
+		logger.Printf("DEBUG", "Connection from %v failed during setup: %v", remote, err)
+		conn.Close()
+		return
+	}
+	logger.Printf("DEBUG", "Session started for %v (%s framing)", remote, framing)
+	server := newSession(t)
+	sessions.add(server)
+	defer sessions.remove(server)
+	if err := server.Run(); err != nil {
+		logger.Printf("DEBUG", "Session for %v ended with error: %v", remote, err)
+	}
+	t.Close()
+	logger.Printf("DEBUG", "Session for %v closed", remote)
+}
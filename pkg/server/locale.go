@@ -0,0 +1,123 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"sqirvy/mcp/pkg/mcp"
+)
+
+// serverInstructions are the instructions sent in the InitializeResult.
+const serverInstructions = "Welcome to the Go MCP Example Server! The 'random_data' resource, 'ping' tool, and 'query' prompt are available."
+
+// localeStrings are the translated server strings for one locale. Strings
+// left out fall back to the built-in English ones.
+type localeStrings struct {
+	Instructions string            `json:"instructions"`
+	Tools        map[string]string `json:"tools"`   // Tool name -> description
+	Prompts      map[string]string `json:"prompts"` // Prompt name -> description
+}
+
+// localeCatalog holds the translations loaded with -locales, keyed by
+// lower-case BCP 47 tag, e.g.
+//
+//	{"fr": {"instructions": "Bienvenue !", "tools": {"ping": "Envoie un ping ..."}},
+//	 "de-CH": {"tools": {...}}}
+type localeCatalog map[string]localeStrings
+
+// loadLocaleCatalog reads a catalog file.
+func loadLocaleCatalog(path string) (localeCatalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read locales: %w", err)
+	}
+	var raw map[string]localeStrings
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid locales file %s: %w", path, err)
+	}
+	catalog := make(localeCatalog, len(raw))
+	for tag, translated := range raw {
+		key := normalizeLocale(tag)
+		if key == "" {
+			return nil, fmt.Errorf("invalid locales file %s: empty locale tag", path)
+		}
+		catalog[key] = translated
+	}
+	return catalog, nil
+}
+
+// normalizeLocale lower-cases a tag and accepts POSIX spellings such as "fr_CA.UTF-8".
+func normalizeLocale(tag string) string {
+	tag, _, _ = strings.Cut(strings.TrimSpace(tag), ".")
+	return strings.ToLower(strings.ReplaceAll(tag, "_", "-"))
+}
+
+// match returns the catalog locale for tag: the tag itself or, failing that,
+// the longest less specific tag ("zh-hant" for "zh-Hant-TW", "fr" for "fr-CA").
+func (c localeCatalog) match(tag string) (string, bool) {
+	for key := normalizeLocale(tag); key != ""; {
+		if _, ok := c[key]; ok {
+			return key, true
+		}
+		i := strings.LastIndex(key, "-")
+		if i < 0 {
+			break
+		}
+		key = key[:i]
+	}
+	return "", false
+}
+
+// selectLocale picks the session locale from the locale hint of an initialize
+// request: the ExperimentalLocale capability, or else _meta.locale. It
+// returns "" if the client gave none or the catalog has no match.
+func (c localeCatalog) selectLocale(params mcp.InitializeParams) string {
+	var hint string
+	var settings mcp.LocaleSettings
+	if ok, err := params.Capabilities.Experimental.Decode(mcp.ExperimentalLocale, &settings); ok && err == nil {
+		hint = settings.Locale
+	}
+	if hint == "" {
+		hint, _ = params.Meta[mcp.MetaKeyLocale].(string)
+	}
+	locale, _ := c.match(hint)
+	return locale
+}
+
+// negotiateLocale is the negotiator of the ExperimentalLocale capability. It
+// runs after the session locale is chosen and announces it.
+func (s *Server) negotiateLocale(json.RawMessage) (interface{}, bool) {
+	if s.locale == "" {
+		return nil, false
+	}
+	return mcp.LocaleSettings{Locale: s.locale}, true
+}
+
+// localized returns the session locale's strings, which are empty without a locale.
+func (s *Server) localized() localeStrings {
+	return s.locales[s.locale]
+}
+
+// localizeTools replaces tool descriptions with those of the session locale.
+func (s *Server) localizeTools(tools []mcp.Tool) []mcp.Tool {
+	descriptions := s.localized().Tools
+	for i, t := range tools {
+		if d, ok := descriptions[t.Name]; ok && d != "" {
+			tools[i].Description = d
+		}
+	}
+	return tools
+}
+
+// localizePrompts replaces prompt descriptions with those of the session locale.
+func (s *Server) localizePrompts(prompts []mcp.Prompt) []mcp.Prompt {
+	descriptions := s.localized().Prompts
+	for i, p := range prompts {
+		if d, ok := descriptions[p.Name]; ok && d != "" {
+			prompts[i].Description = d
+		}
+	}
+	return prompts
+}
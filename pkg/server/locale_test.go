@@ -0,0 +1,81 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/utils"
+)
+
+func TestLocaleCatalogMatch(t *testing.T) {
+	catalog := localeCatalog{"fr": {}, "zh-hant": {}}
+	for tag, want := range map[string]string{"fr": "fr", "FR-ca": "fr", "fr_CA.UTF-8": "fr", "zh-Hant-TW": "zh-hant", "zh": "", "de": "", "": ""} {
+		if got, _ := catalog.match(tag); got != want {
+			t.Errorf("match(%q) = %q, want %q", tag, got, want)
+		}
+	}
+}
+
+func TestLocalizedStrings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locales.json")
+	os.WriteFile(path, []byte(`{"FR": {"instructions": "Bienvenue !", "tools": {"ping": "Envoie un ping."}, "prompts": {"query": "Pose une question."}}}`), 0o644)
+	catalog, err := loadLocaleCatalog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := func() *Server {
+		s := NewServer(&captureTransport{}, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+		s.locales = catalog
+		s.RegisterExperimental(mcp.ExperimentalLocale, s.negotiateLocale)
+		return s
+	}
+	initialize := func(s *Server, extra string) *mcp.InitializeResult {
+		t.Helper()
+		response, err := s.handleInitializeRequest(1, []byte(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05",
+			"clientInfo":{"name":"test","version":"0"},`+extra+`}}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, _, _, err := mcp.UnmarshalInitializeResponse(response)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	s := session()
+	result := initialize(s, `"capabilities":{"experimental":{"locale":{"locale":"fr-CA"}}}`)
+	var chosen mcp.LocaleSettings
+	if ok, _ := result.Capabilities.Experimental.Decode(mcp.ExperimentalLocale, &chosen); !ok || chosen.Locale != "fr" || result.Instructions != "Bienvenue !" {
+		t.Errorf("initialize = locale %q, instructions %q", chosen.Locale, result.Instructions)
+	}
+	response, _ := s.handleListTools(2)
+	var tools struct {
+		Result mcp.ListToolsResult `json:"result"`
+	}
+	json.Unmarshal(response, &tools)
+	if tools.Result.Tools[0].Name != pingToolName || tools.Result.Tools[0].Description != "Envoie un ping." || tools.Result.Tools[1].Description != queryTableTool().Description {
+		t.Errorf("tools/list = %+v", tools.Result.Tools[:2])
+	}
+	response, _ = s.handleListPrompts(3)
+	var prompts struct {
+		Result mcp.ListPromptsResult `json:"result"`
+	}
+	json.Unmarshal(response, &prompts)
+	if prompts.Result.Prompts[0].Description != "Pose une question." {
+		t.Errorf("prompts/list = %+v", prompts.Result.Prompts)
+	}
+
+	if result := initialize(session(), `"capabilities":{},"_meta":{"locale":"fr"}`); result.Instructions != "Bienvenue !" {
+		t.Errorf("_meta.locale instructions = %q", result.Instructions)
+	}
+	result = initialize(session(), `"capabilities":{"experimental":{"locale":{"locale":"de-DE"}}}`)
+	if result.Instructions != serverInstructions || result.Capabilities.Experimental.Has(mcp.ExperimentalLocale) {
+		t.Errorf("unknown locale = %q, experimental %v", result.Instructions, result.Capabilities.Experimental.Names())
+	}
+}
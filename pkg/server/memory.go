@@ -0,0 +1,134 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/memory"
+)
+
+const (
+	memoryDefaultK = 5
+	memoryMaxK     = 50
+)
+
+// memoryNamespace returns the namespace of the session's notes: its tenant
+// (see tenants.go), else the client name from initialize, so each agent host
+// sees only its own. Names are not authenticated, so clients that share a
+// name share notes.
+func (s *Server) memoryNamespace() string {
+	if s.tenant != "" {
+		return s.tenant
+	}
+	if name := strings.TrimSpace(s.clientInfo.Name); name != "" {
+		return name
+	}
+	return "anonymous"
+}
+
+// memorySearchResult is the structured result of memory_search.
+type memorySearchResult struct {
+	Namespace string         `json:"namespace"`
+	Matches   []memory.Match `json:"matches"`
+}
+
+// memoryModule returns the memory_store and memory_search tools over store.
+func memoryModule(store *memory.Store) *toolModule {
+	return &toolModule{
+		name: "memory",
+		tools: []moduleTool{
+			{
+				tool: mcp.Tool{
+					Name:  "memory_store",
+					Title: "Store Memory",
+					Description: "Saves a note to long-term memory, so that it can be recalled with memory_search in later " +
+						"conversations. Store facts about the user, decisions and things to remember, one per note.",
+					InputSchema: mcp.ToolInputSchema{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"text": map[string]interface{}{
+								"type": "string", "description": fmt.Sprintf("The note, at most %d characters", memory.MaxTextLength),
+							},
+							"tags": map[string]interface{}{
+								"type": "array", "items": map[string]interface{}{"type": "string"}, "maxItems": memory.MaxTags,
+								"description": "Optional tags to find the note by, e.g. preferences",
+							},
+						},
+						"required": []string{"text"},
+					},
+				},
+				call: func(_ context.Context, session *Server, raw map[string]interface{}) (mcp.CallToolResult, error) {
+					var args struct {
+						Text string   `json:"text"`
+						Tags []string `json:"tags"`
+					}
+					if err := decodeToolArgs(raw, &args); err != nil {
+						return mcp.CallToolResult{}, err
+					}
+					m, err := store.Add(session.memoryNamespace(), args.Text, args.Tags)
+					if err != nil {
+						return mcp.CallToolResult{}, err
+					}
+					return mcp.NewToolResultText(fmt.Sprintf("Stored as %s.", m.ID)), nil
+				},
+			},
+			{
+				tool: mcp.Tool{
+					Name:        "memory_search",
+					Title:       "Search Memories",
+					Description: "Recalls notes saved with memory_store that match a query, best first, or the newest notes with a tag.",
+					InputSchema: mcp.ToolInputSchema{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"query": map[string]interface{}{"type": "string", "description": "What to recall"},
+							"tag":   map[string]interface{}{"type": "string", "description": "Only notes with this tag; the query may then be omitted"},
+							"k": map[string]interface{}{
+								"type": "integer", "minimum": 1, "maximum": memoryMaxK,
+								"description": fmt.Sprintf("Most notes to return (default %d)", memoryDefaultK),
+							},
+						},
+					},
+				},
+				call: func(_ context.Context, session *Server, raw map[string]interface{}) (mcp.CallToolResult, error) {
+					var args struct {
+						Query string `json:"query"`
+						Tag   string `json:"tag"`
+						K     *int   `json:"k"`
+					}
+					if err := decodeToolArgs(raw, &args); err != nil {
+						return mcp.CallToolResult{}, err
+					}
+					k := memoryDefaultK
+					if args.K != nil {
+						k = *args.K
+					}
+					if k < 1 || k > memoryMaxK {
+						return mcp.CallToolResult{}, fmt.Errorf("k must be between 1 and %d", memoryMaxK)
+					}
+					namespace := session.memoryNamespace()
+					matches, err := store.Search(namespace, args.Query, args.Tag, k)
+					if err != nil {
+						return mcp.CallToolResult{}, err
+					}
+					if len(matches) == 0 {
+						return mcp.NewToolResultText("No matching notes."), nil
+					}
+					var text strings.Builder
+					for _, m := range matches {
+						fmt.Fprintf(&text, "[%s, %s", m.ID, m.Created.Format("2006-01-02"))
+						if len(m.Tags) > 0 {
+							fmt.Fprintf(&text, ", tags: %s", strings.Join(m.Tags, ", "))
+						}
+						fmt.Fprintf(&text, "] %s\n", m.Text)
+					}
+					return structuredResult(text.String(), "memory:"+namespace, memorySearchResult{Namespace: namespace, Matches: matches})
+				},
+			},
+		},
+		check: func() (string, error) {
+			return fmt.Sprintf("notes found by %s", store.Mode()), nil
+		},
+	}
+}
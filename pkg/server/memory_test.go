@@ -0,0 +1,48 @@
+package server
+
+import (
+	"io"
+	"log"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/memory"
+	"sqirvy/mcp/pkg/utils"
+)
+
+func TestMemoryTools(t *testing.T) {
+	store, err := memory.Open(filepath.Join(t.TempDir(), "memory.json"), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := func(client string) *Server {
+		s := NewServer(&captureTransport{}, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+		s.modules = []*toolModule{memoryModule(store)}
+		s.clientInfo = mcp.Implementation{Name: client}
+		return s
+	}
+	alice, bob := session("alice"), session("bob")
+
+	result := callTool(t, alice, "memory_store", map[string]interface{}{"text": "Prefers short answers", "tags": []string{"style"}})
+	if result.IsError || mustText(result) != "Stored as mem-1." {
+		t.Fatalf("memory_store = %q", mustText(result))
+	}
+	result = callTool(t, alice, "memory_search", map[string]interface{}{"query": "short answers"})
+	if result.IsError || !strings.Contains(mustText(result), "tags: style] Prefers short answers") || len(result.Content) != 2 {
+		t.Errorf("memory_search = %q", mustText(result))
+	}
+	if result := callTool(t, bob, "memory_search", map[string]interface{}{"query": "short answers"}); mustText(result) != "No matching notes." {
+		t.Errorf("another client's search = %q", mustText(result))
+	}
+	if result := callTool(t, session(""), "memory_store", map[string]interface{}{"text": "x"}); result.IsError || store.Count("anonymous") != 1 {
+		t.Errorf("unnamed client: %q", mustText(result))
+	}
+
+	for _, args := range []map[string]interface{}{{}, {"query": "x", "k": 0}} {
+		if result := callTool(t, alice, "memory_search", args); !result.IsError {
+			t.Errorf("memory_search %v succeeded, want a tool error", args)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"sqirvy/mcp/pkg/mcp"
+)
+
+// MethodHandler answers a request for a method outside the standard ones that
+// dispatch routes itself, e.g. a vendor extension. It returns either a result
+// to marshal or an RPC error. Missing, null or empty params arrive as {}.
+type MethodHandler func(ctx context.Context, params json.RawMessage) (interface{}, *mcp.RPCError)
+
+// HandleMethod registers h for requests with the given method, replacing any
+// previous handler. The method must be named x-<vendor>/<method> (see
+// mcp.ValidateExtensionMethod), which keeps it apart from the standard methods;
+// HandleMethod panics otherwise. Registered methods are advertised to clients
+// under capabilities.experimental.methods. Register handlers before Run.
+func (s *Server) HandleMethod(method string, h MethodHandler) {
+	if err := mcp.ValidateExtensionMethod(method); err != nil {
+		panic(err)
+	}
+	if s.extensions == nil {
+		s.extensions = make(map[string]MethodHandler)
+		s.RegisterExperimental(mcp.ExperimentalMethods, s.negotiateExtensionMethods)
+	}
+	s.extensions[method] = h
+}
+
+// negotiateExtensionMethods advertises the methods registered with
+// HandleMethod, whatever the client declared.
+func (s *Server) negotiateExtensionMethods(json.RawMessage) (interface{}, bool) {
+	methods := make([]string, 0, len(s.extensions))
+	for method := range s.extensions {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods, true
+}
+
+// dispatchExtension answers a request with the handler registered for its
+// method, or with MethodNotFound.
+func (s *Server) dispatchExtension(ctx context.Context, id mcp.RequestID, method string, payload []byte) ([]byte, error) {
+	h, ok := s.extensions[method]
+	if !ok {
+		s.logger.Printf("DEBUG", "Received unsupported method '%s' for request ID %v", method, id)
+		return createMethodNotFoundResponse(id, method), nil
+	}
+	result, rpcErr := h(ctx, mcp.MessageParams(payload))
+	if rpcErr != nil {
+		return s.marshalErrorResponse(id, rpcErr)
+	}
+	return s.marshalResponse(id, result)
+}
@@ -0,0 +1,276 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"sqirvy/mcp/pkg/index"
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/memory"
+	"sqirvy/mcp/pkg/resources"
+	"sqirvy/mcp/pkg/tools"
+)
+
+// toolModule is an optional group of tools and resources for one backend
+// (Kubernetes, Docker, ...), enabled from the command line. Modules are built
+// once in main and shared by every session.
+type toolModule struct {
+	name      string
+	tools     []moduleTool
+	resources []moduleResource
+	// check verifies the module can reach its backend, for the doctor subcommand.
+	check func() (string, error)
+}
+
+// moduleConfig holds the command line values that enable and configure tool modules.
+type moduleConfig struct {
+	k8s         bool
+	kubeconfig  string
+	kubeContext string
+	docker      bool
+	dockerHost  string
+	dockerAllow string
+	promURL     string
+	promToken   string // File holding a bearer token for Prometheus
+	webhookURL  string
+	webhookFmt  string
+	webhookRate int // Messages per minute
+	desktop     bool
+	index       bool
+	indexFile   string
+	embeddings  string // Embeddings provider: hash or openai:<model>
+	embedURL    string
+	embedKey    string // File holding an API key for the embeddings provider
+	memoryFile  string
+	memoryEmbed bool   // Search memories with the -embeddings provider instead of keywords
+	sealKey     []byte // Master key of encryption at rest, nil if off; see seal.go
+	// new-tool inserts fields above this line
+}
+
+// buildModules returns the modules enabled by cfg.
+func buildModules(cfg moduleConfig) ([]*toolModule, error) {
+	var modules []*toolModule
+	if cfg.k8s {
+		modules = append(modules, kubernetesModule(tools.Kubectl{Kubeconfig: cfg.kubeconfig, Context: cfg.kubeContext}))
+	}
+	if cfg.docker {
+		allow, err := parseDockerAllowlist(cfg.dockerAllow)
+		if err != nil {
+			return nil, err
+		}
+		if len(allow) == 0 {
+			return nil, errors.New("the docker tools need -docker-allow to name the containers they may see (\"*\" for all)")
+		}
+		client, err := tools.NewDockerClient(cfg.dockerHost, dockerTimeout)
+		if err != nil {
+			return nil, err
+		}
+		modules = append(modules, dockerModule(client, allow))
+	}
+	if cfg.promURL != "" {
+		var token string
+		if cfg.promToken != "" {
+			data, err := os.ReadFile(cfg.promToken)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read Prometheus token: %w", err)
+			}
+			token = strings.TrimSpace(string(data))
+		}
+		client, err := tools.NewPrometheusClient(cfg.promURL, token, promTimeout)
+		if err != nil {
+			return nil, err
+		}
+		modules = append(modules, prometheusModule(client))
+	}
+	if cfg.webhookURL != "" {
+		if cfg.webhookRate <= 0 {
+			return nil, errors.New("-webhook-rate must be at least 1")
+		}
+		hook, err := tools.NewWebhook(cfg.webhookURL, cfg.webhookFmt, webhookTimeout)
+		if err != nil {
+			return nil, err
+		}
+		modules = append(modules, webhookModule(hook, cfg.webhookRate))
+	}
+	if cfg.desktop {
+		modules = append(modules, desktopModule(tools.Desktop{Timeout: desktopTimeout}))
+	}
+	if cfg.index {
+		embedder, err := cfg.embedder()
+		if err != nil {
+			return nil, err
+		}
+		ix, err := index.Open(resources.ProjectRoot(), cfg.indexFile, embedder)
+		if err != nil {
+			return nil, err
+		}
+		modules = append(modules, indexModule(ix))
+	}
+	if cfg.memoryFile != "" {
+		var embedder index.Embedder
+		if cfg.memoryEmbed {
+			var err error
+			if embedder, err = cfg.embedder(); err != nil {
+				return nil, err
+			}
+		}
+		sealer, err := newSealer(cfg.sealKey, sealScopeMemory)
+		if err != nil {
+			return nil, err
+		}
+		store, err := memory.Open(cfg.memoryFile, embedder, sealer)
+		if err != nil {
+			return nil, err
+		}
+		modules = append(modules, memoryModule(store))
+	}
+	// new-tool inserts modules above this line
+	return modules, nil
+}
+
+// embedder returns the embeddings provider configured by -embeddings.
+func (cfg moduleConfig) embedder() (index.Embedder, error) {
+	var key string
+	if cfg.embedKey != "" {
+		data, err := os.ReadFile(cfg.embedKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read embeddings API key: %w", err)
+		}
+		key = strings.TrimSpace(string(data))
+	}
+	return index.NewEmbedder(cfg.embeddings, cfg.embedURL, key, embeddingsTimeout)
+}
+
+// moduleTool is one tool of a module. call returns the tool result; an error
+// becomes a tool error result so the model can react to it. ctx carries the
+// request's metadata (see mcp.SessionFromContext) for code in other packages;
+// session is the calling session, for tools that need something from the client.
+type moduleTool struct {
+	tool  mcp.Tool
+	limit int // Default concurrency limit, 0 = unlimited; see limits.go
+	// cacheTTL caches results of a pure tool for this long, 0 = not cached; see resultcache.go
+	cacheTTL time.Duration
+	// destructive marks a tool that changes something outside the server; see features.go
+	destructive bool
+	// deprecated marks a tool being phased out, nil if it is not; see deprecation.go
+	deprecated *mcp.Deprecation
+	call       func(ctx context.Context, session *Server, args map[string]interface{}) (mcp.CallToolResult, error)
+}
+
+// moduleResource is one concrete resource of a module, read as text. ctx
+// carries the request's metadata, as for tools.
+type moduleResource struct {
+	resource   mcp.Resource
+	deprecated *mcp.Deprecation // nil unless being phased out; see deprecation.go
+	read       func(ctx context.Context) (string, error)
+}
+
+// moduleTool returns the module tool with the given name.
+func (s *Server) moduleTool(name string) (moduleTool, bool) {
+	for _, m := range s.modules {
+		for _, t := range m.tools {
+			if t.tool.Name == name {
+				return t, true
+			}
+		}
+	}
+	return moduleTool{}, false
+}
+
+// moduleResource returns the module resource with the given URI.
+func (s *Server) moduleResource(uri string) (moduleResource, bool) {
+	for _, m := range s.modules {
+		for _, r := range m.resources {
+			if r.resource.URI == uri {
+				return r, true
+			}
+		}
+	}
+	return moduleResource{}, false
+}
+
+// moduleToolLimits returns the default concurrency limits of the modules' tools.
+func moduleToolLimits(modules []*toolModule) map[string]int {
+	limits := make(map[string]int)
+	for _, m := range modules {
+		for _, t := range m.tools {
+			if t.limit > 0 {
+				limits[t.tool.Name] = t.limit
+			}
+		}
+	}
+	return limits
+}
+
+// moduleToolCacheTTLs returns the result cache TTLs declared by module tools.
+func moduleToolCacheTTLs(modules []*toolModule) map[string]time.Duration {
+	ttls := make(map[string]time.Duration)
+	for _, m := range modules {
+		for _, t := range m.tools {
+			if t.cacheTTL > 0 {
+				ttls[t.tool.Name] = t.cacheTTL
+			}
+		}
+	}
+	return ttls
+}
+
+// handleModuleTool runs a module tool for tools/call.
+func (s *Server) handleModuleTool(ctx context.Context, id mcp.RequestID, t moduleTool, params mcp.CallToolParams) ([]byte, error) {
+	s.logger.Printf("DEBUG", "Handle  : tools/call request for '%s' (ID: %v)", params.Name, id)
+	result, err := t.call(ctx, s, params.Arguments)
+	if err != nil {
+		s.logger.Printf("DEBUG", "Tool '%s' (ID: %v) failed: %v", params.Name, id, err)
+		result = toolError(fmt.Errorf("%s: %w", params.Name, err))
+	} else if err := result.Validate(); err != nil {
+		s.logger.Printf("INFO", "Tool '%s' (ID: %v) returned an invalid result: %v", params.Name, id, err)
+		result = mcp.NewToolResultError(fmt.Errorf("%s returned an invalid result: %w", params.Name, err))
+	}
+	return s.marshalResponse(id, result)
+}
+
+// handleModuleResource reads a module resource for resources/read.
+func (s *Server) handleModuleResource(ctx context.Context, id mcp.RequestID, r moduleResource) ([]byte, error) {
+	text, err := r.read(ctx)
+	if err != nil {
+		s.logger.Printf("DEBUG", "Error reading resource URI '%s': %v", r.resource.URI, err)
+		rpcErr := mcp.NewRPCError(mcp.ErrorCodeInternalError, err.Error(), map[string]string{"uri": r.resource.URI})
+		return s.marshalErrorResponse(id, rpcErr)
+	}
+	var result mcp.ReadResourceResult
+	result.Add(mcp.NewTextResource(r.resource.URI, r.resource.MimeType, text))
+	return s.marshalResponse(id, result)
+}
+
+// decodeToolArgs decodes tool arguments into the struct v.
+func decodeToolArgs(args map[string]interface{}, v interface{}) error {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	return nil
+}
+
+// structuredResult returns a tool result with a text rendering followed by v as an
+// embedded application/json resource, for clients that want the data itself.
+// When v is a JSON object it is the structured content of the result as well.
+func structuredResult(text, uri string, v interface{}) (mcp.CallToolResult, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return mcp.CallToolResult{}, fmt.Errorf("failed to marshal structured result: %w", err)
+	}
+	result := mcp.NewToolResultText(text)
+	result.Content = append(result.Content, mcp.NewEmbeddedResource(mcp.NewTextResource(uri, "application/json", string(data))))
+	if len(data) > 0 && data[0] == '{' {
+		result.StructuredContent = data
+	}
+	return result, nil
+}
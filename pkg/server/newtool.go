@@ -0,0 +1,267 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// toolNamePattern is the form of names accepted by new-tool: snake case, which
+// is also the tool name the model sees.
+var toolNamePattern = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+
+// scaffold holds the names derived from a new tool's name, e.g. git_log gives
+// the identifier gitLog, the exported form GitLog and the flag -git-log.
+type scaffold struct {
+	Name     string
+	Ident    string
+	Export   string
+	Title    string // Display name, e.g. "Git Log"
+	Flag     string
+	Resource bool // Also generate a resource
+}
+
+// newScaffold validates name and derives the other names from it.
+func newScaffold(name string, resource bool) (scaffold, error) {
+	if !toolNamePattern.MatchString(name) {
+		return scaffold{}, fmt.Errorf("invalid name %q: use lower case snake case, e.g. git_log", name)
+	}
+	var export strings.Builder
+	var words []string
+	for _, part := range strings.Split(name, "_") {
+		word := strings.ToUpper(part[:1]) + part[1:]
+		export.WriteString(word)
+		words = append(words, word)
+	}
+	sc := scaffold{Name: name, Export: export.String(), Title: strings.Join(words, " "), Flag: strings.ReplaceAll(name, "_", "-"), Resource: resource}
+	sc.Ident = strings.ToLower(sc.Export[:1]) + sc.Export[1:]
+	if token.IsKeyword(sc.Ident) {
+		return scaffold{}, fmt.Errorf("invalid name %q: it is a Go keyword", name)
+	}
+	return sc, nil
+}
+
+// scaffoldMarkers are the comments in existing files that new-tool inserts
+// code above, and the code inserted.
+var scaffoldMarkers = []struct {
+	file, marker, code string
+}{
+	{"modules.go", "\t// new-tool inserts fields above this line\n", "\t{{.Ident}} bool\n"},
+	{"modules.go", "\t// new-tool inserts modules above this line\n",
+		"\tif cfg.{{.Ident}} {\n\t\tmodules = append(modules, {{.Ident}}Module())\n\t}\n"},
+	{"command.go", "\t// new-tool inserts flags above this line\n",
+		"\tenable{{.Export}} := flag.Bool(\"{{.Flag}}\", false, \"Enable the {{.Name}} tool\")\n"},
+	{"command.go", "\t\t// new-tool inserts settings above this line\n", "\t\t{{.Ident}}: *enable{{.Export}},\n"},
+}
+
+// runNewTool implements "mcp-server new-tool [-dir dir] [-resource] <name>": it
+// writes <name>.go with a tool module and <name>_test.go with a test for it,
+// and registers the module in modules.go behind a new -<name> flag in command.go.
+// It returns the process exit code.
+func runNewTool(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("new-tool", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	dir := flags.String("dir", ".", "The mcp-server source directory, pkg/server")
+	resource := flags.Bool("resource", false, "Also generate a resource in the module")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: mcp-server new-tool [-dir dir] [-resource] <name>")
+		fmt.Fprintln(stderr, "Scaffolds a tool module named <name> (snake case) and registers it behind a -<name> flag.")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	sc, err := newScaffold(flags.Arg(0), *resource)
+	if err != nil {
+		fmt.Fprintf(stderr, "new-tool: %v\n", err)
+		return 1
+	}
+	written, err := writeScaffold(*dir, sc)
+	if err != nil {
+		fmt.Fprintf(stderr, "new-tool: %v\n", err)
+		return 1
+	}
+	for _, f := range written {
+		fmt.Fprintf(stdout, "wrote %s\n", f)
+	}
+	fmt.Fprintf(stdout, "\nNext: fill in the TODOs in %s.go, then run go test and try it with -%s.\n", sc.Name, sc.Flag)
+	return 0
+}
+
+// writeScaffold generates the files for sc in dir. Nothing is written unless
+// every file could be generated.
+func writeScaffold(dir string, sc scaffold) ([]string, error) {
+	if _, err := os.Stat(filepath.Join(dir, "modules.go")); err != nil {
+		return nil, fmt.Errorf("%s is not the mcp-server source directory pkg/server (no modules.go); use -dir", dir)
+	}
+	files := map[string][]byte{}
+	for _, name := range []string{sc.Name + ".go", sc.Name + "_test.go"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return nil, fmt.Errorf("%s already exists", name)
+		}
+	}
+	for _, f := range []struct{ name, tmpl string }{{sc.Name + ".go", moduleTemplate}, {sc.Name + "_test.go", moduleTestTemplate}} {
+		code, err := expandScaffold(f.tmpl, sc)
+		if err != nil {
+			return nil, err
+		}
+		files[f.name] = code
+	}
+	for _, m := range scaffoldMarkers {
+		src, ok := files[m.file]
+		if !ok {
+			data, err := os.ReadFile(filepath.Join(dir, m.file))
+			if err != nil {
+				return nil, err
+			}
+			if bytes.Contains(data, []byte(sc.Ident+"Module(")) {
+				return nil, fmt.Errorf("a %s module is already registered in %s", sc.Name, m.file)
+			}
+			src = data
+		}
+		code, err := expandScaffold(m.code, sc)
+		if err != nil {
+			return nil, err
+		}
+		i := bytes.Index(src, []byte(m.marker))
+		if i < 0 {
+			return nil, fmt.Errorf("%s has no %q comment to insert code at", m.file, strings.TrimSpace(m.marker))
+		}
+		files[m.file] = append(append(append([]byte{}, src[:i]...), code...), src[i:]...)
+	}
+
+	var written []string
+	for _, name := range []string{sc.Name + ".go", sc.Name + "_test.go", "modules.go", "command.go"} {
+		formatted, err := format.Source(files[name])
+		if err != nil {
+			return nil, fmt.Errorf("generated %s does not compile: %w", name, err)
+		}
+		files[name] = formatted
+	}
+	for _, name := range []string{sc.Name + ".go", sc.Name + "_test.go", "modules.go", "command.go"} {
+		if err := os.WriteFile(filepath.Join(dir, name), files[name], 0644); err != nil {
+			return written, err
+		}
+		written = append(written, filepath.Join(dir, name))
+	}
+	return written, nil
+}
+
+// expandScaffold executes a code template for sc.
+func expandScaffold(text string, sc scaffold) ([]byte, error) {
+	tmpl, err := template.New("scaffold").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, sc); err != nil {
+		return nil, errors.New("failed to generate code: " + err.Error())
+	}
+	return buf.Bytes(), nil
+}
+
+const moduleTemplate = `package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"sqirvy/mcp/pkg/mcp"
+)
+
+// {{.Ident}}Args are the arguments of the {{.Name}} tool.
+type {{.Ident}}Args struct {
+	Input string ` + "`json:\"input\"`" + `
+}
+
+// {{.Ident}}Module returns the {{.Name}} tool.
+// TODO: describe what the module does and what it talks to.
+func {{.Ident}}Module() *toolModule {
+	return &toolModule{
+		name: "{{.Name}}",
+		tools: []moduleTool{
+			{
+				tool: mcp.Tool{
+					Name:        "{{.Name}}",
+					Title:       "{{.Title}}",
+					Description: "TODO: tell the model what {{.Name}} does and when to use it.",
+					InputSchema: mcp.ToolInputSchema{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"input": map[string]interface{}{"type": "string", "description": "TODO: describe the argument"},
+						},
+						"required": []string{"input"},
+					},
+				},
+				call: func(_ context.Context, _ *Server, raw map[string]interface{}) (mcp.CallToolResult, error) {
+					var args {{.Ident}}Args
+					if err := decodeToolArgs(raw, &args); err != nil {
+						return mcp.CallToolResult{}, err
+					}
+					return run{{.Export}}(args)
+				},
+			},
+		},
+{{- if .Resource}}
+		resources: []moduleResource{
+			{
+				resource: mcp.Resource{URI: "{{.Flag}}://status", Name: "{{.Name}} status", MimeType: "text/plain"},
+				read: func(context.Context) (string, error) {
+					return "TODO: report the state of {{.Name}}", nil
+				},
+			},
+		},
+{{- end}}
+		check: func() (string, error) {
+			// TODO: check that whatever the tool needs is there, for the doctor subcommand.
+			return "ok", nil
+		},
+	}
+}
+
+// run{{.Export}} runs the {{.Name}} tool. A returned error becomes a tool error result.
+func run{{.Export}}(args {{.Ident}}Args) (mcp.CallToolResult, error) {
+	if strings.TrimSpace(args.Input) == "" {
+		return mcp.CallToolResult{}, fmt.Errorf("input is required")
+	}
+	return mcp.NewToolResultText("TODO: " + args.Input), nil
+}
+`
+
+const moduleTestTemplate = `package server
+
+import (
+	"io"
+	"log"
+	"testing"
+
+	"sqirvy/mcp/pkg/utils"
+)
+
+func Test{{.Export}}Tool(t *testing.T) {
+	s := NewServer(&captureTransport{}, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	s.modules = []*toolModule{ {{- .Ident}}Module()}
+
+	result := callTool(t, s, "{{.Name}}", map[string]interface{}{"input": "hello"})
+	if result.IsError || mustText(result) != "TODO: hello" {
+		t.Errorf("{{.Name}} = %q", mustText(result))
+	}
+	if result := callTool(t, s, "{{.Name}}", map[string]interface{}{}); !result.IsError {
+		t.Errorf("{{.Name}} without input = %q, want a tool error", mustText(result))
+	}
+}
+`
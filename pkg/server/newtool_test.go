@@ -0,0 +1,70 @@
+package server
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewScaffold(t *testing.T) {
+	sc, err := newScaffold("git_log", false)
+	if err != nil || sc.Ident != "gitLog" || sc.Export != "GitLog" || sc.Flag != "git-log" {
+		t.Errorf("newScaffold(git_log) = %+v, %v", sc, err)
+	}
+	for _, name := range []string{"GitLog", "git-log", "_x", "x__y", "1x", "func"} {
+		if _, err := newScaffold(name, false); err == nil {
+			t.Errorf("newScaffold(%q) succeeded", name)
+		}
+	}
+}
+
+func TestRunNewTool(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"modules.go", "command.go"} {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		os.WriteFile(filepath.Join(dir, name), data, 0644)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := runNewTool([]string{"-dir", dir, "-resource", "weather"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("new-tool = %d: %s", code, stderr.String())
+	}
+	fset := token.NewFileSet()
+	for _, name := range []string{"weather.go", "weather_test.go", "modules.go", "command.go"} {
+		if _, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, 0); err != nil {
+			t.Errorf("generated %s: %v", name, err)
+		}
+	}
+	modules, _ := os.ReadFile(filepath.Join(dir, "modules.go"))
+	command, _ := os.ReadFile(filepath.Join(dir, "command.go"))
+	generated, _ := os.ReadFile(filepath.Join(dir, "weather.go"))
+	for _, want := range []struct {
+		file []byte
+		text string
+	}{
+		{modules, "modules = append(modules, weatherModule())"},
+		{command, `enableWeather := flag.Bool("weather", false,`},
+		{command, "weather:     *enableWeather,"},
+		{generated, `URI: "weather://status"`},
+	} {
+		if !bytes.Contains(want.file, []byte(want.text)) {
+			t.Errorf("generated code lacks %q", want.text)
+		}
+	}
+
+	// A second run must not register the module twice
+	stderr.Reset()
+	if code := runNewTool([]string{"-dir", dir, "weather"}, &stdout, &stderr); code != 1 || !strings.Contains(stderr.String(), "already exists") {
+		t.Errorf("second new-tool = %d: %s", code, stderr.String())
+	}
+	if code := runNewTool([]string{"-dir", t.TempDir(), "weather"}, &stdout, &stderr); code != 1 {
+		t.Errorf("new-tool outside the source directory = %d", code)
+	}
+}
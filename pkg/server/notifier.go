@@ -0,0 +1,117 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"sqirvy/mcp/pkg/clock"
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/utils"
+)
+
+// defaultNotifyWindow is how long change notifications are held for coalescing.
+const defaultNotifyWindow = 50 * time.Millisecond
+
+// pendingNotification is a change notification waiting for the window to close.
+type pendingNotification struct {
+	method string
+	params interface{}
+}
+
+// notifier coalesces change notifications so that a burst of changes (e.g. a
+// bulk file write) reaches the client as one frame per distinct change.
+//
+// Within one window, resources/updated is sent once per URI and each
+// list_changed notification once per method. Other notifications are not
+// coalesced and are sent immediately. Pending notifications are flushed in
+// the order they were first raised.
+type notifier struct {
+	window time.Duration
+	send   func(payload []byte)
+	logger *utils.Logger
+	clock  clock.Clock
+
+	mu      sync.Mutex
+	pending []pendingNotification
+	keys    map[string]bool // Coalescing keys of the pending notifications
+	timer   clock.Timer     // Running while notifications are pending
+}
+
+// newNotifier creates a notifier that passes marshalled notifications to send.
+// A window of zero disables coalescing.
+func newNotifier(window time.Duration, send func(payload []byte), logger *utils.Logger) *notifier {
+	return &notifier{
+		window: window,
+		send:   send,
+		logger: logger,
+		clock:  clock.Real,
+		keys:   make(map[string]bool),
+	}
+}
+
+// coalesceKey returns the identity used to merge duplicate notifications, or
+// "" if the notification must not be coalesced.
+func coalesceKey(method string, params interface{}) string {
+	switch method {
+	case mcp.MethodResourceUpdated:
+		switch p := params.(type) {
+		case mcp.ResourceUpdatedParams:
+			return method + " " + p.URI
+		case *mcp.ResourceUpdatedParams:
+			return method + " " + p.URI
+		}
+		return ""
+	case mcp.MethodResourceListChanged, mcp.MethodToolListChanged, mcp.MethodPromptListChanged:
+		return method
+	}
+	return ""
+}
+
+// notify raises a notification, merging it with an identical pending one.
+func (n *notifier) notify(method string, params interface{}) {
+	key := coalesceKey(method, params)
+	if n.window <= 0 || key == "" {
+		n.emit(pendingNotification{method: method, params: params})
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.keys[key] {
+		n.logger.Printf("DEBUG", "Coalesced notification %s", key)
+		return
+	}
+	n.keys[key] = true
+	n.pending = append(n.pending, pendingNotification{method: method, params: params})
+	if n.timer == nil {
+		n.timer = n.clock.AfterFunc(n.window, n.flush)
+	}
+}
+
+// flush sends every pending notification now.
+func (n *notifier) flush() {
+	n.mu.Lock()
+	pending := n.pending
+	n.pending = nil
+	n.keys = make(map[string]bool)
+	if n.timer != nil {
+		n.timer.Stop()
+		n.timer = nil
+	}
+	// Emit under the lock so that a concurrent flush cannot reorder batches
+	defer n.mu.Unlock()
+
+	for _, p := range pending {
+		n.emit(p)
+	}
+}
+
+// emit marshals and sends a single notification.
+func (n *notifier) emit(p pendingNotification) {
+	payload, err := mcp.MarshalNotification(p.method, p.params)
+	if err != nil {
+		n.logger.Printf("DEBUG", "Failed to marshal notification %s: %v", p.method, err)
+		return
+	}
+	n.send(payload)
+}
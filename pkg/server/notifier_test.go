@@ -0,0 +1,108 @@
+package server
+
+import (
+	"io"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"sqirvy/mcp/pkg/clock"
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/utils"
+)
+
+// recordSends collects the payloads handed to a notifier.
+type recordSends struct {
+	mu   sync.Mutex
+	sent []string
+}
+
+func (r *recordSends) send(payload []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, string(payload))
+}
+
+func (r *recordSends) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.sent...)
+}
+
+func TestNotifierCoalesces(t *testing.T) {
+	rec := &recordSends{}
+	n := newNotifier(time.Hour, rec.send, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+
+	for i := 0; i < 100; i++ {
+		n.notify(mcp.MethodResourceUpdated, mcp.ResourceUpdatedParams{URI: "file:///a"})
+		n.notify(mcp.MethodResourceListChanged, nil)
+	}
+	n.notify(mcp.MethodResourceUpdated, &mcp.ResourceUpdatedParams{URI: "file:///b"})
+	n.notify(mcp.MethodToolListChanged, nil)
+	if got := rec.get(); len(got) != 0 {
+		t.Fatalf("sent before flush: %v", got)
+	}
+
+	n.flush()
+	want := []string{
+		`{"jsonrpc":"2.0","method":"notifications/resources/updated","params":{"uri":"file:///a"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/resources/list_changed"}`,
+		`{"jsonrpc":"2.0","method":"notifications/resources/updated","params":{"uri":"file:///b"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/tools/list_changed"}`,
+	}
+	got := rec.get()
+	if len(got) != len(want) {
+		t.Fatalf("sent %d notifications, want %d: %v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("notification %d = %s, want %s", i, got[i], want[i])
+		}
+	}
+
+	// A new window starts after a flush
+	n.notify(mcp.MethodResourceUpdated, mcp.ResourceUpdatedParams{URI: "file:///a"})
+	n.flush()
+	if got := rec.get(); len(got) != len(want)+1 {
+		t.Fatalf("sent %d notifications after second flush, want %d", len(got), len(want)+1)
+	}
+}
+
+func TestNotifierWindowTimer(t *testing.T) {
+	rec := &recordSends{}
+	n := newNotifier(10*time.Millisecond, rec.send, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+	n.clock = fake
+
+	n.notify(mcp.MethodPromptListChanged, nil)
+	fake.Advance(9 * time.Millisecond)
+	n.notify(mcp.MethodPromptListChanged, nil)
+	if got := rec.get(); len(got) != 0 {
+		t.Fatalf("sent %v before the window closed", got)
+	}
+	fake.Advance(time.Millisecond)
+	if got := rec.get(); len(got) != 1 {
+		t.Fatalf("sent %v, want exactly one notification", got)
+	}
+}
+
+func TestNotifierPassThrough(t *testing.T) {
+	rec := &recordSends{}
+	logger := utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo)
+
+	// Coalescing disabled
+	n := newNotifier(0, rec.send, logger)
+	n.notify(mcp.MethodToolListChanged, nil)
+	n.notify(mcp.MethodToolListChanged, nil)
+	if got := rec.get(); len(got) != 2 {
+		t.Fatalf("window 0: sent %d notifications, want 2", len(got))
+	}
+
+	// Notifications without a coalescing key are never held
+	n = newNotifier(time.Hour, rec.send, logger)
+	n.notify("notifications/message", map[string]string{"level": "info"})
+	if got := rec.get(); len(got) != 3 {
+		t.Fatalf("uncoalesced: sent %d notifications, want 3", len(got))
+	}
+}
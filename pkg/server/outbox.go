@@ -0,0 +1,137 @@
+package server
+
+import (
+	"sync"
+
+	"sqirvy/mcp/pkg/transport"
+	"sqirvy/mcp/pkg/utils"
+)
+
+// Ordering contract
+//
+// Requests are dispatched to their handlers concurrently, but everything the
+// server writes goes through a single outbox and leaves in the order in which
+// it was queued:
+//
+//   - a response slot is reserved when its request is read, so responses are
+//     written in request arrival order (per-session FIFO) regardless of which
+//     handler finishes first;
+//   - a server-initiated message (notification) is queued behind every response
+//     already reserved, so it never overtakes the reply to an earlier request and
+//     never splits a message on the wire;
+//   - a server-initiated request sent by a handler (see clientrequests.go) is
+//     written at once, ahead of the reserved responses: the handler's own
+//     response is among them and cannot be written until the client replies.
+//
+// A slow handler therefore delays the responses queued behind it. That is the
+// price of a deterministic order, which several hosts rely on.
+//
+// If a write fails because the peer is gone, the outbox marks itself failed
+// and drops everything queued after it; the session is then torn down (see
+// Server.Run). A payload the transport refuses on its own is dropped alone.
+
+// outboxSlot is a reserved position in the output order.
+type outboxSlot struct {
+	payload []byte
+	filled  bool
+}
+
+// outbox serializes writes to the transport in reservation order.
+type outbox struct {
+	mu      sync.Mutex
+	pending []*outboxSlot // Reserved slots not yet handed to the writer, oldest first
+	ready   chan []byte   // Payloads in final order, consumed by writeLoop
+	done    chan struct{} // Closed when writeLoop exits
+	failed  chan struct{} // Closed on the first write failure, see writeErr
+	err     error         // The write failure; set before failed is closed
+
+	transport transport.Transport
+	logger    *utils.Logger
+}
+
+// newOutbox creates an outbox and starts its writer goroutine.
+func newOutbox(t transport.Transport, logger *utils.Logger) *outbox {
+	o := &outbox{
+		ready:     make(chan []byte, 64),
+		done:      make(chan struct{}),
+		failed:    make(chan struct{}),
+		transport: t,
+		logger:    logger,
+	}
+	go o.writeLoop()
+	return o
+}
+
+// reserve claims the next position in the output order.
+func (o *outbox) reserve() *outboxSlot {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	slot := &outboxSlot{}
+	o.pending = append(o.pending, slot)
+	return slot
+}
+
+// fill stores the payload for slot and releases every leading filled slot to the writer.
+// A nil payload releases the slot without writing anything.
+func (o *outbox) fill(slot *outboxSlot, payload []byte) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	slot.payload = payload
+	slot.filled = true
+	for len(o.pending) > 0 && o.pending[0].filled {
+		if p := o.pending[0].payload; p != nil {
+			o.ready <- p
+		}
+		o.pending[0] = nil
+		o.pending = o.pending[1:]
+	}
+}
+
+// enqueue queues payload behind everything reserved so far.
+func (o *outbox) enqueue(payload []byte) {
+	o.fill(o.reserve(), payload)
+}
+
+// sendNow hands payload to the writer ahead of every reserved slot.
+func (o *outbox) sendNow(payload []byte) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.ready <- payload
+}
+
+// writeLoop writes released payloads to the transport one at a time. After a
+// write failure it keeps consuming payloads, so that producers never block,
+// but discards them.
+func (o *outbox) writeLoop() {
+	defer close(o.done)
+	for p := range o.ready {
+		if o.err != nil {
+			continue
+		}
+		err := o.transport.WriteMessage(p)
+		switch {
+		case err == nil:
+		case transport.IsMessageError(err):
+			o.logger.Printf("DEBUG", "Error writing message: %v", err)
+		default:
+			o.logger.Printf("DEBUG", "Write failed, the session is broken: %v", err)
+			o.err = err
+			close(o.failed)
+		}
+	}
+}
+
+// writeErr returns the write failure once failed is closed.
+func (o *outbox) writeErr() error {
+	<-o.failed
+	return o.err
+}
+
+// close stops accepting payloads and waits until everything released has been written.
+// Slots still reserved are dropped; callers wait for their handlers first.
+func (o *outbox) close() {
+	o.mu.Lock()
+	close(o.ready)
+	o.mu.Unlock()
+	<-o.done
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"testing"
+
+	"sqirvy/mcp/pkg/utils"
+)
+
+func TestOutboxPreservesReservationOrder(t *testing.T) {
+	tr := &captureTransport{written: make(chan []byte, 64)}
+	o := newOutbox(tr, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+
+	const n = 20
+	slots := make([]*outboxSlot, n)
+	for i := range slots {
+		slots[i] = o.reserve()
+	}
+
+	// Fill from the back, concurrently, as slow early handlers would
+	var wg sync.WaitGroup
+	for i := n - 1; i >= 0; i-- {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			o.fill(slots[i], []byte(fmt.Sprintf(`{"id":%d}`, i)))
+		}(i)
+	}
+	wg.Wait()
+	o.close()
+
+	for i := 0; i < n; i++ {
+		got := string(<-tr.written)
+		if want := fmt.Sprintf(`{"id":%d}`, i); got != want {
+			t.Fatalf("message %d = %s, want %s", i, got, want)
+		}
+	}
+}
+
+func TestOutboxNotificationWaitsForEarlierResponses(t *testing.T) {
+	tr := &captureTransport{written: make(chan []byte, 8)}
+	o := newOutbox(tr, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+
+	first := o.reserve()
+	o.enqueue([]byte(`{"method":"notifications/message"}`))
+	skipped := o.reserve()
+	last := o.reserve()
+
+	o.fill(last, []byte(`{"id":3}`))
+	o.fill(skipped, nil) // Released without output
+	o.fill(first, []byte(`{"id":1}`))
+	o.close()
+
+	want := []string{`{"id":1}`, `{"method":"notifications/message"}`, `{"id":3}`}
+	for i, w := range want {
+		if got := string(<-tr.written); got != w {
+			t.Fatalf("message %d = %s, want %s", i, got, w)
+		}
+	}
+	select {
+	case extra := <-tr.written:
+		t.Fatalf("unexpected extra message %s", extra)
+	default:
+	}
+}
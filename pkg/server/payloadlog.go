@@ -0,0 +1,51 @@
+package server
+
+import (
+	"sync/atomic"
+
+	"sqirvy/mcp/pkg/utils"
+)
+
+// defaultPayloadLogMax is how much of a payload is logged unless -log-payload-max says otherwise.
+const defaultPayloadLogMax = 4096
+
+// payloadLog logs the messages a session receives (R:) and sends (S:) at
+// INFO. At high message rates full payloads dominate the cost of a request, so
+// they are cut to maxBytes and only one in every payloads is logged. Payload
+// logs are shared by the sessions of a process, so the sampling is process-wide.
+type payloadLog struct {
+	maxBytes int    // Bytes of a payload logged; 0 logs it whole
+	every    uint64 // Log one in every payloads per direction; 0 and 1 log all
+	received atomic.Uint64
+	sent     atomic.Uint64
+}
+
+// newPayloadLog returns a payload log; see payloadLog for the parameters.
+func newPayloadLog(maxBytes int, every uint64) *payloadLog {
+	return &payloadLog{maxBytes: maxBytes, every: every}
+}
+
+// logReceived logs a message read from the client.
+func (p *payloadLog) logReceived(logger *utils.Logger, payload []byte) {
+	p.log(logger, "R", &p.received, payload)
+}
+
+// logSent logs a message for the client.
+func (p *payloadLog) logSent(logger *utils.Logger, payload []byte) {
+	p.log(logger, "S", &p.sent, payload)
+}
+
+// log checks the level and the sample before anything is formatted or copied.
+func (p *payloadLog) log(logger *utils.Logger, direction string, count *atomic.Uint64, payload []byte) {
+	if !logger.Enabled(utils.LevelInfo) {
+		return
+	}
+	if p.every > 1 && (count.Add(1)-1)%p.every != 0 {
+		return
+	}
+	if p.maxBytes > 0 && len(payload) > p.maxBytes {
+		logger.Printf("INFO", "%s:%s... (%d bytes)", direction, payload[:p.maxBytes], len(payload))
+		return
+	}
+	logger.Printf("INFO", "%s:%s", direction, payload)
+}
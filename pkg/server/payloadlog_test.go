@@ -0,0 +1,51 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"strings"
+	"testing"
+
+	"sqirvy/mcp/pkg/utils"
+)
+
+func TestPayloadLogTruncatesAndSamples(t *testing.T) {
+	var out bytes.Buffer
+	logger := utils.New(&out, "", 0, utils.LevelInfo)
+	p := newPayloadLog(8, 2)
+	for _, payload := range []string{`{"id":1,"method":"ping"}`, `{"id":2}`, `{"id":3}`} {
+		p.logReceived(logger, []byte(payload))
+	}
+	p.logSent(logger, []byte(`{"id":1}`))
+
+	want := "R:{\"id\":1,... (24 bytes)\nR:{\"id\":3}\nS:{\"id\":1}\n"
+	if out.String() != want {
+		t.Errorf("log = %q, want %q", out.String(), want)
+	}
+}
+
+// benchmarkPayload is a tools/call response with a 16 KiB text result.
+var benchmarkPayload = []byte(`{"jsonrpc":"2.0","id":7,"result":{"content":[{"type":"text","text":"` + strings.Repeat("x", 16<<10) + `"}]}}`)
+
+// BenchmarkPayloadLog logs a received and a sent payload per iteration; "whole"
+// is what every message cost before payload logs could be cut and sampled.
+func BenchmarkPayloadLog(b *testing.B) {
+	logger := utils.New(io.Discard, "", log.LstdFlags|log.Lshortfile, utils.LevelInfo)
+	for _, bench := range []struct {
+		name string
+		log  *payloadLog
+	}{
+		{"whole", newPayloadLog(0, 1)},
+		{"truncated", newPayloadLog(defaultPayloadLogMax, 1)},
+		{"sampled", newPayloadLog(defaultPayloadLogMax, 100)},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				bench.log.logReceived(logger, benchmarkPayload)
+				bench.log.logSent(logger, benchmarkPayload)
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Transports a profile can be chosen by, as named in the -profiles file.
+const (
+	profileTransportStdio   = "stdio"
+	profileTransportUnix    = "unix"
+	profileTransportTCP     = "tcp"
+	profileTransportHTTP    = "http"    // MCP over Streamable HTTP, -mcp-http
+	profileTransportGRPC    = "grpc"    // MCP over gRPC, -transport=grpc
+	profileTransportGateway = "gateway" // The HTTP gateway, -http
+)
+
+// profileFile is the -profiles file, e.g.
+//
+//	{"profiles": {
+//	   "untrusted": {"tools": ["ping", "query_table"], "resources": ["data://*"], "prompts": [], "maxCalls": 100},
+//	   "internal": {}},
+//	 "transports": {"stdio": "internal", "unix": "internal"},
+//	 "principals": {"ci-bot": {"tokenFile": "ci-bot.token", "profile": "internal"}},
+//	 "default": "untrusted"}
+//
+// A principal is a client of -mcp-http that sends its token as
+// "Authorization: Bearer <token>". A session gets the profile of its
+// principal, else that of its transport, else the default profile. Without a
+// default, sessions matched by neither are not restricted.
+type profileFile struct {
+	Profiles   map[string]profileEntry   `json:"profiles"`
+	Transports map[string]string         `json:"transports"` // Transport -> profile
+	Principals map[string]principalEntry `json:"principals"` // Principal name -> token and profile
+	Default    string                    `json:"default"`
+}
+
+// profileEntry is a profile as written in the file. A list left out allows
+// everything; an empty list allows nothing. Patterns match names and URIs,
+// with "*" standing for any run of characters.
+type profileEntry struct {
+	Tools     []string `json:"tools"`
+	Resources []string `json:"resources"` // Resource URIs and URI templates
+	Prompts   []string `json:"prompts"`
+	MaxCalls  int64    `json:"maxCalls"` // Overrides -session-max-calls when set
+	MaxBytes  int64    `json:"maxBytes"` // Overrides -session-max-bytes when set
+}
+
+// principalEntry names the file holding a principal's bearer token and its profile.
+type principalEntry struct {
+	TokenFile string `json:"tokenFile"` // Relative to the -profiles file
+	Profile   string `json:"profile"`
+}
+
+// capabilityProfile limits what a session sees and may use. The methods of a
+// nil profile allow everything.
+type capabilityProfile struct {
+	name      string
+	tools     *patternList
+	resources *patternList
+	prompts   *patternList
+	maxCalls  int64
+	maxBytes  int64
+}
+
+// patternList matches against a list of patterns; nil matches everything.
+type patternList struct {
+	patterns []*regexp.Regexp
+}
+
+// newPatternList compiles patterns, returning nil for a nil list.
+func newPatternList(patterns []string) *patternList {
+	if patterns == nil {
+		return nil
+	}
+	list := &patternList{}
+	for _, p := range patterns {
+		parts := strings.Split(p, "*")
+		for i, part := range parts {
+			parts[i] = regexp.QuoteMeta(part)
+		}
+		list.patterns = append(list.patterns, regexp.MustCompile("^"+strings.Join(parts, ".*")+"$"))
+	}
+	return list
+}
+
+// match reports whether s matches one of the patterns.
+func (l *patternList) match(s string) bool {
+	if l == nil {
+		return true
+	}
+	for _, p := range l.patterns {
+		if p.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *capabilityProfile) allowsTool(name string) bool {
+	return p == nil || p.tools.match(name)
+}
+
+func (p *capabilityProfile) allowsResource(uri string) bool {
+	return p == nil || p.resources.match(uri)
+}
+
+func (p *capabilityProfile) allowsPrompt(name string) bool {
+	return p == nil || p.prompts.match(name)
+}
+
+// principal is a client identified by its bearer token.
+type principal struct {
+	name    string
+	token   []byte
+	profile *capabilityProfile
+}
+
+// profileSet holds the loaded profiles and how sessions are matched to them.
+// The methods of a nil set choose no profile.
+type profileSet struct {
+	transports map[string]*capabilityProfile
+	principals []principal
+	fallback   *capabilityProfile
+}
+
+// errUnknownToken is returned by authenticate for a bearer token of no principal.
+var errUnknownToken = errors.New("unknown bearer token")
+
+// loadProfiles reads a profiles file and the token files of its principals.
+func loadProfiles(path string) (*profileSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profiles: %w", err)
+	}
+	var file profileFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid profiles file %s: %w", path, err)
+	}
+	profiles := make(map[string]*capabilityProfile, len(file.Profiles))
+	for name, entry := range file.Profiles {
+		profiles[name] = &capabilityProfile{
+			name:      name,
+			tools:     newPatternList(entry.Tools),
+			resources: newPatternList(entry.Resources),
+			prompts:   newPatternList(entry.Prompts),
+			maxCalls:  entry.MaxCalls,
+			maxBytes:  entry.MaxBytes,
+		}
+	}
+	lookup := func(what, name string) (*capabilityProfile, error) {
+		p, ok := profiles[name]
+		if !ok {
+			return nil, fmt.Errorf("invalid profiles file %s: %s names unknown profile %q", path, what, name)
+		}
+		return p, nil
+	}
+
+	set := &profileSet{transports: make(map[string]*capabilityProfile)}
+	for transportName, name := range file.Transports {
+		switch transportName {
+		case profileTransportStdio, profileTransportUnix, profileTransportTCP, profileTransportHTTP, profileTransportGRPC, profileTransportGateway:
+		default:
+			return nil, fmt.Errorf("invalid profiles file %s: unknown transport %q", path, transportName)
+		}
+		if set.transports[transportName], err = lookup("transport "+transportName, name); err != nil {
+			return nil, err
+		}
+	}
+	for name, entry := range file.Principals {
+		p := principal{name: name}
+		if p.profile, err = lookup("principal "+name, entry.Profile); err != nil {
+			return nil, err
+		}
+		tokenFile := entry.TokenFile
+		if !filepath.IsAbs(tokenFile) {
+			tokenFile = filepath.Join(filepath.Dir(path), tokenFile)
+		}
+		token, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid profiles file %s: principal %s: %w", path, name, err)
+		}
+		if p.token = []byte(strings.TrimSpace(string(token))); len(p.token) == 0 {
+			return nil, fmt.Errorf("invalid profiles file %s: principal %s has an empty token", path, name)
+		}
+		set.principals = append(set.principals, p)
+	}
+	if file.Default != "" {
+		if set.fallback, err = lookup("default", file.Default); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
+// authenticate returns the principal whose token the request carries, "" for
+// a request without one, or errUnknownToken.
+func (ps *profileSet) authenticate(r *http.Request) (string, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if ps == nil || !ok {
+		return "", nil
+	}
+	for _, p := range ps.principals {
+		if subtle.ConstantTimeCompare([]byte(token), p.token) == 1 {
+			return p.name, nil
+		}
+	}
+	return "", errUnknownToken
+}
+
+// choose returns the profile of a session on transportName, authenticated as
+// principalName if that is not "".
+func (ps *profileSet) choose(transportName, principalName string) *capabilityProfile {
+	if ps == nil {
+		return nil
+	}
+	for _, p := range ps.principals {
+		if principalName != "" && p.name == principalName {
+			return p.profile
+		}
+	}
+	if p, ok := ps.transports[transportName]; ok {
+		return p
+	}
+	return ps.fallback
+}
+
+// applyProfile restricts the session to profile, which may be nil.
+func (s *Server) applyProfile(profile *capabilityProfile) {
+	if profile == nil {
+		return
+	}
+	s.profile = profile
+	if profile.maxCalls > 0 {
+		s.quota.maxCalls = profile.maxCalls
+	}
+	if profile.maxBytes > 0 {
+		s.quota.maxBytes = profile.maxBytes
+	}
+	s.logger.Printf("DEBUG", "Session uses capability profile '%s'", profile.name)
+}
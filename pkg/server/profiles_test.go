@@ -0,0 +1,221 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/transport"
+	"sqirvy/mcp/pkg/utils"
+)
+
+// testProfiles is a profiles file with a restricted and an unrestricted profile.
+const testProfiles = `{
+	"profiles": {
+		"untrusted": {"tools": ["ping", "query_*"], "resources": ["data://*"], "prompts": [], "maxCalls": 5},
+		"internal": {}
+	},
+	"transports": {"stdio": "internal"},
+	"principals": {"ci-bot": {"tokenFile": "ci.token", "profile": "internal"}},
+	"default": "untrusted"
+}`
+
+// writeProfiles writes a profiles file and the ci-bot token next to it.
+func writeProfiles(t *testing.T, config string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "ci.token"), []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "profiles.json")
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadProfiles(t *testing.T) {
+	profiles, err := loadProfiles(writeProfiles(t, testProfiles))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct{ transport, principal, want string }{
+		{profileTransportStdio, "", "internal"},
+		{profileTransportTCP, "", "untrusted"},
+		{profileTransportHTTP, "ci-bot", "internal"},
+		{profileTransportHTTP, "", "untrusted"},
+	} {
+		if got := profiles.choose(tt.transport, tt.principal); got == nil || got.name != tt.want {
+			t.Errorf("choose(%q, %q) = %+v, want %s", tt.transport, tt.principal, got, tt.want)
+		}
+	}
+
+	for name, config := range map[string]string{
+		"unknown profile":   `{"profiles": {}, "default": "nope"}`,
+		"unknown transport": `{"profiles": {"p": {}}, "transports": {"carrier-pigeon": "p"}}`,
+		"missing token":     `{"profiles": {"p": {}}, "principals": {"bot": {"tokenFile": "none.token", "profile": "p"}}}`,
+	} {
+		if _, err := loadProfiles(writeProfiles(t, config)); err == nil {
+			t.Errorf("%s: loadProfiles succeeded", name)
+		}
+	}
+}
+
+func TestProfileRestrictsSession(t *testing.T) {
+	profiles, err := loadProfiles(writeProfiles(t, testProfiles))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(&captureTransport{}, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	s.applyProfile(profiles.choose(profileTransportTCP, ""))
+
+	var names []string
+	for _, tool := range s.listTools() {
+		names = append(names, tool.Name)
+	}
+	if got := strings.Join(names, ","); got != "ping,query_table" {
+		t.Errorf("tools = %s, want ping,query_table", got)
+	}
+	payload, _ := mcp.MarshalCallToolRequest(1, mcp.CallToolParams{Name: diffToolName, Arguments: map[string]interface{}{}})
+	response, _ := s.handleCallTool(s.requestContext(1), 1, payload)
+	if m := decodeWire(t, response); m.Error == nil || m.Error.Code != mcp.ErrorCodeMethodNotFound {
+		t.Errorf("call of a tool outside the profile = %s", response)
+	}
+
+	response, _ = s.handleListPrompts(1)
+	if !strings.Contains(string(response), `"prompts":[]`) {
+		t.Errorf("prompts/list = %s, want no prompts", response)
+	}
+	response, _ = s.handleGetPrompt(1, []byte(`{"jsonrpc":"2.0","id":1,"method":"prompts/get","params":{"name":"query"}}`))
+	if m := decodeWire(t, response); m.Error == nil {
+		t.Errorf("prompts/get outside the profile = %s", response)
+	}
+
+	response, _ = s.handleListResources(1)
+	if !strings.Contains(string(response), `"resources":[]`) {
+		t.Errorf("resources/list = %s, want no resources", response)
+	}
+	response, _ = s.handleReadResource(s.requestContext(1), 1, []byte(`{"jsonrpc":"2.0","id":1,"method":"resources/read","params":{"uri":"file:///documents/example.txt"}}`))
+	if m := decodeWire(t, response); m.Error == nil || m.Error.Code != mcp.ErrorCodeInvalidParams {
+		t.Errorf("read of a resource outside the profile = %s", response)
+	}
+	response, _ = s.handleReadResource(s.requestContext(1), 1, []byte(`{"jsonrpc":"2.0","id":1,"method":"resources/read","params":{"uri":"data://random_data?length=4"}}`))
+	if m := decodeWire(t, response); m.Error != nil {
+		t.Errorf("read of a resource in the profile = %s", response)
+	}
+	if s.quota.maxCalls != 5 {
+		t.Errorf("maxCalls = %d, want the profile's 5", s.quota.maxCalls)
+	}
+}
+
+func TestProfileRestrictsResourcesReadByTools(t *testing.T) {
+	profiles, err := loadProfiles(writeProfiles(t, `{
+		"profiles": {"tools-only": {"tools": ["diff", "summarize", "query_table"], "resources": ["data://*"]}},
+		"default": "tools-only"
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(&captureTransport{}, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	s.modules = []*toolModule{{
+		name: "test",
+		resources: []moduleResource{{
+			resource: mcp.Resource{URI: "journal://recent", MimeType: "text/plain"},
+			read:     func(context.Context) (string, error) { return "other sessions' requests", nil },
+		}},
+	}}
+	s.clientCapabilities.Sampling = map[string]interface{}{}
+	s.applyProfile(profiles.choose(profileTransportTCP, ""))
+
+	for _, tt := range []struct {
+		tool string
+		args map[string]interface{}
+		uri  string
+	}{
+		{diffToolName, map[string]interface{}{"old_uri": "journal://recent", "new_text": ""}, "journal://recent"},
+		{diffToolName, map[string]interface{}{"old_uri": "file:///documents/example.txt", "new_text": ""}, "file:///documents/example.txt"},
+		{summarizeToolName, map[string]interface{}{"uri": "journal://recent"}, "journal://recent"},
+		{queryTableToolName, map[string]interface{}{"file": "data/table.csv"}, "file:///data/table.csv"},
+	} {
+		result := callTool(t, s, tt.tool, tt.args)
+		if want := fmt.Sprintf("Resource '%s' not found", tt.uri); !result.IsError || !strings.Contains(mustText(result), want) {
+			t.Errorf("%s %v = %q, want a tool error %q", tt.tool, tt.args, mustText(result), want)
+		}
+	}
+}
+
+// decodeWire decodes a marshalled response.
+func decodeWire(t *testing.T, response []byte) wireMessage {
+	t.Helper()
+	var m wireMessage
+	if err := json.Unmarshal(response, &m); err != nil {
+		t.Fatalf("invalid response %s: %v", response, err)
+	}
+	return m
+}
+
+func TestEndpointPrincipals(t *testing.T) {
+	profiles, err := loadProfiles(writeProfiles(t, testProfiles))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := newTestEndpoint()
+	e.profiles = profiles
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+
+	post := func(token, session, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		if session != "" {
+			req.Header.Set(transport.HeaderSessionID, session)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+	listTools := `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`
+
+	if resp := post("wrong", "", initializeRequest); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("initialize with an unknown token = %s", resp.Status)
+	}
+
+	// The ci-bot principal gets the internal profile and keeps its session to itself
+	resp := post("s3cret", "", initializeRequest)
+	session := resp.Header.Get(transport.HeaderSessionID)
+	if resp.StatusCode != http.StatusOK || session == "" {
+		t.Fatalf("initialize as ci-bot = %s", resp.Status)
+	}
+	post("s3cret", session, initializedNotify)
+	if resp := post("", session, listTools); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("ci-bot's session without its token = %s", resp.Status)
+	}
+	body, _ := io.ReadAll(post("s3cret", session, listTools).Body)
+	if !strings.Contains(string(body), fmt.Sprintf("%q", diffToolName)) {
+		t.Errorf("ci-bot's tools = %s, want every tool", body)
+	}
+
+	// Anonymous clients get the default profile
+	anonymous := post("", "", initializeRequest).Header.Get(transport.HeaderSessionID)
+	post("", anonymous, initializedNotify)
+	body, _ = io.ReadAll(post("", anonymous, listTools).Body)
+	if strings.Contains(string(body), fmt.Sprintf("%q", diffToolName)) || !strings.Contains(string(body), `"ping"`) {
+		t.Errorf("anonymous tools = %s, want the untrusted profile's", body)
+	}
+}
@@ -0,0 +1,201 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/tools"
+)
+
+const (
+	promTimeout          = 30 * time.Second
+	promDefaultPoints    = 120   // Samples per series when no step is given
+	promMaxPoints        = 11000 // Prometheus rejects range queries with more points per series
+	promMaxSeries        = 500   // Series returned in the structured result
+	promMaxSummarySeries = 50    // Series described in the text summary
+)
+
+// promCacheTTL caches promql_query results for about one scrape interval,
+// so repeated "now" queries do not hit Prometheus but stay current.
+const promCacheTTL = 15 * time.Second
+
+// promArgs are the arguments of the promql_query tool.
+type promArgs struct {
+	Query string `json:"query"`
+	Time  string `json:"time"`
+	Start string `json:"start"`
+	End   string `json:"end"`
+	Range string `json:"range"`
+	Step  string `json:"step"`
+}
+
+// promQueryResult is the structured result of promql_query.
+type promQueryResult struct {
+	*tools.QueryResult
+	TotalSeries int  `json:"totalSeries"`
+	Truncated   bool `json:"truncated,omitempty"`
+}
+
+// parsePromTime parses "now", RFC 3339 or unix seconds, relative to now.
+func parsePromTime(s string, now time.Time) (time.Time, error) {
+	switch {
+	case s == "" || s == "now":
+		return now, nil
+	case strings.Contains(s, "T"):
+		return time.Parse(time.RFC3339, s)
+	}
+	sec, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: want now, RFC 3339 or unix seconds", s)
+	}
+	whole, frac := math.Modf(sec)
+	return time.Unix(int64(whole), int64(frac*1e9)), nil
+}
+
+// prometheusModule returns the promql_query tool, which runs read-only queries against client.
+func prometheusModule(client *tools.PrometheusClient) *toolModule {
+	stringProp := func(description string) map[string]interface{} {
+		return map[string]interface{}{"type": "string", "description": description}
+	}
+	return &toolModule{
+		name: "prometheus",
+		tools: []moduleTool{
+			{
+				tool: mcp.Tool{
+					Name:  "promql_query",
+					Title: "PromQL Query",
+					Description: "Runs a PromQL query against Prometheus. Without start or range it is an instant query; " +
+						"with them, a range query. Returns a text summary followed by the series as JSON.",
+					InputSchema: mcp.ToolInputSchema{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"query": stringProp("PromQL expression, e.g. sum by (job) (rate(http_requests_total[5m]))"),
+							"time":  stringProp("Evaluation time of an instant query: now (default), RFC 3339 or unix seconds"),
+							"range": stringProp("Range query over this duration ending at end, e.g. 1h"),
+							"start": stringProp("Range query start: RFC 3339 or unix seconds"),
+							"end":   stringProp("Range query end: now (default), RFC 3339 or unix seconds"),
+							"step":  stringProp(fmt.Sprintf("Range query resolution, e.g. 30s (default: about %d points)", promDefaultPoints)),
+						},
+						"required": []string{"query"},
+					},
+				},
+				limit:    4,
+				cacheTTL: promCacheTTL,
+				call: func(_ context.Context, _ *Server, raw map[string]interface{}) (mcp.CallToolResult, error) {
+					var args promArgs
+					if err := decodeToolArgs(raw, &args); err != nil {
+						return mcp.CallToolResult{}, err
+					}
+					result, err := runPromQuery(client, args, time.Now())
+					if err != nil {
+						return mcp.CallToolResult{}, err
+					}
+					structured := promQueryResult{QueryResult: result, TotalSeries: len(result.Series)}
+					summary := summarizePromResult(result)
+					if len(result.Series) > promMaxSeries {
+						limited := *result
+						limited.Series = result.Series[:promMaxSeries]
+						structured.QueryResult, structured.Truncated = &limited, true
+					}
+					return structuredResult(summary, "promql:"+args.Query, structured)
+				},
+			},
+		},
+		check: func() (string, error) { return client.BuildInfo() },
+	}
+}
+
+// runPromQuery validates the arguments and runs an instant or range query.
+func runPromQuery(client *tools.PrometheusClient, args promArgs, now time.Time) (*tools.QueryResult, error) {
+	if strings.TrimSpace(args.Query) == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	if args.Start == "" && args.Range == "" {
+		if args.End != "" || args.Step != "" {
+			return nil, fmt.Errorf("end and step need start or range")
+		}
+		t, err := parsePromTime(args.Time, now)
+		if err != nil {
+			return nil, err
+		}
+		return client.Query(args.Query, t)
+	}
+
+	end, err := parsePromTime(args.End, now)
+	if err != nil {
+		return nil, err
+	}
+	var start time.Time
+	switch {
+	case args.Start != "" && args.Range != "":
+		return nil, fmt.Errorf("give either start or range, not both")
+	case args.Range != "":
+		d, err := time.ParseDuration(args.Range)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid range %q: want a duration such as 1h", args.Range)
+		}
+		start = end.Add(-d)
+	default:
+		if start, err = parsePromTime(args.Start, now); err != nil {
+			return nil, err
+		}
+	}
+	if !start.Before(end) {
+		return nil, fmt.Errorf("start must be before end")
+	}
+
+	step := end.Sub(start) / promDefaultPoints
+	if args.Step != "" {
+		if step, err = time.ParseDuration(args.Step); err != nil || step <= 0 {
+			return nil, fmt.Errorf("invalid step %q: want a duration such as 30s", args.Step)
+		}
+	}
+	step = max(step.Round(time.Second), time.Second)
+	if points := end.Sub(start) / step; points > promMaxPoints {
+		return nil, fmt.Errorf("step %v gives %d points per series, more than %d; use a larger step", step, points, promMaxPoints)
+	}
+	return client.QueryRange(args.Query, start, end, step)
+}
+
+// summarizePromResult describes a result for the text content: the value of
+// each instant series, or the sample count, range and last value of each range series.
+func summarizePromResult(result *tools.QueryResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s result, %d series\n", result.ResultType, len(result.Series))
+	for _, w := range result.Warnings {
+		fmt.Fprintf(&b, "warning: %s\n", w)
+	}
+	format := func(f float64) string { return strconv.FormatFloat(f, 'g', 6, 64) }
+	for i, s := range result.Series {
+		if i == promMaxSummarySeries {
+			fmt.Fprintf(&b, "... %d more series in the JSON result\n", len(result.Series)-i)
+			break
+		}
+		if len(s.Samples) == 0 {
+			continue
+		}
+		last := s.Samples[len(s.Samples)-1]
+		name := s.LabelString()
+		if name == "{}" {
+			name = result.ResultType // Scalars and aggregates over everything have no labels
+		}
+		if result.ResultType != "matrix" {
+			fmt.Fprintf(&b, "%s  %s\n", name, format(last.Value))
+			continue
+		}
+		lo, hi := math.Inf(1), math.Inf(-1)
+		for _, sample := range s.Samples {
+			if !math.IsNaN(sample.Value) { // Stale markers and 0/0 would swallow the range
+				lo, hi = math.Min(lo, sample.Value), math.Max(hi, sample.Value)
+			}
+		}
+		fmt.Fprintf(&b, "%s  %d samples, min %s, max %s, last %s at %s\n",
+			name, len(s.Samples), format(lo), format(hi), format(last.Value), last.Time.Format(time.RFC3339))
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
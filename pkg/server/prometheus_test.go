@@ -0,0 +1,112 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/tools"
+	"sqirvy/mcp/pkg/utils"
+)
+
+func TestPromQLQuery(t *testing.T) {
+	var lastForm map[string][]string
+	prom := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		lastForm = r.PostForm
+		if r.Header.Get("Authorization") != "Bearer s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.URL.Path == "/api/v1/query" && r.PostForm.Get("query") == "bad(":
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"status":"error","errorType":"bad_data","error":"parse error: unclosed left parenthesis"}`)
+		case r.URL.Path == "/api/v1/query":
+			io.WriteString(w, `{"status":"success","data":{"resultType":"vector","result":[
+				{"metric":{"__name__":"up","job":"api"},"value":[1700000000,"1"]},
+				{"metric":{"__name__":"up","job":"db"},"value":[1700000000,"0"]}]}}`)
+		case r.URL.Path == "/api/v1/query_range":
+			io.WriteString(w, `{"status":"success","data":{"resultType":"matrix","result":[
+				{"metric":{"job":"api"},"values":[[1700000000,"3"],[1700000060,"9"],[1700000120,"NaN"]]}]}}`)
+		}
+	}))
+	defer prom.Close()
+
+	client, err := tools.NewPrometheusClient(prom.URL, "s3cret", promTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(&captureTransport{}, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	s.modules = []*toolModule{prometheusModule(client)}
+	content := func(result mcp.CallToolResult) (string, promQueryResult) {
+		var text mcp.TextContent
+		json.Unmarshal(result.Content[0], &text)
+		var embedded struct {
+			Resource mcp.TextResourceContents `json:"resource"`
+		}
+		var structured promQueryResult
+		if len(result.Content) > 1 {
+			json.Unmarshal(result.Content[1], &embedded)
+			json.Unmarshal([]byte(embedded.Resource.Text), &structured)
+		}
+		return text.Text, structured
+	}
+
+	text, structured := content(callTool(t, s, "promql_query", map[string]interface{}{"query": "up"}))
+	if !strings.Contains(text, `up{job="api"}  1`) || !strings.Contains(text, `up{job="db"}  0`) {
+		t.Errorf("instant summary = %q", text)
+	}
+	if structured.ResultType != "vector" || len(structured.Series) != 2 || structured.Series[0].Samples[0].Time.Unix() != 1700000000 {
+		t.Errorf("instant structured = %+v", structured)
+	}
+
+	text, structured = content(callTool(t, s, "promql_query", map[string]interface{}{"query": "rate(x[5m])", "range": "1h"}))
+	if !strings.Contains(text, `{job="api"}  3 samples, min 3, max 9, last NaN`) {
+		t.Errorf("range summary = %q", text)
+	}
+	if lastForm["step"][0] != "30" {
+		t.Errorf("step = %v, want 30 (1h over %d points)", lastForm["step"], promDefaultPoints)
+	}
+	if len(structured.Series) != 1 || len(structured.Series[0].Samples) != 3 {
+		t.Errorf("range structured = %+v", structured)
+	}
+
+	for _, args := range []map[string]interface{}{
+		{"query": "bad("},
+		{"query": "up", "range": "24h", "step": "1s"},
+		{"query": "up", "start": "2024-01-01T00:00:00Z", "range": "1h"},
+		{"query": "up", "step": "1m"},
+		{"query": ""},
+	} {
+		result := callTool(t, s, "promql_query", args)
+		if text, _ := content(result); !result.IsError {
+			t.Errorf("promql_query %v = %q, want a tool error", args, text)
+		}
+	}
+	if result := callTool(t, s, "promql_query", map[string]interface{}{"query": "bad("}); !strings.Contains(mustText(result), "unclosed left parenthesis") {
+		t.Errorf("API error not passed on: %q", mustText(result))
+	}
+}
+
+func mustText(result mcp.CallToolResult) string {
+	var text mcp.TextContent
+	json.Unmarshal(result.Content[0], &text)
+	return text.Text
+}
+
+func TestParsePromTime(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	for input, want := range map[string]int64{"": 1700000000, "now": 1700000000, "1600000000.5": 1600000000, "2024-01-01T00:00:00Z": 1704067200} {
+		got, err := parsePromTime(input, now)
+		if err != nil || got.Unix() != want {
+			t.Errorf("parsePromTime(%q) = %v, %v, want unix %d", input, got, err, want)
+		}
+	}
+}
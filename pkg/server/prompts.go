@@ -0,0 +1,29 @@
+package server
+
+import (
+	"sqirvy/mcp/pkg/mcp"
+	prompts "sqirvy/mcp/pkg/prompts"
+	// Import the custom logger
+)
+
+const (
+	QueryPromptName = "query"
+)
+
+// handleQueryPrompt handles the "prompts/get" request for the sqirvy_query prompt
+// It returns the prompt messages as defined in the sqirvyPrompt function
+func (s *Server) handleQueryPrompt(id mcp.RequestID, params mcp.GetPromptParams) ([]byte, error) {
+	s.logger.Printf("DEBUG", "Handle  : prompts/get request for '%s' (ID: %v)", params.Name, id)
+
+	result, err := mcp.NewGetPromptResult("A prompt for querying information using the Sqirvy system").
+		Assistant(mcp.NewTextContent(prompts.QueryPrompt(params.Name, params.Arguments))).
+		Build()
+	if err != nil {
+		s.logger.Printf("DEBUG", "Failed to build sqirvy_query prompt: %v", err)
+		rpcErr := mcp.NewRPCError(mcp.ErrorCodeInternalError, err.Error(), nil)
+		return s.marshalErrorResponse(id, rpcErr)
+	}
+
+	// Marshal the successful response
+	return s.marshalResponse(id, result)
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/tools"
+)
+
+const (
+	queryTableToolName = "query_table"
+	// queryTableDefaultLimit and queryTableMaxLimit bound the rows returned by one call.
+	queryTableDefaultLimit = 50
+	queryTableMaxLimit     = 1000
+)
+
+// queryTableArgs are the arguments of the query_table tool.
+type queryTableArgs struct {
+	File       string   `json:"file"`
+	Format     string   `json:"format"`
+	Columns    []string `json:"columns"`
+	Where      string   `json:"where"`
+	GroupBy    []string `json:"group_by"`
+	Aggregates []string `json:"aggregates"`
+	OrderBy    string   `json:"order_by"`
+	Limit      int      `json:"limit"`
+}
+
+// queryTableRows is the structured part of a query_table result.
+type queryTableRows struct {
+	Columns   []string                 `json:"columns"`
+	Rows      []map[string]interface{} `json:"rows"`
+	TotalRows int                      `json:"totalRows"` // Matching rows before the limit
+	Truncated bool                     `json:"truncated,omitempty"`
+}
+
+// queryTableTool describes the query_table tool for tools/list.
+func queryTableTool() mcp.Tool {
+	stringList := func(description string) map[string]interface{} {
+		return map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": description}
+	}
+	return mcp.Tool{
+		Name:  queryTableToolName,
+		Title: "Query Table",
+		Description: "Queries a CSV, TSV or JSON (array of objects) data file under the project root. " +
+			"Returns the result as a text table followed by the rows as JSON.",
+		InputSchema: mcp.ToolInputSchema{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"file":    map[string]interface{}{"type": "string", "description": "Path relative to the project root, or a file:// URI"},
+				"format":  map[string]interface{}{"type": "string", "enum": []string{"csv", "tsv", "json"}, "description": "File format; detected from the extension if omitted"},
+				"columns": stringList("Columns to return (default all)"),
+				"where": map[string]interface{}{"type": "string", "description": "Row filter, e.g. region = 'EU' and (amount >= 100 or note contains \"refund\"). " +
+					"Operators: = != < <= > >= contains, is [not] null, and, or, not, parentheses. Back-quote column names with spaces."},
+				"group_by":   stringList("Columns to group by for aggregates"),
+				"aggregates": stringList("Aggregates: count(*), count(col), sum(col), avg(col), min(col), max(col)"),
+				"order_by":   map[string]interface{}{"type": "string", "description": "Result column to sort by; prefix with - for descending"},
+				"limit":      map[string]interface{}{"type": "integer", "default": queryTableDefaultLimit, "description": fmt.Sprintf("Maximum rows to return (default %d, at most %d)", queryTableDefaultLimit, queryTableMaxLimit)},
+			},
+			"required": []string{"file"},
+		},
+	}
+}
+
+// handleQueryTableTool handles the "tools/call" request for the "query_table" tool.
+// Problems with the file or the query are reported as a tool error so the model can correct them.
+func (s *Server) handleQueryTableTool(id mcp.RequestID, params mcp.CallToolParams) ([]byte, error) {
+	s.logger.Printf("DEBUG", "Handle  : tools/call request for '%s' (ID: %v)", params.Name, id)
+
+	var args queryTableArgs
+	argBytes, _ := json.Marshal(params.Arguments) // Arguments came from JSON
+	if err := json.Unmarshal(argBytes, &args); err != nil {
+		rpcErr := mcp.NewRPCError(mcp.ErrorCodeInvalidParams, fmt.Sprintf("invalid arguments for %s: %v", queryTableToolName, err), nil)
+		return s.marshalErrorResponse(id, rpcErr)
+	}
+	if args.File == "" {
+		rpcErr := mcp.NewRPCError(mcp.ErrorCodeInvalidParams, fmt.Sprintf("%s requires a file argument", queryTableToolName), nil)
+		return s.marshalErrorResponse(id, rpcErr)
+	}
+
+	uri, result, total, err := s.runTableQuery(args)
+	if err != nil {
+		s.logger.Printf("DEBUG", "query_table on %s failed: %v", args.File, err)
+		return s.marshalResponse(id, mcp.NewToolResultError(fmt.Errorf("query_table: %w", err)))
+	}
+
+	rows := queryTableRows{Columns: result.Columns, Rows: result.Records(), TotalRows: total, Truncated: total > len(result.Rows)}
+	summary := fmt.Sprintf("%d of %d row(s)", len(result.Rows), total)
+	toolResult, err := structuredResult(result.Render()+summary, uri, rows)
+	if err != nil {
+		rpcErr := mcp.NewRPCError(mcp.ErrorCodeInternalError, err.Error(), nil)
+		return s.marshalErrorResponse(id, rpcErr)
+	}
+	return s.marshalResponse(id, toolResult)
+}
+
+// runTableQuery loads the file named by args within the project root and runs the query.
+// It returns the file's URI, the result cut to the limit and the number of rows before the cut.
+func (s *Server) runTableQuery(args queryTableArgs) (string, *tools.Table, int, error) {
+	uri := args.File
+	if !strings.HasPrefix(uri, "file://") {
+		uri = "file:///" + strings.TrimPrefix(uri, "/")
+	}
+	format := args.Format
+	if format == "" {
+		if format = tools.TableFormat(uri); format == "" {
+			return "", nil, 0, fmt.Errorf("cannot tell the format of %s from its extension; pass format", args.File)
+		}
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = queryTableDefaultLimit
+	}
+	limit = min(limit, queryTableMaxLimit)
+
+	// openFileResource applies the profile, confines the path to the session's file root and enforces the size limit
+	file, err := s.openFileResource(uri)
+	if err != nil {
+		return "", nil, 0, err
+	}
+	defer file.Close()
+	table, err := tools.LoadTable(file, format)
+	if err != nil {
+		return "", nil, 0, fmt.Errorf("failed to load %s: %w", args.File, err)
+	}
+	result, err := table.Query(tools.TableQuery{
+		Columns:    args.Columns,
+		Where:      args.Where,
+		GroupBy:    args.GroupBy,
+		Aggregates: args.Aggregates,
+		OrderBy:    args.OrderBy,
+	})
+	if err != nil {
+		return "", nil, 0, err
+	}
+
+	total := len(result.Rows)
+	if total > limit {
+		result.Rows = result.Rows[:limit]
+	}
+	return uri, result, total, nil
+}
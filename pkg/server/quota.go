@@ -0,0 +1,89 @@
+package server
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"sqirvy/mcp/pkg/mcp"
+)
+
+// Quotas
+//
+// A session may be given budgets so that a runaway agent cannot exhaust the
+// server: a number of calls (requests other than ping) and a number of bytes
+// transferred (every message received, plus the responses sent). Once a budget
+// is spent, further requests are refused with ErrorCodeQuotaExceeded; ping is
+// still answered so the client can tell a refused session from a dead one.
+// The first refusal for each quota is logged and sent to the client as a
+// notifications/message warning.
+
+// Names of the quotas, as reported in the error data.
+const (
+	quotaCalls = "calls"
+	quotaBytes = "bytes"
+)
+
+// sessionQuota limits what one session may use. Zero disables a limit.
+type sessionQuota struct {
+	maxCalls int64
+	maxBytes int64
+}
+
+// quotaUsage tracks a session's usage. The counters are updated by handler
+// goroutines too; reported is only touched by the processing loop.
+type quotaUsage struct {
+	calls    atomic.Int64
+	bytes    atomic.Int64
+	reported map[string]bool // Quotas whose exhaustion was already logged and notified
+}
+
+// quotaExceededData is the data of an ErrorCodeQuotaExceeded error.
+type quotaExceededData struct {
+	Quota string `json:"quota"`
+	Limit int64  `json:"limit"`
+	Used  int64  `json:"used"`
+}
+
+// exhausted returns the first quota the session has used up, if any.
+func (s *Server) exhausted() (quotaExceededData, bool) {
+	if max := s.quota.maxCalls; max > 0 {
+		if used := s.usage.calls.Load(); used >= max {
+			return quotaExceededData{Quota: quotaCalls, Limit: max, Used: used}, true
+		}
+	}
+	if max := s.quota.maxBytes; max > 0 {
+		if used := s.usage.bytes.Load(); used >= max {
+			return quotaExceededData{Quota: quotaBytes, Limit: max, Used: used}, true
+		}
+	}
+	return quotaExceededData{}, false
+}
+
+// chargeCall counts a request against the session's call quota, or returns the
+// error to refuse it with if a quota is used up. It must be called from the
+// processing loop.
+func (s *Server) chargeCall(method string) *mcp.RPCError {
+	if method == mcp.MethodPing {
+		return nil
+	}
+	data, over := s.exhausted()
+	if !over {
+		s.usage.calls.Add(1)
+		return nil
+	}
+	if !s.usage.reported[data.Quota] {
+		if s.usage.reported == nil {
+			s.usage.reported = make(map[string]bool)
+		}
+		s.usage.reported[data.Quota] = true
+		message := fmt.Sprintf("Session %s quota of %d exceeded; further requests are refused", data.Quota, data.Limit)
+		s.logger.Printf("INFO", "%s (client %q)", message, s.clientInfo.Name)
+		s.notify(mcp.MethodLogMessage, mcp.LoggingMessageParams{Level: "warning", Logger: "quota", Data: message})
+	}
+	return mcp.NewRPCError(mcp.ErrorCodeQuotaExceeded, fmt.Sprintf("Session %s quota exceeded", data.Quota), data)
+}
+
+// chargeBytes counts n bytes transferred against the session's byte quota.
+func (s *Server) chargeBytes(n int) {
+	s.usage.bytes.Add(int64(n))
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"testing"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/utils"
+)
+
+func TestQuotaRefusesRequestsOverBudget(t *testing.T) {
+	tr := &captureTransport{written: make(chan []byte, 16)}
+	s := NewServer(tr, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	s.quota = sessionQuota{maxCalls: 1}
+
+	s.processMessage([]byte(initializeRequest))
+	s.processMessage([]byte(initializedNotify))
+	readWire(t, tr.written)
+
+	s.processMessage([]byte(`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`))
+	s.processMessage([]byte(`{"jsonrpc":"2.0","id":3,"method":"tools/list"}`))
+	s.processMessage([]byte(`{"jsonrpc":"2.0","id":4,"method":"ping"}`))
+	s.processMessage([]byte(`{"jsonrpc":"2.0","id":5,"method":"prompts/list"}`))
+
+	if m := readWire(t, tr.written); fmt.Sprint(m.ID) != "2" || m.Error != nil {
+		t.Fatalf("first call = %+v, want a result", m)
+	}
+	var note struct {
+		Method string                   `json:"method"`
+		Params mcp.LoggingMessageParams `json:"params"`
+	}
+	if err := json.Unmarshal(<-tr.written, &note); err != nil || note.Method != mcp.MethodLogMessage || note.Params.Level != "warning" {
+		t.Fatalf("notification = %+v, %v; want a warning log message", note, err)
+	}
+	m := readWire(t, tr.written)
+	if fmt.Sprint(m.ID) != "3" || m.Error == nil || m.Error.Code != mcp.ErrorCodeQuotaExceeded {
+		t.Fatalf("second call = %+v, want ErrorCodeQuotaExceeded", m)
+	}
+	data, _ := json.Marshal(m.Error.Data)
+	if string(data) != `{"limit":1,"quota":"calls","used":1}` {
+		t.Errorf("error data = %s", data)
+	}
+	if m := readWire(t, tr.written); fmt.Sprint(m.ID) != "4" || m.Error != nil {
+		t.Errorf("ping = %+v, want a result", m)
+	}
+	// The exhaustion is reported once; later refusals only get the error
+	if m := readWire(t, tr.written); fmt.Sprint(m.ID) != "5" || m.Error == nil || m.Error.Code != mcp.ErrorCodeQuotaExceeded {
+		t.Errorf("third call = %+v, want ErrorCodeQuotaExceeded", m)
+	}
+}
+
+func TestQuotaCountsBytes(t *testing.T) {
+	tr := &captureTransport{written: make(chan []byte, 16)}
+	s := NewServer(tr, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	s.quota = sessionQuota{maxBytes: int64(len(initializeRequest))}
+
+	s.processMessage([]byte(initializeRequest))
+	s.processMessage([]byte(initializedNotify))
+	readWire(t, tr.written)
+	s.processMessage([]byte(`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`))
+	<-tr.written // The log message
+	if m := readWire(t, tr.written); m.Error == nil || m.Error.Code != mcp.ErrorCodeQuotaExceeded {
+		t.Errorf("call = %+v, want ErrorCodeQuotaExceeded", m)
+	}
+	if s.usage.bytes.Load() <= s.quota.maxBytes {
+		t.Errorf("bytes used = %d, want the initialize exchange counted", s.usage.bytes.Load())
+	}
+}
@@ -0,0 +1,180 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"sqirvy/mcp/pkg/mcp"
+	resources "sqirvy/mcp/pkg/resources" // Import the resources package (for ReadFileResource)
+	// Import the custom logger
+)
+
+// Define the example file resource as a package-level variable
+var exampleFileResource mcp.Resource = mcp.Resource{
+	Name:        "example.txt", // A user-friendly name
+	Title:       "Example Text File",
+	URI:         "file:///documents/example.txt",
+	Description: "An example text file.",
+	MimeType:    "text/plain", // Assuming text/plain
+	// Size could be determined by os.Stat if needed
+}
+
+// handleReadResource handles the "resources/read" request.
+// It parses the request, determines the resource type (e.g., file, data),
+// calls the appropriate reader function, and formats the response.
+func (s *Server) handleReadResource(ctx context.Context, id mcp.RequestID, payload []byte) ([]byte, error) {
+	s.logger.Printf("DEBUG", "Handle  : resources/read request (ID: %v)", id)
+
+	// Decode the params straight from the payload in one pass
+	var params mcp.ReadResourceParams
+	if err := mcp.UnmarshalParams(payload, &params); err != nil {
+		err = fmt.Errorf("failed to unmarshal read resource params: %w", err)
+		s.logger.Println("DEBUG", err.Error())
+		rpcErr := mcp.NewRPCError(mcp.ErrorCodeInvalidParams, err.Error(), nil)
+		return s.marshalErrorResponse(id, rpcErr)
+	}
+
+	if err := s.checkResourceProfile(params.URI); err != nil {
+		rpcErr := mcp.NewRPCError(mcp.ErrorCodeInvalidParams, err.Error(), map[string]string{"uri": params.URI})
+		return s.marshalErrorResponse(id, rpcErr)
+	}
+	if rpcErr := s.checkResourceDeprecation(id, params.URI); rpcErr != nil {
+		return s.marshalErrorResponse(id, rpcErr)
+	}
+
+	// Resources of tool modules are matched by their exact URI, see modules.go
+	if r, ok := s.moduleResource(params.URI); ok {
+		return s.handleModuleResource(ctx, id, r)
+	}
+	// Then registered resource templates, see templates.go
+	if t, vars, ok := s.matchTemplate(params.URI); ok {
+		return s.handleTemplateResource(ctx, id, params.URI, t, vars)
+	}
+
+	// Parse the URI
+	parsedURI, err := url.Parse(params.URI)
+	if err != nil {
+		err = fmt.Errorf("failed to parse resource URI '%s': %w", params.URI, err)
+		s.logger.Println("DEBUG", err.Error())
+		rpcErr := mcp.NewRPCError(mcp.ErrorCodeInvalidParams, err.Error(), nil)
+		return s.marshalErrorResponse(id, rpcErr)
+	}
+
+	// --- Route based on URI scheme/path ---
+	var resourceContentBytes []byte
+	var resourceMimeType string
+	var resourceErr error
+
+	switch parsedURI.Scheme {
+	case "data":
+		// data:// URIs are served by resource templates, such as random_data in templates.go
+		resourceErr = fmt.Errorf("unsupported data URI: %s", params.URI)
+
+	case "file":
+		// Delegate to the file reader in resources/read.go
+		file, openErr := s.openFileResource(params.URI)
+		if openErr != nil {
+			resourceErr = openErr
+			break
+		}
+		defer file.Close()
+		if file.Size > resources.StreamThreshold {
+			// Large files are base64-encoded straight into the response to keep memory bounded
+			s.logger.Printf("DEBUG", "Streaming %d byte file %s as blob", file.Size, file.Path)
+			responseBytes, err := marshalStreamedBlobResponse(id, params.URI, file.MimeType, file, file.Size)
+			if err != nil {
+				resourceErr = err
+				break
+			}
+			return responseBytes, nil
+		}
+		resourceMimeType = file.MimeType
+		if resourceContentBytes, err = io.ReadAll(file); err != nil {
+			resourceErr = fmt.Errorf("error reading file %s: %w", file.Path, err)
+		}
+
+	default:
+		// Scheme not supported
+		resourceErr = fmt.Errorf("resource URI scheme '%s' not supported", parsedURI.Scheme)
+	}
+
+	// --- Handle errors from resource reading ---
+	if resourceErr != nil {
+		s.logger.Printf("DEBUG", "Error reading resource URI '%s': %v", params.URI, resourceErr)
+		// Determine appropriate RPC error code based on the error type
+		// TODO: Refine error mapping (e.g., distinguish not found, permission denied)
+		rpcErrCode := mcp.ErrorCodeInternalError // Default to internal error
+		if errors.Is(resourceErr, resources.ErrFileTooLarge) {
+			rpcErrCode = mcp.ErrorCodeInvalidParams
+		} else if strings.Contains(resourceErr.Error(), "not found") {
+			// Use a specific code if available, e.g., a custom server error code or InvalidParams
+			rpcErrCode = mcp.ErrorCodeInvalidParams // Or a custom -320xx code
+		} else if strings.Contains(resourceErr.Error(), "permission denied") {
+			rpcErrCode = mcp.ErrorCodeInternalError // Or a custom -320xx code
+		} else if strings.Contains(resourceErr.Error(), "unsupported") || strings.Contains(resourceErr.Error(), "invalid") {
+			rpcErrCode = mcp.ErrorCodeInvalidParams
+		}
+		rpcErr := mcp.NewRPCError(rpcErrCode, resourceErr.Error(), map[string]string{"uri": params.URI})
+		return s.marshalErrorResponse(id, rpcErr)
+	}
+
+	// --- Prepare successful response ---
+	// Text types are served as text, anything else base64-encoded as a blob
+	var result mcp.ReadResourceResult
+	if isTextMimeType(resourceMimeType) {
+		result.Add(mcp.NewTextResource(params.URI, resourceMimeType, string(resourceContentBytes)))
+	} else {
+		result.Add(mcp.NewBlobResource(params.URI, resourceMimeType, resourceContentBytes))
+	}
+
+	return s.marshalResponse(id, result)
+}
+
+// isTextMimeType reports whether resources of this MIME type are served as text.
+func isTextMimeType(mimeType string) bool {
+	return strings.HasPrefix(mimeType, "text/") || mimeType == "application/json"
+}
+
+// checkResourceProfile returns the error resources/read answers with if the
+// session's profile does not allow uri: such a resource is reported as not
+// found, as if it did not exist.
+func (s *Server) checkResourceProfile(uri string) error {
+	if s.profile.allowsResource(uri) {
+		return nil
+	}
+	s.logger.Printf("DEBUG", "Access to resource '%s' refused by profile '%s'", uri, s.profile.name)
+	return fmt.Errorf("Resource '%s' not found", uri)
+}
+
+// readTextResource returns the text of a module or file:// resource, for tools
+// that work on resources. It applies the session's profile and goes through
+// openFileResource for files, so tools see exactly what resources/read would
+// serve.
+func (s *Server) readTextResource(ctx context.Context, uri string) (string, error) {
+	if err := s.checkResourceProfile(uri); err != nil {
+		return "", err
+	}
+	if r, ok := s.moduleResource(uri); ok {
+		return r.read(ctx)
+	}
+	file, err := s.openFileResource(uri)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	if !isTextMimeType(file.MimeType) {
+		return "", fmt.Errorf("%s is not a text resource (%s)", uri, file.MimeType)
+	}
+	if file.Size > resources.StreamThreshold {
+		return "", fmt.Errorf("%w: %s is larger than %d bytes", resources.ErrFileTooLarge, uri, resources.StreamThreshold)
+	}
+	content, err := io.ReadAll(io.LimitReader(file, resources.StreamThreshold+1))
+	if err != nil {
+		return "", fmt.Errorf("error reading file %s: %w", file.Path, err)
+	}
+	return string(content), nil
+}
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"sqirvy/mcp/pkg/clock"
+	"sqirvy/mcp/pkg/mcp"
+)
+
+// defaultToolCacheEntries bounds the results kept by the result cache.
+const defaultToolCacheEntries = 500
+
+// defaultToolCacheTTLs lists the tools whose results are cached and for how
+// long. Only pure tools belong here: the same arguments must give the same
+// result for the whole TTL. Override with the -tool-cache flag.
+var defaultToolCacheTTLs = map[string]time.Duration{
+	summarizeToolName: 5 * time.Minute, // Each call costs the client a model request
+}
+
+// cachedResult is a successful tools/call result.
+type cachedResult struct {
+	result  json.RawMessage
+	expires time.Time
+}
+
+// resultCache keeps the results of pure tools by tool name and normalized
+// arguments, so that a model repeating a call gets the answer without the
+// tool running again. Tool error results are not cached. Like the tool
+// limits, the cache is shared by every session.
+type resultCache struct {
+	mu         sync.Mutex
+	ttls       map[string]time.Duration // Tools without a positive TTL are not cached
+	maxEntries int
+	entries    map[string]cachedResult
+	clock      clock.Clock
+}
+
+// newResultCache creates a cache. maxEntries <= 0 disables it.
+func newResultCache(ttls map[string]time.Duration, maxEntries int) *resultCache {
+	c := &resultCache{
+		ttls:       make(map[string]time.Duration, len(ttls)),
+		maxEntries: maxEntries,
+		entries:    make(map[string]cachedResult),
+		clock:      clock.Real,
+	}
+	for name, ttl := range ttls {
+		c.ttls[name] = ttl
+	}
+	return c
+}
+
+// key returns the cache key of a call, and false if the tool is not cached.
+// Arguments are normalized: keys are sorted and null arguments dropped, so
+// calls that differ only in those respects share a result. Results are kept
+// apart per tenant, see tenants.go.
+func (c *resultCache) key(tenant string, params mcp.CallToolParams) (string, bool) {
+	if c.maxEntries <= 0 || c.ttls[params.Name] <= 0 {
+		return "", false
+	}
+	args := make(map[string]interface{}, len(params.Arguments))
+	for name, value := range params.Arguments {
+		if value != nil {
+			args[name] = value
+		}
+	}
+	data, err := json.Marshal(args)
+	if err != nil {
+		return "", false
+	}
+	return tenant + "\x00" + params.Name + "\x00" + string(data), true
+}
+
+// get returns the unexpired result stored under key.
+func (c *resultCache) get(key string) (json.RawMessage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || !c.clock.Now().Before(entry.expires) {
+		return nil, false
+	}
+	return entry.result, true
+}
+
+// store records the result in response under key, if the call succeeded.
+func (c *resultCache) store(key, tool string, response []byte) {
+	var resp mcp.RPCResponse
+	if err := json.Unmarshal(response, &resp); err != nil || resp.Error != nil {
+		return
+	}
+	var result mcp.CallToolResult
+	if err := json.Unmarshal(resp.Result, &result); err != nil || result.IsError {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.clock.Now()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.maxEntries {
+		c.evict(now)
+	}
+	c.entries[key] = cachedResult{result: resp.Result, expires: now.Add(c.ttls[tool])}
+}
+
+// evict drops expired results, or if there are none, the result that
+// expires first. The caller holds c.mu.
+func (c *resultCache) evict(now time.Time) {
+	var oldestKey string
+	var oldest time.Time
+	for key, entry := range c.entries {
+		switch {
+		case !now.Before(entry.expires):
+			delete(c.entries, key)
+		case oldestKey == "" || entry.expires.Before(oldest):
+			oldestKey, oldest = key, entry.expires
+		}
+	}
+	if len(c.entries) >= c.maxEntries {
+		delete(c.entries, oldestKey)
+	}
+}
+
+// parseToolCacheTTLs parses a -tool-cache value such as "summarize=10m,fetch=30s".
+// A TTL of 0 turns caching off for that tool.
+func parseToolCacheTTLs(spec string) (map[string]time.Duration, error) {
+	ttls := make(map[string]time.Duration)
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(field, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid tool cache TTL %q, want name=duration", field)
+		}
+		ttl, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || ttl < 0 {
+			return nil, fmt.Errorf("invalid duration in tool cache TTL %q", field)
+		}
+		ttls[strings.TrimSpace(name)] = ttl
+	}
+	return ttls, nil
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"sqirvy/mcp/pkg/clock"
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/utils"
+)
+
+func TestParseToolCacheTTLs(t *testing.T) {
+	got, err := parseToolCacheTTLs(" summarize=10m, fetch = 30s ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got["summarize"] != 10*time.Minute || got["fetch"] != 30*time.Second {
+		t.Errorf("parseToolCacheTTLs = %v", got)
+	}
+	for _, bad := range []string{"summarize", "=1m", "summarize=10", "summarize=-1m"} {
+		if _, err := parseToolCacheTTLs(bad); err == nil {
+			t.Errorf("parseToolCacheTTLs(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestResultCache(t *testing.T) {
+	runs := 0
+	lookup := &toolModule{name: "lookup", tools: []moduleTool{{
+		tool:     mcp.Tool{Name: "lookup"},
+		cacheTTL: time.Minute,
+		call: func(_ context.Context, _ *Server, args map[string]interface{}) (mcp.CallToolResult, error) {
+			runs++
+			if args["fail"] == true {
+				return mcp.NewToolResultError(errors.New("failed")), nil
+			}
+			return mcp.NewToolResultText(string(rune('0' + runs))), nil
+		},
+	}}}
+	s := NewServer(&captureTransport{}, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	s.modules = []*toolModule{lookup}
+	s.results = newResultCache(moduleToolCacheTTLs(s.modules), 2)
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+	s.results.clock = fake
+
+	call := func(args map[string]interface{}) string { return mustText(callTool(t, s, "lookup", args)) }
+	if first, again := call(map[string]interface{}{"a": 1, "b": "x"}), call(map[string]interface{}{"b": "x", "a": 1, "c": nil}); first != "1" || again != "1" {
+		t.Errorf("repeated call = %q then %q, want the cached result", first, again)
+	}
+	fake.Advance(time.Second)
+	if got := call(map[string]interface{}{"a": 2}); got != "2" {
+		t.Errorf("other arguments = %q, want a new run", got)
+	}
+	call(map[string]interface{}{"fail": true})
+	call(map[string]interface{}{"fail": true})
+	if runs != 4 {
+		t.Errorf("tool error results were cached: %d runs", runs)
+	}
+
+	// The cache holds two results; a third evicts the one that expires first.
+	fake.Advance(time.Second)
+	call(map[string]interface{}{"a": 3})
+	if got := call(map[string]interface{}{"a": 1, "b": "x"}); got != "6" {
+		t.Errorf("evicted result = %q, want a new run", got)
+	}
+	fake.Advance(time.Minute)
+	if got := call(map[string]interface{}{"a": 3}); got != "7" {
+		t.Errorf("expired result = %q, want a new run", got)
+	}
+
+	s.results = newResultCache(nil, 10)
+	call(nil)
+	if got := call(nil); got != "9" {
+		t.Errorf("tool without a TTL = %q, want a new run", got)
+	}
+}
@@ -0,0 +1,261 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"sqirvy/mcp/pkg/mcp"
+)
+
+// redacted replaces secrets found by the secrets sanitizer.
+const redacted = "[REDACTED]"
+
+// A sanitizer rewrites the text of a tool result or resource before it is
+// returned to the client, e.g. to keep secrets out of the model's context.
+type sanitizer func(text string) string
+
+// secretPatterns match credentials that should never reach a model. Patterns
+// with a group keep the group (the key name) and redact the rest.
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+	regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),                          // AWS access key IDs
+	regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`),                         // GitHub tokens
+	regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`),                         // Slack tokens
+	regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{20,}`),                                // OpenAI and Anthropic API keys
+	regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`), // JWTs
+	regexp.MustCompile(`(?i)(\bbearer\s+)[A-Za-z0-9._~+/=-]{16,}`),
+	regexp.MustCompile(`(?i)(\b(?:password|passwd|secret|api[_-]?key|access[_-]?token|auth[_-]?token)["']?\s*[:=]\s*["']?)[^\s"',;]+`),
+}
+
+// redactSecrets replaces credentials matched by secretPatterns.
+func redactSecrets(text string) string {
+	for _, re := range secretPatterns {
+		if re.NumSubexp() > 0 {
+			text = re.ReplaceAllString(text, "${1}"+redacted)
+		} else {
+			text = re.ReplaceAllLiteralString(text, redacted)
+		}
+	}
+	return text
+}
+
+var (
+	htmlBlocks = regexp.MustCompile(`(?is)<(script|style)\b.*?</(script|style)\s*>|<!--.*?-->`)
+	htmlTags   = regexp.MustCompile(`(?s)<[a-zA-Z/!][^>]*>`)
+)
+
+// stripHTML removes tags, comments, scripts and styles and decodes entities, so
+// markup cannot hide instructions from the user while the model reads them.
+func stripHTML(text string) string {
+	text = htmlBlocks.ReplaceAllString(text, "")
+	text = htmlTags.ReplaceAllString(text, "")
+	return html.UnescapeString(text)
+}
+
+// truncateText returns a sanitizer that keeps the first max characters and
+// says how much was cut.
+func truncateText(max int) sanitizer {
+	return func(text string) string {
+		n := utf8.RuneCountInString(text)
+		if n <= max {
+			return text
+		}
+		cut := 0
+		for i := range text {
+			if cut == max {
+				text = text[:i]
+				break
+			}
+			cut++
+		}
+		return fmt.Sprintf("%s\n[truncated: showing %d of %d characters]", text, max, n)
+	}
+}
+
+// sanitizePolicy picks the sanitizers for each tool and resource. Tools are
+// matched by name, resources by URI pattern, where a trailing * matches any
+// suffix; the longest matching pattern wins. "*" applies to everything
+// without a rule of its own.
+type sanitizePolicy struct {
+	rules map[string][]sanitizer
+}
+
+// toolSanitizers returns the sanitizers for a tool's results.
+func (p *sanitizePolicy) toolSanitizers(tool string) []sanitizer {
+	if rule, ok := p.rules[tool]; ok {
+		return rule
+	}
+	return p.rules["*"]
+}
+
+// resourceSanitizers returns the sanitizers for the contents of uri.
+func (p *sanitizePolicy) resourceSanitizers(uri string) []sanitizer {
+	best, found := "", false
+	for pattern := range p.rules {
+		if !strings.Contains(pattern, "://") {
+			continue // A tool name
+		}
+		prefix, wildcard := strings.CutSuffix(pattern, "*")
+		if (uri == pattern || wildcard && strings.HasPrefix(uri, prefix)) && len(pattern) > len(best) {
+			best, found = pattern, true
+		}
+	}
+	if found {
+		return p.rules[best]
+	}
+	return p.rules["*"]
+}
+
+// toolResponse sanitizes the text blocks of a marshalled tools/call response.
+// Error responses and responses that cannot be decoded are returned unchanged.
+func (p *sanitizePolicy) toolResponse(tool string, response []byte) []byte {
+	sanitizers := p.toolSanitizers(tool)
+	if len(sanitizers) == 0 {
+		return response
+	}
+	return rewriteResult(response, func(result map[string]json.RawMessage) bool {
+		var content []map[string]json.RawMessage
+		if json.Unmarshal(result["content"], &content) != nil {
+			return false
+		}
+		changed := false
+		for _, block := range content {
+			if sanitizeTextField(block, sanitizers) {
+				changed = true
+			}
+			var resource map[string]json.RawMessage // Embedded resource blocks
+			if json.Unmarshal(block["resource"], &resource) == nil && sanitizeTextField(resource, sanitizers) {
+				block["resource"], _ = json.Marshal(resource)
+				changed = true
+			}
+		}
+		if changed {
+			result["content"], _ = json.Marshal(content)
+		}
+		return changed
+	})
+}
+
+// resourceResponse sanitizes the text contents of a marshalled resources/read
+// response, choosing the sanitizers by each content's URI.
+func (p *sanitizePolicy) resourceResponse(response []byte) []byte {
+	if len(p.rules) == 0 {
+		return response
+	}
+	return rewriteResult(response, func(result map[string]json.RawMessage) bool {
+		var contents []map[string]json.RawMessage
+		if json.Unmarshal(result["contents"], &contents) != nil {
+			return false
+		}
+		changed := false
+		for _, c := range contents {
+			var uri string
+			json.Unmarshal(c["uri"], &uri)
+			if sanitizeTextField(c, p.resourceSanitizers(uri)) {
+				changed = true
+			}
+		}
+		if changed {
+			result["contents"], _ = json.Marshal(contents)
+		}
+		return changed
+	})
+}
+
+// rewriteResult decodes the result of a response, lets rewrite change it, and
+// marshals the response again if it did. The ID is kept raw so that it is
+// echoed verbatim.
+func rewriteResult(response []byte, rewrite func(result map[string]json.RawMessage) bool) []byte {
+	var resp struct {
+		JSONRPC string          `json:"jsonrpc"`
+		Result  json.RawMessage `json:"result,omitempty"`
+		Error   *mcp.RPCError   `json:"error,omitempty"`
+		ID      json.RawMessage `json:"id"`
+	}
+	if err := json.Unmarshal(response, &resp); err != nil || resp.Error != nil {
+		return response
+	}
+	var result map[string]json.RawMessage
+	if err := json.Unmarshal(resp.Result, &result); err != nil || !rewrite(result) {
+		return response
+	}
+	resp.Result, _ = json.Marshal(result) // Re-marshalling decoded JSON cannot fail
+	out, err := json.Marshal(resp)
+	if err != nil {
+		return response
+	}
+	return out
+}
+
+// sanitizeTextField applies sanitizers to the "text" member of obj, reporting
+// whether it changed.
+func sanitizeTextField(obj map[string]json.RawMessage, sanitizers []sanitizer) bool {
+	var text string
+	if len(sanitizers) == 0 || json.Unmarshal(obj["text"], &text) != nil {
+		return false
+	}
+	clean := text
+	for _, sanitize := range sanitizers {
+		clean = sanitize(clean)
+	}
+	if clean == text {
+		return false
+	}
+	obj["text"], _ = json.Marshal(clean)
+	return true
+}
+
+// parseSanitizePolicy parses a -sanitize value such as
+// "*=secrets,fetch=secrets+html+max:4000,file://*=secrets+max:100000". Each
+// rule lists sanitizers joined by +: secrets, html and max:<characters>. An
+// empty list turns sanitizing off for that target.
+func parseSanitizePolicy(spec string) (*sanitizePolicy, error) {
+	p := &sanitizePolicy{rules: make(map[string][]sanitizer)}
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		target, list, ok := strings.Cut(field, "=")
+		target = strings.TrimSpace(target)
+		if !ok || target == "" {
+			return nil, fmt.Errorf("invalid sanitize rule %q, want target=sanitizer+sanitizer", field)
+		}
+		var sanitizers []sanitizer
+		for _, name := range strings.Split(list, "+") {
+			switch name = strings.TrimSpace(name); {
+			case name == "":
+			case name == "secrets":
+				sanitizers = append(sanitizers, redactSecrets)
+			case name == "html":
+				sanitizers = append(sanitizers, stripHTML)
+			case strings.HasPrefix(name, "max:"):
+				n, err := strconv.Atoi(strings.TrimPrefix(name, "max:"))
+				if err != nil || n <= 0 {
+					return nil, fmt.Errorf("invalid length in sanitize rule %q", field)
+				}
+				sanitizers = append(sanitizers, truncateText(n))
+			default:
+				return nil, fmt.Errorf("unknown sanitizer %q in rule %q (want secrets, html or max:N)", name, field)
+			}
+		}
+		p.rules[target] = sanitizers
+	}
+	return p, nil
+}
+
+// String lists the configured targets, for logging.
+func (p *sanitizePolicy) String() string {
+	targets := make([]string, 0, len(p.rules))
+	for target := range p.rules {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	return strings.Join(targets, ",")
+}
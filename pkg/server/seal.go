@@ -0,0 +1,87 @@
+package server
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"sqirvy/mcp/pkg/utils"
+)
+
+// Encryption at rest
+//
+// With -seal-key-file, or the key in $MCP_SEAL_KEY, what the server persists
+// is encrypted with AES-256-GCM (see utils.Sealer): the log, the request and
+// response payloads in the -journal, the -memory-file and the -session-store.
+// Each scope below gets a key of its own, derived from the master key, so data
+// written for one cannot be passed off as another's.
+
+// sealKeyEnv holds the master key; it takes precedence over -seal-key-file.
+const sealKeyEnv = "MCP_SEAL_KEY"
+
+// Scopes of the sealed data.
+const (
+	sealScopeLog      = "log"      // The -log file, one sealed record per line
+	sealScopeJournal  = "journal"  // Payload columns of the -journal database
+	sealScopeMemory   = "memory"   // The -memory-file
+	sealScopeSessions = "sessions" // The -session-store file
+)
+
+// newSealer returns the Sealer of scope, or nil if key is nil: encryption at
+// rest is off.
+func newSealer(key []byte, scope string) (*utils.Sealer, error) {
+	if key == nil {
+		return nil, nil
+	}
+	return utils.NewSealer(key, scope)
+}
+
+// runUnseal implements "mcp-server unseal [-seal-key-file file] <log>": it
+// prints the records of a log written with encryption at rest. It returns the
+// process exit code.
+func runUnseal(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("unseal", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	keyFile := flags.String("seal-key-file", "", "File holding the key the log was sealed with; $"+sealKeyEnv+" overrides it")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: mcp-server unseal [-seal-key-file file] <log>")
+		fmt.Fprintln(stderr, "Prints a -log file written with encryption at rest.")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	key, err := utils.LoadSealKey(sealKeyEnv, *keyFile)
+	if err == nil && key == nil {
+		err = fmt.Errorf("no key: set $%s or -seal-key-file", sealKeyEnv)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "unseal: %v\n", err)
+		return 1
+	}
+	sealer, err := newSealer(key, sealScopeLog)
+	if err != nil {
+		fmt.Fprintf(stderr, "unseal: %v\n", err)
+		return 1
+	}
+	f, err := os.Open(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "unseal: %v\n", err)
+		return 1
+	}
+	defer f.Close()
+	err = utils.ReadSealedRecords(f, sealer, func(record []byte) error {
+		_, err := stdout.Write(record)
+		return err
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "unseal: %s: %v\n", flags.Arg(0), err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sqirvy/mcp/pkg/utils"
+)
+
+func TestUnsealLog(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, utils.SealKeySize)
+	t.Setenv(sealKeyEnv, hex.EncodeToString(key))
+	sealer, err := newSealer(key, sealScopeLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "mcp-server.log")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := utils.New(utils.NewSealedWriter(f, sealer), "", 0, utils.LevelInfo)
+	logger.Println("INFO", `received {"method":"tools/call","params":{"name":"secret"}}`)
+	f.Close()
+
+	data, _ := os.ReadFile(path)
+	if bytes.Contains(data, []byte("secret")) {
+		t.Fatalf("sealed log contains plaintext: %s", data)
+	}
+	var stdout, stderr bytes.Buffer
+	if code := runUnseal([]string{path}, &stdout, &stderr); code != 0 {
+		t.Fatalf("unseal = %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), `"name":"secret"`) {
+		t.Errorf("unsealed log = %q", stdout.String())
+	}
+
+	// Another key cannot read it
+	t.Setenv(sealKeyEnv, hex.EncodeToString(bytes.Repeat([]byte{8}, utils.SealKeySize)))
+	stdout.Reset()
+	if code := runUnseal([]string{path}, &stdout, &stderr); code != 1 || stdout.Len() != 0 {
+		t.Errorf("unseal with another key = %d, %q", code, stdout.String())
+	}
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"sqirvy/mcp/pkg/index"
+	"sqirvy/mcp/pkg/mcp"
+)
+
+const (
+	embeddingsTimeout    = 60 * time.Second
+	indexRefreshInterval = 30 * time.Second // Searches refresh an index older than this first
+	semanticDefaultK     = 5
+	semanticMaxK         = 50
+)
+
+// semanticSearchArgs are the arguments of the semantic_search tool.
+type semanticSearchArgs struct {
+	Query string `json:"query"`
+	K     *int   `json:"k"`
+	Path  string `json:"path"`
+}
+
+// semanticSearchResult is the structured result of semantic_search.
+type semanticSearchResult struct {
+	Query   string         `json:"query"`
+	Results []index.Result `json:"results"`
+}
+
+// indexModule returns the semantic_search tool over ix. The index is refreshed
+// incrementally by the first search after indexRefreshInterval, so only files
+// changed since then are embedded again.
+func indexModule(ix *index.Index) *toolModule {
+	return &toolModule{
+		name: "index",
+		tools: []moduleTool{
+			{
+				tool: mcp.Tool{
+					Name:  "semantic_search",
+					Title: "Semantic Search",
+					Description: "Searches the text files under the project root by meaning and returns the best matching " +
+						"chunks with their file:// URIs, line ranges and similarity scores (1 is best). Read a whole " +
+						"file with resources/read.",
+					InputSchema: mcp.ToolInputSchema{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"query": map[string]interface{}{"type": "string", "description": "What to look for, in words"},
+							"k": map[string]interface{}{
+								"type": "integer", "minimum": 1, "maximum": semanticMaxK, "default": semanticDefaultK,
+								"description": fmt.Sprintf("Number of chunks to return (default %d)", semanticDefaultK),
+							},
+							"path": map[string]interface{}{
+								"type": "string", "description": "Only search under this directory or file, relative to the project root",
+							},
+						},
+						"required": []string{"query"},
+					},
+				},
+				limit: 4,
+				call: func(_ context.Context, _ *Server, raw map[string]interface{}) (mcp.CallToolResult, error) {
+					var args semanticSearchArgs
+					if err := decodeToolArgs(raw, &args); err != nil {
+						return mcp.CallToolResult{}, err
+					}
+					return semanticSearch(ix, args)
+				},
+			},
+		},
+		check: func() (string, error) {
+			if _, err := ix.Embedder().Embed([]string{"doctor"}); err != nil {
+				return "", err
+			}
+			files, chunks, _ := ix.Stats()
+			return fmt.Sprintf("%s embeddings, %d file(s) and %d chunk(s) stored", ix.Embedder().Name(), files, chunks), nil
+		},
+	}
+}
+
+// semanticSearch validates the arguments, refreshes a stale index and searches it.
+func semanticSearch(ix *index.Index, args semanticSearchArgs) (mcp.CallToolResult, error) {
+	if strings.TrimSpace(args.Query) == "" {
+		return mcp.CallToolResult{}, fmt.Errorf("query is required")
+	}
+	k := semanticDefaultK
+	if args.K != nil {
+		k = *args.K
+	}
+	if k < 1 || k > semanticMaxK {
+		return mcp.CallToolResult{}, fmt.Errorf("k must be between 1 and %d", semanticMaxK)
+	}
+	if _, _, last := ix.Stats(); time.Since(last) > indexRefreshInterval {
+		if _, err := ix.Refresh(); err != nil {
+			return mcp.CallToolResult{}, fmt.Errorf("failed to refresh the index: %w", err)
+		}
+	}
+	results, err := ix.Search(args.Query, k, args.Path)
+	if err != nil {
+		return mcp.CallToolResult{}, err
+	}
+	if len(results) == 0 {
+		return mcp.NewToolResultText("No indexed files match."), nil
+	}
+
+	var text strings.Builder
+	for i, r := range results {
+		fmt.Fprintf(&text, "%d. %s lines %d-%d (score %.3f)\n", i+1, r.Path, r.StartLine, r.EndLine, r.Score)
+	}
+	result, err := structuredResult(text.String(), "semantic_search:"+args.Query, semanticSearchResult{Query: args.Query, Results: results})
+	if err != nil {
+		return mcp.CallToolResult{}, err
+	}
+	for _, r := range results {
+		result.Content = append(result.Content, mcp.NewResourceLink(mcp.ResourceLink{
+			URI: r.URI, Name: r.Path, MimeType: "text/plain",
+			Description: fmt.Sprintf("Lines %d-%d, score %.3f", r.StartLine, r.EndLine, r.Score),
+		}))
+	}
+	return result, nil
+}
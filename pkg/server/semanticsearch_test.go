@@ -0,0 +1,47 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sqirvy/mcp/pkg/index"
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/utils"
+)
+
+func TestSemanticSearchTool(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "notes.txt"), []byte("The outbox keeps responses in request order.\n"), 0644)
+	os.WriteFile(filepath.Join(root, "other.txt"), []byte("Unrelated words about gardening.\n"), 0644)
+	ix, err := index.Open(root, "", index.HashEmbedder{Dims: index.DefaultHashDims})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(&captureTransport{}, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	s.modules = []*toolModule{indexModule(ix)}
+
+	// The first search builds the index
+	result := callTool(t, s, "semantic_search", map[string]interface{}{"query": "response order in the outbox", "k": 1})
+	if result.IsError || !strings.HasPrefix(mustText(result), "1. notes.txt lines 1-1 (score ") || len(result.Content) != 3 {
+		t.Fatalf("semantic_search = %q (%d items)", mustText(result), len(result.Content))
+	}
+	var link mcp.ResourceLink
+	json.Unmarshal(result.Content[2], &link)
+	if link.Type != "resource_link" || link.URI != "file:///notes.txt" || link.Name != "notes.txt" {
+		t.Errorf("resource link: %+v", link)
+	}
+
+	for _, args := range []map[string]interface{}{{"query": " "}, {"query": "x", "k": 0}, {"query": "x", "k": semanticMaxK + 1}} {
+		if result := callTool(t, s, "semantic_search", args); !result.IsError {
+			t.Errorf("semantic_search %v succeeded, want a tool error", args)
+		}
+	}
+	if result := callTool(t, s, "semantic_search", map[string]interface{}{"query": "outbox", "path": "missing"}); mustText(result) != "No indexed files match." {
+		t.Errorf("search of a missing path = %q", mustText(result))
+	}
+}
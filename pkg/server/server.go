@@ -0,0 +1,726 @@
+// Package server implements mcp-server. A Server runs one MCP session over a
+// transport.Transport; an Endpoint (see embed.go) serves sessions to the
+// connections and HTTP requests of a program that embeds the server; and Main
+// is the mcp-server command, which cmd/mcp-server runs.
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	// Use the absolute module path
+	"sqirvy/mcp/pkg/clock"
+	"sqirvy/mcp/pkg/journal"
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/transport"
+	"sqirvy/mcp/pkg/utils" // Import the custom logger
+)
+
+// peekMessageType attempts to unmarshal just enough to get the method/id/error.
+// This is useful for logging before full unmarshalling and handling.
+func peekMessageType(logger *utils.Logger, payload []byte) (method string, id mcp.RequestID, isNotification bool, isResponse bool, isError bool) {
+	info, err := mcp.ClassifyMessage(payload)
+	if err != nil {
+		// Cannot determine type if basic unmarshal fails
+		logger.Printf("DEBUG", "Failed to classify JSON-RPC message: %v", err)
+		return "", nil, false, false, false
+	}
+
+	isNotification = info.Kind == mcp.KindNotification
+	isResponse = info.Kind == mcp.KindResponse || info.Kind == mcp.KindErrorResponse
+	isError = info.Kind == mcp.KindErrorResponse
+	return info.Method, info.ID, isNotification, isResponse, isError
+}
+
+// Server handles the MCP communication logic of one session: one client
+// connection, with its own lifecycle state, initialize results, request IDs
+// and quotas. A process hosts any number of them, one per stdio pipe, socket
+// connection (see listen.go) or Streamable HTTP session (see embed.go), all
+// created by the same newSession function. What the sessions share, such as
+// the tool limiter, the caches, the feature flags and the modules, is created
+// once per process and handed to each.
+type Server struct {
+	transport          transport.Transport // Message transport, e.g. newline-delimited JSON over stdio
+	logger             *utils.Logger       // Use the custom logger type
+	payloads           *payloadLog         // Logs received and sent payloads, see payloadlog.go
+	state              sessionState        // Lifecycle state, see state.go
+	legacyInit         bool                // Also accept the legacy "initialized" notification name
+	debug              bool                // Enables debug-only methods such as server/info
+	serverVersion      string
+	serverInfo         mcp.Implementation
+	incomingMessages   chan incomingMessage   // Channel for incoming messages
+	shutdown           chan struct{}          // Channel to signal shutdown
+	drainRequests      chan string            // Reasons passed to Drain, see drain.go
+	clock              clock.Clock            // Time source of timers and timestamps, see setClock
+	policy             sessionPolicy          // Session lifetime limits, see drain.go
+	quota              sessionQuota           // Session call and byte budgets, see quota.go
+	usage              quotaUsage             // Usage counted against quota
+	journal            *journal.Journal       // Records handled requests, nil without -journal; see journal.go
+	journalSession     int64                  // The session's number in the journal
+	out                *outbox                // Orders everything written to the transport, see outbox.go
+	notifications      *notifier              // Coalesces change notifications, see notifier.go
+	toolLimits         *toolLimiter           // Per-tool concurrency limits, see limits.go
+	idempotency        *idempotencyCache      // tools/call results by idempotency key, see idempotency.go
+	results            *resultCache           // Results of pure tools, see resultcache.go
+	argMode            argumentMode           // How tool arguments are checked against inputSchema, see toolargs.go
+	sanitizers         *sanitizePolicy        // Rewrites tool results and resource text before they are returned, see sanitize.go
+	locales            localeCatalog          // Translated server strings, see locale.go
+	locale             string                 // Catalog locale chosen at initialize, "" for the built-in strings
+	icons              *iconSet               // Icons of tools and prompts from -icons, nil without; see icons.go
+	profile            *capabilityProfile     // What the session may see and use, nil for everything; see profiles.go
+	hotpath            *hotPathMonitor        // Pending requests of every session, nil without it; see hotpath.go
+	features           *featureFlags          // Process-wide feature flags, see features.go
+	featureAdmin       bool                   // Feature flags may change at runtime, so the tool list may too
+	featureChanged     chan struct{}          // Signalled by the feature flags watcher
+	registry           *adminRegistry         // Sessions and errors shown by the admin surface, nil without it; see admin.go
+	status             sessionStatus          // Published for the admin surface
+	modules            []*toolModule          // Optional tool modules, see modules.go
+	templates          []*resourceTemplate    // Registered resource templates, see templates.go
+	deprecations       deprecations           // Deprecated tools and resources, see deprecation.go
+	tenancy            tenancy                // How the session's tenant is chosen, see tenants.go
+	tenant             string                 // Whose files and state the session sees, "" = unscoped; see tenants.go
+	clientRequests     clientRequests         // Server-initiated requests awaiting a reply, see clientrequests.go
+	clientCapabilities mcp.ClientCapabilities // From the initialize request
+	clientInfo         mcp.Implementation     // From the initialize request
+	handlers           sync.WaitGroup         // In-flight request handlers
+	requests           cancelableRequests     // Contexts of the in-flight requests, see cancel.go
+	activeIDs          activeRequestIDs       // IDs of the requests not yet answered, see claimRequestID
+	hooks              mcp.Hooks              // Lifecycle hooks of the embedder, see hooks.go
+	resumption         resumption             // Saves a Streamable HTTP session for resumption, see sessions.go
+
+	extensions map[string]MethodHandler // Non-standard methods, see methods.go
+
+	// Experimental capabilities, see experimental.go
+	experimental           map[string]mcp.ExperimentalNegotiator // Registered negotiators
+	negotiatedExperimental mcp.Experimental                      // Advertised in the InitializeResult
+	// Add state for resources, tools, prompts later
+}
+
+// NewServer creates a new MCP server instance communicating over the given transport.
+// Hooks, if any, are called in order at points in the session's life.
+func NewServer(t transport.Transport, logger *utils.Logger, hooks ...mcp.Hooks) *Server {
+	s := &Server{
+		transport:        t,
+		logger:           logger,
+		state:            stateAwaitingInitialize,
+		serverVersion:    "2024-11-05",                   // Align with your spec/schema version
+		incomingMessages: make(chan incomingMessage, 10), // Buffered channel
+		shutdown:         make(chan struct{}),
+		drainRequests:    make(chan string, 1),
+		clock:            clock.Real,
+		payloads:         newPayloadLog(defaultPayloadLogMax, 1),
+		out:              newOutbox(t, logger),
+		toolLimits:       newToolLimiter(defaultToolLimits, defaultToolQueueTimeout),
+		idempotency:      newIdempotencyCache(defaultIdempotencyWindow),
+		results:          newResultCache(nil, 0),
+		argMode:          argsLenient,
+		sanitizers:       &sanitizePolicy{},
+		features:         newFeatureFlags(),
+		featureChanged:   make(chan struct{}, 1),
+		experimental:     make(map[string]mcp.ExperimentalNegotiator),
+		serverInfo: mcp.Implementation{
+			Name:    "GoMCPExampleServer",
+			Title:   "Go MCP Example Server",
+			Version: serverVersionString(readBuildInfo()), // Set via -ldflags, see version.go
+		},
+	}
+	if len(hooks) > 0 {
+		s.hooks = mcp.ChainHooks(hooks...)
+	}
+	s.notifications = newNotifier(defaultNotifyWindow, func(payload []byte) { s.sendRawMessage(payload) }, logger)
+	s.RegisterResourceTemplate(RandomDataTemplate, randomDataHandler)
+	return s
+}
+
+// setClock replaces the time source of the session and its notifier, e.g. with
+// a fake clock in tests. The shared tool limiter and caches have their own.
+func (s *Server) setClock(c clock.Clock) {
+	s.clock = c
+	s.notifications.clock = c
+}
+
+// Run starts the server's main loop.
+func (s *Server) Run() error {
+	s.state = stateAwaitingInitialize // Ensure server starts in non-initialized state
+	if s.resumption.resumed {
+		s.state = stateReady // The handshake was done before, see sessions.go
+	}
+	s.status.setState(s.state.String(), "")
+	if s.registry != nil {
+		s.registry.add(s)
+		defer s.registry.remove(s)
+	}
+
+	// 1. Start background reader loop immediately
+	go s.readLoop()
+
+	// 2. Session policy timers, see drain.go
+	lifetimeTimer, lifetime := policyTimer(s.clock, s.policy.maxLifetime)
+	if lifetimeTimer != nil {
+		defer lifetimeTimer.Stop()
+	}
+	idleTimer, idle := policyTimer(s.clock, s.policy.idleTimeout)
+	if idleTimer != nil {
+		defer idleTimer.Stop()
+	}
+	// A host may start the server and never connect to it: without a handshake
+	// there is nothing to drain, so the session just ends
+	initTimer, initDeadline := policyTimer(s.clock, s.policy.initTimeout)
+	if initTimer != nil {
+		defer initTimer.Stop()
+	}
+	var drained <-chan struct{} // Set once a drain has started
+	defer s.features.watch(func() {
+		select {
+		case s.featureChanged <- struct{}{}:
+		default: // A change is already pending
+		}
+	})()
+	drainReason := ""
+	drain := func(reason string) {
+		if drained == nil {
+			drained, drainReason = s.beginDrain(reason), reason
+		}
+	}
+
+	// 3. Main processing loop
+	for {
+		// s.logger.Print("Waiting for incoming messages...")
+		select {
+		case msg := <-s.incomingMessages:
+			if idleTimer != nil {
+				idleTimer.Reset(s.policy.idleTimeout)
+			}
+			// Process the received message
+			s.processIncoming(msg)
+		case <-lifetime:
+			drain("maximum session lifetime reached")
+		case <-idle:
+			drain("idle timeout")
+		case <-initDeadline:
+			if s.state != stateAwaitingInitialize && s.state != stateAwaitingInitialized {
+				continue
+			}
+			s.logger.Printf("INFO", "No MCP handshake from the client within %s (state %s); the host may have started the server without connecting to it. Closing the session.",
+				s.policy.initTimeout, s.state)
+			s.finish()
+			s.hookShutdown("initialize timeout")
+			return nil
+		case reason := <-s.drainRequests:
+			drain(reason)
+		case <-s.featureChanged:
+			// A feature flag may have added or removed tools
+			if s.state == stateReady {
+				s.notify(mcp.MethodToolListChanged, nil)
+			}
+		case <-drained:
+			s.logger.Println("DEBUG", "Session drained. Exiting processing loop.")
+			s.finish()
+			s.hookShutdown(drainReason)
+			return nil
+		case <-s.shutdown:
+			s.logger.Println("DEBUG", "Shutdown signal received. Exiting processing loop.") // INFO level for shutdown
+			s.finish()
+			s.hookShutdown("connection closed")
+			return nil // Normal shutdown
+		case <-s.out.failed:
+			s.setState(stateBroken)
+			s.abandon()
+			err := fmt.Errorf("%w: %v", errSessionBroken, s.out.writeErr())
+			s.hookShutdown(err.Error())
+			return err
+		}
+	}
+}
+
+// finish processes the messages already read, lets in-flight handlers complete
+// and writes everything still queued before Run returns.
+func (s *Server) finish() {
+	for len(s.incomingMessages) > 0 {
+		s.processIncoming(<-s.incomingMessages)
+	}
+	s.handlers.Wait()
+	s.notifications.flush()
+	s.out.close()
+}
+
+// abandon tears down a session whose transport failed. Nothing more can reach
+// the client, so Run returns at once: the contexts of the in-flight requests
+// are canceled, so handlers waiting on the client, for a tool slot or on a
+// program give up; they finish in the background and their output is dropped
+// by the outbox.
+func (s *Server) abandon() {
+	s.logger.Printf("INFO", "Session broken, abandoning it: %v", s.out.writeErr())
+	s.requests.cancelAll()
+	go func() {
+		s.handlers.Wait()
+		s.notifications.flush()
+		s.out.close()
+	}()
+}
+
+// readLoop continuously reads messages from the server's transport,
+// sending valid JSON payloads to the incomingMessages channel.
+// It exits when the transport encounters an error (like io.EOF).
+func (s *Server) readLoop() {
+	defer func() {
+		s.logger.Println("DEBUG", "Exiting read loop.")
+		close(s.shutdown) // Signal the main loop to shut down when reading stops
+	}()
+
+	for {
+		payload, err := s.transport.ReadMessage()
+		var msg incomingMessage
+		if errors.As(err, &msg.tooLong) {
+			// The line was skipped; it is answered in turn and the session goes on
+			err = nil
+		}
+		if err != nil {
+			if err == io.EOF {
+				s.logger.Println("DEBUG", "EOF received from transport. Shutting down read loop.") // INFO level for EOF
+			} else {
+				s.logger.Printf("DEBUG", "Error reading from transport: %v", err)
+			}
+			return // Exit loop on EOF or any other error
+		}
+
+		// Basic validation: Check if it looks like JSON
+		if msg.tooLong == nil && !(bytes.HasPrefix(payload, []byte("{")) && bytes.HasSuffix(payload, []byte("}"))) {
+			s.logger.Printf("DEBUG", "Received message does not look like JSON object, skipping: %.200s", payload)
+			continue
+		}
+
+		// Send the raw payload (single line) to the processing loop
+		// Use a select with a default to prevent blocking if the channel is full,
+		// though the channel is buffered. Consider error handling if it fills up.
+		msg.payload = payload
+		select {
+		case s.incomingMessages <- msg:
+			// Successfully sent to channel
+		default:
+			s.logger.Println("DEBUG", "Warning: incomingMessages channel full. Discarding message.")
+			// Or potentially block, log more severely, or increase buffer size.
+		}
+	}
+}
+
+// incomingMessage is a message read by readLoop: a payload, or a line too long
+// to read, which is answered in arrival order like any other message.
+type incomingMessage struct {
+	payload []byte
+	tooLong *transport.LineTooLongError
+}
+
+// processIncoming processes a message read by readLoop.
+func (s *Server) processIncoming(msg incomingMessage) {
+	if msg.tooLong == nil {
+		s.processMessage(msg.payload)
+		return
+	}
+	// Answer with a parse error, naming the request if its ID is near the start
+	id := mcp.SalvageRequestID(msg.tooLong.Prefix)
+	s.logger.Printf("DEBUG", "Discarding oversized message (ID: %v): %v", id, msg.tooLong)
+	s.sendError(id, "", mcp.NewRPCError(mcp.ErrorCodeParseError,
+		fmt.Sprintf("Message of %d bytes exceeds the maximum of %d bytes", msg.tooLong.Size, msg.tooLong.Max), nil))
+}
+
+// setState records a lifecycle transition.
+func (s *Server) setState(next sessionState) {
+	s.logger.Printf("DEBUG", "Session state %s -> %s", s.state, next)
+	s.state = next
+	s.status.setState(next.String(), s.clientInfo.Name)
+}
+
+// isInitializedNotification reports whether method names the initialized notification.
+// The legacy "initialized" name is only recognized when legacyInit is set.
+func (s *Server) isInitializedNotification(method string) bool {
+	return method == mcp.MethodInitialized || (s.legacyInit && method == mcp.MethodInitializedLegacy)
+}
+
+// processMessage determines the type of message and routes it appropriately.
+// It also drives the lifecycle state machine in state.go: requests other than ping
+// are rejected with ErrorCodeServerNotReady until the initialized notification arrives.
+func (s *Server) processMessage(payload []byte) {
+	method, id, isNotification, isResponse, isError := peekMessageType(s.logger, payload)
+	s.payloads.logReceived(s.logger, payload)
+	s.chargeBytes(len(payload))
+
+	// Request IDs must be unique among the requests not yet answered. The ID of
+	// a request that is not dispatched is released when this returns, having
+	// been answered (or ignored) here; a dispatched one releases its own.
+	dispatched := false
+	if id != nil && !isNotification && !isResponse && !isError {
+		if !s.claimRequestID(id) {
+			s.logger.Printf("DEBUG", "Rejecting request (ID: %v, Method: %s): duplicate request ID", id, method)
+			rpcErr := mcp.NewRPCError(mcp.ErrorCodeInvalidRequest, fmt.Sprintf("Duplicate request ID %v", id), nil)
+			s.sendError(id, method, rpcErr)
+			return
+		}
+		defer func() {
+			if !dispatched {
+				s.releaseRequestID(id)
+			}
+		}()
+	}
+
+	// --- State Machine: Before Initialization ---
+	if s.state == stateAwaitingInitialize {
+		// State 1: Waiting for "initialize" request
+		if method == mcp.MethodInitialize && !isNotification && id != nil {
+			// Malformed params get an error and the client may try again
+			if rpcErr := s.validateParams(method, payload); rpcErr != nil {
+				s.sendError(id, method, rpcErr)
+				return
+			}
+			if rpcErr := s.hookInitialize(id, payload); rpcErr != nil {
+				s.logger.Printf("DEBUG", "Rejecting 'initialize' request (ID: %v): %s", id, rpcErr.Message)
+				s.sendError(id, method, rpcErr)
+				return
+			}
+			// s.logger.Printf("Received 'initialize' request (ID: %v) while not initialized.", id)
+			responseBytes, handleErr := s.handleInitializeRequest(id, payload)
+			if responseBytes != nil {
+				s.chargeBytes(len(responseBytes))
+				s.sendRawMessage(responseBytes) // Success or error, marshalled by the handler
+			}
+			if handleErr != nil {
+				// Only this session's handshake failed: it stays awaiting initialize,
+				// and the client may try again
+				s.logger.Printf("DEBUG", "Rejecting 'initialize' request (ID: %v): %v", id, handleErr)
+				return
+			}
+			s.setState(stateAwaitingInitialized) // Wait for notifications/initialized before serving requests
+			return
+		}
+	}
+
+	// --- State Machine: Initialized ---
+	// Handle messages received *after* initialization is complete.
+	// s.logger.Printf("Server is initialized. Processing message (Method: %s, ID: %v)", method, id)
+
+	if isNotification {
+		s.hookNotification(method, payload)
+		if s.isInitializedNotification(method) {
+			if next := s.state.afterInitialized(); next != s.state {
+				s.setState(next)
+				s.saveSession()
+				s.hookInitialized()
+			} else {
+				// Early or duplicate initialized notification (benign)
+				s.logger.Printf("DEBUG", "Ignoring '%s' notification in state %s.", method, s.state)
+			}
+			return
+		}
+		if method == mcp.MethodInitializedLegacy {
+			s.logger.Printf("DEBUG", "Ignoring legacy '%s' notification; start the server with -legacy-initialized to accept it.", method)
+			return
+		}
+		if method == mcp.MethodCancelled {
+			s.handleCancelled(payload)
+			return
+		}
+		s.logger.Printf("DEBUG", "Received Notification (Method: %s). No response needed.", method)
+		return
+	}
+
+	if isResponse || isError {
+		// Replies to server-initiated requests, see clientrequests.go
+		if !s.handleClientReply(id, payload) {
+			s.logger.Printf("DEBUG", "Warning: Received unexpected Response/Error message (ID: %v, Method: %s, IsError: %t). Ignoring.", id, method, isError)
+		}
+		return
+	}
+
+	// It's a Request (must have ID and method, not result/error)
+	if id == nil || method == "" {
+		s.logger.Printf("DEBUG", "Error: Received message that is not a valid Request, Notification, or Response. Payload: %.200s", payload)
+		// Cannot send error response if ID is missing.
+		return
+	}
+
+	// s.logger.Printf("Received Request (ID: %v, Method: %s)", id, method)
+
+	// A draining session finishes what it has but takes on nothing new
+	if s.state == stateDraining {
+		s.logger.Printf("DEBUG", "Rejecting request (ID: %v, Method: %s): session is draining", id, method)
+		rpcErr := mcp.NewRPCError(mcp.ErrorCodeShuttingDown, "Server shutting down", nil)
+		s.sendError(id, method, rpcErr)
+		return
+	}
+
+	// Until the client confirms initialization only ping (and initialize) may be dispatched
+	if !s.state.admits(method) {
+		s.logger.Printf("DEBUG", "Rejecting request (ID: %v, Method: %s): server not ready (state %s)", id, method, s.state)
+		rpcErr := mcp.NewRPCError(mcp.ErrorCodeServerNotReady, "Server not ready", map[string]string{"state": s.state.String()})
+		s.sendError(id, method, rpcErr)
+		return
+	}
+
+	// A session that has used up its budget is refused, see quota.go
+	if rpcErr := s.chargeCall(method); rpcErr != nil {
+		s.logger.Printf("DEBUG", "Rejecting request (ID: %v, Method: %s): %s", id, method, rpcErr.Message)
+		s.sendError(id, method, rpcErr)
+		return
+	}
+
+	// Handlers run concurrently; the reserved slot keeps the response in arrival order
+	received := s.clock.Now()
+	slot := s.out.reserve()
+	s.handlers.Add(1)
+	s.requests.begin(id) // Before the handler starts, so a cancellation cannot miss it
+	s.status.begin(id, method, received)
+	ticket := s.hotpath.begin(received)
+	dispatched = true
+	go func() {
+		defer s.handlers.Done()
+		defer s.status.end(id)
+		defer s.hotpath.end(ticket)
+		defer s.requests.end(id)
+		select {
+		case <-s.out.failed:
+			s.releaseRequestID(id)
+			s.out.fill(slot, nil) // Nobody to answer
+			return
+		default:
+		}
+		s.out.fill(slot, timeHandler(s.clock, method, received, func() []byte {
+			response := s.dispatch(id, method, payload)
+			s.releaseRequestID(id) // Before the response is queued: a client that has it may reuse the ID
+			if s.requests.end(id) {
+				s.logger.Printf("DEBUG", "Request (ID: %v, Method: %s) was canceled by the client; not answering", id, method)
+				return nil
+			}
+			s.recordErrorResponse(id, response)
+			s.chargeBytes(len(response))
+			s.journalRequest(id, method, received, payload, response)
+			s.hookResponse(id, method, response, s.clock.Since(received))
+			return response
+		}))
+	}()
+}
+
+// dispatch routes a request to its handler and returns the marshalled response.
+// It runs on its own goroutine, so handlers must not touch the session state.
+func (s *Server) dispatch(id mcp.RequestID, method string, payload []byte) []byte {
+	var responseBytes []byte
+	var handleErr error // Error returned by the handler function itself
+
+	// Reject params that do not match the method's schema before any handler runs
+	if method != mcp.MethodInitialize {
+		if rpcErr := s.validateParams(method, payload); rpcErr != nil {
+			return mcp.MustErrorResponse(id, rpcErr)
+		}
+	}
+
+	// Route to the appropriate handler: a switch over the interned standard
+	// method names (see mcp.InternMethod), then the extension methods
+	ctx := s.requestContext(id)
+	if s.hooks.OnRequest != nil {
+		if rpcErr := s.hooks.OnRequest(ctx, method, mcp.MessageParams(payload)); rpcErr != nil {
+			s.logger.Printf("DEBUG", "Request (ID: %v, Method: %s) rejected by hook: %s", id, method, rpcErr.Message)
+			return mcp.MustErrorResponse(id, rpcErr)
+		}
+	}
+	switch method {
+	case mcp.MethodInitialize:
+		// Handle duplicate 'initialize' request after initialization
+		s.logger.Printf("DEBUG", "Error: Received duplicate 'initialize' request (ID: %v) after initialization.", id)
+		rpcErr := mcp.NewRPCError(mcp.ErrorCodeInvalidRequest, "Server already initialized", nil)
+		responseBytes, handleErr = s.marshalErrorResponse(id, rpcErr) // Use helper
+
+	case mcp.MethodListTools:
+		responseBytes, handleErr = s.handleListTools(id)
+	case mcp.MethodCallTool:
+		// Pass the full payload to handleCallTool for parsing params
+		responseBytes, handleErr = s.handleCallTool(ctx, id, payload)
+	case mcp.MethodListPrompts:
+		responseBytes, handleErr = s.handleListPrompts(id)
+	case mcp.MethodGetPrompt:
+		responseBytes, handleErr = s.handleGetPrompt(id, payload)
+	case mcp.MethodListResources:
+		responseBytes, handleErr = s.handleListResources(id)
+	case mcp.MethodListResourceTemplates: // Added case for templates list
+		responseBytes, handleErr = s.handleListResourceTemplates(id)
+	case mcp.MethodComplete:
+		responseBytes, handleErr = s.handleComplete(id, payload)
+	case mcp.MethodReadResource: // Handle resources/read
+		responseBytes, handleErr = s.handleReadResource(ctx, id, payload)
+		responseBytes = s.sanitizers.resourceResponse(responseBytes)
+	case mcp.MethodPing: // Handle ping
+		responseBytes, handleErr = s.handlePingRequest(id)
+	case methodServerInfo: // Debug-only build/runtime info
+		if !s.debug {
+			responseBytes = createMethodNotFoundResponse(id, method)
+			break
+		}
+		responseBytes, handleErr = s.handleServerInfo(id)
+	default:
+		responseBytes, handleErr = s.dispatchExtension(ctx, id, method, payload)
+	}
+
+	// --- Response Sending ---
+	if handleErr != nil {
+		// The handler failed internally (e.g., failed to marshal its *intended* response/error).
+		s.logger.Printf("DEBUG", "Error during handling of request (ID: %v, Method: %s): %v", id, method, handleErr)
+		// If responseBytes is not nil here, it means the handler *did* manage to marshal an error response despite the internal error.
+		if responseBytes == nil {
+			// If the handler couldn't even produce an error response, create a generic one.
+			s.logger.Printf("DEBUG", "Handler failed without producing an error response. Creating generic InternalError.")
+			rpcErr := mcp.NewRPCError(mcp.ErrorCodeInternalError, fmt.Sprintf("Internal server error processing method %s", method), nil)
+			responseBytes = mcp.MustErrorResponse(id, rpcErr)
+		}
+	}
+
+	if responseBytes == nil {
+		// A handler returned neither bytes nor an error; answer anyway rather than leave the request hanging
+		s.logger.Printf("DEBUG", "Warning: No response bytes generated for request (ID: %v, Method: %s)", id, method)
+		rpcErr := mcp.NewRPCError(mcp.ErrorCodeInternalError, fmt.Sprintf("Internal server error processing method %s", method), nil)
+		responseBytes = mcp.MustErrorResponse(id, rpcErr)
+	}
+	// Return the response (either success or error marshalled by the handler or the generic error)
+	return responseBytes
+}
+
+// requestContext returns the context a request's handler runs with; it carries
+// the session, request ID and logger for mcp.SessionFromContext and friends,
+// and ends when the client cancels the request (see cancel.go).
+func (s *Server) requestContext(id mcp.RequestID) context.Context {
+	parent := context.Background()
+	if id != nil {
+		if ctx := s.requests.context(id); ctx != nil {
+			parent = ctx
+		}
+	}
+	return mcp.WithRequest(parent, s, id, s.logger)
+}
+
+// ClientInfo returns the client's name and version from initialize. With
+// ClientCapabilities it makes the session an mcp.Session for handlers.
+func (s *Server) ClientInfo() mcp.Implementation {
+	return s.clientInfo
+}
+
+// ClientCapabilities returns the capabilities the client declared in initialize.
+func (s *Server) ClientCapabilities() mcp.ClientCapabilities {
+	return s.clientCapabilities
+}
+
+// activeRequestIDs holds the IDs of the requests not yet answered. Handler
+// goroutines release them, so it is guarded by mu; it never holds more IDs
+// than there are requests in flight.
+type activeRequestIDs struct {
+	mu  sync.Mutex
+	ids map[string]struct{}
+}
+
+// claimRequestID records id as in use and reports whether no request not yet
+// answered has it. Numeric and string IDs are distinct, so 1 and "1" do not
+// collide. The caller must release a claimed ID with releaseRequestID.
+func (s *Server) claimRequestID(id mcp.RequestID) bool {
+	key := requestIDKey(id)
+	s.activeIDs.mu.Lock()
+	defer s.activeIDs.mu.Unlock()
+	if _, active := s.activeIDs.ids[key]; active {
+		return false
+	}
+	if s.activeIDs.ids == nil {
+		s.activeIDs.ids = make(map[string]struct{})
+	}
+	s.activeIDs.ids[key] = struct{}{}
+	return true
+}
+
+// releaseRequestID frees an ID claimed with claimRequestID once its request
+// is answered.
+func (s *Server) releaseRequestID(id mcp.RequestID) {
+	s.activeIDs.mu.Lock()
+	defer s.activeIDs.mu.Unlock()
+	delete(s.activeIDs.ids, requestIDKey(id))
+}
+
+// requestIDKey makes a request ID a map key. Numeric and string IDs are
+// distinct, so 1 and "1" do not collide.
+func requestIDKey(id mcp.RequestID) string {
+	switch id := id.(type) {
+	case json.Number:
+		return "n:" + string(id)
+	case string:
+		return "s:" + id
+	}
+	return fmt.Sprintf("%T:%v", id, id)
+}
+
+// sendError marshals and queues an error response for id, a request for
+// method that is refused before it is dispatched.
+func (s *Server) sendError(id mcp.RequestID, method string, rpcErr *mcp.RPCError) {
+	if s.registry != nil {
+		s.registry.recordError(s, id, rpcErr)
+	}
+	s.hookError(id, method, rpcErr)
+	responseBytes, _ := s.marshalErrorResponse(id, rpcErr) // Always a response; failures are logged
+	s.sendRawMessage(responseBytes)
+}
+
+// notify sends a server-initiated notification to the client. Change
+// notifications (resources/updated, */list_changed) are coalesced.
+func (s *Server) notify(method string, params interface{}) {
+	s.notifications.notify(method, params)
+}
+
+// sendRawMessage queues pre-marshalled bytes on the outbox behind every response
+// already reserved; the transport adds the framing. It returns immediately. A
+// failed write is logged by the outbox writer and ends this session only (see
+// out.failed in Run), never the process.
+func (s *Server) sendRawMessage(payload []byte) {
+	s.out.enqueue(payload)
+}
+
+// sendResponse marshals a successful result into a full RPCResponse and sends it.
+// Returns the marshalled bytes and any error during marshalling.
+// It does *not* send the bytes itself.
+func (s *Server) marshalResponse(id mcp.RequestID, result interface{}) ([]byte, error) {
+	resultBytes, err := json.Marshal(result)
+	if err != nil {
+		err = fmt.Errorf("failed to marshal result for response ID %v: %w", id, err)
+		s.logger.Println("DEBUG", err.Error())
+		// Return bytes for an internal error instead, along with the original error
+		rpcErr := mcp.NewRPCError(mcp.ErrorCodeInternalError, "Failed to marshal response result", nil)
+		return mcp.MustErrorResponse(id, rpcErr), err
+	}
+
+	resp := mcp.RPCResponse{
+		JSONRPC: mcp.JSONRPCVersion,
+		Result:  resultBytes,
+		ID:      id,
+	}
+	respBytes, err := json.Marshal(resp)
+	if err != nil {
+		// This is highly unlikely if result marshalling worked, but handle defensively
+		err = fmt.Errorf("failed to marshal final response object for ID %v: %w", id, err)
+		s.logger.Println("DEBUG", err.Error())
+		// Return bytes for an internal error instead, along with the original error
+		rpcErr := mcp.NewRPCError(mcp.ErrorCodeInternalError, "Failed to marshal final response object", nil)
+		return mcp.MustErrorResponse(id, rpcErr), err
+	}
+	s.payloads.logSent(s.logger, respBytes)
+
+	return respBytes, nil // Return marshalled success response bytes and nil error
+}
+
+// marshalErrorResponse marshals an RPCError into a full RPCResponse.
+// The bytes are always a valid response: if rpcErr cannot be marshalled, a
+// generic error is returned in its place along with the marshalling error.
+// It does *not* send the bytes itself.
+func (s *Server) marshalErrorResponse(id mcp.RequestID, rpcErr *mcp.RPCError) ([]byte, error) {
+	responseBytes, err := mcp.MarshalErrorResponse(id, rpcErr)
+	if err != nil {
+		s.logger.Printf("DEBUG", "CRITICAL: Failed to marshal error response (Code: %d, Msg: %s) for ID %v: %v", rpcErr.Code, rpcErr.Message, id, err)
+		return mcp.MustErrorResponse(id, rpcErr), fmt.Errorf("failed to marshal error response (Code: %d), sending generic error instead: %w", rpcErr.Code, err)
+	}
+	return responseBytes, nil
+}
@@ -0,0 +1,355 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/transport"
+	"sqirvy/mcp/pkg/transport/mem"
+	"sqirvy/mcp/pkg/utils"
+)
+
+// streamTransport reads newline-delimited input from a Stream and records what is written.
+type streamTransport struct {
+	*transport.Stream
+	captureTransport
+}
+
+func (s *streamTransport) ReadMessage() ([]byte, error) { return s.Stream.ReadMessage() }
+func (s *streamTransport) WriteMessage(p []byte) error  { return s.captureTransport.WriteMessage(p) }
+func (s *streamTransport) Close() error                 { return nil }
+
+func TestOversizedMessageIsAnswered(t *testing.T) {
+	huge := `{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"ping","arguments":{"x":"` + strings.Repeat("x", 5000) + `"}}}`
+	input := huge + "\n" + pingRequest + "\n" + `{"jsonrpc":"2.0","method":"x","params":"` + strings.Repeat("y", 5000) + "\"}\n"
+	stream := transport.NewStream(strings.NewReader(input), io.Discard)
+	stream.MaxLineSize = 1000
+	tr := &streamTransport{Stream: stream, captureTransport: captureTransport{written: make(chan []byte, 16)}}
+	s := NewServer(tr, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	if err := s.Run(); err != nil {
+		t.Fatalf("Run = %v", err)
+	}
+
+	if m := readWire(t, tr.written); m.ID != float64(7) || m.Error == nil || m.Error.Code != mcp.ErrorCodeParseError {
+		t.Errorf("first message = %+v, want a parse error for request 7", m)
+	}
+	// The session keeps serving after the oversized line
+	if m := readWire(t, tr.written); m.ID == nil || m.Error != nil {
+		t.Errorf("second message = %+v, want the ping reply", m)
+	}
+	if m := readWire(t, tr.written); m.ID != nil || m.Error == nil || m.Error.Code != mcp.ErrorCodeParseError {
+		t.Errorf("third message = %+v, want a parse error without an ID", m)
+	}
+}
+
+func TestResponsesEchoRequestIDVerbatim(t *testing.T) {
+	ids := []string{`9007199254740993`, `2.0`, `1e2`, `-0`, `"req-1"`}
+	var input strings.Builder
+	for _, id := range ids {
+		input.WriteString(`{"jsonrpc":"2.0","id":` + id + `,"method":"ping"}` + "\n")
+	}
+	stream := transport.NewStream(strings.NewReader(input.String()), io.Discard)
+	tr := &streamTransport{Stream: stream, captureTransport: captureTransport{written: make(chan []byte, 16)}}
+	s := NewServer(tr, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	if err := s.Run(); err != nil {
+		t.Fatalf("Run = %v", err)
+	}
+
+	for _, id := range ids {
+		select {
+		case out := <-tr.written:
+			if want := `"id":` + id + `}`; !strings.HasSuffix(string(out), want) {
+				t.Errorf("response %s does not end with %s", out, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no response for id %s", id)
+		}
+	}
+}
+
+func TestRequestIDReleasedWhenAnswered(t *testing.T) {
+	clientSide, serverSide := mem.NewPair()
+	s := NewServer(serverSide, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	release := make(chan struct{})
+	s.HandleMethod("x-test/wait", func(ctx context.Context, _ json.RawMessage) (interface{}, *mcp.RPCError) {
+		<-release
+		return map[string]string{}, nil
+	})
+	marked := make(chan struct{})
+	s.HandleMethod("x-test/mark", func(ctx context.Context, _ json.RawMessage) (interface{}, *mcp.RPCError) {
+		close(marked)
+		return map[string]string{}, nil
+	})
+	go s.Run()
+	defer clientSide.Close()
+
+	write := func(msg string) {
+		t.Helper()
+		if err := clientSide.WriteMessage([]byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(initializeRequest)
+	write(initializedNotify)
+	readResponse(t, clientSide, "1")
+
+	// An ID in flight cannot be reused
+	write(`{"jsonrpc":"2.0","id":5,"method":"x-test/wait"}`)
+	write(`{"jsonrpc":"2.0","id":5,"method":"ping"}`)
+	write(`{"jsonrpc":"2.0","id":6,"method":"x-test/mark"}`)
+	<-marked // Messages are taken in order, so the duplicate has been seen
+	close(release)
+	// Answers keep the order of the requests
+	if m := readMessage(t, clientSide); m.Error != nil {
+		t.Errorf("first request 5 = %+v, want a result", m)
+	}
+	if m := readMessage(t, clientSide); m.Error == nil || m.Error.Code != mcp.ErrorCodeInvalidRequest {
+		t.Errorf("duplicate of a request in flight = %+v, want -32600", m)
+	}
+	readResponse(t, clientSide, "6")
+
+	// Once answered it may, and the session keeps no record of it
+	for i := 0; i < 3; i++ {
+		write(`{"jsonrpc":"2.0","id":5,"method":"ping"}`)
+		if m := readMessage(t, clientSide); m.Error != nil {
+			t.Fatalf("ping %d reusing ID 5 = %+v, want a result", i, m)
+		}
+	}
+	s.activeIDs.mu.Lock()
+	defer s.activeIDs.mu.Unlock()
+	if n := len(s.activeIDs.ids); n != 0 {
+		t.Errorf("%d request IDs still held after every request was answered", n)
+	}
+}
+
+// failingTransport fails every write after the first ok ones, like a closed pipe.
+type failingTransport struct {
+	chanTransport
+	mu     sync.Mutex
+	ok     int
+	writes int
+}
+
+func (f *failingTransport) WriteMessage(payload []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.writes++; f.writes > f.ok {
+		return errors.New("write |1: broken pipe")
+	}
+	return f.captureTransport.WriteMessage(payload)
+}
+
+func TestWriteFailureBreaksSession(t *testing.T) {
+	tr := &failingTransport{chanTransport: chanTransport{captureTransport: captureTransport{written: make(chan []byte, 16)}, in: make(chan []byte, 4)}, ok: 1}
+	defer close(tr.in)
+	s := NewServer(tr, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	waited := make(chan error, 1)
+	s.modules = []*toolModule{{name: "test", tools: []moduleTool{{
+		tool: mcp.Tool{Name: "ask"},
+		call: func(ctx context.Context, session *Server, _ map[string]interface{}) (mcp.CallToolResult, error) {
+			var result struct{}
+			err := session.requestClient(ctx, "test/ask", nil, &result, time.Minute)
+			waited <- err
+			return mcp.CallToolResult{}, err
+		},
+	}}}}
+
+	done := make(chan error, 1)
+	go func() { done <- s.Run() }()
+	tr.in <- []byte(initializeRequest)
+	tr.in <- []byte(initializedNotify)
+	tr.in <- []byte(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"ask"}}`)
+
+	// Writing the request to the client fails: Run returns and the waiting handler gives up
+	select {
+	case err := <-done:
+		if !errors.Is(err, errSessionBroken) || !strings.Contains(err.Error(), "broken pipe") {
+			t.Errorf("Run = %v, want errSessionBroken", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after a write failure")
+	}
+	if s.state != stateBroken {
+		t.Errorf("state = %s, want %s", s.state, stateBroken)
+	}
+	select {
+	case err := <-waited:
+		if !errors.Is(err, errSessionBroken) {
+			t.Errorf("requestClient = %v, want errSessionBroken", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("handler still waiting for the client")
+	}
+}
+
+func TestWriteFailureCancelsHandlers(t *testing.T) {
+	tr := &failingTransport{chanTransport: chanTransport{captureTransport: captureTransport{written: make(chan []byte, 16)}, in: make(chan []byte, 4)}, ok: 1}
+	defer close(tr.in)
+	s := NewServer(tr, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	stopped := make(chan error, 1)
+	s.HandleMethod("x-test/wait", func(ctx context.Context, _ json.RawMessage) (interface{}, *mcp.RPCError) {
+		s.out.sendNow([]byte(`{"jsonrpc":"2.0","method":"x-test/progress"}`)) // Fails
+		<-ctx.Done()
+		stopped <- ctx.Err()
+		return nil, nil
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- s.Run() }()
+	tr.in <- []byte(initializeRequest)
+	tr.in <- []byte(initializedNotify)
+	tr.in <- []byte(`{"jsonrpc":"2.0","id":2,"method":"x-test/wait"}`)
+
+	// The session breaks and the running handler sees its context end
+	select {
+	case err := <-done:
+		if !errors.Is(err, errSessionBroken) {
+			t.Errorf("Run = %v, want errSessionBroken", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after a write failure")
+	}
+	select {
+	case err := <-stopped:
+		if err != context.Canceled {
+			t.Errorf("handler context ended with %v, want canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("handler still running after the session broke")
+	}
+}
+
+func TestSessionOverMemTransport(t *testing.T) {
+	clientSide, serverSide := mem.NewPair()
+	s := NewServer(serverSide, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	done := make(chan error, 1)
+	go func() { done <- s.Run() }()
+
+	request := func(payload string) []byte {
+		t.Helper()
+		if err := clientSide.WriteMessage([]byte(payload)); err != nil {
+			t.Fatal(err)
+		}
+		reply, err := clientSide.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return reply
+	}
+	request(initializeRequest)
+	if err := clientSide.WriteMessage([]byte(initializedNotify)); err != nil {
+		t.Fatal(err)
+	}
+	result, _, rpcErr, err := mcp.UnmarshalListToolsResponse(request(`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`))
+	if err != nil || rpcErr != nil || len(result.Tools) == 0 {
+		t.Fatalf("tools/list = %+v, %v, %v", result, rpcErr, err)
+	}
+
+	// Closing the client side ends the session
+	clientSide.Close()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after the client closed")
+	}
+}
+
+func TestFailedInitializeKeepsSessionOpen(t *testing.T) {
+	clientSide, serverSide := mem.NewPair()
+	s := NewServer(serverSide, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	done := make(chan error, 1)
+	go func() { done <- s.Run() }()
+	defer clientSide.Close()
+
+	// Passes the schema, fails in the handler: answered, and the session waits for another try
+	if err := clientSide.WriteMessage([]byte(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"","capabilities":{},"clientInfo":{"name":"c","version":"1"}}}`)); err != nil {
+		t.Fatal(err)
+	}
+	reply, err := clientSide.ReadMessage()
+	if err != nil || !strings.Contains(string(reply), `"error"`) {
+		t.Fatalf("initialize without protocolVersion = %s, %v", reply, err)
+	}
+	if err := clientSide.WriteMessage([]byte(strings.Replace(initializeRequest, `"id":1`, `"id":3`, 1))); err != nil {
+		t.Fatal(err)
+	}
+	if reply, err = clientSide.ReadMessage(); err != nil || !strings.Contains(string(reply), `"serverInfo"`) {
+		t.Fatalf("second initialize = %s, %v", reply, err)
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("session ended: %v", err)
+	default:
+	}
+}
+
+func TestConcurrentSessionsKeepTheirOwnState(t *testing.T) {
+	logger := utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo)
+	type session struct {
+		client *mem.Transport
+		server *Server
+		done   chan error
+	}
+	start := func() *session {
+		clientSide, serverSide := mem.NewPair()
+		ss := &session{client: clientSide, server: NewServer(serverSide, logger), done: make(chan error, 1)}
+		go func() { ss.done <- ss.server.Run() }()
+		return ss
+	}
+	request := func(ss *session, payload string) *mcp.RPCError {
+		t.Helper()
+		if err := ss.client.WriteMessage([]byte(payload)); err != nil {
+			t.Fatal(err)
+		}
+		reply, err := ss.client.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var resp mcp.RPCResponse
+		if err := json.Unmarshal(reply, &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp.Error
+	}
+	initialize := func(ss *session, client string) {
+		t.Helper()
+		if rpcErr := request(ss, strings.Replace(initializeRequest, `"name":"test"`, `"name":"`+client+`"`, 1)); rpcErr != nil {
+			t.Fatalf("initialize %s: %v", client, rpcErr)
+		}
+		ss.client.WriteMessage([]byte(initializedNotify))
+	}
+
+	// Both initialize with request ID 1: IDs are only unique within a session
+	a, b := start(), start()
+	initialize(a, "a")
+	if rpcErr := request(a, listToolsRequest); rpcErr != nil {
+		t.Errorf("initialized session refused tools/list: %v", rpcErr)
+	}
+	// b has not initialized, whatever a did
+	if rpcErr := request(b, listToolsRequest); rpcErr == nil || rpcErr.Code != mcp.ErrorCodeServerNotReady {
+		t.Errorf("uninitialized session answered tools/list: %v", rpcErr)
+	}
+	initialize(b, "b")
+	if rpcErr := request(b, `{"jsonrpc":"2.0","id":3,"method":"tools/list"}`); rpcErr != nil {
+		t.Errorf("tools/list after initialize: %v", rpcErr)
+	}
+	if a.server.clientInfo.Name != "a" || b.server.clientInfo.Name != "b" {
+		t.Errorf("client names = %q, %q", a.server.clientInfo.Name, b.server.clientInfo.Name)
+	}
+
+	for _, ss := range []*session{a, b} {
+		ss.client.Close()
+		select {
+		case <-ss.done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("session did not end")
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"sqirvy/mcp/pkg/clock"
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/utils"
+)
+
+// defaultSessionTTL is how long an HTTP session that stopped running can be
+// resumed with its Mcp-Session-Id.
+const defaultSessionTTL = time.Hour
+
+// savedSession is the state an HTTP session negotiated at initialize, kept so
+// that a client can go on using its Mcp-Session-Id after the session's server
+// ended, e.g. on an idle timeout or a restart of the process, without a new
+// handshake. Replay buffers and requests in flight are not kept: a resumed
+// session starts with fresh event streams.
+type savedSession struct {
+	ID                 string                 `json:"id"`
+	Principal          string                 `json:"principal,omitempty"` // See profiles.go
+	Tenant             string                 `json:"tenant,omitempty"`    // See tenants.go
+	ClientInfo         mcp.Implementation     `json:"clientInfo"`
+	ClientCapabilities mcp.ClientCapabilities `json:"clientCapabilities"`
+	Experimental       mcp.Experimental       `json:"experimental,omitempty"` // Negotiated, see experimental.go
+	Locale             string                 `json:"locale,omitempty"`       // See locale.go
+	LastSeen           time.Time              `json:"lastSeen"`
+}
+
+// sessionStore keeps the saved state of HTTP sessions by session ID, in memory
+// and, with -session-store, in a JSON file that survives restarts, sealed with
+// -seal-key-file. Entries not seen for ttl are forgotten.
+type sessionStore struct {
+	path   string        // JSON file of the sessions, "" to keep them in memory only
+	ttl    time.Duration // How long a session can be resumed after it was last seen
+	sealer *utils.Sealer // Encrypts the file at rest, nil for plain JSON; see seal.go
+	clock  clock.Clock
+	mu     sync.Mutex
+	saved  map[string]savedSession
+}
+
+// openSessionStore returns a store persisted to path, loading the sessions
+// already there, or an in-memory store if path is "". With a sealKey the file
+// is encrypted at rest; a plain file written before is read as it is.
+func openSessionStore(path string, ttl time.Duration, sealKey []byte) (*sessionStore, error) {
+	sealer, err := newSealer(sealKey, sealScopeSessions)
+	if err != nil {
+		return nil, err
+	}
+	st := &sessionStore{path: path, ttl: ttl, sealer: sealer, clock: clock.Real, saved: make(map[string]savedSession)}
+	if path == "" {
+		return st, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session store: %w", err)
+	}
+	if sealer != nil && !json.Valid(data) {
+		if data, err = sealer.Open(data, nil); err != nil {
+			return nil, fmt.Errorf("failed to read session store %s: %w", path, err)
+		}
+	}
+	var sessions []savedSession
+	if err := json.Unmarshal(data, &sessions); err != nil {
+		return nil, fmt.Errorf("failed to read session store %s: %w", path, err)
+	}
+	for _, saved := range sessions {
+		st.saved[saved.ID] = saved
+	}
+	return st, nil
+}
+
+// put records the state of a session, as last seen now.
+func (st *sessionStore) put(saved savedSession) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	saved.LastSeen = st.clock.Now()
+	st.saved[saved.ID] = saved
+	return st.save()
+}
+
+// get returns the state of the session id, unless it is unknown or expired.
+func (st *sessionStore) get(id string) (savedSession, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	saved, ok := st.saved[id]
+	if ok && st.clock.Now().Sub(saved.LastSeen) > st.ttl {
+		delete(st.saved, id)
+		return savedSession{}, false
+	}
+	return saved, ok
+}
+
+// remove forgets the session id, which can then no longer be resumed.
+func (st *sessionStore) remove(id string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if _, ok := st.saved[id]; !ok {
+		return nil
+	}
+	delete(st.saved, id)
+	return st.save()
+}
+
+// save drops expired sessions and writes the rest to a temporary file that is
+// renamed into place. The caller holds st.mu.
+func (st *sessionStore) save() error {
+	now := st.clock.Now()
+	sessions := make([]savedSession, 0, len(st.saved))
+	for id, saved := range st.saved {
+		if now.Sub(saved.LastSeen) > st.ttl {
+			delete(st.saved, id)
+			continue
+		}
+		sessions = append(sessions, saved)
+	}
+	if st.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(sessions)
+	if err != nil {
+		return fmt.Errorf("failed to save session store: %w", err)
+	}
+	if st.sealer != nil {
+		if data, err = st.sealer.Seal(data, nil); err != nil {
+			return fmt.Errorf("failed to save session store: %w", err)
+		}
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(st.path), filepath.Base(st.path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to save session store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save session store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save session store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), st.path); err != nil {
+		return fmt.Errorf("failed to save session store: %w", err)
+	}
+	return nil
+}
+
+// resumption is what a Streamable HTTP session needs to be saved to and
+// resumed from a sessionStore.
+type resumption struct {
+	store     *sessionStore // nil if the session cannot be resumed
+	id        string        // Mcp-Session-Id
+	principal string        // Bearer token principal, see profiles.go
+	resumed   bool          // Restored from store: Run starts ready
+	saved     bool          // The handshake is complete and the session was saved
+}
+
+// saveSession records the session's negotiated state in its store, if it has
+// one, once the handshake is complete and again when the session ends.
+func (s *Server) saveSession() {
+	if s.resumption.store == nil {
+		return
+	}
+	err := s.resumption.store.put(savedSession{
+		ID:                 s.resumption.id,
+		Principal:          s.resumption.principal,
+		Tenant:             s.tenant,
+		ClientInfo:         s.clientInfo,
+		ClientCapabilities: s.clientCapabilities,
+		Experimental:       s.negotiatedExperimental,
+		Locale:             s.locale,
+	})
+	if err != nil {
+		s.logger.Printf("INFO", "HTTP session %s cannot be resumed: %v", s.resumption.id, err)
+		return
+	}
+	s.resumption.saved = true
+}
+
+// restoreSession makes the session continue where the saved one left off:
+// Run starts it in the ready state, with the client's capabilities, locale and
+// tenant as negotiated before, instead of waiting for initialize.
+func (s *Server) restoreSession(saved savedSession) {
+	s.clientInfo = saved.ClientInfo
+	s.clientCapabilities = saved.ClientCapabilities
+	s.negotiatedExperimental = saved.Experimental
+	s.locale = saved.Locale
+	if saved.Tenant != "" {
+		s.setTenant(saved.Tenant)
+	}
+	s.resumption.resumed, s.resumption.saved = true, true
+}
@@ -0,0 +1,177 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"sqirvy/mcp/pkg/clock"
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/transport"
+	"sqirvy/mcp/pkg/utils"
+)
+
+func TestSessionStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.json")
+	st, err := openSessionStore(path, time.Minute, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := clock.NewFake(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	st.clock = fake
+	saved := savedSession{ID: "a", Principal: "alice", ClientInfo: mcp.Implementation{Name: "client"}, Locale: "de"}
+	if err := st.put(saved); err != nil {
+		t.Fatal(err)
+	}
+
+	// The file survives a restart
+	reopened, err := openSessionStore(path, time.Minute, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reopened.clock = fake
+	got, ok := reopened.get("a")
+	if !ok || got.Principal != "alice" || got.ClientInfo.Name != "client" || got.Locale != "de" {
+		t.Errorf("reopened session = %+v, %v", got, ok)
+	}
+
+	// Sessions expire ttl after they were last seen
+	fake.Advance(2 * time.Minute)
+	if _, ok := reopened.get("a"); ok {
+		t.Error("expired session can still be resumed")
+	}
+	if err := reopened.put(savedSession{ID: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := reopened.remove("b"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := reopened.get("b"); ok {
+		t.Error("removed session can still be resumed")
+	}
+}
+
+func TestSessionStoreSealed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.json")
+	key := bytes.Repeat([]byte{3}, utils.SealKeySize)
+	st, err := openSessionStore(path, time.Minute, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.put(savedSession{ID: "a", Principal: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if bytes.Contains(data, []byte("alice")) {
+		t.Fatalf("session store is not sealed: %s", data)
+	}
+	reopened, err := openSessionStore(path, time.Minute, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := reopened.get("a"); !ok || got.Principal != "alice" {
+		t.Errorf("reopened session = %+v, %v", got, ok)
+	}
+	if _, err := openSessionStore(path, time.Minute, nil); err == nil {
+		t.Error("sealed session store opened without the key")
+	}
+}
+
+func TestServeHTTPResumesSession(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.json")
+	newEndpoint := func() *Endpoint {
+		e := newTestEndpoint()
+		var err error
+		if e.store, err = openSessionStore(path, time.Minute, nil); err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+	var mu sync.Mutex
+	e := newEndpoint()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		current := e
+		mu.Unlock()
+		current.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	client, err := transport.NewHTTP(srv.URL, transport.HTTPOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.WriteMessage([]byte(initializeRequest)); err != nil {
+		t.Fatal(err)
+	}
+	readResponse(t, client, "1")
+	if err := client.WriteMessage([]byte(initializedNotify)); err != nil {
+		t.Fatal(err)
+	}
+	session := client.SessionID()
+
+	// Restart: the old sessions drain, a new endpoint takes over the store
+	e.Drain("restarting")
+	waitForSessions(t, e, 0)
+	mu.Lock()
+	e = newEndpoint()
+	mu.Unlock()
+
+	// The session goes on without a new handshake
+	if err := client.WriteMessage([]byte(listToolsRequest)); err != nil {
+		t.Fatalf("request after restart: %v", err)
+	}
+	if m := readResponse(t, client, "2"); m.Error != nil {
+		t.Errorf("tools/list after restart = %+v", m.Error)
+	}
+	if client.SessionID() != session {
+		t.Errorf("session ID changed from %s to %s", session, client.SessionID())
+	}
+
+	// DELETE ends it for good
+	client.Close()
+	waitForSessions(t, e, 0)
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader(`{"jsonrpc":"2.0","id":3,"method":"ping"}`))
+	req.Header.Set(transport.HeaderSessionID, session)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("request after DELETE = %s, want 404", resp.Status)
+	}
+}
+
+// readResponse reads messages from tr until the response to request id.
+func readResponse(t *testing.T, tr transport.Transport, id string) wireMessage {
+	t.Helper()
+	for {
+		if m := readMessage(t, tr); m.Method == "" && fmt.Sprint(m.ID) == id {
+			return m
+		}
+	}
+}
+
+// waitForSessions waits until e has n HTTP sessions running.
+func waitForSessions(t *testing.T, e *Endpoint, n int) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(5 * time.Millisecond) {
+		e.mu.Lock()
+		open := len(e.http)
+		e.mu.Unlock()
+		if open == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d HTTP sessions running, want %d", open, n)
+		}
+	}
+}
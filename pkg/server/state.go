@@ -0,0 +1,60 @@
+package server
+
+import "sqirvy/mcp/pkg/mcp"
+
+// sessionState tracks the MCP lifecycle of a session (see Server).
+//
+//	awaitingInitialize --initialize response sent--> awaitingInitialized
+//	awaitingInitialized --notifications/initialized--> ready
+//	any state --drain (see drain.go)--> draining
+//	any state --write failure (see outbox.go)--> broken
+type sessionState int
+
+const (
+	stateAwaitingInitialize  sessionState = iota // No initialize request handled yet
+	stateAwaitingInitialized                     // InitializeResult sent, waiting for notifications/initialized
+	stateReady                                   // Normal operation
+	stateDraining                                // Shutting down: in-flight requests finish, new ones are refused
+	stateBroken                                  // The transport failed; nothing more can reach the client
+)
+
+// String returns the state name used in log messages.
+func (st sessionState) String() string {
+	switch st {
+	case stateAwaitingInitialize:
+		return "awaiting-initialize"
+	case stateAwaitingInitialized:
+		return "awaiting-initialized"
+	case stateReady:
+		return "ready"
+	case stateDraining:
+		return "draining"
+	case stateBroken:
+		return "broken"
+	default:
+		return "unknown"
+	}
+}
+
+// admits reports whether a request for method may be dispatched in this state.
+// ping is allowed at any time until the session drains; initialize is always
+// dispatched so that a duplicate can be answered with a specific error.
+func (st sessionState) admits(method string) bool {
+	if st == stateDraining || st == stateBroken {
+		return false
+	}
+	switch method {
+	case mcp.MethodPing, mcp.MethodInitialize:
+		return true
+	}
+	return st == stateReady
+}
+
+// afterInitialized returns the state that follows an initialized notification.
+// The notification only has an effect while awaiting it.
+func (st sessionState) afterInitialized() sessionState {
+	if st == stateAwaitingInitialized {
+		return stateReady
+	}
+	return st
+}
@@ -0,0 +1,207 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/utils"
+)
+
+// captureTransport records everything the server writes.
+type captureTransport struct {
+	written chan []byte
+}
+
+func (c *captureTransport) ReadMessage() ([]byte, error) { return nil, io.EOF }
+
+func (c *captureTransport) WriteMessage(payload []byte) error {
+	c.written <- append([]byte(nil), payload...)
+	return nil
+}
+
+func (c *captureTransport) Close() error { return nil }
+
+func TestSessionStateAdmits(t *testing.T) {
+	tests := []struct {
+		state  sessionState
+		method string
+		want   bool
+	}{
+		{stateAwaitingInitialize, mcp.MethodInitialize, true},
+		{stateAwaitingInitialize, mcp.MethodPing, true},
+		{stateAwaitingInitialize, mcp.MethodListTools, false},
+		{stateAwaitingInitialized, mcp.MethodPing, true},
+		{stateAwaitingInitialized, mcp.MethodCallTool, false},
+		{stateAwaitingInitialized, mcp.MethodReadResource, false},
+		{stateAwaitingInitialized, mcp.MethodGetPrompt, false},
+		{stateReady, mcp.MethodListTools, true},
+		{stateReady, mcp.MethodInitialize, true},
+		{stateReady, "unknown/method", true},
+		{stateDraining, mcp.MethodPing, false},
+		{stateDraining, mcp.MethodListTools, false},
+	}
+	for _, tt := range tests {
+		if got := tt.state.admits(tt.method); got != tt.want {
+			t.Errorf("%s.admits(%q) = %v, want %v", tt.state, tt.method, got, tt.want)
+		}
+	}
+}
+
+func TestSessionStateAfterInitialized(t *testing.T) {
+	tests := []struct {
+		state sessionState
+		want  sessionState
+	}{
+		{stateAwaitingInitialize, stateAwaitingInitialize},
+		{stateAwaitingInitialized, stateReady},
+		{stateReady, stateReady},
+		{stateDraining, stateDraining},
+	}
+	for _, tt := range tests {
+		if got := tt.state.afterInitialized(); got != tt.want {
+			t.Errorf("%s.afterInitialized() = %s, want %s", tt.state, got, tt.want)
+		}
+	}
+}
+
+const (
+	initializeRequest   = `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test","version":"0"}}}`
+	initializedNotify   = `{"jsonrpc":"2.0","method":"notifications/initialized"}`
+	legacyInitialized   = `{"jsonrpc":"2.0","method":"initialized"}`
+	listToolsRequest    = `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`
+	pingRequest         = `{"jsonrpc":"2.0","id":3,"method":"ping"}`
+	duplicateInitialize = `{"jsonrpc":"2.0","id":4,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test","version":"0"}}}`
+	listToolsAgain      = `{"jsonrpc":"2.0","id":5,"method":"tools/list"}`
+	listToolsStringID   = `{"jsonrpc":"2.0","id":"2","method":"tools/list"}`
+	invalidInitialize   = `{"jsonrpc":"2.0","id":6,"method":"initialize","params":{"capabilities":{},"clientInfo":{"name":"test"}}}`
+)
+
+// step is one message fed to the server. reply is false for messages that
+// must not produce a response; wantCode is 0 for a successful result.
+type step struct {
+	payload   string
+	reply     bool
+	wantCode  int
+	wantState sessionState
+}
+
+func TestServerLifecycle(t *testing.T) {
+	tests := []struct {
+		name       string
+		legacyInit bool
+		steps      []step
+	}{
+		{
+			name: "request before initialize",
+			steps: []step{
+				{listToolsRequest, true, mcp.ErrorCodeServerNotReady, stateAwaitingInitialize},
+			},
+		},
+		{
+			name: "ping before initialize",
+			steps: []step{
+				{pingRequest, true, 0, stateAwaitingInitialize},
+			},
+		},
+		{
+			name: "initialized before initialize is ignored",
+			steps: []step{
+				{initializedNotify, false, 0, stateAwaitingInitialize},
+				{listToolsRequest, true, mcp.ErrorCodeServerNotReady, stateAwaitingInitialize},
+			},
+		},
+		{
+			name: "full handshake",
+			steps: []step{
+				{initializeRequest, true, 0, stateAwaitingInitialized},
+				{listToolsRequest, true, mcp.ErrorCodeServerNotReady, stateAwaitingInitialized},
+				{pingRequest, true, 0, stateAwaitingInitialized},
+				{initializedNotify, false, 0, stateReady},
+				{listToolsAgain, true, 0, stateReady},
+				{initializedNotify, false, 0, stateReady},
+				{duplicateInitialize, true, mcp.ErrorCodeInvalidRequest, stateReady},
+			},
+		},
+		{
+			name: "request id reused once answered",
+			steps: []step{
+				{initializeRequest, true, 0, stateAwaitingInitialized},
+				{initializedNotify, false, 0, stateReady},
+				{listToolsRequest, true, 0, stateReady},
+				{listToolsRequest, true, 0, stateReady},
+				{listToolsStringID, true, 0, stateReady},
+				{initializeRequest, true, mcp.ErrorCodeInvalidRequest, stateReady},
+			},
+		},
+		{
+			name: "initialize with invalid params can be retried",
+			steps: []step{
+				{invalidInitialize, true, mcp.ErrorCodeInvalidParams, stateAwaitingInitialize},
+				{initializeRequest, true, 0, stateAwaitingInitialized},
+			},
+		},
+		{
+			name: "legacy name rejected by default",
+			steps: []step{
+				{initializeRequest, true, 0, stateAwaitingInitialized},
+				{legacyInitialized, false, 0, stateAwaitingInitialized},
+				{listToolsRequest, true, mcp.ErrorCodeServerNotReady, stateAwaitingInitialized},
+			},
+		},
+		{
+			name:       "legacy name accepted with flag",
+			legacyInit: true,
+			steps: []step{
+				{initializeRequest, true, 0, stateAwaitingInitialized},
+				{legacyInitialized, false, 0, stateReady},
+				{listToolsRequest, true, 0, stateReady},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &captureTransport{written: make(chan []byte, 16)}
+			s := NewServer(tr, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+			s.legacyInit = tt.legacyInit
+
+			for i, st := range tt.steps {
+				s.processMessage([]byte(st.payload))
+				if s.state != st.wantState {
+					t.Fatalf("step %d: state = %s, want %s", i, s.state, st.wantState)
+				}
+				if !st.reply {
+					continue
+				}
+				select {
+				case out := <-tr.written:
+					var resp struct {
+						Result json.RawMessage `json:"result"`
+						Error  *mcp.RPCError   `json:"error"`
+					}
+					if err := json.Unmarshal(out, &resp); err != nil {
+						t.Fatalf("step %d: bad response %s: %v", i, out, err)
+					}
+					gotCode := 0
+					if resp.Error != nil {
+						gotCode = resp.Error.Code
+					}
+					if gotCode != st.wantCode {
+						t.Fatalf("step %d: response code = %d, want %d (%s)", i, gotCode, st.wantCode, out)
+					}
+				case <-time.After(time.Second):
+					t.Fatalf("step %d: no response", i)
+				}
+			}
+			select {
+			case out := <-tr.written:
+				t.Fatalf("unexpected extra message: %s", out)
+			case <-time.After(20 * time.Millisecond):
+			}
+		})
+	}
+}
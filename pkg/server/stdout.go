@@ -0,0 +1,59 @@
+package server
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"sync/atomic"
+
+	"sqirvy/mcp/pkg/utils"
+)
+
+// stdoutGuard keeps stray output away from the stdio protocol stream.
+//
+// With the stdio transport, stdout carries nothing but JSON-RPC messages; a single
+// fmt.Println, from this server or a library it uses, corrupts the stream and the
+// host drops the connection. The guard hands the real stdout to the transport and
+// points os.Stdout at a pipe whose contents are logged as warnings instead.
+// Output written directly to file descriptor 1 (e.g. by C code) is not intercepted.
+type stdoutGuard struct {
+	protocol *os.File      // The real stdout, for the transport only
+	pipe     *os.File      // Write end installed as os.Stdout
+	done     chan struct{} // Closed when all stray output has been logged
+	strays   atomic.Int64  // Lines of stray output intercepted
+}
+
+// guardStdout installs the guard and returns it. The transport must write to g.protocol.
+func guardStdout(logger *utils.Logger) (*stdoutGuard, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	g := &stdoutGuard{protocol: os.Stdout, pipe: w, done: make(chan struct{})}
+	os.Stdout = w
+
+	go func() {
+		defer close(g.done)
+		defer r.Close()
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 4096), 1<<20)
+		for scanner.Scan() {
+			g.strays.Add(1)
+			logger.Printf("INFO", "WARNING: intercepted stray write to stdout (not sent to the client): %q", scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			logger.Printf("INFO", "WARNING: stray stdout output no longer logged: %v", err)
+			io.Copy(io.Discard, r) // Keep draining so writers never block
+		}
+	}()
+	return g, nil
+}
+
+// release restores os.Stdout and waits until the intercepted output has been logged.
+// It returns the number of stray lines seen.
+func (g *stdoutGuard) release() int64 {
+	os.Stdout = g.protocol
+	g.pipe.Close()
+	<-g.done
+	return g.strays.Load()
+}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"sqirvy/mcp/pkg/utils"
+)
+
+func TestStdoutGuardInterceptsStrayOutput(t *testing.T) {
+	real := os.Stdout
+	var logBuf bytes.Buffer
+	g, err := guardStdout(utils.New(&logBuf, "", log.LstdFlags, utils.LevelInfo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.protocol != real || os.Stdout == real {
+		t.Fatal("os.Stdout was not redirected")
+	}
+
+	fmt.Println("debug output from a library")
+	fmt.Fprint(os.Stdout, "partial line")
+
+	if n := g.release(); n != 2 {
+		t.Errorf("release() = %d stray lines, want 2", n)
+	}
+	if os.Stdout != real {
+		t.Error("os.Stdout was not restored")
+	}
+	logged := logBuf.String()
+	for _, want := range []string{"debug output from a library", "partial line"} {
+		if !strings.Contains(logged, want) {
+			t.Errorf("log does not mention %q:\n%s", want, logged)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"sqirvy/mcp/pkg/mcp"
+	resources "sqirvy/mcp/pkg/resources"
+)
+
+// marshalStreamedBlobResponse builds a resources/read response whose single
+// BlobResourceContents is base64-encoded straight from r into the output
+// buffer. Unlike marshalling a ReadResourceResult, the raw content is never
+// held in memory next to its encoding, so a read costs roughly the encoded
+// size. At most resources.MaxFileSize bytes are accepted from r.
+func marshalStreamedBlobResponse(id mcp.RequestID, uri, mimeType string, r io.Reader, size int64) ([]byte, error) {
+	idBytes, err := json.Marshal(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal response id: %w", err)
+	}
+	uriBytes, err := json.Marshal(uri)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal resource uri: %w", err)
+	}
+	mimeBytes, err := json.Marshal(mimeType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal resource mime type: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if size > 0 && size <= resources.MaxFileSize {
+		buf.Grow(base64.StdEncoding.EncodedLen(int(size)) + len(uriBytes) + len(mimeBytes) + len(idBytes) + 96)
+	}
+	buf.WriteString(`{"jsonrpc":"` + mcp.JSONRPCVersion + `","result":{"contents":[{"uri":`)
+	buf.Write(uriBytes)
+	buf.WriteString(`,"mimeType":`)
+	buf.Write(mimeBytes)
+	buf.WriteString(`,"blob":"`)
+
+	enc := base64.NewEncoder(base64.StdEncoding, &buf)
+	n, err := io.Copy(enc, io.LimitReader(r, resources.MaxFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("error reading resource %s: %w", uri, err)
+	}
+	if n > resources.MaxFileSize {
+		return nil, fmt.Errorf("%w: %s exceeds the maximum of %d bytes", resources.ErrFileTooLarge, uri, resources.MaxFileSize)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode resource %s: %w", uri, err)
+	}
+
+	buf.WriteString(`"}]},"id":`)
+	buf.Write(idBytes)
+	buf.WriteString(`}`)
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"sqirvy/mcp/pkg/mcp"
+	resources "sqirvy/mcp/pkg/resources"
+)
+
+func TestMarshalStreamedBlobResponse(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789\x00\xff"), 10000)
+	out, err := marshalStreamedBlobResponse("r-1", `file:///big "file".bin`, "text/plain", bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, id, rpcErr, err := mcp.UnmarshalReadResourcesResponse(out)
+	if err != nil || rpcErr != nil {
+		t.Fatalf("unmarshal failed: err=%v rpcErr=%v\n%s", err, rpcErr, out[:200])
+	}
+	if id != "r-1" || len(result.Contents) != 1 {
+		t.Fatalf("id = %v, contents = %d", id, len(result.Contents))
+	}
+	var blob mcp.BlobResourceContents
+	if err := json.Unmarshal(result.Contents[0], &blob); err != nil {
+		t.Fatal(err)
+	}
+	if blob.URI != `file:///big "file".bin` || blob.MimeType != "text/plain" {
+		t.Errorf("blob metadata = %+v", blob)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(blob.Blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, content) {
+		t.Error("decoded blob differs from the input")
+	}
+}
+
+func TestMarshalStreamedBlobResponseTooLarge(t *testing.T) {
+	r := io.LimitReader(zeroReader{}, resources.MaxFileSize+1)
+	_, err := marshalStreamedBlobResponse(1, "file:///huge", "text/plain", r, 0)
+	if !errors.Is(err, resources.ErrFileTooLarge) {
+		t.Fatalf("error = %v, want ErrFileTooLarge", err)
+	}
+}
+
+// zeroReader is an endless source of zero bytes.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
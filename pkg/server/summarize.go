@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/tools"
+)
+
+const (
+	summarizeToolName = "summarize"
+	// The client may ask the user to approve a sampling request, so allow for a person in the loop
+	summarizeTimeout          = 2 * time.Minute
+	summarizeDefaultMaxTokens = 400
+	summarizeMaxMaxTokens     = 4000
+	summarizeMaxInput         = 100000 // Characters of the resource sent to the model
+)
+
+// summarizeArgs are the arguments of the summarize tool.
+type summarizeArgs struct {
+	URI       string `json:"uri"`
+	Focus     string `json:"focus"`
+	MaxTokens int    `json:"max_tokens"`
+}
+
+// summarizeTool describes the summarize tool for tools/list.
+func summarizeTool() mcp.Tool {
+	return mcp.Tool{
+		Name:  summarizeToolName,
+		Title: "Summarize Resource",
+		Description: "Summarizes a text resource (e.g. file:///docs/design.md). The server does not call a model itself: " +
+			"it asks the client to sample one (sampling/createMessage), so the client must support sampling.",
+		InputSchema: mcp.ToolInputSchema{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"uri":        map[string]interface{}{"type": "string", "description": "URI of the resource to summarize"},
+				"focus":      map[string]interface{}{"type": "string", "description": "Optional aspect to concentrate on, e.g. \"open questions\""},
+				"max_tokens": map[string]interface{}{"type": "integer", "default": summarizeDefaultMaxTokens, "description": fmt.Sprintf("Length limit of the summary in tokens (default %d, at most %d)", summarizeDefaultMaxTokens, summarizeMaxMaxTokens)},
+			},
+			"required": []string{"uri"},
+		},
+	}
+}
+
+// handleSummarizeTool handles the "tools/call" request for the "summarize" tool:
+// it reads the resource, embeds it in a sampling/createMessage request to the
+// client and returns the client's completion.
+func (s *Server) handleSummarizeTool(ctx context.Context, id mcp.RequestID, params mcp.CallToolParams) ([]byte, error) {
+	s.logger.Printf("DEBUG", "Handle  : tools/call request for '%s' (ID: %v)", params.Name, id)
+
+	var args summarizeArgs
+	argBytes, _ := json.Marshal(params.Arguments) // Arguments came from JSON
+	if err := json.Unmarshal(argBytes, &args); err != nil {
+		rpcErr := mcp.NewRPCError(mcp.ErrorCodeInvalidParams, fmt.Sprintf("invalid arguments for %s: %v", summarizeToolName, err), nil)
+		return s.marshalErrorResponse(id, rpcErr)
+	}
+	if args.URI == "" {
+		rpcErr := mcp.NewRPCError(mcp.ErrorCodeInvalidParams, fmt.Sprintf("%s requires a uri argument", summarizeToolName), nil)
+		return s.marshalErrorResponse(id, rpcErr)
+	}
+
+	summary, err := s.summarize(ctx, args)
+	if err != nil {
+		s.logger.Printf("DEBUG", "summarize of %s failed: %v", args.URI, err)
+		return s.marshalResponse(id, mcp.NewToolResultError(fmt.Errorf("summarize: %w", err)))
+	}
+	return s.marshalResponse(id, mcp.NewToolResultText(summary))
+}
+
+// summarize asks the client's model for a summary of the resource named by args.
+func (s *Server) summarize(ctx context.Context, args summarizeArgs) (string, error) {
+	text, err := s.readTextResource(ctx, args.URI)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(text) == "" {
+		return "", fmt.Errorf("%s is empty", args.URI)
+	}
+	text, truncated := tools.TruncateText(text, summarizeMaxInput)
+	maxTokens := args.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = summarizeDefaultMaxTokens
+	}
+	maxTokens = min(maxTokens, summarizeMaxMaxTokens)
+
+	prompt := "Summarize the resource below."
+	if args.Focus != "" {
+		prompt += " Concentrate on: " + args.Focus + "."
+	}
+	if truncated {
+		prompt += fmt.Sprintf(" Only its first %d characters are included.", summarizeMaxInput)
+	}
+	prompt += fmt.Sprintf("\n\n<resource uri=%q>\n%s\n</resource>", args.URI, text)
+
+	speed, cost := 0.8, 0.5
+	result, err := s.createMessage(ctx, mcp.CreateMessageParams{
+		Messages:         []mcp.SamplingMessage{mcp.NewTextSamplingMessage(mcp.RoleUser, prompt)},
+		SystemPrompt:     "You write concise, accurate summaries. Reply with the summary only.",
+		IncludeContext:   mcp.IncludeContextNone,
+		MaxTokens:        maxTokens,
+		ModelPreferences: &mcp.ModelPreferences{SpeedPriority: &speed, CostPriority: &cost},
+		Metadata:         map[string]interface{}{"tool": summarizeToolName, "uri": args.URI},
+	}, summarizeTimeout)
+	if err != nil {
+		return "", fmt.Errorf("sampling failed: %w", err)
+	}
+	var content mcp.TextContent
+	if err := json.Unmarshal(result.Content, &content); err != nil || content.Type != "text" {
+		return "", fmt.Errorf("the client's model did not reply with text")
+	}
+	s.logger.Printf("DEBUG", "Summary of %s by %s (stop reason %q)", args.URI, result.Model, result.StopReason)
+	return content.Text, nil
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"strings"
+	"testing"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/utils"
+)
+
+func TestSummarizeSamplesThroughTheClient(t *testing.T) {
+	out := &captureTransport{written: make(chan []byte, 10)}
+	s := NewServer(out, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	s.modules = []*toolModule{{
+		name: "test",
+		resources: []moduleResource{{
+			resource: mcp.Resource{URI: "test://notes", MimeType: "text/plain"},
+			read:     func(context.Context) (string, error) { return "The launch moved to May.\nBudget is unchanged.", nil },
+		}},
+	}}
+	s.clientCapabilities.Sampling = map[string]interface{}{}
+
+	// The client: answer the sampling request with a canned completion
+	requests := make(chan mcp.CreateMessageParams, 1)
+	go func() {
+		var req struct {
+			ID     string                  `json:"id"`
+			Method string                  `json:"method"`
+			Params mcp.CreateMessageParams `json:"params"`
+		}
+		json.Unmarshal(<-out.written, &req)
+		if req.Method != mcp.MethodCreateMessage {
+			t.Errorf("server sent %s, want %s", req.Method, mcp.MethodCreateMessage)
+		}
+		requests <- req.Params
+		content, _ := json.Marshal(mcp.TextContent{Type: "text", Text: "Launch slips to May; budget holds."})
+		result, _ := json.Marshal(mcp.CreateMessageResult{Role: mcp.RoleAssistant, Content: content, Model: "test-model", StopReason: "endTurn"})
+		reply, _ := json.Marshal(mcp.RPCResponse{JSONRPC: mcp.JSONRPCVersion, ID: req.ID, Result: result})
+		s.handleClientReply(req.ID, reply)
+	}()
+
+	result := callTool(t, s, summarizeToolName, map[string]interface{}{"uri": "test://notes", "focus": "dates", "max_tokens": 100000})
+	if text := mustText(result); result.IsError || text != "Launch slips to May; budget holds." {
+		t.Errorf("summarize = %q", text)
+	}
+	params := <-requests
+	var prompt mcp.TextContent
+	json.Unmarshal(params.Messages[0].Content, &prompt)
+	if params.Messages[0].Role != mcp.RoleUser || !strings.Contains(prompt.Text, `<resource uri="test://notes">`) ||
+		!strings.Contains(prompt.Text, "The launch moved to May.") || !strings.Contains(prompt.Text, "dates") {
+		t.Errorf("sampling prompt = %q", prompt.Text)
+	}
+	if params.MaxTokens != summarizeMaxMaxTokens || params.IncludeContext != mcp.IncludeContextNone {
+		t.Errorf("sampling params = %+v", params)
+	}
+}
+
+func TestSummarizeReportsToolErrors(t *testing.T) {
+	s := NewServer(&captureTransport{}, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	s.modules = []*toolModule{{
+		name: "test",
+		resources: []moduleResource{{
+			resource: mcp.Resource{URI: "test://notes", MimeType: "text/plain"},
+			read:     func(context.Context) (string, error) { return "text", nil },
+		}},
+	}}
+	// No sampling capability
+	result := callTool(t, s, summarizeToolName, map[string]interface{}{"uri": "test://notes"})
+	if !result.IsError || !strings.Contains(mustText(result), "does not support sampling") {
+		t.Errorf("summarize without sampling = %q, want a tool error", mustText(result))
+	}
+	s.clientCapabilities.Sampling = map[string]interface{}{}
+	if result := callTool(t, s, summarizeToolName, map[string]interface{}{"uri": "file:///../secret.txt"}); !result.IsError {
+		t.Errorf("summarize outside the project root = %q, want a tool error", mustText(result))
+	}
+}
@@ -0,0 +1,187 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"sqirvy/mcp/pkg/generators"
+	"sqirvy/mcp/pkg/mcp"
+)
+
+// Define the random_data template
+var RandomDataTemplate mcp.ResourceTemplate = mcp.ResourceTemplate{
+	Name:        "random_data",
+	Title:       "Random Data",
+	URITemplate: "data://random_data?length={length}", // RFC 6570 template
+	Description: "Returns a string of random ASCII characters. Use URI like 'data://random_data?length=N' in resources/read, where N is the desired length.",
+	MimeType:    "text/plain",
+}
+
+// randomDataLengths are the lengths suggested when completing random_data's length.
+var randomDataLengths = []string{"16", "32", "64", "128", "256", "512", "1024"}
+
+// randomDataHandler serves RandomDataTemplate.
+var randomDataHandler = ResourceTemplateHandler{
+	Read: readRandomData,
+	Complete: map[string]func(string, map[string]string) []string{
+		"length": func(value string, _ map[string]string) []string {
+			return completePrefix(randomDataLengths, value)
+		},
+	},
+}
+
+// ResourceTemplateHandler serves the resources of a template registered with
+// RegisterResourceTemplate.
+type ResourceTemplateHandler struct {
+	// Read returns the contents of uri, given the values of the template's
+	// variables in it. An *mcp.RPCError is sent to the client as is, any
+	// other error as an internal error.
+	Read func(ctx context.Context, uri string, vars map[string]string) (mcp.ReadResourceResult, error)
+	// Complete suggests values for a variable, by name, given what has been
+	// typed so far and the values of the variables already filled in.
+	// Variables without a completer complete to nothing.
+	Complete map[string]func(value string, vars map[string]string) []string
+}
+
+// resourceTemplate is a template registered with RegisterResourceTemplate.
+type resourceTemplate struct {
+	template mcp.ResourceTemplate
+	uri      *mcp.URITemplate
+	handler  ResourceTemplateHandler
+}
+
+// RegisterResourceTemplate adds a resource template. resources/templates/list
+// lists it, resources/read routes the URIs matching it to handler.Read, and
+// completion/complete completes its variables with handler.Complete, so the
+// three always agree. A template registered again with the same URI template
+// replaces the first. It panics if the URI template cannot be parsed or a
+// completer names a variable the template does not have, and must be called
+// before Run.
+func (s *Server) RegisterResourceTemplate(tmpl mcp.ResourceTemplate, handler ResourceTemplateHandler) {
+	uri, err := mcp.ParseURITemplate(tmpl.URITemplate)
+	if err != nil {
+		panic(fmt.Sprintf("RegisterResourceTemplate: %v", err))
+	}
+	for name := range handler.Complete {
+		if !slices.Contains(uri.Variables(), name) {
+			panic(fmt.Sprintf("RegisterResourceTemplate: %s has no variable %q to complete", tmpl.URITemplate, name))
+		}
+	}
+	t := &resourceTemplate{template: tmpl, uri: uri, handler: handler}
+	for i, registered := range s.templates {
+		if registered.template.URITemplate == tmpl.URITemplate {
+			s.templates[i] = t
+			return
+		}
+	}
+	s.templates = append(s.templates, t)
+}
+
+// matchTemplate returns the first registered template that uri matches, and
+// the values of its variables.
+func (s *Server) matchTemplate(uri string) (*resourceTemplate, map[string]string, bool) {
+	for _, t := range s.templates {
+		if vars, ok := t.uri.Match(uri); ok {
+			return t, vars, true
+		}
+	}
+	return nil, nil, false
+}
+
+// handleTemplateResource reads uri, which matched t, and marshals the response.
+func (s *Server) handleTemplateResource(ctx context.Context, id mcp.RequestID, uri string, t *resourceTemplate, vars map[string]string) ([]byte, error) {
+	s.logger.Printf("DEBUG", "Processing %s resource for URI: %s", t.template.Name, uri)
+	result, err := t.handler.Read(ctx, uri, vars)
+	if err != nil {
+		s.logger.Printf("DEBUG", "Error reading resource URI '%s': %v", uri, err)
+		var rpcErr *mcp.RPCError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = mcp.NewRPCError(mcp.ErrorCodeInternalError, err.Error(), map[string]string{"uri": uri})
+		}
+		return s.marshalErrorResponse(id, rpcErr)
+	}
+	return s.marshalResponse(id, result)
+}
+
+// handleComplete handles the "completion/complete" request. Resource template
+// variables are completed by their template's handler; prompts have no
+// completers, so their arguments complete to nothing.
+func (s *Server) handleComplete(id mcp.RequestID, payload []byte) ([]byte, error) {
+	s.logger.Printf("DEBUG", "Handle  : completion/complete request (ID: %v)", id)
+
+	var params mcp.CompleteParams
+	if err := mcp.UnmarshalParams(payload, &params); err != nil {
+		err = fmt.Errorf("failed to unmarshal complete params: %w", err)
+		s.logger.Println("DEBUG", err.Error())
+		rpcErr := mcp.NewRPCError(mcp.ErrorCodeInvalidParams, err.Error(), nil)
+		return s.marshalErrorResponse(id, rpcErr)
+	}
+
+	var values []string
+	switch params.Ref.Type {
+	case mcp.RefPrompt:
+	case mcp.RefResource:
+		var t *resourceTemplate
+		for _, registered := range s.templates {
+			if registered.template.URITemplate == params.Ref.URI && s.profile.allowsResource(params.Ref.URI) {
+				t = registered
+			}
+		}
+		if t == nil {
+			rpcErr := mcp.NewRPCError(mcp.ErrorCodeInvalidParams, fmt.Sprintf("Resource template '%s' not found", params.Ref.URI), nil)
+			return s.marshalErrorResponse(id, rpcErr)
+		}
+		if complete := t.handler.Complete[params.Argument.Name]; complete != nil {
+			var vars map[string]string
+			if params.Context != nil {
+				vars = params.Context.Arguments
+			}
+			values = complete(params.Argument.Value, vars)
+		}
+	default:
+		rpcErr := mcp.NewRPCError(mcp.ErrorCodeInvalidParams, fmt.Sprintf("Unknown reference type '%s'", params.Ref.Type), nil)
+		return s.marshalErrorResponse(id, rpcErr)
+	}
+	return s.marshalResponse(id, mcp.CompleteResult{Completion: mcp.NewCompletion(values)})
+}
+
+// completePrefix returns the candidates that start with value, in order.
+func completePrefix(candidates []string, value string) []string {
+	var values []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, value) {
+			values = append(values, c)
+		}
+	}
+	return values
+}
+
+// readRandomData reads a data://random_data resource: length random characters.
+func readRandomData(ctx context.Context, uri string, vars map[string]string) (mcp.ReadResourceResult, error) {
+	var result mcp.ReadResourceResult
+	lengthStr := vars["length"]
+	if lengthStr == "" {
+		return result, mcp.NewRPCError(mcp.ErrorCodeInvalidParams, fmt.Sprintf("missing 'length' query parameter in URI: %s", uri), nil)
+	}
+	length, err := strconv.Atoi(lengthStr)
+	if err != nil {
+		return result, mcp.NewRPCError(mcp.ErrorCodeInvalidParams, fmt.Sprintf("invalid 'length' query parameter '%s': %v", lengthStr, err), nil)
+	}
+
+	// Generate random data, see pkg/generators
+	randomString, err := generators.RandomData(length)
+	if err != nil {
+		err = fmt.Errorf("failed to generate random data for URI %s: %w", uri, err)
+		// A bad length is the client's mistake
+		if errors.Is(err, generators.ErrLengthNotPositive) || errors.Is(err, generators.ErrLengthTooLong) {
+			return result, mcp.NewRPCError(mcp.ErrorCodeInvalidParams, err.Error(), nil)
+		}
+		return result, err
+	}
+	result.Add(mcp.NewTextResource(uri, "text/plain", randomString))
+	return result, nil
+}
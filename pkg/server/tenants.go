@@ -0,0 +1,100 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"sqirvy/mcp/pkg/resources"
+)
+
+// tenancy is how the -tenants flag tells the clients of network transports
+// apart. A session's tenant sees file:// resources under its own directory,
+// tenants/<tenant> of the project root, and keeps its own memory notes,
+// idempotency keys and cached tool results. stdio sessions are never scoped.
+type tenancy string
+
+const (
+	tenancyNone tenancy = ""
+	// tenancyPrincipal scopes by the principal of the -mcp-http bearer token
+	// (see profiles.go), which the client cannot choose. Sessions without one
+	// share the tenant "anonymous".
+	tenancyPrincipal tenancy = "principal"
+	// tenancyClient scopes by the client name sent in initialize. Names are
+	// not authenticated, so this only keeps cooperating clients apart.
+	tenancyClient tenancy = "client"
+)
+
+// anonymousTenant is the tenant of sessions that name none.
+const anonymousTenant = "anonymous"
+
+// parseTenancy parses a -tenants value.
+func parseTenancy(s string) (tenancy, error) {
+	switch mode := tenancy(s); mode {
+	case tenancyNone, tenancyPrincipal, tenancyClient:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown tenancy %q, want principal or client", s)
+}
+
+// check returns an error if the mode is combined with a feature that sees
+// every tenant's data and so cannot be scoped: the semantic_search index of
+// the project root, the request journal tool and resource, or the HTTP
+// gateway, which serves all its callers from one session.
+func (mode tenancy) check(index, journalAdmin, gateway bool) error {
+	if mode == tenancyNone {
+		return nil
+	}
+	switch {
+	case index:
+		return errors.New("the -index semantic_search tool cannot be scoped per tenant")
+	case journalAdmin:
+		return errors.New("the -journal-admin tool and resource cannot be scoped per tenant")
+	case gateway:
+		return errors.New("the -http gateway cannot be scoped per tenant")
+	}
+	return nil
+}
+
+// setTenancy scopes the session by mode. principal is the session's bearer
+// token principal, "" for none; client tenancy is settled by initialize.
+func (s *Server) setTenancy(mode tenancy, principal string) {
+	s.tenancy = mode
+	if mode == tenancyPrincipal {
+		s.setTenant(principal)
+	}
+}
+
+// setTenant makes name, or anonymousTenant if it is empty, the session's
+// tenant. Characters that are unsafe in a directory name are replaced.
+func (s *Server) setTenant(name string) {
+	name = strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r == '.' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' {
+			return r
+		}
+		return '_'
+	}, strings.TrimSpace(name))
+	if strings.Trim(name, ".") == "" {
+		name = anonymousTenant
+	}
+	s.tenant = name
+	s.logger.Printf("DEBUG", "Session belongs to tenant '%s'", name)
+}
+
+// fileRoot returns the directory the session's file:// URIs resolve in.
+func (s *Server) fileRoot() string {
+	if s.tenant == "" {
+		return resources.ProjectRoot()
+	}
+	return filepath.Join(resources.ProjectRoot(), "tenants", s.tenant)
+}
+
+// openFileResource opens a file:// resource within the session's file root,
+// if the session's profile allows it (see checkResourceProfile).
+func (s *Server) openFileResource(uri string) (*resources.FileResource, error) {
+	if err := s.checkResourceProfile(uri); err != nil {
+		return nil, err
+	}
+	return resources.OpenFileResourceIn(s.fileRoot(), uri, s.logger)
+}
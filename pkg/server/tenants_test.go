@@ -0,0 +1,93 @@
+package server
+
+import (
+	"io"
+	"log"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/resources"
+	"sqirvy/mcp/pkg/utils"
+)
+
+func TestTenancy(t *testing.T) {
+	newSession := func() *Server {
+		return NewServer(&captureTransport{}, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	}
+
+	if _, err := parseTenancy("everyone"); err == nil {
+		t.Error("parseTenancy accepted an unknown mode")
+	}
+
+	// Unscoped sessions see the project root
+	plain := newSession()
+	if plain.fileRoot() != resources.ProjectRoot() || plain.memoryNamespace() != anonymousTenant {
+		t.Errorf("unscoped session: root %s, notes %s", plain.fileRoot(), plain.memoryNamespace())
+	}
+
+	// Principal tenancy is settled when the session starts
+	bot := newSession()
+	bot.setTenancy(tenancyPrincipal, "ci-bot")
+	if want := filepath.Join(resources.ProjectRoot(), "tenants", "ci-bot"); bot.fileRoot() != want {
+		t.Errorf("root = %s, want %s", bot.fileRoot(), want)
+	}
+	if bot.memoryNamespace() != "ci-bot" {
+		t.Errorf("notes = %s", bot.memoryNamespace())
+	}
+	anonymous := newSession()
+	anonymous.setTenancy(tenancyPrincipal, "")
+	if anonymous.tenant != anonymousTenant {
+		t.Errorf("tenant without principal = %q", anonymous.tenant)
+	}
+
+	// Client tenancy by initialize, with the name made safe for a directory
+	client := newSession()
+	client.setTenancy(tenancyClient, "ignored")
+	if client.tenant != "" {
+		t.Fatalf("tenant before initialize = %q", client.tenant)
+	}
+	if _, err := client.handleInitializeRequest(1, []byte(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"../../etc","version":"1"}}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if client.tenant != ".._.._etc" {
+		t.Errorf("tenant = %q", client.tenant)
+	}
+
+	// A tenant cannot reach outside its root
+	if _, err := bot.openFileResource("file:///../other/secret.txt"); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("escape from tenant root: %v", err)
+	}
+
+	// Nor share another tenant's cached results
+	cache := newResultCache(map[string]time.Duration{"diff": time.Minute}, 10)
+	call := mcp.CallToolParams{Name: "diff", Arguments: map[string]interface{}{"a": "x"}}
+	k1, _ := cache.key(bot.tenant, call)
+	k2, _ := cache.key(anonymous.tenant, call)
+	if k1 == k2 {
+		t.Error("tenants share a result cache key")
+	}
+}
+
+func TestTenancyRejectsUnscopedFeatures(t *testing.T) {
+	for _, tt := range []struct {
+		name                         string
+		index, journalAdmin, gateway bool
+	}{
+		{"index", true, false, false},
+		{"journal admin", false, true, false},
+		{"gateway", false, false, true},
+	} {
+		if err := tenancyPrincipal.check(tt.index, tt.journalAdmin, tt.gateway); err == nil {
+			t.Errorf("%s: tenancy accepted a feature that sees every tenant", tt.name)
+		}
+		if err := tenancyNone.check(tt.index, tt.journalAdmin, tt.gateway); err != nil {
+			t.Errorf("%s without tenancy: %v", tt.name, err)
+		}
+	}
+	if err := tenancyClient.check(false, false, false); err != nil {
+		t.Errorf("tenancy alone: %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"encoding/json"
+	"time"
+
+	"sqirvy/mcp/pkg/clock"
+	"sqirvy/mcp/pkg/mcp"
+)
+
+// timedMethods lists the methods whose results carry _meta.timing.
+var timedMethods = map[string]bool{
+	mcp.MethodCallTool:     true,
+	mcp.MethodReadResource: true,
+}
+
+// withTiming adds _meta.timing to a marshalled success response. Error responses
+// and payloads that cannot be decoded are returned unchanged.
+func withTiming(responseBytes []byte, timing mcp.Timing) []byte {
+	var resp mcp.RPCResponse
+	if err := json.Unmarshal(responseBytes, &resp); err != nil || resp.Error != nil || resp.Result == nil {
+		return responseBytes
+	}
+	var result map[string]json.RawMessage
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		return responseBytes
+	}
+	meta := map[string]interface{}{}
+	if raw, ok := result["_meta"]; ok {
+		if err := json.Unmarshal(raw, &meta); err != nil {
+			return responseBytes
+		}
+	}
+	meta[mcp.MetaKeyTiming] = timing
+
+	metaBytes, err := json.Marshal(meta)
+	if err != nil {
+		return responseBytes
+	}
+	result["_meta"] = metaBytes
+	if resp.Result, err = json.Marshal(result); err != nil {
+		return responseBytes
+	}
+	out, err := json.Marshal(resp)
+	if err != nil {
+		return responseBytes
+	}
+	return out
+}
+
+// timeHandler runs handle and, for timed methods, attaches the queue time since
+// received and the execution time, as measured by c, to its response.
+func timeHandler(c clock.Clock, method string, received time.Time, handle func() []byte) []byte {
+	started := c.Now()
+	responseBytes := handle()
+	if !timedMethods[method] || responseBytes == nil {
+		return responseBytes
+	}
+	return withTiming(responseBytes, mcp.NewTiming(started.Sub(received), c.Since(started)))
+}
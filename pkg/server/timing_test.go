@@ -0,0 +1,60 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"sqirvy/mcp/pkg/clock"
+	"sqirvy/mcp/pkg/mcp"
+)
+
+func TestWithTiming(t *testing.T) {
+	timing := mcp.NewTiming(time.Millisecond, 3*time.Millisecond)
+
+	in := []byte(`{"jsonrpc":"2.0","result":{"_meta":{"trace":"x"},"content":[]},"id":1}`)
+	result, id, rpcErr, err := mcp.UnmarshalCallToolResponse(withTiming(in, timing))
+	if err != nil || rpcErr != nil {
+		t.Fatalf("unmarshal failed: err=%v rpcErr=%v", err, rpcErr)
+	}
+	if id != float64(1) {
+		t.Errorf("id = %v, want 1", id)
+	}
+	if got := result.Timing(); got == nil || *got != timing {
+		t.Errorf("Timing() = %+v, want %+v", got, timing)
+	}
+	if result.Meta["trace"] != "x" {
+		t.Errorf("existing _meta entries lost: %v", result.Meta)
+	}
+
+	// Error responses are left alone
+	errResp, _ := mcp.MarshalErrorResponse(2, mcp.NewRPCError(mcp.ErrorCodeInvalidParams, "bad", nil))
+	if out := withTiming(errResp, timing); string(out) != string(errResp) {
+		t.Errorf("error response modified: %s", out)
+	}
+}
+
+func TestTimeHandlerOnlyTimedMethods(t *testing.T) {
+	resp := []byte(`{"jsonrpc":"2.0","result":{"tools":[]},"id":1}`)
+	if out := timeHandler(clock.Real, mcp.MethodListTools, time.Now(), func() []byte { return resp }); string(out) != string(resp) {
+		t.Errorf("tools/list response modified: %s", out)
+	}
+
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+	received := fake.Now()
+	fake.Advance(5 * time.Millisecond)
+	out := timeHandler(fake, mcp.MethodReadResource, received, func() []byte {
+		fake.Advance(2 * time.Millisecond)
+		return []byte(`{"jsonrpc":"2.0","result":{"contents":[]},"id":1}`)
+	})
+	var parsed struct {
+		Result mcp.ReadResourceResult `json:"result"`
+	}
+	if err := json.Unmarshal(out, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	timing := parsed.Result.Timing()
+	if timing == nil || timing.Queue() != 5*time.Millisecond || timing.Execution() != 2*time.Millisecond {
+		t.Errorf("Timing() = %+v, want queue 5ms and execution 2ms", timing)
+	}
+}
@@ -0,0 +1,301 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sqirvy/mcp/pkg/mcp"
+)
+
+// argumentMode selects how tools/call arguments are checked against the
+// tool's inputSchema before the tool runs.
+type argumentMode string
+
+const (
+	// argsLenient fills in schema defaults and coerces compatible values
+	// ("5" for an integer, 3 for a string, "x" for an array of strings)
+	// before checking types, required arguments and enums. Models often send
+	// slightly mistyped arguments, and a failed call costs them a turn.
+	argsLenient argumentMode = "lenient"
+	// argsStrict fills in defaults but rejects any value of the wrong type.
+	argsStrict argumentMode = "strict"
+	// argsOff passes the arguments to the tool as sent.
+	argsOff argumentMode = "off"
+)
+
+// parseArgumentMode parses a -tool-args value.
+func parseArgumentMode(s string) (argumentMode, error) {
+	switch mode := argumentMode(s); mode {
+	case argsLenient, argsStrict, argsOff:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown argument mode %q, want lenient, strict or off", s)
+}
+
+// prepareArguments applies schema to the arguments of a tools/call in mode.
+// It returns the arguments to hand to the tool, or an error listing every
+// argument that does not fit the schema.
+func prepareArguments(schema mcp.ToolInputSchema, args map[string]interface{}, mode argumentMode) (map[string]interface{}, error) {
+	if mode == argsOff || schema == nil {
+		return args, nil
+	}
+	c := argumentChecker{coerce: mode == argsLenient}
+	prepared := c.object(map[string]interface{}(schema), args, "")
+	if len(c.problems) > 0 {
+		return nil, fmt.Errorf("invalid arguments: %s", strings.Join(c.problems, "; "))
+	}
+	return prepared, nil
+}
+
+// argumentChecker walks a value and its schema, collecting problems.
+type argumentChecker struct {
+	coerce   bool
+	problems []string
+}
+
+func (c *argumentChecker) fail(path, format string, a ...interface{}) {
+	c.problems = append(c.problems, fmt.Sprintf("argument '%s' %s", path, fmt.Sprintf(format, a...)))
+}
+
+// object checks the properties of an object against schema, filling in
+// defaults for absent or null properties. The result is a new map; args is
+// not modified.
+func (c *argumentChecker) object(schema map[string]interface{}, args map[string]interface{}, path string) map[string]interface{} {
+	properties, _ := schema["properties"].(map[string]interface{})
+	out := make(map[string]interface{}, len(args)+len(properties))
+	for name, value := range args {
+		out[name] = value
+	}
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		property, _ := properties[name].(map[string]interface{})
+		if property == nil {
+			continue
+		}
+		if value, ok := out[name]; ok && value != nil {
+			out[name] = c.value(property, value, path+name)
+		} else if def, ok := property["default"]; ok {
+			out[name] = cloneJSON(def)
+		}
+	}
+	for _, name := range schemaStrings(schema["required"]) {
+		if value, ok := out[name]; !ok || value == nil {
+			c.fail(path+name, "is missing%s", expectation(properties[name]))
+		}
+	}
+	return out
+}
+
+// value checks one value against its schema and returns it, coerced if allowed.
+func (c *argumentChecker) value(schema map[string]interface{}, value interface{}, path string) interface{} {
+	types := schemaStrings(schema["type"])
+	if len(types) > 0 {
+		coerced, ok := value, false
+		for _, t := range types {
+			if jsonType(value, t) {
+				ok = true
+				break
+			}
+		}
+		for _, t := range types {
+			if ok || !c.coerce {
+				break
+			}
+			coerced, ok = coerceValue(value, t)
+		}
+		if !ok {
+			c.fail(path, "must be %s, got %s", strings.Join(types, " or "), describeValue(value))
+			return value
+		}
+		value = coerced
+	}
+	if enum := schemaValues(schema["enum"]); len(enum) > 0 {
+		value = c.enum(enum, value, path)
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if _, ok := schema["properties"]; ok {
+			return c.object(schema, v, path+".")
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			out := make([]interface{}, len(v))
+			for i, item := range v {
+				out[i] = c.value(items, item, fmt.Sprintf("%s[%d]", path, i))
+			}
+			return out
+		}
+	}
+	return value
+}
+
+// enum checks value against the allowed values. Lenient mode accepts a
+// string that differs only in case and returns the listed spelling.
+func (c *argumentChecker) enum(enum []interface{}, value interface{}, path string) interface{} {
+	for _, allowed := range enum {
+		if allowed == value {
+			return value
+		}
+	}
+	if s, ok := value.(string); ok && c.coerce {
+		for _, allowed := range enum {
+			if a, ok := allowed.(string); ok && strings.EqualFold(a, strings.TrimSpace(s)) {
+				return a
+			}
+		}
+	}
+	listed := make([]string, len(enum))
+	for i, allowed := range enum {
+		listed[i] = describeValue(allowed)
+	}
+	c.fail(path, "must be one of %s, got %s", strings.Join(listed, ", "), describeValue(value))
+	return value
+}
+
+// jsonType reports whether a decoded JSON value has the JSON Schema type t.
+func jsonType(value interface{}, t string) bool {
+	switch v := value.(type) {
+	case string:
+		return t == "string"
+	case float64:
+		return t == "number" || t == "integer" && v == math.Trunc(v)
+	case bool:
+		return t == "boolean"
+	case []interface{}:
+		return t == "array"
+	case map[string]interface{}:
+		return t == "object"
+	case nil:
+		return t == "null"
+	}
+	return false
+}
+
+// coerceValue converts value to the JSON Schema type t if the conversion
+// loses nothing.
+func coerceValue(value interface{}, t string) (interface{}, bool) {
+	switch v := value.(type) {
+	case string:
+		s := strings.TrimSpace(v)
+		switch t {
+		case "integer":
+			if f, err := strconv.ParseFloat(s, 64); err == nil && f == math.Trunc(f) && !math.IsInf(f, 0) {
+				return f, true
+			}
+		case "number":
+			if f, err := strconv.ParseFloat(s, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+				return f, true
+			}
+		case "boolean":
+			switch strings.ToLower(s) {
+			case "true":
+				return true, true
+			case "false":
+				return false, true
+			}
+		case "array", "object":
+			// A model sometimes sends structured arguments JSON-encoded in a string
+			var decoded interface{}
+			if json.Unmarshal([]byte(s), &decoded) == nil && jsonType(decoded, t) {
+				return decoded, true
+			}
+		}
+	case float64:
+		if t == "string" {
+			return strconv.FormatFloat(v, 'f', -1, 64), true
+		}
+	case bool:
+		if t == "string" {
+			return strconv.FormatBool(v), true
+		}
+	}
+	if t == "array" && value != nil {
+		if _, ok := value.([]interface{}); !ok {
+			return []interface{}{value}, true // A single item for a list
+		}
+	}
+	return nil, false
+}
+
+// expectation describes what a missing property should be, e.g. " (expected string)".
+func expectation(property interface{}) string {
+	schema, _ := property.(map[string]interface{})
+	if types := schemaStrings(schema["type"]); len(types) > 0 {
+		return fmt.Sprintf(" (expected %s)", strings.Join(types, " or "))
+	}
+	return ""
+}
+
+// describeValue renders a decoded JSON value for an error message.
+func describeValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		if len(v) > 40 {
+			v = v[:40] + "..."
+		}
+		return fmt.Sprintf("string %q", v)
+	case float64:
+		return "number " + strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return "boolean " + strconv.FormatBool(v)
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// schemaStrings returns a schema keyword that is a string or a list of
+// strings, e.g. "type" or "required", as a slice. Schemas are written as Go
+// literals, so lists may be []string as well as []interface{}.
+func schemaStrings(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	case []interface{}:
+		var out []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// schemaValues returns a list keyword such as "enum" as decoded JSON values.
+func schemaValues(v interface{}) []interface{} {
+	if v == nil {
+		return nil
+	}
+	values, _ := cloneJSON(v).([]interface{})
+	return values
+}
+
+// cloneJSON returns v as decoded JSON, so Go literals from a schema (an int
+// default, a []string enum) compare equal to values decoded from a request.
+func cloneJSON(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return v
+	}
+	return out
+}
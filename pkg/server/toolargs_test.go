@@ -0,0 +1,87 @@
+package server
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"sqirvy/mcp/pkg/mcp"
+)
+
+var toolArgsSchema = mcp.ToolInputSchema{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"file":   map[string]interface{}{"type": "string"},
+		"limit":  map[string]interface{}{"type": "integer", "default": 50},
+		"ratio":  map[string]interface{}{"type": "number"},
+		"all":    map[string]interface{}{"type": "boolean"},
+		"format": map[string]interface{}{"type": "string", "enum": []string{"csv", "json"}},
+		"tags":   map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		"filter": map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"max": map[string]interface{}{"type": "integer", "default": 10}},
+		},
+	},
+	"required": []string{"file"},
+}
+
+func TestPrepareArgumentsLenient(t *testing.T) {
+	args := map[string]interface{}{
+		"file": 42.0, "ratio": " 0.5", "all": "TRUE", "format": "CSV", "tags": "red",
+		"filter": `{"max": "3"}`, "extra": "kept",
+	}
+	got, err := prepareArguments(toolArgsSchema, args, argsLenient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"file": "42", "limit": 50.0, "ratio": 0.5, "all": true, "format": "csv", "tags": []interface{}{"red"},
+		"filter": map[string]interface{}{"max": 3.0}, "extra": "kept",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("prepareArguments = %#v\nwant %#v", got, want)
+	}
+	if args["file"] != 42.0 {
+		t.Error("prepareArguments modified its input")
+	}
+
+	got, err = prepareArguments(toolArgsSchema, map[string]interface{}{"file": "a", "limit": nil, "filter": map[string]interface{}{}}, argsLenient)
+	if err != nil || got["limit"] != 50.0 || !reflect.DeepEqual(got["filter"], map[string]interface{}{"max": 10.0}) {
+		t.Errorf("defaults = %v, %v", got, err)
+	}
+}
+
+func TestPrepareArgumentsErrors(t *testing.T) {
+	args := map[string]interface{}{"limit": "5.5", "format": "xml", "tags": []interface{}{"a", map[string]interface{}{}}}
+	_, err := prepareArguments(toolArgsSchema, args, argsLenient)
+	if err == nil {
+		t.Fatal("prepareArguments succeeded, want errors")
+	}
+	for _, want := range []string{
+		`argument 'file' is missing (expected string)`,
+		`argument 'limit' must be integer, got string "5.5"`,
+		`argument 'format' must be one of string "csv", string "json", got string "xml"`,
+		`argument 'tags[1]' must be string, got object`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+}
+
+func TestPrepareArgumentsModes(t *testing.T) {
+	args := map[string]interface{}{"file": "a", "limit": "5"}
+	if _, err := prepareArguments(toolArgsSchema, args, argsStrict); err == nil || !strings.Contains(err.Error(), "'limit' must be integer") {
+		t.Errorf("strict mode accepted a string for an integer: %v", err)
+	}
+	got, err := prepareArguments(toolArgsSchema, map[string]interface{}{"file": "a"}, argsStrict)
+	if err != nil || got["limit"] != 50.0 {
+		t.Errorf("strict mode defaults = %v, %v", got, err)
+	}
+	if got, err := prepareArguments(toolArgsSchema, args, argsOff); err != nil || !reflect.DeepEqual(got, args) {
+		t.Errorf("off mode = %v, %v", got, err)
+	}
+	if _, err := parseArgumentMode("loose"); err == nil {
+		t.Error("parseArgumentMode accepted an unknown mode")
+	}
+}
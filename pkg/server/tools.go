@@ -0,0 +1,52 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"sqirvy/mcp/pkg/mcp"
+	ping "sqirvy/mcp/pkg/tools"
+	// Import the custom logger
+)
+
+const (
+	pingTargetIP = "192.168.5.4"
+	pingTimeout  = 5 * time.Second // Timeout for the ping command
+	pingToolName = "ping"
+)
+
+// handlePingTool handles the "tools/call" request specifically for the "ping" tool.
+// It executes the ping command and returns the result or an error.
+func (s *Server) handlePingTool(ctx context.Context, id mcp.RequestID, params mcp.CallToolParams) ([]byte, error) {
+	s.logger.Printf("DEBUG", "Handle  : tools/call request for '%s' (ID: %v)", params.Name, id)
+
+	// Execute the ping command
+	output, err := ping.PingHost(ctx, pingTargetIP, pingTimeout)
+
+	var result mcp.CallToolResult
+	if err != nil {
+		s.logger.Printf("DEBUG", "Error executing ping to %s: %v", pingTargetIP, err)
+		// Ping failed, return the error message in the content
+		result = toolError(fmt.Errorf("Error pinging %s: %w", pingTargetIP, err))
+	} else {
+		s.logger.Printf("DEBUG", "Ping to %s successful. Output:\n%s", pingTargetIP, output)
+		result = mcp.NewToolResultText(output)
+	}
+
+	// Marshal the successful (or tool-error) CallToolResult response
+	return s.marshalResponse(id, result)
+}
+
+// toolError returns the tool error result for err. A command stopped before it
+// finished (see tools.CanceledError) also reports what it printed until then,
+// which is often enough for the model to go on.
+func toolError(err error) mcp.CallToolResult {
+	result := mcp.NewToolResultError(err)
+	var stopped *ping.CanceledError
+	if errors.As(err, &stopped) && stopped.Output != "" {
+		result.Content = append(result.Content, mcp.NewTextContent("Output before it was stopped:\n"+stopped.Output))
+	}
+	return result
+}
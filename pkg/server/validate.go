@@ -0,0 +1,25 @@
+package server
+
+import (
+	"encoding/json"
+
+	"sqirvy/mcp/pkg/mcp"
+)
+
+// validateParams checks a request's params against the schema registered for
+// its method in pkg/mcp, before any handler sees them. It returns an
+// InvalidParams error listing each problem by JSON Pointer, or nil.
+func (s *Server) validateParams(method string, payload []byte) *mcp.RPCError {
+	var req struct {
+		Params json.RawMessage `json:"params"`
+	}
+	if err := json.Unmarshal(payload, &req); err != nil {
+		return nil // Already classified as a request; the handler reports malformed JSON
+	}
+	violations := mcp.ValidateRequestParams(method, req.Params)
+	if len(violations) == 0 {
+		return nil
+	}
+	s.logger.Printf("DEBUG", "Rejecting %s request: %d invalid param(s)", method, len(violations))
+	return mcp.NewInvalidParamsError(method, violations)
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"reflect"
+	"testing"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/utils"
+)
+
+func TestDispatchRejectsInvalidParams(t *testing.T) {
+	s := NewServer(&captureTransport{written: make(chan []byte, 1)}, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+
+	tests := []struct {
+		method string
+		params string
+		want   []string // Pointers reported in data.errors; nil if the request is valid
+	}{
+		{mcp.MethodCallTool, `{"name":5,"arguments":[]}`, []string{"/params/arguments", "/params/name"}},
+		{mcp.MethodCallTool, `{"arguments":{}}`, []string{"/params/name"}},
+		{mcp.MethodGetPrompt, `{"name":"p","arguments":{"a":1}}`, []string{"/params/arguments/a"}},
+		{mcp.MethodReadResource, ``, []string{"/params"}},
+		{mcp.MethodReadResource, `[]`, []string{"/params"}},
+		{mcp.MethodListTools, ``, nil},
+		{mcp.MethodListTools, `{"cursor":"c","extra":true}`, nil},
+	}
+	for _, tt := range tests {
+		payload := `{"jsonrpc":"2.0","id":1,"method":"` + tt.method + `"}`
+		if tt.params != "" {
+			payload = `{"jsonrpc":"2.0","id":1,"method":"` + tt.method + `","params":` + tt.params + `}`
+		}
+		var resp struct {
+			Error *struct {
+				Code int `json:"code"`
+				Data struct {
+					Errors []mcp.SchemaViolation `json:"errors"`
+				} `json:"data"`
+			} `json:"error"`
+		}
+		out := s.dispatch(1, tt.method, []byte(payload))
+		if err := json.Unmarshal(out, &resp); err != nil {
+			t.Fatalf("%s %s: bad response %s: %v", tt.method, tt.params, out, err)
+		}
+		if tt.want == nil {
+			if resp.Error != nil && resp.Error.Code == mcp.ErrorCodeInvalidParams {
+				t.Errorf("%s %s: rejected valid params: %s", tt.method, tt.params, out)
+			}
+			continue
+		}
+		if resp.Error == nil || resp.Error.Code != mcp.ErrorCodeInvalidParams {
+			t.Errorf("%s %s: response = %s, want InvalidParams", tt.method, tt.params, out)
+			continue
+		}
+		var got []string
+		for _, v := range resp.Error.Data.Errors {
+			got = append(got, v.Pointer)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s %s: pointers = %v, want %v", tt.method, tt.params, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"flag"
+	"runtime"
+	"runtime/debug"
+
+	"sqirvy/mcp/pkg/mcp"
+)
+
+// Build metadata. These are overridden at link time, e.g.
+//
+//	go build -ldflags "-X sqirvy/mcp/pkg/server.version=1.2.3 -X sqirvy/mcp/pkg/server.commit=abc1234 -X sqirvy/mcp/pkg/server.buildDate=2025-01-01T00:00:00Z" ./cmd/mcp-server
+//
+// When left empty, commit and buildDate fall back to the VCS stamp recorded by the Go toolchain.
+var (
+	version   = "0.1.0"
+	commit    = ""
+	buildDate = ""
+)
+
+const (
+	// methodServerInfo is a non-standard, debug-only method returning build and runtime details.
+	methodServerInfo = "server/info"
+)
+
+// BuildInfo describes how and where the running server binary was built.
+type BuildInfo struct {
+	Version       string            `json:"version"`
+	Commit        string            `json:"commit,omitempty"`
+	BuildDate     string            `json:"buildDate,omitempty"`
+	Modified      bool              `json:"modified,omitempty"` // Working tree had uncommitted changes at build time
+	ModulePath    string            `json:"modulePath,omitempty"`
+	ModuleVersion string            `json:"moduleVersion,omitempty"`
+	GoVersion     string            `json:"goVersion"`
+	GOOS          string            `json:"goos"`
+	GOARCH        string            `json:"goarch"`
+	NumCPU        int               `json:"numCPU"`
+	BuildSettings map[string]string `json:"buildSettings,omitempty"` // -tags, -ldflags, CGO_ENABLED, ...
+	Flags         map[string]string `json:"flags,omitempty"`         // Command line flags the server was started with
+}
+
+// readBuildInfo collects the link-time variables and the toolchain-embedded build information.
+func readBuildInfo() BuildInfo {
+	info := BuildInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+		GOOS:      runtime.GOOS,
+		GOARCH:    runtime.GOARCH,
+		NumCPU:    runtime.NumCPU(),
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		info.ModulePath = bi.Main.Path
+		info.ModuleVersion = bi.Main.Version
+		info.BuildSettings = make(map[string]string, len(bi.Settings))
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.BuildDate == "" {
+					info.BuildDate = setting.Value
+				}
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			default:
+				info.BuildSettings[setting.Key] = setting.Value
+			}
+		}
+	}
+
+	// Only report flags once they have been parsed (i.e. when running as the real binary)
+	if flag.Parsed() {
+		info.Flags = make(map[string]string)
+		flag.VisitAll(func(f *flag.Flag) {
+			info.Flags[f.Name] = f.Value.String()
+		})
+	}
+
+	return info
+}
+
+// serverVersionString returns the version reported in InitializeResult.ServerInfo.
+// The short commit hash is appended as semver build metadata when known, e.g. "0.1.0+abc1234".
+func serverVersionString(info BuildInfo) string {
+	if info.Commit == "" {
+		return info.Version
+	}
+	short := info.Commit
+	if len(short) > 7 {
+		short = short[:7]
+	}
+	if info.Modified {
+		short += ".dirty"
+	}
+	return info.Version + "+" + short
+}
+
+// handleServerInfo handles the debug-only "server/info" request.
+// It is only routed when the server was started with debugging enabled.
+func (s *Server) handleServerInfo(id mcp.RequestID) ([]byte, error) {
+	s.logger.Printf("DEBUG", "Handle  : %s request (ID: %v)", methodServerInfo, id)
+	return s.marshalResponse(id, readBuildInfo())
+}
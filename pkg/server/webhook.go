@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"sqirvy/mcp/pkg/mcp"
+	"sqirvy/mcp/pkg/tools"
+)
+
+const (
+	webhookTimeout     = 10 * time.Second
+	defaultWebhookRate = 10 // Messages per minute
+)
+
+// notifyLevels are the accepted values of the notify tool's level argument.
+var notifyLevels = []string{"info", "warning", "critical"}
+
+// rateLimiter allows at most limit events in any window, so a looping agent
+// cannot flood the people it alerts.
+type rateLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	events []time.Time // Times of the events in the current window, oldest first
+}
+
+// allow records an event at now and returns 0 if the limit permits it, or how
+// long to wait until it would.
+func (r *rateLimiter) allow(now time.Time) time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	start := now.Add(-r.window)
+	kept := r.events[:0]
+	for _, t := range r.events {
+		if t.After(start) {
+			kept = append(kept, t)
+		}
+	}
+	r.events = kept
+	if len(r.events) >= r.limit {
+		return r.events[0].Sub(start)
+	}
+	r.events = append(r.events, now)
+	return 0
+}
+
+// webhookModule returns the notify tool, which posts messages to hook at most
+// rate times per minute.
+func webhookModule(hook *tools.Webhook, rate int) *toolModule {
+	limiter := &rateLimiter{limit: rate, window: time.Minute}
+	stringProp := func(description string) map[string]interface{} {
+		return map[string]interface{}{"type": "string", "description": description}
+	}
+	return &toolModule{
+		name: "webhook",
+		tools: []moduleTool{
+			{
+				tool: mcp.Tool{
+					Name:  "notify",
+					Title: "Send Notification",
+					Description: fmt.Sprintf("Sends a message to the humans watching this server's %s webhook. Use it for "+
+						"alerts that need attention, not for progress updates: at most %d messages per minute are sent, "+
+						"and messages longer than %d characters are truncated.", hook.Format(), rate, hook.MaxLength()),
+					InputSchema: mcp.ToolInputSchema{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"message": stringProp("The message"),
+							"title":   stringProp("Optional short title"),
+							"level": map[string]interface{}{
+								"type": "string", "enum": notifyLevels,
+								"description": "Severity (default info)",
+							},
+						},
+						"required": []string{"message"},
+					},
+				},
+				limit:       1,
+				destructive: true,
+				call: func(_ context.Context, _ *Server, raw map[string]interface{}) (mcp.CallToolResult, error) {
+					var args struct {
+						Message string `json:"message"`
+						Title   string `json:"title"`
+						Level   string `json:"level"`
+					}
+					if err := decodeToolArgs(raw, &args); err != nil {
+						return mcp.CallToolResult{}, err
+					}
+					msg, err := notifyMessage(args.Message, args.Title, args.Level)
+					if err != nil {
+						return mcp.CallToolResult{}, err
+					}
+					if wait := limiter.allow(time.Now()); wait > 0 {
+						return mcp.CallToolResult{}, fmt.Errorf("rate limit of %d messages per minute reached; retry in %v", rate, wait.Round(time.Second))
+					}
+					truncated, err := hook.Post(msg)
+					if err != nil {
+						return mcp.CallToolResult{}, err
+					}
+					if truncated {
+						return mcp.NewToolResultText(fmt.Sprintf("Message sent, truncated to %d characters.", hook.MaxLength())), nil
+					}
+					return mcp.NewToolResultText("Message sent."), nil
+				},
+			},
+		},
+		check: func() (string, error) {
+			// Posting a test message would alert people, so only the configuration is reported.
+			return fmt.Sprintf("%s webhook at %s, at most %d message(s) per minute (not contacted)", hook.Format(), hook.Host(), rate), nil
+		},
+	}
+}
+
+// notifyMessage validates the notify tool's arguments.
+func notifyMessage(message, title, level string) (tools.WebhookMessage, error) {
+	if strings.TrimSpace(message) == "" {
+		return tools.WebhookMessage{}, fmt.Errorf("message is required")
+	}
+	if level == "" {
+		level = "info"
+	}
+	for _, l := range notifyLevels {
+		if level == l {
+			title = strings.Join(strings.Fields(title), " ") // Keep the heading on one line
+			return tools.WebhookMessage{Title: title, Message: message, Level: level, Source: "mcp-server"}, nil
+		}
+	}
+	return tools.WebhookMessage{}, fmt.Errorf("invalid level %q (want %s)", level, strings.Join(notifyLevels, ", "))
+}
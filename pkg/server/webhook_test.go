@@ -0,0 +1,91 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"sqirvy/mcp/pkg/tools"
+	"sqirvy/mcp/pkg/utils"
+)
+
+func TestNotifyTool(t *testing.T) {
+	var payloads []map[string]interface{}
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		payloads = append(payloads, payload)
+	}))
+	defer hook.Close()
+
+	webhook, err := tools.NewWebhook(hook.URL+"/hook", tools.WebhookDiscord, webhookTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(&captureTransport{}, utils.New(io.Discard, "", log.LstdFlags, utils.LevelInfo))
+	s.modules = []*toolModule{webhookModule(webhook, 2)}
+
+	result := callTool(t, s, "notify", map[string]interface{}{"message": "disk full", "title": "db-1", "level": "critical"})
+	if result.IsError || len(payloads) != 1 || payloads[0]["content"] != "**[CRITICAL] db-1**\ndisk full" {
+		t.Fatalf("notify = %q, payloads %v", mustText(result), payloads)
+	}
+
+	result = callTool(t, s, "notify", map[string]interface{}{"message": strings.Repeat("é", 3000)})
+	content, _ := payloads[1]["content"].(string)
+	if !strings.Contains(mustText(result), "truncated") || utf8.RuneCountInString(content) != 2000 || !strings.HasSuffix(content, "(truncated)") {
+		t.Errorf("long message: %q, sent %d characters", mustText(result), utf8.RuneCountInString(content))
+	}
+
+	result = callTool(t, s, "notify", map[string]interface{}{"message": "third"})
+	if !result.IsError || !strings.Contains(mustText(result), "rate limit") || len(payloads) != 2 {
+		t.Errorf("third message in a minute = %q, want a rate limit error", mustText(result))
+	}
+
+	for _, args := range []map[string]interface{}{{"message": " "}, {"message": "x", "level": "panic"}} {
+		if result := callTool(t, s, "notify", args); !result.IsError {
+			t.Errorf("notify %v succeeded, want a tool error", args)
+		}
+	}
+}
+
+func TestWebhookFormats(t *testing.T) {
+	for url, want := range map[string]string{
+		"https://hooks.slack.com/services/T0/B0/x": tools.WebhookSlack,
+		"https://discord.com/api/webhooks/1/abc":   tools.WebhookDiscord,
+		"https://alerts.example.com/hook":          tools.WebhookGeneric,
+		"https://discord.com/channels/1/2":         tools.WebhookGeneric,
+	} {
+		hook, err := tools.NewWebhook(url, "auto", webhookTimeout)
+		if err != nil || hook.Format() != want {
+			t.Errorf("NewWebhook(%q) = %v, %v, want format %s", url, hook, err, want)
+		}
+	}
+	for _, url := range []string{"ftp://example.com/x", "not a url", ""} {
+		if _, err := tools.NewWebhook(url, "", webhookTimeout); err == nil {
+			t.Errorf("NewWebhook(%q) succeeded", url)
+		}
+	}
+	if _, err := tools.NewWebhook("https://example.com", "teams", webhookTimeout); err == nil {
+		t.Error("unknown format accepted")
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	r := &rateLimiter{limit: 2, window: time.Minute}
+	start := time.Unix(1700000000, 0)
+	if r.allow(start) != 0 || r.allow(start.Add(10*time.Second)) != 0 {
+		t.Fatal("first two events refused")
+	}
+	if wait := r.allow(start.Add(20 * time.Second)); wait != 40*time.Second {
+		t.Errorf("wait = %v, want 40s", wait)
+	}
+	if r.allow(start.Add(61*time.Second)) != 0 {
+		t.Error("event after the window refused")
+	}
+}
@@ -0,0 +1,123 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// MaxCommandOutput bounds the output kept from an external command; the rest is dropped.
+const MaxCommandOutput = 1 << 20
+
+// TerminateGrace is how long a stopped command has to exit after SIGTERM
+// before it is killed with SIGKILL.
+var TerminateGrace = 2 * time.Second
+
+// CanceledError is returned for a command stopped before it finished, because
+// the caller's context ended (e.g. the client canceled the tool call) or the
+// command's own timeout passed.
+type CanceledError struct {
+	Command string
+	Timeout time.Duration // The timeout that passed, 0 if the context ended
+	Output  string        // Standard output and error captured before the command was stopped
+	Err     error         // context.Canceled or context.DeadlineExceeded
+}
+
+func (e *CanceledError) Error() string {
+	if e.Timeout > 0 {
+		return fmt.Sprintf("%s timed out after %v", e.Command, e.Timeout)
+	}
+	return fmt.Sprintf("%s was canceled: %v", e.Command, e.Err)
+}
+
+func (e *CanceledError) Unwrap() error { return e.Err }
+
+// stopGracefully makes cmd, created with exec.CommandContext, receive SIGTERM
+// when its context ends, and SIGKILL if it is still running TerminateGrace
+// later. Wait then returns without waiting for children holding its output.
+func stopGracefully(cmd *exec.Cmd) {
+	cmd.Cancel = func() error { return terminate(cmd.Process) }
+	cmd.WaitDelay = TerminateGrace
+}
+
+// terminate asks p to exit.
+func terminate(p *os.Process) error {
+	if runtime.GOOS == "windows" {
+		return p.Kill() // Windows has no SIGTERM
+	}
+	return p.Signal(syscall.SIGTERM)
+}
+
+// canceled returns the error for a command run under ctx, stopped after it
+// printed output. It tells a timeout of its own from the end of parent.
+func canceled(parent context.Context, name string, timeout time.Duration, output string) *CanceledError {
+	err := &CanceledError{Command: name, Output: strings.TrimSpace(output), Err: parent.Err()}
+	if err.Err == nil {
+		err.Timeout, err.Err = timeout, context.DeadlineExceeded
+	}
+	return err
+}
+
+// runCommand runs name with args, stopping it when ctx ends or after timeout
+// (if non-zero). It returns the standard output, truncated to
+// MaxCommandOutput, or an error carrying the command's standard error; a
+// *CanceledError if it was stopped.
+func runCommand(ctx context.Context, timeout time.Duration, name string, args ...string) (string, error) {
+	return runCommandInput(ctx, timeout, nil, name, args...)
+}
+
+// runCommandInput is runCommand with stdin as the command's standard input.
+func runCommandInput(ctx context.Context, timeout time.Duration, stdin io.Reader, name string, args ...string) (string, error) {
+	runCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(runCtx, name, args...)
+	stopGracefully(cmd)
+	cmd.Stdin = stdin
+	stdout := &limitedBuffer{limit: MaxCommandOutput}
+	stderr := &limitedBuffer{limit: 64 << 10}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+
+	err := cmd.Run()
+	output := stdout.String()
+	if stdout.truncated {
+		output += fmt.Sprintf("\n[output truncated at %d bytes]", MaxCommandOutput)
+	}
+	switch {
+	case err != nil && runCtx.Err() != nil:
+		return "", canceled(ctx, name, timeout, output+stderr.String())
+	case err != nil:
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+		return "", fmt.Errorf("%s failed: %s", name, message)
+	}
+	return output, nil
+}
+
+// limitedBuffer is a bytes.Buffer that silently drops writes past limit.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); len(p) > room {
+		b.truncated = true
+		b.Buffer.Write(p[:max(room, 0)])
+		return len(p), nil // Keep the command running; the excess is discarded
+	}
+	return b.Buffer.Write(p)
+}
@@ -0,0 +1,62 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunCommandStopsOnCancel(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh")
+	}
+	defer func(grace time.Duration) { TerminateGrace = grace }(TerminateGrace)
+	TerminateGrace = 200 * time.Millisecond
+
+	// A canceled call sends SIGTERM, which sh exits on
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(300*time.Millisecond, cancel)
+	start := time.Now()
+	_, err := runCommand(ctx, 0, "sh", "-c", "echo partial; sleep 10")
+	var stopped *CanceledError
+	if !errors.As(err, &stopped) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want canceled", err)
+	}
+	if stopped.Output != "partial" || stopped.Timeout != 0 {
+		t.Errorf("stopped = %+v", stopped)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("took %v to stop", elapsed)
+	}
+
+	// A canceled command ignoring SIGTERM is killed after the grace period
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(300*time.Millisecond, cancel)
+	start = time.Now()
+	_, err = runCommand(ctx, 0, "sh", "-c", "trap '' TERM; echo ignoring; sleep 10")
+	if !errors.As(err, &stopped) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want canceled", err)
+	}
+	if stopped.Output != "ignoring" {
+		t.Errorf("stopped = %+v", stopped)
+	}
+	if elapsed := time.Since(start); elapsed < 500*time.Millisecond || elapsed > 5*time.Second {
+		t.Errorf("took %v to kill, want the grace period after the cancel", elapsed)
+	}
+
+	// A command ignoring SIGTERM is killed after the grace period
+	start = time.Now()
+	_, err = runCommand(context.Background(), 300*time.Millisecond, "sh", "-c", "trap '' TERM; echo stubborn >&2; sleep 10")
+	if !errors.As(err, &stopped) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want timeout", err)
+	}
+	if !strings.Contains(err.Error(), "timed out after 300ms") || stopped.Output != "stubborn" {
+		t.Errorf("err = %v, output %q", err, stopped.Output)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("took %v to kill", elapsed)
+	}
+}
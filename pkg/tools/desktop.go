@@ -0,0 +1,133 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// desktopFileArg in a screenshot command's arguments is replaced with the
+// path of the PNG file the command must write.
+const desktopFileArg = "{file}"
+
+// MaxScreenshotSize bounds the PNG returned by Desktop.Screenshot.
+const MaxScreenshotSize = 10 << 20
+
+// desktopCommand is an external program the desktop tools can use.
+type desktopCommand struct {
+	name string
+	args []string
+}
+
+func (c desktopCommand) available() bool {
+	_, err := exec.LookPath(c.name)
+	return err == nil
+}
+
+// Desktop reads and writes the clipboard and captures the screen of the machine
+// the server runs on. Each operation uses the first of the platform's commands
+// (see desktop_<os>.go) that is installed.
+type Desktop struct {
+	Timeout time.Duration
+}
+
+// findDesktopCommand returns the first installed command of the given kind.
+func findDesktopCommand(kind string, candidates []desktopCommand) (desktopCommand, error) {
+	for _, c := range candidates {
+		if c.available() {
+			return c, nil
+		}
+	}
+	if len(candidates) == 0 {
+		return desktopCommand{}, fmt.Errorf("no %s support on %s", kind, runtime.GOOS)
+	}
+	names := make([]string, len(candidates))
+	for i, c := range candidates {
+		names[i] = c.name
+	}
+	return desktopCommand{}, fmt.Errorf("no %s command found; install one of: %s", kind, strings.Join(names, ", "))
+}
+
+// ReadClipboard returns the text on the clipboard.
+func (d Desktop) ReadClipboard(ctx context.Context) (string, error) {
+	c, err := findDesktopCommand("clipboard", clipboardReadCommands())
+	if err != nil {
+		return "", err
+	}
+	return runCommand(ctx, d.Timeout, c.name, c.args...)
+}
+
+// WriteClipboard replaces the clipboard contents with text.
+func (d Desktop) WriteClipboard(ctx context.Context, text string) error {
+	c, err := findDesktopCommand("clipboard", clipboardWriteCommands())
+	if err != nil {
+		return err
+	}
+	_, err = runCommandInput(ctx, d.Timeout, strings.NewReader(text), c.name, c.args...)
+	return err
+}
+
+// Screenshot captures the whole screen and returns it as PNG.
+func (d Desktop) Screenshot(ctx context.Context) ([]byte, error) {
+	c, err := findDesktopCommand("screenshot", screenshotCommands())
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "mcp-screenshot-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "screen.png")
+	args := make([]string, len(c.args))
+	for i, arg := range c.args {
+		args[i] = strings.ReplaceAll(arg, desktopFileArg, file)
+	}
+	if _, err := runCommand(ctx, d.Timeout, c.name, args...); err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s wrote no screenshot", c.name)
+	}
+	if info.Size() > MaxScreenshotSize {
+		return nil, fmt.Errorf("screenshot is %d bytes, more than %d", info.Size(), MaxScreenshotSize)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")) {
+		return nil, fmt.Errorf("%s did not write a PNG image", c.name)
+	}
+	return data, nil
+}
+
+// Check reports which commands the desktop tools would use.
+func (d Desktop) Check() (string, error) {
+	var found []string
+	var errs []error
+	for _, kind := range []struct {
+		name       string
+		candidates []desktopCommand
+	}{
+		{"clipboard read", clipboardReadCommands()},
+		{"clipboard write", clipboardWriteCommands()},
+		{"screenshot", screenshotCommands()},
+	} {
+		c, err := findDesktopCommand(kind.name, kind.candidates)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		found = append(found, kind.name+": "+c.name)
+	}
+	return strings.Join(found, ", "), errors.Join(errs...)
+}
@@ -0,0 +1,14 @@
+package tools
+
+func clipboardReadCommands() []desktopCommand {
+	return []desktopCommand{{"pbpaste", nil}}
+}
+
+func clipboardWriteCommands() []desktopCommand {
+	return []desktopCommand{{"pbcopy", nil}}
+}
+
+func screenshotCommands() []desktopCommand {
+	// -x: no shutter sound. Needs the Screen Recording permission for the terminal or host app.
+	return []desktopCommand{{"screencapture", []string{"-x", "-t", "png", desktopFileArg}}}
+}
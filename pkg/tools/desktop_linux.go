@@ -0,0 +1,42 @@
+package tools
+
+import "os"
+
+// On Wayland the wl-clipboard and grim commands are tried first; the X11
+// commands also work under XWayland for most applications.
+
+func wayland() bool { return os.Getenv("WAYLAND_DISPLAY") != "" }
+
+func clipboardReadCommands() []desktopCommand {
+	x11 := []desktopCommand{
+		{"xclip", []string{"-selection", "clipboard", "-out"}},
+		{"xsel", []string{"--clipboard", "--output"}},
+	}
+	if wayland() {
+		return append([]desktopCommand{{"wl-paste", []string{"--no-newline"}}}, x11...)
+	}
+	return x11
+}
+
+func clipboardWriteCommands() []desktopCommand {
+	x11 := []desktopCommand{
+		{"xclip", []string{"-selection", "clipboard", "-in"}},
+		{"xsel", []string{"--clipboard", "--input"}},
+	}
+	if wayland() {
+		return append([]desktopCommand{{"wl-copy", nil}}, x11...)
+	}
+	return x11
+}
+
+func screenshotCommands() []desktopCommand {
+	x11 := []desktopCommand{
+		{"gnome-screenshot", []string{"--file=" + desktopFileArg}},
+		{"import", []string{"-window", "root", desktopFileArg}}, // ImageMagick
+		{"scrot", []string{"--overwrite", desktopFileArg}},
+	}
+	if wayland() {
+		return append([]desktopCommand{{"grim", []string{desktopFileArg}}}, x11...)
+	}
+	return x11
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !windows
+
+package tools
+
+// The desktop tools are not implemented on this platform.
+
+func clipboardReadCommands() []desktopCommand  { return nil }
+func clipboardWriteCommands() []desktopCommand { return nil }
+func screenshotCommands() []desktopCommand     { return nil }
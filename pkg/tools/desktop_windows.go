@@ -0,0 +1,24 @@
+package tools
+
+// The Windows commands are PowerShell scripts.
+
+func powershell(script string) desktopCommand {
+	return desktopCommand{"powershell.exe", []string{"-NoProfile", "-NonInteractive", "-Command", script}}
+}
+
+func clipboardReadCommands() []desktopCommand {
+	return []desktopCommand{powershell("Get-Clipboard -Raw")}
+}
+
+func clipboardWriteCommands() []desktopCommand {
+	return []desktopCommand{powershell("[Console]::In.ReadToEnd() | Set-Clipboard")}
+}
+
+func screenshotCommands() []desktopCommand {
+	return []desktopCommand{powershell(`Add-Type -AssemblyName System.Windows.Forms, System.Drawing
+$b = [System.Windows.Forms.SystemInformation]::VirtualScreen
+$bmp = New-Object System.Drawing.Bitmap $b.Width, $b.Height
+$g = [System.Drawing.Graphics]::FromImage($bmp)
+$g.CopyFromScreen($b.Left, $b.Top, 0, 0, $bmp.Size)
+$bmp.Save('` + desktopFileArg + `', [System.Drawing.Imaging.ImageFormat]::Png)`)}
+}
@@ -0,0 +1,253 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxDiffEdits bounds the search for a shortest edit script. Inputs that differ
+// by more edits still get a correct diff, but the rest of it is reported as one
+// replacement instead of a minimal one.
+const maxDiffEdits = 2000
+
+// Hunk is one hunk of a unified diff. Lines carry the unified diff prefix:
+// ' ' for context, '-' for a removed line and '+' for an added line.
+type Hunk struct {
+	OldStart int      `json:"oldStart"` // 1-based; the line before the hunk if OldLines is 0
+	OldLines int      `json:"oldLines"`
+	NewStart int      `json:"newStart"`
+	NewLines int      `json:"newLines"`
+	Lines    []string `json:"lines"`
+	// NoNewline lists indexes into Lines of lines that end without a newline.
+	NoNewline []int `json:"noNewline,omitempty"`
+}
+
+// Header returns the hunk's "@@ -l,s +l,s @@" line.
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.OldStart, h.OldLines), hunkRange(h.NewStart, h.NewLines))
+}
+
+func hunkRange(start, lines int) string {
+	if lines == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, lines)
+}
+
+// diffOp is one line of an edit script.
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// splitLines splits s after each newline; the last line may lack one.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Diff compares two texts line by line and returns the hunks of their unified
+// diff with the given number of context lines. Equal texts give no hunks.
+func Diff(oldText, newText string, context int) []Hunk {
+	return hunks(diffLines(splitLines(oldText), splitLines(newText)), max(context, 0))
+}
+
+// diffLines returns an edit script turning a into b. The common prefix and
+// suffix are split off first, so small changes to large texts stay cheap.
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	var ops []diffOp
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	ops = append(ops, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+// myers finds a shortest edit script with Myers' O(ND) algorithm, keeping the
+// frontier of every round to trace the path back. Beyond maxDiffEdits rounds it
+// gives up and replaces a with b.
+func myers(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+	for d := 0; d <= n+m; d++ {
+		if d > maxDiffEdits {
+			return replaceAll(a, b)
+		}
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1] // Down: insert from b
+			} else {
+				x = v[offset+k-1] + 1 // Right: delete from a
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace, d)
+			}
+		}
+	}
+	return replaceAll(a, b) // Not reached
+}
+
+// backtrack walks the recorded frontiers back from (len(a), len(b)) and
+// returns the edit script in order. trace[d] holds the frontier before round d
+// for diagonals -d..d, at index k+d.
+func backtrack(a, b []string, trace [][]int, d int) []diffOp {
+	var ops []diffOp
+	x, y := len(a), len(b)
+	for ; d > 0; d-- {
+		prev := trace[d]
+		k := x - y
+		down := k == -d || (k != d && prev[k-1+d] < prev[k+1+d])
+		var startX int // Where this round's snake began
+		if down {
+			startX = prev[k+1+d]
+		} else {
+			startX = prev[k-1+d] + 1
+		}
+		for x > startX {
+			x, y = x-1, y-1
+			ops = append(ops, diffOp{' ', a[x]})
+		}
+		if down {
+			y--
+			ops = append(ops, diffOp{'+', b[y]})
+		} else {
+			x--
+			ops = append(ops, diffOp{'-', a[x]})
+		}
+	}
+	for x > 0 {
+		x--
+		ops = append(ops, diffOp{' ', a[x]})
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// replaceAll is the edit script deleting all of a and inserting all of b.
+func replaceAll(a, b []string) []diffOp {
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, line := range a {
+		ops = append(ops, diffOp{'-', line})
+	}
+	for _, line := range b {
+		ops = append(ops, diffOp{'+', line})
+	}
+	return ops
+}
+
+// hunks groups the changes of an edit script into hunks with context lines
+// around them; changes closer than 2*context lines share a hunk.
+func hunks(ops []diffOp, context int) []Hunk {
+	var result []Hunk
+	oldLine, newLine := 1, 1 // Line numbers of ops[i] in either text
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			oldLine, newLine, i = oldLine+1, newLine+1, i+1
+			continue
+		}
+		// A change: back up over the leading context
+		lead := 0
+		for lead < context && i-lead > 0 && ops[i-lead-1].kind == ' ' {
+			lead++
+		}
+		start := i - lead
+		h := Hunk{OldStart: oldLine - lead, NewStart: newLine - lead}
+		// Take in the following changes separated by at most 2*context equal lines
+		last := i
+		for j := i + 1; j < len(ops); {
+			if ops[j].kind != ' ' {
+				last, j = j, j+1
+				continue
+			}
+			run := 0
+			for j+run < len(ops) && ops[j+run].kind == ' ' {
+				run++
+			}
+			if j+run == len(ops) || run > 2*context {
+				break
+			}
+			j += run
+		}
+		end := min(last+1+context, len(ops))
+		for j := start; j < end; j++ {
+			op := ops[j]
+			if op.kind != '+' {
+				h.OldLines++
+			}
+			if op.kind != '-' {
+				h.NewLines++
+			}
+			line := strings.TrimSuffix(op.line, "\n")
+			if line == op.line {
+				h.NoNewline = append(h.NoNewline, len(h.Lines))
+			}
+			h.Lines = append(h.Lines, string(op.kind)+line)
+		}
+		// The line before an empty side, as diff reports it
+		if h.OldLines == 0 {
+			h.OldStart--
+		}
+		if h.NewLines == 0 {
+			h.NewStart--
+		}
+		result = append(result, h)
+		for ; i < end; i++ {
+			if ops[i].kind != '+' {
+				oldLine++
+			}
+			if ops[i].kind != '-' {
+				newLine++
+			}
+		}
+	}
+	return result
+}
+
+// FormatUnified renders hunks as a unified diff between the texts named oldName
+// and newName. It returns "" if there are no hunks.
+func FormatUnified(oldName, newName string, hunks []Hunk) string {
+	if len(hunks) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+	for _, h := range hunks {
+		b.WriteString(h.Header() + "\n")
+		noNewline := make(map[int]bool, len(h.NoNewline))
+		for _, i := range h.NoNewline {
+			noNewline[i] = true
+		}
+		for i, line := range h.Lines {
+			b.WriteString(line + "\n")
+			if noNewline[i] {
+				b.WriteString("\\ No newline at end of file\n")
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,135 @@
+package tools
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestDiffUnified(t *testing.T) {
+	oldText := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\n"
+	newText := "a\nB\nc\nd\ne\nf\ng\nh\ni\nk\nl"
+	want := `--- old
++++ new
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -7,5 +7,5 @@
+ g
+ h
+ i
+-j
+ k
++l
+\ No newline at end of file
+`
+	if got := FormatUnified("old", "new", Diff(oldText, newText, 3)); got != want {
+		t.Errorf("unified diff:\n%s\nwant:\n%s", got, want)
+	}
+	// With more context the two changes share one hunk
+	if hunks := Diff(oldText, newText, 5); len(hunks) != 1 || hunks[0].Header() != "@@ -1,11 +1,11 @@" {
+		t.Errorf("context 5: %+v", hunks)
+	}
+	if hunks := Diff(oldText, oldText, 3); len(hunks) != 0 {
+		t.Errorf("equal texts: %+v", hunks)
+	}
+}
+
+func TestDiffEmptySides(t *testing.T) {
+	tests := []struct{ old, new, header string }{
+		{"", "x\ny\n", "@@ -0,0 +1,2 @@"},
+		{"x\n", "", "@@ -1 +0,0 @@"},
+		{"a\nb\n", "a\nx\nb\n", "@@ -1,2 +1,3 @@"},
+	}
+	for _, tt := range tests {
+		hunks := Diff(tt.old, tt.new, 3)
+		if len(hunks) != 1 || hunks[0].Header() != tt.header {
+			t.Errorf("Diff(%q, %q) = %+v, want one hunk %s", tt.old, tt.new, hunks, tt.header)
+		}
+	}
+}
+
+// applyHunks rebuilds the new text from the old one and the hunks.
+func applyHunks(oldText string, hunks []Hunk) string {
+	oldLines := splitLines(oldText)
+	var out []string
+	next := 0 // Index of the next old line to copy
+	for _, h := range hunks {
+		start := h.OldStart - 1
+		if h.OldLines == 0 {
+			start++
+		}
+		out = append(out, oldLines[next:start]...)
+		noNewline := map[int]bool{}
+		for _, i := range h.NoNewline {
+			noNewline[i] = true
+		}
+		for i, line := range h.Lines {
+			text := line[1:]
+			if !noNewline[i] {
+				text += "\n"
+			}
+			if line[0] != '-' {
+				out = append(out, text)
+			}
+		}
+		next = start + h.OldLines
+	}
+	return strings.Join(append(out, oldLines[next:]...), "")
+}
+
+func TestDiffRandomRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	random := func() string {
+		lines := make([]string, rng.Intn(30))
+		for i := range lines {
+			lines[i] = string(rune('a' + rng.Intn(4)))
+		}
+		text := strings.Join(lines, "\n")
+		if rng.Intn(2) == 0 && text != "" {
+			text += "\n"
+		}
+		return text
+	}
+	for i := 0; i < 2000; i++ {
+		oldText, newText := random(), random()
+		context := rng.Intn(4)
+		if got := applyHunks(oldText, Diff(oldText, newText, context)); got != newText {
+			t.Fatalf("Diff(%q, %q, %d) does not apply: got %q", oldText, newText, context, got)
+		}
+	}
+}
+
+func TestDiffMinimal(t *testing.T) {
+	// One line changed in the middle of repeated lines: exactly one - and one +
+	oldText := strings.Repeat("x\n", 50) + "old\n" + strings.Repeat("x\n", 50)
+	newText := strings.Repeat("x\n", 50) + "new\n" + strings.Repeat("x\n", 50)
+	changes := 0
+	for _, h := range Diff(oldText, newText, 3) {
+		for _, line := range h.Lines {
+			if line[0] != ' ' {
+				changes++
+			}
+		}
+	}
+	if changes != 2 {
+		t.Errorf("%d changed lines, want 2", changes)
+	}
+	if ops := myers([]string{"a", "b", "c", "a", "b", "b", "a"}, []string{"c", "b", "a", "b", "a", "c"}); countEdits(ops) != 5 {
+		t.Errorf("myers found %d edits, want the minimum of 5", countEdits(ops))
+	}
+}
+
+func countEdits(ops []diffOp) int {
+	n := 0
+	for _, op := range ops {
+		if op.kind != ' ' {
+			n++
+		}
+	}
+	return n
+}
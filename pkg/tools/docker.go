@@ -0,0 +1,182 @@
+package tools
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultDockerHost is the Docker Engine API address used when none is configured.
+const DefaultDockerHost = "unix:///var/run/docker.sock"
+
+// dockerAPIVersion is the Engine API version requested; 1.41 is Docker 20.10.
+const dockerAPIVersion = "v1.41"
+
+// DockerClient makes read-only calls to the Docker Engine API.
+type DockerClient struct {
+	http    *http.Client
+	baseURL string
+}
+
+// NewDockerClient returns a client for host, a unix:// socket path or a tcp:// address.
+func NewDockerClient(host string, timeout time.Duration) (*DockerClient, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid docker host %q: %w", host, err)
+	}
+	transport := &http.Transport{}
+	baseURL := "http://docker/" + dockerAPIVersion
+	switch u.Scheme {
+	case "unix":
+		socket := u.Path
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		}
+	case "tcp":
+		baseURL = "http://" + u.Host + "/" + dockerAPIVersion
+	default:
+		return nil, fmt.Errorf("unsupported docker host scheme %q (want unix or tcp)", u.Scheme)
+	}
+	return &DockerClient{http: &http.Client{Transport: transport, Timeout: timeout}, baseURL: baseURL}, nil
+}
+
+// DockerContainer is a container as listed by DockerClient.Containers.
+type DockerContainer struct {
+	ID      string            `json:"Id"`
+	Names   []string          `json:"Names"`
+	Image   string            `json:"Image"`
+	Command string            `json:"Command"`
+	Created int64             `json:"Created"`
+	State   string            `json:"State"`
+	Status  string            `json:"Status"`
+	Labels  map[string]string `json:"Labels"`
+}
+
+// Name returns the container's primary name without the leading slash.
+func (c DockerContainer) Name() string {
+	if len(c.Names) == 0 {
+		return ""
+	}
+	return strings.TrimPrefix(c.Names[0], "/")
+}
+
+// get performs a GET request against the API and returns the response body,
+// limited to MaxCommandOutput bytes. Non-2xx responses become errors carrying
+// the API's message.
+func (d *DockerClient) get(path string, query url.Values) ([]byte, error) {
+	target := d.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	resp, err := d.http.Get(target)
+	if err != nil {
+		return nil, fmt.Errorf("docker API unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MaxCommandOutput))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read docker API response: %w", err)
+	}
+	if resp.StatusCode/100 != 2 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Message != "" {
+			return nil, fmt.Errorf("docker API: %s", apiErr.Message)
+		}
+		return nil, fmt.Errorf("docker API: %s", resp.Status)
+	}
+	return body, nil
+}
+
+// Ping checks that the API answers and returns the server's version.
+func (d *DockerClient) Ping() (string, error) {
+	body, err := d.get("/version", nil)
+	if err != nil {
+		return "", err
+	}
+	var version struct {
+		Version    string `json:"Version"`
+		APIVersion string `json:"ApiVersion"`
+	}
+	if err := json.Unmarshal(body, &version); err != nil {
+		return "", fmt.Errorf("invalid docker version response: %w", err)
+	}
+	return fmt.Sprintf("Docker %s (API %s)", version.Version, version.APIVersion), nil
+}
+
+// Containers lists running containers, or all containers if all is set.
+func (d *DockerClient) Containers(all bool) ([]DockerContainer, error) {
+	body, err := d.get("/containers/json", url.Values{"all": {strconv.FormatBool(all)}})
+	if err != nil {
+		return nil, err
+	}
+	var containers []DockerContainer
+	if err := json.Unmarshal(body, &containers); err != nil {
+		return nil, fmt.Errorf("invalid container list: %w", err)
+	}
+	return containers, nil
+}
+
+// Inspect returns the low-level information about a container as raw JSON.
+func (d *DockerClient) Inspect(id string) (json.RawMessage, error) {
+	return d.get("/containers/"+url.PathEscape(id)+"/json", nil)
+}
+
+// DockerLogOptions selects the log lines returned by DockerClient.Logs.
+type DockerLogOptions struct {
+	Tail       int    // Number of most recent lines; all if 0
+	Since      string // Unix timestamp or Go duration relative to now, e.g. 10m
+	Timestamps bool
+}
+
+// Logs returns a container's combined stdout and stderr.
+func (d *DockerClient) Logs(id string, opts DockerLogOptions) (string, error) {
+	query := url.Values{"stdout": {"true"}, "stderr": {"true"}, "timestamps": {strconv.FormatBool(opts.Timestamps)}}
+	if opts.Tail > 0 {
+		query.Set("tail", strconv.Itoa(opts.Tail))
+	}
+	if opts.Since != "" {
+		if since, err := time.ParseDuration(opts.Since); err == nil {
+			query.Set("since", strconv.FormatInt(time.Now().Add(-since).Unix(), 10))
+		} else {
+			query.Set("since", opts.Since)
+		}
+	}
+	body, err := d.get("/containers/"+url.PathEscape(id)+"/logs", query)
+	if err != nil {
+		return "", err
+	}
+	return demuxDockerLogs(body), nil
+}
+
+// demuxDockerLogs strips the stream headers Docker puts in front of every log
+// chunk of containers without a TTY: one byte stream type (0-2), three zero bytes
+// and a big-endian uint32 length. Output of TTY containers is returned unchanged.
+func demuxDockerLogs(data []byte) string {
+	var out strings.Builder
+	for rest := data; len(rest) > 0; {
+		if len(rest) < 8 || rest[0] > 2 || rest[1] != 0 || rest[2] != 0 || rest[3] != 0 {
+			if out.Len() == 0 {
+				return string(data) // Not multiplexed
+			}
+			out.Write(rest) // Truncated by the size limit
+			break
+		}
+		size := int(binary.BigEndian.Uint32(rest[4:8]))
+		rest = rest[8:]
+		size = min(size, len(rest))
+		out.Write(rest[:size])
+		rest = rest[size:]
+	}
+	return out.String()
+}
@@ -0,0 +1,279 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Filter reports whether a table row matches a filter expression.
+type Filter func(row []Value) bool
+
+// CompileFilter compiles a filter expression against a table's columns, which
+// resolve maps to column indexes. The language is deliberately small:
+//
+//	expr    = term { "or" term }
+//	term    = factor { "and" factor }
+//	factor  = "not" factor | "(" expr ")" | operand op operand | operand "is" ["not"] "null"
+//	op      = "=" | "!=" | "<" | "<=" | ">" | ">=" | "contains"
+//	operand = column | number | 'string' | "string"
+//
+// Columns are bare names or `back-quoted` for names with spaces. Values compare
+// numerically when both sides are numbers and as text otherwise; contains is a
+// case-insensitive substring test. Keywords are case-insensitive. A comparison
+// with a null cell is false.
+func CompileFilter(expr string, resolve func(column string) (int, error)) (Filter, error) {
+	tokens, err := tokenizeFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{tokens: tokens, resolve: resolve}
+	filter, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEnd {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+	}
+	return filter, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEnd    tokenKind = iota
+	tokenWord             // Column name or keyword
+	tokenColumn           // Back-quoted column name
+	tokenString
+	tokenNumber
+	tokenOp
+	tokenOpen
+	tokenClose
+)
+
+type filterToken struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// tokenizeFilter splits a filter expression into tokens.
+func tokenizeFilter(expr string) ([]filterToken, error) {
+	var tokens []filterToken
+	runes := []rune(expr)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		start := i
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case r == '(':
+			tokens = append(tokens, filterToken{tokenOpen, "(", start})
+			i++
+		case r == ')':
+			tokens = append(tokens, filterToken{tokenClose, ")", start})
+			i++
+		case r == '\'' || r == '"' || r == '`':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("unterminated %c at position %d", r, start)
+			}
+			kind := tokenString
+			if r == '`' {
+				kind = tokenColumn
+			}
+			tokens = append(tokens, filterToken{kind, string(runes[i+1 : end]), start})
+			i = end + 1
+		case strings.ContainsRune("=!<>", r):
+			op := string(r)
+			i++
+			if i < len(runes) && runes[i] == '=' {
+				i++
+				if r != '=' { // "==" is the same as "="
+					op += "="
+				}
+			}
+			if op == "!" {
+				return nil, fmt.Errorf("unexpected '!' at position %d", start)
+			}
+			tokens = append(tokens, filterToken{tokenOp, op, start})
+		case unicode.IsDigit(r) || ((r == '-' || r == '.') && i+1 < len(runes) && (unicode.IsDigit(runes[i+1]) || runes[i+1] == '.')):
+			end := i + 1
+			for end < len(runes) && (unicode.IsDigit(runes[end]) || strings.ContainsRune(".eE", runes[end]) ||
+				((runes[end] == '-' || runes[end] == '+') && (runes[end-1] == 'e' || runes[end-1] == 'E'))) {
+				end++
+			}
+			tokens = append(tokens, filterToken{tokenNumber, string(runes[i:end]), start})
+			i = end
+		case unicode.IsLetter(r) || r == '_':
+			end := i + 1
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || strings.ContainsRune("_.-", runes[end])) {
+				end++
+			}
+			tokens = append(tokens, filterToken{tokenWord, string(runes[i:end]), start})
+			i = end
+		default:
+			return nil, fmt.Errorf("unexpected %q at position %d", r, start)
+		}
+	}
+	return append(tokens, filterToken{tokenEnd, "end of expression", len(runes)}), nil
+}
+
+// filterParser is a recursive descent parser producing Filter closures.
+type filterParser struct {
+	tokens  []filterToken
+	pos     int
+	resolve func(column string) (int, error)
+}
+
+func (p *filterParser) peek() filterToken { return p.tokens[p.pos] }
+
+func (p *filterParser) next() filterToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEnd {
+		p.pos++
+	}
+	return tok
+}
+
+// keyword reports whether the next token is the given keyword, consuming it if so.
+func (p *filterParser) keyword(word string) bool {
+	if tok := p.peek(); tok.kind == tokenWord && strings.EqualFold(tok.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) expr() (Filter, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(row []Value) bool { return l(row) || right(row) }
+	}
+	return left, nil
+}
+
+func (p *filterParser) term() (Filter, error) {
+	left, err := p.factor()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(row []Value) bool { return l(row) && right(row) }
+	}
+	return left, nil
+}
+
+func (p *filterParser) factor() (Filter, error) {
+	if p.keyword("not") {
+		inner, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		return func(row []Value) bool { return !inner(row) }, nil
+	}
+	if p.peek().kind == tokenOpen {
+		p.next()
+		inner, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if tok := p.next(); tok.kind != tokenClose {
+			return nil, fmt.Errorf("expected ')' at position %d, found %q", tok.pos, tok.text)
+		}
+		return inner, nil
+	}
+
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	if p.keyword("is") {
+		negate := p.keyword("not")
+		if !p.keyword("null") {
+			tok := p.peek()
+			return nil, fmt.Errorf("expected null at position %d, found %q", tok.pos, tok.text)
+		}
+		return func(row []Value) bool { return left(row).Null != negate }, nil
+	}
+	tok := p.next()
+	op := tok.text
+	switch {
+	case tok.kind == tokenOp:
+	case tok.kind == tokenWord && strings.EqualFold(tok.text, "contains"):
+		op = "contains"
+	default:
+		return nil, fmt.Errorf("expected a comparison operator at position %d, found %q", tok.pos, tok.text)
+	}
+	right, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	return func(row []Value) bool {
+		a, b := left(row), right(row)
+		if a.Null || b.Null {
+			return false
+		}
+		if op == "contains" {
+			return strings.Contains(strings.ToLower(a.Text), strings.ToLower(b.Text))
+		}
+		c := compareValues(a, b)
+		switch op {
+		case "=":
+			return c == 0
+		case "!=":
+			return c != 0
+		case "<":
+			return c < 0
+		case "<=":
+			return c <= 0
+		case ">":
+			return c > 0
+		default: // ">="
+			return c >= 0
+		}
+	}, nil
+}
+
+// operand parses a column reference or a literal into a function returning its value for a row.
+func (p *filterParser) operand() (func(row []Value) Value, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenWord, tokenColumn:
+		index, err := p.resolve(tok.text)
+		if err != nil {
+			return nil, err
+		}
+		return func(row []Value) Value { return row[index] }, nil
+	case tokenNumber:
+		v := textValue(tok.text)
+		if !v.IsNum {
+			return nil, fmt.Errorf("invalid number %q at position %d", tok.text, tok.pos)
+		}
+		return func([]Value) Value { return v }, nil
+	case tokenString:
+		v := textValue(tok.text)
+		if v.Null {
+			v = Value{} // '' compares as empty text, not null
+		}
+		return func([]Value) Value { return v }, nil
+	}
+	return nil, fmt.Errorf("expected a column or value at position %d, found %q", tok.pos, tok.text)
+}
@@ -0,0 +1,30 @@
+package tools
+
+import (
+	"context"
+	"time"
+)
+
+// KubectlCommand is the external program Kubectl runs; it must be on the PATH.
+const KubectlCommand = "kubectl"
+
+// Kubectl runs kubectl against the cluster selected by Kubeconfig and Context.
+// Empty fields leave the choice to kubectl's own defaults ($KUBECONFIG,
+// ~/.kube/config and its current context).
+type Kubectl struct {
+	Kubeconfig string
+	Context    string
+	Timeout    time.Duration
+}
+
+// Run runs kubectl with args and returns its standard output, stopping it
+// when ctx ends. Callers are responsible for only passing read-only subcommands.
+func (k Kubectl) Run(ctx context.Context, args ...string) (string, error) {
+	if k.Kubeconfig != "" {
+		args = append([]string{"--kubeconfig=" + k.Kubeconfig}, args...)
+	}
+	if k.Context != "" {
+		args = append([]string{"--context=" + k.Context}, args...)
+	}
+	return runCommand(ctx, k.Timeout, KubectlCommand, args...)
+}
@@ -0,0 +1,48 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// PingCommand is the external program PingHost runs; it must be on the PATH.
+const PingCommand = "ping"
+
+// PingHost pings host once and returns ping's output. The command is stopped
+// (see stopGracefully) when ctx ends or after timeout, with a *CanceledError.
+func PingHost(ctx context.Context, host string, timeout time.Duration) (string, error) {
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	// Use -c 1 for Linux/macOS to send only one packet
+	// Use -W 1 for a 1-second wait time for the reply (adjust if needed)
+	// Consider using platform-specific flags if necessary or a go ping library
+	cmd := exec.CommandContext(runCtx, PingCommand, "-c", "1", "-W", "1", host)
+	stopGracefully(cmd)
+
+	var out bytes.Buffer
+	var stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+
+	err := cmd.Start()
+	if err != nil {
+		return "", fmt.Errorf("failed to start ping command: %w", err)
+	}
+
+	// Wait for the command to finish or be stopped
+	err = cmd.Wait()
+	output := out.String() + stderr.String()
+	if err != nil && runCtx.Err() != nil {
+		return "", canceled(ctx, PingCommand, timeout, output)
+	}
+	if err != nil {
+		// Ping might return non-zero exit code even if it gets output (e.g., packet loss)
+		// We return the output along with the error in this case.
+		return strings.TrimSpace(output), fmt.Errorf("ping command failed with exit code: %w. Output: %s", err, output)
+	}
+	return strings.TrimSpace(output), nil
+}
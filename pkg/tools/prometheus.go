@@ -0,0 +1,249 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PrometheusClient runs PromQL queries against the Prometheus HTTP API.
+type PrometheusClient struct {
+	http        *http.Client
+	baseURL     string
+	bearerToken string
+}
+
+// NewPrometheusClient returns a client for the Prometheus server at baseURL,
+// e.g. http://localhost:9090. A non-empty bearerToken is sent with every request.
+func NewPrometheusClient(baseURL, bearerToken string, timeout time.Duration) (*PrometheusClient, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid Prometheus URL %q: want http(s)://host[:port][/path]", baseURL)
+	}
+	return &PrometheusClient{
+		http:        &http.Client{Timeout: timeout},
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		bearerToken: bearerToken,
+	}, nil
+}
+
+// Sample is one value of a series.
+type Sample struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// MarshalJSON encodes NaN and infinities, which JSON numbers cannot hold, as strings.
+func (s Sample) MarshalJSON() ([]byte, error) {
+	var value interface{} = s.Value
+	if math.IsNaN(s.Value) || math.IsInf(s.Value, 0) {
+		value = strconv.FormatFloat(s.Value, 'f', -1, 64)
+	}
+	return json.Marshal(struct {
+		Time  time.Time   `json:"time"`
+		Value interface{} `json:"value"`
+	}{s.Time, value})
+}
+
+// Series is a labelled series of samples; instant queries return one sample per series.
+type Series struct {
+	Labels  map[string]string `json:"labels"`
+	Samples []Sample          `json:"samples"`
+}
+
+// LabelString formats the labels the way Prometheus does: name{k="v", ...}.
+func (s Series) LabelString() string {
+	names := make([]string, 0, len(s.Labels))
+	for k := range s.Labels {
+		if k != "__name__" {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	pairs := make([]string, len(names))
+	for i, k := range names {
+		pairs[i] = fmt.Sprintf("%s=%q", k, s.Labels[k])
+	}
+	return s.Labels["__name__"] + "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// QueryResult is the normalized result of a query.
+type QueryResult struct {
+	ResultType string   `json:"resultType"` // vector, matrix or scalar
+	Series     []Series `json:"series"`
+	Warnings   []string `json:"warnings,omitempty"`
+}
+
+// Query runs an instant query at t (now if zero).
+func (p *PrometheusClient) Query(query string, t time.Time) (*QueryResult, error) {
+	params := url.Values{"query": {query}}
+	if !t.IsZero() {
+		params.Set("time", formatPromTime(t))
+	}
+	return p.do("/api/v1/query", params)
+}
+
+// QueryRange runs a range query from start to end with the given resolution step.
+func (p *PrometheusClient) QueryRange(query string, start, end time.Time, step time.Duration) (*QueryResult, error) {
+	params := url.Values{
+		"query": {query},
+		"start": {formatPromTime(start)},
+		"end":   {formatPromTime(end)},
+		"step":  {strconv.FormatFloat(step.Seconds(), 'f', -1, 64)},
+	}
+	return p.do("/api/v1/query_range", params)
+}
+
+// BuildInfo returns the server's version, to check that the endpoint works.
+func (p *PrometheusClient) BuildInfo() (string, error) {
+	req, err := http.NewRequest(http.MethodGet, p.baseURL+"/api/v1/status/buildinfo", nil)
+	if err != nil {
+		return "", err
+	}
+	body, err := p.send(req)
+	if err != nil {
+		return "", err
+	}
+	var info struct {
+		Data struct {
+			Version string `json:"version"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &info); err != nil {
+		return "", fmt.Errorf("invalid buildinfo response: %w", err)
+	}
+	return "Prometheus " + info.Data.Version, nil
+}
+
+// do posts a query and decodes the result.
+func (p *PrometheusClient) do(path string, params url.Values) (*QueryResult, error) {
+	req, err := http.NewRequest(http.MethodPost, p.baseURL+path, strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	body, err := p.send(req)
+	if err != nil {
+		return nil, err
+	}
+	return parsePromResponse(body)
+}
+
+// send performs a request and returns the body of a successful response. API
+// errors, which Prometheus reports with 4xx/5xx statuses, carry its message.
+func (p *PrometheusClient) send(req *http.Request) ([]byte, error) {
+	if p.bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+p.bearerToken)
+	}
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("prometheus unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4*MaxCommandOutput))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prometheus response: %w", err)
+	}
+	if resp.StatusCode/100 != 2 {
+		var apiErr struct {
+			ErrorType string `json:"errorType"`
+			Error     string `json:"error"`
+		}
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Error != "" {
+			return nil, fmt.Errorf("prometheus %s error: %s", apiErr.ErrorType, apiErr.Error)
+		}
+		return nil, fmt.Errorf("prometheus: %s", resp.Status)
+	}
+	return body, nil
+}
+
+// parsePromResponse converts the API's result encoding, where samples are
+// [unixSeconds, "value"] pairs, into a QueryResult.
+func parsePromResponse(body []byte) (*QueryResult, error) {
+	var resp struct {
+		Status   string   `json:"status"`
+		Warnings []string `json:"warnings"`
+		Data     struct {
+			ResultType string          `json:"resultType"`
+			Result     json.RawMessage `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil || resp.Status != "success" {
+		return nil, fmt.Errorf("invalid prometheus response: %.200s", body)
+	}
+	result := &QueryResult{ResultType: resp.Data.ResultType, Series: []Series{}, Warnings: resp.Warnings}
+
+	type rawSeries struct {
+		Metric map[string]string    `json:"metric"`
+		Value  [2]json.RawMessage   `json:"value"`
+		Values [][2]json.RawMessage `json:"values"`
+	}
+	switch resp.Data.ResultType {
+	case "vector", "matrix":
+		var series []rawSeries
+		if err := json.Unmarshal(resp.Data.Result, &series); err != nil {
+			return nil, fmt.Errorf("invalid %s result: %w", resp.Data.ResultType, err)
+		}
+		for _, raw := range series {
+			pairs := raw.Values
+			if resp.Data.ResultType == "vector" {
+				pairs = [][2]json.RawMessage{raw.Value}
+			}
+			s := Series{Labels: raw.Metric, Samples: make([]Sample, 0, len(pairs))}
+			if s.Labels == nil {
+				s.Labels = map[string]string{}
+			}
+			for _, pair := range pairs {
+				sample, err := parsePromSample(pair)
+				if err != nil {
+					return nil, err
+				}
+				s.Samples = append(s.Samples, sample)
+			}
+			result.Series = append(result.Series, s)
+		}
+	case "scalar":
+		var pair [2]json.RawMessage
+		if err := json.Unmarshal(resp.Data.Result, &pair); err != nil {
+			return nil, fmt.Errorf("invalid %s result: %w", resp.Data.ResultType, err)
+		}
+		sample, err := parsePromSample(pair)
+		if err != nil {
+			return nil, err
+		}
+		result.Series = append(result.Series, Series{Labels: map[string]string{}, Samples: []Sample{sample}})
+	default:
+		return nil, fmt.Errorf("unsupported result type %q", resp.Data.ResultType)
+	}
+	return result, nil
+}
+
+// parsePromSample parses a [unixSeconds, "value"] pair.
+func parsePromSample(pair [2]json.RawMessage) (Sample, error) {
+	var ts float64
+	var value string
+	if err := json.Unmarshal(pair[0], &ts); err != nil {
+		return Sample{}, fmt.Errorf("invalid sample time %s", pair[0])
+	}
+	if err := json.Unmarshal(pair[1], &value); err != nil {
+		return Sample{}, fmt.Errorf("invalid sample value %s", pair[1])
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return Sample{}, fmt.Errorf("invalid sample value %q", value)
+	}
+	sec, frac := math.Modf(ts)
+	return Sample{Time: time.Unix(int64(sec), int64(frac*1e9)).UTC().Round(time.Millisecond), Value: f}, nil
+}
+
+// formatPromTime formats t as the API's unix seconds with millisecond precision.
+func formatPromTime(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixMilli())/1000, 'f', -1, 64)
+}
@@ -0,0 +1,496 @@
+package tools
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Value is one cell of a Table. Text is always set (empty for nulls); Num holds
+// the numeric value when the text parses as a number.
+type Value struct {
+	Text  string
+	Num   float64
+	IsNum bool
+	Null  bool
+}
+
+// textValue makes a Value from text, recognizing numbers. Empty text is null.
+func textValue(s string) Value {
+	if s == "" {
+		return Value{Null: true}
+	}
+	if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil && !math.IsNaN(f) && !math.IsInf(f, 0) {
+		return Value{Text: s, Num: f, IsNum: true}
+	}
+	return Value{Text: s}
+}
+
+// numValue makes a Value from a computed number.
+func numValue(f float64) Value {
+	return Value{Text: strconv.FormatFloat(f, 'f', -1, 64), Num: f, IsNum: true}
+}
+
+// Interface returns the value as it should appear in JSON: a number, a string or nil.
+func (v Value) Interface() interface{} {
+	switch {
+	case v.Null:
+		return nil
+	case v.IsNum:
+		return v.Num
+	default:
+		return v.Text
+	}
+}
+
+// Table is tabular data loaded from a CSV or JSON file.
+type Table struct {
+	Columns []string
+	Rows    [][]Value
+}
+
+// TableFormat returns the format LoadTable should use for a file name: "csv",
+// "tsv" or "json", or "" if the extension is not recognized.
+func TableFormat(name string) string {
+	switch {
+	case strings.HasSuffix(strings.ToLower(name), ".csv"):
+		return "csv"
+	case strings.HasSuffix(strings.ToLower(name), ".tsv"):
+		return "tsv"
+	case strings.HasSuffix(strings.ToLower(name), ".json"):
+		return "json"
+	}
+	return ""
+}
+
+// LoadTable reads a table in the given format. CSV and TSV files must have a
+// header row. JSON files must hold an array of objects; the columns are the
+// object keys in order of first appearance, and nested values are kept as JSON text.
+func LoadTable(r io.Reader, format string) (*Table, error) {
+	switch format {
+	case "csv", "tsv":
+		return loadDelimited(r, format == "tsv")
+	case "json":
+		return loadJSON(r)
+	}
+	return nil, fmt.Errorf("unsupported table format %q (want csv, tsv or json)", format)
+}
+
+func loadDelimited(r io.Reader, tabs bool) (*Table, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1 // Short rows are padded with nulls below
+	if tabs {
+		reader.Comma = '\t'
+	}
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid header: %w", err)
+	}
+	table := &Table{Columns: header}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return table, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := make([]Value, len(header))
+		for i := range row {
+			if i < len(record) {
+				row[i] = textValue(record[i])
+			} else {
+				row[i] = Value{Null: true}
+			}
+		}
+		table.Rows = append(table.Rows, row)
+	}
+}
+
+func loadJSON(r io.Reader) (*Table, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return nil, errors.New("JSON table must be an array of objects")
+	}
+	table := &Table{}
+	index := make(map[string]int)
+	var objects []map[string]Value
+	for dec.More() {
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+			return nil, fmt.Errorf("row %d is not an object", len(objects)+1)
+		}
+		object := make(map[string]Value)
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key := tok.(string) // Object keys are always strings
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				return nil, err
+			}
+			if _, ok := index[key]; !ok {
+				index[key] = len(table.Columns)
+				table.Columns = append(table.Columns, key)
+			}
+			object[key] = jsonValue(raw)
+		}
+		if _, err := dec.Token(); err != nil { // Closing brace
+			return nil, err
+		}
+		objects = append(objects, object)
+	}
+	for _, object := range objects {
+		row := make([]Value, len(table.Columns))
+		for i, column := range table.Columns {
+			if v, ok := object[column]; ok {
+				row[i] = v
+			} else {
+				row[i] = Value{Null: true}
+			}
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table, nil
+}
+
+// jsonValue converts a JSON value to a cell: strings and numbers as text, null
+// as null, anything else as its JSON text.
+func jsonValue(raw json.RawMessage) Value {
+	var s string
+	switch {
+	case string(raw) == "null":
+		return Value{Null: true}
+	case json.Unmarshal(raw, &s) == nil:
+		if s == "" {
+			return Value{Text: ""} // An explicit empty string is not null
+		}
+		return textValue(s)
+	}
+	return textValue(string(raw))
+}
+
+// column returns the index of the named column.
+func (t *Table) column(name string) (int, error) {
+	for i, c := range t.Columns {
+		if c == name {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown column %q (columns: %s)", name, strings.Join(t.Columns, ", "))
+}
+
+// TableQuery selects, filters and aggregates the rows of a Table.
+type TableQuery struct {
+	// Columns to return; all if empty. Not allowed with Aggregates.
+	Columns []string
+	// Where is a filter expression, see CompileFilter.
+	Where string
+	// GroupBy lists the columns to group by when aggregating.
+	GroupBy []string
+	// Aggregates are count, count(*), count(col), sum(col), avg(col), min(col) and max(col).
+	Aggregates []string
+	// OrderBy is a result column to sort by, prefixed with "-" for descending order.
+	OrderBy string
+}
+
+// Query runs q against the table and returns the result as a new table.
+func (t *Table) Query(q TableQuery) (*Table, error) {
+	rows := t.Rows
+	if strings.TrimSpace(q.Where) != "" {
+		filter, err := CompileFilter(q.Where, t.column)
+		if err != nil {
+			return nil, err
+		}
+		rows = nil
+		for _, row := range t.Rows {
+			if filter(row) {
+				rows = append(rows, row)
+			}
+		}
+	}
+
+	var result *Table
+	var err error
+	switch {
+	case len(q.Aggregates) > 0 || len(q.GroupBy) > 0:
+		if len(q.Columns) > 0 {
+			return nil, errors.New("columns cannot be combined with aggregates; list the columns in group_by instead")
+		}
+		result, err = t.aggregate(rows, q.GroupBy, q.Aggregates)
+	default:
+		result, err = t.project(rows, q.Columns)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if q.OrderBy != "" {
+		if err := result.sort(q.OrderBy); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// project keeps the named columns of rows.
+func (t *Table) project(rows [][]Value, columns []string) (*Table, error) {
+	if len(columns) == 0 {
+		return &Table{Columns: t.Columns, Rows: append([][]Value(nil), rows...)}, nil // Copied, sort reorders in place
+	}
+	indexes := make([]int, len(columns))
+	for i, name := range columns {
+		index, err := t.column(name)
+		if err != nil {
+			return nil, err
+		}
+		indexes[i] = index
+	}
+	result := &Table{Columns: columns, Rows: make([][]Value, len(rows))}
+	for r, row := range rows {
+		result.Rows[r] = make([]Value, len(indexes))
+		for i, index := range indexes {
+			result.Rows[r][i] = row[index]
+		}
+	}
+	return result, nil
+}
+
+// aggregator accumulates one aggregate over a group.
+type aggregator struct {
+	fn     string // count, sum, avg, min or max
+	column int    // -1 for count(*)
+	count  int
+	sum    float64
+	best   Value
+}
+
+func (a *aggregator) add(row []Value) {
+	if a.column < 0 {
+		a.count++
+		return
+	}
+	v := row[a.column]
+	if v.Null || (a.fn != "count" && a.fn != "min" && a.fn != "max" && !v.IsNum) {
+		return // Nulls are skipped, and sum and avg only see numbers
+	}
+	a.count++
+	a.sum += v.Num
+	if a.count == 1 || (a.fn == "min" && compareValues(v, a.best) < 0) || (a.fn == "max" && compareValues(v, a.best) > 0) {
+		a.best = v
+	}
+}
+
+func (a *aggregator) result() Value {
+	switch a.fn {
+	case "count":
+		return numValue(float64(a.count))
+	case "sum":
+		return numValue(a.sum)
+	case "avg":
+		if a.count == 0 {
+			return Value{Null: true}
+		}
+		return numValue(a.sum / float64(a.count))
+	}
+	if a.count == 0 {
+		return Value{Null: true}
+	}
+	return a.best
+}
+
+// parseAggregate parses an aggregate such as "sum(amount)".
+func (t *Table) parseAggregate(spec string) (aggregator, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "count" {
+		return aggregator{fn: "count", column: -1}, nil
+	}
+	open := strings.Index(spec, "(")
+	if open < 0 || !strings.HasSuffix(spec, ")") {
+		return aggregator{}, fmt.Errorf("invalid aggregate %q: want e.g. count(*) or sum(column)", spec)
+	}
+	fn := strings.ToLower(strings.TrimSpace(spec[:open]))
+	arg := strings.TrimSpace(spec[open+1 : len(spec)-1])
+	switch fn {
+	case "count", "sum", "avg", "min", "max":
+	default:
+		return aggregator{}, fmt.Errorf("unknown aggregate function %q (want count, sum, avg, min or max)", fn)
+	}
+	if arg == "*" && fn == "count" {
+		return aggregator{fn: fn, column: -1}, nil
+	}
+	column, err := t.column(arg)
+	if err != nil {
+		return aggregator{}, err
+	}
+	return aggregator{fn: fn, column: column}, nil
+}
+
+// aggregate groups rows by the groupBy columns, in order of first appearance,
+// and computes the aggregates of each group.
+func (t *Table) aggregate(rows [][]Value, groupBy, aggregates []string) (*Table, error) {
+	keys := make([]int, len(groupBy))
+	for i, name := range groupBy {
+		index, err := t.column(name)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = index
+	}
+	templates := make([]aggregator, len(aggregates))
+	for i, spec := range aggregates {
+		agg, err := t.parseAggregate(spec)
+		if err != nil {
+			return nil, err
+		}
+		templates[i] = agg
+	}
+
+	type group struct {
+		key  []Value
+		aggs []aggregator
+	}
+	var groups []*group
+	byKey := make(map[string]*group)
+	for _, row := range rows {
+		key := make([]Value, len(keys))
+		parts := make([]string, len(keys))
+		for i, index := range keys {
+			key[i] = row[index]
+			parts[i] = row[index].Text
+		}
+		id := strings.Join(parts, "\x00")
+		g, ok := byKey[id]
+		if !ok {
+			g = &group{key: key, aggs: append([]aggregator(nil), templates...)}
+			byKey[id] = g
+			groups = append(groups, g)
+		}
+		for i := range g.aggs {
+			g.aggs[i].add(row)
+		}
+	}
+	if len(groups) == 0 && len(keys) == 0 {
+		// Aggregates over no rows still produce one row, e.g. count(*) = 0
+		groups = append(groups, &group{aggs: templates})
+	}
+
+	result := &Table{Columns: append(append([]string(nil), groupBy...), aggregates...)}
+	for _, g := range groups {
+		row := append([]Value(nil), g.key...)
+		for i := range g.aggs {
+			row = append(row, g.aggs[i].result())
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result, nil
+}
+
+// sort orders the rows by a column, "-column" for descending. Nulls sort last.
+func (t *Table) sort(orderBy string) error {
+	descending := strings.HasPrefix(orderBy, "-")
+	index, err := t.column(strings.TrimPrefix(orderBy, "-"))
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(t.Rows, func(i, j int) bool {
+		a, b := t.Rows[i][index], t.Rows[j][index]
+		if a.Null || b.Null {
+			return !a.Null && b.Null
+		}
+		if descending {
+			return compareValues(a, b) > 0
+		}
+		return compareValues(a, b) < 0
+	})
+	return nil
+}
+
+// compareValues compares numerically when both values are numbers, as text otherwise.
+func compareValues(a, b Value) int {
+	if a.IsNum && b.IsNum {
+		switch {
+		case a.Num < b.Num:
+			return -1
+		case a.Num > b.Num:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(a.Text, b.Text)
+}
+
+// Records returns the rows as objects keyed by column name, for structured output.
+func (t *Table) Records() []map[string]interface{} {
+	records := make([]map[string]interface{}, len(t.Rows))
+	for r, row := range t.Rows {
+		record := make(map[string]interface{}, len(t.Columns))
+		for i, column := range t.Columns {
+			record[column] = row[i].Interface()
+		}
+		records[r] = record
+	}
+	return records
+}
+
+// Render formats the table as aligned text with a header row. Numeric columns
+// are right-aligned and nulls are shown as empty cells.
+func (t *Table) Render() string {
+	widths := make([]int, len(t.Columns))
+	numeric := make([]bool, len(t.Columns))
+	for i, c := range t.Columns {
+		widths[i] = utf8.RuneCountInString(c)
+		numeric[i] = len(t.Rows) > 0
+	}
+	for _, row := range t.Rows {
+		for i, v := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(v.Text))
+			if !v.Null && !v.IsNum {
+				numeric[i] = false
+			}
+		}
+	}
+
+	var b strings.Builder
+	line := func(cells []string) {
+		for i, cell := range cells {
+			if i > 0 {
+				b.WriteString("  ")
+			}
+			pad := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+			if numeric[i] {
+				b.WriteString(pad + cell)
+			} else if i < len(cells)-1 {
+				b.WriteString(cell + pad)
+			} else {
+				b.WriteString(cell) // No trailing spaces
+			}
+		}
+		b.WriteString("\n")
+	}
+	line(t.Columns)
+	rules := make([]string, len(widths))
+	for i, w := range widths {
+		rules[i] = strings.Repeat("-", w)
+	}
+	line(rules)
+	for _, row := range t.Rows {
+		cells := make([]string, len(row))
+		for i, v := range row {
+			cells[i] = v.Text
+		}
+		line(cells)
+	}
+	return b.String()
+}
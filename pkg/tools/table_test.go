@@ -0,0 +1,124 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+const salesCSV = `region,product,amount,note
+EU,widget,120,
+US,widget,80,refund requested
+EU,gadget,45.5,
+US,gadget,200,Refund issued
+APAC,widget,,pending
+`
+
+func loadSales(t *testing.T) *Table {
+	t.Helper()
+	table, err := LoadTable(strings.NewReader(salesCSV), "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return table
+}
+
+// column returns the text of one column of a table, joined with commas.
+func column(t *testing.T, table *Table, name string) string {
+	t.Helper()
+	index, err := table.column(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var values []string
+	for _, row := range table.Rows {
+		values = append(values, row[index].Text)
+	}
+	return strings.Join(values, ",")
+}
+
+func TestTableFilter(t *testing.T) {
+	tests := []struct {
+		where string
+		want  string // amounts of the matching rows
+	}{
+		{"region = 'EU'", "120,45.5"},
+		{"amount > 100", "120,200"},
+		{"amount >= 80 and not (region == \"US\")", "120"},
+		{"note contains 'REFUND' or amount < 50", "80,45.5,200"},
+		{"amount is null", ""},
+		{"note is not null and region != 'APAC'", "80,200"},
+		{"`product` = 'widget'", "120,80,"},
+	}
+	for _, tt := range tests {
+		result, err := loadSales(t).Query(TableQuery{Where: tt.where})
+		if err != nil {
+			t.Errorf("Query(where %q) error = %v", tt.where, err)
+			continue
+		}
+		if got := column(t, result, "amount"); got != tt.want {
+			t.Errorf("Query(where %q) amounts = %q, want %q", tt.where, got, tt.want)
+		}
+	}
+}
+
+func TestTableFilterErrors(t *testing.T) {
+	for _, where := range []string{"region =", "price > 1", "region 'EU'", "(amount > 1", "note contains 'x' and", "amount ! 3", "region = 'EU"} {
+		if _, err := loadSales(t).Query(TableQuery{Where: where}); err == nil {
+			t.Errorf("Query(where %q) succeeded, want an error", where)
+		}
+	}
+}
+
+func TestTableAggregate(t *testing.T) {
+	result, err := loadSales(t).Query(TableQuery{
+		GroupBy:    []string{"product"},
+		Aggregates: []string{"count(*)", "count(amount)", "sum(amount)", "avg(amount)", "max(region)"},
+		OrderBy:    "-sum(amount)",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "product  count(*)  count(amount)  sum(amount)  avg(amount)  max(region)\n" +
+		"-------  --------  -------------  -----------  -----------  -----------\n" +
+		"gadget          2              2        245.5       122.75  US\n" +
+		"widget          3              2          200          100  US\n"
+	if got := result.Render(); got != want {
+		t.Errorf("Render() =\n%s\nwant\n%s", got, want)
+	}
+
+	empty, err := loadSales(t).Query(TableQuery{Where: "region = 'MARS'", Aggregates: []string{"count", "sum(amount)"}})
+	if err != nil || len(empty.Rows) != 1 || empty.Rows[0][0].Text != "0" {
+		t.Errorf("aggregate over no rows = %+v, %v, want one row with count 0", empty, err)
+	}
+	if _, err := loadSales(t).Query(TableQuery{Columns: []string{"region"}, Aggregates: []string{"count"}}); err == nil {
+		t.Error("Query() accepted columns together with aggregates")
+	}
+	if _, err := loadSales(t).Query(TableQuery{Aggregates: []string{"median(amount)"}}); err == nil {
+		t.Error("Query() accepted an unknown aggregate")
+	}
+}
+
+func TestLoadJSONTable(t *testing.T) {
+	table, err := LoadTable(strings.NewReader(`[{"name":"a","size":3,"tags":["x"]},{"name":"b","extra":null}]`), "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(table.Columns, ","); got != "name,size,tags,extra" {
+		t.Errorf("Columns = %s, want the keys in order of appearance", got)
+	}
+	result, err := table.Query(TableQuery{Columns: []string{"name", "size"}, OrderBy: "size"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := result.Records()
+	if records[0]["size"] != 3.0 || records[1]["size"] != nil || records[0]["name"] != "a" {
+		t.Errorf("Records() = %v", records)
+	}
+	if table.Rows[0][2].Text != `["x"]` {
+		t.Errorf("nested value = %q, want its JSON text", table.Rows[0][2].Text)
+	}
+
+	if _, err := LoadTable(strings.NewReader(`{"rows":[]}`), "json"); err == nil {
+		t.Error("LoadTable() accepted a JSON object")
+	}
+}
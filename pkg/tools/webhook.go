@@ -0,0 +1,142 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Webhook payload formats.
+const (
+	WebhookSlack   = "slack"
+	WebhookDiscord = "discord"
+	WebhookGeneric = "generic"
+)
+
+// Longest message text each format accepts. Slack truncates longer messages in
+// the client and Discord rejects them; the generic limit keeps alerts readable.
+var webhookMaxLength = map[string]int{
+	WebhookSlack:   4000,
+	WebhookDiscord: 2000,
+	WebhookGeneric: 4000,
+}
+
+// Webhook posts messages to a Slack or Discord incoming webhook, or as plain
+// JSON to any other URL.
+type Webhook struct {
+	http   *http.Client
+	url    *url.URL
+	format string
+}
+
+// NewWebhook returns a webhook posting to rawURL. An empty format is detected
+// from the URL: Slack and Discord webhook hosts use their own payloads and
+// everything else gets the generic one.
+func NewWebhook(rawURL, format string, timeout time.Duration) (*Webhook, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid webhook URL: want http(s)://host/path")
+	}
+	switch format {
+	case "", "auto":
+		format = detectWebhookFormat(u)
+	case WebhookSlack, WebhookDiscord, WebhookGeneric:
+	default:
+		return nil, fmt.Errorf("unknown webhook format %q (want slack, discord or generic)", format)
+	}
+	return &Webhook{http: &http.Client{Timeout: timeout}, url: u, format: format}, nil
+}
+
+// detectWebhookFormat picks the payload format from the webhook's host.
+func detectWebhookFormat(u *url.URL) string {
+	host := strings.ToLower(u.Hostname())
+	switch {
+	case host == "hooks.slack.com":
+		return WebhookSlack
+	case (host == "discord.com" || host == "discordapp.com") && strings.HasPrefix(u.Path, "/api/webhooks/"):
+		return WebhookDiscord
+	}
+	return WebhookGeneric
+}
+
+// Format returns the payload format: slack, discord or generic.
+func (w *Webhook) Format() string { return w.format }
+
+// Host returns the webhook's host, for messages that must not reveal the URL,
+// which usually embeds a secret token.
+func (w *Webhook) Host() string { return w.url.Host }
+
+// MaxLength returns the longest message text the format accepts, in characters.
+func (w *Webhook) MaxLength() int { return webhookMaxLength[w.format] }
+
+// WebhookMessage is one notification.
+type WebhookMessage struct {
+	Title   string `json:"title,omitempty"`
+	Message string `json:"message"`
+	Level   string `json:"level"` // info, warning or critical
+	Source  string `json:"source"`
+}
+
+// chatText renders the message for the chat formats: the level and title in
+// bold on the first line, then the message.
+func (m WebhookMessage) chatText(bold string) string {
+	heading := "[" + strings.ToUpper(m.Level) + "]"
+	if m.Title != "" {
+		heading += " " + m.Title
+	}
+	return bold + heading + bold + "\n" + m.Message
+}
+
+// Post sends m. Text longer than MaxLength is truncated, keeping the heading,
+// and Post reports whether that happened.
+func (w *Webhook) Post(m WebhookMessage) (truncated bool, err error) {
+	var payload interface{}
+	switch w.format {
+	case WebhookSlack:
+		var text string
+		text, truncated = TruncateText(m.chatText("*"), w.MaxLength())
+		payload = map[string]string{"text": text}
+	case WebhookDiscord:
+		var text string
+		text, truncated = TruncateText(m.chatText("**"), w.MaxLength())
+		payload = map[string]interface{}{"content": text, "allowed_mentions": map[string][]string{"parse": {}}}
+	default:
+		m.Message, truncated = TruncateText(m.Message, w.MaxLength())
+		payload = m
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return false, err
+	}
+	resp, err := w.http.Post(w.url.String(), "application/json", bytes.NewReader(body))
+	if err != nil {
+		// The error text includes the URL; report the host only.
+		return false, fmt.Errorf("webhook at %s unreachable", w.Host())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+		return false, fmt.Errorf("webhook at %s: %s %s", w.Host(), resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return truncated, nil
+}
+
+// truncationMark ends a truncated text.
+const truncationMark = "… (truncated)"
+
+// TruncateText shortens s to at most limit characters, ending it with a mark
+// that says so, and reports whether it did.
+func TruncateText(s string, limit int) (string, bool) {
+	if utf8.RuneCountInString(s) <= limit {
+		return s, false
+	}
+	keep := limit - utf8.RuneCountInString(truncationMark)
+	runes := []rune(s)
+	return strings.TrimRightFunc(string(runes[:keep]), func(r rune) bool { return r == ' ' || r == '\n' }) + truncationMark, true
+}
@@ -0,0 +1,144 @@
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// AutoFramed is a Transport that adopts the framing of its peer, for a server
+// that must work with hosts of either convention without being told which.
+// The first ReadMessage peeks at the incoming bytes: a Content-Length header
+// selects ContentLength, the "MCPL" preamble or a zero byte (the start of a
+// 4-byte length) selects LengthPrefixed, and anything else newline-delimited
+// JSON. Replies then use the same framing. Messages written before anything
+// has been read are newline-delimited.
+type AutoFramed struct {
+	reader *bufio.Reader
+	writer io.Writer
+	closer []io.Closer
+
+	// MaxMessageSize is the largest accepted message once the framing is
+	// known; 0 keeps each framing's default. Set it before use.
+	MaxMessageSize int
+	// Detected, if set, is called once with the framing chosen.
+	Detected func(Framing)
+
+	mu      sync.Mutex
+	framed  Transport // Of the detected framing, nil until the first read
+	framing Framing
+	early   *Stream // Writes before the framing is known
+}
+
+// NewAutoFramed creates a transport reading from r and writing to w in the
+// framing the peer uses. If r or w implement io.Closer they are closed by Close.
+func NewAutoFramed(r io.Reader, w io.Writer) *AutoFramed {
+	return &AutoFramed{
+		reader: bufio.NewReader(r),
+		writer: w,
+		closer: closersOf(r, w),
+	}
+}
+
+// Framing returns the framing detected, and false while none has been.
+func (a *AutoFramed) Framing() (Framing, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.framing, a.framed != nil
+}
+
+// ReadMessage detects the framing on the first call, then reads messages in it.
+func (a *AutoFramed) ReadMessage() ([]byte, error) {
+	a.mu.Lock()
+	framed := a.framed
+	a.mu.Unlock()
+	if framed == nil {
+		framing, err := sniffFraming(a.reader)
+		if err != nil {
+			return nil, err
+		}
+		framed = a.adopt(framing)
+	}
+	return framed.ReadMessage()
+}
+
+// adopt creates the transport of framing over the reader and writer.
+func (a *AutoFramed) adopt(framing Framing) Transport {
+	var framed Transport
+	switch framing {
+	case FramingContentLength:
+		t := NewContentLength(a.reader, a.writer)
+		if a.MaxMessageSize > 0 {
+			t.MaxFrameSize = a.MaxMessageSize
+		}
+		framed = t
+	case FramingLength:
+		t := NewLengthPrefixed(a.reader, a.writer)
+		if a.MaxMessageSize > 0 {
+			t.MaxFrameSize = a.MaxMessageSize
+		}
+		framed = t
+	default:
+		t := NewStream(a.reader, a.writer)
+		if a.MaxMessageSize > 0 {
+			t.MaxLineSize = a.MaxMessageSize
+		}
+		framed = t
+	}
+	a.mu.Lock()
+	a.framed, a.framing = framed, framing
+	a.mu.Unlock()
+	if a.Detected != nil {
+		a.Detected(framing)
+	}
+	return framed
+}
+
+// WriteMessage writes payload in the detected framing, or newline-delimited
+// before the first read.
+func (a *AutoFramed) WriteMessage(payload []byte) error {
+	a.mu.Lock()
+	framed := a.framed
+	if framed == nil {
+		if a.early == nil {
+			a.early = NewStream(nil, a.writer)
+		}
+		framed = a.early
+	}
+	a.mu.Unlock()
+	return framed.WriteMessage(payload)
+}
+
+// Close closes the underlying reader and writer if they are closable.
+func (a *AutoFramed) Close() error {
+	return closeAll(a.closer)
+}
+
+// sniffFraming tells the peer's framing from the first byte waiting in r,
+// blocking until one arrives. The length preamble is consumed; everything else
+// is left for the transport of the framing returned.
+func sniffFraming(r *bufio.Reader) (Framing, error) {
+	first, err := r.Peek(1)
+	if err != nil {
+		return 0, err
+	}
+	switch first[0] {
+	case 'C', 'c':
+		return FramingContentLength, nil
+	case 0:
+		return FramingLength, nil
+	case lengthPreamble[0]:
+		preamble := make([]byte, len(lengthPreamble))
+		if _, err := io.ReadFull(r, preamble); err != nil {
+			return 0, err
+		}
+		if !bytes.Equal(preamble, lengthPreamble) {
+			return 0, fmt.Errorf("invalid framing preamble %q", preamble)
+		}
+		return FramingLength, nil
+	default:
+		return FramingNewline, nil
+	}
+}
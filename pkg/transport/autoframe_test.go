@@ -0,0 +1,59 @@
+package transport
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestAutoFramedAdoptsPeerFraming(t *testing.T) {
+	msg := `{"jsonrpc":"2.0","id":1,"method":"ping"}`
+	reply := `{"jsonrpc":"2.0","id":1,"result":{}}`
+	lengthFrame := func(payload string) string {
+		var n [4]byte
+		binary.BigEndian.PutUint32(n[:], uint32(len(payload)))
+		return string(n[:]) + payload
+	}
+	tests := []struct {
+		framing      Framing
+		input, wrote string
+	}{
+		{FramingNewline, msg + "\n", reply + "\n"},
+		{FramingContentLength, "Content-Length: 40\r\n\r\n" + msg, "Content-Length: 36\r\n\r\n" + reply},
+		{FramingLength, lengthFrame(msg), lengthFrame(reply)},
+		{FramingLength, "MCPL" + lengthFrame(msg), lengthFrame(reply)},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		a := NewAutoFramed(bytes.NewBufferString(tt.input), &out)
+		var detected []Framing
+		a.Detected = func(f Framing) { detected = append(detected, f) }
+		if _, ok := a.Framing(); ok {
+			t.Fatal("framing known before the first read")
+		}
+		got, err := a.ReadMessage()
+		if err != nil || string(got) != msg {
+			t.Fatalf("%v: ReadMessage() = %q, %v", tt.framing, got, err)
+		}
+		if f, ok := a.Framing(); !ok || f != tt.framing || len(detected) != 1 || detected[0] != tt.framing {
+			t.Errorf("%v: detected %v (%v), callback saw %v", tt.framing, f, ok, detected)
+		}
+		if err := a.WriteMessage([]byte(reply)); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != tt.wrote {
+			t.Errorf("%v: wrote %q, want %q", tt.framing, out.String(), tt.wrote)
+		}
+	}
+}
+
+func TestAutoFramedWritesNewlineBeforeDetection(t *testing.T) {
+	var out bytes.Buffer
+	a := NewAutoFramed(bytes.NewBufferString(""), &out)
+	if err := a.WriteMessage([]byte(`{"jsonrpc":"2.0","method":"x"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "{\"jsonrpc\":\"2.0\",\"method\":\"x\"}\n" {
+		t.Errorf("wrote %q", out.String())
+	}
+}
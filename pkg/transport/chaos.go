@@ -0,0 +1,264 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"sqirvy/mcp/pkg/mcp"
+)
+
+// ErrChaosDisconnect is returned once a Chaos transport has simulated a dropped connection.
+var ErrChaosDisconnect = errors.New("chaos: simulated disconnect")
+
+// defaultReorderWindow bounds how long a held-back notification waits for a later message to overtake it.
+const defaultReorderWindow = 100 * time.Millisecond
+
+// ChaosConfig configures the faults injected by a Chaos transport. The zero value injects nothing.
+type ChaosConfig struct {
+	// Latency is added before every read and write.
+	Latency time.Duration
+	// Jitter adds a further random delay in [0, Jitter) to every read and write.
+	Jitter time.Duration
+	// DuplicateRate is the probability that a message is delivered (read) or sent (written) twice.
+	DuplicateRate float64
+	// ReorderRate is the probability that an outgoing notification is held back so that the
+	// next outgoing message overtakes it. Requests and responses are never reordered.
+	ReorderRate float64
+	// ReorderWindow is the longest a notification is held back. Defaults to 100ms.
+	ReorderWindow time.Duration
+	// DisconnectRate is the probability, per read or write, of simulating a dropped connection.
+	// After a disconnect the inner transport is closed and every operation fails with ErrChaosDisconnect.
+	DisconnectRate float64
+	// Seed seeds the fault generator for reproducible runs. Zero uses the current time.
+	Seed int64
+}
+
+// ParseChaosConfig parses a comma-separated key=value spec as accepted by the --chaos flags, e.g.
+//
+//	latency=20ms,jitter=10ms,dup=0.05,reorder=0.2,disconnect=0.001,seed=42
+func ParseChaosConfig(spec string) (ChaosConfig, error) {
+	var cfg ChaosConfig
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return cfg, fmt.Errorf("invalid chaos option %q: expected key=value", field)
+		}
+		var err error
+		switch strings.ToLower(key) {
+		case "latency":
+			cfg.Latency, err = time.ParseDuration(value)
+		case "jitter":
+			cfg.Jitter, err = time.ParseDuration(value)
+		case "window":
+			cfg.ReorderWindow, err = time.ParseDuration(value)
+		case "dup", "duplicate":
+			cfg.DuplicateRate, err = parseRate(value)
+		case "reorder":
+			cfg.ReorderRate, err = parseRate(value)
+		case "disconnect":
+			cfg.DisconnectRate, err = parseRate(value)
+		case "seed":
+			cfg.Seed, err = strconv.ParseInt(value, 10, 64)
+		default:
+			return cfg, fmt.Errorf("unknown chaos option %q", key)
+		}
+		if err != nil {
+			return cfg, fmt.Errorf("invalid value for chaos option %q: %w", key, err)
+		}
+	}
+	return cfg, nil
+}
+
+// parseRate parses a probability in [0, 1].
+func parseRate(value string) (float64, error) {
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	if rate < 0 || rate > 1 {
+		return 0, fmt.Errorf("rate %v out of range [0, 1]", rate)
+	}
+	return rate, nil
+}
+
+// Chaos is a Transport decorator that injects latency, duplication, notification reordering
+// and random disconnects into another transport, to exercise client and server behavior
+// under unreliable conditions.
+type Chaos struct {
+	inner Transport
+	cfg   ChaosConfig
+
+	randMu sync.Mutex // rand.Rand is not safe for concurrent use
+	rand   *rand.Rand
+
+	readMu  sync.Mutex // Protects pending
+	pending [][]byte   // Duplicated messages waiting to be read again
+
+	writeMu      sync.Mutex  // Serializes writes and protects the fields below
+	held         []byte      // Notification held back for reordering
+	heldTimer    *time.Timer // Flushes held when nothing overtakes it in time
+	disconnected bool
+}
+
+// NewChaos wraps inner with fault injection configured by cfg.
+func NewChaos(inner Transport, cfg ChaosConfig) *Chaos {
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	if cfg.ReorderWindow <= 0 {
+		cfg.ReorderWindow = defaultReorderWindow
+	}
+	return &Chaos{
+		inner: inner,
+		cfg:   cfg,
+		rand:  rand.New(rand.NewSource(seed)),
+	}
+}
+
+// ReadMessage reads from the inner transport after the configured delay, possibly
+// duplicating the message or simulating a disconnect.
+func (c *Chaos) ReadMessage() ([]byte, error) {
+	c.readMu.Lock()
+	if len(c.pending) > 0 {
+		payload := c.pending[0]
+		c.pending = c.pending[1:]
+		c.readMu.Unlock()
+		return payload, nil
+	}
+	c.readMu.Unlock()
+
+	if err := c.maybeDisconnect(); err != nil {
+		return nil, err
+	}
+	payload, err := c.inner.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+	c.sleep()
+	if c.chance(c.cfg.DuplicateRate) {
+		c.readMu.Lock()
+		c.pending = append(c.pending, append([]byte(nil), payload...))
+		c.readMu.Unlock()
+	}
+	return payload, nil
+}
+
+// WriteMessage writes to the inner transport after the configured delay, possibly
+// duplicating the message, holding back a notification, or simulating a disconnect.
+func (c *Chaos) WriteMessage(payload []byte) error {
+	if err := c.maybeDisconnect(); err != nil {
+		return err
+	}
+	c.sleep()
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.disconnected {
+		return ErrChaosDisconnect
+	}
+
+	if c.held == nil && c.chance(c.cfg.ReorderRate) && isNotification(payload) {
+		c.held = append([]byte(nil), payload...)
+		c.heldTimer = time.AfterFunc(c.cfg.ReorderWindow, c.flushHeld)
+		return nil
+	}
+
+	if err := c.inner.WriteMessage(payload); err != nil {
+		return err
+	}
+	if c.chance(c.cfg.DuplicateRate) {
+		if err := c.inner.WriteMessage(payload); err != nil {
+			return err
+		}
+	}
+	// The message just written has overtaken any held notification; release it now.
+	return c.releaseHeldLocked()
+}
+
+// Close flushes any held notification and closes the inner transport.
+func (c *Chaos) Close() error {
+	c.writeMu.Lock()
+	if !c.disconnected {
+		_ = c.releaseHeldLocked()
+	}
+	c.writeMu.Unlock()
+	return c.inner.Close()
+}
+
+// flushHeld is called by the reorder timer when no later message overtook the held notification.
+func (c *Chaos) flushHeld() {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if !c.disconnected {
+		_ = c.releaseHeldLocked()
+	}
+}
+
+// releaseHeldLocked writes the held notification, if any. writeMu must be held.
+func (c *Chaos) releaseHeldLocked() error {
+	if c.held == nil {
+		return nil
+	}
+	held := c.held
+	c.held = nil
+	c.heldTimer.Stop()
+	return c.inner.WriteMessage(held)
+}
+
+// maybeDisconnect simulates a dropped connection with probability DisconnectRate.
+func (c *Chaos) maybeDisconnect() error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.disconnected {
+		return ErrChaosDisconnect
+	}
+	if c.chance(c.cfg.DisconnectRate) {
+		c.disconnected = true
+		c.held = nil
+		if c.heldTimer != nil {
+			c.heldTimer.Stop()
+		}
+		_ = c.inner.Close()
+		return ErrChaosDisconnect
+	}
+	return nil
+}
+
+// sleep waits for the configured latency plus jitter.
+func (c *Chaos) sleep() {
+	delay := c.cfg.Latency
+	if c.cfg.Jitter > 0 {
+		c.randMu.Lock()
+		delay += time.Duration(c.rand.Int63n(int64(c.cfg.Jitter)))
+		c.randMu.Unlock()
+	}
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+// chance returns true with the given probability.
+func (c *Chaos) chance(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	c.randMu.Lock()
+	defer c.randMu.Unlock()
+	return c.rand.Float64() < rate
+}
+
+// isNotification reports whether payload is a JSON-RPC notification.
+func isNotification(payload []byte) bool {
+	info, err := mcp.ClassifyMessage(payload)
+	return err == nil && info.Kind == mcp.KindNotification
+}
@@ -0,0 +1,147 @@
+package transport
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// recordingTransport is an in-memory Transport that records writes and serves queued reads.
+type recordingTransport struct {
+	reads  chan []byte
+	writes chan []byte
+	closed bool
+}
+
+func newRecordingTransport() *recordingTransport {
+	return &recordingTransport{reads: make(chan []byte, 16), writes: make(chan []byte, 16)}
+}
+
+func (r *recordingTransport) ReadMessage() ([]byte, error) {
+	payload, ok := <-r.reads
+	if !ok {
+		return nil, ErrClosed
+	}
+	return payload, nil
+}
+
+func (r *recordingTransport) WriteMessage(payload []byte) error {
+	r.writes <- payload
+	return nil
+}
+
+func (r *recordingTransport) Close() error {
+	r.closed = true
+	return nil
+}
+
+func (r *recordingTransport) nextWrite(t *testing.T) string {
+	t.Helper()
+	select {
+	case payload := <-r.writes:
+		return string(payload)
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for a write")
+		return ""
+	}
+}
+
+const (
+	testRequest      = `{"jsonrpc":"2.0","method":"tools/list","id":1}`
+	testNotification = `{"jsonrpc":"2.0","method":"notifications/message"}`
+)
+
+func TestParseChaosConfig(t *testing.T) {
+	cfg, err := ParseChaosConfig("latency=20ms, jitter=5ms,dup=0.5,reorder=1,disconnect=0,seed=7,window=1s")
+	if err != nil {
+		t.Fatalf("ParseChaosConfig() error = %v", err)
+	}
+	want := ChaosConfig{Latency: 20 * time.Millisecond, Jitter: 5 * time.Millisecond, DuplicateRate: 0.5, ReorderRate: 1, Seed: 7, ReorderWindow: time.Second}
+	if cfg != want {
+		t.Errorf("ParseChaosConfig() = %+v, want %+v", cfg, want)
+	}
+
+	for _, bad := range []string{"latency", "dup=2", "reorder=x", "bogus=1"} {
+		if _, err := ParseChaosConfig(bad); err == nil {
+			t.Errorf("ParseChaosConfig(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestChaosLatency(t *testing.T) {
+	inner := newRecordingTransport()
+	c := NewChaos(inner, ChaosConfig{Latency: 20 * time.Millisecond, Seed: 1})
+
+	start := time.Now()
+	if err := c.WriteMessage([]byte(testRequest)); err != nil {
+		t.Fatalf("WriteMessage() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("WriteMessage() took %v, want at least 20ms", elapsed)
+	}
+	if got := inner.nextWrite(t); got != testRequest {
+		t.Errorf("inner write = %s", got)
+	}
+}
+
+func TestChaosDuplicate(t *testing.T) {
+	inner := newRecordingTransport()
+	c := NewChaos(inner, ChaosConfig{DuplicateRate: 1, Seed: 1})
+
+	if err := c.WriteMessage([]byte(testRequest)); err != nil {
+		t.Fatalf("WriteMessage() error = %v", err)
+	}
+	if inner.nextWrite(t) != testRequest || inner.nextWrite(t) != testRequest {
+		t.Errorf("expected the request to be written twice")
+	}
+
+	inner.reads <- []byte(testNotification)
+	for i := 0; i < 2; i++ {
+		got, err := c.ReadMessage()
+		if err != nil || string(got) != testNotification {
+			t.Fatalf("ReadMessage() #%d = %s, %v", i, got, err)
+		}
+	}
+}
+
+func TestChaosReordersNotifications(t *testing.T) {
+	inner := newRecordingTransport()
+	c := NewChaos(inner, ChaosConfig{ReorderRate: 1, ReorderWindow: time.Hour, Seed: 1})
+
+	if err := c.WriteMessage([]byte(testNotification)); err != nil {
+		t.Fatalf("WriteMessage(notification) error = %v", err)
+	}
+	if err := c.WriteMessage([]byte(testRequest)); err != nil {
+		t.Fatalf("WriteMessage(request) error = %v", err)
+	}
+	if first, second := inner.nextWrite(t), inner.nextWrite(t); first != testRequest || second != testNotification {
+		t.Errorf("writes = [%s, %s], want the request to overtake the notification", first, second)
+	}
+}
+
+func TestChaosReorderWindowFlushes(t *testing.T) {
+	inner := newRecordingTransport()
+	c := NewChaos(inner, ChaosConfig{ReorderRate: 1, ReorderWindow: 10 * time.Millisecond, Seed: 1})
+
+	if err := c.WriteMessage([]byte(testNotification)); err != nil {
+		t.Fatalf("WriteMessage() error = %v", err)
+	}
+	if got := inner.nextWrite(t); got != testNotification {
+		t.Errorf("held notification was not flushed after the reorder window, got %s", got)
+	}
+}
+
+func TestChaosDisconnect(t *testing.T) {
+	inner := newRecordingTransport()
+	c := NewChaos(inner, ChaosConfig{DisconnectRate: 1, Seed: 1})
+
+	if err := c.WriteMessage([]byte(testRequest)); !errors.Is(err, ErrChaosDisconnect) {
+		t.Fatalf("WriteMessage() error = %v, want ErrChaosDisconnect", err)
+	}
+	if _, err := c.ReadMessage(); !errors.Is(err, ErrChaosDisconnect) {
+		t.Errorf("ReadMessage() after disconnect error = %v, want ErrChaosDisconnect", err)
+	}
+	if !inner.closed {
+		t.Errorf("inner transport was not closed on disconnect")
+	}
+}
@@ -0,0 +1,263 @@
+package transport
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Content encodings understood by the compression helpers.
+const (
+	EncodingGzip     = "gzip"
+	EncodingDeflate  = "deflate"
+	EncodingIdentity = "identity"
+)
+
+// DefaultCompressionThreshold is the payload size below which compression is not worth its overhead.
+const DefaultCompressionThreshold = 1024
+
+// ErrDecompressedTooLarge is returned when a compressed payload expands beyond the allowed size.
+var ErrDecompressedTooLarge = errors.New("decompressed payload too large")
+
+// NegotiateEncoding picks the content encoding to use for a peer that sent the given
+// Accept-Encoding (HTTP) or equivalent offer list, e.g. "gzip;q=0.8, deflate".
+// gzip is preferred over deflate at equal quality; identity is returned if neither is acceptable.
+func NegotiateEncoding(accept string) string {
+	best, bestQ := EncodingIdentity, 0.0
+	wildcard := -1.0
+	offered := map[string]float64{}
+	for _, part := range strings.Split(accept, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		if k, v, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(k) == "q" {
+			if parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				q = parsed
+			}
+		}
+		if name == "*" {
+			wildcard = q
+			continue
+		}
+		offered[name] = q
+	}
+	for _, enc := range []string{EncodingGzip, EncodingDeflate} {
+		q, ok := offered[enc]
+		if !ok && wildcard >= 0 {
+			q, ok = wildcard, true
+		}
+		if ok && q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+	return best
+}
+
+// newCompressor returns a writer compressing into w with the given encoding.
+func newCompressor(encoding string, w io.Writer) (io.WriteCloser, error) {
+	switch encoding {
+	case EncodingGzip:
+		return gzip.NewWriter(w), nil
+	case EncodingDeflate:
+		return flate.NewWriter(w, flate.DefaultCompression)
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+}
+
+// CompressPayload compresses payload with encoding if it is at least threshold bytes long.
+// It returns the bytes to send and the encoding actually applied, which is identity for
+// small payloads, for the identity encoding, or when compression would not save space.
+func CompressPayload(encoding string, payload []byte, threshold int) ([]byte, string, error) {
+	if encoding == "" || encoding == EncodingIdentity || len(payload) < threshold {
+		return payload, EncodingIdentity, nil
+	}
+	var buf bytes.Buffer
+	zw, err := newCompressor(encoding, &buf)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := zw.Write(payload); err != nil {
+		return nil, "", fmt.Errorf("failed to compress payload: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, "", fmt.Errorf("failed to compress payload: %w", err)
+	}
+	if buf.Len() >= len(payload) {
+		return payload, EncodingIdentity, nil
+	}
+	return buf.Bytes(), encoding, nil
+}
+
+// DecompressPayload reverses CompressPayload. maxSize bounds the decompressed size to
+// protect against compression bombs; zero or less means no limit.
+func DecompressPayload(encoding string, data []byte, maxSize int64) ([]byte, error) {
+	var r io.Reader
+	switch encoding {
+	case "", EncodingIdentity:
+		return data, nil
+	case EncodingGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip payload: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	case EncodingDeflate:
+		zr := flate.NewReader(bytes.NewReader(data))
+		defer zr.Close()
+		r = zr
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+	if maxSize > 0 {
+		r = io.LimitReader(r, maxSize+1)
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress payload: %w", err)
+	}
+	if maxSize > 0 && int64(len(out)) > maxSize {
+		return nil, ErrDecompressedTooLarge
+	}
+	return out, nil
+}
+
+// CompressHandler wraps an HTTP handler serving MCP messages so that its responses are
+// compressed with the encoding negotiated from the request's Accept-Encoding header.
+//
+// Ordinary responses are buffered and compressed only if they reach threshold bytes.
+// Event streams (Content-Type text/event-stream) are compressed per stream and flushed
+// whenever the handler flushes, so events are not delayed.
+func CompressHandler(next http.Handler, threshold int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoding := NegotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == EncodingIdentity {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding, threshold: threshold}
+		defer cw.finish()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// compressWriter is the http.ResponseWriter used by CompressHandler.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding  string
+	threshold int
+
+	status  int
+	buf     bytes.Buffer   // Buffered body of a non-streaming response
+	stream  io.WriteCloser // Compressor of a streaming response, once started
+	started bool           // Headers have been sent
+}
+
+// WriteHeader records the status; headers are sent once the encoding is decided.
+func (c *compressWriter) WriteHeader(status int) {
+	if c.status == 0 {
+		c.status = status
+	}
+}
+
+// isStream reports whether the response is an event stream.
+func (c *compressWriter) isStream() bool {
+	return strings.HasPrefix(c.Header().Get("Content-Type"), "text/event-stream")
+}
+
+// Write buffers ordinary responses and compresses event streams as they are written.
+func (c *compressWriter) Write(p []byte) (int, error) {
+	if c.stream == nil && c.isStream() && !c.started {
+		if err := c.startStream(); err != nil {
+			return 0, err
+		}
+	}
+	if c.stream != nil {
+		return c.stream.Write(p)
+	}
+	if c.started {
+		return c.ResponseWriter.Write(p)
+	}
+	return c.buf.Write(p)
+}
+
+// startStream sends the headers of a compressed event stream and creates its compressor.
+func (c *compressWriter) startStream() error {
+	zw, err := newCompressor(c.encoding, c.ResponseWriter)
+	if err != nil {
+		return err
+	}
+	c.Header().Set("Content-Encoding", c.encoding)
+	c.Header().Del("Content-Length")
+	c.sendHeader()
+	c.stream = zw
+	return nil
+}
+
+// sendHeader writes the status line and headers once.
+func (c *compressWriter) sendHeader() {
+	if c.started {
+		return
+	}
+	c.started = true
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	c.ResponseWriter.WriteHeader(c.status)
+}
+
+// Flush pushes buffered event-stream data to the client. Flushing a non-streaming
+// response commits it uncompressed, since its final size is not yet known.
+func (c *compressWriter) Flush() {
+	switch {
+	case c.stream != nil:
+		if f, ok := c.stream.(interface{ Flush() error }); ok {
+			_ = f.Flush()
+		}
+	case !c.started:
+		if c.isStream() {
+			if err := c.startStream(); err != nil {
+				return
+			}
+			break
+		}
+		c.sendHeader()
+		_, _ = c.ResponseWriter.Write(c.buf.Bytes())
+		c.buf.Reset()
+	}
+	if f, ok := c.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// finish completes the response after the wrapped handler returns.
+func (c *compressWriter) finish() {
+	if c.stream != nil {
+		_ = c.stream.Close()
+		return
+	}
+	if c.started {
+		return
+	}
+	body, encoding, err := CompressPayload(c.encoding, c.buf.Bytes(), c.threshold)
+	if err != nil {
+		body, encoding = c.buf.Bytes(), EncodingIdentity
+	}
+	if encoding != EncodingIdentity {
+		c.Header().Set("Content-Encoding", encoding)
+	}
+	c.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	c.sendHeader()
+	_, _ = c.ResponseWriter.Write(body)
+}
@@ -0,0 +1,125 @@
+package transport
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", EncodingIdentity},
+		{"gzip", EncodingGzip},
+		{"deflate", EncodingDeflate},
+		{"deflate, gzip", EncodingGzip},
+		{"gzip;q=0.5, deflate", EncodingDeflate},
+		{"gzip;q=0, deflate;q=0", EncodingIdentity},
+		{"br", EncodingIdentity},
+		{"*", EncodingGzip},
+		{"gzip;q=0, *;q=0.3", EncodingDeflate},
+		{" GZIP ; q=1 ", EncodingGzip},
+	}
+	for _, tt := range tests {
+		if got := NegotiateEncoding(tt.accept); got != tt.want {
+			t.Errorf("NegotiateEncoding(%q) = %q, want %q", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestCompressPayloadRoundTrip(t *testing.T) {
+	payload := []byte(`{"jsonrpc":"2.0","result":{"contents":[{"blob":"` + strings.Repeat("QUJD", 2000) + `"}]},"id":1}`)
+	for _, enc := range []string{EncodingGzip, EncodingDeflate} {
+		compressed, applied, err := CompressPayload(enc, payload, DefaultCompressionThreshold)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if applied != enc || len(compressed) >= len(payload) {
+			t.Fatalf("%s: applied %q, %d -> %d bytes", enc, applied, len(payload), len(compressed))
+		}
+		out, err := DecompressPayload(applied, compressed, int64(len(payload)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(out, payload) {
+			t.Errorf("%s: round trip mismatch", enc)
+		}
+		if _, err := DecompressPayload(applied, compressed, int64(len(payload)-1)); !errors.Is(err, ErrDecompressedTooLarge) {
+			t.Errorf("%s: limit error = %v, want ErrDecompressedTooLarge", enc, err)
+		}
+	}
+
+	small := []byte(`{"jsonrpc":"2.0","id":1,"result":{}}`)
+	if out, applied, _ := CompressPayload(EncodingGzip, small, DefaultCompressionThreshold); applied != EncodingIdentity || !bytes.Equal(out, small) {
+		t.Errorf("small payload was compressed (%q)", applied)
+	}
+}
+
+func TestCompressHandler(t *testing.T) {
+	large := strings.Repeat(`{"x":"y"}`, 500)
+	handler := CompressHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/small" {
+			io.WriteString(w, `{}`)
+			return
+		}
+		io.WriteString(w, large)
+	}), DefaultCompressionThreshold)
+
+	req := httptest.NewRequest("POST", "/large", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Header().Get("Content-Encoding") != EncodingGzip {
+		t.Fatalf("Content-Encoding = %q, want gzip", rec.Header().Get("Content-Encoding"))
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(zr)
+	if string(body) != large {
+		t.Error("decompressed body mismatch")
+	}
+
+	req = httptest.NewRequest("POST", "/small", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != `{}` {
+		t.Errorf("small response: encoding %q body %q", rec.Header().Get("Content-Encoding"), rec.Body.String())
+	}
+}
+
+func TestCompressHandlerEventStream(t *testing.T) {
+	handler := CompressHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 3; i++ {
+			io.WriteString(w, "event: message\ndata: {}\n\n")
+			w.(http.Flusher).Flush()
+		}
+	}), DefaultCompressionThreshold)
+
+	req := httptest.NewRequest("GET", "/sse", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Header().Get("Content-Encoding") != EncodingGzip || !rec.Flushed {
+		t.Fatalf("encoding %q flushed %v", rec.Header().Get("Content-Encoding"), rec.Flushed)
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(zr)
+	if want := strings.Repeat("event: message\ndata: {}\n\n", 3); string(body) != want {
+		t.Errorf("stream body = %q", body)
+	}
+}
@@ -0,0 +1,144 @@
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxHeaderLine bounds a header line of a ContentLength frame.
+const maxHeaderLine = 1024
+
+// ErrMissingContentLength is returned for a ContentLength frame whose headers
+// have no Content-Length.
+var ErrMissingContentLength = errors.New("frame has no Content-Length header")
+
+// ContentLength is a Transport using the framing of the Language Server
+// Protocol, which some hosts also use for MCP: each message is preceded by
+// headers, one per line, and a blank line,
+//
+//	Content-Length: 52\r\n
+//	\r\n
+//	{"jsonrpc":"2.0","id":1,"method":"ping","params":{}}
+//
+// Content-Length, the payload size in bytes, is required; other headers such
+// as Content-Type are ignored. Lines ending in a bare "\n" are accepted.
+type ContentLength struct {
+	reader       *bufio.Reader
+	writer       io.Writer
+	closer       []io.Closer
+	mu           sync.Mutex // Protects writer access
+	MaxFrameSize int        // Largest accepted payload; DefaultMaxFrameSize unless changed before use
+}
+
+// NewContentLength creates a Content-Length framed transport reading from r and
+// writing to w. If r or w implement io.Closer they are closed by Close.
+func NewContentLength(r io.Reader, w io.Writer) *ContentLength {
+	return &ContentLength{
+		reader:       bufio.NewReader(r),
+		writer:       w,
+		closer:       closersOf(r, w),
+		MaxFrameSize: DefaultMaxFrameSize,
+	}
+}
+
+// ReadMessage reads one frame and returns its payload. A stream that ends
+// cleanly between frames returns io.EOF; one that ends inside a frame returns
+// io.ErrUnexpectedEOF.
+func (c *ContentLength) ReadMessage() ([]byte, error) {
+	length := -1
+	started := false // A header line has been read
+	for {
+		line, err := c.readHeaderLine()
+		if err != nil {
+			if err == io.EOF && started {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		if line == "" {
+			if !started {
+				continue // Tolerate blank lines between frames
+			}
+			break
+		}
+		started = true
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid frame header %q", line)
+		}
+		if strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			n, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid Content-Length %q", strings.TrimSpace(value))
+			}
+			length = n
+		}
+	}
+	if length < 0 {
+		return nil, ErrMissingContentLength
+	}
+	if length > c.MaxFrameSize {
+		return nil, fmt.Errorf("%w: %d bytes (max %d)", ErrFrameTooLarge, length, c.MaxFrameSize)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return payload, nil
+}
+
+// readHeaderLine reads one header line without its line ending.
+func (c *ContentLength) readHeaderLine() (string, error) {
+	var line []byte
+	for {
+		chunk, err := c.reader.ReadSlice('\n')
+		line = append(line, chunk...)
+		if len(line) > maxHeaderLine {
+			return "", fmt.Errorf("frame header line exceeds %d bytes", maxHeaderLine)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			if err == io.EOF && len(line) > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return "", err
+		}
+		return string(bytes.TrimRight(line, "\r\n")), nil
+	}
+}
+
+// WriteMessage writes the header and payload in a single write call, so
+// concurrent writers never interleave partial frames.
+func (c *ContentLength) WriteMessage(payload []byte) error {
+	if len(payload) > c.MaxFrameSize {
+		return fmt.Errorf("%w: %d bytes (max %d)", ErrFrameTooLarge, len(payload), c.MaxFrameSize)
+	}
+	frame := make([]byte, 0, len(payload)+32)
+	frame = append(frame, "Content-Length: "...)
+	frame = strconv.AppendInt(frame, int64(len(payload)), 10)
+	frame = append(frame, "\r\n\r\n"...)
+	frame = append(frame, payload...)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.writer.Write(frame); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	return nil
+}
+
+// Close closes the underlying reader and writer if they are closable.
+func (c *ContentLength) Close() error {
+	return closeAll(c.closer)
+}
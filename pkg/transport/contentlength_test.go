@@ -0,0 +1,72 @@
+package transport
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestContentLengthRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewContentLength(nil, &buf)
+	messages := [][]byte{
+		[]byte(`{"jsonrpc":"2.0","method":"ping","id":1}`),
+		[]byte("{\"text\":\"line one\nline two\"}"),
+		{},
+	}
+	for _, m := range messages {
+		if err := w.WriteMessage(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !strings.HasPrefix(buf.String(), "Content-Length: 40\r\n\r\n{") {
+		t.Errorf("frame = %q", buf.String())
+	}
+
+	r := NewContentLength(&buf, nil)
+	for i, want := range messages {
+		got, err := r.ReadMessage()
+		if err != nil {
+			t.Fatalf("message %d: %v", i, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("message %d = %q, want %q", i, got, want)
+		}
+	}
+	if _, err := r.ReadMessage(); err != io.EOF {
+		t.Errorf("read after last frame = %v, want io.EOF", err)
+	}
+}
+
+func TestContentLengthReadsLSPHeaders(t *testing.T) {
+	// Other headers are ignored, names are case-insensitive and bare newlines are accepted
+	input := "Content-Type: application/vscode-jsonrpc; charset=utf-8\r\ncontent-length: 2\r\n\r\n{}" +
+		"\r\nContent-Length: 4\n\n[1 ]"
+	r := NewContentLength(strings.NewReader(input), nil)
+	for _, want := range []string{"{}", "[1 ]"} {
+		if got, err := r.ReadMessage(); err != nil || string(got) != want {
+			t.Errorf("ReadMessage() = %q, %v; want %q", got, err, want)
+		}
+	}
+}
+
+func TestContentLengthErrors(t *testing.T) {
+	tests := map[string]error{
+		"Content-Type: x\r\n\r\n{}":         ErrMissingContentLength,
+		"Content-Length: 10\r\n\r\n{}":      io.ErrUnexpectedEOF,
+		"Content-Length: 2\r\n":             io.ErrUnexpectedEOF,
+		"Content-Length: 99999999\r\n\r\n{": ErrFrameTooLarge,
+	}
+	for input, want := range tests {
+		if _, err := NewContentLength(strings.NewReader(input), nil).ReadMessage(); !errors.Is(err, want) {
+			t.Errorf("%q: error = %v, want %v", input, err, want)
+		}
+	}
+	for _, input := range []string{"{\"jsonrpc\":\"2.0\"}\n", "Content-Length: -1\r\n\r\n", strings.Repeat("x", 2000)} {
+		if _, err := NewContentLength(strings.NewReader(input), nil).ReadMessage(); err == nil {
+			t.Errorf("%.40q: no error", input)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package transport
+
+import "sqirvy/mcp/pkg/mcp"
+
+// EmptyParamsWriter is a Transport decorator that writes requests and
+// notifications without params in one style, for a peer that accepts only that
+// one (see mcp.EmptyParams). Incoming messages are passed through unchanged.
+type EmptyParamsWriter struct {
+	Transport
+	style mcp.EmptyParams
+}
+
+// NewEmptyParamsWriter wraps t to write empty params as style asks.
+func NewEmptyParamsWriter(t Transport, style mcp.EmptyParams) *EmptyParamsWriter {
+	return &EmptyParamsWriter{Transport: t, style: style}
+}
+
+// WriteMessage writes payload with its empty params rewritten.
+func (w *EmptyParamsWriter) WriteMessage(payload []byte) error {
+	return w.Transport.WriteMessage(w.style.Apply(payload))
+}
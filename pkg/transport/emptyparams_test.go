@@ -0,0 +1,28 @@
+package transport
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"sqirvy/mcp/pkg/mcp"
+)
+
+func TestEmptyParamsWriter(t *testing.T) {
+	// Two connections of one process may need different styles
+	var omit, object bytes.Buffer
+	omitting := NewEmptyParamsWriter(NewStream(strings.NewReader(""), &omit), mcp.EmptyParamsOmit)
+	objects := NewEmptyParamsWriter(NewStream(strings.NewReader(""), &object), mcp.EmptyParamsObject)
+	list, _ := mcp.MarshalListToolsRequest(2, nil)
+	for _, w := range []Transport{omitting, objects} {
+		if err := w.WriteMessage(list); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := `{"jsonrpc":"2.0","method":"tools/list","id":2}` + "\n"; omit.String() != want {
+		t.Errorf("omit wrote %q, want %q", omit.String(), want)
+	}
+	if want := string(list) + "\n"; object.String() != want {
+		t.Errorf("object wrote %q, want %q", object.String(), want)
+	}
+}
@@ -0,0 +1,83 @@
+package transport
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// DefaultMaxFrameSize bounds the payload a LengthPrefixed transport accepts, so that a
+// corrupt or hostile length header cannot make the reader allocate unbounded memory.
+const DefaultMaxFrameSize = 16 << 20 // 16 MiB
+
+// ErrFrameTooLarge is returned for frames whose declared length exceeds the maximum.
+var ErrFrameTooLarge = errors.New("frame exceeds maximum size")
+
+// LengthPrefixed is a binary-safe Transport for socket connections. Each message is
+// sent as a 4-byte big-endian payload length followed by the payload, so payloads may
+// contain newlines and no delimiter scanning or header parsing is needed.
+type LengthPrefixed struct {
+	reader       *bufio.Reader
+	writer       io.Writer
+	closer       []io.Closer
+	mu           sync.Mutex // Protects writer access
+	MaxFrameSize int        // Largest accepted payload; DefaultMaxFrameSize unless changed before use
+}
+
+// NewLengthPrefixed creates a length-prefixed transport reading from r and writing to w.
+// If r or w implement io.Closer they are closed by Close.
+func NewLengthPrefixed(r io.Reader, w io.Writer) *LengthPrefixed {
+	return &LengthPrefixed{
+		reader:       bufio.NewReader(r),
+		writer:       w,
+		closer:       closersOf(r, w),
+		MaxFrameSize: DefaultMaxFrameSize,
+	}
+}
+
+// ReadMessage reads one frame and returns its payload. A stream that ends cleanly
+// between frames returns io.EOF; one that ends inside a frame returns io.ErrUnexpectedEOF.
+func (l *LengthPrefixed) ReadMessage() ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(l.reader, header[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(header[:])
+	if uint64(n) > uint64(l.MaxFrameSize) {
+		return nil, fmt.Errorf("%w: %d bytes (max %d)", ErrFrameTooLarge, n, l.MaxFrameSize)
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(l.reader, payload); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return payload, nil
+}
+
+// WriteMessage writes the length header and payload in a single write call,
+// so concurrent writers never interleave partial frames.
+func (l *LengthPrefixed) WriteMessage(payload []byte) error {
+	if len(payload) > l.MaxFrameSize {
+		return fmt.Errorf("%w: %d bytes (max %d)", ErrFrameTooLarge, len(payload), l.MaxFrameSize)
+	}
+	frame := make([]byte, 4+len(payload))
+	binary.BigEndian.PutUint32(frame, uint32(len(payload)))
+	copy(frame[4:], payload)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.writer.Write(frame); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	return nil
+}
+
+// Close closes the underlying reader and writer if they are closable.
+func (l *LengthPrefixed) Close() error {
+	return closeAll(l.closer)
+}
@@ -0,0 +1,140 @@
+package transport
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+)
+
+func TestLengthPrefixedRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewLengthPrefixed(nil, &buf)
+	messages := [][]byte{
+		[]byte(`{"jsonrpc":"2.0","method":"ping","id":1}`),
+		[]byte("{\"text\":\"line one\nline two\"}"), // Raw newline is fine with length framing
+		{},
+	}
+	for _, m := range messages {
+		if err := w.WriteMessage(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := NewLengthPrefixed(&buf, nil)
+	for i, want := range messages {
+		got, err := r.ReadMessage()
+		if err != nil {
+			t.Fatalf("message %d: %v", i, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("message %d = %q, want %q", i, got, want)
+		}
+	}
+	if _, err := r.ReadMessage(); err != io.EOF {
+		t.Errorf("read after last frame = %v, want io.EOF", err)
+	}
+}
+
+func TestLengthPrefixedErrors(t *testing.T) {
+	// Truncated payload
+	frame := make([]byte, 4, 8)
+	binary.BigEndian.PutUint32(frame, 10)
+	frame = append(frame, "abc"...)
+	if _, err := NewLengthPrefixed(bytes.NewReader(frame), nil).ReadMessage(); err != io.ErrUnexpectedEOF {
+		t.Errorf("truncated frame error = %v, want io.ErrUnexpectedEOF", err)
+	}
+
+	// Oversized length header
+	binary.BigEndian.PutUint32(frame, 1<<31)
+	r := NewLengthPrefixed(bytes.NewReader(frame[:4]), nil)
+	if _, err := r.ReadMessage(); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("oversized frame error = %v, want ErrFrameTooLarge", err)
+	}
+
+	w := NewLengthPrefixed(nil, io.Discard)
+	w.MaxFrameSize = 3
+	if err := w.WriteMessage([]byte("abcd")); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("oversized write error = %v, want ErrFrameTooLarge", err)
+	}
+}
+
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		spec, network, address string
+		wantErr                bool
+	}{
+		{"unix:/tmp/mcp.sock", "unix", "/tmp/mcp.sock", false},
+		{"tcp:localhost:9000", "tcp", "localhost:9000", false},
+		{"localhost:9000", "tcp", "localhost:9000", false},
+		{"unix:", "", "", true},
+		{"nocolon", "", "", true},
+	}
+	for _, tt := range tests {
+		network, address, err := ParseAddress(tt.spec)
+		if (err != nil) != tt.wantErr || network != tt.network || address != tt.address {
+			t.Errorf("ParseAddress(%q) = %q, %q, %v", tt.spec, network, address, err)
+		}
+	}
+}
+
+func TestSocketFramingSelection(t *testing.T) {
+	for _, framing := range []Framing{FramingNewline, FramingLength, FramingContentLength} {
+		t.Run(framing.String(), func(t *testing.T) {
+			sock := filepath.Join(t.TempDir(), "mcp.sock")
+			ln, err := net.Listen("unix", sock)
+			if err != nil {
+				t.Skipf("unix sockets unavailable: %v", err)
+			}
+			defer ln.Close()
+
+			type accepted struct {
+				t       Transport
+				framing Framing
+				err     error
+			}
+			done := make(chan accepted, 1)
+			go func() {
+				conn, err := ln.Accept()
+				if err != nil {
+					done <- accepted{err: err}
+					return
+				}
+				tr, f, err := Accept(conn)
+				done <- accepted{tr, f, err}
+			}()
+
+			client, err := Dial("unix", sock, framing)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer client.Close()
+			msg := []byte(`{"jsonrpc":"2.0","method":"ping","id":1}`)
+			if err := client.WriteMessage(msg); err != nil {
+				t.Fatal(err)
+			}
+
+			a := <-done
+			if a.err != nil {
+				t.Fatal(a.err)
+			}
+			defer a.t.Close()
+			if a.framing != framing {
+				t.Errorf("detected framing %v, want %v", a.framing, framing)
+			}
+			got, err := a.t.ReadMessage()
+			if err != nil || !bytes.Equal(got, msg) {
+				t.Fatalf("server read %q, %v", got, err)
+			}
+			if err := a.t.WriteMessage([]byte(`{"jsonrpc":"2.0","result":{},"id":1}`)); err != nil {
+				t.Fatal(err)
+			}
+			if got, err := client.ReadMessage(); err != nil || string(got) != `{"jsonrpc":"2.0","result":{},"id":1}` {
+				t.Fatalf("client read %q, %v", got, err)
+			}
+		})
+	}
+}
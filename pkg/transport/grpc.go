@@ -0,0 +1,165 @@
+package transport
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// gRPC binding
+//
+// proto/transport.proto defines the MCPTransport service: one bidirectional
+// stream of Frame messages per session, each carrying one JSON-RPC message.
+// This package does not depend on gRPC. Instead it provides the two pieces a
+// binary that does needs:
+//
+//   - FrameCodec, a gRPC codec (it satisfies google.golang.org/grpc/encoding.Codec)
+//     that encodes *Frame in the protobuf wire format of the Frame message, so
+//     peers using generated code interoperate with it;
+//   - NewMessageStream, which turns the stream of Connect (grpc.ClientStream or
+//     grpc.ServerStream, both of which implement MessageStream) into a Transport.
+//
+// mcp-server -transport=grpc and mcp-client -transport=grpc use them, see
+// their grpc.go. Wiring up a client looks like:
+//
+//	encoding.RegisterCodec(transport.FrameCodec{})
+//	desc := &grpc.StreamDesc{StreamName: "Connect", ClientStreams: true, ServerStreams: true}
+//	stream, _ := conn.NewStream(ctx, desc, transport.GRPCConnectMethod, grpc.CallContentSubtype(transport.FrameCodecName))
+//	t := transport.NewMessageStream(stream, stream.CloseSend)
+
+// GRPCConnectMethod is the full gRPC method name of MCPTransport.Connect.
+const GRPCConnectMethod = "/mcp.transport.v1.MCPTransport/Connect"
+
+// FrameCodecName is the gRPC content subtype of FrameCodec.
+const FrameCodecName = "mcpframe"
+
+// Frame is one message on an MCPTransport stream.
+type Frame struct {
+	Payload []byte // A single JSON-RPC message
+}
+
+// frameFieldPayload is the protobuf tag of Frame.payload: field 1, length-delimited.
+const frameFieldPayload = 1<<3 | 2
+
+// FrameCodec marshals *Frame values in the protobuf wire format.
+type FrameCodec struct{}
+
+// Name returns FrameCodecName.
+func (FrameCodec) Name() string { return FrameCodecName }
+
+// Marshal encodes a *Frame.
+func (FrameCodec) Marshal(v interface{}) ([]byte, error) {
+	f, ok := v.(*Frame)
+	if !ok {
+		return nil, fmt.Errorf("frame codec: cannot marshal %T", v)
+	}
+	if len(f.Payload) == 0 {
+		return []byte{}, nil // proto3 omits empty fields
+	}
+	out := make([]byte, 0, len(f.Payload)+1+binary.MaxVarintLen64)
+	out = append(out, frameFieldPayload)
+	out = binary.AppendUvarint(out, uint64(len(f.Payload)))
+	return append(out, f.Payload...), nil
+}
+
+// Unmarshal decodes data into a *Frame, skipping unknown fields as protobuf requires.
+func (FrameCodec) Unmarshal(data []byte, v interface{}) error {
+	f, ok := v.(*Frame)
+	if !ok {
+		return fmt.Errorf("frame codec: cannot unmarshal into %T", v)
+	}
+	f.Payload = nil
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errors.New("frame codec: invalid field tag")
+		}
+		data = data[n:]
+		var value []byte
+		switch tag & 7 {
+		case 0: // varint
+			if _, n = binary.Uvarint(data); n <= 0 {
+				return errors.New("frame codec: invalid varint")
+			}
+		case 1: // 64-bit
+			n = 8
+		case 2: // length-delimited
+			length, m := binary.Uvarint(data)
+			if m <= 0 || length > uint64(len(data)-m) {
+				return errors.New("frame codec: invalid length")
+			}
+			value = data[m : m+int(length)]
+			n = m + int(length)
+		case 5: // 32-bit
+			n = 4
+		default:
+			return fmt.Errorf("frame codec: unsupported wire type %d", tag&7)
+		}
+		if n > len(data) {
+			return io.ErrUnexpectedEOF
+		}
+		if tag == frameFieldPayload {
+			f.Payload = append([]byte(nil), value...)
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+// MessageStream is the part of a gRPC stream used by the transport.
+type MessageStream interface {
+	SendMsg(m interface{}) error
+	RecvMsg(m interface{}) error
+}
+
+// messageStream is the Transport returned by NewMessageStream.
+type messageStream struct {
+	stream MessageStream
+	close  func() error
+	mu     sync.Mutex // gRPC streams allow only one concurrent SendMsg
+	once   sync.Once
+}
+
+// NewMessageStream adapts a stream of Frame messages, such as a gRPC Connect
+// stream using FrameCodec, to a Transport. closeFn ends the stream on Close,
+// e.g. ClientStream.CloseSend or the cancel function of the stream's context;
+// it may be nil.
+func NewMessageStream(stream MessageStream, closeFn func() error) Transport {
+	return &messageStream{stream: stream, close: closeFn}
+}
+
+// ReadMessage returns the payload of the next non-empty frame.
+func (m *messageStream) ReadMessage() ([]byte, error) {
+	for {
+		var f Frame
+		if err := m.stream.RecvMsg(&f); err != nil {
+			return nil, err
+		}
+		if len(f.Payload) > 0 {
+			return f.Payload, nil
+		}
+	}
+}
+
+// WriteMessage sends payload as one frame.
+func (m *messageStream) WriteMessage(payload []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.stream.SendMsg(&Frame{Payload: payload}); err != nil {
+		return fmt.Errorf("failed to send frame: %w", err)
+	}
+	return nil
+}
+
+// Close ends the stream once.
+func (m *messageStream) Close() error {
+	var err error
+	m.once.Do(func() {
+		if m.close != nil {
+			err = m.close()
+		}
+	})
+	return err
+}
@@ -0,0 +1,94 @@
+package transport
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestFrameCodecWireFormat(t *testing.T) {
+	var codec FrameCodec
+	data, err := codec.Marshal(&Frame{Payload: []byte("{}")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// What protoc-generated code produces for Frame{payload: "{}"}
+	if want := []byte{0x0a, 0x02, '{', '}'}; !bytes.Equal(data, want) {
+		t.Errorf("Marshal() = % x, want % x", data, want)
+	}
+
+	// Unknown fields (varint 2, fixed64 3, fixed32 4, bytes 5) are skipped
+	withUnknown := []byte{0x10, 0x96, 0x01, 0x19, 1, 2, 3, 4, 5, 6, 7, 8, 0x25, 1, 2, 3, 4, 0x2a, 0x01, 'x'}
+	withUnknown = append(withUnknown, data...)
+	var f Frame
+	if err := codec.Unmarshal(withUnknown, &f); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if string(f.Payload) != "{}" {
+		t.Errorf("Unmarshal() payload = %q", f.Payload)
+	}
+
+	for _, bad := range [][]byte{{0x0a, 0x05, '{'}, {0x0a}, {0x19, 1, 2}, {0x0b}} {
+		if err := codec.Unmarshal(bad, &f); err == nil {
+			t.Errorf("Unmarshal(% x) succeeded, want error", bad)
+		}
+	}
+	if _, err := codec.Marshal("not a frame"); err == nil {
+		t.Error("Marshal(string) succeeded, want error")
+	}
+}
+
+// codecStream is an in-memory MessageStream that passes messages through FrameCodec, as gRPC would.
+type codecStream struct {
+	in  chan []byte
+	out chan []byte
+}
+
+func (s *codecStream) SendMsg(m interface{}) error {
+	data, err := FrameCodec{}.Marshal(m)
+	if err != nil {
+		return err
+	}
+	s.out <- data
+	return nil
+}
+
+func (s *codecStream) RecvMsg(m interface{}) error {
+	data, ok := <-s.in
+	if !ok {
+		return io.EOF
+	}
+	return FrameCodec{}.Unmarshal(data, m)
+}
+
+func TestMessageStreamTransport(t *testing.T) {
+	a2b, b2a := make(chan []byte, 4), make(chan []byte, 4)
+	closed := 0
+	a := NewMessageStream(&codecStream{in: b2a, out: a2b}, func() error { closed++; close(a2b); return nil })
+	b := NewMessageStream(&codecStream{in: a2b, out: b2a}, nil)
+
+	// Payloads may contain newlines; empty frames are skipped
+	payload := "{\"a\":\n1}"
+	if err := a.WriteMessage(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.WriteMessage([]byte(payload)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := b.ReadMessage()
+	if err != nil || string(got) != payload {
+		t.Fatalf("ReadMessage() = %q, %v", got, err)
+	}
+
+	a.Close()
+	a.Close()
+	if closed != 1 {
+		t.Errorf("close function called %d times, want 1", closed)
+	}
+	if _, err := b.ReadMessage(); err != io.EOF {
+		t.Errorf("ReadMessage() after peer close error = %v, want io.EOF", err)
+	}
+	if err := b.Close(); err != nil {
+		t.Errorf("Close() with nil close function = %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+
+	"sqirvy/mcp/pkg/mcp"
+)
+
+// ErrNotProtocol is returned (wrapped) by a Guard for a payload that is not a JSON-RPC message.
+var ErrNotProtocol = errors.New("payload is not a JSON-RPC message")
+
+// Guard is a Transport decorator that refuses to write anything but well-formed JSON-RPC
+// messages, so that a bug producing non-protocol bytes is reported instead of corrupting
+// the stream. Incoming messages are passed through unchanged.
+type Guard struct {
+	Transport
+	report func(payload []byte, err error)
+}
+
+// NewGuard wraps t. report, if not nil, is called for every rejected payload.
+func NewGuard(t Transport, report func(payload []byte, err error)) *Guard {
+	return &Guard{Transport: t, report: report}
+}
+
+// WriteMessage writes payload if it is a JSON-RPC request, notification or response.
+func (g *Guard) WriteMessage(payload []byte) error {
+	if _, err := mcp.ClassifyMessage(payload); err != nil {
+		err = fmt.Errorf("%w: %v", ErrNotProtocol, err)
+		if g.report != nil {
+			g.report(payload, err)
+		}
+		return err
+	}
+	return g.Transport.WriteMessage(payload)
+}
@@ -0,0 +1,445 @@
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"sqirvy/mcp/pkg/mcp"
+)
+
+// Header names used by the Streamable HTTP transport.
+const (
+	HeaderSessionID   = "Mcp-Session-Id"
+	HeaderLastEventID = "Last-Event-ID"
+)
+
+// DefaultSSERetry is how long the HTTP transport waits before reopening a dropped
+// event stream when the server has not sent a retry interval.
+const DefaultSSERetry = time.Second
+
+// maxSSEResumes bounds the reconnection attempts for a dropped event stream
+// that delivers nothing in between.
+const maxSSEResumes = 5
+
+// ErrSessionExpired is returned by HTTP.WriteMessage when the server no longer
+// knows the session (404 Not Found); the client must initialize again.
+var ErrSessionExpired = errors.New("HTTP session expired")
+
+// errNoEventStream means the server does not offer a GET event stream (405 Method Not Allowed).
+var errNoEventStream = errors.New("server does not offer an event stream")
+
+// HTTPOptions configures an HTTP transport.
+type HTTPOptions struct {
+	// Client sends the requests. Nil uses http.DefaultClient; see NewHTTPClient
+	// for proxies and certificate authorities.
+	Client *http.Client
+	// Header is added to every request, e.g. X-Api-Key. It cannot replace the
+	// headers the transport sets itself.
+	Header http.Header
+}
+
+// HTTP is the client side of the MCP Streamable HTTP transport. Every message is
+// POSTed to a single endpoint, and the server answers with a JSON body, an SSE
+// stream of messages, or 202 Accepted. The session ID the server assigns during
+// initialize is sent with every later request. Once the initialized notification
+// has been sent, a GET event stream carries server-initiated messages. Event
+// streams that break are resumed with Last-Event-ID.
+type HTTP struct {
+	endpoint string
+	client   *http.Client
+	header   http.Header
+
+	ctx      context.Context // Canceled by Close, aborting requests and event streams
+	cancel   context.CancelFunc
+	incoming chan []byte
+	errs     chan error     // Holds the first error that ends the transport
+	streams  sync.WaitGroup // Running event stream readers
+
+	mu        sync.Mutex // Protects the fields below
+	sessionID string
+	listening bool // The GET event stream has been started
+	closed    bool
+}
+
+// NewHTTP returns a transport for the MCP endpoint at rawURL, e.g. https://example.com/mcp.
+func NewHTTP(rawURL string, opts HTTPOptions) (*HTTP, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid URL %q (want http:// or https://)", rawURL)
+	}
+	client := opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &HTTP{
+		endpoint: u.String(),
+		client:   client,
+		header:   opts.Header.Clone(),
+		ctx:      ctx,
+		cancel:   cancel,
+		incoming: make(chan []byte, 16),
+		errs:     make(chan error, 1),
+	}, nil
+}
+
+// SessionID returns the session ID assigned by the server, if any.
+func (h *HTTP) SessionID() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.sessionID
+}
+
+// ReadMessage returns the next message from any of the server's responses and
+// event streams.
+func (h *HTTP) ReadMessage() ([]byte, error) {
+	select {
+	case payload := <-h.incoming: // Deliver what has arrived before reporting an error
+		return payload, nil
+	default:
+	}
+	select {
+	case payload := <-h.incoming:
+		return payload, nil
+	case err := <-h.errs:
+		return nil, err
+	case <-h.ctx.Done():
+		return nil, ErrClosed
+	}
+}
+
+// WriteMessage POSTs one message. Messages in the response, either its JSON
+// body or an event stream, are returned by ReadMessage.
+func (h *HTTP) WriteMessage(payload []byte) error {
+	req, err := h.newRequest(http.MethodPost, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	resp, err := h.client.Do(req)
+	if err != nil {
+		if h.ctx.Err() != nil {
+			return ErrClosed
+		}
+		return fmt.Errorf("POST %s: %w", h.endpoint, err)
+	}
+	if id := resp.Header.Get(HeaderSessionID); id != "" {
+		h.mu.Lock()
+		h.sessionID = id
+		h.mu.Unlock()
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusAccepted:
+		resp.Body.Close()
+		if isInitializedNotification(payload) {
+			h.listen()
+		}
+		return nil
+	case resp.StatusCode == http.StatusNotFound && req.Header.Get(HeaderSessionID) != "":
+		resp.Body.Close()
+		h.mu.Lock()
+		h.sessionID = "" // The next initialize starts a new session
+		h.mu.Unlock()
+		return ErrSessionExpired
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return statusError(resp)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/event-stream":
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if h.closed {
+			resp.Body.Close()
+			return ErrClosed
+		}
+		h.streams.Add(1)
+		go h.consume(resp.Body, false)
+		return nil
+	case "application/json":
+		defer resp.Body.Close()
+		body, err := io.ReadAll(io.LimitReader(resp.Body, DefaultMaxLineSize+1))
+		if err != nil {
+			return fmt.Errorf("failed to read response: %w", err)
+		}
+		if len(body) > DefaultMaxLineSize {
+			return &LineTooLongError{Size: int64(len(body)), Max: DefaultMaxLineSize, Prefix: body[:lineTooLongPrefix]}
+		}
+		return h.deliverJSON(body)
+	default:
+		resp.Body.Close()
+		return fmt.Errorf("POST %s: unexpected Content-Type %q", h.endpoint, resp.Header.Get("Content-Type"))
+	}
+}
+
+// Close stops all event streams and ends the session on the server.
+func (h *HTTP) Close() error {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return nil
+	}
+	h.closed = true
+	sessionID := h.sessionID
+	h.mu.Unlock()
+
+	h.cancel()
+	h.streams.Wait()
+	if sessionID == "" {
+		return nil
+	}
+	// Servers may refuse to end sessions (405); either way the session is over for us
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, h.endpoint, nil)
+	if err != nil {
+		return nil
+	}
+	for name, values := range h.header {
+		req.Header[name] = values
+	}
+	req.Header.Set(HeaderSessionID, sessionID)
+	if resp, err := h.client.Do(req); err == nil {
+		resp.Body.Close()
+	}
+	return nil
+}
+
+// newRequest builds a request to the endpoint that carries the session ID.
+func (h *HTTP) newRequest(method string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(h.ctx, method, h.endpoint, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s request: %w", method, err)
+	}
+	for name, values := range h.header {
+		req.Header[name] = values
+	}
+	if id := h.SessionID(); id != "" {
+		req.Header.Set(HeaderSessionID, id)
+	}
+	return req, nil
+}
+
+// listen starts the GET event stream for server-initiated messages, once. A
+// server without one is not an error.
+func (h *HTTP) listen() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.listening || h.closed {
+		return
+	}
+	h.listening = true
+	h.streams.Add(1)
+	go func() {
+		body, err := h.openStream("")
+		if err != nil {
+			h.streams.Done()
+			return
+		}
+		h.consume(body, true)
+	}()
+}
+
+// openStream opens a GET event stream, resuming after lastEventID if it is set.
+func (h *HTTP) openStream(lastEventID string) (io.ReadCloser, error) {
+	req, err := h.newRequest(http.MethodGet, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if lastEventID != "" {
+		req.Header.Set(HeaderLastEventID, lastEventID)
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("GET %s: %w", h.endpoint, err)
+	}
+	if resp.StatusCode == http.StatusMethodNotAllowed {
+		resp.Body.Close()
+		return nil, errNoEventStream
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp)
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "text/event-stream" {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: unexpected Content-Type %q", h.endpoint, resp.Header.Get("Content-Type"))
+	}
+	return resp.Body, nil
+}
+
+// consume delivers the messages of an event stream. A stream answering a POST
+// ends when the server closes it; if it breaks instead, it is resumed with GET
+// and Last-Event-ID, and failing to resume ends the transport. The listening GET
+// stream is reopened whenever it ends, until that fails maxSSEResumes times in a row.
+func (h *HTTP) consume(body io.ReadCloser, listen bool) {
+	defer h.streams.Done()
+	var lastEventID string
+	retry := DefaultSSERetry
+	attempts := 0
+	for {
+		err := readSSE(body, func(ev sseEvent) {
+			if ev.id != "" {
+				lastEventID = ev.id
+			}
+			if ev.retry > 0 {
+				retry = ev.retry
+			}
+			if ev.data != "" && (ev.event == "" || ev.event == "message") {
+				attempts = 0
+				h.deliver([]byte(ev.data))
+			}
+		})
+		body.Close()
+		if h.ctx.Err() != nil || (err == nil && !listen) {
+			return
+		}
+		if !listen && lastEventID == "" {
+			h.fail(fmt.Errorf("event stream broke before any resumable event: %w", err))
+			return
+		}
+
+		for {
+			if attempts++; attempts > maxSSEResumes {
+				if !listen { // Losing the GET stream only loses server-initiated messages
+					h.fail(fmt.Errorf("event stream lost after %d attempts to resume: %w", maxSSEResumes, err))
+				}
+				return
+			}
+			select {
+			case <-time.After(retry):
+			case <-h.ctx.Done():
+				return
+			}
+			if body, err = h.openStream(lastEventID); err == nil {
+				break
+			}
+			if errors.Is(err, errNoEventStream) {
+				if !listen {
+					h.fail(fmt.Errorf("cannot resume event stream: %w", err))
+				}
+				return
+			}
+		}
+	}
+}
+
+// deliverJSON delivers a JSON response body holding one message or a batch.
+func (h *HTTP) deliverJSON(body []byte) error {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 || body[0] != '[' {
+		h.deliver(body)
+		return nil
+	}
+	var batch []json.RawMessage
+	if err := json.Unmarshal(body, &batch); err != nil {
+		return fmt.Errorf("invalid JSON-RPC batch in response: %w", err)
+	}
+	for _, payload := range batch {
+		h.deliver(payload)
+	}
+	return nil
+}
+
+// deliver queues a message for ReadMessage unless the transport is closed.
+func (h *HTTP) deliver(payload []byte) {
+	select {
+	case h.incoming <- payload:
+	case <-h.ctx.Done():
+	}
+}
+
+// fail records err as the reason the transport stopped working, if it is the first.
+func (h *HTTP) fail(err error) {
+	select {
+	case h.errs <- err:
+	default:
+	}
+}
+
+// statusError reports an unexpected HTTP status, including the start of the body.
+func statusError(resp *http.Response) error {
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if text := strings.TrimSpace(string(body)); text != "" {
+		return fmt.Errorf("%s %s: %s: %s", resp.Request.Method, resp.Request.URL, resp.Status, text)
+	}
+	return fmt.Errorf("%s %s: %s", resp.Request.Method, resp.Request.URL, resp.Status)
+}
+
+// isInitializedNotification reports whether payload is the notification that
+// completes the initialization handshake.
+func isInitializedNotification(payload []byte) bool {
+	info, err := mcp.ClassifyMessage(payload)
+	return err == nil && info.Kind == mcp.KindNotification && info.Method == mcp.MethodInitialized
+}
+
+// sseEvent is one server-sent event.
+type sseEvent struct {
+	id    string
+	event string
+	data  string
+	retry time.Duration
+}
+
+// readSSE parses a text/event-stream, calling fn for each complete event. It
+// returns nil when the stream ends cleanly; an unterminated last event is dropped.
+func readSSE(r io.Reader, fn func(sseEvent)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), DefaultMaxLineSize)
+	var ev sseEvent
+	var data []string
+	pending := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if pending {
+				ev.data = strings.Join(data, "\n")
+				fn(ev)
+			}
+			ev, data, pending = sseEvent{}, nil, false
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue // Comment, e.g. a keep-alive
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			ev.event = value
+		case "data":
+			data = append(data, value)
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				ev.id = value
+			}
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms >= 0 {
+				ev.retry = time.Duration(ms) * time.Millisecond
+			}
+		default:
+			continue
+		}
+		pending = true
+	}
+	return scanner.Err()
+}
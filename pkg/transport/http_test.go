@@ -0,0 +1,195 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// streamableServer is a minimal Streamable HTTP endpoint. tools/list is answered
+// on an event stream that breaks after its first event, so the client must
+// resume it with Last-Event-ID to get the response.
+type streamableServer struct {
+	mu      sync.Mutex
+	resumed string   // Last-Event-ID of the resuming GET
+	deleted bool     // The session was ended with DELETE
+	missing []string // Requests that lacked the session ID
+}
+
+func (s *streamableServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	if r.Header.Get(HeaderSessionID) != "sess-1" && !strings.Contains(readBody(r), `"initialize"`) {
+		s.missing = append(s.missing, r.Method)
+	}
+	s.mu.Unlock()
+	switch r.Method {
+	case http.MethodDelete:
+		s.mu.Lock()
+		s.deleted = true
+		s.mu.Unlock()
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "text/event-stream")
+		if last := r.Header.Get(HeaderLastEventID); last != "" {
+			s.mu.Lock()
+			s.resumed = last
+			s.mu.Unlock()
+			fmt.Fprint(w, "id: 2\ndata: {\"jsonrpc\":\"2.0\",\"id\":2,\"result\":{\"tools\":[]}}\n\n")
+			return
+		}
+		fmt.Fprint(w, ": keep-alive\nevent: message\ndata: {\"jsonrpc\":\"2.0\",\n")
+		fmt.Fprint(w, "data: \"method\":\"notifications/tools/list_changed\"}\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	case http.MethodPost:
+		body := readBody(r)
+		switch {
+		case strings.Contains(body, `"initialize"`):
+			w.Header().Set(HeaderSessionID, "sess-1")
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":{}}`)
+		case strings.Contains(body, `"tools/list"`):
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "retry: 10\nid: 1\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\"}\n\n")
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler) // Drop the connection mid-stream
+		default:
+			w.WriteHeader(http.StatusAccepted)
+		}
+	}
+}
+
+func readBody(r *http.Request) string {
+	if r.Body == nil {
+		return ""
+	}
+	body, _ := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(strings.NewReader(string(body)))
+	return string(body)
+}
+
+func TestHTTPStreamable(t *testing.T) {
+	s := &streamableServer{}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	h, err := NewHTTP(srv.URL+"/mcp", HTTPOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(payload string) {
+		t.Helper()
+		if err := h.WriteMessage([]byte(payload)); err != nil {
+			t.Fatalf("WriteMessage(%s) = %v", payload, err)
+		}
+	}
+	read := func() string {
+		t.Helper()
+		done := make(chan string, 1)
+		go func() {
+			payload, err := h.ReadMessage()
+			if err != nil {
+				payload = []byte(err.Error())
+			}
+			done <- string(payload)
+		}()
+		select {
+		case payload := <-done:
+			return payload
+		case <-time.After(5 * time.Second):
+			t.Fatal("no message")
+			return ""
+		}
+	}
+
+	write(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`)
+	if got := read(); got != `{"jsonrpc":"2.0","id":1,"result":{}}` {
+		t.Fatalf("initialize response = %s", got)
+	}
+	if h.SessionID() != "sess-1" {
+		t.Fatalf("SessionID = %q", h.SessionID())
+	}
+	write(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+	write(`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)
+
+	got := []string{read(), read(), read()}
+	sort.Strings(got)
+	want := []string{
+		`{"jsonrpc":"2.0",` + "\n" + `"method":"notifications/tools/list_changed"}`,
+		`{"jsonrpc":"2.0","id":2,"result":{"tools":[]}}`,
+		`{"jsonrpc":"2.0","method":"notifications/progress"}`,
+	}
+	sort.Strings(want)
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("messages = %q\nwant %q", got, want)
+	}
+
+	if err := h.Close(); err != nil {
+		t.Fatalf("Close = %v", err)
+	}
+	if _, err := h.ReadMessage(); !errors.Is(err, ErrClosed) {
+		t.Errorf("ReadMessage after Close = %v, want ErrClosed", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.resumed != "1" || !s.deleted || len(s.missing) > 0 {
+		t.Errorf("resumed after %q, deleted %v, requests without session %v", s.resumed, s.deleted, s.missing)
+	}
+}
+
+func TestHTTPSessionExpired(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(HeaderSessionID) != "" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set(HeaderSessionID, "old")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	h, err := NewHTTP(srv.URL, HTTPOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	if err := h.WriteMessage([]byte(`{"jsonrpc":"2.0","method":"notifications/progress"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.WriteMessage([]byte(`{"jsonrpc":"2.0","method":"notifications/progress"}`)); !errors.Is(err, ErrSessionExpired) {
+		t.Errorf("WriteMessage = %v, want ErrSessionExpired", err)
+	}
+	if h.SessionID() != "" {
+		t.Errorf("SessionID after expiry = %q", h.SessionID())
+	}
+}
+
+func TestNewHTTPRejectsNonHTTPURLs(t *testing.T) {
+	for _, u := range []string{"ftp://example.com/mcp", "example.com/mcp", "http://"} {
+		if _, err := NewHTTP(u, HTTPOptions{}); err == nil {
+			t.Errorf("NewHTTP(%q) succeeded", u)
+		}
+	}
+}
+
+func TestReadSSE(t *testing.T) {
+	stream := "id: 7\nevent: ping\ndata\n\n: comment\ndata: a\r\ndata:b\n\nretry: 250\n\ndata: unterminated"
+	var got []sseEvent
+	if err := readSSE(strings.NewReader(stream), func(ev sseEvent) { got = append(got, ev) }); err != nil {
+		t.Fatal(err)
+	}
+	want := []sseEvent{
+		{id: "7", event: "ping"},
+		{data: "a\nb"},
+		{retry: 250 * time.Millisecond},
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("events = %+v, want %+v", got, want)
+	}
+}
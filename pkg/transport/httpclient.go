@@ -0,0 +1,68 @@
+package transport
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// NetworkConfig configures the HTTP client of network transports for
+// enterprise networks: proxies, private certificate authorities and, for
+// testing only, disabled certificate checks.
+type NetworkConfig struct {
+	// ProxyURL is the proxy for all requests, e.g. http://proxy:3128 or
+	// socks5://proxy:1080. Empty uses HTTP_PROXY, HTTPS_PROXY and NO_PROXY.
+	ProxyURL string
+	// CAFile is a PEM bundle of certificate authorities trusted in addition to
+	// the system roots.
+	CAFile string
+	// InsecureSkipVerify disables server certificate verification. Connections
+	// can then be intercepted by anyone on the path; never use it in production.
+	InsecureSkipVerify bool
+}
+
+// NewHTTPClient returns an http.Client configured by cfg. Its transport can be
+// wrapped, e.g. by NewOAuth, and the client passed in HTTPOptions.
+func NewHTTPClient(cfg NetworkConfig) (*http.Client, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.ProxyURL != "" {
+		proxy, err := url.Parse(cfg.ProxyURL)
+		if err != nil || proxy.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", cfg.ProxyURL)
+		}
+		t.Proxy = http.ProxyURL(proxy)
+	}
+	if cfg.CAFile != "" || cfg.InsecureSkipVerify {
+		t.TLSClientConfig = &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+	}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", cfg.CAFile)
+		}
+		t.TLSClientConfig.RootCAs = roots
+	}
+	return &http.Client{Transport: t}, nil
+}
+
+// ParseHeader parses a "Name: value" header line, e.g. from a command line flag.
+func ParseHeader(line string) (name, value string, err error) {
+	name, value, ok := strings.Cut(line, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" || strings.ContainsAny(name, " \t") {
+		return "", "", fmt.Errorf("invalid header %q (want \"Name: value\")", line)
+	}
+	return textproto.CanonicalMIMEHeaderKey(name), strings.TrimSpace(value), nil
+}
@@ -0,0 +1,107 @@
+package transport
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewHTTPClientTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		cfg  NetworkConfig
+		ok   bool
+	}{
+		{"system roots only", NetworkConfig{}, false},
+		{"CA bundle", NetworkConfig{CAFile: caFile}, true},
+		{"skip verify", NetworkConfig{InsecureSkipVerify: true}, true},
+	}
+	for _, tt := range tests {
+		client, err := NewHTTPClient(tt.cfg)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		resp, err := client.Get(srv.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: GET error = %v, want success %v", tt.name, err, tt.ok)
+		}
+	}
+
+	if _, err := NewHTTPClient(NetworkConfig{CAFile: filepath.Join(t.TempDir(), "missing.pem")}); err == nil {
+		t.Error("NewHTTPClient accepted a missing CA bundle")
+	}
+	if err := os.WriteFile(caFile, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewHTTPClient(NetworkConfig{CAFile: caFile}); err == nil {
+		t.Error("NewHTTPClient accepted a bundle without certificates")
+	}
+}
+
+func TestNewHTTPClientProxyAndHeaders(t *testing.T) {
+	var proxied *http.Request
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer proxy.Close()
+
+	client, err := NewHTTPClient(NetworkConfig{ProxyURL: proxy.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := NewHTTP("http://mcp.example/mcp", HTTPOptions{Client: client, Header: http.Header{"X-Api-Key": {"secret"}, "Accept": {"text/plain"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	if err := h.WriteMessage([]byte(`{"jsonrpc":"2.0","method":"notifications/progress"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if proxied == nil || proxied.URL.String() != "http://mcp.example/mcp" {
+		t.Fatalf("proxy saw %v, want the absolute endpoint URL", proxied)
+	}
+	if got := proxied.Header.Get("X-Api-Key"); got != "secret" {
+		t.Errorf("X-Api-Key = %q", got)
+	}
+	if got := proxied.Header.Get("Accept"); got != "application/json, text/event-stream" {
+		t.Errorf("Accept = %q; custom headers must not replace the transport's own", got)
+	}
+
+	if _, err := NewHTTPClient(NetworkConfig{ProxyURL: "::"}); err == nil {
+		t.Error("NewHTTPClient accepted an invalid proxy URL")
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	tests := []struct {
+		line, name, value string
+		ok                bool
+	}{
+		{"x-api-key: abc", "X-Api-Key", "abc", true},
+		{"Authorization:Bearer a:b", "Authorization", "Bearer a:b", true},
+		{"Empty:", "Empty", "", true},
+		{"no colon", "", "", false},
+		{": value", "", "", false},
+		{"Bad Name: v", "", "", false},
+	}
+	for _, tt := range tests {
+		name, value, err := ParseHeader(tt.line)
+		if (err == nil) != tt.ok || name != tt.name || value != tt.value {
+			t.Errorf("ParseHeader(%q) = %q, %q, %v", tt.line, name, value, err)
+		}
+	}
+}
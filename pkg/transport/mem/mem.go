@@ -0,0 +1,99 @@
+// Package mem provides an in-process Transport, so that an MCP client and
+// server can be wired together inside one test binary without a subprocess,
+// a socket or any framing:
+//
+//	clientSide, serverSide := mem.NewPair()
+//	go serve(serverSide)            // The server session under test
+//	client := newClient(clientSide) // Any client taking a transport.Transport
+//
+// Messages are handed over on channels, each copied so that neither side sees
+// the other reuse its buffers.
+package mem
+
+import (
+	"io"
+	"sync"
+
+	"sqirvy/mcp/pkg/transport"
+)
+
+// DefaultBuffer is how many messages NewPair lets one side write before the
+// other reads; further writes block, as on a full pipe.
+const DefaultBuffer = 64
+
+// Transport is one end of an in-memory connection made by NewPair or NewBufferedPair.
+type Transport struct {
+	in     <-chan []byte
+	out    chan<- []byte
+	closed chan struct{} // Closed by Close
+	peer   *Transport
+
+	once sync.Once
+}
+
+var _ transport.Transport = (*Transport)(nil)
+
+// NewPair returns the two connected ends of an in-memory connection, each
+// reading what the other writes.
+func NewPair() (*Transport, *Transport) {
+	return NewBufferedPair(DefaultBuffer)
+}
+
+// NewBufferedPair is NewPair with room for buffer unread messages in each
+// direction; with 0 every write waits for the peer to read it.
+func NewBufferedPair(buffer int) (*Transport, *Transport) {
+	aToB := make(chan []byte, buffer)
+	bToA := make(chan []byte, buffer)
+	a := &Transport{in: bToA, out: aToB, closed: make(chan struct{})}
+	b := &Transport{in: aToB, out: bToA, closed: make(chan struct{})}
+	a.peer, b.peer = b, a
+	return a, b
+}
+
+// ReadMessage returns the next message written by the peer. Once the peer is
+// closed and every message it wrote has been read it returns io.EOF, and
+// transport.ErrClosed after this end is closed.
+func (t *Transport) ReadMessage() ([]byte, error) {
+	select {
+	case payload := <-t.in:
+		return payload, nil
+	case <-t.closed:
+		return nil, transport.ErrClosed
+	case <-t.peer.closed:
+		// Deliver what the peer wrote before it closed
+		select {
+		case payload := <-t.in:
+			return payload, nil
+		default:
+			return nil, io.EOF
+		}
+	}
+}
+
+// WriteMessage hands a copy of payload to the peer, blocking while its buffer
+// is full. It returns io.ErrClosedPipe once the peer is closed.
+func (t *Transport) WriteMessage(payload []byte) error {
+	select {
+	case <-t.closed:
+		return transport.ErrClosed
+	case <-t.peer.closed:
+		return io.ErrClosedPipe
+	default:
+	}
+	message := append([]byte(nil), payload...)
+	select {
+	case t.out <- message:
+		return nil
+	case <-t.closed:
+		return transport.ErrClosed
+	case <-t.peer.closed:
+		return io.ErrClosedPipe
+	}
+}
+
+// Close closes this end. Blocked reads and writes on it return
+// transport.ErrClosed; the peer reads io.EOF once it has read what was written.
+func (t *Transport) Close() error {
+	t.once.Do(func() { close(t.closed) })
+	return nil
+}
@@ -0,0 +1,73 @@
+package mem
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"sqirvy/mcp/pkg/transport"
+)
+
+func TestPair(t *testing.T) {
+	client, server := NewPair()
+
+	// Messages arrive in order, as copies
+	payload := []byte(`{"jsonrpc":"2.0","id":1,"method":"ping"}`)
+	if err := client.WriteMessage(payload); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.WriteMessage([]byte(`{"jsonrpc":"2.0","id":2,"method":"ping"}`)); err != nil {
+		t.Fatal(err)
+	}
+	payload[0] = 'X'
+	for _, want := range []string{`{"jsonrpc":"2.0","id":1,"method":"ping"}`, `{"jsonrpc":"2.0","id":2,"method":"ping"}`} {
+		got, err := server.ReadMessage()
+		if err != nil || string(got) != want {
+			t.Fatalf("ReadMessage = %s, %v; want %s", got, err, want)
+		}
+	}
+
+	// Both directions work
+	if err := server.WriteMessage([]byte(`{"jsonrpc":"2.0","id":1,"result":{}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := client.ReadMessage(); err != nil || string(got) != `{"jsonrpc":"2.0","id":1,"result":{}}` {
+		t.Fatalf("client ReadMessage = %s, %v", got, err)
+	}
+
+	// What was written before Close is still delivered, then io.EOF
+	server.WriteMessage([]byte(`{"jsonrpc":"2.0","method":"notifications/progress"}`))
+	server.Close()
+	if got, err := client.ReadMessage(); err != nil || len(got) == 0 {
+		t.Fatalf("read after peer close = %s, %v", got, err)
+	}
+	if _, err := client.ReadMessage(); err != io.EOF {
+		t.Errorf("read of closed peer = %v, want io.EOF", err)
+	}
+	if err := client.WriteMessage([]byte(`{}`)); !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("write to closed peer = %v", err)
+	}
+	if _, err := server.ReadMessage(); !errors.Is(err, transport.ErrClosed) {
+		t.Errorf("read of closed end = %v", err)
+	}
+}
+
+func TestCloseUnblocksRead(t *testing.T) {
+	a, _ := NewBufferedPair(0)
+	done := make(chan error, 1)
+	go func() {
+		_, err := a.ReadMessage()
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	a.Close()
+	select {
+	case err := <-done:
+		if !errors.Is(err, transport.ErrClosed) {
+			t.Errorf("err = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close did not unblock ReadMessage")
+	}
+}
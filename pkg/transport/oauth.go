@@ -0,0 +1,596 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenExpiryMargin is how long before its expiry an access token is refreshed.
+const tokenExpiryMargin = 30 * time.Second
+
+// oauthMaxBody bounds the metadata, registration and token responses read.
+const oauthMaxBody = 1 << 20
+
+// Consent obtains the user's authorization. It sends the user to authURL and
+// returns the URL the authorization server redirected the browser to, whose query
+// carries the authorization code and state (or an error). See BrowserConsent and
+// HeadlessConsent.
+type Consent func(ctx context.Context, authURL string) (*url.URL, error)
+
+// OAuthCredentials are what an OAuth flow leaves behind for a protected resource:
+// the (usually dynamically registered) client and its current tokens.
+type OAuthCredentials struct {
+	Issuer        string    `json:"issuer"`
+	TokenEndpoint string    `json:"token_endpoint"`
+	ClientID      string    `json:"client_id"`
+	ClientSecret  string    `json:"client_secret,omitempty"`
+	AccessToken   string    `json:"access_token,omitempty"`
+	RefreshToken  string    `json:"refresh_token,omitempty"`
+	Expiry        time.Time `json:"expiry,omitzero"`
+}
+
+// valid reports whether the access token can still be used.
+func (c *OAuthCredentials) valid() bool {
+	return c.AccessToken != "" && (c.Expiry.IsZero() || time.Until(c.Expiry) > tokenExpiryMargin)
+}
+
+// CredentialStore keeps OAuthCredentials between runs, keyed by resource URL.
+type CredentialStore interface {
+	// Load returns the stored credentials, or nil if there are none.
+	Load(resource string) (*OAuthCredentials, error)
+	Save(resource string, creds *OAuthCredentials) error
+}
+
+// FileCredentialStore stores credentials for every resource in one JSON file,
+// readable only by its owner.
+type FileCredentialStore struct {
+	Path string
+	mu   sync.Mutex
+}
+
+// Load implements CredentialStore.
+func (f *FileCredentialStore) Load(resource string) (*OAuthCredentials, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	all, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+	return all[resource], nil
+}
+
+// Save implements CredentialStore.
+func (f *FileCredentialStore) Save(resource string, creds *OAuthCredentials) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	all, err := f.read()
+	if err != nil {
+		return err
+	}
+	all[resource] = creds
+	data, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal credentials: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0o700); err != nil {
+		return fmt.Errorf("failed to create credential directory: %w", err)
+	}
+	tmp := f.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write credentials: %w", err)
+	}
+	return os.Rename(tmp, f.Path)
+}
+
+func (f *FileCredentialStore) read() (map[string]*OAuthCredentials, error) {
+	all := map[string]*OAuthCredentials{}
+	data, err := os.ReadFile(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return all, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials: %w", err)
+	}
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("invalid credential file %s: %w", f.Path, err)
+	}
+	return all, nil
+}
+
+// memoryCredentialStore keeps credentials for the lifetime of the process.
+type memoryCredentialStore struct {
+	mu    sync.Mutex
+	creds map[string]*OAuthCredentials
+}
+
+func (m *memoryCredentialStore) Load(resource string) (*OAuthCredentials, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.creds[resource], nil
+}
+
+func (m *memoryCredentialStore) Save(resource string, creds *OAuthCredentials) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.creds[resource] = creds
+	return nil
+}
+
+// OAuthConfig configures OAuth authorization for an HTTP transport.
+type OAuthConfig struct {
+	// Consent obtains the user's authorization. Required.
+	Consent Consent
+	// RedirectURL is the redirect URI registered for the client, e.g.
+	// http://127.0.0.1:8976/callback.
+	RedirectURL string
+	// ClientName is sent during dynamic client registration.
+	ClientName string
+	// ClientID and ClientSecret identify a pre-registered client. When ClientID
+	// is empty the client registers itself with the authorization server.
+	ClientID     string
+	ClientSecret string
+	// Scopes to request. Empty uses the scope from the server's challenge, if any.
+	Scopes []string
+	// Store keeps credentials between runs. Nil keeps them in memory.
+	Store CredentialStore
+	// Client makes discovery, registration and token requests. Nil uses http.DefaultClient.
+	Client *http.Client
+}
+
+// OAuth is an http.RoundTripper that authorizes requests to an MCP server with
+// OAuth 2.1 bearer tokens, as the MCP authorization spec describes. When the
+// server answers 401 it discovers the authorization server from the protected
+// resource metadata, registers a client dynamically if needed, runs the
+// authorization code flow with PKCE through the Consent hook, and retries the
+// request. Expired tokens are refreshed before use.
+type OAuth struct {
+	resource string // The MCP endpoint, used as the RFC 8707 resource indicator
+	base     http.RoundTripper
+	cfg      OAuthConfig
+
+	flowMu sync.Mutex // Serializes authorization so concurrent 401s run one flow
+	mu     sync.Mutex // Protects creds
+	creds  *OAuthCredentials
+}
+
+// NewOAuth returns a RoundTripper that authorizes requests to resource, the MCP
+// endpoint URL, and sends them with base (nil uses http.DefaultTransport).
+func NewOAuth(resource string, base http.RoundTripper, cfg OAuthConfig) (*OAuth, error) {
+	if cfg.Consent == nil {
+		return nil, errors.New("OAuth needs a Consent hook")
+	}
+	if cfg.RedirectURL == "" {
+		return nil, errors.New("OAuth needs a redirect URL")
+	}
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	if cfg.Store == nil {
+		cfg.Store = &memoryCredentialStore{creds: map[string]*OAuthCredentials{}}
+	}
+	creds, err := cfg.Store.Load(resource)
+	if err != nil {
+		return nil, err
+	}
+	return &OAuth{resource: resource, base: base, cfg: cfg, creds: creds}, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (o *OAuth) RoundTrip(req *http.Request) (*http.Response, error) {
+	token := o.accessToken(req.Context())
+	resp, err := o.send(req, token)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil // The body is gone; the caller sees the 401
+	}
+	challenge := resp.Header.Get("WWW-Authenticate")
+	io.Copy(io.Discard, io.LimitReader(resp.Body, oauthMaxBody))
+	resp.Body.Close()
+
+	if token, err = o.authorize(req.Context(), token, challenge); err != nil {
+		return nil, fmt.Errorf("authorization failed: %w", err)
+	}
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	return o.send(retry, token)
+}
+
+// send sends req with token as its bearer credential, if there is one.
+func (o *OAuth) send(req *http.Request, token string) (*http.Response, error) {
+	if token != "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return o.base.RoundTrip(req)
+}
+
+// accessToken returns a usable access token, refreshing an expired one, or ""
+// if there is none and the server has to ask for one first.
+func (o *OAuth) accessToken(ctx context.Context) string {
+	o.mu.Lock()
+	creds := o.creds
+	o.mu.Unlock()
+	if creds == nil {
+		return ""
+	}
+	if creds.valid() {
+		return creds.AccessToken
+	}
+	if creds.RefreshToken == "" {
+		return ""
+	}
+	o.flowMu.Lock()
+	defer o.flowMu.Unlock()
+	if token, err := o.refresh(ctx); err == nil {
+		return token
+	}
+	return ""
+}
+
+// authorize obtains a new access token after rejected was refused. If another
+// request has already replaced rejected, that token is used instead.
+func (o *OAuth) authorize(ctx context.Context, rejected, challenge string) (string, error) {
+	o.flowMu.Lock()
+	defer o.flowMu.Unlock()
+
+	o.mu.Lock()
+	creds := o.creds
+	o.mu.Unlock()
+	if creds != nil && creds.AccessToken != rejected && creds.valid() {
+		return creds.AccessToken, nil
+	}
+	if creds != nil && creds.RefreshToken != "" {
+		if token, err := o.refresh(ctx); err == nil {
+			return token, nil
+		}
+	}
+	return o.runFlow(ctx, parseBearerChallenge(challenge))
+}
+
+// runFlow runs discovery, registration and the authorization code flow with PKCE.
+func (o *OAuth) runFlow(ctx context.Context, challenge map[string]string) (string, error) {
+	meta, err := o.discover(ctx, challenge["resource_metadata"])
+	if err != nil {
+		return "", err
+	}
+	creds, err := o.client(ctx, meta)
+	if err != nil {
+		return "", err
+	}
+
+	verifier := randomToken()
+	sum := sha256.Sum256([]byte(verifier))
+	state := randomToken()
+	scope := strings.Join(o.cfg.Scopes, " ")
+	if scope == "" {
+		scope = challenge["scope"]
+	}
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {creds.ClientID},
+		"redirect_uri":          {o.cfg.RedirectURL},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(sum[:])},
+		"code_challenge_method": {"S256"},
+		"state":                 {state},
+		"resource":              {o.resource},
+	}
+	if scope != "" {
+		query.Set("scope", scope)
+	}
+	authURL, err := url.Parse(meta.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid authorization endpoint: %w", err)
+	}
+	if existing := authURL.Query(); len(existing) > 0 {
+		for k, v := range existing {
+			query[k] = v
+		}
+	}
+	authURL.RawQuery = query.Encode()
+
+	callback, err := o.cfg.Consent(ctx, authURL.String())
+	if err != nil {
+		return "", fmt.Errorf("consent: %w", err)
+	}
+	result := callback.Query()
+	if e := result.Get("error"); e != "" {
+		return "", fmt.Errorf("authorization denied: %s %s", e, result.Get("error_description"))
+	}
+	if result.Get("state") != state {
+		return "", errors.New("authorization response has the wrong state")
+	}
+	code := result.Get("code")
+	if code == "" {
+		return "", errors.New("authorization response has no code")
+	}
+
+	return o.requestToken(ctx, creds, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {o.cfg.RedirectURL},
+		"code_verifier": {verifier},
+	})
+}
+
+// refresh exchanges the refresh token for a new access token. Callers hold flowMu.
+func (o *OAuth) refresh(ctx context.Context) (string, error) {
+	o.mu.Lock()
+	creds := *o.creds
+	o.mu.Unlock()
+	return o.requestToken(ctx, &creds, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {creds.RefreshToken},
+	})
+}
+
+// requestToken calls the token endpoint and stores the tokens it returns.
+func (o *OAuth) requestToken(ctx context.Context, creds *OAuthCredentials, form url.Values) (string, error) {
+	form.Set("client_id", creds.ClientID)
+	form.Set("resource", o.resource)
+	if creds.ClientSecret != "" {
+		form.Set("client_secret", creds.ClientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, creds.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	var token struct {
+		AccessToken      string `json:"access_token"`
+		TokenType        string `json:"token_type"`
+		ExpiresIn        int64  `json:"expires_in"`
+		RefreshToken     string `json:"refresh_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := o.doJSON(req, &token)
+	if err != nil {
+		return "", fmt.Errorf("token request: %w", err)
+	}
+	if token.Error != "" || status != http.StatusOK {
+		return "", fmt.Errorf("token request: %d %s %s", status, token.Error, token.ErrorDescription)
+	}
+	if token.AccessToken == "" || !strings.EqualFold(token.TokenType, "bearer") {
+		return "", fmt.Errorf("token request: unusable %q token", token.TokenType)
+	}
+
+	next := *creds
+	next.AccessToken = token.AccessToken
+	next.Expiry = time.Time{}
+	if token.ExpiresIn > 0 {
+		next.Expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	if token.RefreshToken != "" { // Servers may rotate refresh tokens or keep the old one
+		next.RefreshToken = token.RefreshToken
+	}
+	if err := o.save(&next); err != nil {
+		return "", err
+	}
+	return next.AccessToken, nil
+}
+
+func (o *OAuth) save(creds *OAuthCredentials) error {
+	o.mu.Lock()
+	o.creds = creds
+	o.mu.Unlock()
+	if err := o.cfg.Store.Save(o.resource, creds); err != nil {
+		return fmt.Errorf("failed to store credentials: %w", err)
+	}
+	return nil
+}
+
+// authServerMetadata is the RFC 8414 authorization server metadata the flow uses.
+type authServerMetadata struct {
+	Issuer                        string   `json:"issuer"`
+	AuthorizationEndpoint         string   `json:"authorization_endpoint"`
+	TokenEndpoint                 string   `json:"token_endpoint"`
+	RegistrationEndpoint          string   `json:"registration_endpoint"`
+	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported"`
+}
+
+// discover finds the authorization server for the resource: from the protected
+// resource metadata (RFC 9728) at resourceMetadata or its well-known location,
+// then from that server's metadata (RFC 8414). Servers that publish neither
+// are their own authorization server with the default endpoints.
+func (o *OAuth) discover(ctx context.Context, resourceMetadata string) (*authServerMetadata, error) {
+	resource, err := url.Parse(o.resource)
+	if err != nil {
+		return nil, fmt.Errorf("invalid resource URL: %w", err)
+	}
+	origin := &url.URL{Scheme: resource.Scheme, Host: resource.Host}
+
+	issuer := origin.String()
+	candidates := []string{resourceMetadata, wellKnownURL(resource, "oauth-protected-resource"), wellKnownURL(origin, "oauth-protected-resource")}
+	for _, u := range candidates {
+		var prm struct {
+			AuthorizationServers []string `json:"authorization_servers"`
+		}
+		if u != "" && o.getJSON(ctx, u, &prm) == nil && len(prm.AuthorizationServers) > 0 {
+			issuer = prm.AuthorizationServers[0]
+			break
+		}
+	}
+
+	issuerURL, err := url.Parse(issuer)
+	if err != nil {
+		return nil, fmt.Errorf("invalid authorization server %q: %w", issuer, err)
+	}
+	var meta authServerMetadata
+	found := false
+	for _, u := range []string{wellKnownURL(issuerURL, "oauth-authorization-server"), wellKnownURL(issuerURL, "openid-configuration"), strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"} {
+		if o.getJSON(ctx, u, &meta) == nil && meta.AuthorizationEndpoint != "" && meta.TokenEndpoint != "" {
+			found = true
+			break
+		}
+	}
+	if !found {
+		meta = authServerMetadata{
+			Issuer:                issuer,
+			AuthorizationEndpoint: strings.TrimSuffix(issuer, "/") + "/authorize",
+			TokenEndpoint:         strings.TrimSuffix(issuer, "/") + "/token",
+			RegistrationEndpoint:  strings.TrimSuffix(issuer, "/") + "/register",
+		}
+	}
+	if meta.Issuer == "" {
+		meta.Issuer = issuer
+	}
+	if len(meta.CodeChallengeMethodsSupported) > 0 && !slices.Contains(meta.CodeChallengeMethodsSupported, "S256") {
+		return nil, fmt.Errorf("authorization server %s does not support PKCE with S256", meta.Issuer)
+	}
+	return &meta, nil
+}
+
+// client returns the credentials to authorize with: the configured client, the
+// one already registered with this issuer, or a newly registered one.
+func (o *OAuth) client(ctx context.Context, meta *authServerMetadata) (*OAuthCredentials, error) {
+	creds := &OAuthCredentials{Issuer: meta.Issuer, TokenEndpoint: meta.TokenEndpoint, ClientID: o.cfg.ClientID, ClientSecret: o.cfg.ClientSecret}
+	if creds.ClientID != "" {
+		return creds, nil
+	}
+	o.mu.Lock()
+	stored := o.creds
+	o.mu.Unlock()
+	if stored != nil && stored.Issuer == meta.Issuer && stored.ClientID != "" {
+		creds.ClientID, creds.ClientSecret = stored.ClientID, stored.ClientSecret
+		return creds, nil
+	}
+	if meta.RegistrationEndpoint == "" {
+		return nil, fmt.Errorf("authorization server %s does not support dynamic client registration; configure a client ID", meta.Issuer)
+	}
+
+	name := o.cfg.ClientName
+	if name == "" {
+		name = "mcp-client"
+	}
+	body, _ := json.Marshal(map[string]interface{}{ // Only strings and string slices
+		"client_name":                name,
+		"redirect_uris":              []string{o.cfg.RedirectURL},
+		"grant_types":                []string{"authorization_code", "refresh_token"},
+		"response_types":             []string{"code"},
+		"token_endpoint_auth_method": "none",
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.RegistrationEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create registration request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	var registered struct {
+		ClientID     string `json:"client_id"`
+		ClientSecret string `json:"client_secret"`
+	}
+	status, err := o.doJSON(req, &registered)
+	if err != nil {
+		return nil, fmt.Errorf("client registration: %w", err)
+	}
+	if status != http.StatusCreated && status != http.StatusOK || registered.ClientID == "" {
+		return nil, fmt.Errorf("client registration: status %d without a client ID", status)
+	}
+	creds.ClientID, creds.ClientSecret = registered.ClientID, registered.ClientSecret
+	return creds, o.save(creds)
+}
+
+// getJSON fetches a metadata document.
+func (o *OAuth) getJSON(ctx context.Context, u string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	status, err := o.doJSON(req, v)
+	if err == nil && status != http.StatusOK {
+		err = fmt.Errorf("GET %s: status %d", u, status)
+	}
+	return err
+}
+
+// doJSON sends req and decodes a JSON response body into v, whatever the status.
+func (o *OAuth) doJSON(req *http.Request, v interface{}) (int, error) {
+	resp, err := o.cfg.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, oauthMaxBody))
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := json.Unmarshal(data, v); err != nil && resp.StatusCode < 300 {
+			return resp.StatusCode, fmt.Errorf("invalid JSON from %s: %w", req.URL, err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// wellKnownURL inserts /.well-known/<name> between the host and the path of u,
+// as RFC 8414 and RFC 9728 specify.
+func wellKnownURL(u *url.URL, name string) string {
+	w := url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/.well-known/" + name + strings.TrimSuffix(u.Path, "/")}
+	return w.String()
+}
+
+// parseBearerChallenge returns the auth-params of a Bearer WWW-Authenticate
+// challenge, e.g. resource_metadata and scope.
+func parseBearerChallenge(header string) map[string]string {
+	params := map[string]string{}
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	if !strings.EqualFold(scheme, "bearer") {
+		return params
+	}
+	for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimLeft(rest, ", ") {
+		key, after, ok := strings.Cut(rest, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		var value string
+		if strings.HasPrefix(after, `"`) {
+			var b strings.Builder
+			i := 1
+			for ; i < len(after) && after[i] != '"'; i++ {
+				if after[i] == '\\' && i+1 < len(after) {
+					i++
+				}
+				b.WriteByte(after[i])
+			}
+			value, rest = b.String(), after[min(i+1, len(after)):]
+		} else {
+			value, rest, _ = strings.Cut(after, ",")
+			value = strings.TrimSpace(value)
+		}
+		params[key] = value
+	}
+	return params
+}
+
+// randomToken returns 32 random bytes, base64url-encoded, for PKCE verifiers and state.
+func randomToken() string {
+	b := make([]byte, 32)
+	rand.Read(b) // Never fails
+	return base64.RawURLEncoding.EncodeToString(b)
+}
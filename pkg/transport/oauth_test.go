@@ -0,0 +1,202 @@
+package transport
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// authServer is an MCP endpoint at /mcp protected by an authorization server at /as.
+type authServer struct {
+	srv *httptest.Server
+
+	mu         sync.Mutex
+	challenges map[string]string // code -> PKCE challenge
+	grants     []string          // grant_type of each token request
+	valid      map[string]bool   // Accepted access tokens
+}
+
+func newAuthServer(t *testing.T) *authServer {
+	a := &authServer{challenges: map[string]string{}, valid: map[string]bool{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mcp", func(w http.ResponseWriter, r *http.Request) {
+		a.mu.Lock()
+		ok := a.valid[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+		a.mu.Unlock()
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="mcp", resource_metadata="`+a.srv.URL+`/.well-known/oauth-protected-resource/mcp", scope="tools"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		io.Copy(w, r.Body)
+	})
+	mux.HandleFunc("/.well-known/oauth-protected-resource/mcp", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"resource":%q,"authorization_servers":[%q]}`, a.srv.URL+"/mcp", a.srv.URL+"/as")
+	})
+	mux.HandleFunc("/.well-known/oauth-authorization-server/as", func(w http.ResponseWriter, r *http.Request) {
+		u := a.srv.URL + "/as"
+		fmt.Fprintf(w, `{"issuer":%q,"authorization_endpoint":%q,"token_endpoint":%q,"registration_endpoint":%q,"code_challenge_methods_supported":["S256"]}`,
+			u, u+"/authorize", u+"/token", u+"/register")
+	})
+	mux.HandleFunc("POST /as/register", func(w http.ResponseWriter, r *http.Request) {
+		var reg struct {
+			RedirectURIs []string `json:"redirect_uris"`
+			AuthMethod   string   `json:"token_endpoint_auth_method"`
+		}
+		if json.NewDecoder(r.Body).Decode(&reg) != nil || len(reg.RedirectURIs) != 1 || reg.AuthMethod != "none" {
+			http.Error(w, `{"error":"invalid_client_metadata"}`, http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"client_id":"client-1"}`)
+	})
+	mux.HandleFunc("GET /as/authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("client_id") != "client-1" || q.Get("code_challenge_method") != "S256" || q.Get("scope") != "tools" || q.Get("resource") != a.srv.URL+"/mcp" {
+			t.Errorf("authorization request = %v", q)
+		}
+		a.mu.Lock()
+		a.challenges["code-1"] = q.Get("code_challenge")
+		a.mu.Unlock()
+		http.Redirect(w, r, q.Get("redirect_uri")+"?code=code-1&state="+url.QueryEscape(q.Get("state")), http.StatusFound)
+	})
+	mux.HandleFunc("POST /as/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		grant := r.PostForm.Get("grant_type")
+		a.grants = append(a.grants, grant)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case grant == "authorization_code" && pkceMatches(r.PostForm.Get("code_verifier"), a.challenges[r.PostForm.Get("code")]):
+			a.valid["token-1"] = true
+			fmt.Fprint(w, `{"access_token":"token-1","token_type":"Bearer","expires_in":1,"refresh_token":"refresh-1"}`)
+		case grant == "refresh_token" && r.PostForm.Get("refresh_token") == "refresh-1":
+			a.valid["token-2"] = true
+			fmt.Fprint(w, `{"access_token":"token-2","token_type":"bearer","expires_in":3600}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"invalid_grant"}`)
+		}
+	})
+	a.srv = httptest.NewServer(mux)
+	t.Cleanup(a.srv.Close)
+	return a
+}
+
+func pkceMatches(verifier, challenge string) bool {
+	sum := sha256.Sum256([]byte(verifier))
+	return challenge != "" && base64.RawURLEncoding.EncodeToString(sum[:]) == challenge
+}
+
+// followConsent plays the user: it follows the authorization URL and returns
+// where the authorization server redirected to.
+func followConsent(calls *int) Consent {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	return func(ctx context.Context, authURL string) (*url.URL, error) {
+		*calls++
+		resp, err := client.Get(authURL)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body.Close()
+		return resp.Location()
+	}
+}
+
+func TestOAuthFlow(t *testing.T) {
+	a := newAuthServer(t)
+	store := &FileCredentialStore{Path: filepath.Join(t.TempDir(), "oauth", "credentials.json")}
+	consents := 0
+	cfg := OAuthConfig{Consent: followConsent(&consents), RedirectURL: "http://127.0.0.1:1/callback", Store: store}
+	post := func(rt http.RoundTripper, body string) {
+		t.Helper()
+		resp, err := (&http.Client{Transport: rt}).Post(a.srv.URL+"/mcp", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		got, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK || string(got) != body {
+			t.Fatalf("POST = %s %q, want 200 %q", resp.Status, got, body)
+		}
+	}
+
+	oauth, err := NewOAuth(a.srv.URL+"/mcp", nil, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	post(oauth, `{"n":1}`) // 401, full flow, retried with the body intact
+	post(oauth, `{"n":2}`) // token-1 is about to expire: refreshed first
+
+	// A new run picks up the stored, still valid token-2 without asking again
+	again, err := NewOAuth(a.srv.URL+"/mcp", nil, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	post(again, `{"n":3}`)
+
+	if consents != 1 || !reflect.DeepEqual(a.grants, []string{"authorization_code", "refresh_token"}) {
+		t.Errorf("consents = %d, grants = %v", consents, a.grants)
+	}
+	creds, err := store.Load(a.srv.URL + "/mcp")
+	if err != nil || creds == nil || creds.ClientID != "client-1" || creds.AccessToken != "token-2" || creds.RefreshToken != "refresh-1" {
+		t.Errorf("stored credentials = %+v (%v)", creds, err)
+	}
+	if info, err := os.Stat(store.Path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("credential file mode = %v (%v)", info.Mode().Perm(), err)
+	}
+}
+
+func TestOAuthRejectsWrongState(t *testing.T) {
+	a := newAuthServer(t)
+	consent := func(ctx context.Context, authURL string) (*url.URL, error) {
+		return url.Parse("http://127.0.0.1:1/callback?code=code-1&state=forged")
+	}
+	oauth, err := NewOAuth(a.srv.URL+"/mcp", nil, OAuthConfig{Consent: consent, RedirectURL: "http://127.0.0.1:1/callback"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = (&http.Client{Transport: oauth}).Get(a.srv.URL + "/mcp")
+	if err == nil || !strings.Contains(err.Error(), "wrong state") {
+		t.Errorf("GET = %v, want a state error", err)
+	}
+}
+
+func TestParseBearerChallenge(t *testing.T) {
+	got := parseBearerChallenge(`Bearer realm="a \"b\"", error=invalid_token, resource_metadata="https://x/.well-known/oauth-protected-resource"`)
+	want := map[string]string{"realm": `a "b"`, "error": "invalid_token", "resource_metadata": "https://x/.well-known/oauth-protected-resource"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseBearerChallenge = %v, want %v", got, want)
+	}
+	if got := parseBearerChallenge(`Basic realm="x"`); len(got) != 0 {
+		t.Errorf("parseBearerChallenge(Basic) = %v", got)
+	}
+}
+
+func TestHeadlessConsent(t *testing.T) {
+	var out strings.Builder
+	consent := HeadlessConsent(strings.NewReader("http://127.0.0.1:1/callback?code=c&state=s\n"), &out)
+	callback, err := consent(context.Background(), "https://as/authorize?x=1")
+	if err != nil || callback.Query().Get("code") != "c" {
+		t.Fatalf("consent = %v, %v", callback, err)
+	}
+	if !strings.Contains(out.String(), "https://as/authorize?x=1") {
+		t.Errorf("prompt %q does not show the authorization URL", out.String())
+	}
+	if _, err := HeadlessConsent(strings.NewReader("nonsense\n"), io.Discard)(context.Background(), "u"); err == nil {
+		t.Error("HeadlessConsent accepted a line without a query")
+	}
+}
@@ -0,0 +1,98 @@
+package transport
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// BrowserConsent returns a Consent that opens the authorization URL in the user's
+// browser and receives the redirect on a loopback listener at redirectURL, e.g.
+// http://127.0.0.1:8976/callback. The URL is also written to w in case no
+// browser can be started.
+func BrowserConsent(redirectURL string, w io.Writer) Consent {
+	return func(ctx context.Context, authURL string) (*url.URL, error) {
+		redirect, err := url.Parse(redirectURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redirect URL: %w", err)
+		}
+		if host := redirect.Hostname(); redirect.Scheme != "http" || (host != "localhost" && net.ParseIP(host) == nil) {
+			return nil, fmt.Errorf("redirect URL %s is not a loopback http URL", redirectURL)
+		}
+		listener, err := net.Listen("tcp", redirect.Host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen for the authorization redirect: %w", err)
+		}
+
+		callbacks := make(chan *url.URL, 1)
+		path := redirect.Path
+		if path == "" {
+			path = "/"
+		}
+		mux := http.NewServeMux()
+		mux.HandleFunc(path, func(rw http.ResponseWriter, r *http.Request) {
+			select {
+			case callbacks <- r.URL:
+				fmt.Fprintln(rw, "Authorization complete. You can close this window.")
+			default:
+				http.Error(rw, "Authorization already received.", http.StatusConflict)
+			}
+		})
+		server := &http.Server{Handler: mux}
+		go server.Serve(listener)
+		defer server.Close()
+
+		fmt.Fprintf(w, "Opening the browser to authorize access. If it does not open, visit:\n%s\n", authURL)
+		openBrowser(authURL)
+
+		select {
+		case callback := <-callbacks:
+			return callback, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// openBrowser starts the platform's URL opener; failures are ignored because
+// the URL has been printed as well.
+func openBrowser(u string) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", u)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", u)
+	default:
+		cmd = exec.Command("xdg-open", u)
+	}
+	if cmd.Start() == nil {
+		go cmd.Wait()
+	}
+}
+
+// HeadlessConsent returns a Consent for machines without a browser: it writes the
+// authorization URL to w and reads from r the URL the browser on another machine
+// was redirected to, which the user copies from its address bar.
+func HeadlessConsent(r io.Reader, w io.Writer) Consent {
+	return func(ctx context.Context, authURL string) (*url.URL, error) {
+		fmt.Fprintf(w, "Visit this URL to authorize access:\n%s\nThen paste the URL the browser was redirected to: ", authURL)
+		line, err := bufio.NewReader(r).ReadString('\n')
+		if err != nil && (line == "" || !errors.Is(err, io.EOF)) {
+			return nil, fmt.Errorf("failed to read the redirect URL: %w", err)
+		}
+		callback, err := url.Parse(strings.TrimSpace(line))
+		if err != nil || callback.RawQuery == "" {
+			return nil, fmt.Errorf("%q is not the redirect URL", strings.TrimSpace(line))
+		}
+		return callback, nil
+	}
+}
@@ -0,0 +1,141 @@
+package transport
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// loopback is a Transport whose writes are read back, for testing decorators.
+type loopback struct {
+	frames chan []byte
+}
+
+func (l *loopback) ReadMessage() ([]byte, error) { return <-l.frames, nil }
+func (l *loopback) WriteMessage(p []byte) error  { l.frames <- append([]byte(nil), p...); return nil }
+func (l *loopback) Close() error                 { return nil }
+
+func TestSignedRoundTrip(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	wire := &loopback{frames: make(chan []byte, 4)}
+	sender, receiver := NewSigned(wire, key, SignedClient), NewSigned(wire, key, SignedServer)
+
+	payload := `{"jsonrpc":"2.0", "id":1,  "method":"ping"}`
+	if err := sender.WriteMessage([]byte(payload)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := receiver.ReadMessage()
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+	if string(got) != payload {
+		t.Errorf("ReadMessage() = %s, want %s verbatim", got, payload)
+	}
+}
+
+func TestSignedRejectsTamperingAndReplay(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	wire := &loopback{frames: make(chan []byte, 4)}
+	sender, receiver := NewSigned(wire, key, SignedClient), NewSigned(wire, key, SignedServer)
+
+	if err := sender.WriteMessage([]byte(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"ping"}}`)); err != nil {
+		t.Fatal(err)
+	}
+	frame := <-wire.frames
+
+	tests := []struct {
+		name  string
+		frame []byte
+		want  error
+	}{
+		{"tampered", bytes.Replace(frame, []byte(`"ping"`), []byte(`"rm"`), 1), ErrBadSignature},
+		{"unsigned", []byte(`{"jsonrpc":"2.0","id":1,"method":"ping"}`), ErrBadSignature},
+		{"wrong key", nil, ErrBadSignature},
+		{"original", frame, nil},
+		{"replayed", frame, ErrReplayedMessage},
+	}
+	for _, tt := range tests {
+		if tt.frame == nil {
+			other := NewSigned(wire, []byte("fedcba9876543210"), SignedClient)
+			other.WriteMessage([]byte(`{}`))
+			tt.frame = <-wire.frames
+		}
+		wire.frames <- tt.frame
+		if _, err := receiver.ReadMessage(); !errors.Is(err, tt.want) {
+			t.Errorf("%s: ReadMessage() error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestSignedRejectsReflection(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	wire := &loopback{frames: make(chan []byte, 4)}
+	server := NewSigned(wire, key, SignedServer)
+
+	// A frame the server sent, sent back to it
+	server.WriteMessage([]byte(`{"jsonrpc":"2.0","id":"srv-1","method":"roots/list"}`))
+	if _, err := server.ReadMessage(); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("ReadMessage() of a reflected frame error = %v, want ErrBadSignature", err)
+	}
+}
+
+func TestSignedRejectsReplayAcrossConnections(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	nonces := NewNonceStore()
+	connect := func() (client, server *Signed, wire *loopback) {
+		wire = &loopback{frames: make(chan []byte, 4)}
+		server = NewSigned(wire, key, SignedServer)
+		server.Nonces = nonces // Shared by the listener's connections
+		return NewSigned(wire, key, SignedClient), server, wire
+	}
+
+	client, first, wire := connect()
+	client.WriteMessage([]byte(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"ping"}}`))
+	frame := <-wire.frames
+	wire.frames <- frame
+	if _, err := first.ReadMessage(); err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+
+	_, second, wire := connect()
+	wire.frames <- frame
+	if _, err := second.ReadMessage(); !errors.Is(err, ErrReplayedMessage) {
+		t.Errorf("ReadMessage() of a frame from another connection error = %v, want ErrReplayedMessage", err)
+	}
+}
+
+func TestSignedRejectsStaleMessages(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	wire := &loopback{frames: make(chan []byte, 4)}
+	sender, receiver := NewSigned(wire, key, SignedClient), NewSigned(wire, key, SignedServer)
+	sender.now = func() time.Time { return time.Now().Add(-time.Minute) }
+
+	sender.WriteMessage([]byte(`{}`))
+	if _, err := receiver.ReadMessage(); !errors.Is(err, ErrReplayedMessage) {
+		t.Errorf("ReadMessage() of a minute-old message error = %v, want ErrReplayedMessage", err)
+	}
+
+	receiver.MaxSkew = 2 * time.Minute
+	sender.WriteMessage([]byte(`{}`))
+	if _, err := receiver.ReadMessage(); err != nil {
+		t.Errorf("ReadMessage() within a wider MaxSkew error = %v", err)
+	}
+}
+
+func TestReadSecretFile(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good")
+	os.WriteFile(good, []byte("  0123456789abcdef\n"), 0600)
+	if secret, err := ReadSecretFile(good); err != nil || string(secret) != "0123456789abcdef" {
+		t.Errorf("ReadSecretFile() = %q, %v", secret, err)
+	}
+	short := filepath.Join(dir, "short")
+	os.WriteFile(short, []byte("secret"), 0600)
+	if _, err := ReadSecretFile(short); err == nil || !strings.Contains(err.Error(), "at least") {
+		t.Errorf("ReadSecretFile(short) error = %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package transport
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+)
+
+// Framing selects how messages are delimited on a socket connection or stdio.
+type Framing int
+
+const (
+	// FramingNewline is newline-delimited JSON, identical to the stdio transport.
+	FramingNewline Framing = iota
+	// FramingLength is 4-byte big-endian length-prefixed frames (see LengthPrefixed).
+	FramingLength
+	// FramingContentLength is LSP-style Content-Length headers (see ContentLength).
+	FramingContentLength
+	// FramingAuto adopts the framing of the peer (see AutoFramed). Only the
+	// reading side of a connection can use it.
+	FramingAuto
+)
+
+// lengthPreamble is sent by a dialer that wants length-prefixed framing. It cannot be
+// mistaken for newline-delimited JSON, which always starts with '{' or whitespace.
+var lengthPreamble = []byte("MCPL")
+
+// String returns the name accepted by ParseFraming.
+func (f Framing) String() string {
+	switch f {
+	case FramingNewline:
+		return "newline"
+	case FramingLength:
+		return "length"
+	case FramingContentLength:
+		return "content-length"
+	case FramingAuto:
+		return "auto"
+	default:
+		return fmt.Sprintf("Framing(%d)", int(f))
+	}
+}
+
+// ParseFraming parses "newline", "length", "content-length" (also "lsp") or "auto".
+func ParseFraming(name string) (Framing, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "newline", "ndjson":
+		return FramingNewline, nil
+	case "length", "length-prefixed":
+		return FramingLength, nil
+	case "content-length", "lsp", "header":
+		return FramingContentLength, nil
+	case "auto":
+		return FramingAuto, nil
+	default:
+		return 0, fmt.Errorf("unknown framing %q (want newline, length, content-length or auto)", name)
+	}
+}
+
+// NewFramed creates a transport of the given framing reading from r and writing
+// to w, e.g. over stdio where both sides are told the framing. Unlike NewConn it
+// sends no preamble. If r or w implement io.Closer they are closed by Close.
+func NewFramed(r io.Reader, w io.Writer, framing Framing) (Transport, error) {
+	switch framing {
+	case FramingNewline:
+		return NewStream(r, w), nil
+	case FramingLength:
+		return NewLengthPrefixed(r, w), nil
+	case FramingContentLength:
+		return NewContentLength(r, w), nil
+	case FramingAuto:
+		return NewAutoFramed(r, w), nil
+	default:
+		return nil, fmt.Errorf("unsupported framing %v", framing)
+	}
+}
+
+// ParseAddress splits a socket address of the form "unix:/path/to/sock" or "tcp:host:port"
+// into the network and address expected by net.Dial and net.Listen. A bare "host:port"
+// is treated as TCP.
+func ParseAddress(spec string) (network, address string, err error) {
+	network, address, ok := strings.Cut(spec, ":")
+	switch {
+	case ok && (network == "unix" || network == "tcp" || network == "tcp4" || network == "tcp6"):
+		if address == "" {
+			return "", "", fmt.Errorf("missing address in %q", spec)
+		}
+		return network, address, nil
+	case ok:
+		return "tcp", spec, nil
+	default:
+		return "", "", fmt.Errorf("invalid socket address %q (want unix:/path or tcp:host:port)", spec)
+	}
+}
+
+// NewConn wraps the dialing side of a connection. For FramingLength the preamble is
+// written immediately so that the accepting side can select the same framing;
+// the other framings are recognized by their first byte.
+func NewConn(conn net.Conn, framing Framing) (Transport, error) {
+	switch framing {
+	case FramingNewline:
+		return NewStream(conn, conn), nil
+	case FramingContentLength:
+		return NewContentLength(conn, conn), nil
+	case FramingLength:
+		if _, err := conn.Write(lengthPreamble); err != nil {
+			return nil, fmt.Errorf("failed to send framing preamble: %w", err)
+		}
+		return NewLengthPrefixed(conn, conn), nil
+	default:
+		return nil, fmt.Errorf("unsupported framing %v", framing)
+	}
+}
+
+// Dial connects to an MCP server listening on a socket and selects the given framing.
+func Dial(network, address string, framing Framing) (Transport, error) {
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	t, err := NewConn(conn, framing)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// Accept wraps the accepting side of a connection, detecting the framing chosen by the
+// peer: length-prefixed if it starts with the preamble, Content-Length if it starts
+// with a header, newline-delimited otherwise. It blocks until the peer sends its
+// first bytes.
+func Accept(conn net.Conn) (Transport, Framing, error) {
+	reader := bufio.NewReader(conn)
+	framing, err := sniffFraming(reader)
+	if err != nil {
+		return nil, 0, err
+	}
+	t, err := NewFramed(reader, conn, framing)
+	return t, framing, err
+}
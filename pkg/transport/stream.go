@@ -0,0 +1,169 @@
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// DefaultMaxLineSize bounds the message a Stream accepts, so that a single huge
+// line cannot make the reader allocate unbounded memory.
+const DefaultMaxLineSize = 8 << 20 // 8 MiB
+
+// lineTooLongPrefix is how much of an oversized line is kept for the error.
+const lineTooLongPrefix = 4096
+
+// ErrLineTooLong is returned, as a *LineTooLongError, for lines longer than the maximum.
+var ErrLineTooLong = errors.New("line exceeds maximum size")
+
+// LineTooLongError reports a line that was discarded for being too long. The
+// stream remains usable: the next ReadMessage returns the line after it.
+type LineTooLongError struct {
+	Size   int64  // Length of the discarded line without its newline
+	Max    int    // The limit it exceeded
+	Prefix []byte // The start of the line, e.g. to salvage a request ID
+}
+
+func (e *LineTooLongError) Error() string {
+	return fmt.Sprintf("%v: %d bytes (max %d)", ErrLineTooLong, e.Size, e.Max)
+}
+
+func (e *LineTooLongError) Unwrap() error { return ErrLineTooLong }
+
+// ErrRawNewline is returned by Stream.WriteMessage for a payload containing a
+// newline, which would break the framing.
+var ErrRawNewline = errors.New("message contains a raw newline and cannot be newline-framed")
+
+// Stream is a Transport carrying newline-delimited JSON messages over a byte stream,
+// as used by the MCP stdio transport. Each message is written as a single line.
+type Stream struct {
+	reader      *bufio.Reader
+	writer      io.Writer
+	closer      []io.Closer
+	mu          sync.Mutex // Protects writer access
+	MaxLineSize int        // Largest accepted line; DefaultMaxLineSize unless changed before use
+}
+
+// NewStream creates a newline-delimited transport reading from r and writing to w.
+// If r or w implement io.Closer they are closed by Close.
+func NewStream(r io.Reader, w io.Writer) *Stream {
+	return &Stream{
+		reader:      bufio.NewReader(r),
+		writer:      w,
+		closer:      closersOf(r, w),
+		MaxLineSize: DefaultMaxLineSize,
+	}
+}
+
+// closersOf returns the distinct io.Closers among r and w.
+func closersOf(r io.Reader, w io.Writer) []io.Closer {
+	var closers []io.Closer
+	if c, ok := r.(io.Closer); ok {
+		closers = append(closers, c)
+	}
+	if c, ok := w.(io.Closer); ok && (len(closers) == 0 || !sameCloser(closers[0], c)) {
+		closers = append(closers, c)
+	}
+	return closers
+}
+
+// closeAll closes every closer and returns the first error.
+func closeAll(closers []io.Closer) error {
+	var firstErr error
+	for _, c := range closers {
+		if err := c.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// sameCloser reports whether a and b are the same object (e.g. a net.Conn used for both directions).
+func sameCloser(a, b io.Closer) (same bool) {
+	defer func() {
+		if recover() != nil {
+			same = false // Non-comparable dynamic types cannot be the same object
+		}
+	}()
+	return a == b
+}
+
+// ReadMessage returns the next non-empty line with surrounding whitespace trimmed.
+// A final line that is not newline-terminated is discarded, since the message may be truncated.
+// A line longer than MaxLineSize is skipped without being held in memory and
+// reported as a *LineTooLongError; reading can continue after it.
+func (s *Stream) ReadMessage() ([]byte, error) {
+	for {
+		line, err := s.readLine()
+		if err != nil {
+			return nil, err
+		}
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue // Skip empty lines
+		}
+		return line, nil
+	}
+}
+
+// readLine reads up to and including the next newline. Once a line exceeds
+// MaxLineSize the rest of it is read and dropped, keeping only a prefix.
+func (s *Stream) readLine() ([]byte, error) {
+	var line []byte
+	var tooLong *LineTooLongError
+	for {
+		chunk, err := s.reader.ReadSlice('\n')
+		if tooLong == nil {
+			size := len(line) + len(chunk)
+			if err == nil {
+				size-- // The newline does not count
+			}
+			if size <= s.MaxLineSize {
+				line = append(line, chunk...)
+			} else {
+				head := append(line, chunk...)
+				prefix := bytes.Clone(head[:min(len(head), lineTooLongPrefix)])
+				tooLong = &LineTooLongError{Size: int64(len(head)), Max: s.MaxLineSize, Prefix: prefix}
+				line = nil
+			}
+		} else {
+			tooLong.Size += int64(len(chunk))
+		}
+		switch {
+		case err == bufio.ErrBufferFull:
+			continue
+		case err != nil:
+			return nil, err
+		case tooLong != nil:
+			tooLong.Size-- // The newline
+			return nil, tooLong
+		}
+		return line, nil
+	}
+}
+
+// WriteMessage writes payload followed by a newline in a single write call,
+// so concurrent writers never interleave partial messages.
+func (s *Stream) WriteMessage(payload []byte) error {
+	if bytes.IndexByte(payload, '\n') >= 0 {
+		return ErrRawNewline
+	}
+	frame := make([]byte, 0, len(payload)+1)
+	frame = append(frame, payload...)
+	frame = append(frame, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.writer.Write(frame); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	return nil
+}
+
+// Close closes the underlying reader and writer if they are closable.
+func (s *Stream) Close() error {
+	return closeAll(s.closer)
+}
@@ -0,0 +1,137 @@
+package transport
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestStreamReadMessage(t *testing.T) {
+	input := "\n{\"a\":1}\r\n   \n  {\"b\":2}  \n{\"truncated\":"
+	s := NewStream(strings.NewReader(input), io.Discard)
+
+	for _, want := range []string{`{"a":1}`, `{"b":2}`} {
+		got, err := s.ReadMessage()
+		if err != nil {
+			t.Fatalf("ReadMessage() error = %v", err)
+		}
+		if string(got) != want {
+			t.Errorf("ReadMessage() = %q, want %q", got, want)
+		}
+	}
+	if _, err := s.ReadMessage(); err != io.EOF {
+		t.Errorf("ReadMessage() on truncated final line error = %v, want io.EOF", err)
+	}
+}
+
+func TestStreamLineTooLong(t *testing.T) {
+	huge := `{"jsonrpc":"2.0","id":7,"params":"` + strings.Repeat("x", 20000) + `"}`
+	input := `{"a":1}` + "\n" + huge + "\r\n" + `{"b":2}` + "\n" + strings.Repeat("y", 100)
+	s := NewStream(strings.NewReader(input), io.Discard)
+	s.MaxLineSize = 10000
+
+	if got, err := s.ReadMessage(); err != nil || string(got) != `{"a":1}` {
+		t.Fatalf("first ReadMessage() = %q, %v", got, err)
+	}
+	_, err := s.ReadMessage()
+	var tooLong *LineTooLongError
+	if !errors.As(err, &tooLong) || !errors.Is(err, ErrLineTooLong) {
+		t.Fatalf("ReadMessage() of a long line error = %v, want a LineTooLongError", err)
+	}
+	if tooLong.Size != int64(len(huge)+1) || tooLong.Max != 10000 || !strings.HasPrefix(huge, string(tooLong.Prefix)) || len(tooLong.Prefix) != lineTooLongPrefix {
+		t.Errorf("error = %v with a %d byte prefix", tooLong, len(tooLong.Prefix))
+	}
+	// The stream continues with the next line
+	if got, err := s.ReadMessage(); err != nil || string(got) != `{"b":2}` {
+		t.Errorf("ReadMessage() after a long line = %q, %v", got, err)
+	}
+	if _, err := s.ReadMessage(); err != io.EOF {
+		t.Errorf("ReadMessage() on truncated final line error = %v, want io.EOF", err)
+	}
+}
+
+func TestStreamWriteMessage(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewStream(strings.NewReader(""), &buf)
+
+	if err := s.WriteMessage([]byte(`{"a":1}`)); err != nil {
+		t.Fatalf("WriteMessage() error = %v", err)
+	}
+	if err := s.WriteMessage([]byte("{\"a\":\n1}")); err == nil {
+		t.Errorf("WriteMessage() with embedded newline succeeded, want error")
+	}
+	if got := buf.String(); got != "{\"a\":1}\n" {
+		t.Errorf("written bytes = %q", got)
+	}
+}
+
+func FuzzStreamReadMessage(f *testing.F) {
+	f.Add([]byte("{\"jsonrpc\":\"2.0\",\"method\":\"ping\",\"id\":1}\n"))
+	f.Add([]byte("\n\n  {\"jsonrpc\":\"2.0\",\"method\":\"notifications/initialized\"}\r\n"))
+	f.Add([]byte("not json\n{\"a\":1}\n[1,2]\n"))
+	f.Add([]byte("{\"jsonrpc\":\"2.0\",\"method\":\"ping\"")) // Truncated, no newline
+	f.Add([]byte("{\"text\":\"embedded \\n escape\"}\n{}\n")) // Escaped newline inside a string
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		s := NewStream(bytes.NewReader(data), io.Discard)
+		for frames := 0; ; frames++ {
+			if frames > len(data) {
+				t.Fatalf("ReadMessage returned more frames than input bytes")
+			}
+			payload, err := s.ReadMessage()
+			if err != nil {
+				if err != io.EOF {
+					t.Fatalf("ReadMessage returned unexpected error: %v", err)
+				}
+				return
+			}
+			if len(payload) == 0 || len(bytes.TrimSpace(payload)) != len(payload) {
+				t.Fatalf("ReadMessage returned an empty or untrimmed frame: %q", payload)
+			}
+			if bytes.ContainsRune(payload, '\n') {
+				t.Fatalf("ReadMessage returned a frame spanning lines: %q", payload)
+			}
+		}
+	})
+}
+
+func TestGuardRejectsNonProtocolWrites(t *testing.T) {
+	var buf bytes.Buffer
+	var reported []string
+	g := NewGuard(NewStream(strings.NewReader(""), &buf), func(payload []byte, err error) {
+		reported = append(reported, string(payload))
+	})
+
+	valid := `{"jsonrpc":"2.0","method":"notifications/tools/list_changed"}`
+	if err := g.WriteMessage([]byte(valid)); err != nil {
+		t.Fatalf("WriteMessage(valid) error = %v", err)
+	}
+	for _, bad := range []string{"hello", `{"a":1}`, `{"jsonrpc":"1.0","id":1,"result":{}}`} {
+		if err := g.WriteMessage([]byte(bad)); !errors.Is(err, ErrNotProtocol) {
+			t.Errorf("WriteMessage(%q) error = %v, want ErrNotProtocol", bad, err)
+		}
+	}
+	if got := buf.String(); got != valid+"\n" {
+		t.Errorf("written bytes = %q", got)
+	}
+	if len(reported) != 3 {
+		t.Errorf("reported %d payloads, want 3: %q", len(reported), reported)
+	}
+}
+
+func TestIsMessageError(t *testing.T) {
+	s := NewStream(strings.NewReader(""), failWriter{})
+	if err := s.WriteMessage([]byte("{\n}")); !IsMessageError(err) {
+		t.Errorf("raw newline error %v is not a message error", err)
+	}
+	if err := s.WriteMessage([]byte("{}")); err == nil || IsMessageError(err) {
+		t.Errorf("write failure %v is a message error", err)
+	}
+}
+
+// failWriter fails every write, like a closed pipe.
+type failWriter struct{}
+
+func (failWriter) Write([]byte) (int, error) { return 0, io.ErrClosedPipe }
@@ -0,0 +1,124 @@
+package transport
+
+import (
+	"bufio"
+	"io"
+	"sync"
+)
+
+// Tee is a Transport decorator that copies everything read and written to two
+// writers, e.g. files kept for debugging framing problems with a host. For a
+// Stream, LengthPrefixed, ContentLength or AutoFramed transport the copies are the raw bytes, framing
+// included, as they were read and before they are parsed; for any other
+// transport they are the message payloads, each followed by a newline.
+//
+// The copies never affect the live stream: once a write to a copy fails, that
+// copy is abandoned and the error is reported by Err.
+type Tee struct {
+	Transport
+	in, out *teeWriter
+	raw     bool // The byte streams are tapped, so messages need no copying
+}
+
+// NewTee wraps t, copying inbound bytes to in and outbound bytes to out. It
+// must be called before t is used.
+func NewTee(t Transport, in, out io.Writer) *Tee {
+	tee := &Tee{Transport: t, in: &teeWriter{w: in}, out: &teeWriter{w: out}}
+	switch t := t.(type) {
+	case *Stream:
+		t.reader = bufio.NewReader(teeReader{r: t.reader, tee: tee.in})
+		t.writer = teeOut{w: t.writer, tee: tee.out}
+		tee.raw = true
+	case *LengthPrefixed:
+		t.reader = bufio.NewReader(teeReader{r: t.reader, tee: tee.in})
+		t.writer = teeOut{w: t.writer, tee: tee.out}
+		tee.raw = true
+	case *ContentLength:
+		t.reader = bufio.NewReader(teeReader{r: t.reader, tee: tee.in})
+		t.writer = teeOut{w: t.writer, tee: tee.out}
+		tee.raw = true
+	case *AutoFramed:
+		t.reader = bufio.NewReader(teeReader{r: t.reader, tee: tee.in})
+		t.writer = teeOut{w: t.writer, tee: tee.out}
+		tee.raw = true
+	}
+	return tee
+}
+
+// ReadMessage reads from the wrapped transport and copies the payload.
+func (t *Tee) ReadMessage() ([]byte, error) {
+	payload, err := t.Transport.ReadMessage()
+	if err == nil && !t.raw {
+		t.in.writeLine(payload)
+	}
+	return payload, err
+}
+
+// WriteMessage copies the payload and writes it to the wrapped transport.
+func (t *Tee) WriteMessage(payload []byte) error {
+	if !t.raw {
+		t.out.writeLine(payload)
+	}
+	return t.Transport.WriteMessage(payload)
+}
+
+// Err returns the first error writing a copy, or nil.
+func (t *Tee) Err() error {
+	if err := t.in.firstErr(); err != nil {
+		return err
+	}
+	return t.out.firstErr()
+}
+
+// teeWriter writes copies until the first error.
+type teeWriter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	err error
+}
+
+func (tw *teeWriter) write(p []byte) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.err == nil {
+		_, tw.err = tw.w.Write(p)
+	}
+}
+
+func (tw *teeWriter) writeLine(payload []byte) {
+	tw.write(append(append(make([]byte, 0, len(payload)+1), payload...), '\n'))
+}
+
+func (tw *teeWriter) firstErr() error {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	return tw.err
+}
+
+// teeReader copies the bytes read from r.
+type teeReader struct {
+	r   io.Reader
+	tee *teeWriter
+}
+
+func (tr teeReader) Read(p []byte) (int, error) {
+	n, err := tr.r.Read(p)
+	if n > 0 {
+		tr.tee.write(p[:n])
+	}
+	return n, err
+}
+
+// teeOut copies the bytes written to w.
+type teeOut struct {
+	w   io.Writer
+	tee *teeWriter
+}
+
+func (to teeOut) Write(p []byte) (int, error) {
+	n, err := to.w.Write(p)
+	if n > 0 {
+		to.tee.write(p[:n])
+	}
+	return n, err
+}
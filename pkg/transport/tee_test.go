@@ -0,0 +1,63 @@
+package transport
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestTeeStreamCopiesRawBytes(t *testing.T) {
+	input := "{\"a\":1}\r\n\n  {\"b\":2}\n"
+	var wire, in, out bytes.Buffer
+	tee := NewTee(NewStream(strings.NewReader(input), &wire), &in, &out)
+
+	for _, want := range []string{`{"a":1}`, `{"b":2}`} {
+		if got, err := tee.ReadMessage(); err != nil || string(got) != want {
+			t.Fatalf("ReadMessage() = %q, %v; want %q", got, err, want)
+		}
+	}
+	if err := tee.WriteMessage([]byte(`{"c":3}`)); err != nil {
+		t.Fatal(err)
+	}
+	if in.String() != input {
+		t.Errorf("inbound copy = %q, want the raw input %q", in.String(), input)
+	}
+	if out.String() != wire.String() || out.String() != "{\"c\":3}\n" {
+		t.Errorf("outbound copy = %q, wire = %q", out.String(), wire.String())
+	}
+}
+
+func TestTeeMessages(t *testing.T) {
+	var in, out bytes.Buffer
+	var wire bytes.Buffer
+	tee := NewTee(NewGuard(NewStream(strings.NewReader("{\"jsonrpc\":\"2.0\",\"method\":\"x\"}\r\n"), &wire), nil), &in, &out)
+	if _, err := tee.ReadMessage(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tee.WriteMessage([]byte(`{"jsonrpc":"2.0","id":1,"result":{}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if in.String() != "{\"jsonrpc\":\"2.0\",\"method\":\"x\"}\n" || out.String() != "{\"jsonrpc\":\"2.0\",\"id\":1,\"result\":{}}\n" {
+		t.Errorf("copies = %q, %q; want one payload per line", in.String(), out.String())
+	}
+}
+
+func TestTeeFailureKeepsStreamAlive(t *testing.T) {
+	var wire bytes.Buffer
+	tee := NewTee(NewStream(strings.NewReader("{\"a\":1}\n"), &wire), failingWriter{}, failingWriter{})
+	if got, err := tee.ReadMessage(); err != nil || string(got) != `{"a":1}` {
+		t.Fatalf("ReadMessage() = %q, %v", got, err)
+	}
+	if err := tee.WriteMessage([]byte(`{"b":2}`)); err != nil || wire.String() != "{\"b\":2}\n" {
+		t.Errorf("WriteMessage() = %v, wire %q", err, wire.String())
+	}
+	if err := tee.Err(); err == nil || err.Error() != "disk full" {
+		t.Errorf("Err() = %v, want the copy's error", err)
+	}
+}
@@ -0,0 +1,36 @@
+// Package transport defines how complete JSON-RPC messages are moved between an MCP client and server.
+//
+// A Transport delivers whole messages; framing (newline-delimited JSON for stdio, or
+// length-prefixed or LSP-style Content-Length frames, see Framing) is the transport's
+// concern, so the client and server only ever see individual JSON payloads.
+// Decorators such as Chaos, Signed and Tee wrap another Transport to change its behavior without either side noticing.
+// compress.go holds content-encoding helpers for HTTP-based transports.
+// http.go is the client side of the Streamable HTTP transport used by hosted servers; oauth.go authorizes it.
+// grpc.go binds the Transport to a gRPC stream without depending on gRPC (see proto/transport.proto).
+package transport
+
+import "errors"
+
+// ErrClosed is returned by operations on a transport that has been closed.
+var ErrClosed = errors.New("transport closed")
+
+// Transport sends and receives complete JSON-RPC messages.
+// ReadMessage and WriteMessage may be called concurrently with each other, but
+// ReadMessage must not be called concurrently with itself.
+type Transport interface {
+	// ReadMessage blocks until the next message is available and returns its payload
+	// without framing. It returns io.EOF when the peer closes the stream.
+	ReadMessage() ([]byte, error)
+	// WriteMessage sends one complete message. The payload must not include framing.
+	WriteMessage(payload []byte) error
+	// Close releases the transport. Blocked reads return an error.
+	Close() error
+}
+
+// IsMessageError reports whether err, returned by WriteMessage, was caused by
+// the payload alone (it cannot be framed or is not a protocol message), so the
+// transport is still usable. Any other write error means the peer can no
+// longer be reached.
+func IsMessageError(err error) bool {
+	return errors.Is(err, ErrNotProtocol) || errors.Is(err, ErrRawNewline) || errors.Is(err, ErrFrameTooLarge)
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"strings" // Added for ToUpper
+	"sync"
 )
 
 // Define valid log level strings
@@ -15,9 +16,11 @@ const (
 )
 
 // Logger wraps the standard Go logger to provide level-based logging.
+// The level may be changed with SetLevel while other goroutines log.
 type Logger struct {
 	stdLogger *log.Logger
-	level     string // Store level as a string ("INFO" or "DEBUG")
+	mu        sync.RWMutex // Guards level
+	level     string       // Store level as a string ("INFO" or "DEBUG")
 }
 
 // New creates a new Logger instance.
@@ -37,13 +40,36 @@ func New(out io.Writer, prefix string, flag int, level string) *Logger {
 // SetLevel changes the minimum logging level for the logger using a string ("INFO" or "DEBUG").
 // Defaults to "INFO" if an invalid level string is provided.
 func (l *Logger) SetLevel(level string) {
-	l.level = strings.ToUpper(level)
+	normalizedLevel := strings.ToUpper(level)
+	if normalizedLevel != LevelDebug {
+		normalizedLevel = LevelInfo // Default to INFO
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.level = normalizedLevel
+}
+
+// Level returns the current minimum logging level ("INFO" or "DEBUG").
+func (l *Logger) Level() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.level
 }
 
 // shouldLog checks if a message with the given level string should be logged.
+// A DEBUG logger outputs both DEBUG and INFO messages; an INFO logger outputs only INFO messages.
+// Message levels are compared case-insensitively, without allocating.
 func (l *Logger) shouldLog(messageLevel string) bool {
-	// print only if it matches, otherwise nothing
-	return messageLevel == l.level
+	if strings.EqualFold(messageLevel, LevelInfo) {
+		return true
+	}
+	return strings.EqualFold(messageLevel, LevelDebug) && l.Level() == LevelDebug
+}
+
+// Enabled reports whether messages of level are output, so that callers can
+// skip building expensive arguments, such as payload copies, that would be dropped.
+func (l *Logger) Enabled(level string) bool {
+	return l.shouldLog(level)
 }
 
 // Printf logs a formatted string if the message level is appropriate.
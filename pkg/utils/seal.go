@@ -0,0 +1,161 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// SealKeySize is the size in bytes of the master key used by Sealer (AES-256).
+const SealKeySize = 32
+
+// ErrSealedData is returned by Sealer.Open for data that was altered, truncated or
+// sealed with another key or for another session.
+var ErrSealedData = errors.New("sealed data cannot be decrypted")
+
+// Sealer encrypts data at rest with AES-256-GCM, for subsystems that persist tool
+// data containing user content (state stores, audit logs).
+//
+// Each session gets its own key, derived from the master key and the session ID
+// with HKDF-SHA256, so data written for one session cannot be read back, or
+// swapped in, as another session's. Sealed data is nonce || ciphertext || tag.
+type Sealer struct {
+	aead cipher.AEAD
+}
+
+// NewSealer returns a Sealer for one session. key is the master key and must be SealKeySize bytes.
+func NewSealer(key []byte, sessionID string) (*Sealer, error) {
+	if len(key) != SealKeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes, got %d", SealKeySize, len(key))
+	}
+	sessionKey, err := hkdf.Key(sha256.New, key, nil, "mcp session "+sessionID, SealKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive session key: %w", err)
+	}
+	block, err := aes.NewCipher(sessionKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Sealer{aead: aead}, nil
+}
+
+// Seal encrypts plaintext. additionalData (e.g. a record key) is authenticated but
+// not encrypted, and must be passed unchanged to Open.
+func (s *Sealer) Seal(plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, s.aead.NonceSize(), s.aead.NonceSize()+len(plaintext)+s.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return s.aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// Open decrypts data produced by Seal.
+func (s *Sealer) Open(sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < s.aead.NonceSize()+s.aead.Overhead() {
+		return nil, fmt.Errorf("%w: too short", ErrSealedData)
+	}
+	nonce, ciphertext := sealed[:s.aead.NonceSize()], sealed[s.aead.NonceSize():]
+	plaintext, err := s.aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, ErrSealedData
+	}
+	return plaintext, nil
+}
+
+// LoadSealKey reads the master key from the environment variable env if it is set,
+// otherwise from the file at path (e.g. a key file mounted by a KMS agent). The key
+// is SealKeySize bytes written as hex or standard base64; surrounding whitespace is
+// ignored. It returns nil and no error if neither source is configured, meaning
+// encryption at rest is off.
+func LoadSealKey(env, path string) ([]byte, error) {
+	var encoded []byte
+	source := env
+	switch {
+	case env != "" && os.Getenv(env) != "":
+		encoded = []byte(os.Getenv(env))
+	case path != "":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		encoded, source = data, path
+	default:
+		return nil, nil
+	}
+	encoded = bytes.TrimSpace(encoded)
+
+	if key, err := hex.DecodeString(string(encoded)); err == nil && len(key) == SealKeySize {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(string(encoded)); err == nil && len(key) == SealKeySize {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%s: key must be %d bytes encoded as hex or base64", source, SealKeySize)
+}
+
+// SealedWriter encrypts each Write as one record and writes it to the underlying
+// writer as a line of base64, so append-only logs stay line oriented. Callers
+// should write whole entries (the Logger writes one line per call).
+type SealedWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	sealer *Sealer
+}
+
+// NewSealedWriter returns a writer that seals every write to w with sealer.
+func NewSealedWriter(w io.Writer, sealer *Sealer) *SealedWriter {
+	return &SealedWriter{w: w, sealer: sealer}
+}
+
+// Write seals p and writes it as one line.
+func (sw *SealedWriter) Write(p []byte) (int, error) {
+	sealed, err := sw.sealer.Seal(p, nil)
+	if err != nil {
+		return 0, err
+	}
+	line := make([]byte, base64.StdEncoding.EncodedLen(len(sealed))+1)
+	base64.StdEncoding.Encode(line, sealed)
+	line[len(line)-1] = '\n'
+
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	if _, err := sw.w.Write(line); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// ReadSealedRecords decrypts the records written by a SealedWriter, calling fn with each one in order.
+func ReadSealedRecords(r io.Reader, sealer *Sealer, fn func(record []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		sealed, err := base64.StdEncoding.DecodeString(string(scanner.Bytes()))
+		if err != nil {
+			return fmt.Errorf("line %d: %w: not base64", line, ErrSealedData)
+		}
+		record, err := sealer.Open(sealed, nil)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testSealKey = bytes.Repeat([]byte{0x42}, SealKeySize)
+
+func TestSealerRoundTrip(t *testing.T) {
+	sealer, err := NewSealer(testSealKey, "session-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := sealer.Seal([]byte("user content"), []byte("record-7"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(sealed, []byte("user content")) {
+		t.Fatal("Seal() output contains the plaintext")
+	}
+	got, err := sealer.Open(sealed, []byte("record-7"))
+	if err != nil || string(got) != "user content" {
+		t.Fatalf("Open() = %q, %v", got, err)
+	}
+
+	other, _ := NewSealer(testSealKey, "session-2")
+	tampered := append([]byte(nil), sealed...)
+	tampered[len(tampered)-1] ^= 1
+	for name, open := range map[string]func() ([]byte, error){
+		"other session": func() ([]byte, error) { return other.Open(sealed, []byte("record-7")) },
+		"other record":  func() ([]byte, error) { return sealer.Open(sealed, []byte("record-8")) },
+		"tampered":      func() ([]byte, error) { return sealer.Open(tampered, []byte("record-7")) },
+		"truncated":     func() ([]byte, error) { return sealer.Open(sealed[:8], []byte("record-7")) },
+	} {
+		if _, err := open(); !errors.Is(err, ErrSealedData) {
+			t.Errorf("%s: Open() error = %v, want ErrSealedData", name, err)
+		}
+	}
+}
+
+func TestSealedWriterRecords(t *testing.T) {
+	sealer, _ := NewSealer(testSealKey, "audit")
+	var buf bytes.Buffer
+	logger := New(NewSealedWriter(&buf, sealer), "", 0, LevelInfo)
+	logger.Printf("INFO", "tools/call ping %s", "192.168.1.1")
+	logger.Printf("INFO", "resources/read %s", "file:///secret.txt")
+
+	if strings.Contains(buf.String(), "192.168.1.1") {
+		t.Fatalf("sealed log contains plaintext: %s", buf.String())
+	}
+	var records []string
+	if err := ReadSealedRecords(&buf, sealer, func(r []byte) error {
+		records = append(records, string(r))
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"tools/call ping 192.168.1.1\n", "resources/read file:///secret.txt\n"}
+	if strings.Join(records, "|") != strings.Join(want, "|") {
+		t.Errorf("records = %q, want %q", records, want)
+	}
+}
+
+func TestLoadSealKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key")
+	os.WriteFile(path, []byte(hex.EncodeToString(testSealKey)+"\n"), 0600)
+	if key, err := LoadSealKey("", path); err != nil || !bytes.Equal(key, testSealKey) {
+		t.Errorf("LoadSealKey(file) = %x, %v", key, err)
+	}
+
+	t.Setenv("MCP_TEST_SEAL_KEY", "c2hvcnQ=")
+	if _, err := LoadSealKey("MCP_TEST_SEAL_KEY", path); err == nil {
+		t.Error("LoadSealKey() accepted a short key from the environment")
+	}
+	if key, err := LoadSealKey("MCP_TEST_UNSET_KEY", ""); key != nil || err != nil {
+		t.Errorf("LoadSealKey() with no source = %x, %v, want nil, nil", key, err)
+	}
+}